@@ -0,0 +1,283 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+// Package burrow is the API for embedding Burrow in another Go application. It runs the same coordinators as the
+// burrow command, configured with functional options instead of (or as well as) a configuration file:
+//
+//	b, err := burrow.New(
+//		burrow.WithConfigFile("/etc/burrow/burrow.toml"),
+//		burrow.WithSetting("httpserver.default.address", ":8000"),
+//		burrow.WithEvaluations(statusChannel, time.Minute),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	return b.Run(ctx)
+//
+// Modules of classes that are not built in to Burrow can be added with WithModule, and then used in the configuration
+// by setting their class-name. As Burrow keeps its configuration in the global viper instance, only one Burrow can run
+// in a process at a time.
+package burrow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/core"
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// Burrow is an instance of Burrow that has been configured, and is ready to run
+type Burrow struct {
+	app                *protocol.ApplicationContext
+	evaluations        chan<- *protocol.ConsumerGroupStatus
+	evaluationInterval time.Duration
+}
+
+// Option configures a Burrow that is being created with New
+type Option func(b *Burrow) error
+
+// WithConfigFile reads the configuration from a file, including any files that it includes, in the same way as the
+// burrow command. The type of the file is taken from its extension. Options after this one override its settings.
+func WithConfigFile(filename string) Option {
+	return func(b *Burrow) error {
+		viper.SetConfigFile(filename)
+		if err := viper.ReadInConfig(); err != nil {
+			return err
+		}
+		if err := helpers.MergeConfigIncludes(viper.GetViper()); err != nil {
+			return err
+		}
+		return helpers.InterpolateConfig(viper.GetViper())
+	}
+}
+
+// WithConfig merges a map of settings into the configuration, in the same layout as the configuration file (for
+// example, {"cluster": {"local": {"class-name": "kafka", ...}}})
+func WithConfig(settings map[string]interface{}) Option {
+	return func(b *Burrow) error {
+		return viper.MergeConfigMap(settings)
+	}
+}
+
+// WithSetting sets a single configuration key, such as "cluster.local.offset-refresh", overriding the value from any
+// configuration file
+func WithSetting(key string, value interface{}) Option {
+	return func(b *Burrow) error {
+		viper.Set(key, value)
+		return nil
+	}
+}
+
+// WithLogger sets the logger that Burrow uses, and the level that can be changed to adjust it. If this is not given, a
+// logger is created from the logging section of the configuration.
+func WithLogger(logger *zap.Logger, level *zap.AtomicLevel) Option {
+	return func(b *Burrow) error {
+		if (logger == nil) || (level == nil) {
+			return errors.New("both the logger and the level must be given")
+		}
+		b.app.Logger = logger
+		b.app.LogLevel = level
+		return nil
+	}
+}
+
+// WithModule registers a class of module for a coordinator ("cluster", "consumer", "evaluator", "exporter", "reporter",
+// or "storage"), so that modules in the configuration can use it as their class-name. The factory is called once for
+// each module with that class, and must return a module that satisfies the Module interface of the coordinator.
+func WithModule(coordinator, className string, factory helpers.ModuleFactory) Option {
+	return func(b *Burrow) error {
+		switch coordinator {
+		case "cluster", "consumer", "evaluator", "exporter", "reporter", "storage":
+		default:
+			return errors.New("modules cannot be added to the " + coordinator + " coordinator")
+		}
+		if factory == nil {
+			return errors.New("no factory given for class " + className)
+		}
+		helpers.RegisterModuleClass(coordinator, className, factory)
+		return nil
+	}
+}
+
+// WithJSONEncoder registers an encoder for the HTTP server's responses and the statuses that exporters send, so that
+// general.json-encoder can be set to the name to use it instead of encoding/json. The encoder must produce the same
+// JSON as encoding/json does.
+func WithJSONEncoder(name string, encoder helpers.JSONEncoder) Option {
+	return func(b *Burrow) error {
+		if encoder == nil {
+			return errors.New("no encoder given for " + name)
+		}
+		helpers.RegisterJSONEncoder(name, encoder)
+		return nil
+	}
+}
+
+// WithEvaluations has Burrow evaluate every consumer group in every cluster each interval, and send the status of each
+// group to the channel, including all of its partitions. Burrow waits for each status to be received, so the channel
+// must be read from while Burrow is running.
+func WithEvaluations(channel chan<- *protocol.ConsumerGroupStatus, interval time.Duration) Option {
+	return func(b *Burrow) error {
+		if interval <= 0 {
+			return errors.New("the evaluation interval must be more than zero")
+		}
+		b.evaluations = channel
+		b.evaluationInterval = interval
+		return nil
+	}
+}
+
+// New creates a Burrow with the given options, which are applied in order, and checks that the resulting configuration
+// is valid. If any option fails, or the configuration is not valid, an error is returned.
+func New(opts ...Option) (*Burrow, error) {
+	b := &Burrow{
+		app: &protocol.ApplicationContext{},
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	helpers.ApplyConfigDeprecations(viper.GetViper())
+
+	if configErrors := core.CheckConfig(); len(configErrors) > 0 {
+		messages := make([]string, len(configErrors))
+		for i, configError := range configErrors {
+			messages[i] = configError.Subsystem + ": " + configError.Error
+		}
+		return nil, errors.New("invalid configuration: " + strings.Join(messages, "; "))
+	}
+
+	if b.app.Logger == nil {
+		b.app.Logger, b.app.LogLevel = core.ConfigureLogger()
+	}
+	b.app.EvaluatorChannel = helpers.MakeEvaluatorChannel()
+	b.app.StorageChannel = helpers.MakeStorageChannel()
+	b.app.StorageEvaluationChannel = helpers.MakeStorageChannel()
+	b.app.StorageAPIChannel = helpers.MakeStorageChannel()
+	return b, nil
+}
+
+// Run starts Burrow, and blocks until the context is canceled, at which point Burrow is stopped. An error is returned
+// if Burrow fails to start.
+func (b *Burrow) Run(ctx context.Context) error {
+	exitChannel := make(chan os.Signal)
+	result := make(chan int, 1)
+	go func() {
+		result <- core.Start(b.app, exitChannel)
+	}()
+
+	evaluationsDone := make(chan struct{})
+	evaluationCtx, stopEvaluations := context.WithCancel(ctx)
+	defer stopEvaluations()
+	if b.evaluations != nil {
+		go b.evaluateGroups(evaluationCtx, evaluationsDone)
+	} else {
+		close(evaluationsDone)
+	}
+
+	select {
+	case code := <-result:
+		// Burrow stopped on its own, which only happens if it failed to start
+		stopEvaluations()
+		<-evaluationsDone
+		if code != 0 {
+			return errors.New("failed to start Burrow")
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	<-evaluationsDone
+	close(exitChannel)
+	<-result
+	return nil
+}
+
+// evaluateGroups sends the status of every consumer group to the evaluations channel each evaluation interval, until
+// the context is canceled
+func (b *Burrow) evaluateGroups(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(b.evaluationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		clusters, _ := b.fetchStorage(ctx, &protocol.StorageRequest{RequestType: protocol.StorageFetchClusters}).([]string)
+		for _, cluster := range clusters {
+			groups, _ := b.fetchStorage(ctx, &protocol.StorageRequest{
+				RequestType: protocol.StorageFetchConsumers,
+				Cluster:     cluster,
+			}).([]string)
+			for _, group := range groups {
+				status := b.evaluateGroup(ctx, cluster, group)
+				if status == nil {
+					continue
+				}
+				select {
+				case b.evaluations <- status:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// fetchStorage sends a request to the storage coordinator and returns the response, or nil if the context is canceled
+func (b *Burrow) fetchStorage(ctx context.Context, request *protocol.StorageRequest) interface{} {
+	request.Reply = make(chan interface{}, 1)
+	request.Submitted = time.Now()
+	select {
+	case b.app.GetStorageChannel(protocol.StoragePriorityEvaluation) <- request:
+	case <-ctx.Done():
+		return nil
+	}
+	select {
+	case response := <-request.Reply:
+		return response
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// evaluateGroup requests the status of a group from the evaluator, or returns nil if the context is canceled
+func (b *Burrow) evaluateGroup(ctx context.Context, cluster, group string) *protocol.ConsumerGroupStatus {
+	request := &protocol.EvaluatorRequest{
+		Reply:     make(chan *protocol.ConsumerGroupStatus, 1),
+		Submitted: time.Now(),
+		Cluster:   cluster,
+		Group:     group,
+		ShowAll:   true,
+	}
+	select {
+	case b.app.EvaluatorChannel <- request:
+	case <-ctx.Done():
+		return nil
+	}
+	select {
+	case status := <-request.Reply:
+		return status
+	case <-ctx.Done():
+		return nil
+	}
+}
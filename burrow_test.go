@@ -0,0 +1,175 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package burrow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// testCluster is a cluster module that stores a fixed set of offsets for one group when it is started
+type testCluster struct {
+	App  *protocol.ApplicationContext
+	Log  *zap.Logger
+	name string
+}
+
+func (module *testCluster) Configure(name string, configRoot string) {
+	module.name = name
+}
+
+func (module *testCluster) Start() error {
+	go func() {
+		now := time.Now().Unix() * 1000
+		module.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             module.name,
+			Topic:               "testtopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+			Offset:              100,
+			Timestamp:           now,
+		}
+
+		// The requests go to a worker for the group, which can be a different worker than the one for the topic, so
+		// wait for the broker offset to be stored, or the commits could be stored before there is a topic for them
+		for {
+			request := &protocol.StorageRequest{
+				RequestType: protocol.StorageFetchTopic,
+				Cluster:     module.name,
+				Topic:       "testtopic",
+				Reply:       make(chan interface{}),
+			}
+			module.App.StorageChannel <- request
+			if offsets, ok := (<-request.Reply).([]int64); ok && (len(offsets) == 1) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		for i := int64(0); i < 3; i++ {
+			module.App.StorageChannel <- &protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     module.name,
+				Topic:       "testtopic",
+				Group:       "testgroup",
+				Partition:   0,
+				Offset:      98 + i,
+				Order:       i,
+				Timestamp:   now - (3-i)*10000,
+			}
+		}
+	}()
+	return nil
+}
+
+func (module *testCluster) Stop() error {
+	return nil
+}
+
+func TestBurrow_Options(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	_, err := New(WithEvaluations(make(chan *protocol.ConsumerGroupStatus), 0))
+	assert.Error(t, err, "Expected error for zero evaluation interval")
+
+	_, err = New(WithModule("httpserver", "test", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return &testCluster{App: app, Log: log}
+	}))
+	assert.Error(t, err, "Expected error for module on unsupported coordinator")
+
+	_, err = New(WithLogger(nil, nil))
+	assert.Error(t, err, "Expected error for missing logger")
+
+	_, err = New(WithJSONEncoder("test", nil))
+	assert.Error(t, err, "Expected error for missing JSON encoder")
+
+	_, err = New(WithSetting("general.json-encoder", "notanencoder"), WithLogger(zap.NewNop(), &zap.AtomicLevel{}))
+	assert.Error(t, err, "Expected error for unknown JSON encoder")
+
+	_, err = New(WithSetting("logging.level", "notalevel"), WithLogger(zap.NewNop(), &zap.AtomicLevel{}))
+	assert.Error(t, err, "Expected error for invalid configuration")
+}
+
+func TestBurrow_RunWithModule(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	evaluations := make(chan *protocol.ConsumerGroupStatus)
+	b, err := New(
+		WithLogger(zap.NewNop(), &level),
+		WithModule("cluster", "test", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+			return &testCluster{App: app, Log: log}
+		}),
+		WithConfig(map[string]interface{}{
+			"cluster": map[string]interface{}{
+				"testcluster": map[string]interface{}{
+					"class-name": "test",
+				},
+			},
+			"storage": map[string]interface{}{
+				"default": map[string]interface{}{
+					"class-name":   "inmemory",
+					"min-distance": 1,
+				},
+			},
+			"evaluator": map[string]interface{}{
+				"default": map[string]interface{}{
+					"class-name": "caching",
+				},
+			},
+		}),
+		WithEvaluations(evaluations, 50*time.Millisecond),
+	)
+	assert.Nil(t, err, "Expected New to return no error")
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runResult := make(chan error, 1)
+	go func() {
+		runResult <- b.Run(ctx)
+	}()
+
+	var status *protocol.ConsumerGroupStatus
+	timeout := time.After(5 * time.Second)
+	for status == nil {
+		select {
+		case received := <-evaluations:
+			if received.Status != protocol.StatusNotFound {
+				status = received
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for an evaluation")
+		}
+	}
+	assert.Equal(t, "testcluster", status.Cluster, "Expected the evaluation to be for testcluster")
+	assert.Equal(t, "testgroup", status.Group, "Expected the evaluation to be for testgroup")
+	assert.Len(t, status.Partitions, 1, "Expected all partitions to be included in the evaluation")
+
+	cancel()
+	select {
+	case err := <-runResult:
+		assert.Nil(t, err, "Expected Run to return no error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for Run to return")
+	}
+}
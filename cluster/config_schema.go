@@ -0,0 +1,42 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package cluster
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("cluster.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "labels", Type: helpers.ConfigTypeMap},
+		helpers.ConfigKey{Name: "expected-groups", Type: helpers.ConfigTypeStringList},
+	)
+	helpers.RegisterConfigKeys("cluster.*", "kafka", append([]helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "client-pool", Type: helpers.ConfigTypeString},
+		{Name: "offset-refresh", Type: helpers.ConfigTypeInteger, Default: 10},
+		{Name: "topic-refresh", Type: helpers.ConfigTypeInteger, Default: 60},
+		{Name: "topic-refresh-jitter", Type: helpers.ConfigTypeFloat, Default: 0.1},
+		{Name: "topic-refresh-max", Type: helpers.ConfigTypeInteger, Default: 600},
+		{Name: "offset-batch-size", Type: helpers.ConfigTypeInteger, Default: 1000},
+		{Name: "offset-fetch-concurrency", Type: helpers.ConfigTypeInteger, Default: 20},
+		{Name: "offset-fetch-timeout", Type: helpers.ConfigTypeInteger},
+		{Name: "consumed-topics-only", Type: helpers.ConfigTypeBoolean},
+		{Name: "topic-config-refresh", Type: helpers.ConfigTypeInteger, Default: 300},
+		{Name: "topic-configs", Type: helpers.ConfigTypeStringList},
+		{Name: "leader-churn-window", Type: helpers.ConfigTypeInteger, Default: 3600},
+		{Name: "health-max-commit-age", Type: helpers.ConfigTypeInteger, Default: 600},
+		{Name: "health-max-metadata-age", Type: helpers.ConfigTypeInteger},
+		{Name: "health-max-offset-age", Type: helpers.ConfigTypeInteger},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
@@ -18,6 +18,9 @@
 // Currently, the following modules are provided:
 //
 // * kafka - Fetch topic, partition, and offset information from a Kafka cluster
+//
+// * plugin - Run a cluster module that is built as a separate executable (see the plugin package). This is left out
+// of builds with the no_plugin tag
 package cluster
 
 import (
@@ -63,6 +66,14 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 			),
 		}
 	default:
+		if factory := helpers.GetModuleClass("cluster", className); factory != nil {
+			return factory(app, app.Logger.With(
+				zap.String("type", "module"),
+				zap.String("coordinator", "cluster"),
+				zap.String("class", className),
+				zap.String("name", moduleName),
+			))
+		}
 		panic("Unknown cluster className provided: " + className)
 	}
 }
@@ -109,3 +120,8 @@ func (bc *Coordinator) Stop() error {
 	helpers.StopCoordinatorModules(bc.modules)
 	return nil
 }
+
+// Health returns the health of each cluster module
+func (bc *Coordinator) Health() *protocol.CoordinatorHealth {
+	return helpers.GetCoordinatorHealth("cluster", bc.modules)
+}
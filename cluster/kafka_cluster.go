@@ -11,10 +11,18 @@
 package cluster
 
 import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/OneOfOne/xxhash"
 	"github.com/Shopify/sarama"
+	"github.com/rcrowley/go-metrics"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
@@ -25,7 +33,33 @@ import (
 // KafkaCluster is a cluster module which connects to a single Apache Kafka cluster and manages the broker topic and
 // partition information. It periodically updates a list of all topics and partitions, and also fetches the broker
 // end offset (latest) for each partition. This information is forwarded to the storage module for use in consumer
-// evaluations.
+// evaluations. The log start offset (oldest) for each partition is fetched each time the topic list is refreshed, so
+// that consumers which have fallen behind retention can be detected.
+//
+// Topics that are not accepted by the topic-allowlist and topic-denylist configs are skipped entirely, so no offsets
+// are stored for them, and consumer offsets for them are dropped by storage. If a topic stops being accepted (such as
+// when the lists are changed through the HTTP server), it is deleted from storage at the next metadata refresh.
+//
+// At each metadata refresh, partitions that have fewer in-sync replicas than replicas, or that have no leader, are
+// sent to storage, along with when they were first seen that way, as these often explain why consumers have stalled.
+// The brokers in the cluster, and which of them is the controller, are sent to storage at the same time. The leader of
+// each partition is also tracked between refreshes, and the partitions that have changed leader within the
+// leader-churn-window are sent to storage, as frequent leadership changes are another common cause of consumer stalls.
+//
+// Topic metadata is not refreshed for every topic at each topic-refresh interval. Each topic has its own refresh
+// interval, which starts at topic-refresh and doubles each time the topic is refreshed without its metadata changing,
+// up to topic-refresh-max. Topics that are busy (their end offsets have moved since the last refresh) stay at the
+// shortest interval, and a change in a topic's partitions or replicas, or an error fetching its offsets, moves it back
+// to the shortest interval. The metadata for all topics, which is needed to find new topics, is refreshed every
+// topic-refresh-max seconds. Each refresh time is randomized by topic-refresh-jitter, so that topics do not all come
+// due at the same time. On clusters with many idle topics, this greatly reduces the number of metadata requests.
+//
+// The configuration of each topic (such as retention.ms, cleanup.policy, and min.insync.replicas) is also fetched
+// periodically using an admin client, and stored so that it can be seen through the HTTP server alongside the lag.
+//
+// On clusters with a large number of topics that nobody consumes, the module can be set to only fetch offsets for the
+// topics that consumer groups have committed offsets for. The list of consumed topics is retrieved from storage before
+// each offset fetch. Metadata is still refreshed for every topic, so that new topics are found.
 type KafkaCluster struct {
 	// App is a pointer to the application context. This stores the channel to the storage subsystem
 	App *protocol.ApplicationContext
@@ -37,30 +71,125 @@ type KafkaCluster struct {
 	name          string
 	saramaConfig  *sarama.Config
 	servers       []string
+	clientPool    string
 	offsetRefresh int
 	topicRefresh  int
 
+	// Offset requests are sent in batches of up to offsetBatchSize partitions, with up to offsetFetchConcurrency
+	// requests at a time. busyBrokers holds the IDs of brokers that have requests outstanding
+	offsetBatchSize        int
+	offsetFetchConcurrency int
+	offsetFetchTimeout     time.Duration
+	busyBrokers            sync.Map
+
+	// Topic metadata refresh scheduling. topicSchedules is only used from the mainLoop goroutine, but endOffsets is
+	// updated concurrently while fetching offsets
+	topicRefreshMax    int
+	topicRefreshJitter float64
+	topicSchedules     map[string]*topicSchedule
+	nextFullRefresh    time.Time
+	endOffsets         sync.Map
+
+	// Topic configs are only fetched if topicConfigRefresh is more than zero
+	topicConfigRefresh int
+	topicConfigNames   []string
+	admin              helpers.SaramaClusterAdmin
+
 	offsetTicker   *time.Ticker
 	metadataTicker *time.Ticker
+	configTicker   *time.Ticker
 	quitChannel    chan struct{}
 	running        sync.WaitGroup
 
 	fetchMetadata   bool
+	fetchLogStart   bool
 	topicPartitions map[string][]int32
+	filter          *helpers.ConsumerFilter
+
+	// If consumedTopicsOnly is set, offsets are only fetched for the topics in consumedTopics, which is updated from
+	// storage before each offset fetch
+	consumedTopicsOnly bool
+	consumedTopics     map[string]bool
+
+	// The time (in milliseconds) at which each partition was first seen under-replicated or offline
+	underReplicatedSince map[topicPartition]int64
+	offlineSince         map[topicPartition]int64
+
+	// The ID of the leader of each partition at the last metadata refresh (-1 if it had no leader), and the times (in
+	// milliseconds) at which the leader changed within the leader-churn-window
+	leaderChurnWindow int
+	leaders           map[topicPartition]int32
+	leaderChanges     map[topicPartition][]int64
+
+	offsetFetchTime metrics.Histogram
+	offsetCount     metrics.Counter
+	errorCount      metrics.Counter
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// topicSchedule is when the metadata for a topic will next be refreshed
+type topicSchedule struct {
+	interval time.Duration
+	next     time.Time
+
+	// A hash of the topic's partitions and replicas at the last refresh, used to tell if the metadata changed
+	fingerprint uint64
+
+	// Whether the end offsets for the topic have moved since the last refresh
+	active bool
 }
 
 // Configure validates the configuration for the cluster. At minimum, there must be a list of servers provided for the
 // Kafka cluster, of the form host:port. Default values will be set for the intervals to use for refreshing offsets
 // (10 seconds) and topics (60 seconds). A missing, or bad, list of servers will cause this func to panic.
+//
+// Idle topics are refreshed as rarely as every topic-refresh-max seconds (600 by default), which must not be less than
+// topic-refresh. Refresh times are randomized by up to topic-refresh-jitter (0.1 by default) of the interval, which
+// must be at least 0 and less than 1.
+//
+// Offsets are requested from each broker in batches of up to offset-batch-size partitions (1000 by default), with at
+// most offset-fetch-concurrency requests outstanding at once (20 by default). Each refresh waits at most
+// offset-fetch-timeout seconds (by default, the same as offset-refresh) for the responses.
+//
+// If client-pool is set, the module shares a Kafka client with the other modules that have the same client-pool, such
+// as the consumer module for the same cluster. This halves the number of connections to each broker. The modules must
+// use the same servers and client-profile.
+//
+// The cluster's health, as returned by the HTTP server, is bad if broker offsets have not been fetched within
+// health-max-offset-age seconds (three times offset-refresh by default), if metadata has not been refreshed within
+// health-max-metadata-age seconds (three times topic-refresh by default), or if no consumer offset commit newer than
+// health-max-commit-age seconds (600 by default) has been seen. These must all be more than zero.
+//
+// Any labels set for the cluster (a map of name to value, such as env or region) are not used by the module, but are
+// returned with the cluster details, client metrics, and consumer group status.
+//
+// Partition leader changes are counted over the last leader-churn-window seconds (3600 by default), which must be more
+// than zero.
+//
+// If consumed-topics-only is true (false by default), offsets are only fetched for topics that consumer groups have
+// committed offsets for within the storage module's expire-group interval. Groups that have not committed yet will not
+// have lag calculated until their first commit is seen and the next offset fetch has run.
+//
+// Topic configs are fetched every topic-config-refresh seconds (300 by default, and 0 disables fetching them). Only the
+// configs named in topic-configs are fetched, which defaults to retention.ms, retention.bytes, cleanup.policy, and
+// min.insync.replicas. If topic-configs is set to an empty list, every config for the topic is fetched.
 func (module *KafkaCluster) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
 	module.name = name
 	module.quitChannel = make(chan struct{})
 	module.running = sync.WaitGroup{}
+	module.offsetFetchTime = helpers.GetMetricHistogram(configRoot + ".offset-fetch-time")
+	module.offsetCount = helpers.GetMetricCounter(configRoot + ".offsets")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
 
 	profile := viper.GetString(configRoot + ".client-profile")
 	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, name, module.saramaConfig)
 
 	module.servers = viper.GetStringSlice(configRoot + ".servers")
 	if len(module.servers) == 0 {
@@ -68,12 +197,65 @@ func (module *KafkaCluster) Configure(name, configRoot string) {
 	} else if !helpers.ValidateHostList(module.servers) {
 		panic("Cluster '" + name + "' has one or more improperly formatted servers (must be host:port)")
 	}
+	module.clientPool = viper.GetString(configRoot + ".client-pool")
 
 	// Set defaults for configs if needed
 	viper.SetDefault(configRoot+".offset-refresh", 10)
 	viper.SetDefault(configRoot+".topic-refresh", 60)
 	module.offsetRefresh = viper.GetInt(configRoot + ".offset-refresh")
 	module.topicRefresh = viper.GetInt(configRoot + ".topic-refresh")
+
+	viper.SetDefault(configRoot+".offset-batch-size", 1000)
+	viper.SetDefault(configRoot+".offset-fetch-concurrency", 20)
+	viper.SetDefault(configRoot+".offset-fetch-timeout", module.offsetRefresh)
+	module.offsetBatchSize = viper.GetInt(configRoot + ".offset-batch-size")
+	module.offsetFetchConcurrency = viper.GetInt(configRoot + ".offset-fetch-concurrency")
+	module.offsetFetchTimeout = time.Duration(viper.GetInt(configRoot+".offset-fetch-timeout")) * time.Second
+	if (module.offsetBatchSize < 1) || (module.offsetFetchConcurrency < 1) || (module.offsetFetchTimeout <= 0) {
+		panic("Cluster '" + name + "' has an invalid offset-batch-size, offset-fetch-concurrency, or offset-fetch-timeout")
+	}
+
+	viper.SetDefault(configRoot+".topic-refresh-max", 600)
+	viper.SetDefault(configRoot+".topic-refresh-jitter", 0.1)
+	module.topicRefreshMax = viper.GetInt(configRoot + ".topic-refresh-max")
+	module.topicRefreshJitter = viper.GetFloat64(configRoot + ".topic-refresh-jitter")
+	if module.topicRefreshMax < module.topicRefresh {
+		panic("Cluster '" + name + "' has a topic-refresh-max that is less than topic-refresh")
+	}
+	if (module.topicRefreshJitter < 0) || (module.topicRefreshJitter >= 1) {
+		panic("Cluster '" + name + "' has an invalid topic-refresh-jitter")
+	}
+
+	viper.SetDefault(configRoot+".health-max-offset-age", 3*module.offsetRefresh)
+	viper.SetDefault(configRoot+".health-max-metadata-age", 3*module.topicRefresh)
+	viper.SetDefault(configRoot+".health-max-commit-age", 600)
+	if (viper.GetInt(configRoot+".health-max-offset-age") < 1) || (viper.GetInt(configRoot+".health-max-metadata-age") < 1) || (viper.GetInt(configRoot+".health-max-commit-age") < 1) {
+		panic("Cluster '" + name + "' has an invalid health-max-offset-age, health-max-metadata-age, or health-max-commit-age")
+	}
+
+	module.consumedTopicsOnly = viper.GetBool(configRoot + ".consumed-topics-only")
+	module.consumedTopics = make(map[string]bool)
+
+	viper.SetDefault(configRoot+".leader-churn-window", 3600)
+	module.leaderChurnWindow = viper.GetInt(configRoot + ".leader-churn-window")
+	if module.leaderChurnWindow < 1 {
+		panic("Cluster '" + name + "' has an invalid leader-churn-window")
+	}
+
+	viper.SetDefault(configRoot+".topic-config-refresh", 300)
+	viper.SetDefault(configRoot+".topic-configs", []string{"retention.ms", "retention.bytes", "cleanup.policy", "min.insync.replicas"})
+	module.topicConfigRefresh = viper.GetInt(configRoot + ".topic-config-refresh")
+	module.topicConfigNames = viper.GetStringSlice(configRoot + ".topic-configs")
+	if module.topicConfigRefresh < 0 {
+		panic("Cluster '" + name + "' has an invalid topic-config-refresh")
+	}
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile topic filter")
+		panic(err)
+	}
+	module.filter = filter
 }
 
 // Start connects to the Kafka cluster using the Shopify/sarama client. Any error connecting to the cluster is returned
@@ -82,17 +264,29 @@ func (module *KafkaCluster) Start() error {
 	module.Log.Info("starting")
 
 	// Connect Kafka client
-	client, err := sarama.NewClient(module.servers, module.saramaConfig)
+	client, err := helpers.AcquireSaramaClient(module.clientPool, module.servers, module.saramaConfig)
 	if err != nil {
 		module.Log.Error("failed to start client", zap.Error(err))
 		return err
 	}
 
+	if module.topicConfigRefresh > 0 {
+		admin, err := sarama.NewClusterAdminFromClient(client)
+		if err != nil {
+			module.Log.Error("failed to start admin client", zap.Error(err))
+			client.Close()
+			return err
+		}
+		module.admin = admin
+		module.configTicker = time.NewTicker(time.Duration(module.topicConfigRefresh) * time.Second)
+	}
+
 	// Fire off the offset requests once, before we start the ticker, to make sure we start with good data for consumers
 	helperClient := &helpers.BurrowSaramaClient{
 		Client: client,
 	}
 	module.fetchMetadata = true
+	module.fetchLogStart = true
 	module.getOffsets(helperClient)
 
 	// Start main loop that has a timer for offset and topic fetches
@@ -109,6 +303,9 @@ func (module *KafkaCluster) Stop() error {
 
 	module.metadataTicker.Stop()
 	module.offsetTicker.Stop()
+	if module.configTicker != nil {
+		module.configTicker.Stop()
+	}
 	close(module.quitChannel)
 	module.running.Wait()
 
@@ -119,13 +316,23 @@ func (module *KafkaCluster) mainLoop(client helpers.SaramaClient) {
 	module.running.Add(1)
 	defer module.running.Done()
 
+	// Topic configs are fetched here, rather than in Start, as it takes a request for each topic
+	var configTicker <-chan time.Time
+	if module.configTicker != nil {
+		configTicker = module.configTicker.C
+		module.getTopicConfigs()
+	}
+
 	for {
 		select {
 		case <-module.offsetTicker.C:
 			module.getOffsets(client)
 		case <-module.metadataTicker.C:
-			// Update metadata on next offset fetch
+			// Update metadata for the topics that are due, and log start offsets, on next offset fetch
 			module.fetchMetadata = true
+			module.fetchLogStart = true
+		case <-configTicker:
+			module.getTopicConfigs()
 		case <-module.quitChannel:
 			return
 		}
@@ -135,64 +342,342 @@ func (module *KafkaCluster) mainLoop(client helpers.SaramaClient) {
 func (module *KafkaCluster) maybeUpdateMetadataAndDeleteTopics(client helpers.SaramaClient) {
 	if module.fetchMetadata {
 		module.fetchMetadata = false
-		client.RefreshMetadata()
+
+		// Refresh all topics if it is time to, otherwise only the topics that are due. If no topics are due, the
+		// cached metadata is still checked, as the topic filters may have changed
+		nowTime := time.Now()
+		fullRefresh := !nowTime.Before(module.nextFullRefresh)
+		refreshed := make(map[string]bool)
+		if fullRefresh {
+			client.RefreshMetadata()
+			module.nextFullRefresh = nowTime.Add(module.jitter(time.Duration(module.topicRefreshMax) * time.Second))
+		} else if dueTopics := module.dueTopics(nowTime); len(dueTopics) > 0 {
+			client.RefreshMetadata(dueTopics...)
+			for _, topic := range dueTopics {
+				refreshed[topic] = true
+			}
+		}
 
 		// Get the current list of topics and make a map
 		topicList, err := client.Topics()
 		if err != nil {
 			module.Log.Error("failed to fetch topic list", zap.String("sarama_error", err.Error()))
+			module.errorCount.Inc(1)
+			helpers.PublishModuleError("cluster."+module.name, module.name, "failed to fetch topic list: "+err.Error())
+			helpers.SetModuleHealth("cluster."+module.name, protocol.HealthFailed, "failed to fetch topic list: "+err.Error())
 			return
 		}
+		helpers.SetModuleHealth("cluster."+module.name, protocol.HealthOK, "")
 
 		// We'll use topicPartitions later
 		topicPartitions := make(map[string][]int32)
+		now := nowTime.Unix() * 1000
+		replication := &protocol.ClusterReplication{
+			UnderReplicated: make([]*protocol.PartitionReplicas, 0),
+			Offline:         make([]*protocol.PartitionReplicas, 0),
+			Timestamp:       now,
+		}
+		underReplicatedSince := make(map[topicPartition]int64)
+		offlineSince := make(map[topicPartition]int64)
+		leaders := make(map[topicPartition]int32)
+		leaderChanges := make(map[topicPartition][]int64)
+		topicSchedules := make(map[string]*topicSchedule)
 		for _, topic := range topicList {
+			if !module.filter.AcceptTopic(topic) {
+				continue
+			}
 			partitions, err := client.Partitions(topic)
 			if err != nil {
 				module.Log.Error("failed to fetch partition list", zap.String("sarama_error", err.Error()))
+				module.errorCount.Inc(1)
+				helpers.PublishModuleError("cluster."+module.name, module.name, "failed to fetch partition list: "+err.Error())
 				return
 			}
 
 			topicPartitions[topic] = make([]int32, 0, len(partitions))
+			fingerprint := xxhash.New64()
 			for _, partitionID := range partitions {
-				if _, err := client.Leader(topic, partitionID); err != nil {
+				binary.Write(fingerprint, binary.BigEndian, partitionID)
+				leader, err := client.Leader(topic, partitionID)
+				if err != nil {
 					module.Log.Warn("failed to fetch leader for partition",
 						zap.String("topic", topic),
 						zap.Int32("partition", partitionID),
 						zap.String("sarama_error", err.Error()))
+					binary.Write(fingerprint, binary.BigEndian, int32(-1))
+					module.trackLeader(leaders, leaderChanges, topic, partitionID, -1, now)
+					if err == sarama.ErrLeaderNotAvailable {
+						replicas := getPartitionReplicas(client, topic, partitionID)
+						replicas.Since = module.trackSince(module.offlineSince, offlineSince, replicas, now, "partition offline")
+						replication.Offline = append(replication.Offline, replicas)
+					}
 				} else { // partitionID has a leader
 					// NOTE: append only happens here
 					// so cap(topicPartitions[topic]) is the partition count
 					topicPartitions[topic] = append(topicPartitions[topic], partitionID)
+					module.trackLeader(leaders, leaderChanges, topic, partitionID, leader.ID(), now)
+
+					replicas := getPartitionReplicas(client, topic, partitionID)
+					binary.Write(fingerprint, binary.BigEndian, replicas.Replicas)
+					binary.Write(fingerprint, binary.BigEndian, replicas.InSyncReplicas)
+					if len(replicas.InSyncReplicas) < len(replicas.Replicas) {
+						replicas.Since = module.trackSince(module.underReplicatedSince, underReplicatedSince, replicas, now, "partition under-replicated")
+						replication.UnderReplicated = append(replication.UnderReplicated, replicas)
+					}
 				}
 			}
+			topicSchedules[topic] = module.scheduleTopic(topic, fingerprint.Sum64(), fullRefresh || refreshed[topic], nowTime)
 		}
 
-		// Check for deleted topics if we have a previous map to check against
+		// Check for deleted topics if we have a previous map to check against. Kafka does not allow the partition count
+		// of a topic to be reduced, so a topic that has fewer partitions than before was deleted and recreated. It is
+		// removed from storage as well, so that the partitions that no longer exist do not stay in the lag output
 		if module.topicPartitions != nil {
-			for topic := range module.topicPartitions {
-				if _, ok := topicPartitions[topic]; !ok {
-					// Topic no longer exists - tell storage to delete it
-					module.App.StorageChannel <- &protocol.StorageRequest{
-						RequestType: protocol.StorageSetDeleteTopic,
-						Cluster:     module.name,
-						Topic:       topic,
-					}
+			for topic, previous := range module.topicPartitions {
+				reason := ""
+				if current, ok := topicPartitions[topic]; !ok {
+					reason = "deleted"
+				} else if cap(current) < cap(previous) {
+					reason = "partition count decreased"
+				} else {
+					continue
+				}
+
+				// Topic no longer exists as it was - tell storage to delete it
+				module.Log.Info("removing topic",
+					zap.String("topic", topic),
+					zap.String("reason", reason))
+				for _, partitionID := range previous {
+					module.endOffsets.Delete(topicPartition{topic: topic, partition: partitionID})
+				}
+				module.App.StorageChannel <- &protocol.StorageRequest{
+					RequestType: protocol.StorageSetDeleteTopic,
+					Cluster:     module.name,
+					Topic:       topic,
+					Reason:      reason,
 				}
 			}
 		}
 
-		// Save the new topicPartitions for next time
+		// Save the new topicPartitions and schedules for next time
 		module.topicPartitions = topicPartitions
+		module.topicSchedules = topicSchedules
+
+		module.underReplicatedSince = underReplicatedSince
+		module.offlineSince = offlineSince
+		module.leaders = leaders
+		module.leaderChanges = leaderChanges
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetClusterBrokers,
+			Cluster:     module.name,
+			Brokers:     module.getClusterBrokers(client, now),
+		}, 1)
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetClusterReplication,
+			Cluster:     module.name,
+			Replication: replication,
+		}, 1)
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetClusterLeaderChurn,
+			Cluster:     module.name,
+			LeaderChurn: module.getLeaderChurn(now),
+		}, 1)
+	}
+}
+
+// dueTopics returns the topics whose metadata is due to be refreshed, sorted by name
+func (module *KafkaCluster) dueTopics(now time.Time) []string {
+	topics := make([]string, 0)
+	for topic, schedule := range module.topicSchedules {
+		if !now.Before(schedule.next) {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// scheduleTopic returns when the metadata for the topic should next be refreshed. If the topic was not refreshed, the
+// existing schedule is kept. Otherwise, the interval is reset to topic-refresh if the metadata changed or the topic is
+// busy, and doubled (up to topic-refresh-max) if the topic is idle.
+func (module *KafkaCluster) scheduleTopic(topic string, fingerprint uint64, refreshed bool, now time.Time) *topicSchedule {
+	minInterval := time.Duration(module.topicRefresh) * time.Second
+	maxInterval := time.Duration(module.topicRefreshMax) * time.Second
+
+	schedule, ok := module.topicSchedules[topic]
+	switch {
+	case !ok:
+		schedule = &topicSchedule{interval: minInterval}
+	case !refreshed:
+		return schedule
+	case (schedule.fingerprint != fingerprint) || schedule.active:
+		schedule.interval = minInterval
+	default:
+		schedule.interval *= 2
+		if schedule.interval > maxInterval {
+			schedule.interval = maxInterval
+		}
+	}
+	schedule.fingerprint = fingerprint
+	schedule.active = false
+	schedule.next = now.Add(module.jitter(schedule.interval))
+	return schedule
+}
+
+// refreshTopicSoon makes the metadata for the topic due to be refreshed at the next metadata refresh, and resets its
+// refresh interval. This is used when a topic has errors, as its metadata is likely out of date.
+func (module *KafkaCluster) refreshTopicSoon(topic string) {
+	if schedule, ok := module.topicSchedules[topic]; ok {
+		schedule.interval = time.Duration(module.topicRefresh) * time.Second
+		schedule.next = time.Time{}
 	}
+	module.fetchMetadata = true
+}
+
+// jitter returns the interval, randomly adjusted by up to topic-refresh-jitter of it in either direction
+func (module *KafkaCluster) jitter(interval time.Duration) time.Duration {
+	return interval + time.Duration(float64(interval)*module.topicRefreshJitter*(2*rand.Float64()-1))
 }
 
-func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient) (map[int32]*sarama.OffsetRequest, map[int32]helpers.SaramaBroker) {
-	requests := make(map[int32]*sarama.OffsetRequest)
+// getClusterBrokers returns the brokers from the cluster metadata, sorted by ID, and the ID of the controller
+func (module *KafkaCluster) getClusterBrokers(client helpers.SaramaClient, now int64) *protocol.ClusterBrokers {
+	clusterBrokers := &protocol.ClusterBrokers{
+		Brokers:      make([]*protocol.Broker, 0),
+		ControllerID: -1,
+		Timestamp:    now,
+	}
+	for _, broker := range client.Brokers() {
+		clusterBroker := &protocol.Broker{
+			ID:   broker.ID(),
+			Host: broker.Addr(),
+			Rack: broker.Rack(),
+		}
+		if host, port, err := net.SplitHostPort(broker.Addr()); err == nil {
+			portNum, _ := strconv.ParseInt(port, 10, 32)
+			clusterBroker.Host = host
+			clusterBroker.Port = int32(portNum)
+		}
+		clusterBrokers.Brokers = append(clusterBrokers.Brokers, clusterBroker)
+	}
+	sort.Slice(clusterBrokers.Brokers, func(i, j int) bool { return clusterBrokers.Brokers[i].ID < clusterBrokers.Brokers[j].ID })
+
+	controller, err := client.Controller()
+	if err != nil {
+		module.Log.Warn("failed to fetch controller", zap.String("sarama_error", err.Error()))
+	} else {
+		clusterBrokers.ControllerID = controller.ID()
+	}
+	return clusterBrokers
+}
+
+// getPartitionReplicas returns the replicas and in-sync replicas for the partition. Errors are ignored, as sarama
+// returns ErrReplicaNotAvailable, along with the replicas, when some of the replicas are not available
+func getPartitionReplicas(client helpers.SaramaClient, topic string, partitionID int32) *protocol.PartitionReplicas {
+	replicas, _ := client.Replicas(topic, partitionID)
+	isr, _ := client.InSyncReplicas(topic, partitionID)
+	return &protocol.PartitionReplicas{
+		Topic:          topic,
+		Partition:      partitionID,
+		Replicas:       replicas,
+		InSyncReplicas: isr,
+	}
+}
+
+// trackSince records the partition in the current map, keeping the time it was first seen from the previous map if it
+// was there, and returns that time. The message is logged when the partition was not in the previous map.
+func (module *KafkaCluster) trackSince(previous, current map[topicPartition]int64, replicas *protocol.PartitionReplicas, now int64, message string) int64 {
+	key := topicPartition{topic: replicas.Topic, partition: replicas.Partition}
+	since, ok := previous[key]
+	if !ok {
+		since = now
+		module.Log.Warn(message,
+			zap.String("topic", replicas.Topic),
+			zap.Int32("partition", replicas.Partition),
+			zap.Int32s("replicas", replicas.Replicas),
+			zap.Int32s("isr", replicas.InSyncReplicas),
+		)
+	}
+	current[key] = since
+	return since
+}
+
+// trackLeader records the leader of the partition in the current maps, along with the times its leader changed that
+// are still within the leader-churn-window. A change is recorded, and logged, if the leader is different from the
+// leader at the last refresh. A partition that was not seen at the last refresh has no previous leader to compare to.
+func (module *KafkaCluster) trackLeader(leaders map[topicPartition]int32, leaderChanges map[topicPartition][]int64, topic string, partitionID, leader int32, now int64) {
+	key := topicPartition{topic: topic, partition: partitionID}
+	windowStart := now - int64(module.leaderChurnWindow)*1000
+
+	changes := make([]int64, 0)
+	for _, changed := range module.leaderChanges[key] {
+		if changed > windowStart {
+			changes = append(changes, changed)
+		}
+	}
+	if previous, ok := module.leaders[key]; ok && (previous != leader) {
+		module.Log.Info("partition leader changed",
+			zap.String("topic", topic),
+			zap.Int32("partition", partitionID),
+			zap.Int32("previous_leader", previous),
+			zap.Int32("leader", leader),
+		)
+		changes = append(changes, now)
+	}
+
+	leaders[key] = leader
+	if len(changes) > 0 {
+		leaderChanges[key] = changes
+	}
+}
+
+// getLeaderChurn returns the partitions that have changed leader within the leader-churn-window, sorted by the number
+// of changes (most first), then by topic and partition
+func (module *KafkaCluster) getLeaderChurn(now int64) *protocol.ClusterLeaderChurn {
+	churn := &protocol.ClusterLeaderChurn{
+		Partitions: make([]*protocol.PartitionLeaderChurn, 0, len(module.leaderChanges)),
+		Window:     int64(module.leaderChurnWindow),
+		Timestamp:  now,
+	}
+	for key, changes := range module.leaderChanges {
+		churn.Partitions = append(churn.Partitions, &protocol.PartitionLeaderChurn{
+			Topic:         key.topic,
+			Partition:     key.partition,
+			Leader:        module.leaders[key],
+			LeaderChanges: len(changes),
+			LastChange:    changes[len(changes)-1],
+		})
+	}
+	sort.Slice(churn.Partitions, func(i, j int) bool {
+		a, b := churn.Partitions[i], churn.Partitions[j]
+		if a.LeaderChanges != b.LeaderChanges {
+			return a.LeaderChanges > b.LeaderChanges
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+	return churn
+}
+
+// offsetBatch is a single OffsetRequest to a broker, and the topics that are in it
+type offsetBatch struct {
+	request    *sarama.OffsetRequest
+	topics     map[string]bool
+	partitions int
+}
+
+// generateOffsetRequests buckets every topic:partition to its leader broker, in batches of up to offset-batch-size
+// partitions each. If consumed-topics-only is set, topics that are not consumed are skipped
+func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient, offsetTime int64) (map[int32][]*offsetBatch, map[int32]helpers.SaramaBroker) {
+	requests := make(map[int32][]*offsetBatch)
 	brokers := make(map[int32]helpers.SaramaBroker)
 
 	// Generate an OffsetRequest for each topic:partition and bucket it to the leader broker
 	for topic, partitions := range module.topicPartitions {
+		if module.consumedTopicsOnly && !module.consumedTopics[topic] {
+			continue
+		}
 		for _, partitionID := range partitions {
 			broker, err := client.Leader(topic, partitionID)
 			if err != nil {
@@ -200,40 +685,111 @@ func (module *KafkaCluster) generateOffsetRequests(client helpers.SaramaClient)
 					zap.String("topic", topic),
 					zap.Int32("partition", partitionID),
 					zap.String("sarama_error", err.Error()))
-				module.fetchMetadata = true
+				module.refreshTopicSoon(topic)
 				continue
 			}
-			if _, ok := requests[broker.ID()]; !ok {
-				requests[broker.ID()] = &sarama.OffsetRequest{}
+			brokerID := broker.ID()
+			batches := requests[brokerID]
+			if (len(batches) == 0) || (batches[len(batches)-1].partitions >= module.offsetBatchSize) {
+				batches = append(batches, &offsetBatch{
+					request: &sarama.OffsetRequest{},
+					topics:  make(map[string]bool),
+				})
+				requests[brokerID] = batches
 			}
-			brokers[broker.ID()] = broker
-			requests[broker.ID()].AddBlock(topic, partitionID, sarama.OffsetNewest, 1)
+			brokers[brokerID] = broker
+
+			batch := batches[len(batches)-1]
+			batch.request.AddBlock(topic, partitionID, offsetTime, 1)
+			batch.topics[topic] = true
+			batch.partitions++
 		}
 	}
 
 	return requests, brokers
 }
 
-// This function performs massively parallel OffsetRequests, which is better than Sarama's internal implementation,
-// which does one at a time. Several orders of magnitude faster.
+// getOffsets fetches the end offset of every partition. The log start offsets are fetched as well on the first run,
+// and then each time the topic list is refreshed, as they only change when retention removes log segments.
 func (module *KafkaCluster) getOffsets(client helpers.SaramaClient) {
+	defer helpers.UpdateMetricTime(module.offsetFetchTime, time.Now())
+
 	module.maybeUpdateMetadataAndDeleteTopics(client)
-	requests, brokers := module.generateOffsetRequests(client)
+	if module.consumedTopicsOnly {
+		module.updateConsumedTopics()
+	}
+	module.fetchOffsets(client, sarama.OffsetNewest, protocol.StorageSetBrokerOffset)
+
+	if module.fetchLogStart {
+		module.fetchLogStart = false
+		module.fetchOffsets(client, sarama.OffsetOldest, protocol.StorageSetBrokerLogStartOffset)
+	}
+}
 
-	// Send out the OffsetRequest to each broker for all the partitions it is leader for
+// updateConsumedTopics fetches the list of topics that consumer groups are committing offsets for from storage. If
+// storage does not respond, the previous list is kept.
+func (module *KafkaCluster) updateConsumedTopics() {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumedTopics,
+		Cluster:     module.name,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+	}
+	if !helpers.TimeoutSendStorageRequest(module.App.StorageChannel, request, 1) {
+		module.Log.Warn("timed out fetching consumed topics")
+		return
+	}
+
+	response, ok := <-request.Reply
+	if !ok {
+		// Storage does not know about the cluster yet, so no topics are consumed
+		module.consumedTopics = make(map[string]bool)
+		return
+	}
+	consumedTopics := make(map[string]bool)
+	for _, topic := range response.([]string) {
+		consumedTopics[topic] = true
+	}
+	module.consumedTopics = consumedTopics
+}
+
+// This function performs massively parallel OffsetRequests, which is better than Sarama's internal implementation,
+// which does one at a time. Several orders of magnitude faster. At most offset-fetch-concurrency requests are sent at
+// once, and the requests to a single broker are pipelined on its connection. Offsets are sent to storage as each
+// response arrives, and this func only waits offset-fetch-timeout for the responses, so a slow broker does not hold up
+// the next refresh. A broker that still has requests outstanding is skipped until they complete.
+func (module *KafkaCluster) fetchOffsets(client helpers.SaramaClient, offsetTime int64, requestType protocol.StorageRequestConstant) {
+	requests, brokers := module.generateOffsetRequests(client, offsetTime)
+
+	// The requests may complete after this func returns, so they must not use the module's topicPartitions, which is
+	// replaced at each metadata refresh
+	topicPartitions := module.topicPartitions
+
+	// Send out the OffsetRequests to each broker for all the partitions it is leader for
 	// The results go to the offset storage module
 	var wg = sync.WaitGroup{}
 	var errorTopics = sync.Map{}
+	var activeTopics = sync.Map{}
+	semaphore := make(chan struct{}, module.offsetFetchConcurrency)
 
-	getBrokerOffsets := func(brokerID int32, request *sarama.OffsetRequest) {
-		defer wg.Done()
-		response, err := brokers[brokerID].GetAvailableOffsets(request)
+	getBrokerOffsets := func(brokerID int32, batch *offsetBatch) {
+		semaphore <- struct{}{}
+		defer func() { <-semaphore }()
+
+		response, err := brokers[brokerID].GetAvailableOffsets(batch.request)
 		if err != nil {
 			module.Log.Error("failed to fetch offsets from broker",
 				zap.String("sarama_error", err.Error()),
 				zap.Int32("broker", brokerID),
 			)
+			module.errorCount.Inc(1)
+			helpers.PublishModuleError("cluster."+module.name, module.name, "failed to fetch offsets from broker: "+err.Error())
 			brokers[brokerID].Close()
+
+			// The leaders for these partitions may have moved
+			for topic := range batch.topics {
+				errorTopics.Store(topic, true)
+			}
 			return
 		}
 		ts := time.Now().Unix() * 1000
@@ -248,33 +804,106 @@ func (module *KafkaCluster) getOffsets(client helpers.SaramaClient) {
 					)
 
 					// Gather a list of topics that had errors
+					module.errorCount.Inc(1)
+					helpers.PublishModuleError("cluster."+module.name, module.name, "error in OffsetResponse for "+topic+": "+offsetResponse.Err.Error())
 					errorTopics.Store(topic, true)
 					continue
 				}
+				if requestType == protocol.StorageSetBrokerOffset {
+					key := topicPartition{topic: topic, partition: partition}
+					if previous, ok := module.endOffsets.Load(key); ok && (previous.(int64) != offsetResponse.Offsets[0]) {
+						activeTopics.Store(topic, true)
+					}
+					module.endOffsets.Store(key, offsetResponse.Offsets[0])
+				}
+
 				offset := &protocol.StorageRequest{
-					RequestType:         protocol.StorageSetBrokerOffset,
+					RequestType:         requestType,
 					Cluster:             module.name,
 					Topic:               topic,
 					Partition:           partition,
 					Offset:              offsetResponse.Offsets[0],
 					Timestamp:           ts,
-					TopicPartitionCount: int32(cap(module.topicPartitions[topic])),
+					TopicPartitionCount: int32(cap(topicPartitions[topic])),
 				}
 				helpers.TimeoutSendStorageRequest(module.App.StorageChannel, offset, 1)
+				module.offsetCount.Inc(1)
 			}
 		}
 	}
 
-	for brokerID, request := range requests {
-		wg.Add(1)
-		go getBrokerOffsets(brokerID, request)
+	for brokerID, batches := range requests {
+		if _, busy := module.busyBrokers.LoadOrStore(brokerID, true); busy {
+			module.Log.Warn("skipping broker with outstanding offset requests", zap.Int32("broker", brokerID))
+			continue
+		}
+
+		// The broker is no longer busy when the last of its requests completes
+		remaining := int32(len(batches))
+		for _, batch := range batches {
+			wg.Add(1)
+			go func(brokerID int32, batch *offsetBatch) {
+				defer wg.Done()
+				getBrokerOffsets(brokerID, batch)
+				if atomic.AddInt32(&remaining, -1) == 0 {
+					module.busyBrokers.Delete(brokerID)
+				}
+			}(brokerID, batch)
+		}
 	}
 
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(module.offsetFetchTimeout):
+		module.Log.Warn("timed out waiting for offsets from brokers")
+	}
 
-	// If there are any topics that had errors, force a metadata refresh on the next run
+	// If there are any topics that had errors, force a metadata refresh for them on the next run
 	errorTopics.Range(func(key, value interface{}) bool {
-		module.fetchMetadata = true
-		return false
+		module.refreshTopicSoon(key.(string))
+		return true
+	})
+	activeTopics.Range(func(key, value interface{}) bool {
+		if schedule, ok := module.topicSchedules[key.(string)]; ok {
+			schedule.active = true
+		}
+		return true
 	})
 }
+
+// getTopicConfigs fetches the configuration of each topic from the brokers and sends it to storage. If the config for a
+// topic cannot be fetched, the last one that was stored is kept until the next refresh.
+func (module *KafkaCluster) getTopicConfigs() {
+	for topic := range module.topicPartitions {
+		entries, err := module.admin.DescribeConfig(sarama.ConfigResource{
+			Type:        sarama.TopicResource,
+			Name:        topic,
+			ConfigNames: module.topicConfigNames,
+		})
+		if err != nil {
+			module.Log.Warn("failed to fetch topic config",
+				zap.String("topic", topic),
+				zap.String("sarama_error", err.Error()),
+			)
+			continue
+		}
+
+		topicConfig := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			if !entry.Sensitive {
+				topicConfig[entry.Name] = entry.Value
+			}
+		}
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetTopicConfig,
+			Cluster:     module.name,
+			Topic:       topic,
+			TopicConfig: topicConfig,
+		}, 1)
+	}
+}
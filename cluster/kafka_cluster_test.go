@@ -44,6 +44,20 @@ func fixtureModule() *KafkaCluster {
 	return &module
 }
 
+// expectNoBrokers sets up the client mock to return no brokers and no controller, for tests that refresh metadata
+func expectNoBrokers(client *helpers.MockSaramaClient) {
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Brokers").Return([]helpers.SaramaBroker{})
+	client.On("Controller").Return(nilBroker, errors.New("no controller"))
+}
+
+// fixtureLeader returns a broker mock with the ID set, for tests that refresh metadata
+func fixtureLeader(id int32) *helpers.MockSaramaBroker {
+	leader := &helpers.MockSaramaBroker{}
+	leader.On("ID").Return(id)
+	return leader
+}
+
 func TestKafkaCluster_ImplementsModule(t *testing.T) {
 	assert.Implements(t, (*protocol.Module)(nil), new(KafkaCluster))
 }
@@ -60,6 +74,52 @@ func TestKafkaCluster_Configure_DefaultIntervals(t *testing.T) {
 
 	assert.Equal(t, int(10), module.offsetRefresh, "Default OffsetRefresh value of 10 did not get set")
 	assert.Equal(t, int(60), module.topicRefresh, "Default TopicRefresh value of 60 did not get set")
+	assert.Equal(t, int(300), module.topicConfigRefresh, "Default TopicConfigRefresh value of 300 did not get set")
+	assert.Contains(t, module.topicConfigNames, "retention.ms", "Default TopicConfigNames did not get set")
+	assert.Equal(t, int(600), module.topicRefreshMax, "Default TopicRefreshMax value of 600 did not get set")
+	assert.Equal(t, 0.1, module.topicRefreshJitter, "Default TopicRefreshJitter value of 0.1 did not get set")
+}
+
+func TestKafkaCluster_Configure_BadTopicRefreshMax(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-max", 30)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadOffsetBatchSize(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-batch-size", 0)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadOffsetFetchConcurrency(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-fetch-concurrency", 0)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadTopicRefreshJitter(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-jitter", 1.5)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadTopicConfigRefresh(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-config-refresh", -1)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadHealthMaxAge(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.health-max-commit-age", 0)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
+func TestKafkaCluster_Configure_BadLeaderChurnWindow(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.leader-churn-window", 0)
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
 }
 
 func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NoUpdate(t *testing.T) {
@@ -78,14 +138,23 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_NoDelete(t *testing.T)
 	// Set up the mock to return a test topic and partition
 	client := &helpers.MockSaramaClient{}
 	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
 	client.On("Topics").Return([]string{"testtopic"}, nil)
 	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
-	client.On("Leader", "testtopic", int32(0)).Return(&helpers.MockSaramaBroker{}, nil)
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Replicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
 
 	module.fetchMetadata = true
-	module.maybeUpdateMetadataAndDeleteTopics(client)
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	request := <-module.App.StorageChannel
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
 
 	client.AssertExpectations(t)
+	assert.Equalf(t, protocol.StorageSetClusterReplication, request.RequestType, "Expected request sent with type StorageSetClusterReplication, not %v", request.RequestType)
+	assert.Empty(t, request.Replication.UnderReplicated, "Expected no under-replicated partitions")
+	assert.Empty(t, request.Replication.Offline, "Expected no offline partitions")
 	assert.False(t, module.fetchMetadata, "Expected fetchMetadata to be reset to false")
 	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
 	topic, ok := module.topicPartitions["testtopic"]
@@ -100,15 +169,21 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_PartialUpdate(t *testin
 	// Set up the mock to return a test topic and partition
 	client := &helpers.MockSaramaClient{}
 	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
 	client.On("Topics").Return([]string{"testtopic"}, nil)
 	client.On("Partitions", "testtopic").Return([]int32{0, 1}, nil)
 
 	var nilBroker *helpers.BurrowSaramaBroker
 	client.On("Leader", "testtopic", int32(0)).Return(nilBroker, errors.New("no leader error"))
-	client.On("Leader", "testtopic", int32(1)).Return(&helpers.MockSaramaBroker{}, nil)
+	client.On("Leader", "testtopic", int32(1)).Return(fixtureLeader(1), nil)
+	client.On("Replicas", "testtopic", int32(1)).Return([]int32{1, 2}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(1)).Return([]int32{1, 2}, nil)
 
 	module.fetchMetadata = true
-	module.maybeUpdateMetadataAndDeleteTopics(client)
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	<-module.App.StorageChannel
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
 
 	client.AssertExpectations(t)
 	assert.False(t, module.fetchMetadata, "Expected fetchMetadata to be reset to false")
@@ -126,9 +201,12 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
 	// Set up the mock to return a test topic and partition
 	client := &helpers.MockSaramaClient{}
 	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
 	client.On("Topics").Return([]string{"testtopic"}, nil)
 	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
-	client.On("Leader", "testtopic", int32(0)).Return(&helpers.MockSaramaBroker{}, nil)
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Replicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
 
 	module.fetchMetadata = true
 	module.topicPartitions = make(map[string][]int32)
@@ -146,6 +224,12 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
 		assert.Equalf(t, protocol.StorageSetDeleteTopic, request.RequestType, "Expected request sent with type StorageSetDeleteTopic, not %v", request.RequestType)
 		assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
 		assert.Equalf(t, "topictodelete", request.Topic, "Expected request sent with topic topictodelete, not %v", request.Topic)
+		assert.Equalf(t, "deleted", request.Reason, "Expected request sent with reason deleted, not %v", request.Reason)
+
+		<-module.App.StorageChannel // StorageSetClusterBrokers
+		request = <-module.App.StorageChannel
+		assert.Equalf(t, protocol.StorageSetClusterReplication, request.RequestType, "Expected request sent with type StorageSetClusterReplication, not %v", request.RequestType)
+		<-module.App.StorageChannel // StorageSetClusterLeaderChurn
 	}()
 	module.maybeUpdateMetadataAndDeleteTopics(client)
 	wg.Wait()
@@ -158,6 +242,276 @@ func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Delete(t *testing.T) {
 	assert.Equalf(t, 1, len(topic), "Expected testtopic to be recorded with 1 partition, not %v", len(topic))
 }
 
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Recreated(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	// The topic is recreated with 1 partition after having 4
+	client := &helpers.MockSaramaClient{}
+	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
+	client.On("Topics").Return([]string{"testtopic"}, nil)
+	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Replicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+
+	module.fetchMetadata = true
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2, 3}}
+
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetDeleteTopic, request.RequestType, "Expected request sent with type StorageSetDeleteTopic, not %v", request.RequestType)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, "partition count decreased", request.Reason, "Expected request sent with reason partition count decreased, not %v", request.Reason)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	<-module.App.StorageChannel // StorageSetClusterReplication
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
+
+	client.AssertExpectations(t)
+	assert.Equalf(t, 1, cap(module.topicPartitions["testtopic"]), "Expected testtopic to be recorded with 1 partition, not %v", cap(module.topicPartitions["testtopic"]))
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_DueTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-jitter", 0)
+	module.Configure("test", "cluster.test")
+
+	// Only the topic that is due is refreshed, as the full refresh is not due
+	client := &helpers.MockSaramaClient{}
+	client.On("RefreshMetadata", []string{"duetopic"}).Return(nil)
+	expectNoBrokers(client)
+	client.On("Topics").Return([]string{"duetopic", "idletopic"}, nil)
+	for _, topic := range []string{"duetopic", "idletopic"} {
+		client.On("Partitions", topic).Return([]int32{0}, nil)
+		client.On("Leader", topic, int32(0)).Return(fixtureLeader(1), nil)
+		client.On("Replicas", topic, int32(0)).Return([]int32{1, 2}, nil)
+		client.On("InSyncReplicas", topic, int32(0)).Return([]int32{1, 2}, nil)
+	}
+
+	now := time.Now()
+	idleSchedule := &topicSchedule{interval: 60 * time.Second, next: now.Add(time.Minute)}
+	module.fetchMetadata = true
+	module.nextFullRefresh = now.Add(time.Hour)
+	module.topicPartitions = map[string][]int32{"duetopic": {0}, "idletopic": {0}}
+	module.topicSchedules = map[string]*topicSchedule{
+		"duetopic":  {interval: 60 * time.Second, next: now.Add(-time.Second)},
+		"idletopic": idleSchedule,
+	}
+
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	<-module.App.StorageChannel // StorageSetClusterReplication
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
+
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "RefreshMetadata")
+	assert.Lenf(t, module.topicSchedules, 2, "Expected 2 topic schedules, not %v", len(module.topicSchedules))
+	assert.True(t, module.topicSchedules["duetopic"].next.After(now), "Expected duetopic to be rescheduled")
+	assert.Equal(t, idleSchedule, module.topicSchedules["idletopic"], "Expected idletopic schedule to be unchanged")
+}
+
+func TestKafkaCluster_scheduleTopic(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-refresh-max", 200)
+	viper.Set("cluster.test.topic-refresh-jitter", 0)
+	module.Configure("test", "cluster.test")
+	now := time.Now()
+
+	// A new topic starts at the shortest interval
+	module.topicSchedules = make(map[string]*topicSchedule)
+	schedule := module.scheduleTopic("testtopic", 1234, false, now)
+	assert.Equal(t, 60*time.Second, schedule.interval, "Expected new topic to have the shortest interval")
+	assert.Equal(t, now.Add(60*time.Second), schedule.next, "Expected new topic to be scheduled after the interval")
+	module.topicSchedules["testtopic"] = schedule
+
+	// Idle topics back off, up to the maximum
+	assert.Equal(t, 120*time.Second, module.scheduleTopic("testtopic", 1234, true, now).interval, "Expected interval to double")
+	assert.Equal(t, 200*time.Second, module.scheduleTopic("testtopic", 1234, true, now).interval, "Expected interval to be capped")
+
+	// A topic that was not refreshed keeps its schedule
+	assert.Equal(t, 200*time.Second, module.scheduleTopic("testtopic", 5678, false, now).interval, "Expected interval to be unchanged")
+
+	// Changed and busy topics go back to the shortest interval
+	assert.Equal(t, 60*time.Second, module.scheduleTopic("testtopic", 5678, true, now).interval, "Expected changed topic to reset")
+	assert.Equal(t, 120*time.Second, module.scheduleTopic("testtopic", 5678, true, now).interval, "Expected interval to double")
+	schedule.active = true
+	assert.Equal(t, 60*time.Second, module.scheduleTopic("testtopic", 5678, true, now).interval, "Expected busy topic to reset")
+	assert.False(t, schedule.active, "Expected active to be reset after refresh")
+}
+
+func TestKafkaCluster_jitter(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	for i := 0; i < 100; i++ {
+		interval := module.jitter(100 * time.Second)
+		assert.True(t, (interval >= 90*time.Second) && (interval <= 110*time.Second), "Expected interval to be within 10%, not %v", interval)
+	}
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_TopicDenylist(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-denylist", "-changelog$")
+	module.Configure("test", "cluster.test")
+
+	// The denied topic must be skipped without fetching its partitions
+	client := &helpers.MockSaramaClient{}
+	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
+	client.On("Topics").Return([]string{"testtopic", "app-store-changelog"}, nil)
+	client.On("Partitions", "testtopic").Return([]int32{0}, nil)
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Replicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(0)).Return([]int32{1, 2}, nil)
+
+	module.fetchMetadata = true
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	<-module.App.StorageChannel
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
+
+	client.AssertExpectations(t)
+	assert.Lenf(t, module.topicPartitions, 1, "Expected 1 topic entry, not %v", len(module.topicPartitions))
+	_, ok := module.topicPartitions["app-store-changelog"]
+	assert.False(t, ok, "Expected app-store-changelog to be skipped")
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_Replication(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	client := &helpers.MockSaramaClient{}
+	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
+	client.On("Topics").Return([]string{"testtopic"}, nil)
+	client.On("Partitions", "testtopic").Return([]int32{0, 1, 2}, nil)
+
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Leader", "testtopic", int32(1)).Return(fixtureLeader(1), nil)
+	client.On("Leader", "testtopic", int32(2)).Return(nilBroker, sarama.ErrLeaderNotAvailable)
+	client.On("Replicas", "testtopic", int32(0)).Return([]int32{1, 2, 3}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(0)).Return([]int32{1, 2, 3}, nil)
+	client.On("Replicas", "testtopic", int32(1)).Return([]int32{1, 2, 3}, nil)
+	client.On("InSyncReplicas", "testtopic", int32(1)).Return([]int32{1}, nil)
+	client.On("Replicas", "testtopic", int32(2)).Return([]int32{2, 3}, sarama.ErrReplicaNotAvailable)
+	client.On("InSyncReplicas", "testtopic", int32(2)).Return([]int32{}, sarama.ErrReplicaNotAvailable)
+
+	// The time a partition was first seen under-replicated is kept across refreshes
+	module.underReplicatedSince = map[topicPartition]int64{{topic: "testtopic", partition: 1}: 1234}
+
+	module.fetchMetadata = true
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	request := <-module.App.StorageChannel
+	<-module.App.StorageChannel // StorageSetClusterLeaderChurn
+
+	assert.Equalf(t, protocol.StorageSetClusterReplication, request.RequestType, "Expected request sent with type StorageSetClusterReplication, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Lenf(t, request.Replication.UnderReplicated, 1, "Expected 1 under-replicated partition, not %v", len(request.Replication.UnderReplicated))
+	assert.Equal(t, &protocol.PartitionReplicas{Topic: "testtopic", Partition: 1, Replicas: []int32{1, 2, 3}, InSyncReplicas: []int32{1}, Since: 1234}, request.Replication.UnderReplicated[0])
+	assert.Lenf(t, request.Replication.Offline, 1, "Expected 1 offline partition, not %v", len(request.Replication.Offline))
+	assert.Equalf(t, int32(2), request.Replication.Offline[0].Partition, "Expected partition 2 to be offline, not %v", request.Replication.Offline[0].Partition)
+	assert.Equalf(t, request.Replication.Timestamp, request.Replication.Offline[0].Since, "Expected offline partition to be first seen now")
+
+	_, ok := module.offlineSince[topicPartition{topic: "testtopic", partition: 2}]
+	assert.True(t, ok, "Expected offline partition to be tracked")
+	assert.Lenf(t, module.topicPartitions["testtopic"], 2, "Expected 2 partitions with leaders, not %v", len(module.topicPartitions["testtopic"]))
+}
+
+func TestKafkaCluster_maybeUpdateMetadataAndDeleteTopics_LeaderChurn(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	client := &helpers.MockSaramaClient{}
+	client.On("RefreshMetadata").Return(nil)
+	expectNoBrokers(client)
+	client.On("Topics").Return([]string{"testtopic"}, nil)
+	client.On("Partitions", "testtopic").Return([]int32{0, 1, 2}, nil)
+
+	var nilBroker *helpers.BurrowSaramaBroker
+	client.On("Leader", "testtopic", int32(0)).Return(fixtureLeader(1), nil)
+	client.On("Leader", "testtopic", int32(1)).Return(fixtureLeader(2), nil)
+	client.On("Leader", "testtopic", int32(2)).Return(nilBroker, sarama.ErrLeaderNotAvailable)
+	for _, partitionID := range []int32{0, 1, 2} {
+		client.On("Replicas", "testtopic", partitionID).Return([]int32{1, 2}, nil)
+		client.On("InSyncReplicas", "testtopic", partitionID).Return([]int32{1, 2}, nil)
+	}
+
+	// Partition 0 keeps its leader, partition 1 changes leader again, and partition 2 goes offline. The change to
+	// partition 1 from before the window is dropped
+	now := time.Now().Unix() * 1000
+	module.leaders = map[topicPartition]int32{
+		{topic: "testtopic", partition: 0}: 1,
+		{topic: "testtopic", partition: 1}: 1,
+		{topic: "testtopic", partition: 2}: 3,
+	}
+	module.leaderChanges = map[topicPartition][]int64{
+		{topic: "testtopic", partition: 1}: {now - 7200000, now - 60000},
+	}
+
+	module.fetchMetadata = true
+	go module.maybeUpdateMetadataAndDeleteTopics(client)
+	<-module.App.StorageChannel // StorageSetClusterBrokers
+	<-module.App.StorageChannel // StorageSetClusterReplication
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, protocol.StorageSetClusterLeaderChurn, request.RequestType, "Expected request sent with type StorageSetClusterLeaderChurn, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Equalf(t, int64(3600), request.LeaderChurn.Window, "Expected window of 3600, not %v", request.LeaderChurn.Window)
+	assert.Lenf(t, request.LeaderChurn.Partitions, 2, "Expected 2 partitions with leader changes, not %v", len(request.LeaderChurn.Partitions))
+	assert.Equal(t, &protocol.PartitionLeaderChurn{Topic: "testtopic", Partition: 1, Leader: 2, LeaderChanges: 2, LastChange: request.LeaderChurn.Timestamp}, request.LeaderChurn.Partitions[0])
+	assert.Equal(t, &protocol.PartitionLeaderChurn{Topic: "testtopic", Partition: 2, Leader: -1, LeaderChanges: 1, LastChange: request.LeaderChurn.Timestamp}, request.LeaderChurn.Partitions[1])
+	assert.Equalf(t, int32(2), module.leaders[topicPartition{topic: "testtopic", partition: 1}], "Expected leader of partition 1 to be recorded as 2")
+}
+
+func TestKafkaCluster_getClusterBrokers(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	broker1 := &helpers.MockSaramaBroker{}
+	broker1.On("ID").Return(int32(1))
+	broker1.On("Addr").Return("broker1.example.com:9092")
+	broker1.On("Rack").Return("rack-a")
+	broker2 := &helpers.MockSaramaBroker{}
+	broker2.On("ID").Return(int32(2))
+	broker2.On("Addr").Return("broker2.example.com:9093")
+	broker2.On("Rack").Return("")
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Brokers").Return([]helpers.SaramaBroker{broker2, broker1})
+	client.On("Controller").Return(broker2, nil)
+
+	brokers := module.getClusterBrokers(client, 1234)
+	assert.Equal(t, &protocol.ClusterBrokers{
+		Brokers: []*protocol.Broker{
+			{ID: 1, Host: "broker1.example.com", Port: 9092, Rack: "rack-a"},
+			{ID: 2, Host: "broker2.example.com", Port: 9093},
+		},
+		ControllerID: 2,
+		Timestamp:    1234,
+	}, brokers, "Expected brokers sorted by ID, with the controller")
+}
+
+func TestKafkaCluster_getClusterBrokers_NoController(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+
+	client := &helpers.MockSaramaClient{}
+	expectNoBrokers(client)
+
+	brokers := module.getClusterBrokers(client, 1234)
+	assert.Empty(t, brokers.Brokers, "Expected no brokers")
+	assert.Equalf(t, int32(-1), brokers.ControllerID, "Expected ControllerID to be -1, not %v", brokers.ControllerID)
+}
+
+func TestKafkaCluster_Configure_BadTopicRegexp(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.topic-allowlist", "[")
+	assert.Panics(t, func() { module.Configure("test", "cluster.test") }, "The code did not panic")
+}
+
 func TestKafkaCluster_generateOffsetRequests(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
@@ -172,7 +526,7 @@ func TestKafkaCluster_generateOffsetRequests(t *testing.T) {
 	client := &helpers.MockSaramaClient{}
 	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
 
-	requests, brokers := module.generateOffsetRequests(client)
+	requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
 
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
@@ -183,11 +537,31 @@ func TestKafkaCluster_generateOffsetRequests(t *testing.T) {
 	assert.Lenf(t, requests, 1, "Expected 1 request, not %v", len(requests))
 }
 
+func TestKafkaCluster_generateOffsetRequests_Batches(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.offset-batch-size", 2)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0, 1, 2}}
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("ID").Return(int32(13))
+	client := &helpers.MockSaramaClient{}
+	client.On("Leader", "testtopic", mock.AnythingOfType("int32")).Return(broker, nil)
+
+	requests, _ := module.generateOffsetRequests(client, sarama.OffsetNewest)
+
+	assert.Lenf(t, requests[13], 2, "Expected 2 batches, not %v", len(requests[13]))
+	assert.Equalf(t, 2, requests[13][0].partitions, "Expected 2 partitions in the first batch, not %v", requests[13][0].partitions)
+	assert.Equalf(t, 1, requests[13][1].partitions, "Expected 1 partition in the second batch, not %v", requests[13][1].partitions)
+	assert.True(t, requests[13][1].topics["testtopic"], "Expected testtopic in the second batch")
+}
+
 func TestKafkaCluster_generateOffsetRequests_NoLeader(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "cluster.test")
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["testtopic"] = []int32{0, 1}
+	module.topicSchedules = map[string]*topicSchedule{"testtopic": {interval: time.Hour, next: time.Now().Add(time.Hour)}}
 
 	// Set up a broker mock
 	broker := &helpers.MockSaramaBroker{}
@@ -199,7 +573,7 @@ func TestKafkaCluster_generateOffsetRequests_NoLeader(t *testing.T) {
 	client.On("Leader", "testtopic", int32(0)).Return(nilBroker, errors.New("no leader error"))
 	client.On("Leader", "testtopic", int32(1)).Return(broker, nil)
 
-	requests, brokers := module.generateOffsetRequests(client)
+	requests, brokers := module.generateOffsetRequests(client, sarama.OffsetNewest)
 
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
@@ -209,6 +583,52 @@ func TestKafkaCluster_generateOffsetRequests_NoLeader(t *testing.T) {
 	assert.Equal(t, broker, brokers[13], "Expected broker returned to be the mock")
 	assert.Lenf(t, requests, 1, "Expected 1 request, not %v", len(requests))
 	assert.True(t, module.fetchMetadata, "Expected fetchMetadata to be true")
+	assert.True(t, module.topicSchedules["testtopic"].next.IsZero(), "Expected testtopic to be due for refresh")
+}
+
+func TestKafkaCluster_generateOffsetRequests_ConsumedTopicsOnly(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.consumed-topics-only", true)
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}, "idletopic": {0}}
+	module.consumedTopics = map[string]bool{"testtopic": true}
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("ID").Return(int32(13))
+	client := &helpers.MockSaramaClient{}
+	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+
+	requests, _ := module.generateOffsetRequests(client, sarama.OffsetNewest)
+
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "Leader", "idletopic", int32(0))
+	assert.Lenf(t, requests[13], 1, "Expected 1 batch, not %v", len(requests[13]))
+	assert.Equalf(t, 1, requests[13][0].partitions, "Expected 1 partition in the batch, not %v", requests[13][0].partitions)
+	assert.False(t, requests[13][0].topics["idletopic"], "Expected idletopic to be skipped")
+}
+
+func TestKafkaCluster_updateConsumedTopics(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("cluster.test.consumed-topics-only", true)
+	module.Configure("test", "cluster.test")
+
+	go func() {
+		request := <-module.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConsumedTopics, request.RequestType, "Expected request sent with type StorageFetchConsumedTopics, not %v", request.RequestType)
+		assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+		request.Reply <- []string{"testtopic"}
+		close(request.Reply)
+	}()
+	module.updateConsumedTopics()
+	assert.Equal(t, map[string]bool{"testtopic": true}, module.consumedTopics, "Expected consumedTopics to be set")
+
+	// The cluster is not known to storage, so nothing is consumed
+	go func() {
+		request := <-module.App.StorageChannel
+		close(request.Reply)
+	}()
+	module.updateConsumedTopics()
+	assert.Empty(t, module.consumedTopics, "Expected consumedTopics to be empty")
 }
 
 func TestKafkaCluster_getOffsets(t *testing.T) {
@@ -216,6 +636,8 @@ func TestKafkaCluster_getOffsets(t *testing.T) {
 	module.Configure("test", "cluster.test")
 	module.topicPartitions = make(map[string][]int32)
 	module.topicPartitions["testtopic"] = []int32{0, 1}
+	module.topicSchedules = map[string]*topicSchedule{"testtopic": {interval: time.Hour, next: time.Now().Add(time.Hour)}}
+	module.endOffsets.Store(topicPartition{topic: "testtopic", partition: 0}, int64(8000))
 	module.fetchMetadata = false
 
 	// Set up an OffsetResponse
@@ -233,7 +655,11 @@ func TestKafkaCluster_getOffsets(t *testing.T) {
 	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
 	client.On("Leader", "testtopic", int32(1)).Return(nilBroker, errors.New("no leader error"))
 
-	go module.getOffsets(client)
+	done := make(chan struct{})
+	go func() {
+		module.getOffsets(client)
+		close(done)
+	}()
 	request := <-module.App.StorageChannel
 
 	broker.AssertExpectations(t)
@@ -254,6 +680,51 @@ func TestKafkaCluster_getOffsets(t *testing.T) {
 	default:
 		break
 	}
+
+	// The end offset moved, so the topic is busy
+	<-done
+	assert.True(t, module.topicSchedules["testtopic"].active, "Expected testtopic to be marked active")
+}
+
+func TestKafkaCluster_getOffsets_LogStart(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = make(map[string][]int32)
+	module.topicPartitions["testtopic"] = []int32{0}
+	module.fetchMetadata = false
+	module.fetchLogStart = true
+
+	// Set up OffsetResponses for the end offset and the log start offset
+	endResponse := &sarama.OffsetResponse{Version: 1}
+	endResponse.AddTopicPartition("testtopic", 0, 8374)
+	startResponse := &sarama.OffsetResponse{Version: 1}
+	startResponse.AddTopicPartition("testtopic", 0, 1234)
+
+	// Set up a broker mock
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("ID").Return(int32(13))
+	// The end offsets are always fetched before the log start offsets
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(endResponse, nil).Once()
+	broker.On("GetAvailableOffsets", mock.MatchedBy(func(request *sarama.OffsetRequest) bool { return request != nil })).Return(startResponse, nil).Once()
+
+	client := &helpers.MockSaramaClient{}
+	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+
+	go module.getOffsets(client)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerOffset, request.RequestType, "Expected request sent with type StorageSetBrokerOffset, not %v", request.RequestType)
+	assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetBrokerLogStartOffset, request.RequestType, "Expected request sent with type StorageSetBrokerLogStartOffset, not %v", request.RequestType)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(0), request.Partition, "Expected request sent with partition 0, not %v", request.Partition)
+	assert.Equalf(t, int64(1234), request.Offset, "Expected request sent with offset 1234, not %v", request.Offset)
+
+	time.Sleep(100 * time.Millisecond)
+	broker.AssertExpectations(t)
+	client.AssertExpectations(t)
+	assert.False(t, module.fetchLogStart, "Expected fetchLogStart to be reset to false")
 }
 
 func TestKafkaCluster_getOffsets_BrokerFailed(t *testing.T) {
@@ -279,3 +750,82 @@ func TestKafkaCluster_getOffsets_BrokerFailed(t *testing.T) {
 	broker.AssertExpectations(t)
 	client.AssertExpectations(t)
 }
+
+func TestKafkaCluster_getTopicConfigs(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}, "failtopic": {0}}
+
+	admin := &helpers.MockSaramaClusterAdmin{}
+	admin.On("DescribeConfig", sarama.ConfigResource{Type: sarama.TopicResource, Name: "testtopic", ConfigNames: module.topicConfigNames}).Return([]sarama.ConfigEntry{
+		{Name: "retention.ms", Value: "86400000"},
+		{Name: "cleanup.policy", Value: "delete"},
+		{Name: "sasl.secret", Value: "", Sensitive: true},
+	}, nil)
+	admin.On("DescribeConfig", sarama.ConfigResource{Type: sarama.TopicResource, Name: "failtopic", ConfigNames: module.topicConfigNames}).Return([]sarama.ConfigEntry{}, errors.New("bad topic"))
+	module.admin = admin
+
+	go module.getTopicConfigs()
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, protocol.StorageSetTopicConfig, request.RequestType, "Expected request sent with type StorageSetTopicConfig, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equal(t, map[string]string{"retention.ms": "86400000", "cleanup.policy": "delete"}, request.TopicConfig, "Expected sensitive configs to be skipped")
+
+	// The topic that failed should not be sent
+	select {
+	case request := <-module.App.StorageChannel:
+		t.Fatalf("Unexpected request for topic %v", request.Topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+	admin.AssertExpectations(t)
+}
+
+func TestKafkaCluster_getOffsets_BrokerBusy(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.fetchMetadata = false
+	module.busyBrokers.Store(int32(13), true)
+
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("ID").Return(int32(13))
+	client := &helpers.MockSaramaClient{}
+	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+
+	module.getOffsets(client)
+
+	broker.AssertNotCalled(t, "GetAvailableOffsets", mock.Anything)
+	client.AssertExpectations(t)
+}
+
+func TestKafkaCluster_getOffsets_Timeout(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "cluster.test")
+	module.topicPartitions = map[string][]int32{"testtopic": {0}}
+	module.fetchMetadata = false
+	module.offsetFetchTimeout = 50 * time.Millisecond
+
+	// The broker takes longer to respond than the timeout
+	offsetResponse := &sarama.OffsetResponse{Version: 1}
+	offsetResponse.AddTopicPartition("testtopic", 0, 8374)
+	broker := &helpers.MockSaramaBroker{}
+	broker.On("ID").Return(int32(13))
+	broker.On("GetAvailableOffsets", mock.Anything).After(200*time.Millisecond).Return(offsetResponse, nil)
+	client := &helpers.MockSaramaClient{}
+	client.On("Leader", "testtopic", int32(0)).Return(broker, nil)
+
+	start := time.Now()
+	module.getOffsets(client)
+	assert.True(t, time.Since(start) < 200*time.Millisecond, "Expected getOffsets to return before the broker responded")
+	_, busy := module.busyBrokers.Load(int32(13))
+	assert.True(t, busy, "Expected broker to be busy")
+
+	// The offset is still sent to storage when the response arrives, and the broker is no longer busy after
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, int64(8374), request.Offset, "Expected request sent with offset 8374, not %v", request.Offset)
+	time.Sleep(50 * time.Millisecond)
+	_, busy = module.busyBrokers.Load(int32(13))
+	assert.False(t, busy, "Expected broker to not be busy")
+}
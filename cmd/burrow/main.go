@@ -22,9 +22,10 @@
 // have your own application structure to provide configuration and logging. To this end, Burrow can also be used as a
 // library within another app.
 //
-// When embedding Burrow, please refer to https://github.com/linkedin/Burrow/blob/master/main.go for details on what
-// preparation should happen before starting it. This is the wrapper that provides the CLI interface. The main logic
-// for Burrow is in the core package, while the protocol package provides some of the common interfaces that are used.
+// When embedding Burrow, use the burrow package (github.com/linkedin/Burrow), which configures Burrow with functional
+// options, runs it until a context is canceled, and allows modules of your own classes to be added. This package is
+// the wrapper that provides the CLI interface. The main logic for Burrow is in the core package, while the protocol
+// package provides some of the common interfaces that are used.
 //
 // Additional Documentation
 //
@@ -33,6 +34,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -45,6 +47,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/linkedin/Burrow/core"
+	"github.com/linkedin/Burrow/helpers"
 )
 
 // exitCode wraps a return value for the application
@@ -71,26 +74,55 @@ func main() {
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
-	// The only command line arg is the config file
+	// The command line args are the config file, and any number of --set key=value settings that override it. If the
+	// check-config command is given, the configuration is only validated, and Burrow is not started. The config-schema
+	// command prints the keys that can be configured, and does not read the configuration at all
 	configPath := flag.String("config-dir", ".", "Directory that contains the configuration file")
+	overrides := &helpers.ConfigOverrides{}
+	flag.Var(overrides, "set", "Set a configuration `key=value`, overriding the configuration file (may be repeated)")
 	flag.Parse()
+	checkConfig := flag.Arg(0) == "check-config"
+	configSchema := flag.Arg(0) == "config-schema"
+	if (flag.NArg() > 1) || ((flag.NArg() == 1) && !checkConfig && !configSchema) {
+		fmt.Fprintln(os.Stderr, "Unknown command:", strings.Join(flag.Args(), " "))
+		panic(exitCode{1})
+	}
+	if configSchema {
+		output, _ := json.MarshalIndent(helpers.GetConfigSchema(), "", "  ")
+		fmt.Println(string(output))
+		os.Exit(0)
+	}
 
 	// Load the configuration from the file
 	viper.SetConfigName("burrow")
 	viper.AddConfigPath(*configPath)
 	fmt.Fprintln(os.Stderr, "Reading configuration from", *configPath)
 	err := viper.ReadInConfig()
+	if err == nil {
+		// Merge any included files, and replace references to environment variables and files, such as for passwords
+		err = helpers.MergeConfigIncludes(viper.GetViper())
+	}
+	if err == nil {
+		err = helpers.InterpolateConfig(viper.GetViper())
+	}
 	if err != nil {
+		if checkConfig {
+			panic(exitCode{printConfigErrors([]core.ConfigError{{Subsystem: "config", Error: err.Error()}}, nil)})
+		}
 		fmt.Fprintln(os.Stderr, "Failed reading configuration:", err.Error())
 		panic(exitCode{1})
 	}
+	overrides.Apply()
+	helpers.ApplyConfigDeprecations(viper.GetViper())
 
-	// setup viper to be able to read env variables with a configured prefix
+	// setup viper to be able to read env variables with a configured prefix. Modules are found from the configuration,
+	// so a module cannot be added this way
 	viper.SetDefault("general.env-var-prefix", "burrow")
-	envPrefix := viper.GetString("general.env-var-prefix")
-	viper.SetEnvPrefix(envPrefix)
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
-	viper.AutomaticEnv()
+	helpers.SetConfigEnv(viper.GetViper(), viper.GetString("general.env-var-prefix"))
+
+	if checkConfig {
+		panic(exitCode{printConfigErrors(core.CheckConfig(), helpers.CheckConfigKeys(viper.GetViper()))})
+	}
 
 	// Create the PID file to lock out other processes
 	viper.SetDefault("general.pidfile", "burrow.pid")
@@ -107,10 +139,37 @@ func main() {
 		core.OpenOutLog(stdoutLogfile)
 	}
 
-	// Register signal handlers for exiting
+	// Register signal handlers for exiting, for reloading the configuration, and for graceful restarts
 	exitChannel := make(chan os.Signal, 1)
-	signal.Notify(exitChannel, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	signal.Notify(exitChannel, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	if core.RestartSignal != nil {
+		signal.Notify(exitChannel, core.RestartSignal)
+	}
 
 	// This triggers handleExit (after other defers), which will then call os.Exit properly
 	panic(exitCode{core.Start(nil, exitChannel)})
 }
+
+// printConfigErrors writes the result of checking the configuration to stdout as JSON, and returns the exit code to
+// use, which is 1 if there are any errors. Warnings (such as for unknown or deprecated keys) do not make the
+// configuration invalid.
+func printConfigErrors(configErrors []core.ConfigError, warnings []helpers.ConfigKeyWarning) int {
+	if warnings == nil {
+		warnings = []helpers.ConfigKeyWarning{}
+	}
+	output, _ := json.MarshalIndent(struct {
+		Valid    bool                       `json:"valid"`
+		Errors   []core.ConfigError         `json:"errors"`
+		Warnings []helpers.ConfigKeyWarning `json:"warnings"`
+	}{
+		Valid:    len(configErrors) == 0,
+		Errors:   configErrors,
+		Warnings: warnings,
+	}, "", "  ")
+	fmt.Println(string(output))
+
+	if len(configErrors) > 0 {
+		return 1
+	}
+	return 0
+}
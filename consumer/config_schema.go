@@ -0,0 +1,56 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("consumer.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "cluster", Type: helpers.ConfigTypeString},
+	)
+
+	kafkaKeys := []helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "client-pool", Type: helpers.ConfigTypeString},
+		{Name: "offsets-topic", Type: helpers.ConfigTypeString, Default: "__consumer_offsets"},
+		{Name: "start-latest", Type: helpers.ConfigTypeBoolean},
+		{Name: "backfill-earliest", Type: helpers.ConfigTypeBoolean},
+		{Name: "start-from-minutes", Type: helpers.ConfigTypeInteger},
+		{Name: "catch-up-progress-interval", Type: helpers.ConfigTypeInteger},
+		{Name: "channel-buffer-size", Type: helpers.ConfigTypeInteger},
+		{Name: "fetch-default-bytes", Type: helpers.ConfigTypeInteger},
+		{Name: "fetch-max-bytes", Type: helpers.ConfigTypeInteger},
+		{Name: "fetch-min-bytes", Type: helpers.ConfigTypeInteger},
+		{Name: "fetch-max-wait-ms", Type: helpers.ConfigTypeInteger},
+		{Name: "ingest-queue-depth", Type: helpers.ConfigTypeInteger, Default: 100},
+		{Name: "ingest-workers", Type: helpers.ConfigTypeInteger},
+		{Name: "max-bytes-per-second", Type: helpers.ConfigTypeInteger},
+		{Name: "self-lag-threshold", Type: helpers.ConfigTypeInteger},
+		{Name: "shard-count", Type: helpers.ConfigTypeInteger, Default: 1},
+		{Name: "shard-index", Type: helpers.ConfigTypeInteger},
+	}
+	kafkaKeys = append(kafkaKeys, helpers.ConsumerFilterConfigKeys...)
+	kafkaKeys = append(kafkaKeys, helpers.RemovedGroupFilterConfigKeys...)
+	kafkaKeys = append(kafkaKeys, helpers.DeadLetterConfigKeys...)
+	helpers.RegisterConfigKeys("consumer.*", "kafka", kafkaKeys...)
+
+	for _, className := range []string{"kafka_admin", "event_hubs"} {
+		helpers.RegisterConfigKeys("consumer.*", className, append([]helpers.ConfigKey{
+			{Name: "servers", Type: helpers.ConfigTypeStringList},
+			{Name: "client-profile", Type: helpers.ConfigTypeString},
+			{Name: "offset-refresh", Type: helpers.ConfigTypeInteger, Default: 10},
+		}, helpers.ConsumerFilterConfigKeys...)...)
+	}
+}
@@ -18,6 +18,25 @@
 // Currently, the following modules are provided:
 //
 // * kafka - Consume a Kafka cluster's __consumer_offsets topic to get consumer information (new consumer)
+//
+// * kafka_admin - Poll a Kafka cluster's admin APIs to get consumer information, without reading __consumer_offsets
+//
+// * event_hubs - Poll the Kafka endpoint of an Azure Event Hubs namespace, as kafka_admin does (requires a client
+// profile with the azure-event-hubs preset)
+//
+// * kafka_connect - Poll a Kafka Connect cluster's REST API to map connectors to the consumer groups they use
+//
+// * kafka_json - Consume a user topic containing JSON offset checkpoints, using configured field paths
+//
+// * mm2_checkpoint - Consume a MirrorMaker 2 checkpoints topic to get the translated offsets of replicated groups
+//
+// * samza_checkpoint - Consume an Apache Samza job's checkpoint topic to get the input offsets of the job as a group
+//
+// * plugin - Run a consumer module that is built as a separate executable (see the plugin package)
+//
+// The kafka_connect, kafka_json, mm2_checkpoint, samza_checkpoint, and plugin modules are optional, and can be left out
+// of a slim build of Burrow with the no_kafka_connect, no_kafka_json, no_mm2_checkpoint, no_samza_checkpoint, and
+// no_plugin build tags. These modules register their classes when they are built in.
 package consumer
 
 import (
@@ -62,7 +81,21 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 			App: app,
 			Log: logger,
 		}
+	case "kafka_admin":
+		return &KafkaAdminClient{
+			App: app,
+			Log: logger,
+		}
+	case "event_hubs":
+		return &KafkaAdminClient{
+			App:       app,
+			Log:       logger,
+			eventHubs: true,
+		}
 	default:
+		if factory := helpers.GetModuleClass("consumer", className); factory != nil {
+			return factory(app, logger)
+		}
 		panic("Unknown consumer className provided: " + className)
 	}
 }
@@ -112,3 +145,8 @@ func (cc *Coordinator) Stop() error {
 	helpers.StopCoordinatorModules(cc.modules)
 	return nil
 }
+
+// Health returns the health of each consumer module
+func (cc *Coordinator) Health() *protocol.CoordinatorHealth {
+	return helpers.GetCoordinatorHealth("consumer", cc.modules)
+}
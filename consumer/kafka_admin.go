@@ -0,0 +1,321 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// KafkaAdminClient is a consumer module which connects to a single Apache Kafka cluster and periodically fetches
+// consumer group information using the Kafka admin APIs (ListGroups, OffsetFetch, and DescribeGroups), rather than by
+// reading the offsets topic. This is useful for clusters where ACLs do not allow reading __consumer_offsets.
+//
+// The admin APIs do not provide the time that an offset was committed, so an offset is recorded with the time that
+// it was first seen when polling. Offsets that have not changed since the last poll are not sent to storage again.
+//
+// This module is also used for the event_hubs class, for the Kafka endpoint of an Azure Event Hubs namespace. In that
+// case, the client profile must use the azure-event-hubs preset, and the servers must use the Kafka port (9093).
+type KafkaAdminClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name          string
+	cluster       string
+	servers       []string
+	eventHubs     bool
+	offsetRefresh int
+	saramaConfig  *sarama.Config
+	filter        *helpers.ConsumerFilter
+
+	// The last offset sent to storage for each group, topic, and partition. This is only used by the polling goroutine
+	lastOffsets map[string]map[string]map[int32]int64
+
+	admin        helpers.SaramaClusterAdmin
+	offsetTicker *time.Ticker
+	quitChannel  chan struct{}
+	running      sync.WaitGroup
+}
+
+// Configure validates the configuration for the consumer. At minimum, there must be a cluster name to which these
+// consumers belong, as well as a list of servers provided for the Kafka cluster, of the form host:port. The interval
+// for polling consumer groups defaults to 10 seconds. The admin APIs used require a client profile with a Kafka
+// version of at least 0.10.2. If the cluster name is unknown, the Kafka version is too old, or if the server list is
+// missing or invalid, this func will panic.
+func (module *KafkaAdminClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+	module.lastOffsets = make(map[string]map[string]map[int32]int64)
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	profile := viper.GetString(configRoot + ".client-profile")
+	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, module.cluster, module.saramaConfig)
+	if !module.saramaConfig.Version.IsAtLeast(sarama.V0_10_2_0) {
+		panic("Consumer '" + name + "' requires a client profile with a kafka-version of at least 0.10.2")
+	}
+
+	module.servers = viper.GetStringSlice(configRoot + ".servers")
+	if len(module.servers) == 0 {
+		panic("No Kafka brokers specified for consumer " + module.name)
+	} else if !helpers.ValidateHostList(module.servers) {
+		panic("Consumer '" + name + "' has one or more improperly formatted servers (must be host:port)")
+	}
+
+	if module.eventHubs {
+		if viper.GetString("client-profile."+profile+".preset") != "azure-event-hubs" {
+			panic("Consumer '" + name + "' requires a client profile with the azure-event-hubs preset")
+		}
+		for _, server := range module.servers {
+			// Port 443 is the AMQP over websockets endpoint, which is an easy mistake to make
+			if !strings.HasSuffix(server, ":9093") {
+				panic("Consumer '" + name + "' must use the Event Hubs Kafka endpoint on port 9093, not " + server)
+			}
+		}
+	}
+
+	// Set defaults for configs if needed, and get them
+	viper.SetDefault(configRoot+".offset-refresh", 10)
+	module.offsetRefresh = viper.GetInt(configRoot + ".offset-refresh")
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
+	}
+	module.filter = filter
+}
+
+// Start connects to the Kafka cluster using the Shopify/sarama client and creates an admin client from it. Any error
+// connecting to the cluster is returned to the caller. Once the admin client is set up, consumer groups are polled
+// once, and then a ticker is started to poll them periodically.
+func (module *KafkaAdminClient) Start() error {
+	module.Log.Info("starting")
+
+	// Connect Kafka client
+	client, err := helpers.NewSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		module.Log.Error("failed to start client", zap.Error(err))
+		return err
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		module.Log.Error("failed to start admin client", zap.Error(err))
+		client.Close()
+		return err
+	}
+	module.admin = admin
+
+	module.pollConsumerGroups()
+
+	module.offsetTicker = time.NewTicker(time.Duration(module.offsetRefresh) * time.Second)
+	module.running.Add(1)
+	go module.mainLoop()
+
+	return nil
+}
+
+// Stop causes the polling ticker to be stopped, and then it closes the admin client.
+func (module *KafkaAdminClient) Stop() error {
+	module.Log.Info("stopping")
+
+	module.offsetTicker.Stop()
+	close(module.quitChannel)
+	module.running.Wait()
+	module.admin.Close()
+
+	return nil
+}
+
+func (module *KafkaAdminClient) mainLoop() {
+	defer module.running.Done()
+
+	for {
+		select {
+		case <-module.offsetTicker.C:
+			module.pollConsumerGroups()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *KafkaAdminClient) acceptConsumerGroup(group string) bool {
+	return module.filter.AcceptGroup(group)
+}
+
+// pollConsumerGroups fetches the list of consumer groups, and then fetches the offsets and members for each group that
+// is accepted by the allowlist and denylist.
+func (module *KafkaAdminClient) pollConsumerGroups() {
+	groups, err := module.admin.ListConsumerGroups()
+	if err != nil {
+		module.Log.Error("failed to list consumer groups", zap.Error(err))
+		return
+	}
+
+	accepted := make([]string, 0, len(groups))
+	for group := range groups {
+		if module.acceptConsumerGroup(group) {
+			accepted = append(accepted, group)
+		}
+	}
+
+	// Forget about groups that no longer exist, so we will resend their offsets if they come back
+	for group := range module.lastOffsets {
+		if _, ok := groups[group]; !ok {
+			delete(module.lastOffsets, group)
+		}
+	}
+
+	for _, group := range accepted {
+		module.fetchGroupOffsets(group)
+	}
+	if len(accepted) > 0 {
+		module.fetchGroupOwners(accepted)
+	}
+}
+
+func (module *KafkaAdminClient) fetchGroupOffsets(group string) {
+	logger := module.Log.With(zap.String("group", group))
+
+	response, err := module.admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		logger.Warn("failed to fetch offsets", zap.Error(err))
+		return
+	}
+
+	groupOffsets, ok := module.lastOffsets[group]
+	if !ok {
+		groupOffsets = make(map[string]map[int32]int64)
+		module.lastOffsets[group] = groupOffsets
+	}
+
+	// The commit time is not available, so use the time that we saw the offset. This is also used as the order, as
+	// it increases with each poll
+	timestamp := time.Now().Unix() * 1000
+	for topic, partitions := range response.Blocks {
+		if !module.filter.AcceptTopic(topic) {
+			continue
+		}
+		topicOffsets, ok := groupOffsets[topic]
+		if !ok {
+			topicOffsets = make(map[int32]int64)
+			groupOffsets[topic] = topicOffsets
+		}
+
+		for partition, block := range partitions {
+			if (block.Err != sarama.ErrNoError) || (block.Offset < 0) {
+				continue
+			}
+			if lastOffset, ok := topicOffsets[partition]; ok && (lastOffset == block.Offset) {
+				continue
+			}
+			topicOffsets[partition] = block.Offset
+
+			logger.Debug("consumer offset",
+				zap.String("topic", topic),
+				zap.Int32("partition", partition),
+				zap.Int64("offset", block.Offset),
+			)
+			helpers.TimeoutSendStorageRequest(module.App.StorageChannel, protocol.AcquireStorageRequest(protocol.StorageRequest{
+				RequestType: protocol.StorageSetConsumerOffset,
+				Cluster:     module.cluster,
+				Topic:       topic,
+				Partition:   partition,
+				Group:       group,
+				Timestamp:   timestamp,
+				Offset:      block.Offset,
+				Order:       timestamp,
+			}), 1)
+		}
+	}
+}
+
+func (module *KafkaAdminClient) fetchGroupOwners(groups []string) {
+	descriptions, err := module.admin.DescribeConsumerGroups(groups)
+	if err != nil {
+		module.Log.Warn("failed to describe consumer groups", zap.Error(err))
+		return
+	}
+
+	for _, description := range descriptions {
+		if (description.Err != sarama.ErrNoError) || (description.ProtocolType != "consumer") {
+			continue
+		}
+
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageClearConsumerOwners,
+			Cluster:     module.cluster,
+			Group:       description.GroupId,
+		}, 1)
+
+		members := make([]*protocol.ConsumerGroupMember, 0, len(description.Members))
+		for memberID, member := range description.Members {
+			assignment, err := member.GetMemberAssignment()
+			if err != nil {
+				module.Log.Warn("failed to decode member assignment",
+					zap.String("group", description.GroupId),
+					zap.String("client_id", member.ClientId),
+					zap.Error(err),
+				)
+				continue
+			}
+			members = append(members, &protocol.ConsumerGroupMember{
+				MemberID:   memberID,
+				ClientID:   member.ClientId,
+				ClientHost: member.ClientHost,
+				Assignment: assignment.Topics,
+			})
+
+			for topic, partitions := range assignment.Topics {
+				for _, partition := range partitions {
+					helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+						RequestType: protocol.StorageSetConsumerOwner,
+						Cluster:     module.cluster,
+						Topic:       topic,
+						Partition:   partition,
+						Group:       description.GroupId,
+						Owner:       member.ClientHost,
+						ClientID:    member.ClientId,
+					}, 1)
+				}
+			}
+		}
+
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerMembers,
+			Cluster:     module.cluster,
+			Group:       description.GroupId,
+			Members:     members,
+			GroupState:  description.State,
+		}, 1)
+	}
+}
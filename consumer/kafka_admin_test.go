@@ -0,0 +1,229 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureAdminModule() *KafkaAdminClient {
+	module := KafkaAdminClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("client-profile.test.kafka-version", "2.0.0")
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("cluster.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.class-name", "kafka_admin")
+	viper.Set("consumer.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.client-profile", "test")
+
+	return &module
+}
+
+// encodeMemberAssignment builds the wire format of a consumer group member assignment with no user data
+func encodeMemberAssignment(topics map[string][]int32) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, int16(0))
+	binary.Write(buf, binary.BigEndian, int32(len(topics)))
+	for topic, partitions := range topics {
+		binary.Write(buf, binary.BigEndian, int16(len(topic)))
+		buf.WriteString(topic)
+		binary.Write(buf, binary.BigEndian, int32(len(partitions)))
+		for _, partition := range partitions {
+			binary.Write(buf, binary.BigEndian, partition)
+		}
+	}
+	binary.Write(buf, binary.BigEndian, int32(-1))
+	return buf.Bytes()
+}
+
+func fixtureOffsetFetchResponse(offset int64) *sarama.OffsetFetchResponse {
+	response := &sarama.OffsetFetchResponse{}
+	response.AddBlock("testtopic", 0, &sarama.OffsetFetchResponseBlock{Offset: offset, Err: sarama.ErrNoError})
+	response.AddBlock("testtopic", 1, &sarama.OffsetFetchResponseBlock{Offset: -1, Err: sarama.ErrNoError})
+	return response
+}
+
+func TestKafkaAdminClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(KafkaAdminClient))
+}
+
+func TestKafkaAdminClient_Configure(t *testing.T) {
+	module := fixtureAdminModule()
+	module.Configure("test", "consumer.test")
+	assert.NotNil(t, module.saramaConfig, "Expected saramaConfig to be populated")
+	assert.Equalf(t, 10, module.offsetRefresh, "Default offset-refresh value of 10 did not get set, got %v", module.offsetRefresh)
+}
+
+func TestKafkaAdminClient_Configure_OldKafkaVersion(t *testing.T) {
+	module := fixtureAdminModule()
+	viper.Set("client-profile.test.kafka-version", "0.10.1.0")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaAdminClient_Configure_BadCluster(t *testing.T) {
+	module := fixtureAdminModule()
+	viper.Set("consumer.test.cluster", "nocluster")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaAdminClient_Configure_EventHubs(t *testing.T) {
+	module := fixtureAdminModule()
+	module.eventHubs = true
+	viper.Set("client-profile.test.preset", "azure-event-hubs")
+	viper.Set("client-profile.test.connection-string", "Endpoint=sb://test.servicebus.windows.net/;SharedAccessKey=secret")
+	viper.Set("consumer.test.servers", []string{"test.servicebus.windows.net:9093"})
+
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, "$ConnectionString", module.saramaConfig.Net.SASL.User, "Expected SASL user to be $ConnectionString, not %v", module.saramaConfig.Net.SASL.User)
+}
+
+func TestKafkaAdminClient_Configure_EventHubsBadConfig(t *testing.T) {
+	// The AMQP port is not the Kafka endpoint
+	module := fixtureAdminModule()
+	module.eventHubs = true
+	viper.Set("client-profile.test.preset", "azure-event-hubs")
+	viper.Set("client-profile.test.connection-string", "Endpoint=sb://test.servicebus.windows.net/;SharedAccessKey=secret")
+	viper.Set("consumer.test.servers", []string{"test.servicebus.windows.net:443"})
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for port 443")
+
+	// The client profile must use the preset
+	module = fixtureAdminModule()
+	module.eventHubs = true
+	viper.Set("consumer.test.servers", []string{"test.servicebus.windows.net:9093"})
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic without the preset")
+}
+
+func TestKafkaAdminClient_pollConsumerGroups(t *testing.T) {
+	module := fixtureAdminModule()
+	viper.Set("consumer.test.group-denylist", "^denied.*$")
+	module.Configure("test", "consumer.test")
+
+	admin := &helpers.MockSaramaClusterAdmin{}
+	admin.On("ListConsumerGroups").Return(map[string]string{"testgroup": "consumer", "deniedgroup": "consumer"}, nil)
+	admin.On("ListConsumerGroupOffsets", "testgroup", map[string][]int32(nil)).Return(fixtureOffsetFetchResponse(1000), nil)
+	admin.On("DescribeConsumerGroups", []string{"testgroup"}).Return([]*sarama.GroupDescription{
+		{
+			Err:          sarama.ErrNoError,
+			GroupId:      "testgroup",
+			State:        "CompletingRebalance",
+			ProtocolType: "consumer",
+			Members: map[string]*sarama.GroupMemberDescription{
+				"member1": {
+					ClientId:         "testclient",
+					ClientHost:       "/1.2.3.4",
+					MemberAssignment: encodeMemberAssignment(map[string][]int32{"testtopic": {0}}),
+				},
+			},
+		},
+	}, nil)
+	module.admin = admin
+
+	go module.pollConsumerGroups()
+
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerOffset, request.RequestType, "Expected request of type StorageSetConsumerOffset, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request Cluster to be test, not %v", request.Cluster)
+	assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(0), request.Partition, "Expected request Partition to be 0, not %v", request.Partition)
+	assert.Equalf(t, int64(1000), request.Offset, "Expected request Offset to be 1000, not %v", request.Offset)
+	assert.Equalf(t, request.Timestamp, request.Order, "Expected request Order to match Timestamp, not %v", request.Order)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageClearConsumerOwners, request.RequestType, "Expected request of type StorageClearConsumerOwners, not %v", request.RequestType)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerOwner, request.RequestType, "Expected request of type StorageSetConsumerOwner, not %v", request.RequestType)
+	assert.Equalf(t, "/1.2.3.4", request.Owner, "Expected request Owner to be /1.2.3.4, not %v", request.Owner)
+	assert.Equalf(t, "testclient", request.ClientID, "Expected request ClientID to be testclient, not %v", request.ClientID)
+	assert.Equalf(t, int32(0), request.Partition, "Expected request Partition to be 0, not %v", request.Partition)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerMembers, request.RequestType, "Expected request of type StorageSetConsumerMembers, not %v", request.RequestType)
+	assert.Lenf(t, request.Members, 1, "Expected request with exactly one member, not %v", len(request.Members))
+	assert.Equalf(t, "member1", request.Members[0].MemberID, "Expected member MemberID to be member1, not %v", request.Members[0].MemberID)
+	assert.Equalf(t, []int32{0}, request.Members[0].Assignment["testtopic"], "Expected member to be assigned testtopic partition 0, not %v", request.Members[0].Assignment)
+	assert.Equalf(t, "CompletingRebalance", request.GroupState, "Expected request GroupState to be CompletingRebalance, not %v", request.GroupState)
+
+	admin.AssertExpectations(t)
+}
+
+func TestKafkaAdminClient_fetchGroupOffsets_Unchanged(t *testing.T) {
+	module := fixtureAdminModule()
+	module.Configure("test", "consumer.test")
+
+	admin := &helpers.MockSaramaClusterAdmin{}
+	admin.On("ListConsumerGroupOffsets", "testgroup", map[string][]int32(nil)).Return(fixtureOffsetFetchResponse(1000), nil).Once()
+	admin.On("ListConsumerGroupOffsets", "testgroup", map[string][]int32(nil)).Return(fixtureOffsetFetchResponse(1000), nil).Once()
+	admin.On("ListConsumerGroupOffsets", "testgroup", map[string][]int32(nil)).Return(fixtureOffsetFetchResponse(1500), nil).Once()
+	module.admin = admin
+
+	go func() {
+		module.fetchGroupOffsets("testgroup")
+		module.fetchGroupOffsets("testgroup")
+		module.fetchGroupOffsets("testgroup")
+		close(module.App.StorageChannel)
+	}()
+
+	// The second fetch has the same offset, so only two offsets should be sent
+	offsets := make([]int64, 0)
+	for request := range module.App.StorageChannel {
+		offsets = append(offsets, request.Offset)
+	}
+	assert.Equalf(t, []int64{1000, 1500}, offsets, "Expected offsets 1000 and 1500 to be sent, not %v", offsets)
+}
+
+func TestKafkaAdminClient_fetchGroupOffsets_TopicDenylist(t *testing.T) {
+	module := fixtureAdminModule()
+	viper.Set("consumer.test.topic-denylist", ".*")
+	module.Configure("test", "consumer.test")
+
+	admin := &helpers.MockSaramaClusterAdmin{}
+	admin.On("ListConsumerGroupOffsets", "testgroup", map[string][]int32(nil)).Return(fixtureOffsetFetchResponse(1000), nil)
+	module.admin = admin
+
+	// Should not timeout as all topics are dropped by the denylist
+	module.fetchGroupOffsets("testgroup")
+	admin.AssertExpectations(t)
+}
+
+func TestKafkaAdminClient_pollConsumerGroups_Error(t *testing.T) {
+	module := fixtureAdminModule()
+	module.Configure("test", "consumer.test")
+
+	admin := &helpers.MockSaramaClusterAdmin{}
+	admin.On("ListConsumerGroups").Return(map[string]string{}, errors.New("test error"))
+	module.admin = admin
+
+	// Nothing should be sent to storage, and this should not block
+	module.pollConsumerGroups()
+	admin.AssertExpectations(t)
+}
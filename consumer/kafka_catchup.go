@@ -0,0 +1,122 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// catchUpProgress tracks how far the consumers of the offsets topic are from the end of the topic as it was when they
+// started, so that progress can be reported while a restarted module rebuilds its window.
+type catchUpProgress struct {
+	lock      sync.Mutex
+	started   time.Time
+	targets   map[int32]int64
+	positions map[int32]int64
+
+	// The remaining count for each partition at the last report, used to find partitions that have stopped moving
+	lastRemaining map[int32]int64
+}
+
+func newCatchUpProgress() *catchUpProgress {
+	return &catchUpProgress{
+		started:       time.Now(),
+		targets:       make(map[int32]int64),
+		positions:     make(map[int32]int64),
+		lastRemaining: make(map[int32]int64),
+	}
+}
+
+// addPartition sets the offset that the partition consumer starts at, and the offset of the last message in the
+// partition when it started. A partition with nothing to read is not tracked.
+func (progress *catchUpProgress) addPartition(partition int32, start, target int64) {
+	if start > target {
+		return
+	}
+
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+	progress.targets[partition] = target
+	progress.positions[partition] = start - 1
+}
+
+// update records the offset of a message that has been read. Once a partition reaches its target, it stops being
+// tracked.
+func (progress *catchUpProgress) update(partition int32, offset int64) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	target, ok := progress.targets[partition]
+	if !ok {
+		return
+	}
+	if offset >= target {
+		delete(progress.targets, partition)
+		delete(progress.positions, partition)
+		delete(progress.lastRemaining, partition)
+		return
+	}
+	progress.positions[partition] = offset
+}
+
+// report returns the number of partitions that are still catching up, and the number of messages they have left to
+// read. A partition that has not moved since the last report is treated as caught up, as the last messages before the
+// target may be transaction markers (or part of an aborted transaction), which the consumer never returns.
+func (progress *catchUpProgress) report() (int, int64) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	var total int64
+	for partition, target := range progress.targets {
+		remaining := target - progress.positions[partition]
+		if last, ok := progress.lastRemaining[partition]; ok && (last == remaining) {
+			delete(progress.targets, partition)
+			delete(progress.positions, partition)
+			delete(progress.lastRemaining, partition)
+			continue
+		}
+		progress.lastRemaining[partition] = remaining
+		total += remaining
+	}
+	return len(progress.targets), total
+}
+
+// reportCatchUpProgress logs the catch-up progress at the given interval until all partitions have caught up, or
+// until the module is stopped.
+func (module *KafkaClient) reportCatchUpProgress(progress *catchUpProgress, interval time.Duration) {
+	defer module.running.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			partitions, remaining := progress.report()
+			if partitions == 0 {
+				module.Log.Info("caught up with offsets topic",
+					zap.String("topic", module.offsetsTopic),
+					zap.Duration("duration", time.Since(progress.started)),
+				)
+				return
+			}
+			module.Log.Info("catching up with offsets topic",
+				zap.String("topic", module.offsetsTopic),
+				zap.Int("partitions", partitions),
+				zap.Int64("remaining", remaining),
+			)
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatchUpProgress(t *testing.T) {
+	progress := newCatchUpProgress()
+	progress.addPartition(0, 100, 199)
+	progress.addPartition(1, 50, 59)
+	progress.addPartition(2, 10, 9)
+
+	partitions, remaining := progress.report()
+	assert.Equalf(t, 2, partitions, "Expected 2 partitions to be catching up, not %v", partitions)
+	assert.Equalf(t, int64(110), remaining, "Expected 110 messages remaining, not %v", remaining)
+
+	// Partition 1 reaches its target, and partition 0 moves forward
+	progress.update(0, 149)
+	progress.update(1, 59)
+	partitions, remaining = progress.report()
+	assert.Equalf(t, 1, partitions, "Expected 1 partition to be catching up, not %v", partitions)
+	assert.Equalf(t, int64(50), remaining, "Expected 50 messages remaining, not %v", remaining)
+
+	// Partition 0 has not moved since the last report, so it is treated as caught up
+	partitions, _ = progress.report()
+	assert.Equalf(t, 0, partitions, "Expected no partitions to be catching up, not %v", partitions)
+}
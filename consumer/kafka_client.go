@@ -14,11 +14,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
-	"regexp"
 	"sync"
 	"time"
 
+	"github.com/OneOfOne/xxhash"
 	"github.com/Shopify/sarama"
+	"github.com/rcrowley/go-metrics"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
@@ -29,6 +30,10 @@ import (
 // KafkaClient is a consumer module which connects to a single Apache Kafka cluster and reads consumer group information
 // from the offsets topic in the cluster, which is typically __consumer_offsets. The messages in this topic are decoded
 // and the information is forwarded to the storage subsystem for use in evaluations.
+//
+// Offsets are only forwarded for groups and topics that are accepted by the group-allowlist, group-denylist,
+// topic-allowlist, and topic-denylist configs. These can be changed while the module is running through the HTTP
+// server's filter endpoints, without losing the offsets that are already stored.
 type KafkaClient struct {
 	// App is a pointer to the application context. This stores the channel to the storage subsystem
 	App *protocol.ApplicationContext
@@ -40,13 +45,39 @@ type KafkaClient struct {
 	name                  string
 	cluster               string
 	servers               []string
+	clientPool            string
 	offsetsTopic          string
 	startLatest           bool
+	startFromMinutes      int64
 	backfillEarliest      bool
 	reportedConsumerGroup string
 	saramaConfig          *sarama.Config
-	groupAllowlist        *regexp.Regexp
-	groupDenylist         *regexp.Regexp
+	filter                *helpers.ConsumerFilter
+
+	// Messages that cannot be decoded are counted here, and written to the dead letter file if one is configured
+	deadLetters *helpers.DeadLetterQueue
+
+	// If set, this limits the bytes per second read from the offsets topic, across all partitions
+	rateLimiter *helpers.RateLimiter
+
+	// If shardCount is more than 1, only the partitions of the offsets topic where partition % shardCount == shardIndex
+	// are read
+	shardCount int32
+	shardIndex int32
+
+	// If there are ingest workers, messages are decoded by these goroutines instead of by the partition consumers. They
+	// are stopped after the partition consumers, so that the messages that are already queued are not lost
+	ingestWorkers    []chan *sarama.ConsumerMessage
+	ingestQueueDepth int
+	ingestRunning    sync.WaitGroup
+
+	// If set, progress is logged at this interval until the consumers reach the end of the offsets topic
+	catchUpInterval time.Duration
+	catchUp         *catchUpProgress
+
+	configRoot   string
+	messageCount metrics.Counter
+	errorCount   metrics.Counter
 
 	quitChannel chan struct{}
 	running     sync.WaitGroup
@@ -79,6 +110,17 @@ type metadataMember struct {
 	SessionTimeout   int32
 	Assignment       map[string][]int32
 }
+
+// decodeFailure is returned when a message from the offsets topic cannot be decoded. The value version is -1 if the
+// failure happened before it was read.
+type decodeFailure struct {
+	valueVersion int16
+	reason       string
+}
+
+// How often a backfill consumer checks whether it has stopped receiving messages before reaching its target offset
+var backfillIdleInterval = 10 * time.Second
+
 type backfillEndOffset struct {
 	Value int64
 }
@@ -86,13 +128,18 @@ type backfillEndOffset struct {
 // Configure validates the configuration for the consumer. At minimum, there must be a cluster name to which these
 // consumers belong, as well as a list of servers provided for the Kafka cluster, of the form host:port. If not
 // explicitly configured, the offsets topic is set to the default for Kafka, which is __consumer_offsets. If the
-// cluster name is unknown, or if the server list is missing or invalid, this func will panic.
+// cluster name is unknown, or if the server list is missing or invalid, this func will panic. The settings for
+// reading the offsets topic are described in the README.
 func (module *KafkaClient) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
 	module.name = name
+	module.configRoot = configRoot
 	module.quitChannel = make(chan struct{})
 	module.running = sync.WaitGroup{}
+	module.ingestRunning = sync.WaitGroup{}
+	module.messageCount = helpers.GetMetricCounter(configRoot + ".messages")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
 
 	module.cluster = viper.GetString(configRoot + ".cluster")
 	if !viper.IsSet("cluster." + module.cluster) {
@@ -101,6 +148,11 @@ func (module *KafkaClient) Configure(name, configRoot string) {
 
 	profile := viper.GetString(configRoot + ".client-profile")
 	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, module.cluster, module.saramaConfig)
+	if module.saramaConfig.Version.IsAtLeast(sarama.V0_11_0_0) {
+		// If the version is negotiated, this is undone when creating the client if the cluster is older than 0.11
+		module.saramaConfig.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
 
 	module.servers = viper.GetStringSlice(configRoot + ".servers")
 	if len(module.servers) == 0 {
@@ -108,13 +160,42 @@ func (module *KafkaClient) Configure(name, configRoot string) {
 	} else if !helpers.ValidateHostList(module.servers) {
 		panic("Consumer '" + name + "' has one or more improperly formatted servers (must be host:port)")
 	}
+	module.clientPool = viper.GetString(configRoot + ".client-pool")
 
 	// Set defaults for configs if needed, and get them
 	viper.SetDefault(configRoot+".offsets-topic", "__consumer_offsets")
 	module.offsetsTopic = viper.GetString(configRoot + ".offsets-topic")
 	module.startLatest = viper.GetBool(configRoot + ".start-latest")
 	module.backfillEarliest = module.startLatest && viper.GetBool(configRoot+".backfill-earliest")
+	module.startFromMinutes = viper.GetInt64(configRoot + ".start-from-minutes")
+	if (module.startFromMinutes < 0) || (module.startLatest && (module.startFromMinutes > 0)) {
+		panic("Consumer '" + name + "' has an invalid start-from-minutes (it cannot be used with start-latest)")
+	}
+	catchUpInterval := viper.GetInt64(configRoot + ".catch-up-progress-interval")
+	if catchUpInterval < 0 {
+		panic("Consumer '" + name + "' has an invalid catch-up-progress-interval")
+	}
+	module.catchUpInterval = time.Duration(catchUpInterval) * time.Second
+
+	viper.SetDefault(configRoot+".ingest-queue-depth", 100)
+	ingestWorkers := viper.GetInt(configRoot + ".ingest-workers")
+	module.ingestQueueDepth = viper.GetInt(configRoot + ".ingest-queue-depth")
+	if (ingestWorkers < 0) || (module.ingestQueueDepth < 1) {
+		panic("Consumer '" + name + "' has an invalid ingest-workers or ingest-queue-depth")
+	}
+	module.ingestWorkers = make([]chan *sarama.ConsumerMessage, ingestWorkers)
 	module.reportedConsumerGroup = "burrow-" + module.name
+	if viper.GetInt64(configRoot+".self-lag-threshold") < 0 {
+		panic("Consumer '" + name + "' has an invalid self-lag-threshold")
+	}
+	module.configureFetchLimits(configRoot)
+
+	viper.SetDefault(configRoot+".shard-count", 1)
+	module.shardCount = viper.GetInt32(configRoot + ".shard-count")
+	module.shardIndex = viper.GetInt32(configRoot + ".shard-index")
+	if (module.shardCount < 1) || (module.shardIndex < 0) || (module.shardIndex >= module.shardCount) {
+		panic("Consumer '" + name + "' has an invalid shard-count or shard-index")
+	}
 
 	// Check for disallowed config values
 	if viper.IsSet(configRoot+".group-whitelist") || viper.IsSet(configRoot+".group-blacklist") {
@@ -122,24 +203,51 @@ func (module *KafkaClient) Configure(name, configRoot string) {
 		panic("Please change configurations to allowlist and denylist")
 	}
 
-	allowlist := viper.GetString(configRoot + ".group-allowlist")
-	if allowlist != "" {
-		re, err := regexp.Compile(allowlist)
-		if err != nil {
-			module.Log.Panic("Failed to compile group allowlist")
-			panic(err)
-		}
-		module.groupAllowlist = re
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
 	}
+	module.filter = filter
 
-	denylist := viper.GetString(configRoot + ".group-denylist")
-	if denylist != "" {
-		re, err := regexp.Compile(denylist)
-		if err != nil {
-			module.Log.Panic("Failed to compile group denylist")
-			panic(err)
-		}
-		module.groupDenylist = re
+	deadLetters, err := helpers.NewDeadLetterQueue(configRoot, module.cluster)
+	if err != nil {
+		panic("Consumer '" + name + "' has an " + err.Error())
+	}
+	module.deadLetters = deadLetters
+}
+
+// configureFetchLimits sets the fetch sizes on the sarama config, if they are configured, and sets up the rate limiter
+func (module *KafkaClient) configureFetchLimits(configRoot string) {
+	if viper.IsSet(configRoot + ".fetch-min-bytes") {
+		module.saramaConfig.Consumer.Fetch.Min = viper.GetInt32(configRoot + ".fetch-min-bytes")
+	}
+	if viper.IsSet(configRoot + ".fetch-default-bytes") {
+		module.saramaConfig.Consumer.Fetch.Default = viper.GetInt32(configRoot + ".fetch-default-bytes")
+	}
+	if viper.IsSet(configRoot + ".fetch-max-bytes") {
+		module.saramaConfig.Consumer.Fetch.Max = viper.GetInt32(configRoot + ".fetch-max-bytes")
+	}
+	if viper.IsSet(configRoot + ".fetch-max-wait-ms") {
+		module.saramaConfig.Consumer.MaxWaitTime = time.Duration(viper.GetInt(configRoot+".fetch-max-wait-ms")) * time.Millisecond
+	}
+	if viper.IsSet(configRoot + ".channel-buffer-size") {
+		module.saramaConfig.ChannelBufferSize = viper.GetInt(configRoot + ".channel-buffer-size")
+	}
+
+	fetch := module.saramaConfig.Consumer.Fetch
+	if (fetch.Min <= 0) || (fetch.Default <= 0) || (fetch.Max < 0) || ((fetch.Max > 0) && ((fetch.Default > fetch.Max) || (fetch.Min > fetch.Max))) || (module.saramaConfig.Consumer.MaxWaitTime < time.Millisecond) {
+		panic("Consumer '" + module.name + "' has invalid fetch limits")
+	}
+	if module.saramaConfig.ChannelBufferSize < 0 {
+		panic("Consumer '" + module.name + "' has an invalid channel-buffer-size")
+	}
+
+	maxBytesPerSecond := viper.GetInt64(configRoot + ".max-bytes-per-second")
+	if maxBytesPerSecond < 0 {
+		panic("Consumer '" + module.name + "' has an invalid max-bytes-per-second")
+	} else if maxBytesPerSecond > 0 {
+		module.rateLimiter = helpers.NewRateLimiter(maxBytesPerSecond)
 	}
 }
 
@@ -149,11 +257,14 @@ func (module *KafkaClient) Start() error {
 	module.Log.Info("starting")
 
 	// Connect Kafka client
-	client, err := sarama.NewClient(module.servers, module.saramaConfig)
+	client, err := helpers.AcquireSaramaClient(module.clientPool, module.servers, module.saramaConfig)
 	if err != nil {
 		module.Log.Error("failed to start client", zap.Error(err))
 		return err
 	}
+	if module.clientPool != "" {
+		helpers.UseConsumerConfig(client.Config(), module.saramaConfig)
+	}
 
 	// Start the consumers
 	err = module.startKafkaConsumer(&helpers.BurrowSaramaClient{Client: client})
@@ -172,7 +283,11 @@ func (module *KafkaClient) Stop() error {
 
 	close(module.quitChannel)
 	module.running.Wait()
+	module.stopIngestWorkers()
 
+	if err := module.deadLetters.Close(); err != nil {
+		module.Log.Warn("failed to close dead letter file", zap.Error(err))
+	}
 	return nil
 }
 
@@ -239,21 +354,30 @@ func (module *KafkaClient) partitionConsumer(consumer sarama.PartitionConsumer,
 	defer module.running.Done()
 	defer consumer.AsyncClose()
 
+	// The last records before the target offset of a backfill may be transaction markers or part of an aborted
+	// transaction, which the consumer never returns. Check periodically whether the backfill is idle so it can stop
+	var idleCheck <-chan time.Time
+	if stopAtOffset != nil {
+		idleTicker := time.NewTicker(backfillIdleInterval)
+		defer idleTicker.Stop()
+		idleCheck = idleTicker.C
+	}
+	receivedMessage := false
+
 	for {
 		select {
+		case <-idleCheck:
+			if (!receivedMessage) && (consumer.HighWaterMarkOffset() > stopAtOffset.Value) {
+				module.Log.Debug("backfill consumer idle past target offset, terminating",
+					zap.Int64("offset", stopAtOffset.Value),
+				)
+				return
+			}
+			receivedMessage = false
 		case msg := <-consumer.Messages():
-			if module.reportedConsumerGroup != "" {
-				burrowOffset := &protocol.StorageRequest{
-					RequestType: protocol.StorageSetConsumerOffset,
-					Cluster:     module.cluster,
-					Topic:       msg.Topic,
-					Partition:   msg.Partition,
-					Group:       module.reportedConsumerGroup,
-					Timestamp:   time.Now().Unix() * 1000,
-					Offset:      msg.Offset + 1, // emulating a consumer which should commit (lastSeenOffset+1)
-					Order:       msg.Offset,
-				}
-				helpers.TimeoutSendStorageRequest(module.App.StorageChannel, burrowOffset, 1)
+			receivedMessage = true
+			if (module.rateLimiter != nil) && (!module.rateLimiter.Wait(len(msg.Key)+len(msg.Value), module.quitChannel)) {
+				return
 			}
 			if stopAtOffset != nil && msg.Offset >= stopAtOffset.Value {
 				module.Log.Debug("backfill consumer reached target offset, terminating",
@@ -262,19 +386,184 @@ func (module *KafkaClient) partitionConsumer(consumer sarama.PartitionConsumer,
 				)
 				return
 			}
-			module.processConsumerOffsetsMessage(msg)
+			if (stopAtOffset == nil) && (module.catchUp != nil) {
+				module.catchUp.update(msg.Partition, msg.Offset)
+			}
+			if !module.ingestMessage(msg) {
+				return
+			}
 		case err := <-consumer.Errors():
 			module.Log.Error("consume error",
 				zap.String("topic", err.Topic),
 				zap.Int32("partition", err.Partition),
 				zap.String("error", err.Err.Error()),
 			)
+			module.errorCount.Inc(1)
+			helpers.PublishModuleError(module.configRoot, module.cluster, "consume error: "+err.Err.Error())
 		case <-module.quitChannel:
 			return
 		}
 	}
 }
 
+// getPartitionStartOffset returns the offset to start consuming the partition of the offsets topic at. The startFrom
+// argument is either sarama.OffsetOldest, sarama.OffsetNewest, or a timestamp in milliseconds. For a timestamp, the
+// offset of the first message at or after that time is used, or the newest offset if there is none. If catch-up
+// progress is being reported, the partition is added to it.
+func (module *KafkaClient) getPartitionStartOffset(client helpers.SaramaClient, partition int32, startFrom int64) (int64, error) {
+	startOffset := startFrom
+	if startFrom >= 0 {
+		offset, err := client.GetOffset(module.offsetsTopic, partition, startFrom)
+		if err != nil {
+			module.Log.Error("failed to get offset for start time",
+				zap.String("topic", module.offsetsTopic),
+				zap.Int32("partition", partition),
+				zap.String("error", err.Error()),
+			)
+			return 0, err
+		}
+		startOffset = offset
+		if offset < 0 {
+			startOffset = sarama.OffsetNewest
+		}
+	}
+	if (module.catchUp == nil) || (startOffset == sarama.OffsetNewest) {
+		return startOffset, nil
+	}
+
+	// The offset of the first message is needed to track progress from the beginning of the partition
+	firstOffset := startOffset
+	if startOffset == sarama.OffsetOldest {
+		offset, err := client.GetOffset(module.offsetsTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			module.Log.Error("failed to get oldest offset",
+				zap.String("topic", module.offsetsTopic),
+				zap.Int32("partition", partition),
+				zap.String("error", err.Error()),
+			)
+			return 0, err
+		}
+		firstOffset = offset
+	}
+	newestOffset, err := client.GetOffset(module.offsetsTopic, partition, sarama.OffsetNewest)
+	if err != nil {
+		module.Log.Error("failed to get newest offset",
+			zap.String("topic", module.offsetsTopic),
+			zap.Int32("partition", partition),
+			zap.String("error", err.Error()),
+		)
+		return 0, err
+	}
+
+	// GetOffset returns the next (not yet published) offset, but we want the latest published offset
+	module.catchUp.addPartition(partition, firstOffset, newestOffset-1)
+	return startOffset, nil
+}
+
+// ingestMessage handles the message, or queues it for the ingest worker for its group if there are ingest workers. It
+// returns false if the module is stopped while waiting to queue the message.
+func (module *KafkaClient) ingestMessage(msg *sarama.ConsumerMessage) bool {
+	if len(module.ingestWorkers) == 0 {
+		module.handleMessage(msg)
+		return true
+	}
+
+	worker := module.ingestWorkers[int(xxhash.Checksum64(groupFromMessageKey(msg.Key))%uint64(len(module.ingestWorkers)))]
+	select {
+	case worker <- msg:
+		return true
+	case <-module.quitChannel:
+		return false
+	}
+}
+
+// handleMessage decodes the message, and then commits the position of the module in the offsets topic to storage as
+// the reported consumer group, if there is one. The position is only committed once the message has been handled, so
+// the lag of that group includes the messages that are still queued for the ingest workers.
+func (module *KafkaClient) handleMessage(msg *sarama.ConsumerMessage) {
+	module.processConsumerOffsetsMessage(msg)
+	if module.reportedConsumerGroup == "" {
+		return
+	}
+	burrowOffset := protocol.AcquireStorageRequest(protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     module.cluster,
+		Topic:       msg.Topic,
+		Partition:   msg.Partition,
+		Group:       module.reportedConsumerGroup,
+		Timestamp:   time.Now().Unix() * 1000,
+		Offset:      msg.Offset + 1, // emulating a consumer which should commit (lastSeenOffset+1)
+		Order:       msg.Offset,
+	})
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, burrowOffset, 1)
+}
+
+func (module *KafkaClient) startIngestWorkers() {
+	for i := range module.ingestWorkers {
+		module.ingestWorkers[i] = make(chan *sarama.ConsumerMessage, module.ingestQueueDepth)
+		module.ingestRunning.Add(1)
+		go module.ingestWorker(module.ingestWorkers[i])
+	}
+
+	ingestWorkers := module.ingestWorkers
+	helpers.RegisterMetricGaugeFunc(module.configRoot+".ingest-queue-depth", func() int64 {
+		depth := 0
+		for _, worker := range ingestWorkers {
+			depth += len(worker)
+		}
+		return int64(depth)
+	})
+}
+
+// ingestWorker handles the messages queued for it until its channel is closed by stopIngestWorkers
+func (module *KafkaClient) ingestWorker(messages chan *sarama.ConsumerMessage) {
+	defer module.ingestRunning.Done()
+
+	for msg := range messages {
+		module.handleMessage(msg)
+	}
+}
+
+// stopIngestWorkers closes the channels of the ingest workers, and waits for them to handle the messages that are
+// already queued. It must only be called once the partition consumers have stopped, so nothing else is queued.
+func (module *KafkaClient) stopIngestWorkers() {
+	for i, worker := range module.ingestWorkers {
+		if worker != nil {
+			close(worker)
+			module.ingestWorkers[i] = nil
+		}
+	}
+	module.ingestRunning.Wait()
+}
+
+// shardPartitions returns the partitions of the offsets topic that this module is responsible for
+func (module *KafkaClient) shardPartitions(partitions []int32) []int32 {
+	if module.shardCount <= 1 {
+		return partitions
+	}
+	shard := make([]int32, 0, len(partitions)/int(module.shardCount)+1)
+	for _, partition := range partitions {
+		if partition%module.shardCount == module.shardIndex {
+			shard = append(shard, partition)
+		}
+	}
+	return shard
+}
+
+// groupFromMessageKey returns the bytes of the group name in the key of an offsets topic message, which is the first
+// field after the key version for both offset commits and group metadata. If the key is too short, the whole key is
+// returned, as it will fail to decode anyways.
+func groupFromMessageKey(key []byte) []byte {
+	if len(key) < 4 {
+		return key
+	}
+	end := 4 + int(binary.BigEndian.Uint16(key[2:4]))
+	if end > len(key) {
+		return key
+	}
+	return key[4:end]
+}
+
 func (module *KafkaClient) startKafkaConsumer(client helpers.SaramaClient) error {
 	// Create the consumer from the client
 	consumer, err := client.NewConsumerFromClient()
@@ -294,20 +583,32 @@ func (module *KafkaClient) startKafkaConsumer(client helpers.SaramaClient) error
 		client.Close()
 		return err
 	}
+	partitions = module.shardPartitions(partitions)
 
 	// Default to bootstrapping the offsets topic, unless configured otherwise
 	startFrom := sarama.OffsetOldest
 	if module.startLatest {
 		startFrom = sarama.OffsetNewest
+	} else if module.startFromMinutes > 0 {
+		startFrom = time.Now().Add(-time.Duration(module.startFromMinutes)*time.Minute).UnixNano() / int64(time.Millisecond)
+	}
+	if (module.catchUpInterval > 0) && (!module.startLatest) {
+		module.catchUp = newCatchUpProgress()
 	}
 
+	module.startIngestWorkers()
+
 	// Start consumers for each partition with fan in
 	module.Log.Info("starting consumers",
 		zap.String("topic", module.offsetsTopic),
 		zap.Int("count", len(partitions)),
 	)
 	for _, partition := range partitions {
-		pconsumer, err := consumer.ConsumePartition(module.offsetsTopic, partition, startFrom)
+		startOffset, err := module.getPartitionStartOffset(client, partition, startFrom)
+		if err != nil {
+			return err
+		}
+		pconsumer, err := consumer.ConsumePartition(module.offsetsTopic, partition, startOffset)
 		if err != nil {
 			module.Log.Error("failed to consume partition",
 				zap.String("topic", module.offsetsTopic),
@@ -319,6 +620,10 @@ func (module *KafkaClient) startKafkaConsumer(client helpers.SaramaClient) error
 		module.running.Add(1)
 		go module.partitionConsumer(pconsumer, nil)
 	}
+	if module.catchUp != nil {
+		module.running.Add(1)
+		go module.reportCatchUpProgress(module.catchUp, module.catchUpInterval)
+	}
 
 	if module.backfillEarliest {
 		module.Log.Debug("backfilling consumer offsets")
@@ -361,12 +666,38 @@ func (module *KafkaClient) processConsumerOffsetsMessage(msg *sarama.ConsumerMes
 		zap.Int64("offset_offset", msg.Offset),
 	)
 
-	if len(msg.Value) == 0 {
-		// Tombstone message - we don't handle them for now
-		logger.Debug("dropped tombstone")
+	if len(msg.Key) == 0 {
+		// Nothing we know how to decode is written without a key
+		logger.Debug("dropped message with no key")
 		return
 	}
 
+	module.messageCount.Inc(1)
+	keyver, failure := module.decodeConsumerOffsetsMessage(msg, logger)
+	if failure == nil {
+		return
+	}
+	module.errorCount.Inc(1)
+	helpers.PublishModuleError(module.configRoot, module.cluster, "failed to decode message: "+failure.reason)
+	err := module.deadLetters.Add(&helpers.DeadLetter{
+		Topic:        msg.Topic,
+		Partition:    msg.Partition,
+		Offset:       msg.Offset,
+		Timestamp:    msg.Timestamp.UnixNano() / int64(time.Millisecond),
+		KeyVersion:   keyver,
+		ValueVersion: failure.valueVersion,
+		Reason:       failure.reason,
+		Key:          msg.Key,
+		Value:        msg.Value,
+	})
+	if err != nil {
+		logger.Warn("failed to write dead letter", zap.Error(err))
+	}
+}
+
+// decodeConsumerOffsetsMessage decodes a message with a key, and returns the key version (-1 if it cannot be read) and
+// the reason that the message could not be decoded, if it could not
+func (module *KafkaClient) decodeConsumerOffsetsMessage(msg *sarama.ConsumerMessage, logger *zap.Logger) (int16, *decodeFailure) {
 	var keyver int16
 	keyBuffer := bytes.NewBuffer(msg.Key)
 	err := binary.Read(keyBuffer, binary.BigEndian, &keyver)
@@ -374,19 +705,38 @@ func (module *KafkaClient) processConsumerOffsetsMessage(msg *sarama.ConsumerMes
 		logger.Warn("failed to decode",
 			zap.String("reason", "no key version"),
 		)
-		return
+		return -1, &decodeFailure{valueVersion: -1, reason: "no key version"}
+	}
+
+	if len(msg.Value) == 0 {
+		// A group metadata tombstone is written when the group is deleted, or when it expires after being empty
+		if keyver == 2 {
+			return keyver, module.decodeGroupTombstone(keyBuffer, logger)
+		}
+
+		// Offset tombstones - we don't handle them for now
+		logger.Debug("dropped tombstone")
+		return keyver, nil
 	}
 
 	switch keyver {
 	case 0, 1:
-		module.decodeKeyAndOffset(msg.Offset, keyBuffer, msg.Value, logger)
+		return keyver, module.decodeKeyAndOffset(msg.Offset, keyBuffer, msg.Value, logger)
 	case 2:
-		module.decodeGroupMetadata(keyBuffer, msg.Value, logger)
+		return keyver, module.decodeGroupMetadata(keyBuffer, msg.Value, logger)
+	case 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15:
+		// These are written by the new group coordinator (KIP-848) and for share groups. Group offsets are still
+		// written with key versions 0 and 1, so we don't need these
+		logger.Debug("dropped group coordinator record",
+			zap.Int16("version", keyver),
+		)
+		return keyver, nil
 	default:
 		logger.Warn("failed to decode",
 			zap.String("reason", "key version"),
 			zap.Int16("version", keyver),
 		)
+		return keyver, &decodeFailure{valueVersion: -1, reason: "key version"}
 	}
 }
 
@@ -408,17 +758,30 @@ func readString(buf *bytes.Buffer) (string, error) { // nolint:interfacer
 	return string(strbytes), nil
 }
 
-func (module *KafkaClient) acceptConsumerGroup(group string) bool {
-	if (module.groupAllowlist != nil) && (!module.groupAllowlist.MatchString(group)) {
-		return false
+// readCompactString reads a string in the encoding used by flexible message versions, where the length is an unsigned
+// varint that is one more than the string length (zero is a null string)
+func readCompactString(buf *bytes.Buffer) (string, error) { // nolint:interfacer
+	strlen, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return "", err
 	}
-	if (module.groupDenylist != nil) && module.groupDenylist.MatchString(group) {
-		return false
+	if strlen == 0 {
+		return "", nil
 	}
-	return true
+
+	strbytes := make([]byte, strlen-1)
+	n, err := buf.Read(strbytes)
+	if (err != nil) || (uint64(n) != strlen-1) {
+		return "", errors.New("string underflow")
+	}
+	return string(strbytes), nil
 }
 
-func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *bytes.Buffer, value []byte, logger *zap.Logger) {
+func (module *KafkaClient) acceptConsumerGroup(group string) bool {
+	return module.filter.AcceptGroup(group)
+}
+
+func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *bytes.Buffer, value []byte, logger *zap.Logger) *decodeFailure {
 	// Version 0 and 1 keys are decoded the same way
 	offsetKey, errorAt := decodeOffsetKeyV0(keyBuffer)
 	if errorAt != "" {
@@ -429,7 +792,7 @@ func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *byte
 			zap.Int32("partition", offsetKey.Partition),
 			zap.String("reason", errorAt),
 		)
-		return
+		return &decodeFailure{valueVersion: -1, reason: errorAt}
 	}
 
 	offsetLogger := logger.With(
@@ -441,7 +804,11 @@ func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *byte
 
 	if !module.acceptConsumerGroup(offsetKey.Group) {
 		offsetLogger.Debug("dropped", zap.String("reason", "allowlist"))
-		return
+		return nil
+	}
+	if !module.filter.AcceptTopic(offsetKey.Topic) {
+		offsetLogger.Debug("dropped", zap.String("reason", "topic filter"))
+		return nil
 	}
 
 	var valueVersion int16
@@ -451,23 +818,41 @@ func (module *KafkaClient) decodeKeyAndOffset(offsetOrder int64, keyBuffer *byte
 		offsetLogger.Warn("failed to decode",
 			zap.String("reason", "no value version"),
 		)
-		return
+		return &decodeFailure{valueVersion: -1, reason: "no value version"}
 	}
 
 	switch valueVersion {
-	case 0, 1:
-		module.decodeAndSendOffset(offsetOrder, offsetKey, valueBuffer, offsetLogger, decodeOffsetValueV0)
+	case 0, 1, 2:
+		// Versions 1 and 2 add and then remove an expire timestamp after the fields that we use
+		errorAt = module.decodeAndSendOffset(offsetOrder, offsetKey, valueBuffer, offsetLogger, decodeOffsetValueV0)
 	case 3:
-		module.decodeAndSendOffset(offsetOrder, offsetKey, valueBuffer, offsetLogger, decodeOffsetValueV3)
+		errorAt = module.decodeAndSendOffset(offsetOrder, offsetKey, valueBuffer, offsetLogger, decodeOffsetValueV3)
+	case 4:
+		errorAt = module.decodeAndSendOffset(offsetOrder, offsetKey, valueBuffer, offsetLogger, decodeOffsetValueV4)
 	default:
 		offsetLogger.Warn("failed to decode",
 			zap.String("reason", "value version"),
 			zap.Int16("version", valueVersion),
 		)
+		errorAt = "value version"
 	}
+	if errorAt != "" {
+		return &decodeFailure{valueVersion: valueVersion, reason: errorAt}
+	}
+	return nil
 }
 
-func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offsetKey, valueBuffer *bytes.Buffer, logger *zap.Logger, decoder func(*bytes.Buffer) (offsetValue, string)) {
+// decodeAndSendOffset sends the offset to storage, returning where the value could not be decoded if it failed
+func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offsetKey, valueBuffer *bytes.Buffer, logger *zap.Logger, decoder func(*bytes.Buffer) (offsetValue, string)) string {
+	// A sample of the offset commits are traced (tracing.ingest-sample-rate), from decoding to being stored
+	span := helpers.StartTrace("consumer offset commit", helpers.SpanKindConsumer)
+	defer span.End()
+	span.SetAttribute("consumer.module", module.name)
+	span.SetAttribute("kafka.cluster", module.cluster)
+	span.SetAttribute("kafka.consumer_group", offsetKey.Group)
+	span.SetAttribute("kafka.topic", offsetKey.Topic)
+	span.SetAttribute("kafka.partition", offsetKey.Partition)
+
 	offsetValue, errorAt := decoder(valueBuffer)
 	if errorAt != "" {
 		logger.Warn("failed to decode",
@@ -475,10 +860,11 @@ func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offs
 			zap.Int64("timestamp", offsetValue.Timestamp),
 			zap.String("reason", errorAt),
 		)
-		return
+		span.SetError("failed to decode " + errorAt)
+		return errorAt
 	}
 
-	partitionOffset := &protocol.StorageRequest{
+	partitionOffset := protocol.AcquireStorageRequest(protocol.StorageRequest{
 		RequestType: protocol.StorageSetConsumerOffset,
 		Cluster:     module.cluster,
 		Topic:       offsetKey.Topic,
@@ -487,22 +873,24 @@ func (module *KafkaClient) decodeAndSendOffset(offsetOrder int64, offsetKey offs
 		Timestamp:   offsetValue.Timestamp,
 		Offset:      offsetValue.Offset,
 		Order:       offsetOrder,
-	}
+		Trace:       span.Context(),
+	})
 	logger.Debug("consumer offset",
 		zap.Int64("offset", offsetValue.Offset),
 		zap.Int64("timestamp", offsetValue.Timestamp),
 	)
 	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, partitionOffset, 1)
+	return ""
 }
 
-func (module *KafkaClient) decodeGroupMetadata(keyBuffer *bytes.Buffer, value []byte, logger *zap.Logger) {
+func (module *KafkaClient) decodeGroupMetadata(keyBuffer *bytes.Buffer, value []byte, logger *zap.Logger) *decodeFailure {
 	group, err := readString(keyBuffer)
 	if err != nil {
 		logger.Warn("failed to decode",
 			zap.String("message_type", "metadata"),
 			zap.String("reason", "group"),
 		)
-		return
+		return &decodeFailure{valueVersion: -1, reason: "group"}
 	}
 
 	var valueVersion int16
@@ -514,15 +902,19 @@ func (module *KafkaClient) decodeGroupMetadata(keyBuffer *bytes.Buffer, value []
 			zap.String("group", group),
 			zap.String("reason", "no value version"),
 		)
-		return
+		return &decodeFailure{valueVersion: -1, reason: "no value version"}
 	}
 
 	switch valueVersion {
 	case 0, 1, 2, 3:
-		module.decodeAndSendGroupMetadata(valueVersion, group, valueBuffer, logger.With(
+		errorAt := module.decodeAndSendGroupMetadata(valueVersion, group, valueBuffer, logger.With(
 			zap.String("message_type", "metadata"),
 			zap.String("group", group),
 		))
+		if errorAt != "" {
+			return &decodeFailure{valueVersion: valueVersion, reason: errorAt}
+		}
+		return nil
 	default:
 		logger.Warn("failed to decode",
 			zap.String("message_type", "metadata"),
@@ -530,10 +922,47 @@ func (module *KafkaClient) decodeGroupMetadata(keyBuffer *bytes.Buffer, value []
 			zap.String("reason", "value version"),
 			zap.Int16("version", valueVersion),
 		)
+		return &decodeFailure{valueVersion: valueVersion, reason: "value version"}
 	}
 }
 
-func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group string, valueBuffer *bytes.Buffer, logger *zap.Logger) {
+func (module *KafkaClient) decodeGroupTombstone(keyBuffer *bytes.Buffer, logger *zap.Logger) *decodeFailure {
+	group, err := readString(keyBuffer)
+	if err != nil {
+		logger.Warn("failed to decode",
+			zap.String("message_type", "metadata"),
+			zap.String("reason", "group"),
+		)
+		return &decodeFailure{valueVersion: -1, reason: "group"}
+	}
+	if !module.acceptConsumerGroup(group) {
+		return nil
+	}
+
+	logger.Debug("group removed",
+		zap.String("message_type", "metadata"),
+		zap.String("group", group),
+	)
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+		RequestType: protocol.StorageClearConsumerOwners,
+		Cluster:     module.cluster,
+		Group:       group,
+	}, 1)
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     module.cluster,
+		Group:       group,
+		Members:     make([]*protocol.ConsumerGroupMember, 0),
+		GroupState:  "Dead",
+	}, 1)
+	return nil
+}
+
+// decodeAndSendGroupMetadata sends the members of the group to storage. Group metadata is only written when a rebalance
+// completes, so the group is Stable if it has members, and Empty if it does not. A rebalance that is in progress can
+// only be seen with the kafka_admin consumer module, which asks the group coordinator for the group state. If the
+// metadata cannot be decoded, where it failed is returned.
+func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group string, valueBuffer *bytes.Buffer, logger *zap.Logger) string {
 	var metadataHeader metadataHeader
 	var errorAt string
 	switch valueVersion {
@@ -553,12 +982,12 @@ func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group
 		metadataLogger.Warn("failed to decode",
 			zap.String("reason", errorAt),
 		)
-		return
+		return errorAt
 	}
 	metadataLogger.Debug("group metadata")
 	if metadataHeader.ProtocolType != "consumer" {
 		metadataLogger.Debug("skipped metadata because of unknown protocolType")
-		return
+		return ""
 	}
 
 	var memberCount int32
@@ -567,7 +996,7 @@ func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group
 		metadataLogger.Warn("failed to decode",
 			zap.String("reason", "no member size"),
 		)
-		return
+		return "no member size"
 	}
 
 	// If memberCount is zero, clear all ownership
@@ -578,19 +1007,38 @@ func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group
 			Cluster:     module.cluster,
 			Group:       group,
 		}, 1)
-		return
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerMembers,
+			Cluster:     module.cluster,
+			Group:       group,
+			Members:     make([]*protocol.ConsumerGroupMember, 0),
+			GroupState:  "Empty",
+		}, 1)
+		return ""
 	}
 
+	// Decode all the members before sending anything, so that we don't store a partial member list
 	count := int(memberCount)
+	members := make([]*protocol.ConsumerGroupMember, 0, count)
 	for i := 0; i < count; i++ {
 		member, errorAt := decodeMetadataMember(valueBuffer, valueVersion)
 		if errorAt != "" {
 			metadataLogger.Warn("failed to decode",
 				zap.String("reason", errorAt),
 			)
-			return
+			return errorAt
 		}
 
+		members = append(members, &protocol.ConsumerGroupMember{
+			MemberID:        member.MemberID,
+			GroupInstanceID: member.GroupInstanceID,
+			ClientID:        member.ClientID,
+			ClientHost:      member.ClientHost,
+			Assignment:      member.Assignment,
+		})
+	}
+
+	for _, member := range members {
 		for topic, partitions := range member.Assignment {
 			for _, partition := range partitions {
 				helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
@@ -605,6 +1053,16 @@ func (module *KafkaClient) decodeAndSendGroupMetadata(valueVersion int16, group
 			}
 		}
 	}
+
+	metadataLogger.Debug("group members", zap.Int("count", len(members)))
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     module.cluster,
+		Group:       group,
+		Members:     members,
+		GroupState:  "Stable",
+	}, 1)
+	return ""
 }
 
 func decodeMetadataValueHeader(buf *bytes.Buffer) (metadataHeader, string) {
@@ -836,3 +1294,29 @@ func decodeOffsetValueV3(valueBuffer *bytes.Buffer) (offsetValue, string) {
 	}
 	return offsetValue, ""
 }
+
+// Version 4 is the first flexible version. The fields are the same as version 3, but the metadata is a compact string,
+// and tagged fields follow the ones we use
+func decodeOffsetValueV4(valueBuffer *bytes.Buffer) (offsetValue, string) {
+	var err error
+	offsetValue := offsetValue{}
+
+	err = binary.Read(valueBuffer, binary.BigEndian, &offsetValue.Offset)
+	if err != nil {
+		return offsetValue, "offset"
+	}
+	var leaderEpoch int32
+	err = binary.Read(valueBuffer, binary.BigEndian, &leaderEpoch)
+	if err != nil {
+		return offsetValue, "leaderEpoch"
+	}
+	_, err = readCompactString(valueBuffer)
+	if err != nil {
+		return offsetValue, "metadata"
+	}
+	err = binary.Read(valueBuffer, binary.BigEndian, &offsetValue.Timestamp)
+	if err != nil {
+		return offsetValue, "timestamp"
+	}
+	return offsetValue, ""
+}
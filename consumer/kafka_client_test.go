@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/Shopify/sarama"
 	"github.com/spf13/viper"
@@ -72,6 +73,72 @@ func TestKafkaClient_Configure(t *testing.T) {
 	assert.Equal(t, "__consumer_offsets", module.offsetsTopic, "Default OffsetTopic value of __consumer_offsets did not get set")
 }
 
+func TestKafkaClient_Configure_IsolationLevel(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("client-profile..kafka-version", "0.10.2")
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, sarama.ReadUncommitted, module.saramaConfig.Consumer.IsolationLevel, "Expected IsolationLevel to be ReadUncommitted for old Kafka versions, not %v", module.saramaConfig.Consumer.IsolationLevel)
+
+	module = fixtureModule()
+	viper.Set("client-profile..kafka-version", "0.11.0")
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, sarama.ReadCommitted, module.saramaConfig.Consumer.IsolationLevel, "Expected IsolationLevel to be ReadCommitted, not %v", module.saramaConfig.Consumer.IsolationLevel)
+}
+
+func TestKafkaClient_Configure_FetchLimits(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.fetch-min-bytes", 1024)
+	viper.Set("consumer.test.fetch-default-bytes", 65536)
+	viper.Set("consumer.test.fetch-max-bytes", 1048576)
+	viper.Set("consumer.test.fetch-max-wait-ms", 1000)
+	viper.Set("consumer.test.channel-buffer-size", 64)
+	viper.Set("consumer.test.max-bytes-per-second", 10485760)
+	module.Configure("test", "consumer.test")
+
+	assert.Equalf(t, int32(1024), module.saramaConfig.Consumer.Fetch.Min, "Expected Fetch.Min to be 1024, not %v", module.saramaConfig.Consumer.Fetch.Min)
+	assert.Equalf(t, 64, module.saramaConfig.ChannelBufferSize, "Expected ChannelBufferSize to be 64, not %v", module.saramaConfig.ChannelBufferSize)
+
+	assert.Equalf(t, int32(65536), module.saramaConfig.Consumer.Fetch.Default, "Expected Fetch.Default to be 65536, not %v", module.saramaConfig.Consumer.Fetch.Default)
+	assert.Equalf(t, int32(1048576), module.saramaConfig.Consumer.Fetch.Max, "Expected Fetch.Max to be 1048576, not %v", module.saramaConfig.Consumer.Fetch.Max)
+	assert.Equalf(t, time.Second, module.saramaConfig.Consumer.MaxWaitTime, "Expected MaxWaitTime to be 1s, not %v", module.saramaConfig.Consumer.MaxWaitTime)
+	assert.NotNil(t, module.rateLimiter, "Expected rateLimiter to be set")
+}
+
+func TestKafkaClient_Configure_BadFetchLimits(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.fetch-default-bytes", 1048576)
+	viper.Set("consumer.test.fetch-max-bytes", 65536)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for fetch-default-bytes over fetch-max-bytes")
+
+	module = fixtureModule()
+	viper.Set("consumer.test.fetch-min-bytes", 0)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for a zero fetch-min-bytes")
+
+	module = fixtureModule()
+	viper.Set("consumer.test.channel-buffer-size", -1)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for a negative channel-buffer-size")
+
+	module = fixtureModule()
+	viper.Set("consumer.test.max-bytes-per-second", -1)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for a negative max-bytes-per-second")
+}
+
+func TestKafkaClient_Configure_BadStartFromMinutes(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.start-latest", true)
+	viper.Set("consumer.test.start-from-minutes", 60)
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaClient_Configure_BadShard(t *testing.T) {
+	for _, shard := range [][2]int{{0, 0}, {2, 2}, {2, -1}} {
+		module := fixtureModule()
+		viper.Set("consumer.test.shard-count", shard[0])
+		viper.Set("consumer.test.shard-index", shard[1])
+		assert.Panicsf(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for shard-count %v and shard-index %v", shard[0], shard[1])
+	}
+}
+
 func TestKafkaClient_Configure_BadCluster(t *testing.T) {
 	module := fixtureModule()
 	viper.Set("consumer.test.cluster", "nocluster")
@@ -153,6 +220,112 @@ func TestKafkaClient_partitionConsumer_reports_own_progress(t *testing.T) {
 	module.running.Wait()
 }
 
+func TestKafkaClient_partitionConsumer_IngestWorkers(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.ingest-workers", 4)
+	module.Configure("test", "consumer.test")
+	module.reportedConsumerGroup = ""
+	module.startIngestWorkers()
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	consumer := &helpers.MockSaramaPartitionConsumer{}
+	consumer.On("AsyncClose").Return()
+	consumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	consumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+
+	module.running.Add(1)
+	go module.partitionConsumer(consumer, nil)
+
+	// Commits for the same group must be stored in order, even though they are decoded by the worker pool
+	for i := 0; i < 10; i++ {
+		messageChan <- &sarama.ConsumerMessage{
+			Key:   []byte("\x00\x01\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"),
+			Value: append([]byte("\x00\x00\x00\x00\x00\x00\x00\x00\x20"), byte(i), 0, 8, 't', 'e', 's', 't', 'd', 'a', 't', 'a', 0, 0, 0, 0, 0, 0, 6, 0x65),
+		}
+	}
+	for i := 0; i < 10; i++ {
+		request := <-module.App.StorageChannel
+		assert.Equalf(t, int64(0x2000+i), request.Offset, "Expected offset %v, not %v", 0x2000+i, request.Offset)
+	}
+
+	close(module.quitChannel)
+	module.running.Wait()
+	module.stopIngestWorkers()
+}
+
+func TestKafkaClient_stopIngestWorkers(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.ingest-workers", 1)
+	module.Configure("test", "consumer.test")
+	module.startIngestWorkers()
+
+	// Queue two commits while nothing is reading from storage, and then stop the module
+	for i := 0; i < 2; i++ {
+		assert.True(t, module.ingestMessage(&sarama.ConsumerMessage{
+			Topic:  "__consumer_offsets",
+			Offset: int64(10 + i),
+			Key:    []byte("\x00\x01\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"),
+			Value:  append([]byte("\x00\x00\x00\x00\x00\x00\x00\x00\x20"), byte(i), 0, 8, 't', 'e', 's', 't', 'd', 'a', 't', 'a', 0, 0, 0, 0, 0, 0, 6, 0x65),
+		}), "Expected the message to be queued")
+	}
+	close(module.quitChannel)
+	module.running.Wait()
+	stopped := make(chan struct{})
+	go func() {
+		module.stopIngestWorkers()
+		close(stopped)
+	}()
+
+	// Both queued commits are stored, and the position of the module is reported after each one is handled
+	for i := 0; i < 2; i++ {
+		request := <-module.App.StorageChannel
+		assert.Equalf(t, "testgroup", request.Group, "Expected the commit to be stored first, not %v", request.Group)
+		assert.Equalf(t, int64(0x2000+i), request.Offset, "Expected offset %v, not %v", 0x2000+i, request.Offset)
+		request = <-module.App.StorageChannel
+		assert.Equalf(t, "burrow-test", request.Group, "Expected the position of the module, not %v", request.Group)
+		assert.Equalf(t, int64(11+i), request.Offset, "Expected offset %v, not %v", 11+i, request.Offset)
+	}
+	<-stopped
+}
+
+func TestGroupFromMessageKey(t *testing.T) {
+	group := groupFromMessageKey([]byte("\x00\x01\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"))
+	assert.Equalf(t, []byte("testgroup"), group, "Expected group to be testgroup, not %v", string(group))
+
+	group = groupFromMessageKey([]byte("\x00\x02\x00\x20test"))
+	assert.Equalf(t, []byte("\x00\x02\x00\x20test"), group, "Expected whole key for a short key, not %v", group)
+}
+
+func TestKafkaClient_partitionConsumer_BackfillIdle(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test-backfill")
+
+	originalInterval := backfillIdleInterval
+	backfillIdleInterval = 10 * time.Millisecond
+	defer func() { backfillIdleInterval = originalInterval }()
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	// The target offset is a transaction marker, so no message will ever be received for it
+	consumer := &helpers.MockSaramaPartitionConsumer{}
+	consumer.On("AsyncClose").Return()
+	consumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	consumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+	consumer.On("HighWaterMarkOffset").Return(int64(457))
+
+	// The backfill consumer should stop on its own, without closing the quit channel
+	module.running.Add(1)
+	go module.partitionConsumer(consumer, &backfillEndOffset{456})
+	module.running.Wait()
+
+	consumer.AssertExpectations(t)
+}
+
 func TestKafkaClient_startKafkaConsumer(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "consumer.test")
@@ -184,6 +357,40 @@ func TestKafkaClient_startKafkaConsumer(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func TestKafkaClient_startKafkaConsumer_Sharded(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.shard-count", 2)
+	viper.Set("consumer.test.shard-index", 1)
+	module.Configure("test", "consumer.test")
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	mockPartitionConsumer := &helpers.MockSaramaPartitionConsumer{}
+	mockPartitionConsumer.On("AsyncClose").Return()
+	mockPartitionConsumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	mockPartitionConsumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+
+	// Only the odd partitions should be consumed
+	consumer := &helpers.MockSaramaConsumer{}
+	consumer.On("ConsumePartition", "__consumer_offsets", int32(1), sarama.OffsetOldest).Return(mockPartitionConsumer, nil).Once()
+	consumer.On("ConsumePartition", "__consumer_offsets", int32(3), sarama.OffsetOldest).Return(mockPartitionConsumer, nil).Once()
+
+	client := &helpers.MockSaramaClient{}
+	client.On("NewConsumerFromClient").Return(consumer, nil)
+	client.On("Partitions", "__consumer_offsets").Return([]int32{0, 1, 2, 3}, nil)
+
+	err := module.startKafkaConsumer(client)
+	assert.Nil(t, err, "Expected startKafkaConsumer to return no error")
+
+	close(module.quitChannel)
+	module.running.Wait()
+
+	consumer.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
 func TestKafkaClient_startKafkaConsumerWithBackfill(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "consumer.test-backfill")
@@ -218,6 +425,48 @@ func TestKafkaClient_startKafkaConsumerWithBackfill(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func TestKafkaClient_startKafkaConsumer_StartFromMinutes(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.start-from-minutes", 60)
+	viper.Set("consumer.test.catch-up-progress-interval", 30)
+	module.Configure("test", "consumer.test")
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	// Don't assert expectations on this - the way it goes down, they're called but don't show up
+	mockPartitionConsumer := &helpers.MockSaramaPartitionConsumer{}
+	mockPartitionConsumer.On("AsyncClose").Return()
+	mockPartitionConsumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	mockPartitionConsumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+
+	// Partition 0 has messages in the last hour, and partition 1 does not
+	consumer := &helpers.MockSaramaConsumer{}
+	consumer.On("ConsumePartition", "__consumer_offsets", int32(0), int64(400)).Return(mockPartitionConsumer, nil)
+	consumer.On("ConsumePartition", "__consumer_offsets", int32(1), sarama.OffsetNewest).Return(mockPartitionConsumer, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("NewConsumerFromClient").Return(consumer, nil)
+	client.On("Partitions", "__consumer_offsets").Return([]int32{0, 1}, nil)
+	client.On("GetOffset", "__consumer_offsets", int32(0), mock.MatchedBy(func(ts int64) bool { return ts > 0 })).Return(int64(400), nil)
+	client.On("GetOffset", "__consumer_offsets", int32(0), sarama.OffsetNewest).Return(int64(500), nil)
+	client.On("GetOffset", "__consumer_offsets", int32(1), mock.MatchedBy(func(ts int64) bool { return ts > 0 })).Return(int64(-1), nil)
+
+	err := module.startKafkaConsumer(client)
+	assert.Nil(t, err, "Expected startKafkaConsumer to return no error")
+
+	partitions, remaining := module.catchUp.report()
+	assert.Equalf(t, 1, partitions, "Expected 1 partition to be catching up, not %v", partitions)
+	assert.Equalf(t, int64(100), remaining, "Expected 100 messages remaining, not %v", remaining)
+
+	close(module.quitChannel)
+	module.running.Wait()
+
+	consumer.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
 func TestKafkaClient_startKafkaConsumer_FailCreateConsumer(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "consumer.test")
@@ -509,6 +758,44 @@ func TestKafkaClient_decodeOffsetValueV3_Errors(t *testing.T) {
 	}
 }
 
+func TestKafkaClient_decodeOffsetValueV4(t *testing.T) {
+	buf := bytes.NewBuffer([]byte("\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x00\x00\x00\x09testdata\x00\x00\x00\x00\x00\x00\x06\x65\x00"))
+	result, errorAt := decodeOffsetValueV4(buf)
+
+	assert.Equalf(t, "", errorAt, "Expected decodeOffsetValueV4 to return empty errorAt, not %v", errorAt)
+	assert.Equalf(t, int64(8372), result.Offset, "Expected Offset to be 8372, not %v", result.Offset)
+	assert.Equalf(t, int64(1637), result.Timestamp, "Expected Timestamp to be 1637, not %v", result.Timestamp)
+}
+
+var decodeOffsetValueV4Errors = []errorTestSetBytesWithString{
+	{[]byte("\x00\x00\x00\x00\x00"), "offset"},
+	{[]byte("\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x00\x00"), "leaderEpoch"},
+	{[]byte("\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x00\x00\x00\x09tes"), "metadata"},
+	{[]byte("\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x00\x00\x00\x09testdata\x00\x00\x00\x00"), "timestamp"},
+}
+
+func TestKafkaClient_decodeOffsetValueV4_Errors(t *testing.T) {
+	for _, values := range decodeOffsetValueV4Errors {
+		_, errorAt := decodeOffsetValueV4(bytes.NewBuffer(values.Bytes))
+		assert.Equalf(t, values.ErrorAt, errorAt, "Expected errorAt to be %v, not %v", values.ErrorAt, errorAt)
+	}
+}
+
+func TestKafkaClient_processConsumerOffsetsMessage_SkippedRecords(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	// A record with no key, and a record written by the new group coordinator, should both be skipped
+	messages := []*sarama.ConsumerMessage{
+		{Key: []byte{}, Value: []byte("\x00\x00"), Topic: "__consumer_offsets"},
+		{Key: []byte("\x00\x05\x00\x09testgroup"), Value: []byte("\x00\x00"), Topic: "__consumer_offsets"},
+	}
+	for _, msg := range messages {
+		// Should not timeout
+		module.processConsumerOffsetsMessage(msg)
+	}
+}
+
 func TestKafkaClient_decodeKeyAndOffset(t *testing.T) {
 	module := fixtureModule()
 	viper.Set("consumer.test.group-allowlist", "test.*")
@@ -530,10 +817,24 @@ func TestKafkaClient_decodeKeyAndOffset(t *testing.T) {
 	assert.Equalf(t, int64(1637), request.Timestamp, "Expected Timestamp to be 1637, not %v", request.Timestamp)
 }
 
+func TestKafkaClient_decodeKeyAndOffset_ValueVersion2(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	keyBuf := bytes.NewBuffer([]byte("\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"))
+	valueBytes := []byte("\x00\x02\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x08testdata\x00\x00\x00\x00\x00\x00\x06\x65")
+
+	go module.decodeKeyAndOffset(543, keyBuf, valueBytes, zap.NewNop())
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, int64(8372), request.Offset, "Expected Offset to be 8372, not %v", request.Offset)
+	assert.Equalf(t, int64(1637), request.Timestamp, "Expected Timestamp to be 1637, not %v", request.Timestamp)
+}
+
 var decodeKeyAndOffsetErrors = []errorTestSetBytes{
 	{[]byte("\x00\x09testgroup\x00\x09testt"), []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x08testdata\x00\x00\x00\x00\x00\x00\x06\x65")},
 	{[]byte("\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"), []byte("\x00")},
-	{[]byte("\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"), []byte("\x00\x02\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x08testdata\x00\x00\x00\x00\x00\x00\x06\x65")},
+	{[]byte("\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"), []byte("\x00\x04\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x08testdata\x00\x00\x00\x00\x00\x00\x06\x65")},
 }
 
 func TestKafkaClient_decodeKeyAndOffset_BadValueVersion(t *testing.T) {
@@ -558,6 +859,18 @@ func TestKafkaClient_decodeKeyAndOffset_Allowlist(t *testing.T) {
 	module.decodeKeyAndOffset(0, keyBuf, valueBytes, zap.NewNop())
 }
 
+func TestKafkaClient_decodeKeyAndOffset_TopicDenylist(t *testing.T) {
+	module := fixtureModule()
+	viper.Set("consumer.test.topic-denylist", "^test.*")
+	module.Configure("test", "consumer.test")
+
+	keyBuf := bytes.NewBuffer([]byte("\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"))
+	valueBytes := []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x20\xb4\x00\x08testdata\x00\x00\x00\x00\x00\x00\x06\x65")
+
+	// Should not timeout as the topic should be dropped by the denylist
+	module.decodeKeyAndOffset(0, keyBuf, valueBytes, zap.NewNop())
+}
+
 func TestKafkaClient_decodeAndSendOffset_ErrorValue(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "consumer.test")
@@ -590,6 +903,32 @@ func TestKafkaClient_decodeGroupMetadata(t *testing.T) {
 	assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
 	assert.Equalf(t, "testclienthost", request.Owner, "Expected request sent with Owner testclienthost, not %v", request.Owner)
 	assert.Equalf(t, "testclientid", request.ClientID, "Expected request set with ClientID testclientid, not %v", request.ClientID)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerMembers, request.RequestType, "Expected request sent with type StorageSetConsumerMembers, not %v", request.RequestType)
+	assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
+	assert.Lenf(t, request.Members, 1, "Expected request sent with exactly one member, not %v", len(request.Members))
+	assert.Equalf(t, "testmemberid", request.Members[0].MemberID, "Expected member MemberID to be testmemberid, not %v", request.Members[0].MemberID)
+	assert.Equalf(t, "testclienthost", request.Members[0].ClientHost, "Expected member ClientHost to be testclienthost, not %v", request.Members[0].ClientHost)
+	assert.Equalf(t, []int32{11}, request.Members[0].Assignment["topic1"], "Expected member to be assigned topic1 partition 11, not %v", request.Members[0].Assignment)
+	assert.Equalf(t, "Stable", request.GroupState, "Expected request sent with GroupState Stable, not %v", request.GroupState)
+}
+
+func TestKafkaClient_decodeAndSendGroupMetadata_NoMembers(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	valueBuf := bytes.NewBuffer([]byte("\x00\x08consumer\x00\x00\x00\x01\x00\x0ctestprotocol\x00\x0atestleader\x00\x00\x00\x00"))
+	go module.decodeAndSendGroupMetadata(1, "testgroup", valueBuf, zap.NewNop())
+
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageClearConsumerOwners, request.RequestType, "Expected request sent with type StorageClearConsumerOwners, not %v", request.RequestType)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerMembers, request.RequestType, "Expected request sent with type StorageSetConsumerMembers, not %v", request.RequestType)
+	assert.NotNil(t, request.Members, "Expected request sent with an empty member list")
+	assert.Lenf(t, request.Members, 0, "Expected request sent with no members, not %v", len(request.Members))
+	assert.Equalf(t, "Empty", request.GroupState, "Expected request sent with GroupState Empty, not %v", request.GroupState)
 }
 
 var decodeGroupMetadataErrors = []errorTestSetBytes{
@@ -674,6 +1013,30 @@ func TestKafkaClient_processConsumerOffsetsMessage_DifferentProtocolType(t *test
 	}
 }
 
+func TestKafkaClient_processConsumerOffsetsMessage_GroupTombstone(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	msg := &sarama.ConsumerMessage{
+		Key:       []byte("\x00\x02\x00\x09testgroup"),
+		Value:     nil,
+		Topic:     "__consumer_offsets",
+		Partition: 0,
+		Offset:    8232,
+		Timestamp: time.Now(),
+	}
+
+	go module.processConsumerOffsetsMessage(msg)
+	request := <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageClearConsumerOwners, request.RequestType, "Expected request sent with type StorageClearConsumerOwners, not %v", request.RequestType)
+
+	request = <-module.App.StorageChannel
+	assert.Equalf(t, protocol.StorageSetConsumerMembers, request.RequestType, "Expected request sent with type StorageSetConsumerMembers, not %v", request.RequestType)
+	assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
+	assert.Lenf(t, request.Members, 0, "Expected request sent with no members, not %v", len(request.Members))
+	assert.Equalf(t, "Dead", request.GroupState, "Expected request sent with GroupState Dead, not %v", request.GroupState)
+}
+
 func TestKafkaClient_processConsumerOffsetsMessage_Metadata(t *testing.T) {
 	module := fixtureModule()
 	module.Configure("test", "consumer.test")
@@ -722,3 +1085,35 @@ func TestKafkaClient_processConsumerOffsetsMessage_Errors(t *testing.T) {
 		module.processConsumerOffsetsMessage(msg)
 	}
 }
+
+func TestKafkaClient_processConsumerOffsetsMessage_DeadLetters(t *testing.T) {
+	module := fixtureModule()
+	module.Configure("test", "consumer.test")
+
+	messages := []errorTestSetBytes{
+		{[]byte("\x00"), []byte("\x00\x00")},
+		{[]byte("\x00\x01\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"), []byte("\x00\x09\x00\x00")},
+		{[]byte("\x00\x01\x00\x09testgroup\x00\x09testtopic\x00\x00\x00\x0b"), []byte("\x00\x09\x00\x00")},
+		{[]byte("\x00\x63\x00\x09testgroup"), []byte("\x00\x00")},
+	}
+	for _, values := range messages {
+		// Should not timeout
+		module.processConsumerOffsetsMessage(&sarama.ConsumerMessage{
+			Key:       values.KeyBytes,
+			Value:     values.ValueBytes,
+			Topic:     "__consumer_offsets",
+			Partition: 0,
+			Offset:    8232,
+			Timestamp: time.Now(),
+		})
+	}
+
+	counts := make(map[string]uint64)
+	for _, count := range helpers.GetDeadLetterCounts() {
+		if count.Module == "consumer.test" {
+			assert.Equalf(t, "test", count.Cluster, "Expected cluster to be test, not %v", count.Cluster)
+			counts[fmt.Sprintf("%v/%v", count.KeyVersion, count.ValueVersion)] = count.Count
+		}
+	}
+	assert.Equal(t, map[string]uint64{"-1/-1": 1, "1/9": 2, "99/-1": 1}, counts, "Expected counts for each key and value version")
+}
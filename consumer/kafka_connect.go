@@ -0,0 +1,248 @@
+// +build !no_kafka_connect
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func init() {
+	helpers.RegisterModuleClass("consumer", "kafka_connect", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return &KafkaConnectClient{
+			App: app,
+			Log: log,
+		}
+	})
+	helpers.RegisterConfigKeys("consumer.*", "kafka_connect",
+		helpers.ConfigKey{Name: "url", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "username", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "password", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "refresh", Type: helpers.ConfigTypeInteger, Default: 30},
+		helpers.ConfigKey{Name: "timeout", Type: helpers.ConfigTypeInteger, Default: 10},
+	)
+}
+
+// KafkaConnectClient is a consumer module which periodically polls the REST API of a Kafka Connect cluster to get the
+// connectors that are running, along with the state of their tasks. Each sink connector is mapped to the consumer
+// group that it uses, so that its lag can be looked up by the connector name. The consumer offsets themselves must
+// still be collected by a kafka or kafka_admin consumer module for the same cluster.
+type KafkaConnectClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name     string
+	cluster  string
+	url      string
+	refresh  int
+	username string
+	password string
+
+	httpClient    *http.Client
+	refreshTicker *time.Ticker
+	quitChannel   chan struct{}
+	running       sync.WaitGroup
+}
+
+// These are the parts of the response to GET /connectors?expand=status&expand=info that we use
+type connectExpandedConnector struct {
+	Info   connectConnectorInfo   `json:"info"`
+	Status connectConnectorStatus `json:"status"`
+}
+
+type connectConnectorInfo struct {
+	Config map[string]string `json:"config"`
+	Type   string            `json:"type"`
+}
+
+type connectConnectorStatus struct {
+	Connector connectState   `json:"connector"`
+	Tasks     []connectState `json:"tasks"`
+	Type      string         `json:"type"`
+}
+
+type connectState struct {
+	ID       int32  `json:"id"`
+	State    string `json:"state"`
+	WorkerID string `json:"worker_id"`
+	Trace    string `json:"trace"`
+}
+
+// Configure validates the configuration for the module. There must be a cluster name to which the Connect cluster
+// belongs, and a url for the Connect REST API, of the form http://host:port. The interval for polling connectors
+// defaults to 30 seconds, and the timeout for requests defaults to 10 seconds. If the Connect REST API requires basic
+// authentication, a username and password may be provided. If the cluster name is unknown, or if the url is missing or
+// invalid, this func will panic.
+func (module *KafkaConnectClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	module.url = strings.TrimSuffix(viper.GetString(configRoot+".url"), "/")
+	parsedURL, err := url.Parse(module.url)
+	if (module.url == "") || (err != nil) || ((parsedURL.Scheme != "http") && (parsedURL.Scheme != "https")) || (parsedURL.Host == "") {
+		panic("Consumer '" + name + "' must have a url for the Kafka Connect REST API (http://host:port)")
+	}
+
+	// Set defaults for configs if needed, and get them
+	viper.SetDefault(configRoot+".refresh", 30)
+	viper.SetDefault(configRoot+".timeout", 10)
+	module.refresh = viper.GetInt(configRoot + ".refresh")
+	module.username = viper.GetString(configRoot + ".username")
+	module.password = viper.GetString(configRoot + ".password")
+
+	module.httpClient = &http.Client{
+		Timeout: time.Duration(viper.GetInt(configRoot+".timeout")) * time.Second,
+	}
+}
+
+// Start polls the Connect cluster once, and then starts a ticker to poll it periodically. An error polling the Connect
+// cluster is logged, but it does not prevent the module from starting, as the Connect cluster may just be unavailable.
+func (module *KafkaConnectClient) Start() error {
+	module.Log.Info("starting")
+
+	module.pollConnectors()
+
+	module.refreshTicker = time.NewTicker(time.Duration(module.refresh) * time.Second)
+	module.running.Add(1)
+	go module.mainLoop()
+
+	return nil
+}
+
+// Stop causes the polling ticker to be stopped.
+func (module *KafkaConnectClient) Stop() error {
+	module.Log.Info("stopping")
+
+	module.refreshTicker.Stop()
+	close(module.quitChannel)
+	module.running.Wait()
+
+	return nil
+}
+
+func (module *KafkaConnectClient) mainLoop() {
+	defer module.running.Done()
+
+	for {
+		select {
+		case <-module.refreshTicker.C:
+			module.pollConnectors()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *KafkaConnectClient) pollConnectors() {
+	response, err := module.fetchConnectors()
+	if err != nil {
+		// Keep whatever we had before, rather than making all the connectors disappear
+		module.Log.Warn("failed to fetch connectors", zap.Error(err))
+		return
+	}
+
+	connectors := make([]*protocol.Connector, 0, len(response))
+	for name, expanded := range response {
+		connector := &protocol.Connector{
+			Name:           name,
+			ConnectCluster: module.name,
+			Type:           expanded.Status.Type,
+			State:          expanded.Status.Connector.State,
+			WorkerID:       expanded.Status.Connector.WorkerID,
+			Tasks:          make([]*protocol.ConnectorTask, len(expanded.Status.Tasks)),
+		}
+		if connector.Type == "" {
+			connector.Type = expanded.Info.Type
+		}
+		if connector.Type == "sink" {
+			connector.Group = sinkConnectorGroup(name, expanded.Info.Config)
+		}
+		for i, task := range expanded.Status.Tasks {
+			connector.Tasks[i] = &protocol.ConnectorTask{
+				ID:       task.ID,
+				State:    task.State,
+				WorkerID: task.WorkerID,
+				Trace:    task.Trace,
+			}
+		}
+		connectors = append(connectors, connector)
+	}
+
+	module.Log.Debug("connectors", zap.Int("count", len(connectors)))
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConnectors,
+		Cluster:     module.cluster,
+		Source:      module.name,
+		Connectors:  connectors,
+	}, 1)
+}
+
+func (module *KafkaConnectClient) fetchConnectors() (map[string]*connectExpandedConnector, error) {
+	req, err := http.NewRequest("GET", module.url+"/connectors?expand=status&expand=info", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if module.username != "" {
+		req.SetBasicAuth(module.username, module.password)
+	}
+
+	resp, err := module.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected response status " + resp.Status)
+	}
+
+	response := make(map[string]*connectExpandedConnector)
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// sinkConnectorGroup returns the consumer group used by a sink connector. This is connect-<name>, unless the group has
+// been overridden in the connector configuration
+func sinkConnectorGroup(name string, config map[string]string) string {
+	if group, ok := config["consumer.override.group.id"]; ok && (group != "") {
+		return group
+	}
+	return "connect-" + name
+}
@@ -0,0 +1,132 @@
+// +build !no_kafka_connect
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureConnectModule(url string) *KafkaConnectClient {
+	module := KafkaConnectClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("cluster.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.class-name", "kafka_connect")
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.url", url)
+
+	return &module
+}
+
+const testConnectorsResponse = `{
+  "testsink": {
+    "info": {"name": "testsink", "config": {"connector.class": "TestSink"}, "tasks": [{"connector": "testsink", "task": 0}], "type": "sink"},
+    "status": {"name": "testsink", "connector": {"state": "RUNNING", "worker_id": "10.0.0.1:8083"}, "tasks": [{"id": 0, "state": "RUNNING", "worker_id": "10.0.0.1:8083"}], "type": "sink"}
+  },
+  "overriddensink": {
+    "info": {"name": "overriddensink", "config": {"consumer.override.group.id": "customgroup"}, "tasks": [], "type": "sink"},
+    "status": {"name": "overriddensink", "connector": {"state": "PAUSED", "worker_id": "10.0.0.2:8083"}, "tasks": [], "type": "sink"}
+  },
+  "testsource": {
+    "info": {"name": "testsource", "config": {}, "tasks": [{"connector": "testsource", "task": 0}], "type": "source"},
+    "status": {"name": "testsource", "connector": {"state": "RUNNING", "worker_id": "10.0.0.1:8083"}, "tasks": [{"id": 0, "state": "FAILED", "worker_id": "10.0.0.1:8083", "trace": "test error"}], "type": "source"}
+  }
+}`
+
+func TestKafkaConnectClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(KafkaConnectClient))
+}
+
+func TestKafkaConnectClient_Configure(t *testing.T) {
+	module := fixtureConnectModule("http://connect.example.com:8083/")
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, "http://connect.example.com:8083", module.url, "Expected trailing slash to be removed from url, not %v", module.url)
+	assert.Equalf(t, 30, module.refresh, "Default refresh value of 30 did not get set, got %v", module.refresh)
+}
+
+func TestKafkaConnectClient_Configure_BadURL(t *testing.T) {
+	for _, url := range []string{"", "connect.example.com:8083", "ftp://connect.example.com"} {
+		module := fixtureConnectModule(url)
+		assert.Panicsf(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic for url %v", url)
+	}
+}
+
+func TestKafkaConnectClient_Configure_BadCluster(t *testing.T) {
+	module := fixtureConnectModule("http://connect.example.com:8083")
+	viper.Set("consumer.test.cluster", "nocluster")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaConnectClient_pollConnectors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equalf(t, "/connectors", r.URL.Path, "Unexpected request path %v", r.URL.Path)
+		assert.Equalf(t, []string{"status", "info"}, r.URL.Query()["expand"], "Unexpected expand parameters %v", r.URL.Query()["expand"])
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok && (user == "testuser") && (pass == "testpass"), "Expected basic auth credentials")
+		fmt.Fprint(w, testConnectorsResponse)
+	}))
+	defer server.Close()
+
+	module := fixtureConnectModule(server.URL)
+	viper.Set("consumer.test.username", "testuser")
+	viper.Set("consumer.test.password", "testpass")
+	module.Configure("testconnect", "consumer.test")
+
+	go module.pollConnectors()
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, protocol.StorageSetConnectors, request.RequestType, "Expected request of type StorageSetConnectors, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request Cluster to be test, not %v", request.Cluster)
+	assert.Equalf(t, "testconnect", request.Source, "Expected request Source to be testconnect, not %v", request.Source)
+	assert.Lenf(t, request.Connectors, 3, "Expected three connectors, not %v", len(request.Connectors))
+
+	connectors := make(map[string]*protocol.Connector)
+	for _, connector := range request.Connectors {
+		connectors[connector.Name] = connector
+	}
+	assert.Equalf(t, "connect-testsink", connectors["testsink"].Group, "Expected testsink group to be connect-testsink, not %v", connectors["testsink"].Group)
+	assert.Equalf(t, "RUNNING", connectors["testsink"].State, "Expected testsink state to be RUNNING, not %v", connectors["testsink"].State)
+	assert.Equalf(t, "customgroup", connectors["overriddensink"].Group, "Expected overriddensink group to be customgroup, not %v", connectors["overriddensink"].Group)
+	assert.Equalf(t, "", connectors["testsource"].Group, "Expected testsource to have no group, not %v", connectors["testsource"].Group)
+	assert.Equalf(t, "FAILED", connectors["testsource"].Tasks[0].State, "Expected testsource task state to be FAILED, not %v", connectors["testsource"].Tasks[0].State)
+	assert.Equalf(t, "test error", connectors["testsource"].Tasks[0].Trace, "Expected testsource task trace to be set, not %v", connectors["testsource"].Tasks[0].Trace)
+}
+
+func TestKafkaConnectClient_pollConnectors_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	module := fixtureConnectModule(server.URL)
+	module.Configure("testconnect", "consumer.test")
+
+	// Nothing should be sent to storage, and this should not block
+	module.pollConnectors()
+}
@@ -0,0 +1,532 @@
+// +build !no_kafka_json
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func init() {
+	helpers.RegisterModuleClass("consumer", "kafka_json", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return &KafkaJSONClient{
+			App: app,
+			Log: log,
+		}
+	})
+	helpers.RegisterConfigKeys("consumer.*", "kafka_json", append([]helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "offsets-topic", Type: helpers.ConfigTypeString},
+		{Name: "start-latest", Type: helpers.ConfigTypeBoolean},
+		{Name: "group", Type: helpers.ConfigTypeString},
+		{Name: "group-field", Type: helpers.ConfigTypeString, Default: "group"},
+		{Name: "topic-field", Type: helpers.ConfigTypeString, Default: "topic"},
+		{Name: "partition-field", Type: helpers.ConfigTypeString, Default: "partition"},
+		{Name: "offset-field", Type: helpers.ConfigTypeString, Default: "offset"},
+		{Name: "timestamp-field", Type: helpers.ConfigTypeString},
+		{Name: "timestamp-unit", Type: helpers.ConfigTypeString, Default: "ms"},
+		{Name: "value-format", Type: helpers.ConfigTypeString, Default: "json"},
+		{Name: "schema-registry", Type: helpers.ConfigTypeString},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
+
+// KafkaJSONClient is a consumer module which reads consumer offsets from a user topic that contains JSON messages,
+// such as the checkpoints that some stream processing frameworks write to their own topics instead of committing to
+// Kafka. The location of each field in the message is configured as a path of object keys separated by periods (for
+// example, "checkpoint.source.partition"). Numeric fields may be JSON numbers or strings.
+//
+// Messages may also be serialized with a schema from a Confluent Schema Registry, if value-format is set to
+// "schema-registry". Avro, Protobuf, and JSON schemas are supported. Fields in Avro messages are addressed by name, and
+// fields in Protobuf messages are addressed by field number, as the Protobuf schema is not parsed.
+//
+// As the messages for a single group and partition may be spread across partitions of the topic, the commit timestamp
+// is used to order offsets, rather than the position of the message in the topic.
+type KafkaJSONClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name         string
+	cluster      string
+	servers      []string
+	offsetsTopic string
+	startLatest  bool
+	saramaConfig *sarama.Config
+	filter       *helpers.ConsumerFilter
+
+	// If group is set, all offsets belong to this group and the group field is not used
+	group              string
+	groupField         []string
+	topicField         []string
+	partitionField     []string
+	offsetField        []string
+	timestampField     []string
+	timestampInSeconds bool
+
+	// If the values use the Schema Registry wire format, this is the registry that schemas are fetched from. Decoded
+	// schemas are cached here by ID, as schemas never change once they are registered
+	schemaRegistry *helpers.SchemaRegistryClient
+	schemas        map[int32]*kafkaJSONSchema
+	schemaLock     sync.RWMutex
+
+	client      helpers.SaramaClient
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+}
+
+type kafkaJSONSchema struct {
+	schemaType string
+	avro       *avroSchema
+}
+
+// Configure validates the configuration for the consumer. There must be a cluster name to which these consumers
+// belong, a list of servers for the Kafka cluster, of the form host:port, and the topic that the offsets are read
+// from. The field paths default to "group", "topic", "partition", and "offset". If no timestamp-field is configured,
+// or it is missing in a message, the timestamp of the Kafka message is used. Timestamps are in milliseconds, unless
+// timestamp-unit is set to "s". If value-format is "schema-registry", the schema-registry config must name the
+// registry to use. If the cluster name is unknown, the topic is missing, or if the server list is missing or invalid,
+// this func will panic.
+func (module *KafkaJSONClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+	module.schemas = make(map[int32]*kafkaJSONSchema)
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	profile := viper.GetString(configRoot + ".client-profile")
+	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, module.cluster, module.saramaConfig)
+
+	module.servers = viper.GetStringSlice(configRoot + ".servers")
+	if len(module.servers) == 0 {
+		panic("No Kafka brokers specified for consumer " + module.name)
+	} else if !helpers.ValidateHostList(module.servers) {
+		panic("Consumer '" + name + "' has one or more improperly formatted servers (must be host:port)")
+	}
+
+	module.offsetsTopic = viper.GetString(configRoot + ".offsets-topic")
+	if module.offsetsTopic == "" {
+		panic("Consumer '" + name + "' must have an offsets-topic")
+	}
+
+	// Set defaults for configs if needed, and get them
+	viper.SetDefault(configRoot+".group-field", "group")
+	viper.SetDefault(configRoot+".topic-field", "topic")
+	viper.SetDefault(configRoot+".partition-field", "partition")
+	viper.SetDefault(configRoot+".offset-field", "offset")
+	viper.SetDefault(configRoot+".timestamp-unit", "ms")
+	viper.SetDefault(configRoot+".value-format", "json")
+	module.startLatest = viper.GetBool(configRoot + ".start-latest")
+	module.group = viper.GetString(configRoot + ".group")
+	module.groupField = parseJSONFieldPath(viper.GetString(configRoot + ".group-field"))
+	module.topicField = parseJSONFieldPath(viper.GetString(configRoot + ".topic-field"))
+	module.partitionField = parseJSONFieldPath(viper.GetString(configRoot + ".partition-field"))
+	module.offsetField = parseJSONFieldPath(viper.GetString(configRoot + ".offset-field"))
+	module.timestampField = parseJSONFieldPath(viper.GetString(configRoot + ".timestamp-field"))
+
+	switch viper.GetString(configRoot + ".timestamp-unit") {
+	case "ms":
+		module.timestampInSeconds = false
+	case "s":
+		module.timestampInSeconds = true
+	default:
+		panic("Consumer '" + name + "' has an unknown timestamp-unit (must be ms or s)")
+	}
+
+	switch viper.GetString(configRoot + ".value-format") {
+	case "json":
+		module.schemaRegistry = nil
+	case "schema-registry":
+		module.schemaRegistry = helpers.GetSchemaRegistryClientFromProfile(viper.GetString(configRoot + ".schema-registry"))
+	default:
+		panic("Consumer '" + name + "' has an unknown value-format (must be json or schema-registry)")
+	}
+
+	if ((module.group == "") && (module.groupField == nil)) || (module.topicField == nil) || (module.partitionField == nil) || (module.offsetField == nil) {
+		panic("Consumer '" + name + "' must have field paths for the group, topic, partition, and offset")
+	}
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
+	}
+	module.filter = filter
+}
+
+// Start connects to the Kafka cluster using the Shopify/sarama client. Any error connecting to the cluster is returned
+// to the caller. Once the client is set up, consumers for each partition of the offsets topic are started.
+func (module *KafkaJSONClient) Start() error {
+	module.Log.Info("starting")
+
+	// Connect Kafka client
+	client, err := helpers.NewSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		module.Log.Error("failed to start client", zap.Error(err))
+		return err
+	}
+	module.client = &helpers.BurrowSaramaClient{Client: client}
+
+	err = module.startOffsetsConsumer(module.client)
+	if err != nil {
+		module.Log.Error("failed to start consumer", zap.Error(err))
+		client.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Stop closes the goroutines that listen to the offsets topic, and then closes the client.
+func (module *KafkaJSONClient) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	module.client.Close()
+
+	return nil
+}
+
+func (module *KafkaJSONClient) startOffsetsConsumer(client helpers.SaramaClient) error {
+	consumer, err := client.NewConsumerFromClient()
+	if err != nil {
+		return err
+	}
+
+	partitions, err := client.Partitions(module.offsetsTopic)
+	if err != nil {
+		module.Log.Error("failed to get partition count",
+			zap.String("topic", module.offsetsTopic),
+			zap.String("error", err.Error()),
+		)
+		return err
+	}
+
+	// Default to reading the whole offsets topic, unless configured otherwise
+	startFrom := sarama.OffsetOldest
+	if module.startLatest {
+		startFrom = sarama.OffsetNewest
+	}
+
+	module.Log.Info("starting consumers",
+		zap.String("topic", module.offsetsTopic),
+		zap.Int("count", len(partitions)),
+	)
+	for _, partition := range partitions {
+		pconsumer, err := consumer.ConsumePartition(module.offsetsTopic, partition, startFrom)
+		if err != nil {
+			module.Log.Error("failed to consume partition",
+				zap.String("topic", module.offsetsTopic),
+				zap.Int32("partition", partition),
+				zap.String("error", err.Error()),
+			)
+			return err
+		}
+		module.running.Add(1)
+		go module.partitionConsumer(pconsumer)
+	}
+	return nil
+}
+
+func (module *KafkaJSONClient) partitionConsumer(consumer sarama.PartitionConsumer) {
+	defer module.running.Done()
+	defer consumer.AsyncClose()
+
+	for {
+		select {
+		case msg := <-consumer.Messages():
+			module.processOffsetMessage(msg)
+		case err := <-consumer.Errors():
+			module.Log.Error("consume error",
+				zap.String("topic", err.Topic),
+				zap.Int32("partition", err.Partition),
+				zap.String("error", err.Err.Error()),
+			)
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *KafkaJSONClient) acceptConsumerGroup(group string) bool {
+	return module.filter.AcceptGroup(group)
+}
+
+func (module *KafkaJSONClient) processOffsetMessage(msg *sarama.ConsumerMessage) {
+	logger := module.Log.With(
+		zap.String("offset_topic", msg.Topic),
+		zap.Int32("offset_partition", msg.Partition),
+		zap.Int64("offset_offset", msg.Offset),
+	)
+
+	if len(msg.Value) == 0 {
+		logger.Debug("dropped tombstone")
+		return
+	}
+
+	request, errorAt := module.decodeOffsetMessage(msg)
+	if errorAt != "" {
+		logger.Warn("failed to decode",
+			zap.String("reason", errorAt),
+		)
+		return
+	}
+
+	if !module.acceptConsumerGroup(request.Group) {
+		logger.Debug("dropped", zap.String("reason", "allowlist"))
+		return
+	}
+	if !module.filter.AcceptTopic(request.Topic) {
+		logger.Debug("dropped", zap.String("reason", "topic filter"))
+		return
+	}
+
+	logger.Debug("consumer offset",
+		zap.String("group", request.Group),
+		zap.String("topic", request.Topic),
+		zap.Int32("partition", request.Partition),
+		zap.Int64("offset", request.Offset),
+		zap.Int64("timestamp", request.Timestamp),
+	)
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, request, 1)
+}
+
+// decodeOffsetMessage decodes the JSON in the message and builds a storage request from the configured fields. If
+// there is a problem, the name of the field that could not be decoded is returned
+func (module *KafkaJSONClient) decodeOffsetMessage(msg *sarama.ConsumerMessage) (*protocol.StorageRequest, string) {
+	document, err := module.decodeValue(msg.Value)
+	if err != nil {
+		return nil, "value"
+	}
+
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     module.cluster,
+		Group:       module.group,
+	}
+
+	if request.Group == "" {
+		request.Group, err = getJSONFieldString(document, module.groupField)
+		if (err != nil) || (request.Group == "") {
+			return nil, "group"
+		}
+	}
+	request.Topic, err = getJSONFieldString(document, module.topicField)
+	if (err != nil) || (request.Topic == "") {
+		return nil, "topic"
+	}
+	partition, err := getJSONFieldInt64(document, module.partitionField)
+	if (err != nil) || (partition < 0) || (partition > 2147483647) {
+		return nil, "partition"
+	}
+	request.Partition = int32(partition)
+	request.Offset, err = getJSONFieldInt64(document, module.offsetField)
+	if (err != nil) || (request.Offset < 0) {
+		return nil, "offset"
+	}
+
+	// Fall back to the time the message was written if there is no timestamp in the message
+	request.Timestamp = msg.Timestamp.UnixNano() / int64(time.Millisecond)
+	if module.timestampField != nil {
+		if _, ok := lookupJSONField(document, module.timestampField); ok {
+			request.Timestamp, err = getJSONFieldInt64(document, module.timestampField)
+			if err != nil {
+				return nil, "timestamp"
+			}
+			if module.timestampInSeconds {
+				request.Timestamp *= 1000
+			}
+		}
+	}
+	if request.Timestamp <= 0 {
+		request.Timestamp = time.Now().Unix() * 1000
+	}
+	request.Order = request.Timestamp
+
+	return request, ""
+}
+
+// decodeValue decodes the message value, using the schema from the registry if the values use the Schema Registry
+// wire format
+func (module *KafkaJSONClient) decodeValue(value []byte) (interface{}, error) {
+	if module.schemaRegistry == nil {
+		return decodeJSONValue(value)
+	}
+
+	schemaID, payload, err := splitSchemaRegistryValue(value)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := module.getSchema(schemaID)
+	if err != nil {
+		module.Log.Warn("failed to get schema",
+			zap.Int32("schema_id", schemaID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	switch schema.schemaType {
+	case "AVRO":
+		return decodeAvroValue(schema.avro, bytes.NewReader(payload))
+	case "PROTOBUF":
+		payload, err = stripProtobufMessageIndexes(payload)
+		if err != nil {
+			return nil, err
+		}
+		return decodeProtobufMessage(payload)
+	default:
+		return decodeJSONValue(payload)
+	}
+}
+
+// getSchema returns the decoded schema for the ID, fetching it from the registry if it is not cached. Failures are
+// not cached, so the schema will be fetched again for the next message
+func (module *KafkaJSONClient) getSchema(schemaID int32) (*kafkaJSONSchema, error) {
+	module.schemaLock.RLock()
+	schema, ok := module.schemas[schemaID]
+	module.schemaLock.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	registered, err := module.schemaRegistry.GetSchemaByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	schema = &kafkaJSONSchema{schemaType: registered.SchemaType}
+	switch registered.SchemaType {
+	case "AVRO":
+		schema.avro, err = parseAvroSchema(registered.Schema)
+		if err != nil {
+			return nil, err
+		}
+	case "PROTOBUF", "JSON":
+	default:
+		return nil, errors.New("unsupported schema type " + registered.SchemaType)
+	}
+
+	module.schemaLock.Lock()
+	module.schemas[schemaID] = schema
+	module.schemaLock.Unlock()
+	return schema, nil
+}
+
+func decodeJSONValue(value []byte) (interface{}, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	err := decoder.Decode(&document)
+	return document, err
+}
+
+// parseJSONFieldPath splits a field path on periods. An empty path returns nil
+func parseJSONFieldPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupJSONField follows the path of object keys through the document. Array elements may be selected by index, and
+// bytes are decoded as a Protobuf message if the path continues into them
+func lookupJSONField(document interface{}, path []string) (interface{}, bool) {
+	value := document
+	for _, key := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if (err != nil) || (index < 0) || (index >= len(node)) {
+				return nil, false
+			}
+			value = node[index]
+		case []byte:
+			message, err := decodeProtobufMessage(node)
+			if err != nil {
+				return nil, false
+			}
+			child, ok := message[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func getJSONFieldString(document interface{}, path []string) (string, error) {
+	value, ok := lookupJSONField(document, path)
+	if !ok {
+		return "", errors.New("field not found")
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case json.Number:
+		return typedValue.String(), nil
+	case []byte:
+		return string(typedValue), nil
+	default:
+		return "", errors.New("field is not a string")
+	}
+}
+
+func getJSONFieldInt64(document interface{}, path []string) (int64, error) {
+	value, ok := lookupJSONField(document, path)
+	if !ok {
+		return 0, errors.New("field not found")
+	}
+
+	switch typedValue := value.(type) {
+	case json.Number:
+		return typedValue.Int64()
+	case string:
+		return strconv.ParseInt(typedValue, 10, 64)
+	default:
+		return 0, errors.New("field is not a number")
+	}
+}
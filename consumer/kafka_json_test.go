@@ -0,0 +1,312 @@
+// +build !no_kafka_json
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureJSONModule() *KafkaJSONClient {
+	module := KafkaJSONClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("cluster.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.class-name", "kafka_json")
+	viper.Set("consumer.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.offsets-topic", "checkpoints")
+
+	return &module
+}
+
+func TestKafkaJSONClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(KafkaJSONClient))
+}
+
+func TestKafkaJSONClient_Configure(t *testing.T) {
+	module := fixtureJSONModule()
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, []string{"group"}, module.groupField, "Default group-field value of group did not get set, got %v", module.groupField)
+	assert.Equalf(t, []string{"offset"}, module.offsetField, "Default offset-field value of offset did not get set, got %v", module.offsetField)
+	assert.Nilf(t, module.timestampField, "Expected timestampField to be nil, not %v", module.timestampField)
+	assert.False(t, module.timestampInSeconds, "Expected timestampInSeconds to be false")
+}
+
+func TestKafkaJSONClient_Configure_NoTopic(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.offsets-topic", "")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaJSONClient_Configure_NoOffsetField(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.offset-field", "")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaJSONClient_Configure_BadTimestampUnit(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.timestamp-unit", "hours")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaJSONClient_Configure_BadCluster(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.cluster", "nocluster")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestKafkaJSONClient_processOffsetMessage(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.group-field", "job.name")
+	viper.Set("consumer.test.topic-field", "source.topic")
+	viper.Set("consumer.test.partition-field", "source.partition")
+	viper.Set("consumer.test.offset-field", "positions.0")
+	viper.Set("consumer.test.timestamp-field", "time")
+	viper.Set("consumer.test.timestamp-unit", "s")
+	module.Configure("test", "consumer.test")
+
+	msg := &sarama.ConsumerMessage{
+		Value:     []byte(`{"job":{"name":"testgroup"},"source":{"topic":"testtopic","partition":"3"},"positions":[1234],"time":1600000000}`),
+		Topic:     "checkpoints",
+		Partition: 0,
+		Offset:    42,
+		Timestamp: time.Unix(1500000000, 0),
+	}
+
+	go module.processOffsetMessage(msg)
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, protocol.StorageSetConsumerOffset, request.RequestType, "Expected request sent with type StorageSetConsumerOffset, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(3), request.Partition, "Expected request sent with partition 3, not %v", request.Partition)
+	assert.Equalf(t, int64(1234), request.Offset, "Expected request sent with Offset 1234, not %v", request.Offset)
+	assert.Equalf(t, int64(1600000000000), request.Timestamp, "Expected request sent with Timestamp 1600000000000, not %v", request.Timestamp)
+	assert.Equalf(t, request.Timestamp, request.Order, "Expected request Order to match Timestamp, not %v", request.Order)
+}
+
+func TestKafkaJSONClient_processOffsetMessage_StaticGroup(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.group", "staticgroup")
+	viper.Set("consumer.test.timestamp-field", "time")
+	module.Configure("test", "consumer.test")
+
+	// There is no time field, so the message timestamp should be used
+	msg := &sarama.ConsumerMessage{
+		Value:     []byte(`{"group":"ignored","topic":"testtopic","partition":0,"offset":100}`),
+		Timestamp: time.Unix(1500000000, 0),
+	}
+
+	go module.processOffsetMessage(msg)
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, "staticgroup", request.Group, "Expected request sent with Group staticgroup, not %v", request.Group)
+	assert.Equalf(t, int64(100), request.Offset, "Expected request sent with Offset 100, not %v", request.Offset)
+	assert.Equalf(t, int64(1500000000000), request.Timestamp, "Expected request sent with Timestamp 1500000000000, not %v", request.Timestamp)
+}
+
+var decodeOffsetMessageErrors = []struct {
+	Value   string
+	ErrorAt string
+}{
+	{`not json`, "value"},
+	{`{"topic":"testtopic","partition":0,"offset":100}`, "group"},
+	{`{"group":"testgroup","topic":["testtopic"],"partition":0,"offset":100}`, "topic"},
+	{`{"group":"testgroup","topic":"testtopic","partition":-1,"offset":100}`, "partition"},
+	{`{"group":"testgroup","topic":"testtopic","partition":0,"offset":"abc"}`, "offset"},
+	{`{"group":"testgroup","topic":"testtopic","partition":0,"offset":1.5}`, "offset"},
+}
+
+func TestKafkaJSONClient_decodeOffsetMessage_Errors(t *testing.T) {
+	module := fixtureJSONModule()
+	module.Configure("test", "consumer.test")
+
+	for _, values := range decodeOffsetMessageErrors {
+		_, errorAt := module.decodeOffsetMessage(&sarama.ConsumerMessage{Value: []byte(values.Value)})
+		assert.Equalf(t, values.ErrorAt, errorAt, "Expected errorAt to be %v, not %v", values.ErrorAt, errorAt)
+	}
+}
+
+func TestKafkaJSONClient_processOffsetMessage_Denylist(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.group-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the group should be dropped by the denylist
+	module.processOffsetMessage(&sarama.ConsumerMessage{Value: []byte(`{"group":"testgroup","topic":"testtopic","partition":0,"offset":100}`)})
+}
+
+func TestKafkaJSONClient_processOffsetMessage_TopicDenylist(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.topic-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the topic should be dropped by the denylist
+	module.processOffsetMessage(&sarama.ConsumerMessage{Value: []byte(`{"group":"testgroup","topic":"testtopic","partition":0,"offset":100}`)})
+}
+
+func TestKafkaJSONClient_startOffsetsConsumer(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.start-latest", true)
+	module.Configure("test", "consumer.test")
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	// Don't assert expectations on this - the way it goes down, they're called but don't show up
+	mockPartitionConsumer := &helpers.MockSaramaPartitionConsumer{}
+	mockPartitionConsumer.On("AsyncClose").Return()
+	mockPartitionConsumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	mockPartitionConsumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+
+	consumer := &helpers.MockSaramaConsumer{}
+	consumer.On("ConsumePartition", "checkpoints", int32(0), sarama.OffsetNewest).Return(mockPartitionConsumer, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("NewConsumerFromClient").Return(consumer, nil)
+	client.On("Partitions", "checkpoints").Return([]int32{0}, nil)
+
+	err := module.startOffsetsConsumer(client)
+	assert.Nil(t, err, "Expected startOffsetsConsumer to return no error")
+
+	close(module.quitChannel)
+	module.running.Wait()
+
+	consumer.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
+// fixtureSchemaRegistry serves testAvroSchema as schema ID 1 and a Protobuf schema as schema ID 2, and counts requests
+func fixtureSchemaRegistry(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			response, _ := json.Marshal(map[string]string{"schema": testAvroSchema})
+			w.Write(response)
+		case "/schemas/ids/2":
+			w.Write([]byte(`{"schemaType": "PROTOBUF", "schema": "syntax = \"proto3\"; message Checkpoint {}"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestKafkaJSONClient_processOffsetMessage_Avro(t *testing.T) {
+	var requests int32
+	registry := fixtureSchemaRegistry(&requests)
+	defer registry.Close()
+
+	module := fixtureJSONModule()
+	viper.Set("schema-registry.test.url", registry.URL)
+	viper.Set("consumer.test.value-format", "schema-registry")
+	viper.Set("consumer.test.schema-registry", "test")
+	viper.Set("consumer.test.topic-field", "source.topic")
+	viper.Set("consumer.test.partition-field", "source.partition")
+	module.Configure("test", "consumer.test")
+
+	msg := &sarama.ConsumerMessage{
+		Value:     append([]byte("\x00\x00\x00\x00\x01"), encodeTestAvroCheckpoint()...),
+		Timestamp: time.Unix(1500000000, 0),
+	}
+
+	// The schema should only be fetched once
+	for i := 0; i < 2; i++ {
+		go module.processOffsetMessage(msg)
+		request := <-module.App.StorageChannel
+
+		assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+		assert.Equalf(t, int32(3), request.Partition, "Expected request sent with partition 3, not %v", request.Partition)
+		assert.Equalf(t, int64(1234), request.Offset, "Expected request sent with Offset 1234, not %v", request.Offset)
+	}
+	assert.Equalf(t, int32(1), atomic.LoadInt32(&requests), "Expected 1 request to the schema registry, not %v", requests)
+}
+
+func TestKafkaJSONClient_processOffsetMessage_Protobuf(t *testing.T) {
+	var requests int32
+	registry := fixtureSchemaRegistry(&requests)
+	defer registry.Close()
+
+	module := fixtureJSONModule()
+	viper.Set("schema-registry.test.url", registry.URL)
+	viper.Set("consumer.test.value-format", "schema-registry")
+	viper.Set("consumer.test.schema-registry", "test")
+	viper.Set("consumer.test.group-field", "1")
+	viper.Set("consumer.test.topic-field", "2.1")
+	viper.Set("consumer.test.partition-field", "2.2")
+	viper.Set("consumer.test.offset-field", "3")
+	module.Configure("test", "consumer.test")
+
+	// Group testgroup, source message with topic testtopic and partition 3, and offset 1234
+	msg := &sarama.ConsumerMessage{
+		Value:     []byte("\x00\x00\x00\x00\x02\x00\x0a\x09testgroup\x12\x0d\x0a\x09testtopic\x10\x03\x18\xd2\x09"),
+		Timestamp: time.Unix(1500000000, 0),
+	}
+
+	go module.processOffsetMessage(msg)
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, "testgroup", request.Group, "Expected request sent with Group testgroup, not %v", request.Group)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(3), request.Partition, "Expected request sent with partition 3, not %v", request.Partition)
+	assert.Equalf(t, int64(1234), request.Offset, "Expected request sent with Offset 1234, not %v", request.Offset)
+}
+
+func TestKafkaJSONClient_decodeOffsetMessage_UnknownSchema(t *testing.T) {
+	var requests int32
+	registry := fixtureSchemaRegistry(&requests)
+	defer registry.Close()
+
+	module := fixtureJSONModule()
+	viper.Set("schema-registry.test.url", registry.URL)
+	viper.Set("consumer.test.value-format", "schema-registry")
+	viper.Set("consumer.test.schema-registry", "test")
+	module.Configure("test", "consumer.test")
+
+	_, errorAt := module.decodeOffsetMessage(&sarama.ConsumerMessage{Value: []byte("\x00\x00\x00\x00\x03{}")})
+	assert.Equalf(t, "value", errorAt, "Expected errorAt to be value, not %v", errorAt)
+}
+
+func TestKafkaJSONClient_Configure_BadValueFormat(t *testing.T) {
+	module := fixtureJSONModule()
+	viper.Set("consumer.test.value-format", "xml")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
@@ -0,0 +1,315 @@
+// +build !no_mm2_checkpoint
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func init() {
+	helpers.RegisterModuleClass("consumer", "mm2_checkpoint", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return &MM2CheckpointClient{
+			App: app,
+			Log: log,
+		}
+	})
+	helpers.RegisterConfigKeys("consumer.*", "mm2_checkpoint", append([]helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "source-cluster", Type: helpers.ConfigTypeString},
+		{Name: "checkpoints-topic", Type: helpers.ConfigTypeString},
+		{Name: "group-prefix", Type: helpers.ConfigTypeString},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
+
+// MM2CheckpointClient is a consumer module which reads the checkpoints topic that MirrorMaker 2 writes to the target
+// cluster of a replication flow. Each checkpoint translates the committed offset of a consumer group on the source
+// cluster to the equivalent offset in the replicated topic on the target cluster. These offsets are stored as a
+// consumer group on the target cluster, so the lag shows how far behind the group would be if it failed over.
+//
+// The replicated groups are named with a prefix, which defaults to the source cluster alias and a period (the same way
+// MirrorMaker 2 names replicated topics), so they are not confused with groups that are running on the target cluster.
+type MM2CheckpointClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name             string
+	cluster          string
+	servers          []string
+	sourceCluster    string
+	checkpointsTopic string
+	groupPrefix      string
+	saramaConfig     *sarama.Config
+	filter           *helpers.ConsumerFilter
+
+	client      helpers.SaramaClient
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+}
+
+type mm2Checkpoint struct {
+	Group            string
+	Topic            string
+	Partition        int32
+	UpstreamOffset   int64
+	DownstreamOffset int64
+}
+
+// Configure validates the configuration for the consumer. There must be a cluster name, which is the target cluster
+// of the MirrorMaker 2 replication flow, as well as a list of servers for that cluster, of the form host:port. The
+// source-cluster is the alias of the source cluster in the MirrorMaker 2 configuration, and it is used to derive the
+// default checkpoints topic (<source>.checkpoints.internal) and group prefix (<source>.). If the cluster name is
+// unknown, the source cluster alias is missing, or if the server list is missing or invalid, this func will panic.
+func (module *MM2CheckpointClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	module.sourceCluster = viper.GetString(configRoot + ".source-cluster")
+	if module.sourceCluster == "" {
+		panic("Consumer '" + name + "' must have a source-cluster alias")
+	}
+
+	profile := viper.GetString(configRoot + ".client-profile")
+	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, module.cluster, module.saramaConfig)
+
+	module.servers = viper.GetStringSlice(configRoot + ".servers")
+	if len(module.servers) == 0 {
+		panic("No Kafka brokers specified for consumer " + module.name)
+	} else if !helpers.ValidateHostList(module.servers) {
+		panic("Consumer '" + name + "' has one or more improperly formatted servers (must be host:port)")
+	}
+
+	// Set defaults for configs if needed, and get them
+	viper.SetDefault(configRoot+".checkpoints-topic", module.sourceCluster+".checkpoints.internal")
+	viper.SetDefault(configRoot+".group-prefix", module.sourceCluster+".")
+	module.checkpointsTopic = viper.GetString(configRoot + ".checkpoints-topic")
+	module.groupPrefix = viper.GetString(configRoot + ".group-prefix")
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
+	}
+	module.filter = filter
+}
+
+// Start connects to the target Kafka cluster using the Shopify/sarama client. Any error connecting to the cluster is
+// returned to the caller. Once the client is set up, consumers for each partition of the checkpoints topic are started,
+// reading the topic from the beginning.
+func (module *MM2CheckpointClient) Start() error {
+	module.Log.Info("starting")
+
+	// Connect Kafka client
+	client, err := helpers.NewSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		module.Log.Error("failed to start client", zap.Error(err))
+		return err
+	}
+	module.client = &helpers.BurrowSaramaClient{Client: client}
+
+	err = module.startCheckpointConsumer(module.client)
+	if err != nil {
+		module.Log.Error("failed to start consumer", zap.Error(err))
+		client.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Stop closes the goroutines that listen to the checkpoints topic, and then closes the client.
+func (module *MM2CheckpointClient) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	module.client.Close()
+
+	return nil
+}
+
+func (module *MM2CheckpointClient) startCheckpointConsumer(client helpers.SaramaClient) error {
+	consumer, err := client.NewConsumerFromClient()
+	if err != nil {
+		return err
+	}
+
+	partitions, err := client.Partitions(module.checkpointsTopic)
+	if err != nil {
+		module.Log.Error("failed to get partition count",
+			zap.String("topic", module.checkpointsTopic),
+			zap.String("error", err.Error()),
+		)
+		return err
+	}
+
+	module.Log.Info("starting consumers",
+		zap.String("topic", module.checkpointsTopic),
+		zap.Int("count", len(partitions)),
+	)
+	for _, partition := range partitions {
+		pconsumer, err := consumer.ConsumePartition(module.checkpointsTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			module.Log.Error("failed to consume partition",
+				zap.String("topic", module.checkpointsTopic),
+				zap.Int32("partition", partition),
+				zap.String("error", err.Error()),
+			)
+			return err
+		}
+		module.running.Add(1)
+		go module.partitionConsumer(pconsumer)
+	}
+	return nil
+}
+
+func (module *MM2CheckpointClient) partitionConsumer(consumer sarama.PartitionConsumer) {
+	defer module.running.Done()
+	defer consumer.AsyncClose()
+
+	for {
+		select {
+		case msg := <-consumer.Messages():
+			module.processCheckpointMessage(msg)
+		case err := <-consumer.Errors():
+			module.Log.Error("consume error",
+				zap.String("topic", err.Topic),
+				zap.Int32("partition", err.Partition),
+				zap.String("error", err.Err.Error()),
+			)
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *MM2CheckpointClient) acceptConsumerGroup(group string) bool {
+	return module.filter.AcceptGroup(group)
+}
+
+func (module *MM2CheckpointClient) processCheckpointMessage(msg *sarama.ConsumerMessage) {
+	logger := module.Log.With(
+		zap.String("checkpoint_topic", msg.Topic),
+		zap.Int32("checkpoint_partition", msg.Partition),
+		zap.Int64("checkpoint_offset", msg.Offset),
+	)
+
+	if len(msg.Value) == 0 {
+		logger.Debug("dropped tombstone")
+		return
+	}
+
+	checkpoint, errorAt := decodeCheckpoint(msg.Key, msg.Value)
+	if errorAt != "" {
+		logger.Warn("failed to decode",
+			zap.String("reason", errorAt),
+		)
+		return
+	}
+
+	if !module.acceptConsumerGroup(checkpoint.Group) {
+		logger.Debug("dropped", zap.String("reason", "allowlist"))
+		return
+	}
+	if !module.filter.AcceptTopic(checkpoint.Topic) {
+		logger.Debug("dropped", zap.String("reason", "topic filter"))
+		return
+	}
+
+	// The checkpoint is written when the source group commits, so its timestamp is the best commit time we have
+	timestamp := msg.Timestamp.UnixNano() / int64(time.Millisecond)
+	if msg.Timestamp.IsZero() {
+		timestamp = time.Now().Unix() * 1000
+	}
+
+	logger.Debug("checkpoint",
+		zap.String("group", checkpoint.Group),
+		zap.String("topic", checkpoint.Topic),
+		zap.Int32("partition", checkpoint.Partition),
+		zap.Int64("upstream_offset", checkpoint.UpstreamOffset),
+		zap.Int64("downstream_offset", checkpoint.DownstreamOffset),
+	)
+	helpers.TimeoutSendStorageRequest(module.App.StorageChannel, protocol.AcquireStorageRequest(protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     module.cluster,
+		Topic:       checkpoint.Topic,
+		Partition:   checkpoint.Partition,
+		Group:       module.groupPrefix + checkpoint.Group,
+		Timestamp:   timestamp,
+		Offset:      checkpoint.DownstreamOffset,
+		Order:       msg.Offset,
+	}), 1)
+}
+
+// decodeCheckpoint decodes a MirrorMaker 2 checkpoint record. The key is the group, topic (as named on the target
+// cluster), and partition. The value is a version, followed by the upstream offset, downstream offset, and metadata
+func decodeCheckpoint(key, value []byte) (mm2Checkpoint, string) {
+	var err error
+	checkpoint := mm2Checkpoint{}
+
+	keyBuffer := bytes.NewBuffer(key)
+	checkpoint.Group, err = readString(keyBuffer)
+	if err != nil {
+		return checkpoint, "group"
+	}
+	checkpoint.Topic, err = readString(keyBuffer)
+	if err != nil {
+		return checkpoint, "topic"
+	}
+	err = binary.Read(keyBuffer, binary.BigEndian, &checkpoint.Partition)
+	if err != nil {
+		return checkpoint, "partition"
+	}
+
+	var version int16
+	valueBuffer := bytes.NewBuffer(value)
+	err = binary.Read(valueBuffer, binary.BigEndian, &version)
+	if err != nil {
+		return checkpoint, "no value version"
+	}
+	if version != 0 {
+		return checkpoint, "value version"
+	}
+	err = binary.Read(valueBuffer, binary.BigEndian, &checkpoint.UpstreamOffset)
+	if err != nil {
+		return checkpoint, "upstream offset"
+	}
+	err = binary.Read(valueBuffer, binary.BigEndian, &checkpoint.DownstreamOffset)
+	if err != nil {
+		return checkpoint, "downstream offset"
+	}
+	return checkpoint, ""
+}
@@ -0,0 +1,182 @@
+// +build !no_mm2_checkpoint
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureMM2Module() *MM2CheckpointClient {
+	module := MM2CheckpointClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("cluster.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.class-name", "mm2_checkpoint")
+	viper.Set("consumer.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.source-cluster", "primary")
+
+	return &module
+}
+
+// A checkpoint for group testgroup, topic primary.testtopic, partition 3, with upstream offset 1000 and downstream
+// offset 800
+var testCheckpointKey = []byte("\x00\x09testgroup\x00\x11primary.testtopic\x00\x00\x00\x03")
+var testCheckpointValue = []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x03\xe8\x00\x00\x00\x00\x00\x00\x03\x20\x00\x00")
+
+func TestMM2CheckpointClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(MM2CheckpointClient))
+}
+
+func TestMM2CheckpointClient_Configure(t *testing.T) {
+	module := fixtureMM2Module()
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, "primary.checkpoints.internal", module.checkpointsTopic, "Default checkpoints-topic value of primary.checkpoints.internal did not get set, got %v", module.checkpointsTopic)
+	assert.Equalf(t, "primary.", module.groupPrefix, "Default group-prefix value of primary. did not get set, got %v", module.groupPrefix)
+}
+
+func TestMM2CheckpointClient_Configure_NoSourceCluster(t *testing.T) {
+	module := fixtureMM2Module()
+	viper.Set("consumer.test.source-cluster", "")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestMM2CheckpointClient_Configure_BadCluster(t *testing.T) {
+	module := fixtureMM2Module()
+	viper.Set("consumer.test.cluster", "nocluster")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestDecodeCheckpoint(t *testing.T) {
+	checkpoint, errorAt := decodeCheckpoint(testCheckpointKey, testCheckpointValue)
+
+	assert.Equalf(t, "", errorAt, "Expected decodeCheckpoint to return empty errorAt, not %v", errorAt)
+	assert.Equalf(t, "testgroup", checkpoint.Group, "Expected Group to be testgroup, not %v", checkpoint.Group)
+	assert.Equalf(t, "primary.testtopic", checkpoint.Topic, "Expected Topic to be primary.testtopic, not %v", checkpoint.Topic)
+	assert.Equalf(t, int32(3), checkpoint.Partition, "Expected Partition to be 3, not %v", checkpoint.Partition)
+	assert.Equalf(t, int64(1000), checkpoint.UpstreamOffset, "Expected UpstreamOffset to be 1000, not %v", checkpoint.UpstreamOffset)
+	assert.Equalf(t, int64(800), checkpoint.DownstreamOffset, "Expected DownstreamOffset to be 800, not %v", checkpoint.DownstreamOffset)
+}
+
+var decodeCheckpointErrors = []struct {
+	Key     []byte
+	Value   []byte
+	ErrorAt string
+}{
+	{[]byte("\x00\x09test"), testCheckpointValue, "group"},
+	{[]byte("\x00\x09testgroup\x00\x11primary"), testCheckpointValue, "topic"},
+	{[]byte("\x00\x09testgroup\x00\x11primary.testtopic\x00"), testCheckpointValue, "partition"},
+	{testCheckpointKey, []byte("\x00"), "no value version"},
+	{testCheckpointKey, []byte("\x00\x01\x00\x00\x00\x00\x00\x00\x03\xe8"), "value version"},
+	{testCheckpointKey, []byte("\x00\x00\x00\x00\x00\x00"), "upstream offset"},
+	{testCheckpointKey, []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x03\xe8\x00\x00"), "downstream offset"},
+}
+
+func TestDecodeCheckpoint_Errors(t *testing.T) {
+	for _, values := range decodeCheckpointErrors {
+		_, errorAt := decodeCheckpoint(values.Key, values.Value)
+		assert.Equalf(t, values.ErrorAt, errorAt, "Expected errorAt to be %v, not %v", values.ErrorAt, errorAt)
+	}
+}
+
+func TestMM2CheckpointClient_processCheckpointMessage(t *testing.T) {
+	module := fixtureMM2Module()
+	module.Configure("test", "consumer.test")
+
+	msg := &sarama.ConsumerMessage{
+		Key:       testCheckpointKey,
+		Value:     testCheckpointValue,
+		Topic:     "primary.checkpoints.internal",
+		Partition: 0,
+		Offset:    42,
+		Timestamp: time.Unix(1600000000, 0),
+	}
+
+	go module.processCheckpointMessage(msg)
+	request := <-module.App.StorageChannel
+
+	assert.Equalf(t, protocol.StorageSetConsumerOffset, request.RequestType, "Expected request sent with type StorageSetConsumerOffset, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Equalf(t, "primary.testgroup", request.Group, "Expected request sent with Group primary.testgroup, not %v", request.Group)
+	assert.Equalf(t, "primary.testtopic", request.Topic, "Expected request sent with topic primary.testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(3), request.Partition, "Expected request sent with partition 3, not %v", request.Partition)
+	assert.Equalf(t, int64(800), request.Offset, "Expected request sent with Offset 800, not %v", request.Offset)
+	assert.Equalf(t, int64(1600000000000), request.Timestamp, "Expected request sent with Timestamp 1600000000000, not %v", request.Timestamp)
+	assert.Equalf(t, int64(42), request.Order, "Expected request sent with Order 42, not %v", request.Order)
+}
+
+func TestMM2CheckpointClient_processCheckpointMessage_Denylist(t *testing.T) {
+	module := fixtureMM2Module()
+	viper.Set("consumer.test.group-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the group should be dropped by the denylist
+	module.processCheckpointMessage(&sarama.ConsumerMessage{Key: testCheckpointKey, Value: testCheckpointValue})
+}
+
+func TestMM2CheckpointClient_processCheckpointMessage_TopicDenylist(t *testing.T) {
+	module := fixtureMM2Module()
+	viper.Set("consumer.test.topic-denylist", "^test.*$")
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the topic should be dropped by the denylist
+	module.processCheckpointMessage(&sarama.ConsumerMessage{Key: testCheckpointKey, Value: testCheckpointValue})
+}
+
+func TestMM2CheckpointClient_startCheckpointConsumer(t *testing.T) {
+	module := fixtureMM2Module()
+	module.Configure("test", "consumer.test")
+
+	// Channels for testing
+	messageChan := make(chan *sarama.ConsumerMessage)
+	errorChan := make(chan *sarama.ConsumerError)
+
+	// Don't assert expectations on this - the way it goes down, they're called but don't show up
+	mockPartitionConsumer := &helpers.MockSaramaPartitionConsumer{}
+	mockPartitionConsumer.On("AsyncClose").Return()
+	mockPartitionConsumer.On("Messages").Return(func() <-chan *sarama.ConsumerMessage { return messageChan }())
+	mockPartitionConsumer.On("Errors").Return(func() <-chan *sarama.ConsumerError { return errorChan }())
+
+	consumer := &helpers.MockSaramaConsumer{}
+	consumer.On("ConsumePartition", "primary.checkpoints.internal", int32(0), sarama.OffsetOldest).Return(mockPartitionConsumer, nil)
+
+	client := &helpers.MockSaramaClient{}
+	client.On("NewConsumerFromClient").Return(consumer, nil)
+	client.On("Partitions", "primary.checkpoints.internal").Return([]int32{0}, nil)
+
+	err := module.startCheckpointConsumer(client)
+	assert.Nil(t, err, "Expected startCheckpointConsumer to return no error")
+
+	close(module.quitChannel)
+	module.running.Wait()
+
+	consumer.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
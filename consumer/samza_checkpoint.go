@@ -0,0 +1,329 @@
+// +build !no_samza_checkpoint
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func init() {
+	helpers.RegisterModuleClass("consumer", "samza_checkpoint", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return &SamzaCheckpointClient{
+			App: app,
+			Log: log,
+		}
+	})
+	helpers.RegisterConfigKeys("consumer.*", "samza_checkpoint", append([]helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "job-name", Type: helpers.ConfigTypeString},
+		{Name: "job-id", Type: helpers.ConfigTypeString, Default: "1"},
+		{Name: "system", Type: helpers.ConfigTypeString, Default: "kafka"},
+		{Name: "checkpoint-topic", Type: helpers.ConfigTypeString},
+		{Name: "group", Type: helpers.ConfigTypeString},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
+
+// SamzaCheckpointClient is a consumer module which reads the checkpoint topic of an Apache Samza job. Samza does not
+// commit offsets to Kafka, so its jobs do not otherwise show up as consumer groups. Each checkpoint message holds the
+// offsets for every input partition of one task in the job, and these are stored as a single consumer group for the
+// job, so the job can be checked and alerted on like any other consumer.
+//
+// Samza checkpoints the offset of the last message that was processed, where Kafka consumers commit the offset of the
+// next message to be read, so 1 is added to each checkpointed offset to give the same lag as a Kafka consumer. Only
+// version 1 checkpoints (type "checkpoint" in the message key) are read, and only for the configured input system.
+type SamzaCheckpointClient struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name            string
+	cluster         string
+	servers         []string
+	checkpointTopic string
+	group           string
+	system          string
+	saramaConfig    *sarama.Config
+	filter          *helpers.ConsumerFilter
+
+	client      helpers.SaramaClient
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+}
+
+type samzaCheckpointKey struct {
+	Type     string `json:"type"`
+	TaskName string `json:"taskName"`
+}
+
+type samzaCheckpointOffset struct {
+	System    string
+	Topic     string
+	Partition int32
+	Offset    int64
+}
+
+// Configure validates the configuration for the consumer. There must be a cluster name, which is the cluster that the
+// job reads its input from, as well as a list of servers for the cluster that has the checkpoint topic, of the form
+// host:port. The job-name (and job-id, which defaults to 1) are used to derive the default checkpoint topic name, the
+// same way that Samza does, and the job-name is the default group name. If the cluster name is unknown, the job name
+// is missing, or if the server list is missing or invalid, this func will panic.
+func (module *SamzaCheckpointClient) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+
+	module.cluster = viper.GetString(configRoot + ".cluster")
+	if !viper.IsSet("cluster." + module.cluster) {
+		panic("Consumer '" + name + "' references an unknown cluster '" + module.cluster + "'")
+	}
+
+	jobName := viper.GetString(configRoot + ".job-name")
+	if jobName == "" {
+		panic("Consumer '" + name + "' must have a job-name")
+	}
+
+	profile := viper.GetString(configRoot + ".client-profile")
+	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(profile)
+	helpers.RegisterClientMetrics(configRoot, module.cluster, module.saramaConfig)
+
+	module.servers = viper.GetStringSlice(configRoot + ".servers")
+	if len(module.servers) == 0 {
+		panic("No Kafka brokers specified for consumer " + module.name)
+	} else if !helpers.ValidateHostList(module.servers) {
+		panic("Consumer '" + name + "' has one or more improperly formatted servers (must be host:port)")
+	}
+
+	// Set defaults for configs if needed, and get them
+	viper.SetDefault(configRoot+".job-id", "1")
+	viper.SetDefault(configRoot+".group", jobName)
+	viper.SetDefault(configRoot+".system", "kafka")
+	viper.SetDefault(configRoot+".checkpoint-topic", samzaCheckpointTopic(jobName, viper.GetString(configRoot+".job-id")))
+	module.checkpointTopic = viper.GetString(configRoot + ".checkpoint-topic")
+	module.group = viper.GetString(configRoot + ".group")
+	module.system = viper.GetString(configRoot + ".system")
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
+	}
+	module.filter = filter
+}
+
+// samzaCheckpointTopic returns the name of the checkpoint topic that Samza uses for a job
+func samzaCheckpointTopic(jobName, jobID string) string {
+	return "__samza_checkpoint_ver_1_for_" + strings.Replace(jobName, "_", "-", -1) + "_" + strings.Replace(jobID, "_", "-", -1)
+}
+
+// Start connects to the Kafka cluster using the Shopify/sarama client. Any error connecting to the cluster is returned
+// to the caller. Once the client is set up, consumers for each partition of the checkpoint topic are started, reading
+// the topic from the beginning.
+func (module *SamzaCheckpointClient) Start() error {
+	module.Log.Info("starting")
+
+	// Connect Kafka client
+	client, err := helpers.NewSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		module.Log.Error("failed to start client", zap.Error(err))
+		return err
+	}
+	module.client = &helpers.BurrowSaramaClient{Client: client}
+
+	err = module.startCheckpointConsumer(module.client)
+	if err != nil {
+		module.Log.Error("failed to start consumer", zap.Error(err))
+		client.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Stop closes the goroutines that listen to the checkpoint topic, and then closes the client.
+func (module *SamzaCheckpointClient) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	module.client.Close()
+
+	return nil
+}
+
+func (module *SamzaCheckpointClient) startCheckpointConsumer(client helpers.SaramaClient) error {
+	consumer, err := client.NewConsumerFromClient()
+	if err != nil {
+		return err
+	}
+
+	partitions, err := client.Partitions(module.checkpointTopic)
+	if err != nil {
+		module.Log.Error("failed to get partition count",
+			zap.String("topic", module.checkpointTopic),
+			zap.String("error", err.Error()),
+		)
+		return err
+	}
+
+	module.Log.Info("starting consumers",
+		zap.String("topic", module.checkpointTopic),
+		zap.Int("count", len(partitions)),
+	)
+	for _, partition := range partitions {
+		pconsumer, err := consumer.ConsumePartition(module.checkpointTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			module.Log.Error("failed to consume partition",
+				zap.String("topic", module.checkpointTopic),
+				zap.Int32("partition", partition),
+				zap.String("error", err.Error()),
+			)
+			return err
+		}
+		module.running.Add(1)
+		go module.partitionConsumer(pconsumer)
+	}
+	return nil
+}
+
+func (module *SamzaCheckpointClient) partitionConsumer(consumer sarama.PartitionConsumer) {
+	defer module.running.Done()
+	defer consumer.AsyncClose()
+
+	for {
+		select {
+		case msg := <-consumer.Messages():
+			module.processCheckpointMessage(msg)
+		case err := <-consumer.Errors():
+			module.Log.Error("consume error",
+				zap.String("topic", err.Topic),
+				zap.Int32("partition", err.Partition),
+				zap.String("error", err.Err.Error()),
+			)
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+func (module *SamzaCheckpointClient) processCheckpointMessage(msg *sarama.ConsumerMessage) {
+	logger := module.Log.With(
+		zap.String("checkpoint_topic", msg.Topic),
+		zap.Int32("checkpoint_partition", msg.Partition),
+		zap.Int64("checkpoint_offset", msg.Offset),
+	)
+
+	if len(msg.Value) == 0 {
+		logger.Debug("dropped tombstone")
+		return
+	}
+
+	var key samzaCheckpointKey
+	if err := json.Unmarshal(msg.Key, &key); err != nil {
+		logger.Warn("failed to decode", zap.String("reason", "key"))
+		return
+	}
+	if key.Type != "checkpoint" {
+		// Other message types, such as changelog partition mappings, do not have offsets
+		logger.Debug("dropped", zap.String("reason", "message type"), zap.String("type", key.Type))
+		return
+	}
+	logger = logger.With(zap.String("task", key.TaskName))
+
+	if !module.filter.AcceptGroup(module.group) {
+		logger.Debug("dropped", zap.String("reason", "allowlist"))
+		return
+	}
+
+	offsets, errorAt := decodeSamzaCheckpoint(msg.Value)
+	if errorAt != "" {
+		logger.Warn("failed to decode", zap.String("reason", errorAt))
+		return
+	}
+
+	// The checkpoint is written when the task commits, so its timestamp is the commit time
+	timestamp := msg.Timestamp.UnixNano() / int64(time.Millisecond)
+	if msg.Timestamp.IsZero() {
+		timestamp = time.Now().Unix() * 1000
+	}
+
+	for _, offset := range offsets {
+		if (offset.System != module.system) || (!module.filter.AcceptTopic(offset.Topic)) {
+			continue
+		}
+
+		logger.Debug("checkpoint",
+			zap.String("topic", offset.Topic),
+			zap.Int32("partition", offset.Partition),
+			zap.Int64("offset", offset.Offset),
+		)
+		helpers.TimeoutSendStorageRequest(module.App.StorageChannel, protocol.AcquireStorageRequest(protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     module.cluster,
+			Topic:       offset.Topic,
+			Partition:   offset.Partition,
+			Group:       module.group,
+			Timestamp:   timestamp,
+			Offset:      offset.Offset + 1,
+			Order:       msg.Offset,
+		}), 1)
+	}
+}
+
+// decodeSamzaCheckpoint decodes the value of a Samza checkpoint message, which is a JSON object with an entry for each
+// input partition of the task. Each entry is an object with the system, stream, partition, and offset as strings. If
+// an entry cannot be decoded, the name of the field that failed is returned
+func decodeSamzaCheckpoint(value []byte) ([]samzaCheckpointOffset, string) {
+	var entries map[string]map[string]string
+	if err := json.Unmarshal(value, &entries); err != nil {
+		return nil, "value"
+	}
+
+	offsets := make([]samzaCheckpointOffset, 0, len(entries))
+	for _, entry := range entries {
+		partition, err := strconv.ParseInt(entry["partition"], 10, 32)
+		if err != nil {
+			return nil, "partition"
+		}
+		offset, err := strconv.ParseInt(entry["offset"], 10, 64)
+		if err != nil {
+			return nil, "offset"
+		}
+		if entry["stream"] == "" {
+			return nil, "stream"
+		}
+		offsets = append(offsets, samzaCheckpointOffset{
+			System:    entry["system"],
+			Topic:     entry["stream"],
+			Partition: int32(partition),
+			Offset:    offset,
+		})
+	}
+	return offsets, ""
+}
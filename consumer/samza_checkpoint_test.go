@@ -0,0 +1,134 @@
+// +build !no_samza_checkpoint
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureSamzaModule() *SamzaCheckpointClient {
+	module := SamzaCheckpointClient{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{
+		StorageChannel: make(chan *protocol.StorageRequest),
+	}
+
+	viper.Reset()
+	viper.Set("cluster.test.class-name", "kafka")
+	viper.Set("cluster.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.class-name", "samza_checkpoint")
+	viper.Set("consumer.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("consumer.test.cluster", "test")
+	viper.Set("consumer.test.job-name", "page_view_counter")
+
+	return &module
+}
+
+var testSamzaCheckpointKey = []byte(`{"systemstreampartition-grouper-factory":"org.apache.samza.container.grouper.stream.GroupByPartitionFactory","taskName":"Partition 3","type":"checkpoint"}`)
+var testSamzaCheckpointValue = []byte(`{"SystemStreamPartition [kafka, testtopic, 3]":{"system":"kafka","partition":"3","offset":"1234","stream":"testtopic"},` +
+	`"SystemStreamPartition [hdfs, files, 3]":{"system":"hdfs","partition":"3","offset":"99","stream":"files"}}`)
+
+func TestSamzaCheckpointClient_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(SamzaCheckpointClient))
+}
+
+func TestSamzaCheckpointClient_Configure(t *testing.T) {
+	module := fixtureSamzaModule()
+	module.Configure("test", "consumer.test")
+	assert.Equalf(t, "__samza_checkpoint_ver_1_for_page-view-counter_1", module.checkpointTopic, "Default checkpoint-topic did not get set, got %v", module.checkpointTopic)
+	assert.Equalf(t, "page_view_counter", module.group, "Default group value of page_view_counter did not get set, got %v", module.group)
+	assert.Equalf(t, "kafka", module.system, "Default system value of kafka did not get set, got %v", module.system)
+}
+
+func TestSamzaCheckpointClient_Configure_NoJobName(t *testing.T) {
+	module := fixtureSamzaModule()
+	viper.Set("consumer.test.job-name", "")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestSamzaCheckpointClient_Configure_BadCluster(t *testing.T) {
+	module := fixtureSamzaModule()
+	viper.Set("consumer.test.cluster", "nocluster")
+
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestDecodeSamzaCheckpoint_BadOffset(t *testing.T) {
+	_, errorAt := decodeSamzaCheckpoint([]byte(`{"a":{"system":"kafka","partition":"0","offset":"x","stream":"testtopic"}}`))
+	assert.Equalf(t, "offset", errorAt, "Expected decodeSamzaCheckpoint to fail at offset, not %v", errorAt)
+
+	_, errorAt = decodeSamzaCheckpoint([]byte(`[]`))
+	assert.Equalf(t, "value", errorAt, "Expected decodeSamzaCheckpoint to fail at value, not %v", errorAt)
+}
+
+func TestSamzaCheckpointClient_processCheckpointMessage(t *testing.T) {
+	module := fixtureSamzaModule()
+	module.Configure("test", "consumer.test")
+
+	// Only the partition for the kafka system should be sent
+	go func() {
+		module.processCheckpointMessage(&sarama.ConsumerMessage{
+			Key:       testSamzaCheckpointKey,
+			Value:     testSamzaCheckpointValue,
+			Offset:    42,
+			Timestamp: time.Unix(1500000000, 0),
+		})
+		close(module.App.StorageChannel)
+	}()
+
+	requests := make([]*protocol.StorageRequest, 0)
+	for request := range module.App.StorageChannel {
+		requests = append(requests, request)
+	}
+	assert.Lenf(t, requests, 1, "Expected one request to be sent, not %v", len(requests))
+
+	request := requests[0]
+	assert.Equalf(t, protocol.StorageSetConsumerOffset, request.RequestType, "Expected request sent with type StorageSetConsumerOffset, not %v", request.RequestType)
+	assert.Equalf(t, "test", request.Cluster, "Expected request sent with cluster test, not %v", request.Cluster)
+	assert.Equalf(t, "testtopic", request.Topic, "Expected request sent with topic testtopic, not %v", request.Topic)
+	assert.Equalf(t, int32(3), request.Partition, "Expected request sent with partition 3, not %v", request.Partition)
+	assert.Equalf(t, "page_view_counter", request.Group, "Expected request sent with group page_view_counter, not %v", request.Group)
+	assert.Equalf(t, int64(1235), request.Offset, "Expected request sent with offset 1235, not %v", request.Offset)
+	assert.Equalf(t, int64(1500000000000), request.Timestamp, "Expected request sent with timestamp 1500000000000, not %v", request.Timestamp)
+	assert.Equalf(t, int64(42), request.Order, "Expected request sent with Order 42, not %v", request.Order)
+}
+
+func TestSamzaCheckpointClient_processCheckpointMessage_OtherType(t *testing.T) {
+	module := fixtureSamzaModule()
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the message is not a checkpoint
+	module.processCheckpointMessage(&sarama.ConsumerMessage{
+		Key:   []byte(`{"systemstreampartition-grouper-factory":"","taskName":"Partition 3","type":"set-changelog"}`),
+		Value: testSamzaCheckpointValue,
+	})
+}
+
+func TestSamzaCheckpointClient_processCheckpointMessage_Denylist(t *testing.T) {
+	module := fixtureSamzaModule()
+	viper.Set("consumer.test.group-denylist", "^page.*$")
+	module.Configure("test", "consumer.test")
+
+	// Should not timeout as the group should be dropped by the denylist
+	module.processCheckpointMessage(&sarama.ConsumerMessage{Key: testSamzaCheckpointKey, Value: testSamzaCheckpointValue})
+}
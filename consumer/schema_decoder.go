@@ -0,0 +1,367 @@
+// +build !no_kafka_json
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// This file contains the decoders for values that are serialized using the Confluent Schema Registry wire format. The
+// decoded values use the same types as JSON decoded with UseNumber (maps, slices, strings, and json.Number), so that
+// the field paths configured for the kafka_json module work the same way for all formats.
+//
+// Avro values are decoded using the writer schema from the registry, so fields are addressed by name. Protobuf schemas
+// are not parsed, so fields in Protobuf values are addressed by field number instead (for example, "1.3" for field 3
+// of the message in field 1). Length-delimited Protobuf fields are kept as bytes, and are decoded as a message only if
+// the field path continues into them.
+
+var errSchemaDecode = errors.New("value does not match schema")
+
+// splitSchemaRegistryValue splits a value in the Schema Registry wire format into the schema ID and the serialized
+// payload. The format is a zero magic byte, followed by the schema ID as a 4-byte integer
+func splitSchemaRegistryValue(value []byte) (int32, []byte, error) {
+	if (len(value) < 5) || (value[0] != 0) {
+		return 0, nil, errors.New("missing schema registry header")
+	}
+	return int32(binary.BigEndian.Uint32(value[1:5])), value[5:], nil
+}
+
+// stripProtobufMessageIndexes removes the list of message indexes that precedes a Protobuf payload in the Schema
+// Registry wire format. The list is a zigzag varint count, followed by that many zigzag varint indexes
+func stripProtobufMessageIndexes(payload []byte) ([]byte, error) {
+	buf := bytes.NewReader(payload)
+	count, err := binary.ReadVarint(buf)
+	if (err != nil) || (count < 0) {
+		return nil, errSchemaDecode
+	}
+	for i := int64(0); i < count; i++ {
+		if _, err := binary.ReadVarint(buf); err != nil {
+			return nil, errSchemaDecode
+		}
+	}
+	return payload[len(payload)-buf.Len():], nil
+}
+
+// decodeProtobufMessage decodes the fields of a Protobuf message in wire format into a map keyed by the field number.
+// If a field appears more than once, the last value is kept, as it is for scalar fields in Protobuf
+func decodeProtobufMessage(payload []byte) (map[string]interface{}, error) {
+	message := make(map[string]interface{})
+	buf := bytes.NewReader(payload)
+	for buf.Len() > 0 {
+		tag, err := binary.ReadUvarint(buf)
+		if err != nil {
+			return nil, errSchemaDecode
+		}
+		fieldNumber := strconv.FormatUint(tag>>3, 10)
+
+		switch tag & 0x7 {
+		case 0:
+			value, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return nil, errSchemaDecode
+			}
+			message[fieldNumber] = json.Number(strconv.FormatInt(int64(value), 10))
+		case 1:
+			var value int64
+			if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+				return nil, errSchemaDecode
+			}
+			message[fieldNumber] = json.Number(strconv.FormatInt(value, 10))
+		case 2:
+			length, err := binary.ReadUvarint(buf)
+			if (err != nil) || (length > uint64(buf.Len())) {
+				return nil, errSchemaDecode
+			}
+			value := make([]byte, length)
+			if _, err := io.ReadFull(buf, value); err != nil {
+				return nil, errSchemaDecode
+			}
+			message[fieldNumber] = value
+		case 5:
+			var value int32
+			if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+				return nil, errSchemaDecode
+			}
+			message[fieldNumber] = json.Number(strconv.FormatInt(int64(value), 10))
+		default:
+			// Groups are deprecated, and are not supported
+			return nil, errSchemaDecode
+		}
+	}
+	return message, nil
+}
+
+// avroSchema is a parsed Avro schema. Named types that are referenced more than once share the same avroSchema
+type avroSchema struct {
+	Type     string
+	Fields   []avroField
+	Items    *avroSchema
+	Values   *avroSchema
+	Branches []*avroSchema
+	Symbols  []string
+	Size     int
+}
+
+type avroField struct {
+	Name   string
+	Schema *avroSchema
+}
+
+// parseAvroSchema parses an Avro schema in its JSON form
+func parseAvroSchema(schema string) (*avroSchema, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(schema), &document); err != nil {
+		return nil, err
+	}
+	return parseAvroSchemaNode(document, "", make(map[string]*avroSchema))
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || (namespace == "") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func parseAvroSchemaNode(node interface{}, namespace string, names map[string]*avroSchema) (*avroSchema, error) {
+	switch typedNode := node.(type) {
+	case string:
+		switch typedNode {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{Type: typedNode}, nil
+		}
+		if named, ok := names[avroFullName(typedNode, namespace)]; ok {
+			return named, nil
+		}
+		if named, ok := names[typedNode]; ok {
+			return named, nil
+		}
+		return nil, errors.New("unknown Avro type " + typedNode)
+
+	case []interface{}:
+		schema := &avroSchema{Type: "union", Branches: make([]*avroSchema, len(typedNode))}
+		for i, branch := range typedNode {
+			branchSchema, err := parseAvroSchemaNode(branch, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			schema.Branches[i] = branchSchema
+		}
+		return schema, nil
+
+	case map[string]interface{}:
+		typeName, _ := typedNode["type"].(string)
+		if typeName == "" {
+			// The type may itself be a schema, such as {"type": {"type": "array", ...}}
+			return parseAvroSchemaNode(typedNode["type"], namespace, names)
+		}
+
+		schema := &avroSchema{Type: typeName}
+		switch typeName {
+		case "record", "error", "enum", "fixed":
+			if ns, ok := typedNode["namespace"].(string); ok {
+				namespace = ns
+			}
+			name, _ := typedNode["name"].(string)
+			if name == "" {
+				return nil, errors.New("Avro " + typeName + " has no name")
+			}
+			fullName := avroFullName(name, namespace)
+			if idx := strings.LastIndex(fullName, "."); idx != -1 {
+				namespace = fullName[:idx]
+			}
+
+			// Register the name before parsing fields, so that recursive types can refer to themselves
+			names[fullName] = schema
+		}
+
+		switch typeName {
+		case "record", "error":
+			schema.Type = "record"
+			fields, _ := typedNode["fields"].([]interface{})
+			for _, field := range fields {
+				fieldMap, ok := field.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("bad Avro record field")
+				}
+				fieldName, _ := fieldMap["name"].(string)
+				fieldSchema, err := parseAvroSchemaNode(fieldMap["type"], namespace, names)
+				if err != nil {
+					return nil, err
+				}
+				schema.Fields = append(schema.Fields, avroField{Name: fieldName, Schema: fieldSchema})
+			}
+		case "enum":
+			symbols, _ := typedNode["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				symbolName, _ := symbol.(string)
+				schema.Symbols = append(schema.Symbols, symbolName)
+			}
+		case "fixed":
+			size, _ := typedNode["size"].(float64)
+			if size < 0 {
+				return nil, errors.New("bad Avro fixed size")
+			}
+			schema.Size = int(size)
+		case "array":
+			items, err := parseAvroSchemaNode(typedNode["items"], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			schema.Items = items
+		case "map":
+			values, err := parseAvroSchemaNode(typedNode["values"], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			schema.Values = values
+		default:
+			// A primitive with attributes, such as a logical type
+			return parseAvroSchemaNode(typeName, namespace, names)
+		}
+		return schema, nil
+	}
+	return nil, errors.New("bad Avro schema")
+}
+
+// decodeAvroValue decodes an Avro binary value using the schema. Records and maps are decoded as maps, arrays as
+// slices, numbers as json.Number, and the value of a union is decoded without naming the branch
+func decodeAvroValue(schema *avroSchema, buf *bytes.Reader) (interface{}, error) {
+	switch schema.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		value, err := buf.ReadByte()
+		if err != nil {
+			return nil, errSchemaDecode
+		}
+		return value != 0, nil
+	case "int", "long":
+		value, err := binary.ReadVarint(buf)
+		if err != nil {
+			return nil, errSchemaDecode
+		}
+		return json.Number(strconv.FormatInt(value, 10)), nil
+	case "float":
+		var value uint32
+		if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+			return nil, errSchemaDecode
+		}
+		return json.Number(strconv.FormatFloat(float64(math.Float32frombits(value)), 'g', -1, 32)), nil
+	case "double":
+		var value uint64
+		if err := binary.Read(buf, binary.LittleEndian, &value); err != nil {
+			return nil, errSchemaDecode
+		}
+		return json.Number(strconv.FormatFloat(math.Float64frombits(value), 'g', -1, 64)), nil
+	case "bytes", "string":
+		length, err := binary.ReadVarint(buf)
+		if (err != nil) || (length < 0) || (length > int64(buf.Len())) {
+			return nil, errSchemaDecode
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(buf, value); err != nil {
+			return nil, errSchemaDecode
+		}
+		if schema.Type == "string" {
+			return string(value), nil
+		}
+		return value, nil
+	case "fixed":
+		value := make([]byte, schema.Size)
+		if _, err := io.ReadFull(buf, value); err != nil {
+			return nil, errSchemaDecode
+		}
+		return value, nil
+	case "enum":
+		index, err := binary.ReadVarint(buf)
+		if (err != nil) || (index < 0) || (index >= int64(len(schema.Symbols))) {
+			return nil, errSchemaDecode
+		}
+		return schema.Symbols[index], nil
+	case "union":
+		index, err := binary.ReadVarint(buf)
+		if (err != nil) || (index < 0) || (index >= int64(len(schema.Branches))) {
+			return nil, errSchemaDecode
+		}
+		return decodeAvroValue(schema.Branches[index], buf)
+	case "record":
+		record := make(map[string]interface{}, len(schema.Fields))
+		for _, field := range schema.Fields {
+			value, err := decodeAvroValue(field.Schema, buf)
+			if err != nil {
+				return nil, err
+			}
+			record[field.Name] = value
+		}
+		return record, nil
+	case "array":
+		array := make([]interface{}, 0)
+		err := readAvroBlocks(buf, func() error {
+			value, err := decodeAvroValue(schema.Items, buf)
+			array = append(array, value)
+			return err
+		})
+		return array, err
+	case "map":
+		values := make(map[string]interface{})
+		keySchema := &avroSchema{Type: "string"}
+		err := readAvroBlocks(buf, func() error {
+			key, err := decodeAvroValue(keySchema, buf)
+			if err != nil {
+				return err
+			}
+			value, err := decodeAvroValue(schema.Values, buf)
+			values[key.(string)] = value
+			return err
+		})
+		return values, err
+	}
+	return nil, errSchemaDecode
+}
+
+// readAvroBlocks reads the blocks of an Avro array or map, calling readItem for each item. Each block starts with a
+// count of items, and a negative count is followed by the size of the block in bytes. A count of zero ends the list
+func readAvroBlocks(buf *bytes.Reader, readItem func() error) error {
+	for {
+		count, err := binary.ReadVarint(buf)
+		if err != nil {
+			return errSchemaDecode
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(buf); err != nil {
+				return errSchemaDecode
+			}
+		}
+		if count > int64(buf.Len()) {
+			// Every item takes at least one byte, unless the schema is useless
+			return errSchemaDecode
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// +build !no_kafka_json
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAvroSchema = `{"type": "record", "name": "Checkpoint", "namespace": "com.example", "fields": [
+	{"name": "group", "type": "string"},
+	{"name": "source", "type": {"type": "record", "name": "Source", "fields": [
+		{"name": "topic", "type": "string"},
+		{"name": "partition", "type": "int"}
+	]}},
+	{"name": "offset", "type": ["null", "long"]},
+	{"name": "tags", "type": {"type": "map", "values": "string"}},
+	{"name": "history", "type": {"type": "array", "items": "long"}},
+	{"name": "state", "type": {"type": "enum", "name": "State", "symbols": ["RUNNING", "DONE"]}},
+	{"name": "previous", "type": ["null", "Source"]}
+]}`
+
+func avroLong(value int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutVarint(buf, value)]
+}
+
+func avroString(value string) []byte {
+	return append(avroLong(int64(len(value))), value...)
+}
+
+// encodeTestAvroCheckpoint encodes a value for testAvroSchema
+func encodeTestAvroCheckpoint() []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(avroString("testgroup"))
+	buf.Write(avroString("testtopic"))
+	buf.Write(avroLong(3))
+	buf.Write(avroLong(1))
+	buf.Write(avroLong(1234))
+	buf.Write(avroLong(1))
+	buf.Write(avroString("app"))
+	buf.Write(avroString("test"))
+	buf.Write(avroLong(0))
+	buf.Write(avroLong(-2))
+	buf.Write(avroLong(2))
+	buf.Write(avroLong(1000))
+	buf.Write(avroLong(1100))
+	buf.Write(avroLong(0))
+	buf.Write(avroLong(1))
+	buf.Write(avroLong(0))
+	return buf.Bytes()
+}
+
+func TestDecodeAvroValue(t *testing.T) {
+	schema, err := parseAvroSchema(testAvroSchema)
+	assert.Nil(t, err, "Expected parseAvroSchema to return no error")
+
+	value, err := decodeAvroValue(schema, bytes.NewReader(encodeTestAvroCheckpoint()))
+	assert.Nil(t, err, "Expected decodeAvroValue to return no error")
+
+	record := value.(map[string]interface{})
+	assert.Equalf(t, "testgroup", record["group"], "Expected group to be testgroup, not %v", record["group"])
+	assert.Equalf(t, map[string]interface{}{"topic": "testtopic", "partition": json.Number("3")}, record["source"], "Expected source record to be decoded, not %v", record["source"])
+	assert.Equalf(t, json.Number("1234"), record["offset"], "Expected offset to be 1234, not %v", record["offset"])
+	assert.Equalf(t, map[string]interface{}{"app": "test"}, record["tags"], "Expected tags map to be decoded, not %v", record["tags"])
+	assert.Equalf(t, []interface{}{json.Number("1000"), json.Number("1100")}, record["history"], "Expected history array to be decoded, not %v", record["history"])
+	assert.Equalf(t, "DONE", record["state"], "Expected state to be DONE, not %v", record["state"])
+	assert.Nilf(t, record["previous"], "Expected previous to be nil, not %v", record["previous"])
+}
+
+func TestDecodeAvroValue_Truncated(t *testing.T) {
+	schema, _ := parseAvroSchema(testAvroSchema)
+	value := encodeTestAvroCheckpoint()
+
+	_, err := decodeAvroValue(schema, bytes.NewReader(value[:len(value)-3]))
+	assert.NotNil(t, err, "Expected decodeAvroValue to return an error")
+}
+
+func TestParseAvroSchema_UnknownType(t *testing.T) {
+	_, err := parseAvroSchema(`{"type": "record", "name": "Test", "fields": [{"name": "a", "type": "Missing"}]}`)
+	assert.NotNil(t, err, "Expected parseAvroSchema to return an error")
+}
+
+func TestDecodeProtobufMessage(t *testing.T) {
+	// Field 1 is a string, field 2 is a message with varint field 1, and field 3 is a fixed64
+	payload := []byte("\x0a\x09testgroup\x12\x03\x08\xd2\x09\x19\x10\x00\x00\x00\x00\x00\x00\x00")
+
+	message, err := decodeProtobufMessage(payload)
+	assert.Nil(t, err, "Expected decodeProtobufMessage to return no error")
+	assert.Equalf(t, []byte("testgroup"), message["1"], "Expected field 1 to be testgroup, not %v", message["1"])
+	assert.Equalf(t, json.Number("16"), message["3"], "Expected field 3 to be 16, not %v", message["3"])
+
+	value, ok := lookupJSONField(message, []string{"2", "1"})
+	assert.True(t, ok, "Expected field 2.1 to be found")
+	assert.Equalf(t, json.Number("1234"), value, "Expected field 2.1 to be 1234, not %v", value)
+}
+
+func TestStripProtobufMessageIndexes(t *testing.T) {
+	payload, err := stripProtobufMessageIndexes([]byte("\x00\x08\x01"))
+	assert.Nil(t, err, "Expected stripProtobufMessageIndexes to return no error")
+	assert.Equalf(t, []byte("\x08\x01"), payload, "Expected payload to be 0801, not %x", payload)
+
+	payload, err = stripProtobufMessageIndexes([]byte("\x04\x02\x00\x08\x01"))
+	assert.Nil(t, err, "Expected stripProtobufMessageIndexes to return no error")
+	assert.Equalf(t, []byte("\x08\x01"), payload, "Expected payload to be 0801, not %x", payload)
+}
+
+func TestSplitSchemaRegistryValue(t *testing.T) {
+	schemaID, payload, err := splitSchemaRegistryValue([]byte("\x00\x00\x00\x01\x02{}"))
+	assert.Nil(t, err, "Expected splitSchemaRegistryValue to return no error")
+	assert.Equalf(t, int32(258), schemaID, "Expected schema ID to be 258, not %v", schemaID)
+	assert.Equalf(t, []byte("{}"), payload, "Expected payload to be {}, not %v", payload)
+
+	_, _, err = splitSchemaRegistryValue([]byte("{}"))
+	assert.NotNil(t, err, "Expected splitSchemaRegistryValue to return an error")
+}
@@ -20,21 +20,26 @@ package core
 
 import (
 	"os"
+	"syscall"
+	"time"
 
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	"github.com/linkedin/Burrow/cluster"
 	"github.com/linkedin/Burrow/consumer"
 	"github.com/linkedin/Burrow/evaluator"
+	"github.com/linkedin/Burrow/exporter"
 	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/httpserver"
 	"github.com/linkedin/Burrow/protocol"
+	"github.com/linkedin/Burrow/reporter"
 	"github.com/linkedin/Burrow/storage"
 )
 
-func newCoordinators(app *protocol.ApplicationContext) [5]protocol.Coordinator {
+func newCoordinators(app *protocol.ApplicationContext) [7]protocol.Coordinator {
 	// This order is important - it makes sure that the things taking requests start up before things sending requests
-	return [5]protocol.Coordinator{
+	return [7]protocol.Coordinator{
 		&storage.Coordinator{
 			App: app,
 			Log: app.Logger.With(
@@ -70,10 +75,24 @@ func newCoordinators(app *protocol.ApplicationContext) [5]protocol.Coordinator {
 				zap.String("name", "consumer"),
 			),
 		},
+		&reporter.Coordinator{
+			App: app,
+			Log: app.Logger.With(
+				zap.String("type", "coordinator"),
+				zap.String("name", "reporter"),
+			),
+		},
+		&exporter.Coordinator{
+			App: app,
+			Log: app.Logger.With(
+				zap.String("type", "coordinator"),
+				zap.String("name", "exporter"),
+			),
+		},
 	}
 }
 
-func configureCoordinators(app *protocol.ApplicationContext, coordinators [5]protocol.Coordinator) { // nolint:gocritic
+func configureCoordinators(app *protocol.ApplicationContext, coordinators [7]protocol.Coordinator) { // nolint:gocritic
 	// Configure methods are allowed to panic, as their errors are non-recoverable
 	// Catch panics here and flag in the application context if we can't continue
 	defer func() {
@@ -100,8 +119,9 @@ func configureCoordinators(app *protocol.ApplicationContext, coordinators [5]pro
 // logger based on configurations in viper.
 //
 // exitChannel is a signal channel that is provided by the calling application in order to signal Burrow to shut down.
-// Burrow does not currently check the signal type: if any message is received on the channel, or if the channel is
-// closed, Burrow will exit and Start will return 0.
+// If SIGHUP is received on the channel (or a request on the ReloadChannel in the ApplicationContext), the configuration
+// is reloaded, and if RestartSignal is received, a graceful restart is started (see the README for both). If any other
+// message is received on the channel, or if the channel is closed, Burrow will exit and Start will return 0.
 //
 // Start will return a 1 on any failure, including invalid configurations or a failure to start Burrow modules.
 func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
@@ -117,9 +137,27 @@ func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
 	// Set up a specific child logger for main
 	log := app.Logger.With(zap.String("type", "main"), zap.String("name", "burrow"))
 
+	// Warn about keys in the configuration that are misspelled or no longer used, as they are otherwise ignored
+	for _, warning := range helpers.CheckConfigKeys(viper.GetViper()) {
+		log.Warn(warning.Warning, zap.String("key", warning.Key))
+	}
+
 	// send sarama logs to zap
 	helpers.InitSaramaLogging(app.Logger)
 
+	// Start exporting traces, if enabled, before anything that can be traced is started
+	if err := helpers.CheckTracingConfig(); err != nil {
+		log.Error("invalid tracing configuration", zap.Error(err))
+		return 1
+	}
+	helpers.StartTracing(app.Logger)
+	defer helpers.StopTracing()
+
+	if err := helpers.CheckChannelConfig(); err != nil {
+		log.Error("invalid channel configuration", zap.Error(err))
+		return 1
+	}
+
 	// Set up an array of coordinators in the order they are to be loaded (and closed)
 	coordinators := newCoordinators(app)
 
@@ -129,14 +167,46 @@ func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
 	//   * The Notifiers send evaluation requests to the evaluator coordinator to check group status
 	//   * The Evaluators send requests to the storage coordinator for group offset and lag information
 	//   * The HTTP server sends requests to both the evaluator and storage coordinators to fulfill API requests
-	app.EvaluatorChannel = make(chan *protocol.EvaluatorRequest)
-	app.StorageChannel = make(chan *protocol.StorageRequest)
+	//   * The Reporters send requests to both the evaluator and storage coordinators to push consumer status to metrics
+	//
+	// The calling application may create these channels before calling Start, so that it can send requests as soon as
+	// the coordinators are started. Otherwise, they are created with the capacity in the general section
+	if app.EvaluatorChannel == nil {
+		app.EvaluatorChannel = helpers.MakeEvaluatorChannel()
+	}
+	if app.StorageChannel == nil {
+		app.StorageChannel = helpers.MakeStorageChannel()
+	}
+	if app.StorageEvaluationChannel == nil {
+		app.StorageEvaluationChannel = helpers.MakeStorageChannel()
+	}
+	if app.StorageAPIChannel == nil {
+		app.StorageAPIChannel = helpers.MakeStorageChannel()
+	}
+	if app.ReloadChannel == nil {
+		app.ReloadChannel = make(chan *protocol.ReloadRequest)
+	}
+
+	// Keep a copy of the configuration file as it was loaded, so that a reload can tell what has changed
+	loadedConfig, err := readConfigFile()
+	if err != nil {
+		log.Warn("failed to read configuration for reloads", zap.Error(err))
+	}
+
+	// Load the changes made through the HTTP server before the last restart
+	if err := helpers.LoadDynamicConfig(viper.GetString("general.dynamic-config-file")); err != nil {
+		log.Error("failed to load dynamic config file", zap.Error(err))
+		return 1
+	}
 
 	// Configure coordinators and exit if anything fails
 	configureCoordinators(app, coordinators)
 	if !app.ConfigurationValid {
 		return 1
 	}
+	for i, coordinator := range coordinators {
+		helpers.RegisterCoordinatorHealth(coordinatorNames[i], coordinator.Health)
+	}
 
 	// Start the coordinators in order
 	for i, coordinator := range coordinators {
@@ -150,9 +220,98 @@ func Start(app *protocol.ApplicationContext, exitChannel chan os.Signal) int {
 		}
 	}
 
-	// Wait until we're told to exit
-	<-exitChannel
+	// Secrets from external stores (such as Vault) are refreshed by reloading the configuration periodically, if enabled
+	var secretRefresh <-chan time.Time
+	if refresh := viper.GetInt("general.secret-refresh"); refresh > 0 {
+		ticker := time.NewTicker(time.Duration(refresh) * time.Second)
+		defer ticker.Stop()
+		secretRefresh = ticker.C
+	}
+
+	// The configuration file, and any other files given, are watched for changes if enabled
+	var configChanged <-chan struct{}
+	if viper.GetBool("general.watch-config") && (viper.ConfigFileUsed() != "") {
+		files := append([]string{viper.ConfigFileUsed()}, helpers.GetConfigIncludePatterns(viper.GetViper())...)
+		files = append(files, viper.GetStringSlice("general.watch-files")...)
+		watcher, err := newConfigWatcher(files, time.Second, log)
+		if err != nil {
+			log.Warn("failed to watch configuration files", zap.Error(err))
+		} else {
+			defer watcher.Close()
+			configChanged = watcher.Changed
+		}
+	}
+
+	// Let systemd know that a reload is in progress, so that systemctl reload waits for it to finish
+	reload := func() *protocol.ConfigReload {
+		notifySystemd(log, sdNotifyReloading)
+		var result *protocol.ConfigReload
+		result, loadedConfig = reloadConfig(app, loadedConfig)
+		notifySystemd(log, sdNotifyReady)
+		return result
+	}
+
+	// Changed SASL credentials can only be used by creating the Kafka clients again, which is done by restarting
+	restartOnCredentialChange := viper.GetBool("general.restart-on-credential-change")
+	restartForCredentials := func(result *protocol.ConfigReload) {
+		if restartOnCredentialChange && isCredentialChange(result) {
+			log.Info("SASL credentials changed, restarting")
+			gracefulRestart(app, log)
+		}
+	}
+
+	// Reloads that are not requested only log if something changed
+	automaticReload := func() {
+		result := reload()
+		if (result.Error != "") || (len(result.Applied) > 0) || (len(result.RestartRequired) > 0) {
+			logConfigReload(log, result)
+		}
+		restartForCredentials(result)
+	}
+
+	// If systemd's watchdog is enabled, it is notified from this loop, so that a hung Burrow is restarted
+	var watchdog <-chan time.Time
+	if interval := sdWatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+	helpers.CloseInheritedListeners()
+	notifySystemd(log, sdNotifyReady)
+	finishRestart(log)
+
+	// Wait until we're told to exit, reloading the configuration when asked to
+	for running := true; running; {
+		select {
+		case <-watchdog:
+			notifySystemd(log, sdNotifyWatchdog)
+		case <-secretRefresh:
+			automaticReload()
+		case <-configChanged:
+			automaticReload()
+		case sig, ok := <-exitChannel:
+			if ok && (sig == syscall.SIGHUP) {
+				result := reload()
+				logConfigReload(log, result)
+				restartForCredentials(result)
+				continue
+			}
+			if ok && (RestartSignal != nil) && (sig == RestartSignal) {
+				gracefulRestart(app, log)
+				continue
+			}
+			running = false
+		case request := <-app.ReloadChannel:
+			result := reload()
+			logConfigReload(log, result)
+			request.Reply <- result
+			restartForCredentials(result)
+		}
+	}
 	log.Info("Shutdown triggered")
+	if !isReplaced() {
+		notifySystemd(log, sdNotifyStopping)
+	}
 
 	// Stop the coordinators in the reverse order. This assures that request senders are stopped before request servers
 	for i := len(coordinators) - 1; i >= 0; i-- {
@@ -0,0 +1,112 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// ConfigError is a problem with the configuration that was found by CheckConfig. Subsystem is the part of Burrow that
+// rejected the configuration (such as "logging", or a coordinator name like "cluster").
+type ConfigError struct {
+	Subsystem string `json:"subsystem"`
+	Error     string `json:"error"`
+}
+
+// The names of the coordinators returned by newCoordinators, in the same order
+var coordinatorNames = [7]string{"storage", "evaluator", "httpserver", "cluster", "consumer", "reporter", "exporter"}
+
+// CheckConfig validates the configuration that has been loaded by viper, without starting Burrow. Every coordinator
+// is configured, which validates the configuration of all of its modules (including regular expressions, addresses,
+// client profiles, and credentials), but no connections are made. A coordinator stops at the first module that has an
+// error, so at most one error is returned for each coordinator. If the configuration is valid, an empty list is
+// returned.
+func CheckConfig() []ConfigError {
+	configErrors := make([]ConfigError, 0)
+	if _, ok := helpers.ParseLogLevel(viper.GetString("logging.level")); !ok {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "logging",
+			Error:     "invalid logging.level: " + viper.GetString("logging.level"),
+		})
+	}
+	if err := helpers.ValidateSubsystemLogLevels(viper.GetViper()); err != nil {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "logging",
+			Error:     "invalid logging.levels: " + err.Error(),
+		})
+	}
+	if viper.GetBool("logging.syslog.enabled") {
+		if _, err := newSyslogCore(viper.GetViper()); err != nil {
+			configErrors = append(configErrors, ConfigError{
+				Subsystem: "logging",
+				Error:     err.Error(),
+			})
+		}
+	}
+	if format := viper.GetString("logging.format"); (format != "") && (format != "json") && (format != "console") {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "logging",
+			Error:     "invalid logging.format: " + format,
+		})
+	}
+
+	if err := helpers.CheckTracingConfig(); err != nil {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "tracing",
+			Error:     err.Error(),
+		})
+	}
+
+	if err := helpers.CheckChannelConfig(); err != nil {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "general",
+			Error:     err.Error(),
+		})
+	}
+	if _, err := helpers.GetJSONEncoder(); err != nil {
+		configErrors = append(configErrors, ConfigError{
+			Subsystem: "general",
+			Error:     "invalid general.json-encoder: " + err.Error(),
+		})
+	}
+
+	app := &protocol.ApplicationContext{
+		Logger:           zap.NewNop(),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		StorageChannel:   make(chan *protocol.StorageRequest),
+	}
+	for i, coordinator := range newCoordinators(app) {
+		if err := checkCoordinatorConfig(coordinator); err != "" {
+			configErrors = append(configErrors, ConfigError{
+				Subsystem: coordinatorNames[i],
+				Error:     err,
+			})
+		}
+	}
+	return configErrors
+}
+
+// checkCoordinatorConfig configures the coordinator, and returns the reason it panicked, if it did
+func checkCoordinatorConfig(coordinator protocol.Coordinator) (configErr string) {
+	defer func() {
+		if r := recover(); r != nil {
+			configErr = fmt.Sprint(r)
+		}
+	}()
+	coordinator.Configure()
+	return ""
+}
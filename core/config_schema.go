@@ -0,0 +1,58 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("general", "",
+		helpers.ConfigKey{Name: "pidfile", Type: helpers.ConfigTypeString, Default: "burrow.pid"},
+		helpers.ConfigKey{Name: "stdout-logfile", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "env-var-prefix", Type: helpers.ConfigTypeString, Default: "burrow"},
+		helpers.ConfigKey{Name: "include", Type: helpers.ConfigTypeStringList},
+		helpers.ConfigKey{Name: "access-control-allow-origin", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "dynamic-config-file", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "secret-refresh", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "restart-on-credential-change", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "watch-config", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "watch-files", Type: helpers.ConfigTypeStringList},
+		helpers.ConfigKey{Name: "meta-group", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "storage-channel-capacity", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "evaluator-channel-capacity", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "health-error-window", Type: helpers.ConfigTypeInteger, Default: 300},
+		helpers.ConfigKey{Name: "export-interval", Type: helpers.ConfigTypeInteger, Default: 60},
+		helpers.ConfigKey{Name: "status-concurrency", Type: helpers.ConfigTypeInteger, Default: 8},
+		helpers.ConfigKey{Name: "status-timeout", Type: helpers.ConfigTypeInteger, Default: 10},
+		helpers.ConfigKey{Name: "json-encoder", Type: helpers.ConfigTypeString, Default: "standard"},
+	)
+	helpers.RegisterConfigKeys("logging", "",
+		helpers.ConfigKey{Name: "filename", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "level", Type: helpers.ConfigTypeString, Default: "info"},
+		helpers.ConfigKey{Name: "levels", Type: helpers.ConfigTypeMap},
+		helpers.ConfigKey{Name: "format", Type: helpers.ConfigTypeString, Default: "json"},
+		helpers.ConfigKey{Name: "maxsize", Type: helpers.ConfigTypeInteger, Default: 100},
+		helpers.ConfigKey{Name: "maxbackups", Type: helpers.ConfigTypeInteger, Default: 10},
+		helpers.ConfigKey{Name: "maxage", Type: helpers.ConfigTypeInteger, Default: 30},
+		helpers.ConfigKey{Name: "use-compression", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "use-localtime", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "syslog.enabled", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "syslog.network", Type: helpers.ConfigTypeString, Default: "unixgram"},
+		helpers.ConfigKey{Name: "syslog.address", Type: helpers.ConfigTypeString, Default: "/dev/log"},
+		helpers.ConfigKey{Name: "syslog.facility", Type: helpers.ConfigTypeString, Default: "daemon"},
+		helpers.ConfigKey{Name: "syslog.app-name", Type: helpers.ConfigTypeString, Default: "burrow"},
+		helpers.ConfigKey{Name: "syslog.sd-id", Type: helpers.ConfigTypeString, Default: "burrow@32473"},
+		helpers.ConfigKey{Name: "journald.enabled", Type: helpers.ConfigTypeBoolean},
+		helpers.ConfigKey{Name: "journald.socket", Type: helpers.ConfigTypeString, Default: "/run/systemd/journal/socket"},
+		helpers.ConfigKey{Name: "journald.identifier", Type: helpers.ConfigTypeString, Default: "burrow"},
+	)
+}
@@ -0,0 +1,99 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+// journaldSink sends log entries to the systemd journal using its native protocol, so that each field of an entry
+// becomes a journal field that can be used to filter entries (such as with journalctl NAME=consumer). Field names are
+// changed to upper case, with any other characters that are not allowed replaced by underscores.
+type journaldSink struct {
+	conn       *sinkConnection
+	identifier string
+}
+
+// newJournaldCore returns a core that logs to the systemd journal, using the logging.journald section of the
+// configuration:
+//
+// logging.journald.socket = /run/systemd/journal/socket
+// logging.journald.identifier = burrow
+func newJournaldCore(config *viper.Viper) zapcore.Core {
+	config.SetDefault("logging.journald.socket", "/run/systemd/journal/socket")
+	config.SetDefault("logging.journald.identifier", "burrow")
+
+	sink := &journaldSink{
+		conn: &sinkConnection{
+			network: "unixgram",
+			address: config.GetString("logging.journald.socket"),
+		},
+		identifier: config.GetString("logging.journald.identifier"),
+	}
+	return &fieldsCore{write: sink.write}
+}
+
+func (s *journaldSink) write(entry zapcore.Entry, keys []string, fields map[string]string) error {
+	message := &bytes.Buffer{}
+	writeJournaldField(message, "PRIORITY", strconv.Itoa(syslogSeverities[entry.Level]))
+	writeJournaldField(message, "SYSLOG_IDENTIFIER", s.identifier)
+	if entry.Stack != "" {
+		writeJournaldField(message, "MESSAGE", entry.Message+"\n"+entry.Stack)
+	} else {
+		writeJournaldField(message, "MESSAGE", entry.Message)
+	}
+	for _, key := range keys {
+		writeJournaldField(message, journaldFieldName(key), fields[key])
+	}
+	return s.conn.write(message.Bytes())
+}
+
+// writeJournaldField adds a field to a journal message. Values that contain a newline are written with their length,
+// as the protocol requires.
+func writeJournaldField(message *bytes.Buffer, name, value string) {
+	message.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		message.WriteString("=" + value + "\n")
+		return
+	}
+	message.WriteString("\n")
+	binary.Write(message, binary.LittleEndian, uint64(len(value))) // nolint:errcheck
+	message.WriteString(value + "\n")
+}
+
+// journaldFieldName makes a field name valid for the journal, which only allows upper case letters, digits, and
+// underscores, and does not allow names to start with an underscore (which is used for trusted fields) or a digit
+func journaldFieldName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case (r >= 'A') && (r <= 'Z'), (r >= '0') && (r <= '9'), r == '_':
+			return r
+		case (r >= 'a') && (r <= 'z'):
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.TrimLeft(name, "_")
+	if (name == "") || ((name[0] >= '0') && (name[0] <= '9')) {
+		name = "FIELD_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
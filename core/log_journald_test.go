@@ -0,0 +1,76 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJournaldSink_Write(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    zapcore.Entry
+		keys     []string
+		fields   map[string]string
+		expected string
+	}{
+		{
+			name:     "no fields",
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "started"},
+			expected: "PRIORITY=6\nSYSLOG_IDENTIFIER=burrow\nMESSAGE=started\n",
+		},
+		{
+			name:     "fields",
+			entry:    zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "slow"},
+			keys:     []string{"name", "elapsed.ms"},
+			fields:   map[string]string{"name": "consumer", "elapsed.ms": "1500"},
+			expected: "PRIORITY=4\nSYSLOG_IDENTIFIER=burrow\nMESSAGE=slow\nNAME=consumer\nELAPSED_MS=1500\n",
+		},
+		{
+			name:     "multiline",
+			entry:    zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "failed", Stack: "main.go:1"},
+			keys:     []string{"error"},
+			fields:   map[string]string{"error": "a\nb"},
+			expected: "PRIORITY=3\nSYSLOG_IDENTIFIER=burrow\nMESSAGE\n\x10\x00\x00\x00\x00\x00\x00\x00failed\nmain.go:1\nERROR\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		},
+	}
+
+	for _, test := range tests {
+		conn := &recordingConn{}
+		sink := &journaldSink{conn: &sinkConnection{conn: conn}, identifier: "burrow"}
+		assert.NoErrorf(t, sink.write(test.entry, test.keys, test.fields), "Expected no error writing for test %v", test.name)
+		assert.Equalf(t, []string{test.expected}, conn.written, "Unexpected message for test %v", test.name)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"consumer", "CONSUMER"},
+		{"GROUP_1", "GROUP_1"},
+		{"elapsed.ms-total", "ELAPSED_MS_TOTAL"},
+		{"_trusted", "TRUSTED"},
+		{"1st", "FIELD_1ST"},
+		{"__", "FIELD_"},
+		{strings.Repeat("x", 70), strings.Repeat("X", 64)},
+	}
+
+	for _, test := range tests {
+		assert.Equalf(t, test.expected, journaldFieldName(test.name), "Unexpected field name for %q", test.name)
+	}
+}
@@ -0,0 +1,121 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldWriter sends a single log entry, with its fields, to a log sink. The keys of the fields are sorted.
+type fieldWriter func(entry zapcore.Entry, keys []string, fields map[string]string) error
+
+// fieldsCore is a zapcore.Core for log sinks that map each field of an entry to a field of their own (such as syslog
+// structured data, or journald fields), rather than encoding the entry as a single line. Every level is enabled, as
+// the level is checked by the subsystem level core that wraps it.
+type fieldsCore struct {
+	fields []zapcore.Field
+	write  fieldWriter
+}
+
+func (c *fieldsCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *fieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	allFields := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	allFields = append(allFields, c.fields...)
+	return &fieldsCore{
+		fields: append(allFields, fields...),
+		write:  c.write,
+	}
+}
+
+func (c *fieldsCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *fieldsCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	keys := make([]string, 0, len(encoder.Fields))
+	values := make(map[string]string, len(encoder.Fields))
+	for key, value := range encoder.Fields {
+		keys = append(keys, key)
+		values[key] = formatFieldValue(value)
+	}
+	sort.Strings(keys)
+	return c.write(entry, keys, values)
+}
+
+func (c *fieldsCore) Sync() error {
+	return nil
+}
+
+// formatFieldValue returns a field value as a string. Arrays and objects are encoded as JSON.
+func formatFieldValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case time.Time:
+		return typed.Format(time.RFC3339Nano)
+	case []interface{}, map[string]interface{}:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// sinkConnection is a connection to a log sink that is dialed when it is first used, and dialed again if a write
+// fails, so that a restart of the syslog or journald daemon does not stop logging
+type sinkConnection struct {
+	lock    sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+}
+
+func (c *sinkConnection) write(message []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if c.conn, err = net.DialTimeout(c.network, c.address, 5*time.Second); err != nil {
+				c.conn = nil
+				continue
+			}
+		}
+		if _, err = c.conn.Write(message); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7, "uucp": 8, "cron": 9,
+	"authpriv": 10, "ftp": 11, "local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21,
+	"local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  2,
+	zapcore.FatalLevel:  2,
+}
+
+// syslogSink sends log entries to a syslog server in the RFC 5424 format, with the fields of each entry as the
+// parameters of a single structured data element. Messages sent over a stream (tcp or unix) are framed by octet
+// counting, as described in RFC 6587.
+type syslogSink struct {
+	conn     *sinkConnection
+	stream   bool
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	sdID     string
+}
+
+// newSyslogCore returns a core that logs to syslog, using the logging.syslog section of the configuration:
+//
+// logging.syslog.network = unixgram (or udp, tcp, or unix)
+// logging.syslog.address = /dev/log
+// logging.syslog.facility = daemon
+// logging.syslog.app-name = burrow
+// logging.syslog.sd-id = burrow@32473
+//
+// The connection is not made until the first entry is logged, so an error is only returned for a bad configuration.
+func newSyslogCore(config *viper.Viper) (zapcore.Core, error) {
+	config.SetDefault("logging.syslog.network", "unixgram")
+	config.SetDefault("logging.syslog.address", "/dev/log")
+	config.SetDefault("logging.syslog.facility", "daemon")
+	config.SetDefault("logging.syslog.app-name", "burrow")
+	config.SetDefault("logging.syslog.sd-id", "burrow@32473")
+
+	network := config.GetString("logging.syslog.network")
+	switch network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, errors.New("unknown logging.syslog.network: " + network)
+	}
+	facility, ok := syslogFacilities[strings.ToLower(config.GetString("logging.syslog.facility"))]
+	if !ok {
+		return nil, errors.New("unknown logging.syslog.facility: " + config.GetString("logging.syslog.facility"))
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	sink := &syslogSink{
+		conn: &sinkConnection{
+			network: network,
+			address: config.GetString("logging.syslog.address"),
+		},
+		stream:   (network == "tcp") || (network == "unix"),
+		facility: facility,
+		hostname: syslogHeaderValue(hostname, 255),
+		appName:  syslogHeaderValue(config.GetString("logging.syslog.app-name"), 48),
+		procID:   strconv.Itoa(os.Getpid()),
+		sdID:     syslogName(config.GetString("logging.syslog.sd-id")),
+	}
+	return &fieldsCore{write: sink.write}, nil
+}
+
+func (s *syslogSink) write(entry zapcore.Entry, keys []string, fields map[string]string) error {
+	message := &bytes.Buffer{}
+	message.WriteString("<" + strconv.Itoa(s.facility*8+syslogSeverities[entry.Level]) + ">1 ")
+	message.WriteString(entry.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	message.WriteString(" " + s.hostname + " " + s.appName + " " + s.procID + " - ")
+
+	if len(keys) == 0 {
+		message.WriteString("-")
+	} else {
+		message.WriteString("[" + s.sdID)
+		for _, key := range keys {
+			message.WriteString(" " + syslogName(key) + "=\"" + syslogParamEscaper.Replace(fields[key]) + "\"")
+		}
+		message.WriteString("]")
+	}
+	message.WriteString(" " + entry.Message)
+	if entry.Stack != "" {
+		message.WriteString("\n" + entry.Stack)
+	}
+
+	if s.stream {
+		return s.conn.write(append([]byte(strconv.Itoa(message.Len())+" "), message.Bytes()...))
+	}
+	return s.conn.write(message.Bytes())
+}
+
+// syslogParamEscaper escapes the characters that must be escaped in a structured data parameter value
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogName makes a structured data ID or parameter name valid, by replacing the characters that are not allowed
+// with underscores and truncating it to 32 characters
+func syslogName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if (r <= ' ') || (r > '~') || (r == '=') || (r == ']') || (r == '"') {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// syslogHeaderValue makes a header field valid, by replacing the characters that are not allowed with underscores and
+// truncating it to the maximum length for the field. An empty value is replaced with "-".
+func syslogHeaderValue(value string, maxLength int) string {
+	if value == "" {
+		return "-"
+	}
+	value = strings.Map(func(r rune) rune {
+		if (r <= ' ') || (r > '~') {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > maxLength {
+		value = value[:maxLength]
+	}
+	return value
+}
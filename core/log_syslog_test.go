@@ -0,0 +1,130 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingConn is a connection for a log sink that keeps each message written to it
+type recordingConn struct {
+	net.Conn
+	written []string
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.written = append(c.written, string(b))
+	return len(b), nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func fixtureSyslogSink(stream bool, facility int) (*syslogSink, *recordingConn) {
+	conn := &recordingConn{}
+	return &syslogSink{
+		conn:     &sinkConnection{conn: conn},
+		stream:   stream,
+		facility: facility,
+		hostname: "testhost",
+		appName:  "burrow",
+		procID:   "1234",
+		sdID:     "burrow@32473",
+	}, conn
+}
+
+func TestSyslogSink_Write(t *testing.T) {
+	entryTime := time.Date(2017, 1, 2, 3, 4, 5, 6000, time.FixedZone("test", 3600))
+	tests := []struct {
+		name     string
+		stream   bool
+		facility int
+		entry    zapcore.Entry
+		keys     []string
+		fields   map[string]string
+		expected string
+	}{
+		{
+			name:     "no fields",
+			facility: 3,
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel, Time: entryTime, Message: "started"},
+			expected: "<30>1 2017-01-02T02:04:05.000006Z testhost burrow 1234 - - started",
+		},
+		{
+			name:     "fields",
+			facility: 16,
+			entry:    zapcore.Entry{Level: zapcore.ErrorLevel, Time: entryTime, Message: "failed"},
+			keys:     []string{"name", "bad key=x"},
+			fields:   map[string]string{"name": "consumer", "bad key=x": `a\b"c]d`},
+			expected: `<131>1 2017-01-02T02:04:05.000006Z testhost burrow 1234 - [burrow@32473 name="consumer" bad_key_x="a\\b\"c\]d"] failed`,
+		},
+		{
+			name:     "stack",
+			facility: 3,
+			entry:    zapcore.Entry{Level: zapcore.DPanicLevel, Time: entryTime, Message: "panic", Stack: "main.go:1"},
+			expected: "<26>1 2017-01-02T02:04:05.000006Z testhost burrow 1234 - - panic\nmain.go:1",
+		},
+		{
+			name:     "stream",
+			stream:   true,
+			facility: 3,
+			entry:    zapcore.Entry{Level: zapcore.WarnLevel, Time: entryTime, Message: "slow"},
+			expected: "63 <28>1 2017-01-02T02:04:05.000006Z testhost burrow 1234 - - slow",
+		},
+	}
+
+	for _, test := range tests {
+		sink, conn := fixtureSyslogSink(test.stream, test.facility)
+		assert.NoErrorf(t, sink.write(test.entry, test.keys, test.fields), "Expected no error writing for test %v", test.name)
+		assert.Equalf(t, []string{test.expected}, conn.written, "Unexpected message for test %v", test.name)
+	}
+}
+
+func TestSyslogHeaderValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		maxLength int
+		expected  string
+	}{
+		{"burrow", 48, "burrow"},
+		{"", 48, "-"},
+		{"my host\n", 255, "my_host_"},
+		{"burröw", 48, "burr_w"},
+		{strings.Repeat("a", 50), 48, strings.Repeat("a", 48)},
+	}
+
+	for _, test := range tests {
+		assert.Equalf(t, test.expected, syslogHeaderValue(test.value, test.maxLength), "Unexpected header value for %q", test.value)
+	}
+}
+
+func TestSyslogName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"burrow@32473", "burrow@32473"},
+		{"a b=c]d\"e", "a_b_c_d_e"},
+		{"grüße", "gr__e"},
+		{strings.Repeat("n", 40), strings.Repeat("n", 32)},
+	}
+
+	for _, test := range tests {
+		assert.Equalf(t, test.expected, syslogName(test.name), "Unexpected name for %q", test.name)
+	}
+}
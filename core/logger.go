@@ -15,7 +15,6 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -23,6 +22,8 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/linkedin/Burrow/helpers"
 )
 
 // CheckAndCreatePidFile takes a single argument, which is the path to a PID file (a file that contains a single
@@ -56,9 +57,11 @@ func CheckAndCreatePidFile(filename string) bool {
 			return true
 		}
 
-		// Try sending a signal to the process to see if it is still running
-		process, err := os.FindProcess(pid)
-		if err == nil {
+		if isRestartParent(pid) {
+			// This process is replacing the one in the PID file, as part of a graceful restart
+			fmt.Println("Taking over pidfile from the replaced process")
+		} else if process, err := os.FindProcess(pid); err == nil {
+			// Try sending a signal to the process to see if it is still running
 			err = process.Signal(syscall.Signal(0))
 			if (err == nil) || (err == syscall.EPERM) {
 				// The process exists, so we're going to assume it's an old Burrow and we shouldn't start
@@ -69,7 +72,7 @@ func CheckAndCreatePidFile(filename string) bool {
 	}
 
 	// Create a PID file, replacing any existing one (as we already checked it)
-	pidfile, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0644)
+	pidfile, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		fmt.Printf("Cannot write PID file: %v", err)
 		return false
@@ -79,9 +82,13 @@ func CheckAndCreatePidFile(filename string) bool {
 	return true
 }
 
-// RemovePidFile takes a single argument, which is the path to a PID file. That file is deleted. This func should be
-// called when Burrow exits.
+// RemovePidFile takes a single argument, which is the path to a PID file. That file is deleted, unless it has been
+// taken over by another process (such as the replacement in a graceful restart). This func should be called when Burrow
+// exits.
 func RemovePidFile(filename string) {
+	if pidString, err := ioutil.ReadFile(filename); (err == nil) && (string(pidString) != strconv.Itoa(os.Getpid())) {
+		return
+	}
 	err := os.Remove(filename)
 	if err != nil {
 		fmt.Printf("Failed to remove PID file: %v\n", err)
@@ -93,6 +100,11 @@ func RemovePidFile(filename string) {
 // is read from viper, with the following defaults:
 //
 // logging.level = info
+// logging.format = json
+//
+// The format may be "json" or "console" (a human-readable format, for running Burrow in a terminal). Subsystems can
+// have their own levels, set in the logging.levels section with the name of the coordinator as the key (for example,
+// consumer = "debug"). These levels can also be changed while Burrow is running, through the HTTP server.
 //
 // If logging.filename (path to the log file) is provided, a rolling log file is set up using lumberjack. The
 // configuration for that log file is read from viper, with the following defaults:
@@ -102,34 +114,30 @@ func RemovePidFile(filename string) {
 // logging.maxage = 30
 // logging.use-localtime = false
 // logging.use-compression = false
+//
+// Entries can also be sent to syslog, if logging.syslog.enabled is true, and to the systemd journal, if
+// logging.journald.enabled is true. The fields of each entry are kept as structured data for syslog, and as journal
+// fields for journald. See newSyslogCore and newJournaldCore for their configuration.
 func ConfigureLogger() (*zap.Logger, *zap.AtomicLevel) {
 	var level zap.AtomicLevel
 	var syncOutput zapcore.WriteSyncer
 
 	// Set config defaults for logging
 	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "json")
 	viper.SetDefault("logging.maxsize", 100)
 	viper.SetDefault("logging.maxbackups", 10)
 	viper.SetDefault("logging.maxage", 30)
 
 	// Create an AtomicLevel that we can use elsewhere to dynamically change the logging level
 	logLevel := viper.GetString("logging.level")
-	switch strings.ToLower(logLevel) {
-	case "", "info":
-		level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	case "debug":
-		level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	case "warn":
-		level = zap.NewAtomicLevelAt(zap.WarnLevel)
-	case "error":
-		level = zap.NewAtomicLevelAt(zap.ErrorLevel)
-	case "panic":
-		level = zap.NewAtomicLevelAt(zap.PanicLevel)
-	case "fatal":
-		level = zap.NewAtomicLevelAt(zap.FatalLevel)
-	default:
+	levelValue, ok := helpers.ParseLogLevel(logLevel)
+	if !ok {
 		fmt.Printf("Invalid log level supplied. Defaulting to info: %s", logLevel)
-		level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	}
+	level = zap.NewAtomicLevelAt(levelValue)
+	if err := helpers.LoadSubsystemLogLevels(viper.GetViper()); err != nil {
+		fmt.Printf("Invalid subsystem log levels supplied. Using %s for all: %v", level.String(), err)
 	}
 
 	// If a filename has been set, set up a rotating logger. Otherwise, use Stdout
@@ -147,12 +155,34 @@ func ConfigureLogger() (*zap.Logger, *zap.AtomicLevel) {
 		syncOutput = zapcore.Lock(os.Stdout)
 	}
 
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
-		syncOutput,
-		level,
-	)
-	logger := zap.New(core)
+	var encoder zapcore.Encoder
+	switch viper.GetString("logging.format") {
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	case "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	default:
+		fmt.Printf("Invalid log format supplied. Defaulting to json: %s", viper.GetString("logging.format"))
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	}
+
+	// The level is checked by the subsystem core, so that each subsystem can have its own level
+	cores := []zapcore.Core{zapcore.NewCore(encoder, syncOutput, zap.DebugLevel)}
+
+	// Syslog and journald receive the same entries, in addition to the log file or stdout
+	if viper.GetBool("logging.syslog.enabled") {
+		syslogCore, err := newSyslogCore(viper.GetViper())
+		if err != nil {
+			fmt.Printf("Invalid syslog configuration. Not logging to syslog: %v", err)
+		} else {
+			cores = append(cores, syslogCore)
+		}
+	}
+	if viper.GetBool("logging.journald.enabled") {
+		cores = append(cores, newJournaldCore(viper.GetViper()))
+	}
+
+	logger := zap.New(helpers.NewSubsystemLevelCore(zapcore.NewTee(cores...), level))
 	zap.ReplaceGlobals(logger)
 	return logger, &level
 }
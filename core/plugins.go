@@ -0,0 +1,17 @@
+// +build !no_plugin
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+// The plugin package registers the plugin class for the cluster, consumer, and storage coordinators. It is left out of
+// builds with the no_plugin tag, along with its RPC dependencies.
+import _ "github.com/linkedin/Burrow/plugin"
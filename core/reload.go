@@ -0,0 +1,161 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// readConfigFile reads the configuration file that viper was loaded from, and the files that it includes, into a new
+// viper instance, so that it can be compared to the configuration as it was at the last load. Environment variables
+// and command line settings override the file, as they do in viper. If viper was not loaded from a file (such as when
+// Burrow is used as a library), nil is returned.
+func readConfigFile() (*viper.Viper, error) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return nil, nil
+	}
+	config := viper.New()
+	config.SetConfigFile(configFile)
+	if err := config.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	if err := helpers.MergeConfigIncludes(config); err != nil {
+		return nil, err
+	}
+	if err := helpers.InterpolateConfig(config); err != nil {
+		return nil, err
+	}
+	helpers.ApplyConfigDeprecations(config)
+	helpers.ApplyConfigOverrides(config)
+	helpers.SetConfigEnv(config, viper.GetString("general.env-var-prefix"))
+	return config, nil
+}
+
+// reloadConfig reads the configuration file again and applies the changes that can be made while Burrow is running,
+// which are the logging level and the group and topic lists of each module. All other changes are reported as
+// requiring a restart. The new configuration is validated before anything is changed, so if it is not valid, Burrow
+// keeps running with the previous configuration. The configuration that is in use afterwards is returned, to be
+// passed as previous at the next reload.
+//
+// The configuration is read into its own viper instance, and only the values that can be changed are passed on, to the
+// log levels and filters, which can be changed safely while they are in use. The global viper is not changed, as it is
+// read by the modules while they are running, and viper is not safe to change while it is being read.
+func reloadConfig(app *protocol.ApplicationContext, previous *viper.Viper) (*protocol.ConfigReload, *viper.Viper) {
+	result := &protocol.ConfigReload{
+		Applied:         make([]string, 0),
+		RestartRequired: make([]string, 0),
+	}
+	if previous == nil {
+		result.Error = "configuration was not loaded from a file"
+		return result, previous
+	}
+
+	current, err := readConfigFile()
+	if err != nil {
+		result.Error = "failed reading configuration: " + err.Error()
+		return result, previous
+	}
+
+	// Validate everything that will be applied before changing anything
+	level, ok := helpers.ParseLogLevel(current.GetString("logging.level"))
+	if !ok {
+		result.Error = "invalid logging.level: " + current.GetString("logging.level")
+		return result, previous
+	}
+	if err := helpers.ValidateSubsystemLogLevels(current); err != nil {
+		result.Error = "invalid logging.levels: " + err.Error()
+		return result, previous
+	}
+	if err := helpers.ValidateConsumerFilters(current); err != nil {
+		result.Error = "invalid filter: " + err.Error()
+		return result, previous
+	}
+	for _, key := range getChangedKeys(previous, current) {
+		if isReloadableKey(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	app.LogLevel.SetLevel(level)
+	if err := helpers.LoadSubsystemLogLevels(current); err != nil {
+		result.Error = "invalid logging.levels: " + err.Error()
+	}
+	if err := helpers.ReloadConsumerFilters(current); err != nil {
+		result.Error = "invalid filter: " + err.Error()
+	}
+	return result, current
+}
+
+// getChangedKeys returns a sorted list of the config keys that are different between the two configurations, including
+// keys that are only in one of them
+func getChangedKeys(previous, current *viper.Viper) []string {
+	keys := make(map[string]bool)
+	for _, key := range previous.AllKeys() {
+		keys[key] = true
+	}
+	for _, key := range current.AllKeys() {
+		keys[key] = true
+	}
+
+	changed := make([]string, 0)
+	for key := range keys {
+		if !reflect.DeepEqual(previous.Get(key), current.Get(key)) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func isReloadableKey(key string) bool {
+	if (key == "logging.level") || strings.HasPrefix(key, "logging.levels.") {
+		return true
+	}
+	for _, list := range []string{".group-allowlist", ".group-denylist", ".topic-allowlist", ".topic-denylist"} {
+		if strings.HasSuffix(key, list) && (helpers.GetConsumerFilter(strings.TrimSuffix(key, list)) != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCredentialChange returns true if the reload changed any SASL credentials, which are used by the Kafka clients of
+// the modules, and so only take effect when the clients are created again
+func isCredentialChange(result *protocol.ConfigReload) bool {
+	for _, key := range result.RestartRequired {
+		if strings.HasPrefix(key, "sasl.") {
+			return true
+		}
+	}
+	return false
+}
+
+// logConfigReload logs the result of a configuration reload
+func logConfigReload(log *zap.Logger, result *protocol.ConfigReload) {
+	if result.Error != "" {
+		log.Error("failed to reload configuration", zap.String("error", result.Error))
+		return
+	}
+	log.Info("reloaded configuration",
+		zap.Strings("applied", result.Applied),
+		zap.Strings("restart_required", result.RestartRequired),
+	)
+}
@@ -0,0 +1,64 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// restartParentEnv is the environment variable that a replacement process is started with during a graceful restart.
+// It is the PID of the process being replaced, which is stopped once the replacement has started.
+const restartParentEnv = "BURROW_RESTART_PARENT"
+
+// gracefulRestart starts a replacement Burrow process, running the current executable (which may have been upgraded)
+// with the same arguments. The stored offsets are written to the storage snapshot file first, so that the replacement
+// can restore them, and the HTTP listeners are passed to it, so that requests are answered throughout the restart.
+// This process keeps running until the replacement has started all of its coordinators, at which point the
+// replacement tells it to stop. If the replacement fails to start, this process keeps running.
+func gracefulRestart(app *protocol.ApplicationContext, log *zap.Logger) {
+	log.Info("starting graceful restart")
+	if err := writeStorageSnapshot(app); err != nil {
+		log.Error("failed to write storage snapshot, not restarting", zap.Error(err))
+		return
+	}
+	if err := startReplacement(log); err != nil {
+		log.Error("failed to start replacement process", zap.Error(err))
+	}
+}
+
+// writeStorageSnapshot asks the storage module to write its snapshot file, and waits for it to finish
+func writeStorageSnapshot(app *protocol.ApplicationContext) error {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageWriteSnapshot,
+		Reply:       make(chan interface{}, 1),
+		Submitted:   time.Now(),
+	}
+	select {
+	case app.StorageChannel <- request:
+	case <-time.After(10 * time.Second):
+		return errors.New("timed out sending snapshot request")
+	}
+
+	select {
+	case response := <-request.Reply:
+		if err, ok := response.(error); ok {
+			return err
+		}
+		return nil
+	case <-time.After(60 * time.Second):
+		return errors.New("timed out writing snapshot")
+	}
+}
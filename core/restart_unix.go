@@ -0,0 +1,110 @@
+// +build !windows
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+)
+
+// RestartSignal is the signal that starts a graceful restart, when it is received on the exit channel passed to Start
+var RestartSignal os.Signal = syscall.SIGUSR2
+
+// replacementRunning is 1 while a replacement process that was started by a graceful restart is running
+var replacementRunning int32
+
+func startReplacement(log *zap.Logger) error {
+	if !atomic.CompareAndSwapInt32(&replacementRunning, 0, 1) {
+		return errors.New("a replacement process is already running")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		atomic.StoreInt32(&replacementRunning, 0)
+		return err
+	}
+	names, files, err := helpers.GetListenerFiles()
+	if err != nil {
+		atomic.StoreInt32(&replacementRunning, 0)
+		return err
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	env := make([]string, 0)
+	for _, setting := range os.Environ() {
+		if !strings.HasPrefix(setting, helpers.InheritedListenersEnv+"=") && !strings.HasPrefix(setting, restartParentEnv+"=") {
+			env = append(env, setting)
+		}
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(env,
+		helpers.InheritedListenersEnv+"="+strings.Join(names, ","),
+		restartParentEnv+"="+strconv.Itoa(os.Getpid()),
+	)
+	if err := cmd.Start(); err != nil {
+		atomic.StoreInt32(&replacementRunning, 0)
+		return err
+	}
+	log.Info("started replacement process", zap.Int("pid", cmd.Process.Pid))
+
+	go func() {
+		err := cmd.Wait()
+		atomic.StoreInt32(&replacementRunning, 0)
+		log.Error("replacement process exited before taking over", zap.Error(err))
+	}()
+	return nil
+}
+
+// isReplaced returns true if a replacement process has been started, and is still running
+func isReplaced() bool {
+	return atomic.LoadInt32(&replacementRunning) == 1
+}
+
+// isRestartParent returns true if the given PID is the process that started this one as its replacement
+func isRestartParent(pid int) bool {
+	return (os.Getenv(restartParentEnv) == strconv.Itoa(pid)) && (os.Getppid() == pid)
+}
+
+// finishRestart is called once all of the coordinators have started. If this process is the replacement in a graceful
+// restart, the process that it replaced is told to stop, and systemd is told that this is now the main process.
+func finishRestart(log *zap.Logger) {
+	parent, err := strconv.Atoi(os.Getenv(restartParentEnv))
+	os.Unsetenv(restartParentEnv)
+	if (err != nil) || (os.Getppid() != parent) {
+		return
+	}
+
+	notifySystemd(log, "MAINPID="+strconv.Itoa(os.Getpid()))
+	if err := syscall.Kill(parent, syscall.SIGTERM); err != nil {
+		log.Error("failed to stop the replaced process", zap.Int("pid", parent), zap.Error(err))
+		return
+	}
+	log.Info("took over from the replaced process", zap.Int("pid", parent))
+}
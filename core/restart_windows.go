@@ -0,0 +1,37 @@
+// +build windows
+
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"errors"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// RestartSignal is nil, as graceful restarts are not supported on Windows
+var RestartSignal os.Signal
+
+func startReplacement(log *zap.Logger) error {
+	return errors.New("graceful restart is not supported on Windows")
+}
+
+func isReplaced() bool {
+	return false
+}
+
+func isRestartParent(pid int) bool {
+	return false
+}
+
+func finishRestart(log *zap.Logger) {}
@@ -0,0 +1,71 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Notification states that are sent to systemd
+const (
+	sdNotifyReady     = "READY=1"
+	sdNotifyReloading = "RELOADING=1"
+	sdNotifyStopping  = "STOPPING=1"
+	sdNotifyWatchdog  = "WATCHDOG=1"
+)
+
+// sdNotify sends a state notification to systemd over the socket in the NOTIFY_SOCKET environment variable, which is
+// set when Burrow is run as a service with Type=notify. If Burrow was not started by systemd, nothing is sent and no
+// error is returned.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// The socket is in the abstract namespace
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns how often the systemd watchdog must be notified for Burrow to be considered healthy, which
+// is half of the WatchdogSec setting for the service. If the watchdog is not enabled for this process, zero is
+// returned.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if (err != nil) || (usec <= 0) {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && (pid != strconv.Itoa(os.Getpid())) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// notifySystemd sends a state notification to systemd, logging a warning if it fails
+func notifySystemd(log *zap.Logger, state string) {
+	if err := sdNotify(state); err != nil {
+		log.Warn("failed to notify systemd", zap.String("state", state), zap.Error(err))
+	}
+}
@@ -0,0 +1,121 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// configWatcher watches the configuration file, and other files that it uses (such as mounted secrets), for changes.
+// The directories that contain the files are watched, rather than the files themselves, because Kubernetes updates a
+// mounted ConfigMap or Secret by replacing the ..data symlink in its directory, which does not change the files that
+// link to it. The files may be given as glob patterns, so that new files in an included directory are seen. A single
+// update causes several events, so a change is only sent on Changed once the files have not changed for the debounce
+// time.
+type configWatcher struct {
+	// Changed receives a value after each change to the watched files
+	Changed chan struct{}
+
+	log      *zap.Logger
+	watcher  *fsnotify.Watcher
+	patterns []string
+	debounce time.Duration
+	quit     chan struct{}
+}
+
+func newConfigWatcher(files []string, debounce time.Duration, log *zap.Logger) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	configWatcher := &configWatcher{
+		Changed:  make(chan struct{}, 1),
+		log:      log,
+		watcher:  watcher,
+		patterns: make([]string, 0, len(files)),
+		debounce: debounce,
+		quit:     make(chan struct{}),
+	}
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		file = filepath.Clean(file)
+		configWatcher.patterns = append(configWatcher.patterns, file)
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	go configWatcher.run()
+	return configWatcher, nil
+}
+
+// isWatchedEvent returns true if the event is for one of the watched files, or for a Kubernetes update of the directory
+// that it is in
+func (w *configWatcher) isWatchedEvent(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	if strings.HasPrefix(filepath.Base(name), "..") {
+		return true
+	}
+	for _, pattern := range w.patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *configWatcher) run() {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.isWatchedEvent(event) {
+				debounce = time.After(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.log.Warn("error watching configuration files", zap.Error(err))
+		case <-debounce:
+			debounce = nil
+			select {
+			case w.Changed <- struct{}{}:
+			default:
+				// A change is already waiting to be handled
+			}
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// Close stops watching the files
+func (w *configWatcher) Close() {
+	close(w.quit)
+	w.watcher.Close()
+}
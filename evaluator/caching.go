@@ -11,14 +11,18 @@
 package evaluator
 
 import (
+	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/karrick/goswarm"
+	"github.com/rcrowley/go-metrics"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -33,15 +37,59 @@ type CachingEvaluator struct {
 	// fields that are appropriate to identify this coordinator
 	Log *zap.Logger
 
-	name            string
-	expireCache     int
-	minimumComplete float32
+	name               string
+	expireCache        int
+	minimumComplete    float32
+	expectedGroupGrace int64
+	maxCommitInterval  int64
+	retentionMargin    int64
+	aggregation        string
+	aggregationValue   float64
+	metaGroup          string
+	storageSaturation  float64
+	startTime          int64
 
 	RequestChannel chan *protocol.EvaluatorRequest
 	running        sync.WaitGroup
 	cache          *goswarm.Simple
+
+	// The trace context of a request that is being traced, keyed by the cache key, so that an evaluation done for it is
+	// in the same trace. If several requests for the same group are waiting on one evaluation, it is in the trace of the
+	// first of them.
+	traces sync.Map
+
+	// The status of each group at its last evaluation, keyed by the cache key, to find the changes in status
+	lastStatus sync.Map
+
+	// Groups are evaluated in the background each warmInterval, if it is set, so that requests are answered from the
+	// cache. If warmGroups is set, only that many of the most requested groups are, using the counts of requests since
+	// the last time, keyed by the cache key
+	warmInterval    time.Duration
+	warmGroups      int
+	warmConcurrency int
+	requestCounts   sync.Map
+	quitChannel     chan struct{}
+
+	evaluationTime metrics.Histogram
+	roundTripTime  metrics.Histogram
+	notFoundCount  metrics.Counter
+	errorCount     metrics.Counter
+	warmTime       metrics.Histogram
+	warmedCount    metrics.Counter
 }
 
+// The policies that can be used to roll up partition statuses into the group status
+const (
+	// The group status is the worst partition status
+	aggregateWorst = "worst"
+
+	// The group status is the worst status that at least aggregation-threshold percent of partitions are in
+	aggregatePercentage = "percentage"
+
+	// The group status is the worst partition status after ignoring the ignore-partitions worst partitions
+	aggregateIgnoreWorst = "ignore-worst"
+)
+
 type cacheError struct {
 	StatusCode int
 	Reason     string
@@ -52,25 +100,83 @@ func (e *cacheError) Error() string {
 }
 
 // Configure validates the configuration for the module, creates a channel to receive requests on, and sets up the
-// cache. If no expiration time for cache entries is set, a default value of 10 seconds is used. If there is any problem
-// starting the goswarm cache, this func panics.
+// cache. If no expiration time for cache entries is set, a default value of 10 seconds is used. If no grace period for
+// expected groups is set, a default value of 600 seconds is used. The maximum commit interval rule is disabled unless
+// an interval is configured, as is the retention margin rule. If the meta group is enabled (see helpers.GetMetaGroup),
+// its storage check is WARN when the storage queues are at least meta-storage-saturation percent full (80 by default).
+// Partition statuses are aggregated into the group status using the worst partition status unless another aggregation
+// policy is configured. The cache is only warmed in the background if warm-interval is set, in which case it must be
+// less than expire-cache. If the aggregation policy is not valid, or if there is any problem starting the goswarm
+// cache, this func panics.
 func (module *CachingEvaluator) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
 	module.name = name
 	module.RequestChannel = make(chan *protocol.EvaluatorRequest)
 	module.running = sync.WaitGroup{}
+	module.evaluationTime = helpers.GetMetricHistogram(configRoot + ".evaluation-time")
+	module.roundTripTime = helpers.GetMetricHistogram("evaluator.round-trip-time")
+	module.notFoundCount = helpers.GetMetricCounter(configRoot + ".not-found")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+	module.warmTime = helpers.GetMetricHistogram(configRoot + ".warm-time")
+	module.warmedCount = helpers.GetMetricCounter(configRoot + ".warmed")
+	module.quitChannel = make(chan struct{})
 
 	// Set defaults for configs if needed
 	viper.SetDefault(configRoot+".expire-cache", 10)
 	module.expireCache = viper.GetInt(configRoot + ".expire-cache")
 	module.minimumComplete = float32(viper.GetFloat64(configRoot + ".minimum-complete"))
+	viper.SetDefault(configRoot+".expected-group-grace", 600)
+	module.expectedGroupGrace = viper.GetInt64(configRoot + ".expected-group-grace")
+	module.maxCommitInterval = viper.GetInt64(configRoot + ".max-commit-interval")
+	module.retentionMargin = viper.GetInt64(configRoot + ".retention-margin")
+
+	viper.SetDefault(configRoot+".meta-storage-saturation", 80)
+	module.metaGroup = helpers.GetMetaGroup()
+	module.storageSaturation = viper.GetFloat64(configRoot + ".meta-storage-saturation")
+	if (module.storageSaturation <= 0) || (module.storageSaturation > 100) {
+		panic("Meta storage saturation must be greater than 0 and no more than 100 for evaluator " + name)
+	}
+
+	viper.SetDefault(configRoot+".aggregation", aggregateWorst)
+	module.aggregation = viper.GetString(configRoot + ".aggregation")
+	switch module.aggregation {
+	case aggregateWorst:
+	case aggregatePercentage:
+		viper.SetDefault(configRoot+".aggregation-threshold", 10)
+		module.aggregationValue = viper.GetFloat64(configRoot + ".aggregation-threshold")
+		if (module.aggregationValue <= 0) || (module.aggregationValue > 100) {
+			panic("Aggregation threshold must be greater than 0 and no more than 100 for evaluator " + name)
+		}
+	case aggregateIgnoreWorst:
+		viper.SetDefault(configRoot+".ignore-partitions", 1)
+		module.aggregationValue = float64(viper.GetInt(configRoot + ".ignore-partitions"))
+		if module.aggregationValue < 0 {
+			panic("Number of ignored partitions must not be negative for evaluator " + name)
+		}
+	default:
+		panic("Unknown aggregation policy '" + module.aggregation + "' for evaluator " + name)
+	}
 	cacheExpire := time.Duration(module.expireCache) * time.Second
 
+	viper.SetDefault(configRoot+".warm-concurrency", 4)
+	module.warmInterval = time.Duration(viper.GetInt(configRoot+".warm-interval")) * time.Second
+	module.warmGroups = viper.GetInt(configRoot + ".warm-groups")
+	module.warmConcurrency = viper.GetInt(configRoot + ".warm-concurrency")
+	if module.warmInterval < 0 {
+		panic("Warm interval must not be negative for evaluator " + name)
+	}
+	if (module.warmInterval > 0) && (module.warmInterval >= cacheExpire) {
+		panic("Warm interval must be less than expire-cache for evaluator " + name)
+	}
+	if (module.warmGroups < 0) || (module.warmConcurrency < 1) {
+		panic("Warm groups must not be negative, and warm concurrency must be at least 1, for evaluator " + name)
+	}
+
 	newCache, err := goswarm.NewSimple(&goswarm.Config{
 		GoodExpiryDuration: cacheExpire,
 		BadExpiryDuration:  cacheExpire,
-		Lookup:             module.evaluateConsumerStatus,
+		Lookup:             module.evaluateAndPublish,
 	})
 	if err != nil {
 		module.Log.Panic("Failed to start cache")
@@ -84,19 +190,29 @@ func (module *CachingEvaluator) GetCommunicationChannel() chan *protocol.Evaluat
 	return module.RequestChannel
 }
 
-// Start instantiates the main loop that listens for evaluation requests and returns the result
+// Start instantiates the main loop that listens for evaluation requests and returns the result, and the loop that
+// warms the cache, if it is enabled
 func (module *CachingEvaluator) Start() error {
 	module.Log.Info("starting")
 
+	module.startTime = time.Now().Unix() * 1000
+
 	module.running.Add(1)
 	go module.mainLoop()
+
+	if module.warmInterval > 0 {
+		module.running.Add(1)
+		go module.warmLoop()
+	}
 	return nil
 }
 
-// Stop closes the module's RequestChannel, which also terminates the main loop that responds to requests
+// Stop closes the module's RequestChannel, which also terminates the main loop that responds to requests, and stops
+// the loop that warms the cache
 func (module *CachingEvaluator) Stop() error {
 	module.Log.Info("stopping")
 
+	close(module.quitChannel)
 	close(module.RequestChannel)
 	module.running.Wait()
 	return nil
@@ -120,9 +236,28 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 		zap.Bool("showall", request.ShowAll),
 	)
 
-	result, err := module.cache.Query(request.Cluster + " " + request.Group)
+	span := helpers.StartSpan("evaluator "+module.name, helpers.SpanKindInternal, request.Trace)
+	defer span.End()
+	span.SetAttribute("kafka.cluster", request.Cluster)
+	span.SetAttribute("kafka.consumer_group", request.Group)
+
+	cacheKey := request.Cluster + " " + request.Group
+	module.countRequest(cacheKey)
+	if span != nil {
+		if _, loaded := module.traces.LoadOrStore(cacheKey, span.Context()); !loaded {
+			defer module.traces.Delete(cacheKey)
+		}
+	}
+
+	result, err := module.cache.Query(cacheKey)
 	if err != nil {
 		requestLogger.Info(err.Error())
+		if cacheErr, ok := err.(*cacheError); ok && (cacheErr.StatusCode == 404) {
+			module.notFoundCount.Inc(1)
+		} else {
+			module.errorCount.Inc(1)
+			span.SetError(err.Error())
+		}
 
 		// We're just returning all errors as a 404 here
 		request.Reply <- &protocol.ConsumerGroupStatus{
@@ -148,8 +283,16 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 				Complete:        cachedStatus.Complete,
 				Maxlag:          cachedStatus.Maxlag,
 				TotalLag:        cachedStatus.TotalLag,
+				MaxTimeLag:      cachedStatus.MaxTimeLag,
+				CommitRate:      cachedStatus.CommitRate,
+				ProduceRate:     cachedStatus.ProduceRate,
 				TotalPartitions: cachedStatus.TotalPartitions,
 				Partitions:      make([]*protocol.PartitionStatus, cachedStatus.TotalPartitions),
+				Members:         cachedStatus.Members,
+				State:           cachedStatus.State,
+				Rewinds:         cachedStatus.Rewinds,
+				TopicRemovals:   cachedStatus.TopicRemovals,
+				ClusterLabels:   cachedStatus.ClusterLabels,
 			}
 
 			// Copy over any partitions that do not have the status StatusOK
@@ -166,6 +309,35 @@ func (module *CachingEvaluator) getConsumerStatus(request *protocol.EvaluatorReq
 		requestLogger.Debug("ok")
 		request.Reply <- status
 	}
+
+	// The time from the sender building the request to it having the status
+	if !request.Submitted.IsZero() {
+		helpers.UpdateMetricTime(module.roundTripTime, request.Submitted)
+	}
+}
+
+// evaluateAndPublish evaluates the status of the group, and publishes an EventStatusChange if the status is different
+// from the last time the group was evaluated. Nothing is published the first time a group is evaluated.
+func (module *CachingEvaluator) evaluateAndPublish(clusterAndConsumer string) (interface{}, error) {
+	result, err := module.evaluateConsumerStatus(clusterAndConsumer)
+	if err != nil {
+		module.lastStatus.Delete(clusterAndConsumer)
+		return result, err
+	}
+
+	status := result.(*protocol.ConsumerGroupStatus)
+	previous, ok := module.lastStatus.Load(clusterAndConsumer)
+	module.lastStatus.Store(clusterAndConsumer, status.Status)
+	if ok && (previous.(protocol.StatusConstant) != status.Status) {
+		helpers.PublishEvent(&protocol.Event{
+			Type:           protocol.EventStatusChange,
+			Cluster:        status.Cluster,
+			Group:          status.Group,
+			PreviousStatus: previous.(protocol.StatusConstant),
+			Status:         status.Status,
+		})
+	}
+	return result, nil
 }
 
 func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string) (interface{}, error) {
@@ -178,17 +350,60 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 	cluster := parts[0]
 	consumer := parts[1]
 
-	// Fetch all the consumer offset and lag information from storage
+	// Only the evaluations are timed, as the requests that are answered from the cache take almost no time
+	defer helpers.UpdateMetricTime(module.evaluationTime, time.Now())
+
+	// If a request that is waiting for this evaluation is being traced, the storage requests are in its trace
+	var span *helpers.Span
+	if trace, ok := module.traces.Load(clusterAndConsumer); ok {
+		span = helpers.StartSpan("evaluate consumer status", helpers.SpanKindInternal, trace.(*protocol.TraceContext))
+		defer span.End()
+	}
+	trace := span.Context()
+
+	// The meta group is Burrow's own health, rather than a group in storage
+	if (module.metaGroup != "") && (consumer == module.metaGroup) {
+		return module.evaluateMetaGroupStatus(cluster, trace)
+	}
+
+	// Fetch all the consumer offset and lag information, and the rest of the group information, from storage
 	storageRequest := &protocol.StorageRequest{
 		RequestType: protocol.StorageFetchConsumer,
 		Cluster:     cluster,
 		Group:       consumer,
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       trace,
+	}
+	module.App.GetStorageChannel(protocol.StoragePriorityEvaluation) <- storageRequest
+	detail, _ := (<-storageRequest.Reply).(*protocol.ConsumerGroupDetail)
+
+	// If the group has been registered as expected, we need to know when that happened to check for a missing group
+	var registered int64
+	if detail != nil {
+		registered = detail.ExpectedSince
 	}
-	module.App.StorageChannel <- storageRequest
-	response := <-storageRequest.Reply
 
-	if response == nil {
+	if (detail == nil) || (detail.Topics == nil) {
+		if isExpectedGroupMissing(registered, 0, module.expectedGroupGrace, time.Now().Unix()) {
+			// The group is expected, but we have never seen it (or it has expired). This is not an error
+			module.Log.Debug("evaluation result",
+				zap.String("cluster", cluster),
+				zap.String("consumer", consumer),
+				zap.String("status", protocol.StatusMissing.String()),
+			)
+			return &protocol.ConsumerGroupStatus{
+				Cluster:       cluster,
+				Group:         consumer,
+				Status:        protocol.StatusMissing,
+				Complete:      1.0,
+				Partitions:    make([]*protocol.PartitionStatus, 0),
+				Maxlag:        nil,
+				TotalLag:      0,
+				ClusterLabels: helpers.GetClusterLabels(cluster),
+			}, nil
+		}
+
 		// Either the cluster or the consumer doesn't exist. In either case, return an error
 		module.Log.Debug("evaluation result",
 			zap.String("cluster", cluster),
@@ -207,10 +422,19 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 		Maxlag:          nil,
 		TotalLag:        0,
 		TotalPartitions: 0,
+		Members:         detail.Members,
+		State:           detail.State,
+		ClusterLabels:   helpers.GetClusterLabels(cluster),
+	}
+	if len(detail.Rewinds) > 0 {
+		status.Rewinds = detail.Rewinds
+	}
+	if len(detail.TopicRemovals) > 0 {
+		status.TopicRemovals = detail.TopicRemovals
 	}
 
 	// Count up the number of partitions for this consumer first, so we can size our slice correctly
-	topics := response.(protocol.ConsumerTopics)
+	topics := detail.Topics
 	for _, partitions := range topics {
 		for _, partition := range partitions {
 			status.TotalPartitions++
@@ -221,26 +445,26 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 
 	count := 0
 	completePartitions := 0
+	var lastCommit int64
 	for topic, partitions := range topics {
 		for partitionID, partition := range partitions {
-			partitionStatus := evaluatePartitionStatus(partition, module.minimumComplete)
+			partitionStatus := evaluatePartitionStatus(partition, module.minimumComplete, module.maxCommitInterval, module.retentionMargin)
+			if (partitionStatus.End != nil) && (partitionStatus.End.Timestamp > lastCommit) {
+				lastCommit = partitionStatus.End.Timestamp
+			}
 			partitionStatus.Topic = topic
 			partitionStatus.Partition = int32(partitionID)
 			partitionStatus.Owner = partition.Owner
 			partitionStatus.ClientID = partition.ClientID
 
-			if partitionStatus.Status > status.Status {
-				// If the partition status is greater than StatusError, we just mark it as StatusError
-				if partitionStatus.Status > protocol.StatusError {
-					status.Status = protocol.StatusError
-				} else {
-					status.Status = partitionStatus.Status
-				}
-			}
-
 			if (status.Maxlag == nil) || (partitionStatus.CurrentLag > status.Maxlag.CurrentLag) {
 				status.Maxlag = partitionStatus
 			}
+			if partitionStatus.TimeLag > status.MaxTimeLag {
+				status.MaxTimeLag = partitionStatus.TimeLag
+			}
+			status.CommitRate += partitionStatus.CommitRate
+			status.ProduceRate += partitionStatus.ProduceRate
 			if partitionStatus.Complete == 1.0 {
 				completePartitions++
 			}
@@ -249,6 +473,8 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 		}
 	}
 
+	status.Status = module.aggregatePartitionStatus(status.Partitions)
+
 	// Calculate completeness as a percentage of the number of partitions that are complete
 	if status.TotalPartitions > 0 {
 		status.Complete = float32(completePartitions) / float32(status.TotalPartitions)
@@ -256,6 +482,11 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 		status.Complete = 0
 	}
 
+	// An expected group that has stopped committing entirely is reported as missing, regardless of partition status
+	if isExpectedGroupMissing(registered, lastCommit, module.expectedGroupGrace, time.Now().Unix()) {
+		status.Status = protocol.StatusMissing
+	}
+
 	module.Log.Debug("evaluation result",
 		zap.String("cluster", cluster),
 		zap.String("consumer", consumer),
@@ -267,10 +498,54 @@ func (module *CachingEvaluator) evaluateConsumerStatus(clusterAndConsumer string
 	return status, nil
 }
 
-func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumComplete float32) *protocol.PartitionStatus {
+// aggregatePartitionStatus rolls up the statuses of the partitions into a single group status according to the
+// configured aggregation policy. Any partition status that is worse than StatusError is counted as StatusError
+func (module *CachingEvaluator) aggregatePartitionStatus(partitions []*protocol.PartitionStatus) protocol.StatusConstant {
+	statuses := make([]protocol.StatusConstant, len(partitions))
+	for i, partition := range partitions {
+		statuses[i] = partition.Status
+		if statuses[i] > protocol.StatusError {
+			statuses[i] = protocol.StatusError
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] > statuses[j] })
+
+	// Pick the position in the sorted statuses (worst first) that determines the group status
+	index := 0
+	switch module.aggregation {
+	case aggregatePercentage:
+		index = int(math.Ceil(float64(len(statuses))*module.aggregationValue/100)) - 1
+	case aggregateIgnoreWorst:
+		index = int(module.aggregationValue)
+	}
+
+	if (index < 0) || (index >= len(statuses)) || (statuses[index] < protocol.StatusOK) {
+		return protocol.StatusOK
+	}
+	return statuses[index]
+}
+
+// An expected group is missing if it has not committed an offset within the grace period. The grace period starts from
+// the later of the registration time or the last commit, so a newly registered group has time to make its first commit
+func isExpectedGroupMissing(registered, lastCommit, grace, timeNow int64) bool {
+	if registered == 0 {
+		return false
+	}
+	if lastCommit < registered {
+		lastCommit = registered
+	}
+	return ((timeNow * 1000) - lastCommit) > (grace * 1000)
+}
+
+func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumComplete float32, maxCommitInterval, retentionMargin int64) *protocol.PartitionStatus {
 	status := &protocol.PartitionStatus{
-		Status:     protocol.StatusOK,
-		CurrentLag: partition.CurrentLag,
+		Status:           protocol.StatusOK,
+		CurrentLag:       partition.CurrentLag,
+		TimeLag:          partition.TimeLag,
+		CommitRate:       partition.CommitRate,
+		ProduceRate:      partition.ProduceRate,
+		RetentionHorizon: partition.RetentionHorizon,
+		Idle:             isPartitionIdle(partition.BrokerOffsets),
 	}
 
 	// If there are no offsets, we can't do anything
@@ -304,13 +579,42 @@ func evaluatePartitionStatus(partition *protocol.ConsumerPartition, minimumCompl
 
 	// If the partition does not meet the completeness threshold, just return it as OK
 	if status.Complete >= minimumComplete {
-		status.Status = calculatePartitionStatus(offsets, partition.BrokerOffsets, partition.CurrentLag, time.Now().Unix())
+		status.Status = calculatePartitionStatus(offsets, partition.BrokerOffsets, partition.CurrentLag, time.Now().Unix(), maxCommitInterval)
+	}
+
+	// A lagging consumer that retention will overtake within the margin is about to lose data, so it is at least a
+	// warning, even if it is otherwise keeping up
+	if (status.Status == protocol.StatusOK) && checkIfRetentionMarginExceeded(partition.RetentionHorizon, partition.CurrentLag, retentionMargin) {
+		status.Status = protocol.StatusWarning
+	}
+
+	// A consumer whose last commit is below the log start offset has already lost messages to retention, which is
+	// worse than anything else, so this applies even if the partition does not meet the completeness threshold
+	if (partition.LogStartOffset > 0) && (status.End.Offset < partition.LogStartOffset) {
+		status.Status = protocol.StatusDataLoss
 	}
 
 	return status
 }
 
-func calculatePartitionStatus(offsets []*protocol.ConsumerOffset, brokerOffsets []int64, currentLag uint64, timeNow int64) protocol.StatusConstant {
+func calculatePartitionStatus(offsets []*protocol.ConsumerOffset, brokerOffsets []int64, currentLag uint64, timeNow, maxCommitInterval int64) protocol.StatusConstant {
+	// If nothing has been produced to the partition over the window, and the consumer has read everything but the
+	// transaction marker at the end, the lag rules do not apply, as the lag will not change until new messages arrive.
+	// Neither does the maximum commit interval, as a consumer has nothing to commit until then. A consumer with more
+	// lag than that on an idle partition is still checked, as it may have stopped or stalled.
+	if isPartitionIdle(brokerOffsets) && (currentLag <= idleMarkerLag) {
+		if checkIfOffsetsRewind(offsets) {
+			return protocol.StatusRewind
+		}
+		return protocol.StatusOK
+	}
+
+	// A consumer that has not committed within the maximum interval is stopped, even if it has no lag. This catches
+	// consumers that stop right after catching up, once new messages are produced to the partition
+	if checkIfCommitIntervalExceeded(offsets, timeNow, maxCommitInterval) {
+		return protocol.StatusStop
+	}
+
 	// If the current lag is zero, the partition is never in error
 	if currentLag > 0 {
 		// Check if the partition is stopped first, as this is a problem even if the consumer had zero lag at some
@@ -392,6 +696,43 @@ func checkIfLagNotDecreasing(offsets []*protocol.ConsumerOffset) bool {
 	return true
 }
 
+// Rule 6 - If the last offset commit is older than the maximum commit interval, the partition is stopped (error). This
+// rule is disabled if the maximum commit interval is not set, and does not apply to idle partitions
+func checkIfCommitIntervalExceeded(offsets []*protocol.ConsumerOffset, timeNow, maxCommitInterval int64) bool {
+	if maxCommitInterval <= 0 {
+		return false
+	}
+	return ((timeNow * 1000) - offsets[len(offsets)-1].Timestamp) > (maxCommitInterval * 1000)
+}
+
+// Rule 7 - If retention is estimated to remove the consumer's last committed offset within the retention margin, and
+// the consumer is lagging, it is a warning (consumer is about to lose data). This rule is disabled if the retention
+// margin is not set
+func checkIfRetentionMarginExceeded(retentionHorizon int64, currentLag uint64, retentionMargin int64) bool {
+	if (retentionMargin <= 0) || (retentionHorizon < 0) || (currentLag == 0) {
+		return false
+	}
+	return retentionHorizon < (retentionMargin * 1000)
+}
+
+// The lag that a consumer that has read every message in a partition can be left with, as a transactional producer
+// writes a marker after the last message of each transaction that the consumer never sees as a message
+const idleMarkerLag = 1
+
+// A partition is idle if the broker end offset has not changed at all over the stored broker offsets. At least two
+// broker offsets are required to tell this
+func isPartitionIdle(brokerOffsets []int64) bool {
+	if len(brokerOffsets) < 2 {
+		return false
+	}
+	for i := 1; i < len(brokerOffsets); i++ {
+		if brokerOffsets[i] != brokerOffsets[0] {
+			return false
+		}
+	}
+	return true
+}
+
 // Using the most recent committed offset, return true if there was zero lag at some point in the stored broker
 // LEO offsets. This has the effect of returning true if the consumer was up to date on this partition in recent
 // (minutes) history, so it can be used to delay alerting for a short period of time.
@@ -19,6 +19,7 @@ import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 	"github.com/linkedin/Burrow/storage"
 )
@@ -475,6 +476,28 @@ var tests = []testset{
 	},
 }
 
+func TestCachingEvaluator_StatusChangeEvent(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+	events := helpers.SubscribeEvents(protocol.EventStatusChange)
+	defer events.Close()
+
+	// The first evaluation has nothing to compare to, and evaluating again with the same status is not a change
+	module.evaluateAndPublish("testcluster testgroup")
+	module.evaluateAndPublish("testcluster testgroup")
+	assert.Len(t, events.Events, 0, "Expected no events without a change in status")
+
+	module.lastStatus.Store("testcluster testgroup", protocol.StatusError)
+	module.evaluateAndPublish("testcluster testgroup")
+	assert.Len(t, events.Events, 1, "Expected an event for the change in status")
+	event := <-events.Events
+	assert.Equal(t, "testcluster", event.Cluster, "Expected the event to be for testcluster")
+	assert.Equal(t, "testgroup", event.Group, "Expected the event to be for testgroup")
+	assert.Equal(t, protocol.StatusError, event.PreviousStatus, "Expected the previous status to be ERR")
+	assert.Equal(t, protocol.StatusOK, event.Status, "Expected the status to be OK")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
 func TestCachingEvaluator_CheckRules(t *testing.T) {
 	for i, testSet := range tests {
 		result := isLagAlwaysNotZero(testSet.offsets)
@@ -495,7 +518,7 @@ func TestCachingEvaluator_CheckRules(t *testing.T) {
 		result = checkIfRecentLagZero(testSet.offsets, testSet.brokerOffsets)
 		assert.Equalf(t, testSet.checkIfRecentLagZero, result, "TEST %v: Expected checkIfRecentLagZero to return %v, not %v", i, testSet.checkIfRecentLagZero, result)
 
-		status := calculatePartitionStatus(testSet.offsets, testSet.brokerOffsets, testSet.currentLag, testSet.timeNow)
+		status := calculatePartitionStatus(testSet.offsets, testSet.brokerOffsets, testSet.currentLag, testSet.timeNow, 0)
 		assert.Equalf(t, testSet.status, status, "TEST %v: Expected calculatePartitionStatus to return %v, not %v", i, testSet.status.String(), status.String())
 	}
 }
@@ -532,3 +555,571 @@ func TestCachingEvaluator_TopicDeleted(t *testing.T) {
 	assert.Emptyf(t, evalResponse.Partitions, "Expected no partitions to be returned")
 	assert.Equalf(t, float32(0.0), evalResponse.Complete, "Expected 'Complete' to be 0.0")
 }
+
+func TestCachingEvaluator_GroupMembers(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members: []*protocol.ConsumerGroupMember{
+			{
+				MemberID:   "testmember",
+				ClientID:   "testclient",
+				ClientHost: "testhost",
+				Assignment: map[string][]int32{"testtopic": {0}},
+			},
+		},
+		GroupState: "Stable",
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Lenf(t, response.Members, 1, "Expected exactly one member, not %v", len(response.Members))
+	assert.Equalf(t, "testmember", response.Members[0].MemberID, "Expected member MemberID to be testmember, not %v", response.Members[0].MemberID)
+	assert.Equalf(t, "Stable", response.State, "Expected group State to be Stable, not %v", response.State)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Rewinds(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	// The fixture commits offsets up to 1900, so committing 1000 rewinds the group
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Order:       20,
+		Offset:      1000,
+		Timestamp:   time.Now().Unix() * 1000,
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Lenf(t, response.Rewinds, 1, "Expected exactly one rewind, not %v", len(response.Rewinds))
+	assert.Equalf(t, int64(1900), response.Rewinds[0].PreviousOffset, "Expected rewind PreviousOffset to be 1900, not %v", response.Rewinds[0].PreviousOffset)
+	assert.Equalf(t, int64(1000), response.Rewinds[0].Offset, "Expected rewind Offset to be 1000, not %v", response.Rewinds[0].Offset)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_TopicRemovals(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Reason:      "deleted",
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Lenf(t, response.TopicRemovals, 1, "Expected exactly one topic removal, not %v", len(response.TopicRemovals))
+	assert.Equalf(t, "testtopic", response.TopicRemovals[0].Topic, "Expected removal Topic to be testtopic, not %v", response.TopicRemovals[0].Topic)
+	assert.Equalf(t, "deleted", response.TopicRemovals[0].Reason, "Expected removal Reason to be deleted, not %v", response.TopicRemovals[0].Reason)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_ClusterLabels(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+	viper.Set("cluster.testcluster.labels", map[string]interface{}{"env": "prod"})
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Equal(t, map[string]string{"env": "prod"}, response.ClusterLabels, "Expected the cluster labels in the group status")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_ExpectedGroupMissing(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	// Register a group that has never committed offsets, well outside the grace period
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "nosuchgroup",
+		Timestamp:   (time.Now().Unix() - 3600) * 1000,
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "nosuchgroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Equalf(t, protocol.StatusMissing, response.Status, "Expected status to be MISSING, not %v", response.Status.String())
+	assert.Equalf(t, "nosuchgroup", response.Group, "Expected group to be nosuchgroup, not %v", response.Group)
+	assert.Lenf(t, response.Partitions, 0, "Expected 0 partition status objects, not %v", len(response.Partitions))
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_ExpectedGroupInGracePeriod(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "nosuchgroup",
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "nosuchgroup",
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Equalf(t, protocol.StatusNotFound, response.Status, "Expected status to be NOTFOUND, not %v", response.Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_ExpectedGroupStoppedCommitting(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.expected-group-grace", 1)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	storageCoordinator.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Timestamp:   (time.Now().Unix() - 3600) * 1000,
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	assert.Equalf(t, protocol.StatusMissing, response.Status, "Expected status to be MISSING, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 1, "Expected 1 partition status objects, not %v", len(response.Partitions))
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_IsExpectedGroupMissing(t *testing.T) {
+	assert.False(t, isExpectedGroupMissing(0, 0, 600, 10000), "Expected unregistered group to not be missing")
+	assert.False(t, isExpectedGroupMissing(9500000, 0, 600, 10000), "Expected group within grace period of registration to not be missing")
+	assert.True(t, isExpectedGroupMissing(1000000, 0, 600, 10000), "Expected group with no commits past grace period to be missing")
+	assert.False(t, isExpectedGroupMissing(1000000, 9900000, 600, 10000), "Expected group with recent commit to not be missing")
+	assert.True(t, isExpectedGroupMissing(1000000, 2000000, 600, 10000), "Expected group with old commit to be missing")
+}
+
+func TestCachingEvaluator_CheckIfCommitIntervalExceeded(t *testing.T) {
+	offsets := []*protocol.ConsumerOffset{
+		{Offset: 1000, Timestamp: 1000000, Lag: &protocol.Lag{Value: 0}},
+		{Offset: 2000, Timestamp: 2000000, Lag: &protocol.Lag{Value: 0}},
+	}
+
+	assert.False(t, checkIfCommitIntervalExceeded(offsets, 3000, 0), "Expected rule to be disabled with no interval set")
+	assert.False(t, checkIfCommitIntervalExceeded(offsets, 3000, 1200), "Expected commit within interval to pass")
+	assert.True(t, checkIfCommitIntervalExceeded(offsets, 3000, 600), "Expected commit outside interval to fail")
+
+	// The rule applies even when the consumer has no lag
+	status := calculatePartitionStatus(offsets, []int64{2000}, 0, 3000, 600)
+	assert.Equalf(t, protocol.StatusStop, status, "Expected calculatePartitionStatus to return STOP, not %v", status.String())
+	status = calculatePartitionStatus(offsets, []int64{2000}, 0, 3000, 0)
+	assert.Equalf(t, protocol.StatusOK, status, "Expected calculatePartitionStatus to return OK, not %v", status.String())
+}
+
+func TestCachingEvaluator_CommitIntervalIdlePartition(t *testing.T) {
+	offsets := []*protocol.ConsumerOffset{
+		{Offset: 1000, Timestamp: 1000000, Lag: &protocol.Lag{Value: 0}},
+		{Offset: 1000, Timestamp: 2000000, Lag: &protocol.Lag{Value: 0}},
+	}
+
+	commitIntervalTests := []struct {
+		brokerOffsets []int64
+		currentLag    uint64
+		status        protocol.StatusConstant
+	}{
+		// An idle partition with an old commit and no lag has nothing to commit
+		{[]int64{1000, 1000, 1000}, 0, protocol.StatusOK},
+		{[]int64{1001, 1001, 1001}, 1, protocol.StatusOK},
+
+		// A consumer with more lag than a transaction marker is still stopped
+		{[]int64{1500, 1500, 1500}, 500, protocol.StatusStop},
+
+		// A partition that is not idle is still stopped, even with no lag
+		{[]int64{1000}, 0, protocol.StatusStop},
+		{[]int64{998, 999, 1000}, 0, protocol.StatusStop},
+	}
+	for i, testSet := range commitIntervalTests {
+		status := calculatePartitionStatus(offsets, testSet.brokerOffsets, testSet.currentLag, 3000, 600)
+		assert.Equalf(t, testSet.status, status, "TEST %v: Expected calculatePartitionStatus to return %v, not %v", i, testSet.status.String(), status.String())
+	}
+}
+
+func TestCachingEvaluator_IdlePartition(t *testing.T) {
+	assert.False(t, isPartitionIdle([]int64{}), "Expected no broker offsets to not be idle")
+	assert.False(t, isPartitionIdle([]int64{1000}), "Expected a single broker offset to not be idle")
+	assert.False(t, isPartitionIdle([]int64{1000, 1000, 1001}), "Expected moving broker offsets to not be idle")
+	assert.True(t, isPartitionIdle([]int64{1000, 1000, 1000}), "Expected unchanged broker offsets to be idle")
+
+	// A consumer left with the lag of a transaction marker on an idle partition would normally be stalled
+	offsets := []*protocol.ConsumerOffset{
+		{Offset: 999, Timestamp: 1000000, Lag: &protocol.Lag{Value: 1}},
+		{Offset: 999, Timestamp: 2000000, Lag: &protocol.Lag{Value: 1}},
+		{Offset: 999, Timestamp: 3000000, Lag: &protocol.Lag{Value: 1}},
+	}
+	status := calculatePartitionStatus(offsets, []int64{1000, 1000, 1000}, 1, 3000, 0)
+	assert.Equalf(t, protocol.StatusOK, status, "Expected calculatePartitionStatus to return OK, not %v", status.String())
+	status = calculatePartitionStatus(offsets, []int64{998, 999, 1000}, 1, 3000, 0)
+	assert.Equalf(t, protocol.StatusStall, status, "Expected calculatePartitionStatus to return STALL, not %v", status.String())
+
+	// A consumer with more lag than that on an idle partition is still checked
+	for _, offset := range offsets {
+		offset.Offset = 500
+		offset.Lag = &protocol.Lag{Value: 500}
+	}
+	status = calculatePartitionStatus(offsets, []int64{1000, 1000, 1000}, 500, 3000, 0)
+	assert.Equalf(t, protocol.StatusStall, status, "Expected calculatePartitionStatus to return STALL, not %v", status.String())
+	status = calculatePartitionStatus(offsets, []int64{1000, 1000, 1000}, 500, 10000, 0)
+	assert.Equalf(t, protocol.StatusStop, status, "Expected calculatePartitionStatus to return STOP, not %v", status.String())
+
+	// Rewinds are still reported on idle partitions
+	offsets[2].Offset = 400
+	offsets[2].Lag = &protocol.Lag{Value: 600}
+	status = calculatePartitionStatus(offsets, []int64{1000, 1000, 1000}, 600, 3000, 0)
+	assert.Equalf(t, protocol.StatusRewind, status, "Expected calculatePartitionStatus to return REWIND, not %v", status.String())
+	for i, offset := range []int64{999, 998, 999} {
+		offsets[i].Offset = offset
+		offsets[i].Lag = &protocol.Lag{Value: uint64(1000 - offset)}
+	}
+	status = calculatePartitionStatus(offsets, []int64{1000, 1000, 1000}, 1, 3000, 0)
+	assert.Equalf(t, protocol.StatusRewind, status, "Expected calculatePartitionStatus to return REWIND, not %v", status.String())
+
+	partitionStatus := evaluatePartitionStatus(&protocol.ConsumerPartition{
+		Offsets:       offsets,
+		BrokerOffsets: []int64{1000, 1000, 1000},
+		CurrentLag:    1,
+	}, 0, 0, 0)
+	assert.True(t, partitionStatus.Idle, "Expected partition status to be marked idle")
+}
+
+func TestCachingEvaluator_TimeLag(t *testing.T) {
+	partitionStatus := evaluatePartitionStatus(&protocol.ConsumerPartition{
+		Offsets:       []*protocol.ConsumerOffset{{Offset: 900, Timestamp: 1000000, Lag: &protocol.Lag{Value: 100}}},
+		BrokerOffsets: []int64{900, 1000},
+		CurrentLag:    100,
+		TimeLag:       30000,
+		ProduceRate:   12.5,
+	}, 0, 0, 0)
+	assert.Equalf(t, int64(30000), partitionStatus.TimeLag, "Expected partition TimeLag to be 30000, not %v", partitionStatus.TimeLag)
+	assert.Equalf(t, 12.5, partitionStatus.ProduceRate, "Expected partition ProduceRate to be 12.5, not %v", partitionStatus.ProduceRate)
+}
+
+func TestCachingEvaluator_DataLoss(t *testing.T) {
+	timeNow := time.Now().Unix() * 1000
+	partition := &protocol.ConsumerPartition{
+		Offsets: []*protocol.ConsumerOffset{
+			{Offset: 900, Timestamp: timeNow - 10000, Lag: &protocol.Lag{Value: 100}},
+			{Offset: 950, Timestamp: timeNow, Lag: &protocol.Lag{Value: 50}},
+		},
+		BrokerOffsets:  []int64{1000, 1000},
+		CurrentLag:     50,
+		LogStartOffset: 920,
+	}
+
+	partitionStatus := evaluatePartitionStatus(partition, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusOK, partitionStatus.Status, "Expected partition status to be OK, not %v", partitionStatus.Status.String())
+
+	partition.LogStartOffset = 960
+	partitionStatus = evaluatePartitionStatus(partition, 1.1, 0, 0)
+	assert.Equalf(t, protocol.StatusDataLoss, partitionStatus.Status, "Expected partition status to be DATA_LOSS, not %v", partitionStatus.Status.String())
+
+	module := &CachingEvaluator{aggregation: aggregateWorst}
+	status := module.aggregatePartitionStatus([]*protocol.PartitionStatus{partitionStatus})
+	assert.Equalf(t, protocol.StatusError, status, "Expected group status to be ERR, not %v", status.String())
+}
+
+func TestCachingEvaluator_RetentionMargin(t *testing.T) {
+	timeNow := time.Now().Unix() * 1000
+	partition := &protocol.ConsumerPartition{
+		Offsets: []*protocol.ConsumerOffset{
+			{Offset: 900, Timestamp: timeNow - 10000, Lag: &protocol.Lag{Value: 100}},
+			{Offset: 950, Timestamp: timeNow, Lag: &protocol.Lag{Value: 0}},
+		},
+		BrokerOffsets:    []int64{1000, 1000},
+		CurrentLag:       50,
+		LogStartOffset:   920,
+		RetentionHorizon: 600000,
+	}
+
+	// The rule is disabled without a margin
+	partitionStatus := evaluatePartitionStatus(partition, 0, 0, 0)
+	assert.Equalf(t, protocol.StatusOK, partitionStatus.Status, "Expected partition status to be OK, not %v", partitionStatus.Status.String())
+	assert.Equalf(t, int64(600000), partitionStatus.RetentionHorizon, "Expected partition RetentionHorizon to be 600000, not %v", partitionStatus.RetentionHorizon)
+
+	partitionStatus = evaluatePartitionStatus(partition, 0, 0, 3600)
+	assert.Equalf(t, protocol.StatusWarning, partitionStatus.Status, "Expected partition status to be WARN, not %v", partitionStatus.Status.String())
+
+	partitionStatus = evaluatePartitionStatus(partition, 0, 0, 300)
+	assert.Equalf(t, protocol.StatusOK, partitionStatus.Status, "Expected partition status to be OK, not %v", partitionStatus.Status.String())
+
+	// A consumer with no lag, or a horizon that cannot be estimated, is never within the margin
+	assert.False(t, checkIfRetentionMarginExceeded(600000, 0, 3600), "Expected a consumer with no lag to be OK")
+	assert.False(t, checkIfRetentionMarginExceeded(-1, 50, 3600), "Expected an unknown horizon to be OK")
+}
+
+func TestCachingEvaluator_CommitRate(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "testgroup",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	// The fixture commits 10 offsets for the group, all within the last commit-rate-window
+	assert.Equalf(t, 2.0, response.CommitRate, "Expected group CommitRate to be 2.0, not %v", response.CommitRate)
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_Configure_BadAggregation(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.aggregation", "nosuchpolicy")
+
+	assert.Panics(t, func() { module.Configure("test", "evaluator.test") }, "The code did not panic")
+	storageCoordinator.Stop()
+}
+
+func TestCachingEvaluator_Configure_BadAggregationThreshold(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.aggregation", "percentage")
+	viper.Set("evaluator.test.aggregation-threshold", 150)
+
+	assert.Panics(t, func() { module.Configure("test", "evaluator.test") }, "The code did not panic")
+	storageCoordinator.Stop()
+}
+
+func fixturePartitionStatuses(statuses ...protocol.StatusConstant) []*protocol.PartitionStatus {
+	partitions := make([]*protocol.PartitionStatus, len(statuses))
+	for i, status := range statuses {
+		partitions[i] = &protocol.PartitionStatus{Status: status}
+	}
+	return partitions
+}
+
+func TestCachingEvaluator_AggregatePartitionStatus(t *testing.T) {
+	partitions := fixturePartitionStatuses(protocol.StatusOK, protocol.StatusStall, protocol.StatusOK, protocol.StatusWarning,
+		protocol.StatusOK, protocol.StatusOK, protocol.StatusOK, protocol.StatusOK, protocol.StatusOK, protocol.StatusOK)
+
+	module := &CachingEvaluator{aggregation: aggregateWorst}
+	status := module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusError, status, "Expected worst policy to return ERR, not %v", status.String())
+
+	module = &CachingEvaluator{aggregation: aggregateIgnoreWorst, aggregationValue: 1}
+	status = module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusWarning, status, "Expected ignore-worst 1 policy to return WARN, not %v", status.String())
+
+	module = &CachingEvaluator{aggregation: aggregateIgnoreWorst, aggregationValue: 20}
+	status = module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusOK, status, "Expected ignore-worst 20 policy to return OK, not %v", status.String())
+
+	module = &CachingEvaluator{aggregation: aggregatePercentage, aggregationValue: 10}
+	status = module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusError, status, "Expected percentage 10 policy to return ERR, not %v", status.String())
+
+	module = &CachingEvaluator{aggregation: aggregatePercentage, aggregationValue: 20}
+	status = module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusWarning, status, "Expected percentage 20 policy to return WARN, not %v", status.String())
+
+	module = &CachingEvaluator{aggregation: aggregatePercentage, aggregationValue: 30}
+	status = module.aggregatePartitionStatus(partitions)
+	assert.Equalf(t, protocol.StatusOK, status, "Expected percentage 30 policy to return OK, not %v", status.String())
+
+	status = module.aggregatePartitionStatus(fixturePartitionStatuses())
+	assert.Equalf(t, protocol.StatusOK, status, "Expected no partitions to return OK, not %v", status.String())
+}
+
+func TestCachingEvaluator_MetaGroup(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("general.meta-group", "burrow")
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "burrow",
+		ShowAll: true,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	// With no maximum ages configured for the cluster, only the storage queues are checked
+	assert.Equalf(t, protocol.StatusOK, response.Status, "Expected status to be OK, not %v", response.Status.String())
+	assert.Equalf(t, "burrow", response.Group, "Expected group to be burrow, not %v", response.Group)
+	assert.Lenf(t, response.Partitions, 3, "Expected 3 partition status objects, not %v", len(response.Partitions))
+	for _, partition := range response.Partitions {
+		assert.Equalf(t, protocol.StatusOK, partition.Status, "Expected %v to be OK, not %v", partition.Topic, partition.Status.String())
+	}
+
+	request = &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "nocluster",
+		Group:   "burrow",
+	}
+	module.GetCommunicationChannel() <- request
+	response = <-request.Reply
+	assert.Equalf(t, protocol.StatusNotFound, response.Status, "Expected status to be NOTFOUND, not %v", response.Status.String())
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_MetaGroupStale(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("general.meta-group", "burrow")
+	viper.Set("cluster.testcluster.health-max-offset-age", 60)
+	viper.Set("cluster.testcluster.health-max-commit-age", 3600)
+	module.Configure("test", "evaluator.test")
+	module.Start()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster: "testcluster",
+		Group:   "burrow",
+		ShowAll: false,
+	}
+	module.GetCommunicationChannel() <- request
+	response := <-request.Reply
+
+	// The test broker offset was received long ago, so Burrow is not seeing data for the cluster
+	assert.Equalf(t, protocol.StatusError, response.Status, "Expected status to be ERR, not %v", response.Status.String())
+	assert.Lenf(t, response.Partitions, 1, "Expected 1 partition status object, not %v", len(response.Partitions))
+	assert.Equalf(t, metaTopicBrokerOffsets, response.Partitions[0].Topic, "Expected the broker offsets check, not %v", response.Partitions[0].Topic)
+	assert.Equalf(t, protocol.StatusStop, response.Partitions[0].Status, "Expected the check to be STOP, not %v", response.Partitions[0].Status.String())
+	assert.Equalf(t, response.Partitions[0].TimeLag, response.MaxTimeLag, "Expected the max time lag to be the age of the broker offset")
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_GetMetaAgeStatus(t *testing.T) {
+	module := &CachingEvaluator{startTime: 100000}
+
+	partition := module.getMetaAgeStatus(metaTopicConsumerOffsets, 0, 130000, 60000)
+	assert.Equal(t, protocol.StatusOK, partition.Status, "Expected OK when nothing was received, but the evaluator started recently")
+	assert.Equal(t, int64(30000), partition.TimeLag, "Expected the age to be measured from the start")
+
+	partition = module.getMetaAgeStatus(metaTopicConsumerOffsets, 50000, 130000, 60000)
+	assert.Equal(t, protocol.StatusStop, partition.Status, "Expected STOP when the last offset is older than the maximum age")
+	assert.Equal(t, int64(80000), partition.TimeLag, "Expected the age of the last offset")
+
+	partition = module.getMetaAgeStatus(metaTopicConsumerOffsets, 50000, 130000, 0)
+	assert.Equal(t, protocol.StatusOK, partition.Status, "Expected OK when there is no maximum age")
+}
+
+func TestCachingEvaluator_Configure_BadWarmInterval(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.warm-interval", 30)
+
+	assert.Panics(t, func() { module.Configure("test", "evaluator.test") }, "Expected a panic when the cache expires before it is warmed")
+	storageCoordinator.Stop()
+}
+
+func TestCachingEvaluator_WarmCache(t *testing.T) {
+	storageCoordinator, module := startWithTestCluster()
+	warmed := module.warmedCount.Count()
+
+	module.warmCache()
+	assert.Equal(t, warmed+2, module.warmedCount.Count(), "Expected both groups to be warmed")
+	for _, key := range []string{"testcluster testgroup", "testcluster testgroup2"} {
+		value, ok := module.cache.Load(key)
+		assert.True(t, ok, "Expected %v to be in the cache", key)
+		assert.IsType(t, &protocol.ConsumerGroupStatus{}, value, "Expected %v to have a status", key)
+	}
+
+	stopTestCluster(storageCoordinator, module)
+}
+
+func TestCachingEvaluator_GetMostRequested(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.warm-interval", 10)
+	viper.Set("evaluator.test.warm-groups", 2)
+	module.Configure("test", "evaluator.test")
+
+	for i := 0; i < 3; i++ {
+		module.countRequest("testcluster testgroup2")
+	}
+	module.countRequest("testcluster testgroup")
+	module.countRequest("testcluster othergroup")
+	assert.Equal(t, []string{"testcluster testgroup2", "testcluster othergroup"}, module.getMostRequested(2), "Expected the most requested groups")
+
+	// The counts are reset, so groups that are not requested again are dropped
+	module.countRequest("testcluster testgroup")
+	assert.Equal(t, []string{"testcluster testgroup"}, module.getMostRequested(2), "Expected only the group requested since the last time")
+	assert.Empty(t, module.getMostRequested(2), "Expected no groups when none were requested")
+
+	storageCoordinator.Stop()
+}
+
+func TestCachingEvaluator_CountRequest_NoWarmInterval(t *testing.T) {
+	storageCoordinator, module := fixtureModule()
+	viper.Set("evaluator.test.warm-groups", 2)
+	module.Configure("test", "evaluator.test")
+
+	// Without a warm interval the counts would never be reset, so requests are not counted
+	module.countRequest("testcluster testgroup")
+	module.requestCounts.Range(func(key, value interface{}) bool {
+		t.Errorf("Expected no request counts, not %v", key)
+		return true
+	})
+
+	storageCoordinator.Stop()
+}
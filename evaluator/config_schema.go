@@ -0,0 +1,34 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+package evaluator
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("evaluator.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+	)
+	helpers.RegisterConfigKeys("evaluator.*", "caching",
+		helpers.ConfigKey{Name: "expire-cache", Type: helpers.ConfigTypeInteger, Default: 10},
+		helpers.ConfigKey{Name: "minimum-complete", Type: helpers.ConfigTypeFloat},
+		helpers.ConfigKey{Name: "max-commit-interval", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "aggregation", Type: helpers.ConfigTypeString, Default: aggregateWorst},
+		helpers.ConfigKey{Name: "aggregation-threshold", Type: helpers.ConfigTypeFloat, Default: 10},
+		helpers.ConfigKey{Name: "expected-group-grace", Type: helpers.ConfigTypeInteger, Default: 600},
+		helpers.ConfigKey{Name: "ignore-partitions", Type: helpers.ConfigTypeInteger, Default: 1},
+		helpers.ConfigKey{Name: "retention-margin", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "meta-storage-saturation", Type: helpers.ConfigTypeFloat, Default: 80},
+		helpers.ConfigKey{Name: "warm-interval", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "warm-groups", Type: helpers.ConfigTypeInteger},
+		helpers.ConfigKey{Name: "warm-concurrency", Type: helpers.ConfigTypeInteger, Default: 4},
+	)
+}
@@ -70,6 +70,18 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 			),
 		}
 	default:
+		if factory := helpers.GetModuleClass("evaluator", className); factory != nil {
+			module, ok := factory(app, app.Logger.With(
+				zap.String("type", "module"),
+				zap.String("coordinator", "evaluator"),
+				zap.String("class", className),
+				zap.String("name", moduleName),
+			)).(Module)
+			if !ok {
+				panic("Evaluator className " + className + " is not an evaluator module")
+			}
+			return module
+		}
 		panic("Unknown evaluator className provided: " + className)
 	}
 }
@@ -129,13 +141,27 @@ func (ec *Coordinator) Start() error {
 			channel = module.(Module).GetCommunicationChannel()
 		}
 
+		requestCount := helpers.GetMetricCounter("evaluator.requests")
+		evaluatorChannel := ec.App.EvaluatorChannel
+		helpers.RegisterMetricGaugeFunc("evaluator.channel-depth", func() int64 {
+			return int64(len(evaluatorChannel))
+		})
+		helpers.GetMetricGauge("evaluator.channel-capacity").Update(int64(cap(evaluatorChannel)))
+		blockedCount := helpers.GetMetricCounter("evaluator.channel-blocked")
+
 		for {
 			select {
 			case request := <-ec.App.EvaluatorChannel:
+				requestCount.Inc(1)
 				// Yes, this forwarder is silly. However, in the future we want to support multiple evaluator modules
 				// concurrently. However, that will require implementing a router that properly handles requests and
 				// makes sure that only 1 evaluator responds
-				channel <- request
+				select {
+				case channel <- request:
+				default:
+					blockedCount.Inc(1)
+					channel <- request
+				}
 			case <-ec.quitChannel:
 				return
 			}
@@ -157,3 +183,15 @@ func (ec *Coordinator) Stop() error {
 	helpers.StopCoordinatorModules(ec.modules)
 	return nil
 }
+
+// Health returns the health of each evaluator module. The coordinator is DEGRADED if the channel for evaluator requests
+// is full, as the senders are waiting.
+func (ec *Coordinator) Health() *protocol.CoordinatorHealth {
+	health := helpers.GetCoordinatorHealth("evaluator", ec.modules)
+	evaluatorChannel := ec.App.EvaluatorChannel
+	if (health.State == protocol.HealthOK) && (cap(evaluatorChannel) > 0) && (len(evaluatorChannel) == cap(evaluatorChannel)) {
+		health.State = protocol.HealthDegraded
+		health.Reason = "evaluator request channel is full"
+	}
+	return health
+}
@@ -12,11 +12,13 @@ package evaluator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -92,6 +94,31 @@ func TestCoordinator_Start(t *testing.T) {
 	storageCoordinator.Stop()
 }
 
+func TestCoordinator_RoundTripTime(t *testing.T) {
+	evaluatorCoordinator, storageCoordinator := StorageAndEvaluatorCoordinatorsWithOffsets()
+	histogram := helpers.GetMetricHistogram("evaluator.round-trip-time")
+	count := histogram.Count()
+
+	request := &protocol.EvaluatorRequest{
+		Reply:     make(chan *protocol.ConsumerGroupStatus),
+		Submitted: time.Now(),
+		Cluster:   "testcluster",
+		Group:     "testgroup",
+	}
+	evaluatorCoordinator.App.EvaluatorChannel <- request
+	response := <-request.Reply
+	assert.Equal(t, "testgroup", response.Group, "Expected the reply to be sent")
+
+	// The time is recorded once the reply has been taken, so it may not be there yet
+	for i := 0; (i < 100) && (histogram.Count() == count); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, count+1, histogram.Count(), "Expected the round trip time to be recorded")
+
+	evaluatorCoordinator.Stop()
+	storageCoordinator.Stop()
+}
+
 func TestCoordinator_MultipleRequests(t *testing.T) {
 	evaluatorCoordinator, storageCoordinator := StorageAndEvaluatorCoordinatorsWithOffsets()
 
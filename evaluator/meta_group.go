@@ -0,0 +1,125 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package evaluator
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// The topics of the checks that make up the status of the meta group. Each check is reported as partition 0 of its
+// topic, so that the status can be handled like that of any other group
+const (
+	// Broker offsets have been fetched within the cluster's health-max-offset-age
+	metaTopicBrokerOffsets = "burrow.broker-offsets"
+
+	// Consumer offset commits have been seen within the cluster's health-max-commit-age
+	metaTopicConsumerOffsets = "burrow.consumer-offsets"
+
+	// The storage queues are below meta-storage-saturation
+	metaTopicStorage = "burrow.storage"
+)
+
+// evaluateMetaGroupStatus returns the status of the meta group for the cluster, which is Burrow's own health. The
+// broker offsets and consumer offsets checks are STOP if no offsets have been received within the same maximum ages
+// as the cluster health endpoint uses, with the age as the time lag. If none have been received since the evaluator
+// started, the age is measured from the start instead, so that a restart is not reported as a failure. The storage
+// check is WARN if the storage queues are saturated, with the number of waiting requests as the lag. The group status
+// is ERR if any check is STOP, as that means Burrow has stopped seeing data and the statuses of the real groups in
+// the cluster cannot be trusted.
+func (module *CachingEvaluator) evaluateMetaGroupStatus(cluster string, trace *protocol.TraceContext) (interface{}, error) {
+	storageRequest := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterActivity,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       trace,
+	}
+	module.App.GetStorageChannel(protocol.StoragePriorityEvaluation) <- storageRequest
+	response := <-storageRequest.Reply
+	if response == nil {
+		module.Log.Debug("evaluation result",
+			zap.String("cluster", cluster),
+			zap.String("consumer", module.metaGroup),
+			zap.String("status", protocol.StatusNotFound.String()),
+		)
+		return nil, &cacheError{StatusCode: 404, Reason: "cluster not found"}
+	}
+	activity := response.(*protocol.ClusterActivity)
+
+	configRoot := "cluster." + cluster
+	timeNow := time.Now().Unix() * 1000
+	partitions := []*protocol.PartitionStatus{
+		module.getMetaAgeStatus(metaTopicBrokerOffsets, activity.LastBrokerOffset, timeNow, viper.GetInt64(configRoot+".health-max-offset-age")*1000),
+		module.getMetaAgeStatus(metaTopicConsumerOffsets, activity.LastConsumerOffset, timeNow, viper.GetInt64(configRoot+".health-max-commit-age")*1000),
+		{Topic: metaTopicStorage, Status: protocol.StatusOK, CurrentLag: uint64(activity.QueueDepth), RetentionHorizon: -1, Complete: 1.0},
+	}
+	if (activity.QueueCapacity > 0) && (float64(activity.QueueDepth)*100/float64(activity.QueueCapacity) >= module.storageSaturation) {
+		partitions[2].Status = protocol.StatusWarning
+	}
+
+	status := &protocol.ConsumerGroupStatus{
+		Cluster:         cluster,
+		Group:           module.metaGroup,
+		Status:          protocol.StatusOK,
+		Complete:        1.0,
+		Partitions:      partitions,
+		TotalPartitions: len(partitions),
+		ClusterLabels:   helpers.GetClusterLabels(cluster),
+	}
+	for _, partition := range partitions {
+		switch {
+		case partition.Status == protocol.StatusStop:
+			status.Status = protocol.StatusError
+		case (partition.Status == protocol.StatusWarning) && (status.Status == protocol.StatusOK):
+			status.Status = protocol.StatusWarning
+		}
+		if partition.TimeLag > status.MaxTimeLag {
+			status.MaxTimeLag = partition.TimeLag
+		}
+		if (status.Maxlag == nil) || (partition.CurrentLag > status.Maxlag.CurrentLag) {
+			status.Maxlag = partition
+		}
+		status.TotalLag += partition.CurrentLag
+	}
+
+	module.Log.Debug("evaluation result",
+		zap.String("cluster", cluster),
+		zap.String("consumer", module.metaGroup),
+		zap.String("status", status.Status.String()),
+	)
+	return status, nil
+}
+
+// getMetaAgeStatus returns the status of a meta group check that offsets have been received recently. If maxAge is not
+// more than zero (the cluster has no module that sets it), the check is always OK
+func (module *CachingEvaluator) getMetaAgeStatus(topic string, timestamp, timeNow, maxAge int64) *protocol.PartitionStatus {
+	partition := &protocol.PartitionStatus{
+		Topic:            topic,
+		Status:           protocol.StatusOK,
+		RetentionHorizon: -1,
+		Complete:         1.0,
+	}
+	if timestamp > 0 {
+		partition.TimeLag = timeNow - timestamp
+	} else {
+		partition.TimeLag = timeNow - module.startTime
+	}
+	if (maxAge > 0) && (partition.TimeLag > maxAge) {
+		partition.Status = protocol.StatusStop
+	}
+	return partition
+}
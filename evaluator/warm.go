@@ -0,0 +1,160 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package evaluator
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// warmLoop evaluates groups each warm-interval, replacing their entries in the cache, until the module is stopped.
+// Requests for those groups are answered from the cache without waiting, instead of every reporter and HTTP client
+// that asks for a group after its entry expires waiting on a new evaluation.
+func (module *CachingEvaluator) warmLoop() {
+	defer module.running.Done()
+
+	ticker := time.NewTicker(module.warmInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			module.warmCache()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+// warmCache evaluates the groups that are to be kept warm, warm-concurrency at a time. This is every group in storage,
+// unless warm-groups is set, in which case it is that many of the groups that were requested the most since the last
+// time the cache was warmed.
+func (module *CachingEvaluator) warmCache() {
+	startTime := time.Now()
+
+	var keys []string
+	if module.warmGroups > 0 {
+		keys = module.getMostRequested(module.warmGroups)
+	} else {
+		keys = module.getAllGroups()
+	}
+
+	keyChannel := make(chan string)
+	var warmers sync.WaitGroup
+	for i := 0; i < module.warmConcurrency; i++ {
+		warmers.Add(1)
+		go func() {
+			defer warmers.Done()
+			for key := range keyChannel {
+				module.cache.Update(key)
+			}
+		}()
+	}
+
+	warmed := 0
+	for _, key := range keys {
+		select {
+		case keyChannel <- key:
+			warmed++
+		case <-module.quitChannel:
+		}
+	}
+	close(keyChannel)
+	warmers.Wait()
+
+	module.warmedCount.Inc(int64(warmed))
+	helpers.UpdateMetricTime(module.warmTime, startTime)
+	module.Log.Debug("warmed cache",
+		zap.Int("groups", warmed),
+		zap.Duration("elapsed", time.Since(startTime)),
+	)
+}
+
+// getAllGroups returns the cache key of every group in every cluster in storage
+func (module *CachingEvaluator) getAllGroups() []string {
+	clusters, _ := module.fetchStorageList(protocol.StorageFetchClusters, "")
+
+	var keys []string
+	for _, cluster := range clusters {
+		groups, _ := module.fetchStorageList(protocol.StorageFetchConsumers, cluster)
+		for _, group := range groups {
+			keys = append(keys, cluster+" "+group)
+		}
+	}
+	return keys
+}
+
+// getMostRequested returns the cache keys of the count groups that were requested the most since it was last called,
+// and resets the counts. Groups that were not requested at all are forgotten, so that they are not warmed again.
+func (module *CachingEvaluator) getMostRequested(count int) []string {
+	type requestedGroup struct {
+		key      string
+		requests int64
+	}
+
+	var requested []requestedGroup
+	module.requestCounts.Range(func(key, value interface{}) bool {
+		requests := atomic.SwapInt64(value.(*int64), 0)
+		if requests == 0 {
+			module.requestCounts.Delete(key)
+		} else {
+			requested = append(requested, requestedGroup{key: key.(string), requests: requests})
+		}
+		return true
+	})
+	sort.Slice(requested, func(i, j int) bool {
+		if requested[i].requests != requested[j].requests {
+			return requested[i].requests > requested[j].requests
+		}
+		return requested[i].key < requested[j].key
+	})
+
+	if len(requested) > count {
+		requested = requested[:count]
+	}
+	keys := make([]string, len(requested))
+	for i, group := range requested {
+		keys[i] = group.key
+	}
+	return keys
+}
+
+// countRequest counts a request for the group with the cache key, if only the most requested groups are kept warm. The
+// counts are only reset when the cache is warmed, so nothing is counted unless warm-interval is set.
+func (module *CachingEvaluator) countRequest(key string) {
+	if (module.warmGroups == 0) || (module.warmInterval == 0) {
+		return
+	}
+	count, ok := module.requestCounts.Load(key)
+	if !ok {
+		count, _ = module.requestCounts.LoadOrStore(key, new(int64))
+	}
+	atomic.AddInt64(count.(*int64), 1)
+}
+
+// fetchStorageList returns the list of clusters, or of the groups in a cluster, from storage
+func (module *CachingEvaluator) fetchStorageList(requestType protocol.StorageRequestConstant, cluster string) ([]string, bool) {
+	storageRequest := &protocol.StorageRequest{
+		RequestType: requestType,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+	}
+	module.App.GetStorageChannel(protocol.StoragePriorityEvaluation) <- storageRequest
+	response, ok := (<-storageRequest.Reply).([]string)
+	return response, ok
+}
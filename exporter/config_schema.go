@@ -0,0 +1,45 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("exporter.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+	)
+	helpers.RegisterConfigKeys("exporter.*", "kafka", append([]helpers.ConfigKey{
+		{Name: "servers", Type: helpers.ConfigTypeStringList},
+		{Name: "client-profile", Type: helpers.ConfigTypeString},
+		{Name: "topic", Type: helpers.ConfigTypeString},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+	helpers.RegisterConfigKeys("exporter.*", "influxdb", append([]helpers.ConfigKey{
+		{Name: "url", Type: helpers.ConfigTypeString},
+		{Name: "bucket", Type: helpers.ConfigTypeString},
+		{Name: "org", Type: helpers.ConfigTypeString},
+		{Name: "token", Type: helpers.ConfigTypeString},
+		{Name: "database", Type: helpers.ConfigTypeString},
+		{Name: "retention-policy", Type: helpers.ConfigTypeString},
+		{Name: "username", Type: helpers.ConfigTypeString},
+		{Name: "password", Type: helpers.ConfigTypeString},
+		{Name: "tls", Type: helpers.ConfigTypeString},
+		{Name: "prefix", Type: helpers.ConfigTypeString, Default: "burrow_"},
+		{Name: "timeout", Type: helpers.ConfigTypeInteger, Default: 10},
+		{Name: "batch-size", Type: helpers.ConfigTypeInteger, Default: 5000},
+		{Name: "max-retries", Type: helpers.ConfigTypeInteger, Default: 3},
+		{Name: "retry-backoff", Type: helpers.ConfigTypeInteger, Default: 1000},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+	helpers.RegisterConfigKeys("exporter.*", "otlp", append([]helpers.ConfigKey{
+		{Name: "prefix", Type: helpers.ConfigTypeString, Default: "burrow."},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
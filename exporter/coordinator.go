@@ -0,0 +1,211 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+// Package exporter - Consumer status export subsystem.
+// The exporter subsystem evaluates every consumer group on a schedule, and sends the status of each group, including
+// all of its partitions, to each of the configured sinks. This keeps a history of lag in another system, such as a
+// Kafka topic or a time series database, without something outside of Burrow having to poll the HTTP server. The
+// groups are evaluated once each interval, however many sinks there are.
+//
+// Modules
+//
+// Currently, the following modules are provided:
+//
+// * kafka - Produce the status of each group as a JSON message to a Kafka topic
+//
+// * influxdb - Write the lag of each group and partition, and changes in status, to InfluxDB
+//
+// * otlp - Send the lag of each group and partition to an OpenTelemetry collector, using OTLP over HTTP or gRPC
+package exporter
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// Module is responsible for sending the statuses of the consumer groups to a sink. It conforms to the overall
+// protocol.Module interface, but it adds a func that the coordinator calls each interval with the statuses of all of
+// the groups that were evaluated. The module is expected to apply its own group filter, and to have sent the statuses
+// (or given up) when the func returns.
+type Module interface {
+	protocol.Module
+	Export(statuses []*protocol.ConsumerGroupStatus)
+}
+
+// Coordinator manages all exporter modules, making sure they are configured, started, and stopped at the appropriate
+// time. Each general.export-interval seconds (60 by default), it evaluates every consumer group in every cluster, and
+// passes the statuses to every module at the same time. The next interval does not start until every module has
+// returned.
+type Coordinator struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	modules     map[string]protocol.Module
+	interval    time.Duration
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+
+	evaluateTime metrics.Histogram
+	groupCount   metrics.Gauge
+}
+
+// getModuleForClass returns the correct module based on the passed className. As part of the Configure steps, if there
+// is any error, it will panic with an appropriate message describing the problem.
+func getModuleForClass(app *protocol.ApplicationContext, moduleName, className string) protocol.Module {
+	logger := app.Logger.With(
+		zap.String("type", "module"),
+		zap.String("coordinator", "exporter"),
+		zap.String("class", className),
+		zap.String("name", moduleName),
+	)
+
+	switch className {
+	case "kafka":
+		return &KafkaExporter{
+			App: app,
+			Log: logger,
+		}
+	case "influxdb":
+		return &InfluxDBExporter{
+			App: app,
+			Log: logger,
+		}
+	case "otlp":
+		return &OTLPExporter{
+			App: app,
+			Log: logger,
+		}
+	default:
+		if factory := helpers.GetModuleClass("exporter", className); factory != nil {
+			return factory(app, logger)
+		}
+		panic("Unknown exporter className provided: " + className)
+	}
+}
+
+// Configure is called to create each of the configured exporter modules and call their Configure funcs to validate
+// their individual configurations and set them up. If there are any problems, it is expected that these funcs will
+// panic with a descriptive error message, as configuration failures are not recoverable errors.
+func (ec *Coordinator) Configure() {
+	ec.Log.Info("configuring")
+
+	ec.modules = make(map[string]protocol.Module)
+	ec.quitChannel = make(chan struct{})
+	ec.running = sync.WaitGroup{}
+	ec.evaluateTime = helpers.GetMetricHistogram("exporter.evaluate-time")
+	ec.groupCount = helpers.GetMetricGauge("exporter.groups")
+
+	viper.SetDefault("general.export-interval", 60)
+	ec.interval = time.Duration(viper.GetInt("general.export-interval")) * time.Second
+	if ec.interval <= 0 {
+		panic("general.export-interval must be more than zero")
+	}
+
+	// Create all configured exporter modules. Like the reporters, having none is normal
+	modules := viper.GetStringMap("exporter")
+	for name := range modules {
+		configRoot := "exporter." + name
+		module := getModuleForClass(ec.App, name, viper.GetString(configRoot+".class-name"))
+		if _, ok := module.(Module); !ok {
+			panic("Exporter '" + name + "' does not have an Export func")
+		}
+		module.Configure(name, configRoot)
+		ec.modules[name] = module
+	}
+}
+
+// Start calls each of the configured exporter modules' underlying Start funcs. If any module Start returns an error,
+// this func stops immediately and returns that error to the caller. Once the modules are started, if there are any,
+// the coordinator starts a goroutine to evaluate the groups each interval and pass them to the modules.
+func (ec *Coordinator) Start() error {
+	ec.Log.Info("starting")
+
+	err := helpers.StartCoordinatorModules(ec.modules)
+	if err != nil {
+		return errors.New("Error starting exporter module: " + err.Error())
+	}
+
+	if len(ec.modules) > 0 {
+		ec.running.Add(1)
+		go ec.mainLoop()
+	}
+	return nil
+}
+
+// Stop stops the goroutine that evaluates the groups, and then calls each of the configured exporter modules'
+// underlying Stop funcs. It is expected that the module Stop will not return until the module has been completely
+// stopped. While an error can be returned, this func always returns no error, as a failure during stopping is not a
+// critical failure
+func (ec *Coordinator) Stop() error {
+	ec.Log.Info("stopping")
+
+	close(ec.quitChannel)
+	ec.running.Wait()
+
+	helpers.StopCoordinatorModules(ec.modules)
+	return nil
+}
+
+// Health returns the health of each exporter module
+func (ec *Coordinator) Health() *protocol.CoordinatorHealth {
+	return helpers.GetCoordinatorHealth("exporter", ec.modules)
+}
+
+func (ec *Coordinator) mainLoop() {
+	defer ec.running.Done()
+
+	ticker := time.NewTicker(ec.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ec.export()
+		case <-ec.quitChannel:
+			return
+		}
+	}
+}
+
+// export evaluates every group, and passes the statuses to each module
+func (ec *Coordinator) export() {
+	startTime := time.Now()
+	statuses := helpers.GetConsumerStatuses(ec.App, nil, true, ec.quitChannel)
+	helpers.UpdateMetricTime(ec.evaluateTime, startTime)
+	ec.groupCount.Update(int64(len(statuses)))
+
+	// The modules are not given the statuses if Burrow is stopping, as they may not be complete
+	select {
+	case <-ec.quitChannel:
+		return
+	default:
+	}
+
+	var exported sync.WaitGroup
+	for _, module := range ec.modules {
+		exported.Add(1)
+		go func(module Module) {
+			defer exported.Done()
+			module.Export(statuses)
+		}(module.(Module))
+	}
+	exported.Wait()
+}
@@ -0,0 +1,140 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// mockExporter is a MockModule that keeps the statuses that it is given
+type mockExporter struct {
+	helpers.MockModule
+	statuses []*protocol.ConsumerGroupStatus
+}
+
+func (m *mockExporter) Export(statuses []*protocol.ConsumerGroupStatus) {
+	m.statuses = statuses
+}
+
+func fixtureCoordinator() *Coordinator {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+	}
+	coordinator.App = &protocol.ApplicationContext{
+		Logger:           zap.NewNop(),
+		StorageChannel:   make(chan *protocol.StorageRequest),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+	}
+
+	viper.Reset()
+	viper.Set("exporter.test.class-name", "kafka")
+	viper.Set("exporter.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("exporter.test.topic", "burrow-status")
+	return &coordinator
+}
+
+// respondToStatusRequests answers the requests that helpers.GetConsumerStatuses makes, for one cluster with two groups
+func respondToStatusRequests(app *protocol.ApplicationContext) {
+	request := <-app.StorageChannel
+	request.Reply <- []string{"testcluster"}
+	request = <-app.StorageChannel
+	request.Reply <- []string{"testgroup", "dropped"}
+
+	for i := 0; i < 2; i++ {
+		evaluatorRequest := <-app.EvaluatorChannel
+		evaluatorRequest.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:  evaluatorRequest.Cluster,
+			Group:    evaluatorRequest.Group,
+			Status:   protocol.StatusWarning,
+			TotalLag: 2500,
+			Partitions: []*protocol.PartitionStatus{{
+				Topic:      "testtopic",
+				Partition:  0,
+				Status:     protocol.StatusWarning,
+				End:        &protocol.ConsumerOffset{Offset: 1000},
+				CurrentLag: 2500,
+			}},
+		}
+	}
+}
+
+func TestCoordinator_ImplementsCoordinator(t *testing.T) {
+	assert.Implements(t, (*protocol.Coordinator)(nil), new(Coordinator))
+}
+
+func TestCoordinator_Configure(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	assert.Lenf(t, coordinator.modules, 1, "Expected 1 module configured, not %v", len(coordinator.modules))
+	assert.IsType(t, &KafkaExporter{}, coordinator.modules["test"], "Expected a kafka module")
+}
+
+func TestCoordinator_Configure_NoModules(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Reset()
+	coordinator.Configure()
+
+	assert.Empty(t, coordinator.modules, "Expected no modules configured")
+}
+
+func TestCoordinator_Configure_BadClass(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("exporter.test.class-name", "nonexistent")
+	assert.Panics(t, coordinator.Configure, "Expected panic for an unknown class")
+}
+
+func TestCoordinator_Configure_BadInterval(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("general.export-interval", 0)
+	assert.Panics(t, coordinator.Configure, "Expected panic for an interval of zero")
+}
+
+func TestCoordinator_StartStop(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	// Swap out the coordinator modules with a mock for testing
+	mockModule := &mockExporter{}
+	mockModule.On("Start").Return(nil)
+	mockModule.On("Stop").Return(nil)
+	coordinator.modules["test"] = mockModule
+
+	coordinator.Start()
+	mockModule.AssertCalled(t, "Start")
+
+	coordinator.Stop()
+	mockModule.AssertCalled(t, "Stop")
+}
+
+func TestCoordinator_export(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	first := &mockExporter{}
+	second := &mockExporter{}
+	coordinator.modules = map[string]protocol.Module{"first": first, "second": second}
+
+	go respondToStatusRequests(coordinator.App)
+	coordinator.export()
+
+	assert.Len(t, first.statuses, 2, "Expected the statuses of both groups")
+	assert.Equal(t, first.statuses, second.statuses, "Expected every module to be given the same statuses")
+	assert.Len(t, first.statuses[0].Partitions, 1, "Expected the statuses to include every partition")
+	assert.Equal(t, int64(2), helpers.GetMetricGauge("exporter.groups").Value(), "Expected the number of groups")
+}
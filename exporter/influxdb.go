@@ -0,0 +1,302 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// InfluxDBExporter is an exporter module that writes the lag of each consumer group to InfluxDB each interval, using
+// the line protocol over HTTP. If bucket is set, the InfluxDB 2.x write API is used, with the org and token given.
+// Otherwise, the 1.x write API is used, with the database (which is required), and the retention-policy, username, and
+// password if they are set. Anything else that accepts InfluxDB writes, such as a Telegraf listener in front of
+// TimescaleDB, can also be used. Three measurements are written, with the prefix (by default "burrow_"):
+//
+// * consumer_group - The status, total lag, and partition count of each group, tagged with the cluster and group
+//
+// * consumer_partition - The status, lag, time lag, and committed offset of each partition, also tagged with the topic
+// and partition
+//
+// * consumer_status_change - The previous and new status of a group, written when the status is different from the
+// last interval
+//
+// The lines are sent in batches of batch-size lines. A batch that fails because the server could not be reached, or
+// responded with 429 or a 5xx status, is retried up to max-retries times, waiting retry-backoff milliseconds before the
+// first retry and twice as long before each one after that. A batch that still fails is dropped and counted in the
+// errors metric. The groups that are sent can be limited with group-allowlist and group-denylist.
+type InfluxDBExporter struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name         string
+	writeURL     string
+	token        string
+	username     string
+	password     string
+	prefix       string
+	batchSize    int
+	maxRetries   int
+	retryBackoff time.Duration
+	client       *http.Client
+	filter       *helpers.ConsumerFilter
+	quitChannel  chan struct{}
+
+	// The status of each group at the last interval, keyed by cluster and group, to find the status changes. This is
+	// only used by Export, which the coordinator does not call concurrently
+	lastStatus map[[2]string]protocol.StatusConstant
+
+	exportTime metrics.Histogram
+	sentCount  metrics.Counter
+	errorCount metrics.Counter
+	retryCount metrics.Counter
+}
+
+// Configure validates the configuration for the module. The url is required, and either bucket or database must be
+// set. The timeout for each request defaults to 10 seconds, batch-size to 5000 lines, max-retries to 3, and
+// retry-backoff to 1000 milliseconds. If there are any problems, it is expected that this func will panic with a
+// descriptive error message, as configuration failures are not recoverable errors.
+func (module *InfluxDBExporter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.lastStatus = make(map[[2]string]protocol.StatusConstant)
+	module.exportTime = helpers.GetMetricHistogram(configRoot + ".export-time")
+	module.sentCount = helpers.GetMetricCounter(configRoot + ".sent")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+	module.retryCount = helpers.GetMetricCounter(configRoot + ".retries")
+
+	viper.SetDefault(configRoot+".prefix", "burrow_")
+	viper.SetDefault(configRoot+".timeout", 10)
+	viper.SetDefault(configRoot+".batch-size", 5000)
+	viper.SetDefault(configRoot+".max-retries", 3)
+	viper.SetDefault(configRoot+".retry-backoff", 1000)
+
+	baseURL := strings.TrimSuffix(viper.GetString(configRoot+".url"), "/")
+	parsedURL, err := url.Parse(baseURL)
+	if (baseURL == "") || (err != nil) || ((parsedURL.Scheme != "http") && (parsedURL.Scheme != "https")) || (parsedURL.Host == "") {
+		panic("Exporter '" + name + "' has a bad or missing url")
+	}
+
+	query := url.Values{"precision": []string{"ms"}}
+	if bucket := viper.GetString(configRoot + ".bucket"); bucket != "" {
+		query.Set("bucket", bucket)
+		query.Set("org", viper.GetString(configRoot+".org"))
+		module.writeURL = baseURL + "/api/v2/write?" + query.Encode()
+		module.token = viper.GetString(configRoot + ".token")
+	} else {
+		database := viper.GetString(configRoot + ".database")
+		if database == "" {
+			panic("Exporter '" + name + "' must have a bucket or a database")
+		}
+		query.Set("db", database)
+		if retentionPolicy := viper.GetString(configRoot + ".retention-policy"); retentionPolicy != "" {
+			query.Set("rp", retentionPolicy)
+		}
+		module.writeURL = baseURL + "/write?" + query.Encode()
+		module.username = viper.GetString(configRoot + ".username")
+		module.password = viper.GetString(configRoot + ".password")
+	}
+
+	module.prefix = viper.GetString(configRoot + ".prefix")
+	module.batchSize = viper.GetInt(configRoot + ".batch-size")
+	if module.batchSize <= 0 {
+		panic("Exporter '" + name + "' has an invalid batch-size")
+	}
+	module.maxRetries = viper.GetInt(configRoot + ".max-retries")
+	module.retryBackoff = time.Duration(viper.GetInt(configRoot+".retry-backoff")) * time.Millisecond
+	if (module.maxRetries < 0) || (module.retryBackoff < 0) {
+		panic("Exporter '" + name + "' has an invalid max-retries or retry-backoff")
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if viper.IsSet(configRoot + ".tls") {
+		transport.TLSClientConfig = helpers.GetTLSConfigFromProfile(viper.GetString(configRoot + ".tls"))
+	}
+	module.client = &http.Client{
+		Timeout:   time.Duration(viper.GetInt(configRoot+".timeout")) * time.Second,
+		Transport: transport,
+	}
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		panic("Exporter '" + name + "' has an invalid filter: " + err.Error())
+	}
+	module.filter = filter
+}
+
+// Start is a no-op for the module, as the server is not contacted until the first statuses are exported
+func (module *InfluxDBExporter) Start() error {
+	module.Log.Info("starting")
+	return nil
+}
+
+// Stop stops any retries that are waiting, so that the coordinator does not have to wait for them to stop
+func (module *InfluxDBExporter) Stop() error {
+	module.Log.Info("stopping")
+	close(module.quitChannel)
+	return nil
+}
+
+// Export writes the lines for the statuses that are accepted by the group filter, in batches
+func (module *InfluxDBExporter) Export(statuses []*protocol.ConsumerGroupStatus) {
+	defer helpers.UpdateMetricTime(module.exportTime, time.Now())
+
+	lines := module.getLines(statuses, time.Now().UnixNano()/int64(time.Millisecond))
+	for start := 0; start < len(lines); start += module.batchSize {
+		end := start + module.batchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if err := module.writeBatch(lines[start:end]); err != nil {
+			module.errorCount.Inc(1)
+			module.Log.Warn("failed to write lag", zap.Int("lines", end-start), zap.Error(err))
+			helpers.PublishModuleError("exporter."+module.name, "", "failed to write lag: "+err.Error())
+			continue
+		}
+		module.sentCount.Inc(int64(end - start))
+	}
+}
+
+// getLines returns the lines in the line protocol for the statuses, with the timestamp given (in milliseconds). It
+// also records the status of each group, and forgets the groups that are no longer there.
+func (module *InfluxDBExporter) getLines(statuses []*protocol.ConsumerGroupStatus, timestamp int64) []string {
+	suffix := " " + strconv.FormatInt(timestamp, 10)
+	seen := make(map[[2]string]bool)
+	lines := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if !module.filter.AcceptGroup(status.Group) {
+			continue
+		}
+		groupTags := ",cluster=" + escapeInfluxTag(status.Cluster) + ",consumer_group=" + escapeInfluxTag(status.Group)
+		lines = append(lines, escapeInfluxMeasurement(module.prefix+"consumer_group")+groupTags+
+			" status="+strconv.Itoa(int(status.Status))+"i"+
+			",status_name="+quoteInfluxString(status.Status.String())+
+			",total_lag="+strconv.FormatUint(status.TotalLag, 10)+"i"+
+			",partitions="+strconv.Itoa(status.TotalPartitions)+"i"+
+			",complete="+strconv.FormatFloat(float64(status.Complete), 'f', -1, 32)+suffix)
+
+		for _, partition := range status.Partitions {
+			line := escapeInfluxMeasurement(module.prefix+"consumer_partition") + groupTags +
+				",topic=" + escapeInfluxTag(partition.Topic) + ",partition=" + strconv.Itoa(int(partition.Partition)) +
+				" status=" + strconv.Itoa(int(partition.Status)) + "i" +
+				",lag=" + strconv.FormatUint(partition.CurrentLag, 10) + "i" +
+				",time_lag=" + strconv.FormatInt(partition.TimeLag, 10) + "i"
+			if partition.End != nil {
+				line += ",offset=" + strconv.FormatInt(partition.End.Offset, 10) + "i"
+			}
+			lines = append(lines, line+suffix)
+		}
+
+		key := [2]string{status.Cluster, status.Group}
+		seen[key] = true
+		if lastStatus, ok := module.lastStatus[key]; ok && (lastStatus != status.Status) {
+			lines = append(lines, escapeInfluxMeasurement(module.prefix+"consumer_status_change")+groupTags+
+				" previous_status="+strconv.Itoa(int(lastStatus))+"i"+
+				",previous_status_name="+quoteInfluxString(lastStatus.String())+
+				",status="+strconv.Itoa(int(status.Status))+"i"+
+				",status_name="+quoteInfluxString(status.Status.String())+suffix)
+		}
+		module.lastStatus[key] = status.Status
+	}
+
+	for key := range module.lastStatus {
+		if !seen[key] {
+			delete(module.lastStatus, key)
+		}
+	}
+	return lines
+}
+
+// writeBatch sends the lines to the server, retrying if the failure is one that can be retried
+func (module *InfluxDBExporter) writeBatch(lines []string) error {
+	body := []byte(strings.Join(lines, "\n"))
+	backoff := module.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := module.write(body)
+		if (err == nil) || !retry || (attempt >= module.maxRetries) {
+			return err
+		}
+
+		module.retryCount.Inc(1)
+		select {
+		case <-time.After(backoff):
+		case <-module.quitChannel:
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// write sends the body to the server once. If there is an error, it also returns whether the request can be retried
+func (module *InfluxDBExporter) write(body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", module.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if module.token != "" {
+		req.Header.Set("Authorization", "Token "+module.token)
+	} else if module.username != "" {
+		req.SetBasicAuth(module.username, module.password)
+	}
+
+	resp, err := module.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if (resp.StatusCode >= 200) && (resp.StatusCode <= 299) {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.New("unexpected response status " + resp.Status + ": " + strings.TrimSpace(string(message)))
+	return (resp.StatusCode == http.StatusTooManyRequests) || (resp.StatusCode >= 500), err
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", "\\,", " ", "\\ ")
+	influxTagEscaper         = strings.NewReplacer(",", "\\,", "=", "\\=", " ", "\\ ")
+	influxStringEscaper      = strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+)
+
+func escapeInfluxMeasurement(measurement string) string {
+	return influxMeasurementEscaper.Replace(measurement)
+}
+
+func escapeInfluxTag(tag string) string {
+	return influxTagEscaper.Replace(tag)
+}
+
+func quoteInfluxString(value string) string {
+	return "\"" + influxStringEscaper.Replace(value) + "\""
+}
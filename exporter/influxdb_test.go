@@ -0,0 +1,185 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureInfluxDBModule(url string) *InfluxDBExporter {
+	module := InfluxDBExporter{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{}
+
+	viper.Reset()
+	viper.Set("exporter.test.class-name", "influxdb")
+	viper.Set("exporter.test.url", url)
+	viper.Set("exporter.test.database", "burrow")
+	viper.Set("exporter.test.retry-backoff", 1)
+	viper.Set("exporter.test.group-denylist", "^dropped$")
+	return &module
+}
+
+// influxDBServer records the requests that it receives, and responds to each with the next status code, or 204 once
+// there are none left
+type influxDBServer struct {
+	lock     sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func (s *influxDBServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, string(body))
+	statusCode := http.StatusNoContent
+	if len(s.statuses) > 0 {
+		statusCode, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	w.WriteHeader(statusCode)
+}
+
+func TestInfluxDBExporter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*Module)(nil), new(InfluxDBExporter))
+}
+
+func TestInfluxDBExporter_Configure(t *testing.T) {
+	module := fixtureInfluxDBModule("http://influxdb.example.com:8086/")
+	viper.Set("exporter.test.retention-policy", "weekly")
+	module.Configure("test", "exporter.test")
+	assert.Equal(t, "http://influxdb.example.com:8086/write?db=burrow&precision=ms&rp=weekly", module.writeURL, "Expected the 1.x write API")
+
+	module = fixtureInfluxDBModule("https://influxdb.example.com")
+	viper.Set("exporter.test.bucket", "lag")
+	viper.Set("exporter.test.org", "example")
+	viper.Set("exporter.test.token", "secret")
+	module.Configure("test", "exporter.test")
+	assert.Equal(t, "https://influxdb.example.com/api/v2/write?bucket=lag&org=example&precision=ms", module.writeURL, "Expected the 2.x write API")
+	assert.Equal(t, "secret", module.token, "Expected the token")
+}
+
+func TestInfluxDBExporter_Configure_BadURL(t *testing.T) {
+	module := fixtureInfluxDBModule("influxdb.example.com:8086")
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic for a url without a scheme")
+}
+
+func TestInfluxDBExporter_Configure_NoDatabase(t *testing.T) {
+	module := fixtureInfluxDBModule("http://influxdb.example.com:8086")
+	viper.Set("exporter.test.database", "")
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic without a bucket or database")
+}
+
+func TestInfluxDBExporter_Configure_BadBatchSize(t *testing.T) {
+	module := fixtureInfluxDBModule("http://influxdb.example.com:8086")
+	viper.Set("exporter.test.batch-size", 0)
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic for a batch-size of zero")
+}
+
+func TestInfluxDBExporter_getLines(t *testing.T) {
+	module := fixtureInfluxDBModule("http://influxdb.example.com:8086")
+	module.Configure("test", "exporter.test")
+
+	statuses := fixtureStatuses()
+	statuses[0].Cluster = "test cluster"
+	lines := module.getLines(statuses, 1500000000000)
+	assert.Equal(t, []string{
+		`burrow_consumer_group,cluster=test\ cluster,consumer_group=testgroup status=2i,status_name="WARN",total_lag=2500i,partitions=0i,complete=0 1500000000000`,
+		`burrow_consumer_partition,cluster=test\ cluster,consumer_group=testgroup,topic=testtopic,partition=0 status=2i,lag=2500i,time_lag=3000i,offset=1000i 1500000000000`,
+	}, lines, "Expected a line for the group and the partition, as the other group is denied")
+
+	// A change in status is written as well
+	statuses[0].Status = protocol.StatusError
+	lines = module.getLines(statuses, 1500000060000)
+	assert.Len(t, lines, 3, "Expected a line for the status change")
+	assert.Equal(t, `burrow_consumer_status_change,cluster=test\ cluster,consumer_group=testgroup previous_status=2i,previous_status_name="WARN",status=3i,status_name="ERR" 1500000060000`,
+		lines[2], "Expected the previous and new status")
+
+	// A group that is not there any more is forgotten
+	module.getLines([]*protocol.ConsumerGroupStatus{}, 1500000120000)
+	assert.Empty(t, module.lastStatus, "Expected the group to be forgotten")
+}
+
+func TestInfluxDBExporter_Export(t *testing.T) {
+	server := &influxDBServer{}
+	influxDB := httptest.NewServer(server)
+	defer influxDB.Close()
+
+	module := fixtureInfluxDBModule(influxDB.URL)
+	viper.Set("exporter.test.username", "burrow")
+	viper.Set("exporter.test.password", "secret")
+	viper.Set("exporter.test.batch-size", 1)
+	module.Configure("test", "exporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	sent := helpers.GetMetricCounter("exporter.test.sent").Count()
+	module.Export(fixtureStatuses())
+
+	assert.Len(t, server.requests, 2, "Expected a batch for each line")
+	assert.Equal(t, "/write", server.requests[0].URL.Path, "Expected the 1.x write API")
+	username, password, ok := server.requests[0].BasicAuth()
+	assert.True(t, ok && (username == "burrow") && (password == "secret"), "Expected the username and password")
+	assert.True(t, strings.HasPrefix(server.bodies[0], "burrow_consumer_group,"), "Expected the group line first")
+	assert.Equal(t, sent+2, helpers.GetMetricCounter("exporter.test.sent").Count(), "Expected both lines to be counted")
+}
+
+func TestInfluxDBExporter_Export_Retry(t *testing.T) {
+	server := &influxDBServer{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	influxDB := httptest.NewServer(server)
+	defer influxDB.Close()
+
+	module := fixtureInfluxDBModule(influxDB.URL)
+	module.Configure("test", "exporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	retries := helpers.GetMetricCounter("exporter.test.retries").Count()
+	errorCount := helpers.GetMetricCounter("exporter.test.errors").Count()
+	module.Export(fixtureStatuses())
+
+	assert.Len(t, server.requests, 3, "Expected the batch to be retried until it was written")
+	assert.Equal(t, retries+2, helpers.GetMetricCounter("exporter.test.retries").Count(), "Expected two retries")
+	assert.Equal(t, errorCount, helpers.GetMetricCounter("exporter.test.errors").Count(), "Expected no errors")
+}
+
+func TestInfluxDBExporter_Export_NoRetry(t *testing.T) {
+	server := &influxDBServer{statuses: []int{http.StatusBadRequest}}
+	influxDB := httptest.NewServer(server)
+	defer influxDB.Close()
+
+	module := fixtureInfluxDBModule(influxDB.URL)
+	module.Configure("test", "exporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	errorCount := helpers.GetMetricCounter("exporter.test.errors").Count()
+	module.Export(fixtureStatuses())
+
+	assert.Len(t, server.requests, 1, "Expected a rejected batch to not be retried")
+	assert.Equal(t, errorCount+1, helpers.GetMetricCounter("exporter.test.errors").Count(), "Expected the batch to be counted as an error")
+}
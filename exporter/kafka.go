@@ -0,0 +1,197 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// KafkaExporter is an exporter module that produces the status of each consumer group to a Kafka topic each interval,
+// as a JSON message. The message is the same as the status in the HTTP server's lag response, with every partition,
+// and has a timestamp field with the time it was evaluated, in milliseconds. The key is the cluster and group, joined
+// with a slash, so that the messages for a group are kept in order in one partition.
+//
+// The servers of the Kafka cluster are given in servers, and the client is set up with the client-profile given (the
+// producer is not created until the first interval, so a cluster that cannot be reached does not stop Burrow from
+// starting). The groups that are sent can be limited with group-allowlist and group-denylist.
+type KafkaExporter struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name         string
+	servers      []string
+	topic        string
+	saramaConfig *sarama.Config
+	filter       *helpers.ConsumerFilter
+	json         helpers.JSONEncoder
+
+	// The producer is created in the first Export, and closed in Stop
+	producerLock sync.Mutex
+	client       sarama.Client
+	producer     sarama.SyncProducer
+
+	exportTime metrics.Histogram
+	sentCount  metrics.Counter
+	errorCount metrics.Counter
+}
+
+// kafkaExportMessage is the value of each message produced by the KafkaExporter
+type kafkaExportMessage struct {
+	Timestamp int64 `json:"timestamp"`
+	*protocol.ConsumerGroupStatus
+}
+
+// Configure validates the configuration for the module. The servers and topic are required, and the client-profile
+// must exist if it is given. If there are any problems, it is expected that this func will panic with a descriptive
+// error message, as configuration failures are not recoverable errors.
+func (module *KafkaExporter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.exportTime = helpers.GetMetricHistogram(configRoot + ".export-time")
+	module.sentCount = helpers.GetMetricCounter(configRoot + ".sent")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+
+	module.servers = viper.GetStringSlice(configRoot + ".servers")
+	if len(module.servers) == 0 {
+		panic("No Kafka brokers specified for exporter " + name)
+	} else if !helpers.ValidateHostList(module.servers) {
+		panic("Exporter '" + name + "' has one or more improperly formatted servers (must be host:port)")
+	}
+
+	module.topic = viper.GetString(configRoot + ".topic")
+	if !helpers.ValidateTopic(module.topic) {
+		panic("Exporter '" + name + "' has a missing or invalid topic")
+	}
+
+	module.saramaConfig = helpers.GetSaramaConfigFromClientProfile(viper.GetString(configRoot + ".client-profile"))
+	module.saramaConfig.Producer.Return.Successes = true
+	module.saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		panic("Exporter '" + name + "' has an invalid filter: " + err.Error())
+	}
+	module.filter = filter
+
+	encoder, err := helpers.GetJSONEncoder()
+	if err != nil {
+		panic("Exporter '" + name + "' cannot encode messages: " + err.Error())
+	}
+	module.json = encoder
+}
+
+// Start is a no-op for the module, as the producer is created when the first statuses are exported
+func (module *KafkaExporter) Start() error {
+	module.Log.Info("starting")
+	helpers.SetModuleHealth("exporter."+module.name, protocol.HealthOK, "")
+	return nil
+}
+
+// Stop closes the producer and the client, if they were created
+func (module *KafkaExporter) Stop() error {
+	module.Log.Info("stopping")
+
+	module.producerLock.Lock()
+	defer module.producerLock.Unlock()
+	if module.producer != nil {
+		module.producer.Close()
+		module.producer = nil
+	}
+	if module.client != nil {
+		module.client.Close()
+		module.client = nil
+	}
+	return nil
+}
+
+// Export produces a message for each of the statuses that is accepted by the group filter, and waits for them all to
+// be acknowledged. If the producer cannot be created, or any of the messages cannot be produced, it is counted in the
+// errors metric and tried again the next interval.
+func (module *KafkaExporter) Export(statuses []*protocol.ConsumerGroupStatus) {
+	defer helpers.UpdateMetricTime(module.exportTime, time.Now())
+
+	timestamp := time.Now().Unix() * 1000
+	messages := make([]*sarama.ProducerMessage, 0, len(statuses))
+	for _, status := range statuses {
+		if !module.filter.AcceptGroup(status.Group) {
+			continue
+		}
+		value, err := module.json.Marshal(&kafkaExportMessage{Timestamp: timestamp, ConsumerGroupStatus: status})
+		if err != nil {
+			module.Log.Warn("failed to encode status", zap.String("cluster", status.Cluster),
+				zap.String("consumer_group", status.Group), zap.Error(err))
+			continue
+		}
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: module.topic,
+			Key:   sarama.StringEncoder(status.Cluster + "/" + status.Group),
+			Value: sarama.ByteEncoder(value),
+		})
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	module.producerLock.Lock()
+	defer module.producerLock.Unlock()
+	if module.producer == nil {
+		if err := module.createProducer(); err != nil {
+			module.errorCount.Inc(1)
+			module.Log.Warn("failed to create producer", zap.Error(err))
+			helpers.SetModuleHealth("exporter."+module.name, protocol.HealthFailed, "cannot create producer: "+err.Error())
+			return
+		}
+		helpers.SetModuleHealth("exporter."+module.name, protocol.HealthOK, "")
+	}
+
+	if err := module.producer.SendMessages(messages); err != nil {
+		failed := len(messages)
+		if produceErrors, ok := err.(sarama.ProducerErrors); ok {
+			failed = len(produceErrors)
+		}
+		module.sentCount.Inc(int64(len(messages) - failed))
+		module.errorCount.Inc(int64(failed))
+		module.Log.Warn("failed to produce statuses", zap.Int("messages", failed), zap.Error(err))
+		helpers.PublishModuleError("exporter."+module.name, "", "failed to produce statuses: "+err.Error())
+		return
+	}
+	module.sentCount.Inc(int64(len(messages)))
+}
+
+// createProducer creates the client and the producer. It must be called with the producerLock held
+func (module *KafkaExporter) createProducer() error {
+	client, err := helpers.NewSaramaClient(module.servers, module.saramaConfig)
+	if err != nil {
+		return err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return err
+	}
+	module.client = client
+	module.producer = producer
+	return nil
+}
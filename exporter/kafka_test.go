@@ -0,0 +1,123 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureKafkaModule() *KafkaExporter {
+	module := KafkaExporter{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{}
+
+	viper.Reset()
+	viper.Set("exporter.test.class-name", "kafka")
+	viper.Set("exporter.test.servers", []string{"broker1.example.com:1234"})
+	viper.Set("exporter.test.topic", "burrow-status")
+	viper.Set("exporter.test.group-denylist", "^dropped$")
+	return &module
+}
+
+func fixtureStatuses() []*protocol.ConsumerGroupStatus {
+	return []*protocol.ConsumerGroupStatus{
+		{
+			Cluster:  "testcluster",
+			Group:    "testgroup",
+			Status:   protocol.StatusWarning,
+			TotalLag: 2500,
+			Partitions: []*protocol.PartitionStatus{{
+				Topic:      "testtopic",
+				Partition:  0,
+				Status:     protocol.StatusWarning,
+				End:        &protocol.ConsumerOffset{Offset: 1000},
+				CurrentLag: 2500,
+				TimeLag:    3000,
+			}},
+		},
+		{
+			Cluster: "testcluster",
+			Group:   "dropped",
+			Status:  protocol.StatusOK,
+		},
+	}
+}
+
+func TestKafkaExporter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*Module)(nil), new(KafkaExporter))
+}
+
+func TestKafkaExporter_Configure(t *testing.T) {
+	module := fixtureKafkaModule()
+	module.Configure("test", "exporter.test")
+	assert.True(t, module.saramaConfig.Producer.Return.Successes, "Expected the producer to return successes")
+}
+
+func TestKafkaExporter_Configure_NoServers(t *testing.T) {
+	module := fixtureKafkaModule()
+	viper.Set("exporter.test.servers", []string{})
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic without servers")
+}
+
+func TestKafkaExporter_Configure_BadTopic(t *testing.T) {
+	module := fixtureKafkaModule()
+	viper.Set("exporter.test.topic", "bad topic")
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic for an invalid topic")
+}
+
+func TestKafkaExporter_Export(t *testing.T) {
+	module := fixtureKafkaModule()
+	module.Configure("test", "exporter.test")
+
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(value []byte) error {
+		var message map[string]interface{}
+		if err := json.Unmarshal(value, &message); err != nil {
+			return err
+		}
+		if (message["group"] != "testgroup") || (message["timestamp"] == nil) || (len(message["partitions"].([]interface{})) != 1) {
+			return errors.New("unexpected message: " + string(value))
+		}
+		return nil
+	})
+	module.producer = producer
+
+	sent := helpers.GetMetricCounter("exporter.test.sent").Count()
+	module.Export(fixtureStatuses())
+	assert.Equal(t, sent+1, helpers.GetMetricCounter("exporter.test.sent").Count(), "Expected one message, as the other group is denied")
+	module.Stop()
+}
+
+func TestKafkaExporter_Export_Failed(t *testing.T) {
+	module := fixtureKafkaModule()
+	module.Configure("test", "exporter.test")
+
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageAndFail(sarama.ErrNotEnoughReplicas)
+	module.producer = producer
+
+	errorCount := helpers.GetMetricCounter("exporter.test.errors").Count()
+	module.Export(fixtureStatuses())
+	assert.Equal(t, errorCount+1, helpers.GetMetricCounter("exporter.test.errors").Count(), "Expected the failed message to be counted")
+	module.Stop()
+}
@@ -0,0 +1,140 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// OTLPExporter is an exporter module that sends the status and lag of each consumer group, and of each of its
+// partitions, to an OpenTelemetry collector each interval, using OTLP over HTTP or gRPC. The collector is configured in
+// the otlp section, which is shared with tracing and the otlp reporter (see helpers.GetOTLPClient).
+//
+// Unlike the otlp reporter, which only sends a summary of each group, this sends a data point for every partition,
+// with the cluster, group, topic, and partition as attributes, so that the lag of each partition is kept by the
+// metrics system. The groups that are sent can be limited with group-allowlist and group-denylist.
+type OTLPExporter struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name      string
+	client    *helpers.OTLPClient
+	prefix    string
+	filter    *helpers.ConsumerFilter
+	startTime time.Time
+
+	exportTime metrics.Histogram
+	sentCount  metrics.Counter
+	errorCount metrics.Counter
+}
+
+// Configure validates the configuration for the module, including the otlp section. The prefix of every metric name
+// defaults to "burrow.". If there are any problems, it is expected that this func will panic with a descriptive error
+// message, as configuration failures are not recoverable errors.
+func (module *OTLPExporter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.exportTime = helpers.GetMetricHistogram(configRoot + ".export-time")
+	module.sentCount = helpers.GetMetricCounter(configRoot + ".sent")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+
+	viper.SetDefault(configRoot+".prefix", "burrow.")
+	module.client = helpers.GetOTLPClient()
+	module.prefix = viper.GetString(configRoot + ".prefix")
+
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		panic("Exporter '" + name + "' has an invalid filter: " + err.Error())
+	}
+	module.filter = filter
+}
+
+// Start is a no-op for the module, as the collector is not contacted until the first statuses are exported
+func (module *OTLPExporter) Start() error {
+	module.Log.Info("starting")
+	module.startTime = time.Now()
+	return nil
+}
+
+// Stop is a no-op for the module, as the coordinator waits for an export that is in progress to finish
+func (module *OTLPExporter) Stop() error {
+	module.Log.Info("stopping")
+	return nil
+}
+
+// Export sends the gauges for the statuses that are accepted by the group filter to the collector. If the collector
+// does not accept them, it is counted in the errors metric.
+func (module *OTLPExporter) Export(statuses []*protocol.ConsumerGroupStatus) {
+	defer helpers.UpdateMetricTime(module.exportTime, time.Now())
+
+	exported := module.getMetrics(statuses)
+	if err := module.client.ExportMetrics("github.com/linkedin/Burrow/exporter", module.startTime, exported); err != nil {
+		module.errorCount.Inc(1)
+		module.Log.Warn("failed to export statuses", zap.Error(err))
+		helpers.PublishModuleError("exporter."+module.name, "", "failed to export statuses: "+err.Error())
+		return
+	}
+	module.sentCount.Inc(int64(len(exported)))
+}
+
+// getMetrics returns the gauges for the groups and their partitions, with a data point for each group or partition
+func (module *OTLPExporter) getMetrics(statuses []*protocol.ConsumerGroupStatus) []*helpers.OTLPMetric {
+	status := &helpers.OTLPMetric{Name: module.prefix + "consumer.status", Type: helpers.OTLPGauge}
+	totalLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.total_lag", Type: helpers.OTLPGauge}
+	partitionStatus := &helpers.OTLPMetric{Name: module.prefix + "consumer.partition.status", Type: helpers.OTLPGauge}
+	partitionLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.partition.lag", Type: helpers.OTLPGauge}
+	partitionOffset := &helpers.OTLPMetric{Name: module.prefix + "consumer.partition.offset", Type: helpers.OTLPGauge}
+	partitionTimeLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.partition.time_lag", Type: helpers.OTLPGauge, Unit: "ms"}
+
+	for _, groupStatus := range statuses {
+		if !module.filter.AcceptGroup(groupStatus.Group) {
+			continue
+		}
+		attributes := map[string]interface{}{
+			"kafka.cluster":        groupStatus.Cluster,
+			"kafka.consumer_group": groupStatus.Group,
+		}
+		for label, value := range groupStatus.ClusterLabels {
+			attributes[label] = value
+		}
+		status.Points = append(status.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupStatus.Status)})
+		totalLag.Points = append(totalLag.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupStatus.TotalLag)})
+
+		for _, partition := range groupStatus.Partitions {
+			partitionAttributes := make(map[string]interface{}, len(attributes)+2)
+			for key, value := range attributes {
+				partitionAttributes[key] = value
+			}
+			partitionAttributes["kafka.topic"] = partition.Topic
+			partitionAttributes["kafka.partition"] = int64(partition.Partition)
+
+			partitionStatus.Points = append(partitionStatus.Points, helpers.OTLPDataPoint{Attributes: partitionAttributes, Value: int64(partition.Status)})
+			partitionLag.Points = append(partitionLag.Points, helpers.OTLPDataPoint{Attributes: partitionAttributes, Value: int64(partition.CurrentLag)})
+			partitionTimeLag.Points = append(partitionTimeLag.Points, helpers.OTLPDataPoint{Attributes: partitionAttributes, Value: partition.TimeLag})
+			if partition.End != nil {
+				partitionOffset.Points = append(partitionOffset.Points, helpers.OTLPDataPoint{Attributes: partitionAttributes, Value: partition.End.Offset})
+			}
+		}
+	}
+	return []*helpers.OTLPMetric{status, totalLag, partitionStatus, partitionLag, partitionOffset, partitionTimeLag}
+}
@@ -0,0 +1,95 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureOTLPModule(endpoint string) *OTLPExporter {
+	module := OTLPExporter{
+		Log: zap.NewNop(),
+	}
+	module.App = &protocol.ApplicationContext{}
+
+	viper.Reset()
+	viper.Set("otlp.endpoint", endpoint)
+	viper.Set("exporter.test.class-name", "otlp")
+	viper.Set("exporter.test.group-denylist", "^dropped$")
+	return &module
+}
+
+// findOTLPMetric returns the metric with the name from an OTLP metrics request that was decoded as JSON
+func findOTLPMetric(request map[string]interface{}, name string) map[string]interface{} {
+	resourceMetrics := request["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	scopeMetrics := resourceMetrics["scopeMetrics"].([]interface{})[0].(map[string]interface{})
+	for _, metric := range scopeMetrics["metrics"].([]interface{}) {
+		if metric.(map[string]interface{})["name"] == name {
+			return metric.(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+func TestOTLPExporter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*Module)(nil), new(OTLPExporter))
+}
+
+func TestOTLPExporter_Configure_NoEndpoint(t *testing.T) {
+	module := fixtureOTLPModule("")
+	assert.Panics(t, func() { module.Configure("test", "exporter.test") }, "Expected panic without an otlp.endpoint")
+}
+
+func TestOTLPExporter_Export(t *testing.T) {
+	requests := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path, "Expected metrics to be sent to /v1/metrics")
+		var request map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request), "Expected a valid OTLP request")
+		requests <- request
+	}))
+	defer collector.Close()
+
+	module := fixtureOTLPModule(collector.URL)
+	module.Configure("test", "exporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	module.Export(fixtureStatuses())
+	request := <-requests
+
+	totalLag := findOTLPMetric(request, "burrow.consumer.total_lag")
+	assert.NotNil(t, totalLag, "Expected the consumer total lag")
+	points := totalLag["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	assert.Len(t, points, 1, "Expected one data point, as the other group is denied")
+
+	partitionLag := findOTLPMetric(request, "burrow.consumer.partition.lag")
+	assert.NotNil(t, partitionLag, "Expected the partition lag")
+	points = partitionLag["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	assert.Len(t, points, 1, "Expected a data point for the partition")
+	assert.Equal(t, "2500", points[0].(map[string]interface{})["asInt"], "Expected the lag of the partition")
+	assert.Contains(t, points[0].(map[string]interface{})["attributes"], map[string]interface{}{
+		"key":   "kafka.topic",
+		"value": map[string]interface{}{"stringValue": "testtopic"},
+	}, "Expected the topic as an attribute")
+
+	timeLag := findOTLPMetric(request, "burrow.consumer.partition.time_lag")
+	assert.Equal(t, "ms", timeLag["unit"], "Expected the time lag in milliseconds")
+}
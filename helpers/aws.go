@@ -0,0 +1,424 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+)
+
+const (
+	awsMetadataURL          = "http://169.254.169.254"
+	awsContainerMetadataURL = "http://169.254.170.2"
+	awsCredentialsMargin    = 5 * time.Minute
+	mskTokenExpiry          = 15 * time.Minute
+	mskSigningService       = "kafka-cluster"
+	mskUserAgent            = "burrow"
+	awsEmptyPayloadHash     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// getMSKIAMTokenProvider returns a token provider for the AWS_MSK_IAM mechanism in the named sasl profile. The region
+// is taken from the aws-region config, or from the AWS_REGION or AWS_DEFAULT_REGION environment variables. If no
+// region can be found, this func will panic.
+func getMSKIAMTokenProvider(saslName string) sarama.AccessTokenProvider {
+	configRoot := "sasl." + saslName
+	viper.SetDefault(configRoot+".aws-role-session-name", "burrow")
+
+	region := viper.GetString(configRoot + ".aws-region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		panic("AWS_MSK_IAM requires aws-region to be set in sasl profile " + saslName)
+	}
+
+	return &MSKIAMTokenProvider{
+		Region: region,
+		Credentials: &AWSCredentialProvider{
+			Region:          region,
+			RoleSessionName: viper.GetString(configRoot + ".aws-role-session-name"),
+			HTTPClient:      &http.Client{Timeout: 10 * time.Second},
+		},
+	}
+}
+
+// MSKIAMTokenProvider is a sarama.AccessTokenProvider that generates tokens for AWS MSK IAM authentication. The token
+// is a presigned (AWS Signature Version 4) request for the kafka-cluster:Connect action, which MSK accepts over the
+// SASL/OAUTHBEARER mechanism. MSK only allows IAM authentication over TLS, so a tls profile must also be used.
+type MSKIAMTokenProvider struct {
+	// Region is the AWS region that the MSK cluster is in
+	Region string
+
+	// Credentials provides the AWS credentials that the token is signed with
+	Credentials *AWSCredentialProvider
+}
+
+// Token generates a new token for MSK IAM authentication, signed with the current AWS credentials
+func (p *MSKIAMTokenProvider) Token() (*sarama.AccessToken, error) {
+	creds, err := p.Credentials.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+
+	signedURL := presignMSKConnect(p.Region, creds, time.Now().UTC())
+	return &sarama.AccessToken{Token: base64.RawURLEncoding.EncodeToString([]byte(signedURL))}, nil
+}
+
+// presignMSKConnect returns a URL for the kafka-cluster:Connect action in the given region, presigned with AWS
+// Signature Version 4 using the credentials provided
+func presignMSKConnect(region string, creds *AWSCredentials, signTime time.Time) string {
+	host := "kafka." + region + ".amazonaws.com"
+	query := map[string]string{"Action": "kafka-cluster:Connect"}
+	return awsPresign(host, "/", query, region, mskSigningService, awsEmptyPayloadHash, mskTokenExpiry, creds, signTime) + "&User-Agent=" + mskUserAgent
+}
+
+// awsPresign returns an https URL for a GET request to the host, with the AWS Signature Version 4 authentication
+// parameters added to the query string
+func awsPresign(host, path string, query map[string]string, region, service, payloadHash string, expires time.Duration, creds *AWSCredentials, signTime time.Time) string {
+	amzDate := signTime.Format("20060102T150405Z")
+	scope := strings.Join([]string{signTime.Format("20060102"), region, service, "aws4_request"}, "/")
+
+	signedQuery := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       fmt.Sprintf("%d", int64(expires/time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		signedQuery["X-Amz-Security-Token"] = creds.SessionToken
+	}
+	for key, value := range query {
+		signedQuery[key] = value
+	}
+	canonicalQuery := awsCanonicalQuery(signedQuery)
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		path,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		payloadHash,
+	}, "\n")
+	signature := awsSignature(canonicalRequest, amzDate, scope, region, service, creds, signTime)
+
+	return "https://" + host + path + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// awsSignRequest adds AWS Signature Version 4 authentication headers to the request, which has the body given. Every
+// header that is already set on the request, and the host, are signed.
+func awsSignRequest(request *http.Request, body []byte, region, service string, creds *AWSCredentials, signTime time.Time) {
+	amzDate := signTime.Format("20060102T150405Z")
+	scope := strings.Join([]string{signTime.Format("20060102"), region, service, "aws4_request"}, "/")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for key, values := range request.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	signature := awsSignature(canonicalRequest, amzDate, scope, region, service, creds, signTime)
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// awsSignature returns the AWS Signature Version 4 signature for the canonical request
+func awsSignature(canonicalRequest, amzDate, scope, region, service string, creds *AWSCredentials, signTime time.Time) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signingKey := awsHMAC([]byte("AWS4"+creds.SecretAccessKey), signTime.Format("20060102"))
+	signingKey = awsHMAC(signingKey, region)
+	signingKey = awsHMAC(signingKey, service)
+	signingKey = awsHMAC(signingKey, "aws4_request")
+	return hex.EncodeToString(awsHMAC(signingKey, stringToSign))
+}
+
+func awsHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalQuery encodes the query parameters sorted by key, with all characters other than the unreserved ones
+// percent-encoded, as required for AWS signatures
+func awsCanonicalQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = awsURIEncode(key) + "=" + awsURIEncode(query[key])
+	}
+	return strings.Join(parts, "&")
+}
+
+func awsURIEncode(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
+
+// AWSCredentials is a set of AWS credentials, with an optional expiration time for temporary credentials
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// AWSCredentialProvider retrieves AWS credentials from the standard sources, in order: environment variables, a web
+// identity token (such as for IAM roles for service accounts in EKS), the ECS container credentials endpoint, and the
+// EC2 instance profile. Temporary credentials are cached until shortly before they expire.
+type AWSCredentialProvider struct {
+	// Region is used to select the regional STS endpoint for web identity credentials
+	Region string
+
+	// RoleSessionName is the session name used when assuming a role with a web identity token
+	RoleSessionName string
+
+	// HTTPClient is used for all requests to credential endpoints
+	HTTPClient *http.Client
+
+	// These URLs default to the standard AWS endpoints, and only need to be set for testing
+	STSURL               string
+	MetadataURL          string
+	ContainerMetadataURL string
+
+	lock  sync.Mutex
+	creds *AWSCredentials
+}
+
+// Retrieve returns the current AWS credentials, fetching new ones if there are none cached or the cached credentials
+// are about to expire
+func (p *AWSCredentialProvider) Retrieve() (*AWSCredentials, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if (p.creds != nil) && (p.creds.Expiration.IsZero() || time.Now().Add(awsCredentialsMargin).Before(p.creds.Expiration)) {
+		return p.creds, nil
+	}
+
+	var creds *AWSCredentials
+	var err error
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		creds = &AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		// Credentials from the environment are always reread, as they have no expiration time that we know of
+		return creds, nil
+	} else if os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" {
+		creds, err = p.retrieveWebIdentity()
+	} else if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" {
+		creds, err = p.retrieveContainer()
+	} else {
+		creds, err = p.retrieveInstanceProfile()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.creds = creds
+	return creds, nil
+}
+
+type awsAssumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+func (p *AWSCredentialProvider) retrieveWebIdentity() (*AWSCredentials, error) {
+	token, err := ioutil.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	stsURL := p.STSURL
+	if stsURL == "" {
+		stsURL = "https://sts." + p.Region + ".amazonaws.com/"
+	}
+	query := url.Values{}
+	query.Set("Action", "AssumeRoleWithWebIdentity")
+	query.Set("Version", "2011-06-15")
+	query.Set("RoleArn", os.Getenv("AWS_ROLE_ARN"))
+	query.Set("RoleSessionName", p.RoleSessionName)
+	query.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	body, err := p.get(stsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &awsAssumeRoleResponse{}
+	if err := xml.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+	if response.Credentials.AccessKeyID == "" {
+		return nil, errors.New("no credentials returned from AssumeRoleWithWebIdentity")
+	}
+	return &AWSCredentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+		Expiration:      response.Credentials.Expiration,
+	}, nil
+}
+
+type awsMetadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (p *AWSCredentialProvider) retrieveContainer() (*AWSCredentials, error) {
+	baseURL := p.ContainerMetadataURL
+	if baseURL == "" {
+		baseURL = awsContainerMetadataURL
+	}
+
+	body, err := p.get(baseURL+os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetadataCredentials(body)
+}
+
+func (p *AWSCredentialProvider) retrieveInstanceProfile() (*AWSCredentials, error) {
+	baseURL := p.MetadataURL
+	if baseURL == "" {
+		baseURL = awsMetadataURL
+	}
+
+	// Get a session token for IMDSv2 first
+	request, err := http.NewRequest("PUT", baseURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	token, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if (err != nil) || (response.StatusCode != http.StatusOK) {
+		return nil, errors.New("could not get instance metadata token")
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+
+	roles, err := p.get(baseURL+"/latest/meta-data/iam/security-credentials/", headers)
+	if err != nil {
+		return nil, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return nil, errors.New("no instance profile role found")
+	}
+
+	body, err := p.get(baseURL+"/latest/meta-data/iam/security-credentials/"+role, headers)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetadataCredentials(body)
+}
+
+func parseMetadataCredentials(body []byte) (*AWSCredentials, error) {
+	metadataCreds := &awsMetadataCredentials{}
+	if err := json.Unmarshal(body, metadataCreds); err != nil {
+		return nil, err
+	}
+	if metadataCreds.AccessKeyID == "" {
+		return nil, errors.New("no credentials returned from metadata endpoint")
+	}
+	return &AWSCredentials{
+		AccessKeyID:     metadataCreds.AccessKeyID,
+		SecretAccessKey: metadataCreds.SecretAccessKey,
+		SessionToken:    metadataCreds.Token,
+		Expiration:      metadataCreds.Expiration,
+	}, nil
+}
+
+func (p *AWSCredentialProvider) get(requestURL string, headers map[string]string) ([]byte, error) {
+	request, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("credentials request failed with status %v", response.StatusCode)
+	}
+	return ioutil.ReadAll(response.Body)
+}
@@ -0,0 +1,191 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// awsSecretCache holds the secrets that have been read from AWS, keyed by the service and the secret name, so that a
+// secret with several fields (or one that is used in more than one place) is only read once per aws.cache-time
+var awsSecretCache sync.Map
+
+type awsCachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// AWSSecretsClient reads secrets from AWS Secrets Manager and parameters from AWS Systems Manager Parameter Store,
+// signing the requests with the credentials from an AWSCredentialProvider. Values are cached for CacheTime.
+type AWSSecretsClient struct {
+	Region      string
+	Credentials *AWSCredentialProvider
+	HTTPClient  *http.Client
+	CacheTime   time.Duration
+
+	// These URLs default to the regional AWS endpoints, and only need to be set for testing
+	SecretsManagerURL string
+	SSMURL            string
+}
+
+type awsSecretValueResponse struct {
+	SecretString string `json:"SecretString"`
+}
+
+type awsParameterResponse struct {
+	Parameter struct {
+		Value string `json:"Value"`
+	} `json:"Parameter"`
+}
+
+// newAWSSecretsClient creates an AWSSecretsClient from the aws section of the configuration. The region is taken from
+// aws.region, or from the AWS_REGION or AWS_DEFAULT_REGION environment variables.
+func newAWSSecretsClient(config *viper.Viper) (*AWSSecretsClient, error) {
+	region := config.GetString("aws.region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("no AWS region is configured")
+	}
+
+	cacheTime := 60 * time.Second
+	if config.IsSet("aws.cache-time") {
+		cacheTime = time.Duration(config.GetInt("aws.cache-time")) * time.Second
+	}
+	roleSessionName := config.GetString("aws.role-session-name")
+	if roleSessionName == "" {
+		roleSessionName = "burrow"
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	return &AWSSecretsClient{
+		Region: region,
+		Credentials: &AWSCredentialProvider{
+			Region:          region,
+			RoleSessionName: roleSessionName,
+			HTTPClient:      httpClient,
+		},
+		HTTPClient: httpClient,
+		CacheTime:  cacheTime,
+	}, nil
+}
+
+// GetSecretValue returns the current value of the secret from Secrets Manager. If field is not empty, the secret must
+// be a JSON object, and the value of that field (which must be a string) is returned.
+func (client *AWSSecretsClient) GetSecretValue(secretID, field string) (string, error) {
+	secretString, err := client.cached("secretsmanager:"+secretID, func() (string, error) {
+		endpoint := client.SecretsManagerURL
+		if endpoint == "" {
+			endpoint = "https://secretsmanager." + client.Region + ".amazonaws.com/"
+		}
+		body, err := client.post(endpoint, "secretsmanager", "secretsmanager.GetSecretValue", map[string]interface{}{"SecretId": secretID})
+		if err != nil {
+			return "", err
+		}
+		response := &awsSecretValueResponse{}
+		if err := json.Unmarshal(body, response); err != nil {
+			return "", err
+		}
+		return response.SecretString, nil
+	})
+	if (err != nil) || (field == "") {
+		return secretString, err
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(secretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %v is not a JSON object: %v", secretID, err)
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %v has no field %v", secretID, field)
+	}
+	return value, nil
+}
+
+// GetParameter returns the value of the parameter from Parameter Store. SecureString parameters are decrypted.
+func (client *AWSSecretsClient) GetParameter(name string) (string, error) {
+	return client.cached("ssm:"+name, func() (string, error) {
+		endpoint := client.SSMURL
+		if endpoint == "" {
+			endpoint = "https://ssm." + client.Region + ".amazonaws.com/"
+		}
+		body, err := client.post(endpoint, "ssm", "AmazonSSM.GetParameter", map[string]interface{}{"Name": name, "WithDecryption": true})
+		if err != nil {
+			return "", err
+		}
+		response := &awsParameterResponse{}
+		if err := json.Unmarshal(body, response); err != nil {
+			return "", err
+		}
+		return response.Parameter.Value, nil
+	})
+}
+
+func (client *AWSSecretsClient) cached(key string, fetch func() (string, error)) (string, error) {
+	if cached, ok := awsSecretCache.Load(key); ok && time.Now().Before(cached.(*awsCachedSecret).expires) {
+		return cached.(*awsCachedSecret).value, nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	awsSecretCache.Store(key, &awsCachedSecret{value: value, expires: time.Now().Add(client.CacheTime)})
+	return value, nil
+}
+
+// post sends a request to an AWS JSON API, and returns the body of the response
+func (client *AWSSecretsClient) post(endpoint, service, target string, payload map[string]interface{}) ([]byte, error) {
+	creds, err := client.Credentials.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", target)
+	awsSignRequest(request, body, client.Region, service, creds, time.Now().UTC())
+
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v request failed with status %v", target, response.StatusCode)
+	}
+	return responseBody, nil
+}
@@ -0,0 +1,58 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// The overflow policies for a request queue that is full. With OverflowBlock, the sender waits until there is space in
+// the queue. With OverflowDrop, requests that do not have a reply are dropped (and counted), so that the sender is not
+// held up. Requests that have a reply are never dropped, as the sender would wait for the reply forever.
+const (
+	OverflowBlock = "block"
+	OverflowDrop  = "drop"
+)
+
+// IsOverflowPolicy returns true if the policy is one of the overflow policies
+func IsOverflowPolicy(policy string) bool {
+	return (policy == OverflowBlock) || (policy == OverflowDrop)
+}
+
+// CheckChannelConfig returns an error if general.storage-channel-capacity or general.evaluator-channel-capacity is
+// negative
+func CheckChannelConfig() error {
+	if viper.GetInt("general.storage-channel-capacity") < 0 {
+		return errors.New("general.storage-channel-capacity must not be negative")
+	}
+	if viper.GetInt("general.evaluator-channel-capacity") < 0 {
+		return errors.New("general.evaluator-channel-capacity must not be negative")
+	}
+	return nil
+}
+
+// MakeStorageChannel returns a channel for requests of one priority to the storage coordinator, which can hold
+// general.storage-channel-capacity requests (none by default) before senders have to wait. CheckChannelConfig must be
+// called first, as the channel cannot be made with a negative capacity.
+func MakeStorageChannel() chan *protocol.StorageRequest {
+	return make(chan *protocol.StorageRequest, viper.GetInt("general.storage-channel-capacity"))
+}
+
+// MakeEvaluatorChannel returns a channel for requests to the evaluator coordinator, which can hold
+// general.evaluator-channel-capacity requests (none by default) before senders have to wait. CheckChannelConfig must
+// be called first, as the channel cannot be made with a negative capacity.
+func MakeEvaluatorChannel() chan *protocol.EvaluatorRequest {
+	return make(chan *protocol.EvaluatorRequest, viper.GetInt("general.evaluator-channel-capacity"))
+}
@@ -0,0 +1,47 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeChannels(t *testing.T) {
+	viper.Reset()
+	assert.NoError(t, CheckChannelConfig(), "Expected the default capacities to be valid")
+	assert.Equal(t, 0, cap(MakeStorageChannel()), "Expected the storage channel to be unbuffered by default")
+	assert.Equal(t, 0, cap(MakeEvaluatorChannel()), "Expected the evaluator channel to be unbuffered by default")
+
+	viper.Set("general.storage-channel-capacity", 100)
+	viper.Set("general.evaluator-channel-capacity", 10)
+	assert.NoError(t, CheckChannelConfig(), "Expected the capacities to be valid")
+	assert.Equal(t, 100, cap(MakeStorageChannel()), "Expected the storage channel capacity to be 100")
+	assert.Equal(t, 10, cap(MakeEvaluatorChannel()), "Expected the evaluator channel capacity to be 10")
+}
+
+func TestCheckChannelConfig_Negative(t *testing.T) {
+	viper.Reset()
+	viper.Set("general.storage-channel-capacity", -1)
+	assert.Error(t, CheckChannelConfig(), "Expected a negative storage channel capacity to be an error")
+
+	viper.Reset()
+	viper.Set("general.evaluator-channel-capacity", -1)
+	assert.Error(t, CheckChannelConfig(), "Expected a negative evaluator channel capacity to be an error")
+}
+
+func TestIsOverflowPolicy(t *testing.T) {
+	assert.True(t, IsOverflowPolicy("block"), "Expected block to be an overflow policy")
+	assert.True(t, IsOverflowPolicy("drop"), "Expected drop to be an overflow policy")
+	assert.False(t, IsOverflowPolicy("wait"), "Expected wait not to be an overflow policy")
+}
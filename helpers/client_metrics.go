@@ -0,0 +1,73 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// clientMetricsConfigs holds the sarama.Config for each module that has registered its client metrics, keyed by the
+// config root of the module. Sarama records its metrics in the MetricRegistry of the config that the client was
+// created with.
+var clientMetricsConfigs sync.Map
+
+type clientMetricsEntry struct {
+	cluster      string
+	saramaConfig *sarama.Config
+}
+
+// ClientMetrics is a snapshot of the metrics that sarama has recorded for the Kafka client of a single module, such as
+// request and response rates, request latency, and request and response sizes, overall and for each broker.
+type ClientMetrics struct {
+	// The config root of the module that the client belongs to (for example, "cluster.local")
+	Module string `json:"module"`
+
+	// The name of the Kafka cluster that the client connects to
+	Cluster string `json:"cluster"`
+
+	// The labels configured for the cluster, if any
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// The metrics, keyed by the sarama metric name. Each metric is a map of its values (for example, a meter has
+	// count, 1m.rate, 5m.rate, 15m.rate, and mean.rate)
+	Metrics map[string]map[string]interface{} `json:"metrics"`
+}
+
+// RegisterClientMetrics makes the metrics for clients created with the sarama.Config available from GetClientMetrics,
+// labeled with the module config root and the cluster name. It must be called with the same config that is passed to
+// NewSaramaClient, and replaces any registration for the same module.
+func RegisterClientMetrics(configRoot, cluster string, saramaConfig *sarama.Config) {
+	clientMetricsConfigs.Store(configRoot, &clientMetricsEntry{
+		cluster:      cluster,
+		saramaConfig: saramaConfig,
+	})
+}
+
+// GetClientMetrics returns a snapshot of the client metrics for every module that has registered them, sorted by the
+// module config root.
+func GetClientMetrics() []*ClientMetrics {
+	result := make([]*ClientMetrics, 0)
+	clientMetricsConfigs.Range(func(key, value interface{}) bool {
+		entry := value.(*clientMetricsEntry)
+		result = append(result, &ClientMetrics{
+			Module:  key.(string),
+			Cluster: entry.cluster,
+			Labels:  GetClusterLabels(entry.cluster),
+			Metrics: entry.saramaConfig.MetricRegistry.GetAll(),
+		})
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Module < result[j].Module })
+	return result
+}
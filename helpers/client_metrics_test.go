@@ -0,0 +1,35 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClientMetrics(t *testing.T) {
+	saramaConfig := sarama.NewConfig()
+	metrics.GetOrRegisterMeter("request-rate", saramaConfig.MetricRegistry).Mark(5)
+	RegisterClientMetrics("cluster.metricstest", "metricstest", saramaConfig)
+
+	var found *ClientMetrics
+	for _, clientMetrics := range GetClientMetrics() {
+		if clientMetrics.Module == "cluster.metricstest" {
+			found = clientMetrics
+		}
+	}
+	assert.NotNil(t, found, "Expected metrics for the registered module")
+	assert.Equalf(t, "metricstest", found.Cluster, "Expected cluster to be metricstest, not %v", found.Cluster)
+	assert.Equalf(t, int64(5), found.Metrics["request-rate"]["count"], "Expected request-rate count to be 5, not %v", found.Metrics["request-rate"]["count"])
+}
@@ -0,0 +1,115 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// clientPools holds the shared client for each client pool name. It is protected by clientPoolLock, as a client must
+// not be created twice for the same pool.
+var (
+	clientPools    = make(map[string]*clientPoolEntry)
+	clientPoolLock sync.Mutex
+)
+
+type clientPoolEntry struct {
+	client  sarama.Client
+	servers string
+	users   int
+}
+
+// pooledClient is a sarama.Client from a client pool. Closing it releases it from the pool, and the underlying client
+// is only closed when every module that acquired it has closed it.
+type pooledClient struct {
+	sarama.Client
+	pool      string
+	closeOnce sync.Once
+}
+
+// AcquireSaramaClient returns a sarama.Client for the servers. If pool is empty, this is the same as calling
+// NewSaramaClient. Otherwise, modules that acquire a client with the same pool name share a single client, and so a
+// single set of connections to the brokers. The shared client is created with the config of the first module to
+// acquire it, so all modules in a pool should use the same client profile. An error is returned if a module tries to
+// use a pool with a different list of servers. Calling Close on the returned client releases it, and the shared client
+// is closed when the last module releases it.
+func AcquireSaramaClient(pool string, servers []string, saramaConfig *sarama.Config) (sarama.Client, error) {
+	if pool == "" {
+		return NewSaramaClient(servers, saramaConfig)
+	}
+
+	serverList := make([]string, len(servers))
+	copy(serverList, servers)
+	sort.Strings(serverList)
+	serverKey := strings.Join(serverList, ",")
+
+	clientPoolLock.Lock()
+	defer clientPoolLock.Unlock()
+
+	entry, ok := clientPools[pool]
+	if !ok {
+		client, err := NewSaramaClient(servers, saramaConfig)
+		if err != nil {
+			return nil, err
+		}
+		entry = &clientPoolEntry{
+			client:  client,
+			servers: serverKey,
+		}
+		clientPools[pool] = entry
+	} else if entry.servers != serverKey {
+		return nil, errors.New("client pool " + pool + " is already used with a different list of servers")
+	}
+
+	entry.users++
+	return &pooledClient{
+		Client: entry.client,
+		pool:   pool,
+	}, nil
+}
+
+// Close releases the client from the pool, and closes the shared client if no other module is using it. Calling Close
+// more than once has no further effect.
+func (c *pooledClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		clientPoolLock.Lock()
+		defer clientPoolLock.Unlock()
+
+		entry := clientPools[c.pool]
+		entry.users--
+		if entry.users == 0 {
+			delete(clientPools, c.pool)
+			err = entry.client.Close()
+		}
+	})
+	return err
+}
+
+// UseConsumerConfig copies the consumer settings (such as fetch sizes and isolation level) from saramaConfig to the
+// config of a client from a client pool, as the shared client may have been created by a module that does not
+// consume. This must be called before creating consumers from the client. The isolation level is left as read
+// uncommitted if the client's Kafka version does not support transactions.
+func UseConsumerConfig(clientConfig, saramaConfig *sarama.Config) {
+	if clientConfig == saramaConfig {
+		return
+	}
+	clientConfig.Consumer = saramaConfig.Consumer
+	clientConfig.ChannelBufferSize = saramaConfig.ChannelBufferSize
+	if !clientConfig.Version.IsAtLeast(sarama.V0_11_0_0) {
+		clientConfig.Consumer.IsolationLevel = sarama.ReadUncommitted
+	}
+}
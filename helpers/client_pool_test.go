@@ -0,0 +1,91 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureMetadataBroker(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+	return broker
+}
+
+func TestAcquireSaramaClient_Shared(t *testing.T) {
+	broker := fixtureMetadataBroker(t)
+	defer broker.Close()
+
+	saramaConfig := sarama.NewConfig()
+	client1, err := AcquireSaramaClient("testpool", []string{broker.Addr()}, saramaConfig)
+	assert.Nil(t, err, "Expected AcquireSaramaClient to return no error")
+	client2, err := AcquireSaramaClient("testpool", []string{broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected AcquireSaramaClient to return no error")
+
+	assert.Equal(t, client1.(*pooledClient).Client, client2.(*pooledClient).Client, "Expected clients to share the underlying client")
+	assert.Equal(t, saramaConfig, client2.Config(), "Expected shared client to use the config of the first module")
+
+	// The shared client stays open until both have closed it, and closing twice only releases once
+	assert.Nil(t, client1.Close(), "Expected Close to return no error")
+	assert.Nil(t, client1.Close(), "Expected Close to return no error")
+	assert.False(t, client2.Closed(), "Expected shared client to still be open")
+	assert.Nil(t, client2.Close(), "Expected Close to return no error")
+	assert.True(t, client2.Closed(), "Expected shared client to be closed")
+
+	_, ok := clientPools["testpool"]
+	assert.False(t, ok, "Expected pool to be removed")
+}
+
+func TestAcquireSaramaClient_NoPool(t *testing.T) {
+	broker := fixtureMetadataBroker(t)
+	defer broker.Close()
+
+	client1, err := AcquireSaramaClient("", []string{broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected AcquireSaramaClient to return no error")
+	defer client1.Close()
+	client2, err := AcquireSaramaClient("", []string{broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected AcquireSaramaClient to return no error")
+	defer client2.Close()
+
+	assert.NotEqual(t, client1, client2, "Expected separate clients without a pool")
+}
+
+func TestAcquireSaramaClient_DifferentServers(t *testing.T) {
+	broker := fixtureMetadataBroker(t)
+	defer broker.Close()
+
+	client, err := AcquireSaramaClient("testpool", []string{broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected AcquireSaramaClient to return no error")
+	defer client.Close()
+
+	_, err = AcquireSaramaClient("testpool", []string{"broker2.example.com:9092"}, sarama.NewConfig())
+	assert.Error(t, err, "Expected AcquireSaramaClient to return an error")
+}
+
+func TestUseConsumerConfig(t *testing.T) {
+	clientConfig := sarama.NewConfig()
+	clientConfig.Version = sarama.V0_10_2_0
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Consumer.Fetch.Min = 1024
+	saramaConfig.Consumer.IsolationLevel = sarama.ReadCommitted
+	saramaConfig.ChannelBufferSize = 10
+	UseConsumerConfig(clientConfig, saramaConfig)
+
+	assert.Equal(t, int32(1024), clientConfig.Consumer.Fetch.Min, "Expected Fetch.Min to be copied")
+	assert.Equal(t, 10, clientConfig.ChannelBufferSize, "Expected ChannelBufferSize to be copied")
+	assert.Equal(t, sarama.ReadUncommitted, clientConfig.Consumer.IsolationLevel, "Expected IsolationLevel to be ReadUncommitted for an old version")
+}
@@ -0,0 +1,46 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// GetClusterLabels returns the labels configured for the cluster, under cluster.<name>.labels, as a map of label name
+// to value. Labels are arbitrary, and are used to describe the cluster (such as env, region, or owner) so that
+// deployments with many clusters can filter and route on them. As with all config keys, label names are lowercased.
+// If the cluster has no labels, nil is returned.
+func GetClusterLabels(cluster string) map[string]string {
+	labels := viper.GetStringMapString("cluster." + cluster + ".labels")
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// MatchClusterLabels returns true if the cluster has every label in the selectors, each of which is of the form
+// name=value. A selector without a value (name, or name=) matches a cluster that has the label with any value.
+func MatchClusterLabels(cluster string, selectors []string) bool {
+	labels := GetClusterLabels(cluster)
+	for _, selector := range selectors {
+		parts := strings.SplitN(selector, "=", 2)
+		value, ok := labels[strings.ToLower(parts[0])]
+		if !ok {
+			return false
+		}
+		if (len(parts) == 2) && (parts[1] != "") && (parts[1] != value) {
+			return false
+		}
+	}
+	return true
+}
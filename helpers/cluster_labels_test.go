@@ -0,0 +1,38 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClusterLabels(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.labeled.labels", map[string]interface{}{"env": "prod", "Region": "us-west"})
+
+	assert.Equal(t, map[string]string{"env": "prod", "region": "us-west"}, GetClusterLabels("labeled"), "Expected the configured labels")
+	assert.Nil(t, GetClusterLabels("unlabeled"), "Expected no labels for a cluster without them")
+}
+
+func TestMatchClusterLabels(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.labeled.labels", map[string]interface{}{"env": "prod", "region": "us-west"})
+
+	assert.True(t, MatchClusterLabels("labeled", []string{}), "Expected no selectors to match")
+	assert.True(t, MatchClusterLabels("labeled", []string{"env=prod", "region=us-west"}), "Expected all labels to match")
+	assert.True(t, MatchClusterLabels("labeled", []string{"ENV"}), "Expected a label name without a value to match")
+	assert.False(t, MatchClusterLabels("labeled", []string{"env=staging"}), "Expected a different value not to match")
+	assert.False(t, MatchClusterLabels("labeled", []string{"owner=team"}), "Expected a missing label not to match")
+	assert.False(t, MatchClusterLabels("unlabeled", []string{"env=prod"}), "Expected a cluster without labels not to match")
+}
@@ -0,0 +1,63 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// GetConfigIncludePatterns returns the file patterns in the general.include config, such as "conf.d/*.toml". Patterns
+// that are relative paths are made relative to the directory of the configuration file.
+func GetConfigIncludePatterns(config *viper.Viper) []string {
+	configDir := filepath.Dir(config.ConfigFileUsed())
+	patterns := config.GetStringSlice("general.include")
+	for i, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			patterns[i] = filepath.Join(configDir, pattern)
+		}
+	}
+	return patterns
+}
+
+// MergeConfigIncludes merges the files matched by the general.include patterns into the configuration, so that parts
+// of the configuration (such as cluster definitions, or secrets) can be kept in separate files. The patterns are
+// merged in the order they are listed, and the files that match a single pattern are merged in order of their names.
+// Each file overrides the values set by the configuration file and the files merged before it, and maps (such as the
+// cluster section) are merged rather than replaced. The type of each file is taken from its extension, and included
+// files cannot include other files.
+//
+// This must be called each time the configuration file is read, before InterpolateConfig. If a file cannot be read,
+// an error is returned. As with InterpolateConfig, this must not be called on the global viper once Burrow is running.
+func MergeConfigIncludes(config *viper.Viper) error {
+	for _, pattern := range GetConfigIncludePatterns(config) {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return errors.New("bad include pattern " + pattern + ": " + err.Error())
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			include := viper.New()
+			include.SetConfigFile(file)
+			if err := include.ReadInConfig(); err != nil {
+				return errors.New("failed reading included file " + file + ": " + err.Error())
+			}
+			if err := config.MergeConfigMap(include.AllSettings()); err != nil {
+				return errors.New("failed merging included file " + file + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureIncludeConfig(t *testing.T, files map[string]string) (*viper.Viper, string) {
+	dir, err := ioutil.TempDir("", "burrow")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "conf.d"), 0755), "Expected conf.d to be created")
+	for name, contents := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644), "Expected file to be written")
+	}
+
+	config := viper.New()
+	config.SetConfigFile(filepath.Join(dir, "burrow.toml"))
+	assert.NoError(t, config.ReadInConfig(), "Expected config to be read")
+	return config, dir
+}
+
+func TestMergeConfigIncludes(t *testing.T) {
+	config, dir := fixtureIncludeConfig(t, map[string]string{
+		"burrow.toml": `
+[general]
+include=["conf.d/*.toml", "secrets.yaml"]
+[cluster.local]
+servers=["localhost:9092"]
+offset-refresh=10
+`,
+		"conf.d/20-override.toml": `
+[cluster.local]
+offset-refresh=30
+`,
+		"conf.d/10-clusters.toml": `
+[cluster.local]
+offset-refresh=20
+[cluster.remote]
+servers=["remote:9092"]
+`,
+		"secrets.yaml": `
+sasl:
+  test:
+    password: secret
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, MergeConfigIncludes(config), "Expected MergeConfigIncludes to return no error")
+	assert.Equal(t, []string{"localhost:9092"}, config.GetStringSlice("cluster.local.servers"), "Expected local servers from the main file")
+	assert.Equal(t, 30, config.GetInt("cluster.local.offset-refresh"), "Expected the last file in name order to override")
+	assert.Equal(t, []string{"remote:9092"}, config.GetStringSlice("cluster.remote.servers"), "Expected the remote cluster from conf.d")
+	assert.Equal(t, "secret", config.GetString("sasl.test.password"), "Expected the password from the YAML file")
+}
+
+func TestMergeConfigIncludes_BadFile(t *testing.T) {
+	config, dir := fixtureIncludeConfig(t, map[string]string{
+		"burrow.toml": `
+[general]
+include=["conf.d/*.toml"]
+`,
+		"conf.d/bad.toml": `[cluster`,
+	})
+	defer os.RemoveAll(dir)
+
+	assert.Error(t, MergeConfigIncludes(config), "Expected an error for a file that cannot be parsed")
+}
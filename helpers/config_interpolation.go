@@ -0,0 +1,176 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// configInterpolator replaces the references in a single configuration. The Vault and AWS clients are only created if
+// a value refers to them
+type configInterpolator struct {
+	config *viper.Viper
+	vault  *VaultClient
+	aws    *AWSSecretsClient
+}
+
+// InterpolateConfig replaces references to secrets in the string values of the configuration, so that secrets (such as
+// SASL passwords) do not need to be stored in the configuration file. A value that starts with "file:" is replaced by
+// the contents of the file at the path that follows, with any trailing newline removed. A value of the form
+// "vault:path#field" is replaced by the field of the secret at that path in HashiCorp Vault, which is connected to
+// using the vault section of the configuration. A value of the form "aws-secretsmanager:secret-id" (optionally followed
+// by #field, for a secret that is a JSON object) is replaced by the secret from AWS Secrets Manager, and a value of the
+// form "aws-ssm:name" by the parameter from AWS Systems Manager Parameter Store, using the aws section of the
+// configuration. Otherwise, each ${NAME} in the value is replaced by the value of the environment variable NAME.
+// Strings in lists are replaced the same way.
+//
+// This must be called each time the configuration file is read. If an environment variable is not set, or a secret
+// cannot be read, an error is returned and the configuration is not changed. As the configuration is changed, this must
+// not be called on the global viper once Burrow is running; a configuration that is read again is read into a new one.
+func InterpolateConfig(config *viper.Viper) error {
+	interpolator := &configInterpolator{config: config}
+	interpolated := make(map[string]interface{})
+	for _, key := range config.AllKeys() {
+		var value interface{}
+		var changed bool
+		var err error
+
+		switch original := config.Get(key).(type) {
+		case string:
+			value, changed, err = interpolator.interpolateValue(original)
+		case []interface{}:
+			values := make([]interface{}, len(original))
+			for i, item := range original {
+				values[i] = item
+				if str, ok := item.(string); ok {
+					var itemChanged bool
+					values[i], itemChanged, err = interpolator.interpolateValue(str)
+					if err != nil {
+						break
+					}
+					changed = changed || itemChanged
+				}
+			}
+			value = values
+		default:
+			continue
+		}
+
+		if err != nil {
+			return errors.New(key + ": " + err.Error())
+		}
+		if changed {
+			setNestedValue(interpolated, strings.Split(key, "."), value)
+		}
+	}
+
+	if len(interpolated) == 0 {
+		return nil
+	}
+	return config.MergeConfigMap(interpolated)
+}
+
+// interpolateValue returns the value with any Vault, AWS, file, or environment variable references replaced, and
+// whether anything was replaced
+func (interpolator *configInterpolator) interpolateValue(value string) (string, bool, error) {
+	if strings.HasPrefix(value, "vault:") {
+		secret, err := interpolator.readVaultSecret(strings.TrimPrefix(value, "vault:"))
+		return secret, err == nil, err
+	}
+	if strings.HasPrefix(value, "aws-secretsmanager:") || strings.HasPrefix(value, "aws-ssm:") {
+		secret, err := interpolator.readAWSSecret(value)
+		return secret, err == nil, err
+	}
+	return interpolateLocalValue(value)
+}
+
+// interpolateLocalValue returns the value with any file or environment variable references replaced, and whether
+// anything was replaced
+func interpolateLocalValue(value string) (string, bool, error) {
+	if strings.HasPrefix(value, "file:") {
+		contents, err := ioutil.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(contents), "\r\n"), true, nil
+	}
+
+	if !envReference.MatchString(value) {
+		return value, false, nil
+	}
+	var err error
+	value = envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok && (err == nil) {
+			err = errors.New("environment variable " + name + " is not set")
+		}
+		return envValue
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (interpolator *configInterpolator) readVaultSecret(reference string) (string, error) {
+	parts := strings.SplitN(reference, "#", 2)
+	if (len(parts) != 2) || (parts[0] == "") || (parts[1] == "") {
+		return "", errors.New("vault references must be of the form vault:path#field")
+	}
+	if interpolator.vault == nil {
+		client, err := newVaultClient(interpolator.config)
+		if err != nil {
+			return "", err
+		}
+		interpolator.vault = client
+	}
+	return interpolator.vault.ReadSecret(parts[0], parts[1])
+}
+
+func (interpolator *configInterpolator) readAWSSecret(reference string) (string, error) {
+	if interpolator.aws == nil {
+		client, err := newAWSSecretsClient(interpolator.config)
+		if err != nil {
+			return "", err
+		}
+		interpolator.aws = client
+	}
+
+	if strings.HasPrefix(reference, "aws-ssm:") {
+		return interpolator.aws.GetParameter(strings.TrimPrefix(reference, "aws-ssm:"))
+	}
+	parts := strings.SplitN(strings.TrimPrefix(reference, "aws-secretsmanager:"), "#", 2)
+	if len(parts) == 1 {
+		return interpolator.aws.GetSecretValue(parts[0], "")
+	}
+	return interpolator.aws.GetSecretValue(parts[0], parts[1])
+}
+
+func setNestedValue(settings map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := settings[name].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			settings[name] = next
+		}
+		settings = next
+	}
+	settings[path[len(path)-1]] = value
+}
@@ -0,0 +1,75 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureInterpolationConfig(t *testing.T, contents string) *viper.Viper {
+	config := viper.New()
+	config.SetConfigType("toml")
+	assert.NoError(t, config.ReadConfig(strings.NewReader(contents)), "Expected config to be read")
+	return config
+}
+
+func TestInterpolateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	defer os.RemoveAll(dir)
+	passwordFile := filepath.Join(dir, "password")
+	assert.NoError(t, ioutil.WriteFile(passwordFile, []byte("filepassword\n"), 0600), "Expected password file to be written")
+
+	os.Setenv("BURROW_TEST_USERNAME", "envuser")
+	os.Setenv("BURROW_TEST_HOST", "broker1")
+	defer os.Unsetenv("BURROW_TEST_USERNAME")
+	defer os.Unsetenv("BURROW_TEST_HOST")
+
+	config := fixtureInterpolationConfig(t, `
+[sasl.test]
+username="${BURROW_TEST_USERNAME}"
+password="file:`+passwordFile+`"
+[cluster.test]
+servers=["${BURROW_TEST_HOST}:9092", "broker2:9092"]
+topic-denylist="^test$"
+offset-refresh=10
+`)
+	assert.NoError(t, InterpolateConfig(config), "Expected InterpolateConfig to return no error")
+	assert.Equal(t, "envuser", config.GetString("sasl.test.username"), "Expected username from the environment")
+	assert.Equal(t, "filepassword", config.GetString("sasl.test.password"), "Expected password from the file")
+	assert.Equal(t, []string{"broker1:9092", "broker2:9092"}, config.GetStringSlice("cluster.test.servers"), "Expected servers from the environment")
+	assert.Equal(t, "^test$", config.GetString("cluster.test.topic-denylist"), "Expected topic-denylist to be unchanged")
+	assert.Equal(t, 10, config.GetInt("cluster.test.offset-refresh"), "Expected offset-refresh to be unchanged")
+}
+
+func TestInterpolateConfig_Errors(t *testing.T) {
+	os.Unsetenv("BURROW_TEST_UNSET")
+
+	config := fixtureInterpolationConfig(t, `
+[sasl.test]
+password="${BURROW_TEST_UNSET}"
+`)
+	assert.Error(t, InterpolateConfig(config), "Expected an error for an unset environment variable")
+	assert.Equal(t, "${BURROW_TEST_UNSET}", config.GetString("sasl.test.password"), "Expected password to be unchanged")
+
+	config = fixtureInterpolationConfig(t, `
+[sasl.test]
+password="file:/no/such/file"
+`)
+	assert.Error(t, InterpolateConfig(config), "Expected an error for a missing file")
+}
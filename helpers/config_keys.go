@@ -0,0 +1,114 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+// ConsumerFilterConfigKeys are the keys read by NewConsumerFilter, for modules that use it to register in their own
+// section of the configuration schema
+var ConsumerFilterConfigKeys = []ConfigKey{
+	{Name: "group-allowlist", Type: ConfigTypeString},
+	{Name: "group-denylist", Type: ConfigTypeString},
+	{Name: "topic-allowlist", Type: ConfigTypeString},
+	{Name: "topic-denylist", Type: ConfigTypeString},
+}
+
+// RemovedGroupFilterConfigKeys are the old names of the group filter keys, which modules refuse to start with
+var RemovedGroupFilterConfigKeys = []ConfigKey{
+	{Name: "group-whitelist", Type: ConfigTypeString, Removed: true, ReplacedBy: "group-allowlist"},
+	{Name: "group-blacklist", Type: ConfigTypeString, Removed: true, ReplacedBy: "group-denylist"},
+}
+
+// DeadLetterConfigKeys are the keys read by NewDeadLetterWriter
+var DeadLetterConfigKeys = []ConfigKey{
+	{Name: "dead-letter-file", Type: ConfigTypeString},
+	{Name: "dead-letter-max-backups", Type: ConfigTypeInteger, Default: 5},
+	{Name: "dead-letter-max-size", Type: ConfigTypeInteger, Default: 100},
+}
+
+func init() {
+	RegisterConfigKeys("aws", "",
+		ConfigKey{Name: "region", Type: ConfigTypeString},
+		ConfigKey{Name: "cache-time", Type: ConfigTypeInteger},
+		ConfigKey{Name: "role-session-name", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("vault", "",
+		ConfigKey{Name: "address", Type: ConfigTypeString},
+		ConfigKey{Name: "token", Type: ConfigTypeString},
+		ConfigKey{Name: "namespace", Type: ConfigTypeString},
+		ConfigKey{Name: "timeout", Type: ConfigTypeInteger},
+		ConfigKey{Name: "tls", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("client-profile.*", "",
+		ConfigKey{Name: "client-id", Type: ConfigTypeString, Default: "burrow-lagchecker"},
+		ConfigKey{Name: "kafka-version", Type: ConfigTypeString},
+		ConfigKey{Name: "tls", Type: ConfigTypeString},
+		ConfigKey{Name: "sasl", Type: ConfigTypeString},
+		ConfigKey{Name: "preset", Type: ConfigTypeString},
+		ConfigKey{Name: "api-key", Type: ConfigTypeString},
+		ConfigKey{Name: "api-secret", Type: ConfigTypeString},
+		ConfigKey{Name: "connection-string", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("tls.*", "",
+		ConfigKey{Name: "server-name", Type: ConfigTypeString},
+		ConfigKey{Name: "noverify", Type: ConfigTypeBoolean},
+		ConfigKey{Name: "ca", Type: ConfigTypeString},
+		ConfigKey{Name: "cafile", Type: ConfigTypeString},
+		ConfigKey{Name: "cert", Type: ConfigTypeString},
+		ConfigKey{Name: "certfile", Type: ConfigTypeString},
+		ConfigKey{Name: "key", Type: ConfigTypeString},
+		ConfigKey{Name: "keyfile", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("sasl.*", "",
+		ConfigKey{Name: "mechanism", Type: ConfigTypeString, Default: "PLAIN"},
+		ConfigKey{Name: "handshake-first", Type: ConfigTypeBoolean, Default: true},
+		ConfigKey{Name: "username", Type: ConfigTypeString},
+		ConfigKey{Name: "password", Type: ConfigTypeString},
+		ConfigKey{Name: "service-name", Type: ConfigTypeString, Default: "kafka"},
+		ConfigKey{Name: "krb5-config", Type: ConfigTypeString, Default: "/etc/krb5.conf"},
+		ConfigKey{Name: "principal", Type: ConfigTypeString},
+		ConfigKey{Name: "keytab", Type: ConfigTypeString},
+		ConfigKey{Name: "disable-pafx-fast", Type: ConfigTypeBoolean},
+		ConfigKey{Name: "aws-region", Type: ConfigTypeString},
+		ConfigKey{Name: "aws-role-session-name", Type: ConfigTypeString, Default: "burrow"},
+		ConfigKey{Name: "token-provider", Type: ConfigTypeString, Default: "client-credentials"},
+		ConfigKey{Name: "token-timeout", Type: ConfigTypeInteger, Default: 10},
+		ConfigKey{Name: "token-lifetime", Type: ConfigTypeInteger, Default: 3600},
+		ConfigKey{Name: "token-url", Type: ConfigTypeString},
+		ConfigKey{Name: "client-id", Type: ConfigTypeString},
+		ConfigKey{Name: "client-secret", Type: ConfigTypeString},
+		ConfigKey{Name: "scopes", Type: ConfigTypeStringList},
+		ConfigKey{Name: "extensions", Type: ConfigTypeMap},
+		ConfigKey{Name: "token-file", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("schema-registry.*", "",
+		ConfigKey{Name: "url", Type: ConfigTypeString},
+		ConfigKey{Name: "username", Type: ConfigTypeString},
+		ConfigKey{Name: "password", Type: ConfigTypeString},
+		ConfigKey{Name: "tls", Type: ConfigTypeString},
+		ConfigKey{Name: "timeout", Type: ConfigTypeInteger, Default: 10},
+	)
+	RegisterConfigKeys("otlp", "",
+		ConfigKey{Name: "endpoint", Type: ConfigTypeString},
+		ConfigKey{Name: "protocol", Type: ConfigTypeString, Default: "http/json"},
+		ConfigKey{Name: "headers", Type: ConfigTypeMap},
+		ConfigKey{Name: "tls", Type: ConfigTypeString},
+		ConfigKey{Name: "timeout", Type: ConfigTypeInteger, Default: 10},
+		ConfigKey{Name: "service-name", Type: ConfigTypeString, Default: "burrow"},
+		ConfigKey{Name: "resource-attributes", Type: ConfigTypeMap},
+	)
+	RegisterConfigKeys("tracing", "",
+		ConfigKey{Name: "enabled", Type: ConfigTypeBoolean},
+		ConfigKey{Name: "sample-rate", Type: ConfigTypeFloat, Default: 1.0},
+		ConfigKey{Name: "ingest-sample-rate", Type: ConfigTypeFloat, Default: 0.0},
+		ConfigKey{Name: "batch-size", Type: ConfigTypeInteger, Default: 512},
+		ConfigKey{Name: "queue-size", Type: ConfigTypeInteger, Default: 2048},
+		ConfigKey{Name: "export-interval", Type: ConfigTypeInteger, Default: 5},
+	)
+}
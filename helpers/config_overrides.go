@@ -0,0 +1,82 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigOverrides is a flag.Value that collects key=value settings (such as from repeated --set flags on the command
+// line), to be applied over the configuration file with Apply.
+type ConfigOverrides []string
+
+// String returns the settings, separated by commas
+func (overrides *ConfigOverrides) String() string {
+	return strings.Join(*overrides, ",")
+}
+
+// Set adds a key=value setting. An error is returned if there is no key.
+func (overrides *ConfigOverrides) Set(setting string) error {
+	if parts := strings.SplitN(setting, "=", 2); (len(parts) != 2) || (strings.TrimSpace(parts[0]) == "") {
+		return errors.New("settings must be of the form key=value")
+	}
+	*overrides = append(*overrides, setting)
+	return nil
+}
+
+// The settings that were applied to viper, which are applied again to the configuration when it is reloaded
+var appliedOverrides ConfigOverrides
+
+// Apply sets each of the settings in viper, overriding the configuration file (including when it is reloaded) and
+// environment variables. The key uses the same dotted form as the configuration, such as cluster.local.offset-refresh.
+// The value is parsed as a TOML value if it can be, so numbers, booleans, and lists (such as ["host1:9092",
+// "host2:9092"]) have the right type. Otherwise, the value is used as a string.
+func (overrides *ConfigOverrides) Apply() {
+	overrides.applyTo(viper.GetViper())
+	appliedOverrides = append(ConfigOverrides(nil), *overrides...)
+}
+
+// ApplyConfigOverrides sets the settings that were applied to viper with Apply in the given configuration, such as one
+// that has been read from the configuration file again to reload it
+func ApplyConfigOverrides(config *viper.Viper) {
+	appliedOverrides.applyTo(config)
+}
+
+func (overrides ConfigOverrides) applyTo(config *viper.Viper) {
+	for _, setting := range overrides {
+		parts := strings.SplitN(setting, "=", 2)
+		config.Set(strings.TrimSpace(parts[0]), parseOverrideValue(parts[1]))
+	}
+}
+
+// SetConfigEnv sets up the configuration to read environment variables with the prefix. The variable for a key is the
+// prefix and the key in upper case, with dots and dashes replaced by underscores (BURROW_CLUSTER_LOCAL_OFFSET_REFRESH
+// for cluster.local.offset-refresh).
+func SetConfigEnv(config *viper.Viper, prefix string) {
+	config.SetEnvPrefix(prefix)
+	config.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	config.AutomaticEnv()
+}
+
+func parseOverrideValue(value string) interface{} {
+	parsed := viper.New()
+	parsed.SetConfigType("toml")
+	if err := parsed.ReadConfig(strings.NewReader("value = " + value)); err != nil {
+		return value
+	}
+	if parsedValue := parsed.Get("value"); parsedValue != nil {
+		return parsedValue
+	}
+	return value
+}
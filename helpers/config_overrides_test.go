@@ -0,0 +1,51 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigOverrides(t *testing.T) {
+	viper.Reset()
+	viper.Set("cluster.local.offset-refresh", 10)
+
+	overrides := &ConfigOverrides{}
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(overrides, "set", "")
+	err := flags.Parse([]string{
+		"--set", "cluster.local.offset-refresh=30",
+		"--set", `cluster.local.servers=["host1:9092", "host2:9092"]`,
+		"--set", "logging.level=debug",
+		"--set", "storage.default.min-distance=",
+		"--set", "consumer.local.start-latest=true",
+		"--set", "httpserver.default.address=:8000",
+	})
+	assert.NoError(t, err, "Expected flags to parse")
+	overrides.Apply()
+
+	assert.Equal(t, 30, viper.GetInt("cluster.local.offset-refresh"), "Expected offset-refresh to be overridden")
+	assert.Equal(t, []string{"host1:9092", "host2:9092"}, viper.GetStringSlice("cluster.local.servers"), "Expected servers to be a list")
+	assert.Equal(t, "debug", viper.GetString("logging.level"), "Expected logging.level to be a string")
+	assert.Equal(t, "", viper.GetString("storage.default.min-distance"), "Expected an empty string")
+	assert.True(t, viper.GetBool("consumer.local.start-latest"), "Expected start-latest to be a boolean")
+	assert.Equal(t, ":8000", viper.GetString("httpserver.default.address"), "Expected address to be a string")
+}
+
+func TestConfigOverrides_BadSetting(t *testing.T) {
+	overrides := &ConfigOverrides{}
+	assert.Error(t, overrides.Set("nokey"), "Expected an error for a setting without a value")
+	assert.Error(t, overrides.Set("=value"), "Expected an error for a setting without a key")
+}
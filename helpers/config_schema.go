@@ -0,0 +1,322 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// The types of value that a ConfigKey can have
+const (
+	ConfigTypeString     = "string"
+	ConfigTypeInteger    = "integer"
+	ConfigTypeFloat      = "float"
+	ConfigTypeBoolean    = "boolean"
+	ConfigTypeStringList = "string-list"
+
+	// A map can have any keys under it, such as the labels of a cluster
+	ConfigTypeMap = "map"
+)
+
+// ConfigKey describes a single key in the configuration schema
+type ConfigKey struct {
+	// The name of the key within its section, such as "servers" for cluster.<name>.servers
+	Name string `json:"name"`
+
+	// The type of the value, which is one of the ConfigType constants
+	Type string `json:"type"`
+
+	// The default value, if the key has one that does not depend on other keys
+	Default interface{} `json:"default,omitempty"`
+
+	// A deprecated key still works, but ReplacedBy should be used instead. If the replacement is not set, the value of
+	// the deprecated key is copied to it by ApplyConfigDeprecations.
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// A removed key is no longer used, and a module that it was set for will fail to start
+	Removed bool `json:"removed,omitempty"`
+
+	// For a deprecated or removed key, the key in the same section that replaces it
+	ReplacedBy string `json:"replaced-by,omitempty"`
+}
+
+// ConfigSection is a group of keys in the configuration schema. The section name may have a "*" in place of the name
+// of a module or profile, such as "cluster.*" for the keys of every cluster module.
+type ConfigSection struct {
+	Section string `json:"section"`
+
+	// For a section of modules, the class-name of the modules that the keys are used by. If this is empty, the keys are
+	// used by modules of every class.
+	Class string `json:"class,omitempty"`
+
+	Keys []ConfigKey `json:"keys"`
+}
+
+// ConfigKeyWarning is a problem with a key that is set in the configuration, found by CheckConfigKeys
+type ConfigKeyWarning struct {
+	Key     string `json:"key"`
+	Warning string `json:"warning"`
+}
+
+// configSchema holds the keys that have been registered, keyed by the section and then by the class
+var configSchema = struct {
+	lock     sync.RWMutex
+	sections map[string]map[string]map[string]ConfigKey
+}{
+	sections: make(map[string]map[string]map[string]ConfigKey),
+}
+
+// RegisterConfigKeys adds keys to the configuration schema, for the section and class given (see ConfigSection). This
+// is called when a package is loaded, by the package that uses the keys, so that the schema only has the modules that
+// are built in to Burrow. A key that was registered before is replaced. A key named "*" allows keys that are not in the
+// schema to be set, such as for a module that passes its configuration on to something else.
+func RegisterConfigKeys(section, class string, keys ...ConfigKey) {
+	configSchema.lock.Lock()
+	defer configSchema.lock.Unlock()
+
+	if _, ok := configSchema.sections[section]; !ok {
+		configSchema.sections[section] = make(map[string]map[string]ConfigKey)
+	}
+	if _, ok := configSchema.sections[section][class]; !ok {
+		configSchema.sections[section][class] = make(map[string]ConfigKey)
+	}
+	for _, key := range keys {
+		configSchema.sections[section][class][key.Name] = key
+	}
+}
+
+// GetConfigSchema returns every section of the configuration schema, sorted by section and class, with the keys in each
+// sorted by name
+func GetConfigSchema() []ConfigSection {
+	configSchema.lock.RLock()
+	defer configSchema.lock.RUnlock()
+
+	schema := make([]ConfigSection, 0)
+	for section, classes := range configSchema.sections {
+		for class, keys := range classes {
+			configSection := ConfigSection{Section: section, Class: class, Keys: make([]ConfigKey, 0, len(keys))}
+			for _, key := range keys {
+				configSection.Keys = append(configSection.Keys, key)
+			}
+			sort.Slice(configSection.Keys, func(i, j int) bool {
+				return configSection.Keys[i].Name < configSection.Keys[j].Name
+			})
+			schema = append(schema, configSection)
+		}
+	}
+	sort.Slice(schema, func(i, j int) bool {
+		if schema[i].Section != schema[j].Section {
+			return schema[i].Section < schema[j].Section
+		}
+		return schema[i].Class < schema[j].Class
+	})
+	return schema
+}
+
+// configKeyMatch is a key in the configuration, matched to the section of the schema that it is in
+type configKeyMatch struct {
+	// The part of the key before the name in the section, such as "cluster.local"
+	prefix string
+
+	// The name of the key within the section, and the ConfigKey for it (if it is known)
+	name  string
+	key   ConfigKey
+	found bool
+
+	// The names of all of the keys that can be set in the section, or nil if any key can be set
+	names []string
+}
+
+// matchConfigKey finds the section of the schema that a key in the configuration is in. If the key is not in any
+// section, false is returned. The lock must be held when this is called.
+func matchConfigKey(config *viper.Viper, fullKey string) (*configKeyMatch, bool) {
+	parts := strings.Split(fullKey, ".")
+	for section, classes := range configSchema.sections {
+		sectionParts := strings.Split(section, ".")
+		if len(parts) <= len(sectionParts) {
+			continue
+		}
+		matched := true
+		for i, sectionPart := range sectionParts {
+			if (sectionPart != "*") && (sectionPart != parts[i]) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		match := &configKeyMatch{
+			prefix: strings.Join(parts[:len(sectionParts)], "."),
+			name:   strings.Join(parts[len(sectionParts):], "."),
+		}
+
+		// The keys for all classes can always be used. If the section has keys for particular classes, but not for
+		// the class of this module (such as a plugin), the module's keys are not known, and so any key is allowed
+		candidates := make([]map[string]ConfigKey, 0, 2)
+		if keys, ok := classes[""]; ok {
+			candidates = append(candidates, keys)
+		}
+		knownClass := len(classes) == len(candidates)
+		if keys, ok := classes[config.GetString(match.prefix+".class-name")]; ok {
+			candidates = append(candidates, keys)
+			knownClass = true
+		}
+		if knownClass {
+			match.names = make([]string, 0)
+		}
+
+		wildcard := false
+		for _, keys := range candidates {
+			for name, key := range keys {
+				if name == "*" {
+					wildcard = true
+					continue
+				}
+				if match.names != nil {
+					match.names = append(match.names, name)
+				}
+				if (name == match.name) || ((key.Type == ConfigTypeMap) && strings.HasPrefix(match.name, name+".")) {
+					match.key = key
+					match.found = true
+				}
+			}
+		}
+		if wildcard && !match.found {
+			match.names = nil
+		}
+		return match, true
+	}
+	return nil, false
+}
+
+// CheckConfigKeys returns a warning for each key that is set in the configuration that is deprecated, has been removed,
+// or is not in the schema at all (such as a misspelled key). The warning for an unknown key suggests the key that was
+// most likely meant, if there is one that is close.
+func CheckConfigKeys(config *viper.Viper) []ConfigKeyWarning {
+	configSchema.lock.RLock()
+	defer configSchema.lock.RUnlock()
+
+	roots := make([]string, 0, len(configSchema.sections))
+	for section := range configSchema.sections {
+		roots = append(roots, strings.SplitN(section, ".", 2)[0])
+	}
+
+	keys := config.AllKeys()
+	sort.Strings(keys)
+	warnings := make([]ConfigKeyWarning, 0)
+	for _, fullKey := range keys {
+		match, ok := matchConfigKey(config, fullKey)
+		switch {
+		case !ok:
+			root := strings.SplitN(fullKey, ".", 2)[0]
+			warning := "unknown configuration section " + root
+			if suggestion := closestName(root, roots); suggestion != "" {
+				warning += " (did you mean " + suggestion + "?)"
+			}
+			warnings = append(warnings, ConfigKeyWarning{Key: fullKey, Warning: warning})
+		case match.found && match.key.Removed:
+			warning := "configuration key " + fullKey + " is no longer supported"
+			if match.key.ReplacedBy != "" {
+				warning += ", use " + match.prefix + "." + match.key.ReplacedBy + " instead"
+			}
+			warnings = append(warnings, ConfigKeyWarning{Key: fullKey, Warning: warning})
+		case match.found && match.key.Deprecated:
+			warning := "configuration key " + fullKey + " is deprecated"
+			if match.key.ReplacedBy != "" {
+				warning += ", use " + match.prefix + "." + match.key.ReplacedBy + " instead"
+			}
+			warnings = append(warnings, ConfigKeyWarning{Key: fullKey, Warning: warning})
+		case !match.found && (match.names != nil):
+			warning := "unknown configuration key " + fullKey
+			if suggestion := closestName(match.name, match.names); suggestion != "" {
+				warning += " (did you mean " + match.prefix + "." + suggestion + "?)"
+			}
+			warnings = append(warnings, ConfigKeyWarning{Key: fullKey, Warning: warning})
+		}
+	}
+	return warnings
+}
+
+// ApplyConfigDeprecations copies the value of each deprecated key that is set in the configuration to the key that
+// replaces it, unless that is also set, so that modules only need to read the new key. This must be called each time
+// the configuration is loaded, after InterpolateConfig.
+func ApplyConfigDeprecations(config *viper.Viper) {
+	configSchema.lock.RLock()
+	defer configSchema.lock.RUnlock()
+
+	for _, fullKey := range config.AllKeys() {
+		match, ok := matchConfigKey(config, fullKey)
+		if !ok || !match.found || !match.key.Deprecated || (match.key.ReplacedBy == "") {
+			continue
+		}
+		replacement := match.prefix + "." + match.key.ReplacedBy
+		if !config.IsSet(replacement) {
+			config.Set(replacement, config.Get(fullKey))
+		}
+	}
+}
+
+// closestName returns the name that is most similar to the given one, if it is close enough that it was probably
+// meant (such as a misspelling), or an empty string if there is none
+func closestName(name string, names []string) string {
+	closest := ""
+	closestDistance := 0
+	for _, candidate := range names {
+		distance := editDistance(name, candidate)
+		if (closest == "") || (distance < closestDistance) || ((distance == closestDistance) && (candidate < closest)) {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+	if (closest == "") || (closestDistance == 0) || ((closestDistance > 2) && (closestDistance*3 > len(name))) {
+		return ""
+	}
+	return closest
+}
+
+// editDistance returns the number of edits needed to change one string into the other, where an edit is inserting,
+// removing, or changing a character, or swapping two adjacent characters (the optimal string alignment distance)
+func editDistance(a, b string) int {
+	distance := make([][]int, len(a)+1)
+	for i := range distance {
+		distance[i] = make([]int, len(b)+1)
+		distance[i][0] = i
+	}
+	for j := range distance[0] {
+		distance[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			distance[i][j] = minInt(minInt(distance[i-1][j]+1, distance[i][j-1]+1), distance[i-1][j-1]+cost)
+			if (i > 1) && (j > 1) && (a[i-1] == b[j-2]) && (a[i-2] == b[j-1]) {
+				distance[i][j] = minInt(distance[i][j], distance[i-2][j-2]+1)
+			}
+		}
+	}
+	return distance[len(a)][len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	RegisterConfigKeys("testmodule.*", "",
+		ConfigKey{Name: "class-name", Type: ConfigTypeString},
+		ConfigKey{Name: "labels", Type: ConfigTypeMap},
+	)
+	RegisterConfigKeys("testmodule.*", "testclass",
+		ConfigKey{Name: "lagcheck", Type: ConfigTypeInteger, Default: 10},
+		ConfigKey{Name: "interval", Type: ConfigTypeInteger, Deprecated: true, ReplacedBy: "refresh"},
+		ConfigKey{Name: "refresh", Type: ConfigTypeInteger},
+		ConfigKey{Name: "whitelist", Type: ConfigTypeString, Removed: true, ReplacedBy: "allowlist"},
+		ConfigKey{Name: "allowlist", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("testmodule.*", "otherclass",
+		ConfigKey{Name: "other", Type: ConfigTypeString},
+	)
+	RegisterConfigKeys("testmodule.*", "passthrough",
+		ConfigKey{Name: "path", Type: ConfigTypeString},
+		ConfigKey{Name: "*"},
+	)
+}
+
+func findConfigKeyWarning(warnings []ConfigKeyWarning, key string) string {
+	for _, warning := range warnings {
+		if warning.Key == key {
+			return warning.Warning
+		}
+	}
+	return ""
+}
+
+func TestGetConfigSchema(t *testing.T) {
+	schema := GetConfigSchema()
+	for i := 1; i < len(schema); i++ {
+		assert.True(t, (schema[i-1].Section < schema[i].Section) ||
+			((schema[i-1].Section == schema[i].Section) && (schema[i-1].Class < schema[i].Class)), "Expected sections to be sorted")
+	}
+
+	for _, section := range schema {
+		if (section.Section == "testmodule.*") && (section.Class == "testclass") {
+			assert.Len(t, section.Keys, 5, "Expected all keys to be registered")
+			assert.Equal(t, "allowlist", section.Keys[0].Name, "Expected keys to be sorted")
+			return
+		}
+	}
+	t.Error("Expected testmodule.* section for testclass")
+}
+
+func TestCheckConfigKeys(t *testing.T) {
+	config := viper.New()
+	config.Set("testmodule.one.class-name", "testclass")
+	config.Set("testmodule.one.lagcheck", 20)
+	config.Set("testmodule.one.laggcheck", 20)
+	config.Set("testmodule.one.labels.team", "lag")
+	config.Set("testmodule.one.other", "value")
+	config.Set("testmodule.one.interval", 5)
+	config.Set("testmodule.one.whitelist", "foo.*")
+	config.Set("testmodule.two.class-name", "customclass")
+	config.Set("testmodule.two.anything", "value")
+	config.Set("testmodule.three.class-name", "passthrough")
+	config.Set("testmodule.three.anything", "value")
+	config.Set("tls.default.cafile", "/etc/ca.pem")
+	config.Set("tsl.default.cafile", "/etc/ca.pem")
+	config.Set("unrelated.key", "value")
+
+	warnings := CheckConfigKeys(config)
+	assert.Len(t, warnings, 6, "Expected six warnings")
+	assert.Equal(t, "unknown configuration key testmodule.one.laggcheck (did you mean testmodule.one.lagcheck?)",
+		findConfigKeyWarning(warnings, "testmodule.one.laggcheck"), "Expected a suggestion for a typo")
+	assert.Equal(t, "unknown configuration key testmodule.one.other",
+		findConfigKeyWarning(warnings, "testmodule.one.other"), "Expected a key for another class to be unknown")
+	assert.Equal(t, "configuration key testmodule.one.interval is deprecated, use testmodule.one.refresh instead",
+		findConfigKeyWarning(warnings, "testmodule.one.interval"), "Expected a deprecation warning")
+	assert.Equal(t, "configuration key testmodule.one.whitelist is no longer supported, use testmodule.one.allowlist instead",
+		findConfigKeyWarning(warnings, "testmodule.one.whitelist"), "Expected a removal warning")
+	assert.Equal(t, "unknown configuration section tsl (did you mean tls?)",
+		findConfigKeyWarning(warnings, "tsl.default.cafile"), "Expected a suggestion for a section")
+	assert.Equal(t, "unknown configuration section unrelated",
+		findConfigKeyWarning(warnings, "unrelated.key"), "Expected no suggestion for an unrelated section")
+}
+
+func TestApplyConfigDeprecations(t *testing.T) {
+	config := viper.New()
+	config.Set("testmodule.one.class-name", "testclass")
+	config.Set("testmodule.one.interval", 5)
+	config.Set("testmodule.two.class-name", "testclass")
+	config.Set("testmodule.two.interval", 5)
+	config.Set("testmodule.two.refresh", 30)
+	config.Set("testmodule.three.class-name", "otherclass")
+	config.Set("testmodule.three.interval", 5)
+
+	ApplyConfigDeprecations(config)
+	assert.Equal(t, 5, config.GetInt("testmodule.one.refresh"), "Expected the deprecated key to be copied")
+	assert.Equal(t, 30, config.GetInt("testmodule.two.refresh"), "Expected the replacement to not be overwritten")
+	assert.False(t, config.IsSet("testmodule.three.refresh"), "Expected no copy for another class")
+}
+
+func TestClosestName(t *testing.T) {
+	names := []string{"offset-refresh", "topic-refresh", "servers"}
+	assert.Equal(t, "servers", closestName("server", names), "Expected a missing letter to match")
+	assert.Equal(t, "topic-refresh", closestName("topic-refersh", names), "Expected swapped letters to match")
+	assert.Equal(t, "", closestName("client-profile", names), "Expected no match for an unrelated name")
+	assert.Equal(t, "", closestName("servers", names), "Expected no suggestion for an exact match")
+}
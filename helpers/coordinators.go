@@ -16,6 +16,7 @@ package helpers
 
 import (
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -24,6 +25,31 @@ import (
 	"github.com/linkedin/Burrow/protocol"
 )
 
+// ModuleFactory creates a module of a class that is not built in to Burrow. The logger has already been set up with
+// fields that identify the module. The module returned must satisfy the Module interface of the coordinator that it was
+// registered for (for example, storage.Module for the storage coordinator).
+type ModuleFactory func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module
+
+// moduleClasses holds the ModuleFactory for each class that has been registered, keyed by the coordinator name and the
+// class name, separated by a slash
+var moduleClasses sync.Map
+
+// RegisterModuleClass registers a class of module for a coordinator (such as "cluster" or "consumer"), so that modules
+// can be configured with that class-name. This is used when Burrow is embedded in another application, to add modules
+// that are not built in. A factory that was registered for the class before is replaced. Built in classes cannot be
+// replaced, as the coordinators check for them first.
+func RegisterModuleClass(coordinator, className string, factory ModuleFactory) {
+	moduleClasses.Store(coordinator+"/"+className, factory)
+}
+
+// GetModuleClass returns the ModuleFactory that was registered for the coordinator and class, or nil if there is none
+func GetModuleClass(coordinator, className string) ModuleFactory {
+	if factory, ok := moduleClasses.Load(coordinator + "/" + className); ok {
+		return factory.(ModuleFactory)
+	}
+	return nil
+}
+
 // StartCoordinatorModules is a helper func for coordinators to start a list of modules. Given a map of protocol.Module,
 // it calls the Start func on each one. If any module returns an error, it immediately stops and returns that error
 func StartCoordinatorModules(modules map[string]protocol.Module) error {
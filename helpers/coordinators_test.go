@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 
 	"github.com/linkedin/Burrow/protocol"
 )
@@ -67,3 +68,16 @@ func TestStopCoordinatorModules(t *testing.T) {
 	mock1.AssertExpectations(t)
 	mock2.AssertExpectations(t)
 }
+
+func TestRegisterModuleClass(t *testing.T) {
+	assert.Nil(t, GetModuleClass("cluster", "testclass"), "Expected no factory before registering")
+
+	module := &MockModule{}
+	RegisterModuleClass("cluster", "testclass", func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+		return module
+	})
+	factory := GetModuleClass("cluster", "testclass")
+	assert.NotNil(t, factory, "Expected factory to be registered")
+	assert.Equal(t, module, factory(nil, nil), "Expected factory to return the module")
+	assert.Nil(t, GetModuleClass("consumer", "testclass"), "Expected factory to only be registered for cluster")
+}
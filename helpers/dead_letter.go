@@ -0,0 +1,154 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// deadLetterQueues holds every DeadLetterQueue that has been created, keyed by the config root of the module that it
+// was created for.
+var deadLetterQueues sync.Map
+
+// DeadLetter is a message that a consumer module read but could not decode. The key and value are the raw bytes of the
+// message (encoded as base64 in JSON). If the key or value version could not be read, it is -1.
+type DeadLetter struct {
+	Topic        string `json:"topic"`
+	Partition    int32  `json:"partition"`
+	Offset       int64  `json:"offset"`
+	Timestamp    int64  `json:"timestamp"`
+	KeyVersion   int16  `json:"key_version"`
+	ValueVersion int16  `json:"value_version"`
+	Reason       string `json:"reason"`
+	Key          []byte `json:"key"`
+	Value        []byte `json:"value"`
+}
+
+// DeadLetterCount is the number of messages that a module could not decode for one combination of key and value
+// versions.
+type DeadLetterCount struct {
+	Module       string `json:"module"`
+	Cluster      string `json:"cluster"`
+	KeyVersion   int16  `json:"key_version"`
+	ValueVersion int16  `json:"value_version"`
+	Count        uint64 `json:"count"`
+}
+
+type deadLetterVersions struct {
+	keyVersion   int16
+	valueVersion int16
+}
+
+// DeadLetterQueue counts the messages that a module could not decode, by key and value version, and optionally writes
+// each of them to a file so they can be examined later. It is safe to use a DeadLetterQueue from multiple goroutines.
+type DeadLetterQueue struct {
+	cluster string
+	lock    sync.Mutex
+	counts  map[deadLetterVersions]uint64
+	sink    io.WriteCloser
+}
+
+// NewDeadLetterQueue creates a DeadLetterQueue for a module, and registers it under the module's config root so that
+// its counts are returned from GetDeadLetterCounts. If the dead-letter-file config is set, each message is written to
+// that file as a line of JSON. The file is rotated when it reaches dead-letter-max-size megabytes (default 100), and
+// dead-letter-max-backups old files are kept (default 5). If the configs are not valid, an error is returned.
+func NewDeadLetterQueue(configRoot, cluster string) (*DeadLetterQueue, error) {
+	viper.SetDefault(configRoot+".dead-letter-max-size", 100)
+	viper.SetDefault(configRoot+".dead-letter-max-backups", 5)
+	maxSize := viper.GetInt(configRoot + ".dead-letter-max-size")
+	maxBackups := viper.GetInt(configRoot + ".dead-letter-max-backups")
+	if (maxSize < 1) || (maxBackups < 0) {
+		return nil, errors.New("invalid dead-letter-max-size or dead-letter-max-backups")
+	}
+
+	queue := &DeadLetterQueue{
+		cluster: cluster,
+		counts:  make(map[deadLetterVersions]uint64),
+	}
+	if filename := viper.GetString(configRoot + ".dead-letter-file"); filename != "" {
+		queue.sink = &lumberjack.Logger{
+			Filename:   filename,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+		}
+	}
+	deadLetterQueues.Store(configRoot, queue)
+	return queue, nil
+}
+
+// Add counts the message, and writes it to the dead letter file if there is one. The count is updated even if the
+// message cannot be written, in which case the error is returned.
+func (queue *DeadLetterQueue) Add(letter *DeadLetter) error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	queue.counts[deadLetterVersions{letter.KeyVersion, letter.ValueVersion}]++
+	if queue.sink == nil {
+		return nil
+	}
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	_, err = queue.sink.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the dead letter file, if there is one. The counts are still returned from GetDeadLetterCounts.
+func (queue *DeadLetterQueue) Close() error {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	if queue.sink == nil {
+		return nil
+	}
+	err := queue.sink.Close()
+	queue.sink = nil
+	return err
+}
+
+// GetDeadLetterCounts returns the number of messages that each module could not decode, for each combination of key
+// and value versions. The list is sorted by module config root, then by key version and value version.
+func GetDeadLetterCounts() []*DeadLetterCount {
+	result := make([]*DeadLetterCount, 0)
+	deadLetterQueues.Range(func(key, value interface{}) bool {
+		queue := value.(*DeadLetterQueue)
+		queue.lock.Lock()
+		for versions, count := range queue.counts {
+			result = append(result, &DeadLetterCount{
+				Module:       key.(string),
+				Cluster:      queue.cluster,
+				KeyVersion:   versions.keyVersion,
+				ValueVersion: versions.valueVersion,
+				Count:        count,
+			})
+		}
+		queue.lock.Unlock()
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Module != result[j].Module {
+			return result[i].Module < result[j].Module
+		}
+		if result[i].KeyVersion != result[j].KeyVersion {
+			return result[i].KeyVersion < result[j].KeyVersion
+		}
+		return result[i].ValueVersion < result[j].ValueVersion
+	})
+	return result
+}
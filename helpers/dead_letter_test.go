@@ -0,0 +1,86 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueue_Counts(t *testing.T) {
+	viper.Reset()
+	queue, err := NewDeadLetterQueue("consumer.deadlettertest", "testcluster")
+	assert.NoError(t, err, "Expected NewDeadLetterQueue to return no error")
+
+	assert.NoError(t, queue.Add(&DeadLetter{KeyVersion: 1, ValueVersion: 5}))
+	assert.NoError(t, queue.Add(&DeadLetter{KeyVersion: 1, ValueVersion: 5}))
+	assert.NoError(t, queue.Add(&DeadLetter{KeyVersion: 0, ValueVersion: -1}))
+
+	counts := make([]*DeadLetterCount, 0)
+	for _, count := range GetDeadLetterCounts() {
+		if count.Module == "consumer.deadlettertest" {
+			counts = append(counts, count)
+		}
+	}
+	assert.Lenf(t, counts, 2, "Expected 2 counts, not %v", len(counts))
+	assert.Equal(t, &DeadLetterCount{Module: "consumer.deadlettertest", Cluster: "testcluster", KeyVersion: 0, ValueVersion: -1, Count: 1}, counts[0])
+	assert.Equal(t, &DeadLetterCount{Module: "consumer.deadlettertest", Cluster: "testcluster", KeyVersion: 1, ValueVersion: 5, Count: 2}, counts[1])
+}
+
+func TestDeadLetterQueue_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow-dead-letter")
+	assert.NoError(t, err, "Expected temp dir setup to return no error")
+	defer os.RemoveAll(dir)
+
+	viper.Reset()
+	filename := filepath.Join(dir, "dead-letters.json")
+	viper.Set("consumer.deadletterfiletest.dead-letter-file", filename)
+	queue, err := NewDeadLetterQueue("consumer.deadletterfiletest", "testcluster")
+	assert.NoError(t, err, "Expected NewDeadLetterQueue to return no error")
+
+	letter := &DeadLetter{
+		Topic:        "__consumer_offsets",
+		Partition:    3,
+		Offset:       1234,
+		KeyVersion:   1,
+		ValueVersion: 9,
+		Reason:       "value version",
+		Key:          []byte("\x00\x01key"),
+		Value:        []byte("\x00\x09value"),
+	}
+	assert.NoError(t, queue.Add(letter), "Expected Add to return no error")
+	assert.NoError(t, queue.Close(), "Expected Close to return no error")
+
+	file, err := os.Open(filename)
+	assert.NoError(t, err, "Expected dead letter file to exist")
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	assert.True(t, scanner.Scan(), "Expected a line in the dead letter file")
+
+	var written DeadLetter
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &written), "Expected line to be valid JSON")
+	assert.Equal(t, *letter, written, "Expected the written message to match")
+	assert.False(t, scanner.Scan(), "Expected only one line in the dead letter file")
+}
+
+func TestNewDeadLetterQueue_BadConfig(t *testing.T) {
+	viper.Reset()
+	viper.Set("consumer.deadletterbadtest.dead-letter-max-size", 0)
+	_, err := NewDeadLetterQueue("consumer.deadletterbadtest", "testcluster")
+	assert.Error(t, err, "Expected NewDeadLetterQueue to return an error")
+}
@@ -0,0 +1,136 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// DynamicConfig holds the changes to the configuration that have been made through the HTTP server while Burrow is
+// running. These are saved to a file, so that they are kept when Burrow is restarted.
+type DynamicConfig struct {
+	// Filters are the group and topic lists set for a module, keyed by the module's config root
+	Filters map[string]ConsumerFilterSettings `json:"filters"`
+
+	// ExpectedGroups is keyed by cluster and then by group. A group is true if it was added to the expected groups,
+	// and false if it was removed (which matters for groups in the cluster's expected-groups config)
+	ExpectedGroups map[string]map[string]bool `json:"expected-groups"`
+}
+
+var dynamicConfig = struct {
+	lock     sync.Mutex
+	filename string
+	config   DynamicConfig
+}{}
+
+// LoadDynamicConfig sets the file that changes made through the HTTP server are saved to, and loads the changes that
+// were saved before. A file that does not exist yet is treated as having no changes. If filename is empty, changes are
+// not saved, and only last until Burrow is restarted (or the configuration is reloaded).
+func LoadDynamicConfig(filename string) error {
+	dynamicConfig.lock.Lock()
+	defer dynamicConfig.lock.Unlock()
+
+	config := DynamicConfig{
+		Filters:        make(map[string]ConsumerFilterSettings),
+		ExpectedGroups: make(map[string]map[string]bool),
+	}
+	if filename != "" {
+		contents, err := ioutil.ReadFile(filename)
+		if (err != nil) && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal(contents, &config); err != nil {
+				return err
+			}
+		}
+		if config.Filters == nil {
+			config.Filters = make(map[string]ConsumerFilterSettings)
+		}
+		if config.ExpectedGroups == nil {
+			config.ExpectedGroups = make(map[string]map[string]bool)
+		}
+	}
+
+	dynamicConfig.filename = filename
+	dynamicConfig.config = config
+	return nil
+}
+
+// GetDynamicFilter returns the lists that were saved for the module with the given config root, if there are any
+func GetDynamicFilter(configRoot string) (ConsumerFilterSettings, bool) {
+	dynamicConfig.lock.Lock()
+	defer dynamicConfig.lock.Unlock()
+	settings, ok := dynamicConfig.config.Filters[configRoot]
+	return settings, ok
+}
+
+// SaveDynamicFilter saves the lists for the module with the given config root, or removes the saved lists if settings
+// is nil. Nothing is saved if there is no dynamic config file.
+func SaveDynamicFilter(configRoot string, settings *ConsumerFilterSettings) error {
+	dynamicConfig.lock.Lock()
+	defer dynamicConfig.lock.Unlock()
+	if dynamicConfig.filename == "" {
+		return nil
+	}
+
+	if settings == nil {
+		delete(dynamicConfig.config.Filters, configRoot)
+	} else {
+		dynamicConfig.config.Filters[configRoot] = *settings
+	}
+	return writeDynamicConfig()
+}
+
+// GetDynamicExpectedGroups returns the groups that have been added to (true) or removed from (false) the expected
+// groups for the cluster
+func GetDynamicExpectedGroups(cluster string) map[string]bool {
+	dynamicConfig.lock.Lock()
+	defer dynamicConfig.lock.Unlock()
+	groups := make(map[string]bool, len(dynamicConfig.config.ExpectedGroups[cluster]))
+	for group, expected := range dynamicConfig.config.ExpectedGroups[cluster] {
+		groups[group] = expected
+	}
+	return groups
+}
+
+// SaveDynamicExpectedGroup saves that the group was added to, or removed from, the expected groups for the cluster.
+// Nothing is saved if there is no dynamic config file.
+func SaveDynamicExpectedGroup(cluster, group string, expected bool) error {
+	dynamicConfig.lock.Lock()
+	defer dynamicConfig.lock.Unlock()
+	if dynamicConfig.filename == "" {
+		return nil
+	}
+
+	if _, ok := dynamicConfig.config.ExpectedGroups[cluster]; !ok {
+		dynamicConfig.config.ExpectedGroups[cluster] = make(map[string]bool)
+	}
+	dynamicConfig.config.ExpectedGroups[cluster][group] = expected
+	return writeDynamicConfig()
+}
+
+// writeDynamicConfig writes the changes to a temporary file, and then renames it, so that the file is never left
+// partially written. The lock must be held when this is called.
+func writeDynamicConfig() error {
+	contents, err := json.MarshalIndent(dynamicConfig.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFilename := dynamicConfig.filename + ".tmp"
+	if err := ioutil.WriteFile(tempFilename, contents, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempFilename, dynamicConfig.filename)
+}
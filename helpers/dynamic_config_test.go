@@ -0,0 +1,74 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	defer os.RemoveAll(dir)
+	defer LoadDynamicConfig("")
+	filename := filepath.Join(dir, "dynamic.json")
+
+	// A file that does not exist yet has no changes
+	assert.NoError(t, LoadDynamicConfig(filename), "Expected LoadDynamicConfig to return no error")
+	assert.Empty(t, GetDynamicExpectedGroups("testcluster"), "Expected no expected groups")
+
+	assert.NoError(t, SaveDynamicFilter("consumer.test", &ConsumerFilterSettings{TopicDenylist: "^private$"}), "Expected filter to be saved")
+	assert.NoError(t, SaveDynamicExpectedGroup("testcluster", "addedgroup", true), "Expected group to be saved")
+	assert.NoError(t, SaveDynamicExpectedGroup("testcluster", "removedgroup", false), "Expected group to be saved")
+
+	// The changes are read back from the file
+	assert.NoError(t, LoadDynamicConfig(filename), "Expected LoadDynamicConfig to return no error")
+	settings, ok := GetDynamicFilter("consumer.test")
+	assert.True(t, ok, "Expected a saved filter")
+	assert.Equal(t, "^private$", settings.TopicDenylist, "Expected the saved topic denylist")
+	assert.Equal(t, map[string]bool{"addedgroup": true, "removedgroup": false}, GetDynamicExpectedGroups("testcluster"), "Expected the saved groups")
+
+	// The saved filter is used instead of the configuration
+	viper.Reset()
+	viper.Set("consumer.test.topic-denylist", "^other$")
+	filter, err := NewConsumerFilter("consumer.test")
+	assert.NoError(t, err, "Expected NewConsumerFilter to return no error")
+	assert.False(t, filter.AcceptTopic("private"), "Expected private topic to be rejected")
+	assert.True(t, filter.AcceptTopic("other"), "Expected other topic to be accepted")
+
+	// Removing the saved filter goes back to the configuration
+	assert.NoError(t, SaveDynamicFilter("consumer.test", nil), "Expected filter to be removed")
+	assert.NoError(t, filter.Reload(), "Expected Reload to return no error")
+	assert.False(t, filter.AcceptTopic("other"), "Expected other topic to be rejected")
+}
+
+func TestDynamicConfig_NoFile(t *testing.T) {
+	assert.NoError(t, LoadDynamicConfig(""), "Expected LoadDynamicConfig to return no error")
+	assert.NoError(t, SaveDynamicExpectedGroup("testcluster", "addedgroup", true), "Expected save to return no error")
+	assert.Empty(t, GetDynamicExpectedGroups("testcluster"), "Expected nothing to be saved without a file")
+}
+
+func TestDynamicConfig_BadFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "burrow")
+	assert.NoError(t, err, "Expected temp file to be created")
+	defer os.Remove(file.Name())
+	file.WriteString("not json")
+	file.Close()
+	defer LoadDynamicConfig("")
+
+	assert.Error(t, LoadDynamicConfig(file.Name()), "Expected an error for a file that is not JSON")
+}
@@ -0,0 +1,105 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// eventSubscriberBuffer is the number of events that can be waiting for a subscriber before new events are dropped
+const eventSubscriberBuffer = 1000
+
+// eventBus holds the subscriptions to events. Publishing an event sends it to each subscription for its type without
+// blocking, so that a slow subscriber cannot hold up the module that published the event.
+var eventBus = struct {
+	lock          sync.RWMutex
+	subscriptions map[*EventSubscription]struct{}
+}{subscriptions: make(map[*EventSubscription]struct{})}
+
+// EventSubscription receives the events that it subscribed to on its Events channel, until it is closed. If the
+// subscriber does not keep up, and the channel is full, new events for it are dropped and counted in the
+// events.dropped metric.
+type EventSubscription struct {
+	// Events is the channel that the events are sent on. It is closed when the subscription is closed
+	Events <-chan *protocol.Event
+
+	events chan *protocol.Event
+	types  map[protocol.EventType]bool
+}
+
+// SubscribeEvents returns a subscription that receives every event of the given types that is published after it is
+// created, or every event if no types are given. The subscription must be closed when it is no longer used.
+func SubscribeEvents(types ...protocol.EventType) *EventSubscription {
+	events := make(chan *protocol.Event, eventSubscriberBuffer)
+	subscription := &EventSubscription{
+		Events: events,
+		events: events,
+	}
+	if len(types) > 0 {
+		subscription.types = make(map[protocol.EventType]bool)
+		for _, eventType := range types {
+			subscription.types[eventType] = true
+		}
+	}
+
+	eventBus.lock.Lock()
+	eventBus.subscriptions[subscription] = struct{}{}
+	eventBus.lock.Unlock()
+	return subscription
+}
+
+// Close stops the subscription, and closes its Events channel. It is safe to call Close more than once.
+func (subscription *EventSubscription) Close() {
+	eventBus.lock.Lock()
+	defer eventBus.lock.Unlock()
+
+	if _, ok := eventBus.subscriptions[subscription]; ok {
+		delete(eventBus.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// PublishEvent sends the event to every subscription for its type. If the event has no timestamp, it is set to the
+// current time. Subscribers must not change the event, as it is shared between them.
+func PublishEvent(event *protocol.Event) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().Unix() * 1000
+	}
+	GetMetricCounter("events.published." + event.Type.String()).Inc(1)
+
+	eventBus.lock.RLock()
+	defer eventBus.lock.RUnlock()
+	for subscription := range eventBus.subscriptions {
+		if (subscription.types != nil) && !subscription.types[event.Type] {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			GetMetricCounter("events.dropped").Inc(1)
+		}
+	}
+}
+
+// PublishModuleError publishes an EventModuleError for the module with the config root, and the cluster if the module
+// is for one. The module is DEGRADED for a while after this (see GetModuleHealth).
+func PublishModuleError(configRoot, cluster, message string) {
+	recordModuleError(configRoot, message)
+	PublishEvent(&protocol.Event{
+		Type:    protocol.EventModuleError,
+		Module:  configRoot,
+		Cluster: cluster,
+		Message: message,
+	})
+}
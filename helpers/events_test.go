@@ -0,0 +1,67 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	all := SubscribeEvents()
+	defer all.Close()
+	errors := SubscribeEvents(protocol.EventModuleError)
+	defer errors.Close()
+
+	PublishEvent(&protocol.Event{Type: protocol.EventGroupExpired, Cluster: "testcluster", Group: "testgroup"})
+	PublishModuleError("cluster.testcluster", "testcluster", "failed")
+
+	event := <-all.Events
+	assert.Equal(t, protocol.EventGroupExpired, event.Type, "Expected the expiry event first")
+	assert.NotZero(t, event.Timestamp, "Expected the timestamp to be set")
+	event = <-all.Events
+	assert.Equal(t, protocol.EventModuleError, event.Type, "Expected the module error second")
+
+	event = <-errors.Events
+	assert.Equal(t, &protocol.Event{
+		Type:      protocol.EventModuleError,
+		Timestamp: event.Timestamp,
+		Cluster:   "testcluster",
+		Module:    "cluster.testcluster",
+		Message:   "failed",
+	}, event, "Expected only the module error")
+	assert.Len(t, errors.Events, 0, "Expected no other events")
+}
+
+func TestEventSubscription_Close(t *testing.T) {
+	subscription := SubscribeEvents()
+	subscription.Close()
+	subscription.Close()
+
+	PublishEvent(&protocol.Event{Type: protocol.EventGroupExpired})
+	_, ok := <-subscription.Events
+	assert.False(t, ok, "Expected the channel to be closed")
+}
+
+func TestPublishEvent_Full(t *testing.T) {
+	subscription := SubscribeEvents(protocol.EventStatusChange)
+	defer subscription.Close()
+
+	dropped := GetMetricCounter("events.dropped").Count()
+	for i := 0; i < eventSubscriberBuffer+1; i++ {
+		PublishEvent(&protocol.Event{Type: protocol.EventStatusChange})
+	}
+	assert.Len(t, subscription.Events, eventSubscriberBuffer, "Expected the channel to be full")
+	assert.Equal(t, dropped+1, GetMetricCounter("events.dropped").Count(), "Expected the event that did not fit to be dropped")
+}
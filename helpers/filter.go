@@ -0,0 +1,211 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// consumerFilters holds every ConsumerFilter that has been created, keyed by the config root of the module that it
+// was created for (for example, "consumer.local" or "storage.default").
+var consumerFilters sync.Map
+
+// ConsumerFilterSettings are the regular expressions that a ConsumerFilter uses. An empty string means that the list
+// is not used.
+type ConsumerFilterSettings struct {
+	GroupAllowlist string `json:"group-allowlist"`
+	GroupDenylist  string `json:"group-denylist"`
+	TopicAllowlist string `json:"topic-allowlist"`
+	TopicDenylist  string `json:"topic-denylist"`
+}
+
+// ConsumerFilter decides which consumer groups and topics a module accepts, using an allowlist and a denylist for each.
+// A name is accepted if it matches the allowlist (when set) and does not match the denylist (when set). The lists can
+// be changed at any time while the module is running, so modules must check the filter for each offset rather than
+// caching the result. It is safe to use a ConsumerFilter from multiple goroutines.
+type ConsumerFilter struct {
+	configRoot string
+	settings   ConsumerFilterSettings
+
+	// The lists from the configuration, which are replaced when the configuration is reloaded
+	configured ConsumerFilterSettings
+
+	lock           sync.RWMutex
+	groupAllowlist *regexp.Regexp
+	groupDenylist  *regexp.Regexp
+	topicAllowlist *regexp.Regexp
+	topicDenylist  *regexp.Regexp
+}
+
+// NewConsumerFilter creates a ConsumerFilter from the group-allowlist, group-denylist, topic-allowlist, and
+// topic-denylist configs under the config root, and registers it under that config root so that it can be found with
+// GetConsumerFilter. If any of the configs are not valid regular expressions, an error is returned.
+func NewConsumerFilter(configRoot string) (*ConsumerFilter, error) {
+	filter := &ConsumerFilter{
+		configRoot: configRoot,
+		configured: getFilterSettings(viper.GetViper(), configRoot),
+	}
+	if err := filter.Reload(); err != nil {
+		return nil, err
+	}
+	consumerFilters.Store(configRoot, filter)
+	return filter, nil
+}
+
+// GetConsumerFilter returns the ConsumerFilter for the module with the given config root, or nil if there is none.
+func GetConsumerFilter(configRoot string) *ConsumerFilter {
+	if filter, ok := consumerFilters.Load(configRoot); ok {
+		return filter.(*ConsumerFilter)
+	}
+	return nil
+}
+
+// GetConsumerFilterNames returns a sorted list of the config roots that have a ConsumerFilter.
+func GetConsumerFilterNames() []string {
+	names := make([]string, 0)
+	consumerFilters.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// ReloadConsumerFilters reloads every ConsumerFilter from the given configuration, such as one that has been read from
+// a changed configuration file. If a filter fails to reload, it keeps its existing settings, and the first error is
+// returned after the rest of the filters have been reloaded.
+func ReloadConsumerFilters(config *viper.Viper) error {
+	var firstErr error
+	consumerFilters.Range(func(key, value interface{}) bool {
+		if err := value.(*ConsumerFilter).setConfigured(getFilterSettings(config, key.(string))); (err != nil) && (firstErr == nil) {
+			firstErr = errors.New(key.(string) + ": " + err.Error())
+		}
+		return true
+	})
+	return firstErr
+}
+
+// ValidateConsumerFilters checks that the lists for every ConsumerFilter are valid in the given configuration, such as
+// one that has been read from a changed configuration file, without changing the filters. The first error found is
+// returned.
+func ValidateConsumerFilters(config *viper.Viper) error {
+	var firstErr error
+	consumerFilters.Range(func(key, value interface{}) bool {
+		if _, err := getFilterSettings(config, key.(string)).compile(); err != nil {
+			firstErr = errors.New(key.(string) + ": " + err.Error())
+			return false
+		}
+		return true
+	})
+	return firstErr
+}
+
+// Reload sets the lists from the configuration for the filter's config root, as of when the filter was created or the
+// configuration was last reloaded, unless lists for the module have been saved with SaveDynamicFilter, in which case
+// those are used. If any of the lists are not valid, an error is returned and the filter is not changed.
+func (filter *ConsumerFilter) Reload() error {
+	if settings, ok := GetDynamicFilter(filter.configRoot); ok {
+		return filter.Update(settings)
+	}
+	filter.lock.RLock()
+	settings := filter.configured
+	filter.lock.RUnlock()
+	return filter.Update(settings)
+}
+
+// setConfigured replaces the lists from the configuration, and reloads the filter. If any of the lists are not valid,
+// an error is returned and neither is changed.
+func (filter *ConsumerFilter) setConfigured(settings ConsumerFilterSettings) error {
+	if _, err := settings.compile(); err != nil {
+		return err
+	}
+	filter.lock.Lock()
+	filter.configured = settings
+	filter.lock.Unlock()
+	return filter.Reload()
+}
+
+// Update replaces all of the lists with the ones given. If any of them are not valid regular expressions, an error is
+// returned and the filter is not changed. The configuration is not changed, so a later Reload will undo the update.
+func (filter *ConsumerFilter) Update(settings ConsumerFilterSettings) error {
+	compiled, err := settings.compile()
+	if err != nil {
+		return err
+	}
+
+	filter.lock.Lock()
+	defer filter.lock.Unlock()
+	filter.settings = settings
+	filter.groupAllowlist, filter.groupDenylist, filter.topicAllowlist, filter.topicDenylist = compiled[0], compiled[1], compiled[2], compiled[3]
+	return nil
+}
+
+// Settings returns the lists that the filter is currently using.
+func (filter *ConsumerFilter) Settings() ConsumerFilterSettings {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return filter.settings
+}
+
+// AcceptGroup returns true if the consumer group is accepted by the group allowlist and denylist.
+func (filter *ConsumerFilter) AcceptGroup(group string) bool {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return acceptName(group, filter.groupAllowlist, filter.groupDenylist)
+}
+
+// AcceptTopic returns true if the topic is accepted by the topic allowlist and denylist.
+func (filter *ConsumerFilter) AcceptTopic(topic string) bool {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return acceptName(topic, filter.topicAllowlist, filter.topicDenylist)
+}
+
+func getFilterSettings(config *viper.Viper, configRoot string) ConsumerFilterSettings {
+	return ConsumerFilterSettings{
+		GroupAllowlist: config.GetString(configRoot + ".group-allowlist"),
+		GroupDenylist:  config.GetString(configRoot + ".group-denylist"),
+		TopicAllowlist: config.GetString(configRoot + ".topic-allowlist"),
+		TopicDenylist:  config.GetString(configRoot + ".topic-denylist"),
+	}
+}
+
+// compile returns the compiled regular expressions for the group allowlist, group denylist, topic allowlist, and topic
+// denylist, in that order. Lists that are not used are nil.
+func (settings ConsumerFilterSettings) compile() ([4]*regexp.Regexp, error) {
+	var compiled [4]*regexp.Regexp
+	for i, expr := range []string{settings.GroupAllowlist, settings.GroupDenylist, settings.TopicAllowlist, settings.TopicDenylist} {
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return compiled, err
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+func acceptName(name string, allowlist, denylist *regexp.Regexp) bool {
+	if (allowlist != nil) && (!allowlist.MatchString(name)) {
+		return false
+	}
+	if (denylist != nil) && denylist.MatchString(name) {
+		return false
+	}
+	return true
+}
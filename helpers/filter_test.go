@@ -0,0 +1,77 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsumerFilter(t *testing.T) {
+	viper.Reset()
+	viper.Set("consumer.test.group-allowlist", "^test.*$")
+	viper.Set("consumer.test.topic-denylist", "-changelog$")
+
+	filter, err := NewConsumerFilter("consumer.test")
+	assert.Nil(t, err, "Expected NewConsumerFilter to return no error")
+	assert.Equal(t, filter, GetConsumerFilter("consumer.test"), "Expected filter to be registered")
+	assert.Contains(t, GetConsumerFilterNames(), "consumer.test", "Expected filter name to be listed")
+
+	assert.True(t, filter.AcceptGroup("testgroup"), "Expected testgroup to be accepted")
+	assert.False(t, filter.AcceptGroup("othergroup"), "Expected othergroup to be rejected")
+	assert.True(t, filter.AcceptTopic("testtopic"), "Expected testtopic to be accepted")
+	assert.False(t, filter.AcceptTopic("app-store-changelog"), "Expected changelog topic to be rejected")
+}
+
+func TestNewConsumerFilter_BadRegexp(t *testing.T) {
+	viper.Reset()
+	viper.Set("consumer.test.topic-allowlist", "[")
+
+	_, err := NewConsumerFilter("consumer.test")
+	assert.NotNil(t, err, "Expected NewConsumerFilter to return an error")
+}
+
+func TestConsumerFilter_Update(t *testing.T) {
+	viper.Reset()
+	viper.Set("consumer.test.group-denylist", "^othergroup$")
+	filter, _ := NewConsumerFilter("consumer.test")
+
+	err := filter.Update(ConsumerFilterSettings{GroupAllowlist: "^other.*$"})
+	assert.Nil(t, err, "Expected Update to return no error")
+	assert.True(t, filter.AcceptGroup("othergroup"), "Expected othergroup to be accepted")
+	assert.False(t, filter.AcceptGroup("testgroup"), "Expected testgroup to be rejected")
+
+	// An invalid update must not change the filter
+	err = filter.Update(ConsumerFilterSettings{GroupDenylist: "["})
+	assert.NotNil(t, err, "Expected Update to return an error")
+	assert.Equal(t, "^other.*$", filter.Settings().GroupAllowlist, "Expected filter settings to be unchanged")
+
+	// Reloading goes back to the configuration
+	assert.Nil(t, ReloadConsumerFilters(viper.GetViper()), "Expected ReloadConsumerFilters to return no error")
+	assert.False(t, filter.AcceptGroup("othergroup"), "Expected othergroup to be rejected")
+	assert.True(t, filter.AcceptGroup("testgroup"), "Expected testgroup to be accepted")
+}
+
+func TestValidateConsumerFilters(t *testing.T) {
+	viper.Reset()
+	filter, _ := NewConsumerFilter("consumer.test")
+
+	config := viper.New()
+	config.Set("consumer.test.topic-allowlist", "^test.*$")
+	assert.Nil(t, ValidateConsumerFilters(config), "Expected ValidateConsumerFilters to return no error")
+
+	// An invalid list is found, but the filter is not changed
+	config.Set("consumer.test.topic-denylist", "[")
+	assert.NotNil(t, ValidateConsumerFilters(config), "Expected ValidateConsumerFilters to return an error")
+	assert.Equal(t, "", filter.Settings().TopicAllowlist, "Expected filter settings to be unchanged")
+}
@@ -0,0 +1,135 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// defaultHealthErrorWindow is how long (in seconds) a module is DEGRADED after it publishes a module error, unless
+// general.health-error-window is set
+const defaultHealthErrorWindow = 300
+
+// moduleHealth holds the health that has been set for each module with SetModuleHealth, keyed by config root. Modules
+// that are OK are not stored.
+var moduleHealth sync.Map
+
+// moduleErrors holds the most recent error published by each module with PublishModuleError, keyed by config root
+var moduleErrors sync.Map
+
+// coordinatorHealth holds the Health func of each coordinator that has been registered, keyed by coordinator name
+var coordinatorHealth sync.Map
+
+type moduleError struct {
+	time    time.Time
+	message string
+}
+
+// SetModuleHealth sets the health of the module with the config root, which lasts until it is set again. This is for
+// conditions that a module can tell have ended, such as losing the connection to its cluster. Errors that happen once,
+// and are published with PublishModuleError, make the module DEGRADED for a while without it being set.
+func SetModuleHealth(configRoot string, state protocol.HealthState, reason string) {
+	if state == protocol.HealthOK {
+		moduleHealth.Delete(configRoot)
+		return
+	}
+	moduleHealth.Store(configRoot, &protocol.ModuleHealth{State: state, Reason: reason})
+}
+
+// recordModuleError keeps the error as the most recent one for the module with the config root
+func recordModuleError(configRoot, message string) {
+	moduleErrors.Store(configRoot, &moduleError{time: time.Now(), message: message})
+}
+
+// GetModuleHealth returns the health of the module with the config root. This is the health that was set with
+// SetModuleHealth, or DEGRADED if the module has published a module error within the last general.health-error-window
+// seconds (300 by default), whichever is worse. Otherwise, the module is OK.
+func GetModuleHealth(name, configRoot string) *protocol.ModuleHealth {
+	health := &protocol.ModuleHealth{Name: name, State: protocol.HealthOK}
+
+	window := viper.GetInt("general.health-error-window")
+	if window <= 0 {
+		window = defaultHealthErrorWindow
+	}
+	if value, ok := moduleErrors.Load(configRoot); ok {
+		lastError := value.(*moduleError)
+		if time.Since(lastError.time) < time.Duration(window)*time.Second {
+			health.State = protocol.HealthDegraded
+			health.Reason = lastError.message
+		}
+	}
+	if value, ok := moduleHealth.Load(configRoot); ok {
+		set := value.(*protocol.ModuleHealth)
+		if set.State >= health.State {
+			health.State = set.State
+			health.Reason = set.Reason
+		}
+	}
+	return health
+}
+
+// NewCoordinatorHealth rolls up the health of a coordinator's modules. The modules are sorted by name, and the
+// coordinator is in the worst state of any of them, with the reason of the first module in that state.
+func NewCoordinatorHealth(modules []*protocol.ModuleHealth) *protocol.CoordinatorHealth {
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+
+	health := &protocol.CoordinatorHealth{
+		State:   protocol.HealthOK,
+		Modules: modules,
+	}
+	for _, module := range modules {
+		if module.State > health.State {
+			health.State = module.State
+			health.Reason = module.Name + ": " + module.Reason
+		}
+	}
+	return health
+}
+
+// GetCoordinatorHealth returns the health of a coordinator (such as "cluster") from the health of each of its modules,
+// using NewCoordinatorHealth
+func GetCoordinatorHealth(coordinator string, modules map[string]protocol.Module) *protocol.CoordinatorHealth {
+	moduleHealth := make([]*protocol.ModuleHealth, 0, len(modules))
+	for name := range modules {
+		moduleHealth = append(moduleHealth, GetModuleHealth(name, coordinator+"."+name))
+	}
+	return NewCoordinatorHealth(moduleHealth)
+}
+
+// RegisterCoordinatorHealth registers the Health func of a coordinator, so that it is included in GetHealth. A func
+// that was registered for the coordinator name before is replaced.
+func RegisterCoordinatorHealth(name string, health func() *protocol.CoordinatorHealth) {
+	coordinatorHealth.Store(name, health)
+}
+
+// GetHealth returns the health of each coordinator that has been registered, and the worst state of any of them, which
+// is the health of Burrow as a whole
+func GetHealth() (protocol.HealthState, map[string]*protocol.CoordinatorHealth) {
+	state := protocol.HealthOK
+	coordinators := make(map[string]*protocol.CoordinatorHealth)
+	coordinatorHealth.Range(func(key, value interface{}) bool {
+		health := value.(func() *protocol.CoordinatorHealth)()
+		coordinators[key.(string)] = health
+		if health.State > state {
+			state = health.State
+		}
+		return true
+	})
+	return state, coordinators
+}
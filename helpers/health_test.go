@@ -0,0 +1,72 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestGetModuleHealth(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, &protocol.ModuleHealth{Name: "test", State: protocol.HealthOK}, GetModuleHealth("test", "cluster.healthtest"),
+		"Expected a module with no errors to be OK")
+
+	PublishModuleError("cluster.healthtest", "healthtest", "failed to fetch offsets")
+	assert.Equal(t, &protocol.ModuleHealth{Name: "test", State: protocol.HealthDegraded, Reason: "failed to fetch offsets"},
+		GetModuleHealth("test", "cluster.healthtest"), "Expected a module with a recent error to be DEGRADED")
+
+	SetModuleHealth("cluster.healthtest", protocol.HealthFailed, "failed to fetch topic list")
+	assert.Equal(t, &protocol.ModuleHealth{Name: "test", State: protocol.HealthFailed, Reason: "failed to fetch topic list"},
+		GetModuleHealth("test", "cluster.healthtest"), "Expected the health that was set to be worse than the error")
+
+	// Setting OK clears the health that was set, and errors older than the window are ignored
+	SetModuleHealth("cluster.healthtest", protocol.HealthOK, "")
+	moduleErrors.Store("cluster.healthtest", &moduleError{time: time.Now().Add(-301 * time.Second), message: "old"})
+	assert.Equal(t, protocol.HealthOK, GetModuleHealth("test", "cluster.healthtest").State, "Expected the module to be OK")
+
+	viper.Set("general.health-error-window", 600)
+	assert.Equal(t, protocol.HealthDegraded, GetModuleHealth("test", "cluster.healthtest").State,
+		"Expected the error to be within the configured window")
+	moduleErrors.Delete("cluster.healthtest")
+}
+
+func TestNewCoordinatorHealth(t *testing.T) {
+	health := NewCoordinatorHealth([]*protocol.ModuleHealth{
+		{Name: "c", State: protocol.HealthDegraded, Reason: "slow"},
+		{Name: "b", State: protocol.HealthFailed, Reason: "down"},
+		{Name: "a", State: protocol.HealthOK},
+		{Name: "d", State: protocol.HealthFailed, Reason: "also down"},
+	})
+	assert.Equal(t, protocol.HealthFailed, health.State, "Expected the worst module state")
+	assert.Equal(t, "b: down", health.Reason, "Expected the reason of the first failed module")
+	assert.Equal(t, "a", health.Modules[0].Name, "Expected the modules to be sorted")
+
+	health = NewCoordinatorHealth([]*protocol.ModuleHealth{})
+	assert.Equal(t, protocol.HealthOK, health.State, "Expected no modules to be OK")
+	assert.Empty(t, health.Reason, "Expected no reason")
+}
+
+func TestGetHealth(t *testing.T) {
+	RegisterCoordinatorHealth("healthtest", func() *protocol.CoordinatorHealth {
+		return &protocol.CoordinatorHealth{State: protocol.HealthDegraded, Modules: []*protocol.ModuleHealth{}}
+	})
+	defer coordinatorHealth.Delete("healthtest")
+
+	state, coordinators := GetHealth()
+	assert.Equal(t, protocol.HealthDegraded, state, "Expected the worst coordinator state")
+	assert.Contains(t, coordinators, "healthtest", "Expected the coordinator health to be returned")
+}
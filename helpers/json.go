@@ -0,0 +1,76 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// JSONEncoder encodes the HTTP server's responses, and the statuses that exporters send, as JSON. Encoders other than
+// the standard library's can be registered with RegisterJSONEncoder, and selected with general.json-encoder. An encoder
+// must produce the same JSON as encoding/json does for the structs in the protocol package, including their json tags
+// and their MarshalJSON and MarshalText funcs, as clients depend on the format.
+type JSONEncoder interface {
+	// Marshal returns the JSON encoding of the value
+	Marshal(v interface{}) ([]byte, error)
+
+	// NewEncoder returns a JSONStreamEncoder that writes each value to the writer, followed by a newline
+	NewEncoder(w io.Writer) JSONStreamEncoder
+}
+
+// JSONStreamEncoder writes the JSON encoding of values to a writer, as json.Encoder does
+type JSONStreamEncoder interface {
+	Encode(v interface{}) error
+}
+
+// The name of the encoder that uses encoding/json, which is used unless general.json-encoder is set
+const defaultJSONEncoder = "standard"
+
+// standardJSONEncoder is the JSONEncoder that uses encoding/json
+type standardJSONEncoder struct{}
+
+func (standardJSONEncoder) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (standardJSONEncoder) NewEncoder(w io.Writer) JSONStreamEncoder {
+	return json.NewEncoder(w)
+}
+
+var jsonEncoders = sync.Map{}
+
+func init() {
+	RegisterJSONEncoder(defaultJSONEncoder, standardJSONEncoder{})
+}
+
+// RegisterJSONEncoder registers an encoder, so that it can be selected by setting general.json-encoder to the name.
+// This is used when Burrow is embedded in another application, to use a faster encoder (such as jsoniter, configured to
+// be compatible with the standard library) without Burrow depending on it. An encoder that was registered with the name
+// before is replaced.
+func RegisterJSONEncoder(name string, encoder JSONEncoder) {
+	jsonEncoders.Store(name, encoder)
+}
+
+// GetJSONEncoder returns the encoder that is selected by general.json-encoder, or the encoder that uses encoding/json
+// if it is not set. Modules call this in Configure, and panic if an error is returned, as the encoder is not known.
+func GetJSONEncoder() (JSONEncoder, error) {
+	viper.SetDefault("general.json-encoder", defaultJSONEncoder)
+	name := viper.GetString("general.json-encoder")
+	if encoder, ok := jsonEncoders.Load(name); ok {
+		return encoder.(JSONEncoder), nil
+	}
+	return nil, errors.New("unknown JSON encoder '" + name + "'")
+}
@@ -0,0 +1,127 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// fixtureConsumerStatus returns the status of a group that consumes from the number of partitions given
+func fixtureConsumerStatus(partitions int) *protocol.ConsumerGroupStatus {
+	status := &protocol.ConsumerGroupStatus{
+		Cluster:         "testcluster",
+		Group:           "testgroup",
+		Status:          protocol.StatusWarning,
+		Complete:        1.0,
+		TotalPartitions: partitions,
+		Partitions:      make([]*protocol.PartitionStatus, partitions),
+	}
+	for i := range status.Partitions {
+		status.Partitions[i] = &protocol.PartitionStatus{
+			Topic:      "testtopic" + strconv.Itoa(i%10),
+			Partition:  int32(i),
+			Owner:      "testhost.example.com",
+			ClientID:   "testclient",
+			Status:     protocol.StatusWarning,
+			Start:      &protocol.ConsumerOffset{Offset: 100, Timestamp: 1000, Lag: &protocol.Lag{Value: 10}},
+			End:        &protocol.ConsumerOffset{Offset: 200, Timestamp: 2000, Lag: &protocol.Lag{Value: 20}},
+			CurrentLag: 20,
+			Complete:   1.0,
+		}
+		status.TotalLag += 20
+	}
+	status.Maxlag = status.Partitions[0]
+	return status
+}
+
+func TestGetJSONEncoder_Default(t *testing.T) {
+	viper.Reset()
+	encoder, err := GetJSONEncoder()
+	assert.Nil(t, err, "Expected no error")
+	assert.IsType(t, standardJSONEncoder{}, encoder, "Expected the standard encoder")
+}
+
+func TestGetJSONEncoder_Registered(t *testing.T) {
+	viper.Reset()
+	viper.Set("general.json-encoder", "test")
+
+	_, err := GetJSONEncoder()
+	assert.Error(t, err, "Expected an error for an encoder that is not registered")
+
+	RegisterJSONEncoder("test", standardJSONEncoder{})
+	defer jsonEncoders.Delete("test")
+	encoder, err := GetJSONEncoder()
+	assert.Nil(t, err, "Expected no error")
+	assert.NotNil(t, encoder, "Expected the registered encoder")
+}
+
+func TestStandardJSONEncoder(t *testing.T) {
+	status := fixtureConsumerStatus(3)
+	expected, err := json.Marshal(status)
+	assert.Nil(t, err, "Expected the status to be encoded")
+
+	encoder := standardJSONEncoder{}
+	value, err := encoder.Marshal(status)
+	assert.Nil(t, err, "Expected no error")
+	assert.Equal(t, string(expected), string(value), "Expected the same JSON as encoding/json")
+
+	buffer := &bytes.Buffer{}
+	assert.Nil(t, encoder.NewEncoder(buffer).Encode(status), "Expected no error")
+	assert.Equal(t, string(expected)+"\n", buffer.String(), "Expected the same JSON as encoding/json, with a newline")
+}
+
+// The benchmarks are run for every registered encoder, so an encoder can be compared to the standard one by
+// registering it in an init func in a test file that is not committed.
+func benchmarkJSONEncoders(b *testing.B, run func(b *testing.B, encoder JSONEncoder)) {
+	jsonEncoders.Range(func(name, encoder interface{}) bool {
+		b.Run(name.(string), func(b *testing.B) {
+			b.ReportAllocs()
+			run(b, encoder.(JSONEncoder))
+		})
+		return true
+	})
+}
+
+func BenchmarkJSONEncoder_Marshal(b *testing.B) {
+	status := fixtureConsumerStatus(10000)
+	benchmarkJSONEncoders(b, func(b *testing.B, encoder JSONEncoder) {
+		for i := 0; i < b.N; i++ {
+			if _, err := encoder.Marshal(status); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkJSONEncoder_Encode(b *testing.B) {
+	status := fixtureConsumerStatus(10000)
+	benchmarkJSONEncoders(b, func(b *testing.B, encoder JSONEncoder) {
+		var writer io.Writer = ioutil.Discard
+		for i := 0; i < b.N; i++ {
+			stream := encoder.NewEncoder(writer)
+			for _, partition := range status.Partitions {
+				if err := stream.Encode(partition); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Shopify/sarama"
+)
+
+// autoVersionConfigs holds the sarama.Config objects for client profiles with a kafka-version of "auto". The Kafka
+// version for these configs is negotiated with the cluster each time a client is created with NewSaramaClient.
+var autoVersionConfigs sync.Map
+
+// apiVersionMarker is an API (and the max version of that API) that was added in a Kafka release. If a broker supports
+// it, the broker is at least that release.
+type apiVersionMarker struct {
+	apiKey     int16
+	maxVersion int16
+	version    sarama.KafkaVersion
+}
+
+// kafkaVersionMarkers are checked in order, so newer releases must be first. Brokers that are newer than the newest
+// release here are treated as that release, as it is the newest that sarama supports.
+var kafkaVersionMarkers = []apiVersionMarker{
+	{apiKey: 48, maxVersion: 0, version: sarama.V2_6_0_0},  // DescribeClientQuotas
+	{apiKey: 14, maxVersion: 5, version: sarama.V2_5_0_0},  // SyncGroup v5
+	{apiKey: 45, maxVersion: 0, version: sarama.V2_4_0_0},  // AlterPartitionReassignments
+	{apiKey: 44, maxVersion: 0, version: sarama.V2_3_0_0},  // IncrementalAlterConfigs
+	{apiKey: 43, maxVersion: 0, version: sarama.V2_2_0_0},  // ElectLeaders
+	{apiKey: 1, maxVersion: 10, version: sarama.V2_1_0_0},  // Fetch v10
+	{apiKey: 1, maxVersion: 8, version: sarama.V2_0_0_0},   // Fetch v8
+	{apiKey: 42, maxVersion: 0, version: sarama.V1_1_0_0},  // DeleteGroups
+	{apiKey: 37, maxVersion: 0, version: sarama.V1_0_0_0},  // CreatePartitions
+	{apiKey: 22, maxVersion: 0, version: sarama.V0_11_0_0}, // InitProducerId
+	{apiKey: 9, maxVersion: 2, version: sarama.V0_10_2_0},  // OffsetFetch v2
+	{apiKey: 19, maxVersion: 0, version: sarama.V0_10_1_0}, // CreateTopics
+	{apiKey: 18, maxVersion: 0, version: sarama.V0_10_0_0}, // ApiVersions
+}
+
+// NewSaramaClient creates a sarama.Client for the servers. If the config is for a client profile with a kafka-version
+// of "auto", the Kafka version is negotiated with the cluster first, and the client is created with a copy of the
+// config that uses that version. Otherwise, this is the same as calling sarama.NewClient.
+func NewSaramaClient(servers []string, saramaConfig *sarama.Config) (sarama.Client, error) {
+	clientConfig, err := negotiateClientConfig(servers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return sarama.NewClient(servers, clientConfig)
+}
+
+// negotiateClientConfig returns the config to create a client for the servers with. This is the config that is passed
+// in, unless it is for a client profile with a kafka-version of "auto", in which case it is a copy that uses the
+// version negotiated with the cluster.
+func negotiateClientConfig(servers []string, saramaConfig *sarama.Config) (*sarama.Config, error) {
+	if _, ok := autoVersionConfigs.Load(saramaConfig); !ok {
+		return saramaConfig, nil
+	}
+
+	version, err := NegotiateKafkaVersion(servers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	negotiatedConfig := *saramaConfig
+	negotiatedConfig.Version = version
+	if (negotiatedConfig.Consumer.IsolationLevel == sarama.ReadCommitted) && (!version.IsAtLeast(sarama.V0_11_0_0)) {
+		negotiatedConfig.Consumer.IsolationLevel = sarama.ReadUncommitted
+	}
+	return &negotiatedConfig, nil
+}
+
+// NegotiateKafkaVersion sends an ApiVersions request to each server in turn, and returns the Kafka version that matches
+// the APIs that the first broker to answer supports. Brokers older than 0.10.0 do not support ApiVersions, and close
+// the connection, so if that happens the oldest version (0.8.2) is returned. Any other failure (such as a timeout, or
+// an authorization error) moves on to the next server, and an error is returned if none of the servers answer.
+func NegotiateKafkaVersion(servers []string, saramaConfig *sarama.Config) (sarama.KafkaVersion, error) {
+	// sarama will not send the ApiVersions request unless the config version supports it
+	probeConfig := *saramaConfig
+	probeConfig.Version = sarama.MaxVersion
+
+	lastErr := errors.New("no servers to negotiate the Kafka version with")
+	for _, server := range servers {
+		broker := sarama.NewBroker(server)
+		if err := broker.Open(&probeConfig); err != nil {
+			lastErr = err
+			continue
+		}
+		if connected, err := broker.Connected(); !connected {
+			if err == nil {
+				err = errors.New("failed to connect to " + server)
+			}
+			lastErr = err
+			continue
+		}
+
+		response, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+		broker.Close()
+		switch {
+		case isConnectionClosed(err):
+			return sarama.V0_8_2_0, nil
+		case err != nil:
+			lastErr = err
+		case response.Err != sarama.ErrNoError:
+			lastErr = response.Err
+		default:
+			return kafkaVersionFromAPIVersions(response.ApiVersions), nil
+		}
+	}
+	return sarama.KafkaVersion{}, lastErr
+}
+
+// isConnectionClosed returns true if the error is from the broker closing the connection, which is what brokers that
+// do not support a request do
+func isConnectionClosed(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || strings.Contains(err.Error(), "use of closed network connection")
+}
+
+func kafkaVersionFromAPIVersions(apiVersions []*sarama.ApiVersionsResponseBlock) sarama.KafkaVersion {
+	maxVersions := make(map[int16]int16, len(apiVersions))
+	for _, block := range apiVersions {
+		maxVersions[block.ApiKey] = block.MaxVersion
+	}
+
+	for _, marker := range kafkaVersionMarkers {
+		if maxVersion, ok := maxVersions[marker.apiKey]; ok && (maxVersion >= marker.maxVersion) {
+			return marker.version
+		}
+	}
+	return sarama.V0_10_0_0
+}
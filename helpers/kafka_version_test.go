@@ -0,0 +1,136 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var kafkaVersionFromAPIVersionsTests = []struct {
+	APIVersions map[int16]int16
+	Expected    sarama.KafkaVersion
+}{
+	{map[int16]int16{1: 2, 18: 0}, sarama.V0_10_0_0},
+	{map[int16]int16{1: 3, 9: 1, 18: 0, 19: 0}, sarama.V0_10_1_0},
+	{map[int16]int16{1: 3, 9: 2, 18: 0, 19: 0}, sarama.V0_10_2_0},
+	{map[int16]int16{1: 5, 9: 3, 18: 1, 19: 1, 22: 0}, sarama.V0_11_0_0},
+	{map[int16]int16{1: 8, 18: 2, 37: 1, 42: 1}, sarama.V2_0_0_0},
+	{map[int16]int16{1: 11, 14: 4, 18: 3, 43: 2, 44: 1, 45: 0}, sarama.V2_4_0_0},
+	{map[int16]int16{1: 12, 14: 5, 18: 3, 48: 1, 60: 0}, sarama.V2_6_0_0},
+}
+
+func fixtureAPIVersionsResponse(apiVersions map[int16]int16) *sarama.ApiVersionsResponse {
+	response := &sarama.ApiVersionsResponse{Err: sarama.ErrNoError}
+	for apiKey, maxVersion := range apiVersions {
+		response.ApiVersions = append(response.ApiVersions, &sarama.ApiVersionsResponseBlock{ApiKey: apiKey, MaxVersion: maxVersion})
+	}
+	return response
+}
+
+func TestKafkaVersionFromAPIVersions(t *testing.T) {
+	for i, testSet := range kafkaVersionFromAPIVersionsTests {
+		version := kafkaVersionFromAPIVersions(fixtureAPIVersionsResponse(testSet.APIVersions).ApiVersions)
+		assert.Equalf(t, testSet.Expected, version, "TEST %v: Expected version %v, not %v", i, testSet.Expected, version)
+	}
+}
+
+func TestNegotiateKafkaVersion(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(fixtureAPIVersionsResponse(map[int16]int16{1: 8, 18: 2, 37: 1, 42: 1})),
+	})
+
+	version, err := NegotiateKafkaVersion([]string{broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected NegotiateKafkaVersion to return no error")
+	assert.Equalf(t, sarama.V2_0_0_0, version, "Expected version 2.0.0, not %v", version)
+}
+
+func TestNegotiateKafkaVersion_ConnectionClosed(t *testing.T) {
+	// Brokers older than 0.10.0 close the connection when they get an ApiVersions request
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Expected listener setup to return no error")
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	version, err := NegotiateKafkaVersion([]string{listener.Addr().String()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected NegotiateKafkaVersion to return no error")
+	assert.Equalf(t, sarama.V0_8_2_0, version, "Expected version 0.8.2, not %v", version)
+}
+
+func TestNegotiateKafkaVersion_ErrorResponse(t *testing.T) {
+	failing := sarama.NewMockBroker(t, 1)
+	defer failing.Close()
+	failing.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(&sarama.ApiVersionsResponse{Err: sarama.ErrClusterAuthorizationFailed}),
+	})
+
+	// An error from the broker is not taken to mean an old broker
+	_, err := NegotiateKafkaVersion([]string{failing.Addr()}, sarama.NewConfig())
+	assert.Equal(t, sarama.ErrClusterAuthorizationFailed, err, "Expected the error from the broker")
+
+	// The next server is tried instead
+	broker := sarama.NewMockBroker(t, 2)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(fixtureAPIVersionsResponse(map[int16]int16{1: 8, 18: 2, 37: 1, 42: 1})),
+	})
+	version, err := NegotiateKafkaVersion([]string{failing.Addr(), broker.Addr()}, sarama.NewConfig())
+	assert.Nil(t, err, "Expected NegotiateKafkaVersion to return no error")
+	assert.Equalf(t, sarama.V2_0_0_0, version, "Expected version 2.0.0, not %v", version)
+}
+
+func TestNegotiateKafkaVersion_NoServers(t *testing.T) {
+	_, err := NegotiateKafkaVersion([]string{}, sarama.NewConfig())
+	assert.NotNil(t, err, "Expected NegotiateKafkaVersion to return an error")
+}
+
+func TestNegotiateClientConfig_Auto(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.client-id", "testid")
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	saramaConfig.Consumer.IsolationLevel = sarama.ReadCommitted
+
+	// Only the version negotiation is tested, as a sarama client closes its brokers in the background, which would
+	// race with the tests that set sarama.Logger
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockWrapper(fixtureAPIVersionsResponse(map[int16]int16{1: 3, 9: 2, 18: 0, 19: 0})),
+	})
+
+	clientConfig, err := negotiateClientConfig([]string{broker.Addr()}, saramaConfig)
+	assert.Nil(t, err, "Expected negotiateClientConfig to return no error")
+
+	assert.Equalf(t, sarama.V0_10_2_0, clientConfig.Version, "Expected negotiated version 0.10.2, not %v", clientConfig.Version)
+	assert.Equalf(t, sarama.ReadUncommitted, clientConfig.Consumer.IsolationLevel, "Expected IsolationLevel to be ReadUncommitted, not %v", clientConfig.Consumer.IsolationLevel)
+	assert.Equalf(t, sarama.MaxVersion, saramaConfig.Version, "Expected the profile config to be unchanged, not %v", saramaConfig.Version)
+}
+
+func TestNegotiateClientConfig_NotAuto(t *testing.T) {
+	saramaConfig := sarama.NewConfig()
+	clientConfig, err := negotiateClientConfig([]string{}, saramaConfig)
+	assert.Nil(t, err, "Expected negotiateClientConfig to return no error")
+	assert.True(t, clientConfig == saramaConfig, "Expected the config to be used as it is")
+}
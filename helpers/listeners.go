@@ -0,0 +1,127 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InheritedListenersEnv is the environment variable that a replacement Burrow process is started with during a
+// graceful restart. It is a comma-separated list of the names of the listeners that are passed to the process, in the
+// order of their file descriptors, starting at 3.
+const InheritedListenersEnv = "BURROW_INHERITED_LISTENERS"
+
+var listeners = struct {
+	lock      sync.Mutex
+	loaded    bool
+	inherited map[string]*os.File
+	active    map[string]*net.TCPListener
+}{
+	inherited: make(map[string]*os.File),
+	active:    make(map[string]*net.TCPListener),
+}
+
+// Listen returns a TCP listener for the given address, keeping track of it by name so that it can be passed to a
+// replacement process with GetListenerFiles. If this process was started by a graceful restart, and a listener with
+// the same name was passed to it, that listener is used instead, so that no connections are refused during the restart.
+func Listen(name, address string) (*net.TCPListener, error) {
+	listeners.lock.Lock()
+	defer listeners.lock.Unlock()
+	loadInheritedListeners()
+
+	var listener net.Listener
+	var err error
+	if file, ok := listeners.inherited[name]; ok {
+		delete(listeners.inherited, name)
+		listener, err = net.FileListener(file)
+		file.Close()
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		listener.Close()
+		return nil, &net.OpError{Op: "listen", Net: "tcp", Err: os.ErrInvalid}
+	}
+	listeners.active[name] = tcpListener
+	return tcpListener, nil
+}
+
+// ForgetListener stops keeping track of the named listener, which must be called when it is closed
+func ForgetListener(name string) {
+	listeners.lock.Lock()
+	defer listeners.lock.Unlock()
+	delete(listeners.active, name)
+}
+
+// CloseInheritedListeners closes any listeners that were passed to this process by a graceful restart that have not
+// been used, such as when a listener was removed from the configuration
+func CloseInheritedListeners() {
+	listeners.lock.Lock()
+	defer listeners.lock.Unlock()
+	loadInheritedListeners()
+	for name, file := range listeners.inherited {
+		file.Close()
+		delete(listeners.inherited, name)
+	}
+}
+
+// GetListenerFiles returns the names of the listeners that are open, sorted, and a copy of the file for each one that
+// can be passed to a replacement process. The files must be closed by the caller.
+func GetListenerFiles() ([]string, []*os.File, error) {
+	listeners.lock.Lock()
+	defer listeners.lock.Unlock()
+
+	names := make([]string, 0, len(listeners.active))
+	for name := range listeners.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]*os.File, 0, len(names))
+	for _, name := range names {
+		file, err := listeners.active[name].File()
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return nil, nil, err
+		}
+		files = append(files, file)
+	}
+	return names, files, nil
+}
+
+// loadInheritedListeners reads the listeners that were passed to this process, the first time it is called. The lock
+// must be held when this is called.
+func loadInheritedListeners() {
+	if listeners.loaded {
+		return
+	}
+	listeners.loaded = true
+
+	names := os.Getenv(InheritedListenersEnv)
+	if names == "" {
+		return
+	}
+	os.Unsetenv(InheritedListenersEnv)
+	for i, name := range strings.Split(names, ",") {
+		listeners.inherited[name] = os.NewFile(uintptr(3+i), name)
+	}
+}
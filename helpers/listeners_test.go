@@ -0,0 +1,41 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen(t *testing.T) {
+	listener, err := Listen("test.listener", "127.0.0.1:0")
+	assert.NoError(t, err, "Expected listener to start")
+	defer listener.Close()
+
+	names, files, err := GetListenerFiles()
+	assert.NoError(t, err, "Expected listener files to be returned")
+	assert.Equal(t, []string{"test.listener"}, names, "Expected the listener to be returned")
+	assert.Len(t, files, 1, "Expected one file")
+
+	// The file is a copy of the listening socket, which accepts connections to the same address
+	copied, err := net.FileListener(files[0])
+	assert.NoError(t, err, "Expected the file to be a listener")
+	files[0].Close()
+	defer copied.Close()
+	assert.Equal(t, listener.Addr().String(), copied.Addr().String(), "Expected the copy to have the same address")
+
+	ForgetListener("test.listener")
+	names, _, err = GetListenerFiles()
+	assert.NoError(t, err, "Expected listener files to be returned")
+	assert.Empty(t, names, "Expected the listener to be forgotten")
+}
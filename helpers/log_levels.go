@@ -0,0 +1,158 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// subsystemLogLevels holds the log level for each subsystem that has its own level, keyed by the subsystem name (the
+// name of the coordinator, such as "consumer" or "storage"). Subsystems that are not in the map use the default level.
+var subsystemLogLevels sync.Map
+
+// ParseLogLevel returns the zap level for a logging.level config value, and false if the value is not a valid level (in
+// which case, the info level is returned)
+func ParseLogLevel(logLevel string) (zapcore.Level, bool) {
+	switch strings.ToLower(logLevel) {
+	case "", "info":
+		return zap.InfoLevel, true
+	case "debug":
+		return zap.DebugLevel, true
+	case "warn":
+		return zap.WarnLevel, true
+	case "error":
+		return zap.ErrorLevel, true
+	case "panic":
+		return zap.PanicLevel, true
+	case "fatal":
+		return zap.FatalLevel, true
+	}
+	return zap.InfoLevel, false
+}
+
+// SetSubsystemLogLevel sets the log level for a single subsystem, overriding the default level
+func SetSubsystemLogLevel(subsystem string, level zapcore.Level) {
+	subsystemLogLevels.Store(subsystem, level)
+}
+
+// ClearSubsystemLogLevel removes the log level for the subsystem, so that it uses the default level again
+func ClearSubsystemLogLevel(subsystem string) {
+	subsystemLogLevels.Delete(subsystem)
+}
+
+// GetSubsystemLogLevels returns the name of the log level for each subsystem that has its own level
+func GetSubsystemLogLevels() map[string]string {
+	levels := make(map[string]string)
+	subsystemLogLevels.Range(func(key, value interface{}) bool {
+		levels[key.(string)] = value.(zapcore.Level).String()
+		return true
+	})
+	return levels
+}
+
+// LoadSubsystemLogLevels replaces the log levels for all subsystems with the ones in the logging.levels section of the
+// configuration, which maps the name of a subsystem to its level (for example, consumer = "debug"). If any of the
+// levels are not valid, an error is returned and the levels are not changed.
+func LoadSubsystemLogLevels(config *viper.Viper) error {
+	levels, err := getSubsystemLogLevels(config)
+	if err != nil {
+		return err
+	}
+	subsystemLogLevels.Range(func(key, _ interface{}) bool {
+		subsystemLogLevels.Delete(key)
+		return true
+	})
+	for subsystem, level := range levels {
+		subsystemLogLevels.Store(subsystem, level)
+	}
+	return nil
+}
+
+// ValidateSubsystemLogLevels checks that the levels in the logging.levels section of the configuration are valid,
+// without changing the levels that are in use
+func ValidateSubsystemLogLevels(config *viper.Viper) error {
+	_, err := getSubsystemLogLevels(config)
+	return err
+}
+
+func getSubsystemLogLevels(config *viper.Viper) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level)
+	for subsystem, levelName := range config.GetStringMapString("logging.levels") {
+		level, ok := ParseLogLevel(levelName)
+		if !ok {
+			return nil, errors.New("invalid log level for " + subsystem + ": " + levelName)
+		}
+		levels[subsystem] = level
+	}
+	return levels, nil
+}
+
+// subsystemLevelCore wraps a zapcore.Core, deciding whether each entry is logged using the level for the subsystem that
+// the logger belongs to, or the default level if the subsystem does not have its own level. The subsystem is found
+// from the fields added to the logger: the coordinator field for a module, or the name field for a coordinator.
+type subsystemLevelCore struct {
+	zapcore.Core
+	defaultLevel zap.AtomicLevel
+	subsystem    string
+}
+
+// NewSubsystemLevelCore returns a zapcore.Core that logs to the given core using the level for each subsystem, as set
+// with SetSubsystemLogLevel or LoadSubsystemLogLevels. The core that is wrapped must be enabled for all levels, as the
+// level is checked by the returned core. Subsystems without their own level use defaultLevel.
+func NewSubsystemLevelCore(core zapcore.Core, defaultLevel zap.AtomicLevel) zapcore.Core {
+	return &subsystemLevelCore{
+		Core:         core,
+		defaultLevel: defaultLevel,
+	}
+}
+
+func (c *subsystemLevelCore) Enabled(level zapcore.Level) bool {
+	if c.subsystem != "" {
+		if subsystemLevel, ok := subsystemLogLevels.Load(c.subsystem); ok {
+			return subsystemLevel.(zapcore.Level).Enabled(level)
+		}
+	}
+	return c.defaultLevel.Enabled(level)
+}
+
+func (c *subsystemLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	subsystem := c.subsystem
+	isCoordinator := false
+	for _, field := range fields {
+		if (field.Key == "type") && (field.String == "coordinator") {
+			isCoordinator = true
+		}
+	}
+	for _, field := range fields {
+		if (field.Key == "coordinator") || (isCoordinator && (field.Key == "name")) {
+			subsystem = field.String
+		}
+	}
+
+	return &subsystemLevelCore{
+		Core:         c.Core.With(fields),
+		defaultLevel: c.defaultLevel,
+		subsystem:    subsystem,
+	}
+}
+
+func (c *subsystemLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
@@ -0,0 +1,60 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSubsystemLevelCore(t *testing.T) {
+	viper.Reset()
+	viper.Set("logging.levels.consumer", "debug")
+	assert.NoError(t, LoadSubsystemLogLevels(viper.GetViper()), "Expected levels to load")
+	defer ClearSubsystemLogLevel("consumer")
+
+	observed, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(NewSubsystemLevelCore(observed, zap.NewAtomicLevelAt(zap.InfoLevel)))
+	consumerLogger := logger.With(zap.String("type", "coordinator"), zap.String("name", "consumer"))
+	moduleLogger := logger.With(zap.String("type", "module"), zap.String("coordinator", "consumer"), zap.String("name", "local"))
+	storageLogger := logger.With(zap.String("type", "coordinator"), zap.String("name", "storage"))
+
+	consumerLogger.Debug("consumer debug")
+	moduleLogger.Debug("module debug")
+	storageLogger.Debug("storage debug")
+	storageLogger.Info("storage info")
+
+	messages := make([]string, 0)
+	for _, entry := range logs.All() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"consumer debug", "module debug", "storage info"}, messages, "Expected only the consumer to log at debug")
+
+	assert.Equal(t, map[string]string{"consumer": "debug"}, GetSubsystemLogLevels(), "Expected the consumer level to be returned")
+	ClearSubsystemLogLevel("consumer")
+	consumerLogger.Debug("consumer debug after reset")
+	assert.Equal(t, 3, logs.Len(), "Expected the consumer to use the default level after reset")
+}
+
+func TestLoadSubsystemLogLevels_BadLevel(t *testing.T) {
+	viper.Reset()
+	SetSubsystemLogLevel("storage", zap.WarnLevel)
+	defer ClearSubsystemLogLevel("storage")
+	viper.Set("logging.levels.consumer", "loud")
+
+	assert.Error(t, ValidateSubsystemLogLevels(viper.GetViper()), "Expected an error for an invalid level")
+	assert.Error(t, LoadSubsystemLogLevels(viper.GetViper()), "Expected an error for an invalid level")
+	assert.Equal(t, map[string]string{"storage": "warn"}, GetSubsystemLogLevels(), "Expected levels to be unchanged")
+}
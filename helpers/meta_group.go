@@ -0,0 +1,23 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"github.com/spf13/viper"
+)
+
+// GetMetaGroup returns the name of the meta consumer group, from general.meta-group, or an empty string if it is not
+// enabled. The meta group is not a real consumer group: it exists in every cluster, and its status is Burrow's own
+// health for that cluster, as calculated by the evaluator. Modules that list the consumer groups in a cluster to
+// report their status should add it, so that Burrow's health is reported along with the groups.
+func GetMetaGroup() string {
+	return viper.GetString("general.meta-group")
+}
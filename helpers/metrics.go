@@ -0,0 +1,66 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// metricsRegistry holds Burrow's own metrics, such as request counts and channel depths. These are kept separately
+// from the client metrics that sarama records (see GetClientMetrics).
+//
+// Metrics are named with dots, starting with the coordinator for the metrics of a coordinator ("storage.requests"), or
+// with the config root of the module for the metrics of a module ("cluster.local.errors"). Durations are recorded in
+// histograms in microseconds, with names that end in "-time".
+var metricsRegistry = metrics.NewRegistry()
+
+// GetMetricsRegistry returns the registry that Burrow's own metrics are recorded in, for exporting them
+func GetMetricsRegistry() metrics.Registry {
+	return metricsRegistry
+}
+
+// GetMetricCounter returns the counter with the given name, creating it if it does not exist. Counters are kept when
+// a module is stopped, so they continue to count if the module is started again (such as after a reload).
+func GetMetricCounter(name string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(name, metricsRegistry)
+}
+
+// GetMetricGauge returns the gauge with the given name, creating it if it does not exist
+func GetMetricGauge(name string) metrics.Gauge {
+	return metrics.GetOrRegisterGauge(name, metricsRegistry)
+}
+
+// GetMetricHistogram returns the histogram with the given name, creating it if it does not exist. The histogram keeps
+// an exponentially decaying sample, so that its percentiles favor recent values.
+func GetMetricHistogram(name string) metrics.Histogram {
+	return metrics.GetOrRegisterHistogram(name, metricsRegistry, metrics.NewExpDecaySample(1028, 0.015))
+}
+
+// RegisterMetricGaugeFunc registers a gauge whose value is read from the func each time it is reported, such as the
+// depth of a channel. This replaces any metric with the same name, as the func usually refers to something that is
+// created again when a module is configured.
+func RegisterMetricGaugeFunc(name string, value func() int64) {
+	metricsRegistry.Unregister(name)
+	metricsRegistry.Register(name, metrics.NewFunctionalGauge(value))
+}
+
+// UpdateMetricTime records the time since start in the histogram, in microseconds
+func UpdateMetricTime(histogram metrics.Histogram, start time.Time) {
+	histogram.Update(int64(time.Since(start) / time.Microsecond))
+}
+
+// GetMetrics returns a snapshot of all of Burrow's own metrics, keyed by the metric name. Each metric is a map of its
+// values (for example, a histogram has count, min, max, mean, stddev, median, 75%, 95%, 99%, and 99.9%)
+func GetMetrics() map[string]map[string]interface{} {
+	return metricsRegistry.GetAll()
+}
@@ -0,0 +1,47 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestGetMetrics(t *testing.T) {
+	GetMetricCounter("test.counter").Inc(3)
+	assert.Equal(t, int64(3), GetMetricCounter("test.counter").Count(), "Expected the same counter to be returned")
+
+	GetMetricGauge("test.gauge").Update(7)
+	UpdateMetricTime(GetMetricHistogram("test.time"), time.Now().Add(-time.Millisecond))
+
+	metrics := GetMetrics()
+	assert.Equal(t, int64(3), metrics["test.counter"]["count"], "Expected the counter to be returned")
+	assert.Equal(t, int64(7), metrics["test.gauge"]["value"], "Expected the gauge to be returned")
+	assert.Equal(t, int64(1), metrics["test.time"]["count"], "Expected one value in the histogram")
+	assert.True(t, metrics["test.time"]["min"].(int64) >= 1000, "Expected the time in microseconds")
+}
+
+func TestRegisterMetricGaugeFunc(t *testing.T) {
+	RegisterMetricGaugeFunc("test.depth", func() int64 { return 1 })
+	RegisterMetricGaugeFunc("test.depth", func() int64 { return 2 })
+	assert.Equal(t, int64(2), GetMetrics()["test.depth"]["value"], "Expected the gauge func to be replaced")
+}
+
+func TestTimeoutSendStorageRequest_Metric(t *testing.T) {
+	before := GetMetricCounter("storage.send-timeouts").Count()
+	sent := TimeoutSendStorageRequest(make(chan *protocol.StorageRequest), &protocol.StorageRequest{}, 0)
+	assert.False(t, sent, "Expected the request to time out")
+	assert.Equal(t, before+1, GetMetricCounter("storage.send-timeouts").Count(), "Expected the timeout to be counted")
+}
@@ -0,0 +1,192 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+)
+
+// tokenRefreshMargin is how long before the expiration of a token that a new token will be requested
+const tokenRefreshMargin = 60 * time.Second
+
+// defaultTokenLifetime is how long a token is used for if the token endpoint does not say when it expires
+const defaultTokenLifetime = time.Hour
+
+// getOAuthTokenProvider returns a sarama.AccessTokenProvider for the named sasl profile, based on the configured
+// token-provider. The "client-credentials" provider (the default) requests tokens from an OAuth token endpoint, and
+// the "file" provider reads the token from a file that is kept up to date by something outside of Burrow. Any
+// configuration error will cause a panic.
+func getOAuthTokenProvider(saslName string) sarama.AccessTokenProvider {
+	configRoot := "sasl." + saslName
+	viper.SetDefault(configRoot+".token-provider", "client-credentials")
+	viper.SetDefault(configRoot+".token-timeout", 10)
+	viper.SetDefault(configRoot+".token-lifetime", 3600)
+
+	extensions := viper.GetStringMapString(configRoot + ".extensions")
+	providerName := viper.GetString(configRoot + ".token-provider")
+	switch providerName {
+	case "client-credentials":
+		tokenURL := viper.GetString(configRoot + ".token-url")
+		if _, err := url.ParseRequestURI(tokenURL); err != nil {
+			panic("bad or missing token-url in sasl profile " + saslName)
+		}
+		return &ClientCredentialsTokenProvider{
+			TokenURL:     tokenURL,
+			ClientID:     viper.GetString(configRoot + ".client-id"),
+			ClientSecret: viper.GetString(configRoot + ".client-secret"),
+			Scopes:       viper.GetStringSlice(configRoot + ".scopes"),
+			Extensions:   extensions,
+			HTTPClient:   &http.Client{Timeout: time.Duration(viper.GetInt(configRoot+".token-timeout")) * time.Second},
+			Lifetime:     time.Duration(viper.GetInt(configRoot+".token-lifetime")) * time.Second,
+		}
+	case "file":
+		tokenFile := viper.GetString(configRoot + ".token-file")
+		if tokenFile == "" {
+			panic("missing token-file in sasl profile " + saslName)
+		}
+		return &FileTokenProvider{
+			Filename:   tokenFile,
+			Extensions: extensions,
+		}
+	default:
+		panic("unknown token-provider '" + providerName + "' in sasl profile " + saslName)
+	}
+}
+
+// ClientCredentialsTokenProvider is a sarama.AccessTokenProvider that uses the OAuth 2.0 client credentials flow to
+// get tokens for SASL/OAUTHBEARER authentication. Tokens are cached, and a new token is requested shortly before the
+// current one expires.
+type ClientCredentialsTokenProvider struct {
+	// TokenURL is the URL of the token endpoint of the authorization server
+	TokenURL string
+
+	// ClientID and ClientSecret are the credentials that are sent to the token endpoint using HTTP basic auth
+	ClientID     string
+	ClientSecret string
+
+	// Scopes is an optional list of scopes to request
+	Scopes []string
+
+	// Extensions is an optional map of SASL extensions to send to the broker with the token
+	Extensions map[string]string
+
+	// HTTPClient is the client used to make requests to the token endpoint
+	HTTPClient *http.Client
+
+	// Lifetime is how long a token is used for if the response from the token endpoint has no expires_in. If it is not
+	// set, tokens without an expiration are used for an hour
+	Lifetime time.Duration
+
+	lock    sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Token returns the current access token, requesting a new one from the token endpoint if there is no token or the
+// current token is about to expire.
+func (p *ClientCredentialsTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if (p.token == "") || time.Now().Add(tokenRefreshMargin).After(p.expires) {
+		if err := p.refreshToken(); err != nil {
+			return nil, err
+		}
+	}
+	return &sarama.AccessToken{Token: p.token, Extensions: p.Extensions}, nil
+}
+
+func (p *ClientCredentialsTokenProvider) refreshToken() error {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+
+	request, err := http.NewRequest("POST", p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request failed with status %v", response.StatusCode)
+	}
+
+	tokenResponse := &oauthTokenResponse{}
+	if err := json.NewDecoder(response.Body).Decode(tokenResponse); err != nil {
+		return err
+	}
+	if tokenResponse.AccessToken == "" {
+		return errors.New("token response did not contain an access token")
+	}
+
+	// Without an expiration, the token would be requested again every time it is needed
+	lifetime := time.Duration(tokenResponse.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = p.Lifetime
+		if lifetime <= 0 {
+			lifetime = defaultTokenLifetime
+		}
+	}
+
+	p.token = tokenResponse.AccessToken
+	p.expires = time.Now().Add(lifetime)
+	return nil
+}
+
+// FileTokenProvider is a sarama.AccessTokenProvider that reads the token for SASL/OAUTHBEARER authentication from a
+// file. The file is read every time a token is needed, so it can be updated by an external process.
+type FileTokenProvider struct {
+	// Filename is the name of the file that contains the token
+	Filename string
+
+	// Extensions is an optional map of SASL extensions to send to the broker with the token
+	Extensions map[string]string
+}
+
+// Token returns the token that is currently in the token file
+func (p *FileTokenProvider) Token() (*sarama.AccessToken, error) {
+	contents, err := ioutil.ReadFile(p.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return nil, errors.New("token file " + p.Filename + " is empty")
+	}
+	return &sarama.AccessToken{Token: token, Extensions: p.Extensions}, nil
+}
@@ -0,0 +1,203 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCredentialsTokenProvider_ImplementsAccessTokenProvider(t *testing.T) {
+	assert.Implements(t, (*sarama.AccessTokenProvider)(nil), new(ClientCredentialsTokenProvider))
+}
+
+func TestFileTokenProvider_ImplementsAccessTokenProvider(t *testing.T) {
+	assert.Implements(t, (*sarama.AccessTokenProvider)(nil), new(FileTokenProvider))
+}
+
+// fixtureTokenServer returns a token endpoint that issues tokens that expire in expiresIn seconds. If expiresIn is 0,
+// the response does not have an expires_in
+func fixtureTokenServer(expiresIn int, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if (!ok) || (user != "testclient") || (pass != "testsecret") || (r.FormValue("grant_type") != "client_credentials") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		*requests++
+		if expiresIn == 0 {
+			fmt.Fprintf(w, `{"access_token":"token%v","token_type":"bearer"}`, *requests)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token%v","token_type":"bearer","expires_in":%v}`, *requests, expiresIn)
+	}))
+}
+
+func TestClientCredentialsTokenProvider_Token(t *testing.T) {
+	requests := 0
+	server := fixtureTokenServer(3600, &requests)
+	defer server.Close()
+
+	provider := &ClientCredentialsTokenProvider{
+		TokenURL:     server.URL,
+		ClientID:     "testclient",
+		ClientSecret: "testsecret",
+		Extensions:   map[string]string{"logicalCluster": "test"},
+		HTTPClient:   server.Client(),
+	}
+
+	token, err := provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "token1", token.Token, "Expected token to be token1, not %v", token.Token)
+	assert.Equalf(t, "test", token.Extensions["logicalCluster"], "Expected extension to be passed through, not %v", token.Extensions)
+
+	// The token is still valid, so it should be reused
+	token, err = provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "token1", token.Token, "Expected token to be token1, not %v", token.Token)
+	assert.Equalf(t, 1, requests, "Expected 1 token request, not %v", requests)
+}
+
+func TestClientCredentialsTokenProvider_Token_Refresh(t *testing.T) {
+	requests := 0
+	server := fixtureTokenServer(30, &requests)
+	defer server.Close()
+
+	provider := &ClientCredentialsTokenProvider{
+		TokenURL:     server.URL,
+		ClientID:     "testclient",
+		ClientSecret: "testsecret",
+		HTTPClient:   server.Client(),
+	}
+
+	// Tokens that expire within the refresh margin are requested again every time
+	provider.Token()
+	token, err := provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "token2", token.Token, "Expected token to be token2, not %v", token.Token)
+}
+
+func TestClientCredentialsTokenProvider_Token_NoExpiration(t *testing.T) {
+	requests := 0
+	server := fixtureTokenServer(0, &requests)
+	defer server.Close()
+
+	provider := &ClientCredentialsTokenProvider{
+		TokenURL:     server.URL,
+		ClientID:     "testclient",
+		ClientSecret: "testsecret",
+		HTTPClient:   server.Client(),
+	}
+
+	// Without expires_in, the token is used for the default lifetime
+	provider.Token()
+	token, err := provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "token1", token.Token, "Expected token to be token1, not %v", token.Token)
+	assert.Equalf(t, 1, requests, "Expected 1 token request, not %v", requests)
+	assert.WithinDuration(t, time.Now().Add(defaultTokenLifetime), provider.expires, time.Minute,
+		"Expected the token to expire after the default lifetime")
+
+	// A configured lifetime is used instead, and the token is still refreshed before it expires
+	provider = &ClientCredentialsTokenProvider{
+		TokenURL:     server.URL,
+		ClientID:     "testclient",
+		ClientSecret: "testsecret",
+		HTTPClient:   server.Client(),
+		Lifetime:     30 * time.Second,
+	}
+	provider.Token()
+	token, err = provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "token3", token.Token, "Expected token to be token3, not %v", token.Token)
+}
+
+func TestClientCredentialsTokenProvider_Token_BadCredentials(t *testing.T) {
+	requests := 0
+	server := fixtureTokenServer(3600, &requests)
+	defer server.Close()
+
+	provider := &ClientCredentialsTokenProvider{
+		TokenURL:     server.URL,
+		ClientID:     "testclient",
+		ClientSecret: "badsecret",
+		HTTPClient:   server.Client(),
+	}
+
+	token, err := provider.Token()
+	assert.Error(t, err, "Expected Token to return an error")
+	assert.Nil(t, token, "Expected token to be nil")
+}
+
+func TestFileTokenProvider_Token(t *testing.T) {
+	tokenFile, _ := ioutil.TempFile("", "burrow-token")
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("filetoken\n")
+	tokenFile.Close()
+
+	provider := &FileTokenProvider{Filename: tokenFile.Name()}
+	token, err := provider.Token()
+	assert.NoError(t, err, "Expected Token to return no error")
+	assert.Equalf(t, "filetoken", token.Token, "Expected token to be filetoken, not %v", token.Token)
+
+	provider = &FileTokenProvider{Filename: tokenFile.Name() + ".nosuchfile"}
+	_, err = provider.Token()
+	assert.Error(t, err, "Expected Token to return an error")
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLOAuth(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.kafka-version", "2.0.0")
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "OAUTHBEARER")
+	viper.Set("sasl.testsasl.token-url", "https://auth.example.com/token")
+	viper.Set("sasl.testsasl.client-id", "testclient")
+	viper.Set("sasl.testsasl.client-secret", "testsecret")
+	viper.Set("sasl.testsasl.scopes", []string{"kafka"})
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equalf(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), saramaConfig.Net.SASL.Mechanism, "Expected mechanism to be OAUTHBEARER, not %v", saramaConfig.Net.SASL.Mechanism)
+	assert.IsType(t, &ClientCredentialsTokenProvider{}, saramaConfig.Net.SASL.TokenProvider, "Expected client credentials token provider")
+	assert.NoError(t, saramaConfig.Validate(), "Expected sarama config to be valid")
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLOAuthFile(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "OAUTHBEARER")
+	viper.Set("sasl.testsasl.token-provider", "file")
+	viper.Set("sasl.testsasl.token-file", "/var/run/secrets/token")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.IsType(t, &FileTokenProvider{}, saramaConfig.Net.SASL.TokenProvider, "Expected file token provider")
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLOAuthBadConfig(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "OAUTHBEARER")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with no token-url")
+
+	viper.Set("sasl.testsasl.token-provider", "file")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with no token-file")
+
+	viper.Set("sasl.testsasl.token-provider", "nosuchprovider")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with bad token-provider")
+}
@@ -0,0 +1,473 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+)
+
+// The protocols that OTLPClient can send with, which are the values of otlp.protocol. These are the same as the values
+// of OTEL_EXPORTER_OTLP_PROTOCOL for the OpenTelemetry SDKs
+const (
+	// OTLP over HTTP, with JSON encoding
+	OTLPProtocolHTTPJSON = "http/json"
+
+	// OTLP over HTTP, with protobuf encoding
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+
+	// OTLP over gRPC. The collector's gRPC receiver normally listens on port 4317
+	OTLPProtocolGRPC = "grpc"
+)
+
+// The gRPC methods for the OTLP/HTTP paths that are exported to
+var otlpGRPCMethods = map[string]string{
+	"/v1/metrics": "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+	"/v1/traces":  "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+}
+
+// OTLPClient sends telemetry to an OpenTelemetry collector using OTLP, over HTTP with JSON or protobuf encoding, or
+// over gRPC. It is configured in the otlp section of the configuration, which is shared by everything in Burrow that
+// exports with OTLP:
+//
+// * endpoint - The base URL of the collector, such as http://localhost:4318 (required). For gRPC, the scheme is http
+// for a plaintext (h2c) connection, or https for TLS, such as http://localhost:4317
+//
+// * protocol - http/json, http/protobuf, or grpc (defaults to http/json)
+//
+// * headers - A map of HTTP headers to send with each request, such as for an API key
+//
+// * tls - The name of a tls profile to use for HTTPS
+//
+// * timeout - The number of seconds to wait for the collector to respond (defaults to 10)
+//
+// * service-name - The service.name resource attribute (defaults to burrow)
+//
+// * resource-attributes - A map of other resource attributes, such as deployment.environment
+type OTLPClient struct {
+	// URL is the base URL of the collector, with no trailing slash
+	URL string
+
+	// Protocol is the protocol to send with, which is one of the OTLPProtocol constants
+	Protocol string
+
+	// Headers are added to each request
+	Headers map[string]string
+
+	// HTTPClient is the client used to make requests
+	HTTPClient *http.Client
+
+	resource *otlpResource
+}
+
+// otlpAnyValue is the OTLP JSON encoding of an attribute value. 64-bit integers are encoded as strings
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// GetOTLPClient returns a client for the collector in the otlp section of the configuration. If there is any error in
+// the configuration, this func will panic, as it is normally called when Burrow is configured.
+func GetOTLPClient() *OTLPClient {
+	viper.SetDefault("otlp.timeout", 10)
+	viper.SetDefault("otlp.service-name", "burrow")
+	viper.SetDefault("otlp.protocol", OTLPProtocolHTTPJSON)
+
+	endpoint := strings.TrimSuffix(viper.GetString("otlp.endpoint"), "/")
+	parsedURL, err := url.Parse(endpoint)
+	if (endpoint == "") || (err != nil) || ((parsedURL.Scheme != "http") && (parsedURL.Scheme != "https")) || (parsedURL.Host == "") {
+		panic("bad or missing otlp.endpoint")
+	}
+
+	timeout := time.Duration(viper.GetInt("otlp.timeout")) * time.Second
+	var tlsConfig *tls.Config
+	if viper.IsSet("otlp.tls") {
+		tlsConfig = GetTLSConfigFromProfile(viper.GetString("otlp.tls"))
+	}
+	var transport http.RoundTripper
+	switch protocol := viper.GetString("otlp.protocol"); protocol {
+	case OTLPProtocolHTTPJSON, OTLPProtocolHTTPProtobuf:
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	case OTLPProtocolGRPC:
+		transport = newOTLPGRPCTransport(parsedURL.Scheme == "https", tlsConfig, timeout)
+	default:
+		panic("unknown otlp.protocol " + protocol)
+	}
+
+	attributes := map[string]interface{}{
+		"service.name": viper.GetString("otlp.service-name"),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attributes["host.name"] = hostname
+	}
+	addResourceAttributes(attributes, "", viper.GetStringMap("otlp.resource-attributes"))
+
+	return &OTLPClient{
+		URL:      endpoint,
+		Protocol: viper.GetString("otlp.protocol"),
+		Headers:  viper.GetStringMapString("otlp.headers"),
+		HTTPClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		resource: &otlpResource{Attributes: otlpAttributes(attributes)},
+	}
+}
+
+// addResourceAttributes adds the configured resource attributes to the map. Attribute names normally have dots in them
+// (such as deployment.environment), which viper splits into nested maps, so these are joined back together.
+func addResourceAttributes(attributes map[string]interface{}, prefix string, configured map[string]interface{}) {
+	for key, value := range configured {
+		if nested, ok := value.(map[string]interface{}); ok {
+			addResourceAttributes(attributes, prefix+key+".", nested)
+			continue
+		}
+		attributes[prefix+key] = fmt.Sprint(value)
+	}
+}
+
+// newOTLPGRPCTransport returns a transport for gRPC, which requires HTTP/2. For a plaintext endpoint, HTTP/2 is used
+// without TLS (h2c), as the collector's gRPC receiver expects.
+func newOTLPGRPCTransport(useTLS bool, tlsConfig *tls.Config, timeout time.Duration) http.RoundTripper {
+	if useTLS {
+		return &http2.Transport{TLSClientConfig: tlsConfig}
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, config *tls.Config) (net.Conn, error) {
+			return net.DialTimeout(network, addr, timeout)
+		},
+	}
+}
+
+// OTLPStatusError is the error returned by Export when the collector responds with a status other than 2xx (or for
+// gRPC, a status other than OK), so that the caller can tell a collector that rejected the request from one that could
+// not be reached
+type OTLPStatusError struct {
+	// StatusCode is the HTTP status code of the response. For gRPC, if the HTTP request succeeded, it is the gRPC
+	// status code instead, such as 14 for UNAVAILABLE
+	StatusCode int
+
+	// Status is the HTTP status line of the response, such as "503 Service Unavailable", or for gRPC, the gRPC status
+	// code and message
+	Status string
+}
+
+func (e *OTLPStatusError) Error() string {
+	return "unexpected response status " + e.Status
+}
+
+// Export sends the payload to the collector, at the OTLP/HTTP path given (such as /v1/traces), or for gRPC, with the
+// Export method of the service for that path. The payload is one of the OTLP request types, which is encoded for the
+// client's protocol. An error is returned if the collector does not accept it, which is an *OTLPStatusError if the
+// collector responded.
+func (client *OTLPClient) Export(path string, payload interface{}) error {
+	var target, contentType string
+	var body []byte
+	switch client.Protocol {
+	case OTLPProtocolHTTPProtobuf, OTLPProtocolGRPC:
+		message, ok := payload.(otlpProtoMessage)
+		if !ok {
+			return fmt.Errorf("cannot encode %T as protobuf", payload)
+		}
+		body = message.appendProto(nil)
+		target, contentType = client.URL+path, "application/x-protobuf"
+		if client.Protocol == OTLPProtocolGRPC {
+			method, ok := otlpGRPCMethods[path]
+			if !ok {
+				return errors.New("no gRPC method for " + path)
+			}
+
+			// Each gRPC message is prefixed with a byte for compression (none) and its length
+			framed := make([]byte, 5, 5+len(body))
+			binary.BigEndian.PutUint32(framed[1:], uint32(len(body)))
+			body = append(framed, body...)
+			target, contentType = client.URL+method, "application/grpc"
+		}
+	default:
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+		target, contentType = client.URL+path, "application/json"
+	}
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if client.Protocol == OTLPProtocolGRPC {
+		req.Header.Set("TE", "trailers")
+	}
+	for header, value := range client.Headers {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if (resp.StatusCode < 200) || (resp.StatusCode > 299) {
+		return &OTLPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if client.Protocol == OTLPProtocolGRPC {
+		return checkGRPCStatus(resp)
+	}
+	return nil
+}
+
+// checkGRPCStatus returns an *OTLPStatusError if the gRPC status of the response is not OK. The status is in the
+// trailers, which are only read after the body, or in the headers if the response has no body.
+func checkGRPCStatus(resp *http.Response) error {
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		return err
+	}
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return errors.New("response has no gRPC status")
+	}
+	if code != 0 {
+		if unescaped, err := url.PathUnescape(message); err == nil {
+			message = unescaped
+		}
+		return &OTLPStatusError{StatusCode: code, Status: "gRPC status " + status + ": " + message}
+	}
+	return nil
+}
+
+// otlpAttributes converts a map of attributes to OTLP key-values, sorted by key. Values of types that OTLP does not
+// have are converted to strings.
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		keyValue := otlpKeyValue{Key: key}
+		switch typedValue := value.(type) {
+		case string:
+			keyValue.Value.StringValue = &typedValue
+		case bool:
+			keyValue.Value.BoolValue = &typedValue
+		case int:
+			intValue := strconv.Itoa(typedValue)
+			keyValue.Value.IntValue = &intValue
+		case int32:
+			intValue := strconv.FormatInt(int64(typedValue), 10)
+			keyValue.Value.IntValue = &intValue
+		case int64:
+			intValue := strconv.FormatInt(typedValue, 10)
+			keyValue.Value.IntValue = &intValue
+		case float64:
+			keyValue.Value.DoubleValue = &typedValue
+		default:
+			stringValue := fmt.Sprint(typedValue)
+			keyValue.Value.StringValue = &stringValue
+		}
+		keyValues = append(keyValues, keyValue)
+	}
+	sort.Slice(keyValues, func(i, j int) bool { return keyValues[i].Key < keyValues[j].Key })
+	return keyValues
+}
+
+// OTLPMetricType is the kind of an OTLPMetric, which decides how its data points are sent
+type OTLPMetricType int
+
+// The kinds of metric that can be exported
+const (
+	// The value of each data point is the current value
+	OTLPGauge OTLPMetricType = iota
+
+	// The value of each data point is the total since the start time given to ExportMetrics
+	OTLPCounter
+
+	// Each data point has a count, a sum, and quantiles, from a histogram
+	OTLPSummary
+)
+
+// OTLPMetric is a single metric to export with ExportMetrics
+type OTLPMetric struct {
+	Name   string
+	Unit   string
+	Type   OTLPMetricType
+	Points []OTLPDataPoint
+}
+
+// OTLPDataPoint is a single value of an OTLPMetric, such as the lag of one consumer group. Value is used for gauges and
+// counters, and Count, Sum, and Quantiles (keyed by the quantile, from 0 to 1) for summaries.
+type OTLPDataPoint struct {
+	Attributes map[string]interface{}
+	Value      int64
+	Count      int64
+	Sum        float64
+	Quantiles  map[float64]float64
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     *otlpResource      `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name    string       `json:"name"`
+	Unit    string       `json:"unit,omitempty"`
+	Gauge   *otlpGauge   `json:"gauge,omitempty"`
+	Sum     *otlpSum     `json:"sum,omitempty"`
+	Summary *otlpSummary `json:"summary,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryDataPoint `json:"dataPoints"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsInt             string         `json:"asInt"`
+}
+
+type otlpSummaryDataPoint struct {
+	Attributes        []otlpKeyValue      `json:"attributes,omitempty"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	TimeUnixNano      string              `json:"timeUnixNano"`
+	Count             string              `json:"count"`
+	Sum               float64             `json:"sum"`
+	QuantileValues    []otlpQuantileValue `json:"quantileValues"`
+}
+
+type otlpQuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// The OTLP aggregation temporality for a sum that is the total since a fixed start time
+const otlpTemporalityCumulative = 2
+
+// ExportMetrics sends the metrics to the collector, as measured now. Counters and summaries are totals since the start
+// time. The scope is the name of what is exporting the metrics, such as the module. An error is returned if the
+// collector does not accept them.
+func (client *OTLPClient) ExportMetrics(scope string, start time.Time, metrics []*OTLPMetric) error {
+	startTime := strconv.FormatInt(start.UnixNano(), 10)
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	exported := make([]otlpMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		exportedMetric := otlpMetric{Name: metric.Name, Unit: metric.Unit}
+		switch metric.Type {
+		case OTLPGauge, OTLPCounter:
+			points := make([]otlpNumberDataPoint, 0, len(metric.Points))
+			for _, point := range metric.Points {
+				exportedPoint := otlpNumberDataPoint{
+					Attributes:   otlpAttributes(point.Attributes),
+					TimeUnixNano: now,
+					AsInt:        strconv.FormatInt(point.Value, 10),
+				}
+				if metric.Type == OTLPCounter {
+					exportedPoint.StartTimeUnixNano = startTime
+				}
+				points = append(points, exportedPoint)
+			}
+			if metric.Type == OTLPGauge {
+				exportedMetric.Gauge = &otlpGauge{DataPoints: points}
+			} else {
+				exportedMetric.Sum = &otlpSum{
+					DataPoints:             points,
+					AggregationTemporality: otlpTemporalityCumulative,
+					IsMonotonic:            true,
+				}
+			}
+		case OTLPSummary:
+			points := make([]otlpSummaryDataPoint, 0, len(metric.Points))
+			for _, point := range metric.Points {
+				quantiles := make([]otlpQuantileValue, 0, len(point.Quantiles))
+				for quantile, value := range point.Quantiles {
+					quantiles = append(quantiles, otlpQuantileValue{Quantile: quantile, Value: value})
+				}
+				sort.Slice(quantiles, func(i, j int) bool { return quantiles[i].Quantile < quantiles[j].Quantile })
+				points = append(points, otlpSummaryDataPoint{
+					Attributes:        otlpAttributes(point.Attributes),
+					StartTimeUnixNano: startTime,
+					TimeUnixNano:      now,
+					Count:             strconv.FormatInt(point.Count, 10),
+					Sum:               point.Sum,
+					QuantileValues:    quantiles,
+				})
+			}
+			exportedMetric.Summary = &otlpSummary{DataPoints: points}
+		}
+		exported = append(exported, exportedMetric)
+	}
+
+	return client.Export("/v1/metrics", &otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: client.resource,
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: scope},
+				Metrics: exported,
+			}},
+		}},
+	})
+}
@@ -0,0 +1,287 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+)
+
+// The OTLP requests are encoded as protobuf for the grpc and http/protobuf protocols. Each message has an appendProto
+// func that appends its encoding, using the field numbers from the OTLP .proto files (opentelemetry-proto v1). As in
+// proto3, fields with default values are left out, except for fields in a oneof, which are always sent when they are
+// set.
+
+// otlpProtoMessage is an OTLP request that can be encoded as protobuf
+type otlpProtoMessage interface {
+	appendProto(b []byte) []byte
+}
+
+// The protobuf wire types that are used
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+func appendProtoVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+func appendProtoTag(b []byte, field int, wireType int) []byte {
+	return appendProtoVarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = appendProtoTag(b, field, protoWireBytes)
+	b = appendProtoVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendProtoString(b []byte, field int, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = appendProtoTag(b, field, protoWireBytes)
+	b = appendProtoVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendProtoUvarint(b []byte, field int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	return appendProtoVarint(appendProtoTag(b, field, protoWireVarint), value)
+}
+
+func appendProtoFixed64(b []byte, field int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoWireFixed64)
+	var encoded [8]byte
+	binary.LittleEndian.PutUint64(encoded[:], value)
+	return append(b, encoded[:]...)
+}
+
+func appendProtoDouble(b []byte, field int, value float64) []byte {
+	return appendProtoFixed64(b, field, math.Float64bits(value))
+}
+
+// appendProtoMessage appends the message as a field, even if it is empty
+func appendProtoMessage(b []byte, field int, message otlpProtoMessage) []byte {
+	return appendProtoBytes(b, field, message.appendProto(nil))
+}
+
+// parseProtoNano parses a time or count that is kept as a string for the JSON encoding. These are always formatted by
+// Burrow, so they are valid
+func parseProtoNano(value string) uint64 {
+	parsed, _ := strconv.ParseUint(value, 10, 64)
+	return parsed
+}
+
+// parseProtoID decodes a trace or span ID, which is kept as hex for the JSON encoding, to the bytes that protobuf uses
+func parseProtoID(id string) []byte {
+	decoded, _ := hex.DecodeString(id)
+	return decoded
+}
+
+func (value *otlpAnyValue) appendProto(b []byte) []byte {
+	switch {
+	case value.StringValue != nil:
+		b = appendProtoBytes(b, 1, []byte(*value.StringValue))
+	case value.BoolValue != nil:
+		b = appendProtoTag(b, 2, protoWireVarint)
+		if *value.BoolValue {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	case value.IntValue != nil:
+		intValue, _ := strconv.ParseInt(*value.IntValue, 10, 64)
+		b = appendProtoVarint(appendProtoTag(b, 3, protoWireVarint), uint64(intValue))
+	case value.DoubleValue != nil:
+		b = appendProtoTag(b, 4, protoWireFixed64)
+		var encoded [8]byte
+		binary.LittleEndian.PutUint64(encoded[:], math.Float64bits(*value.DoubleValue))
+		b = append(b, encoded[:]...)
+	}
+	return b
+}
+
+func (keyValue *otlpKeyValue) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 1, keyValue.Key)
+	return appendProtoMessage(b, 2, &keyValue.Value)
+}
+
+func appendProtoAttributes(b []byte, field int, attributes []otlpKeyValue) []byte {
+	for i := range attributes {
+		b = appendProtoMessage(b, field, &attributes[i])
+	}
+	return b
+}
+
+func (resource *otlpResource) appendProto(b []byte) []byte {
+	return appendProtoAttributes(b, 1, resource.Attributes)
+}
+
+func (scope *otlpScope) appendProto(b []byte) []byte {
+	return appendProtoString(b, 1, scope.Name)
+}
+
+// ExportMetricsServiceRequest
+func (request *otlpMetricsRequest) appendProto(b []byte) []byte {
+	for i := range request.ResourceMetrics {
+		b = appendProtoMessage(b, 1, &request.ResourceMetrics[i])
+	}
+	return b
+}
+
+func (resourceMetrics *otlpResourceMetrics) appendProto(b []byte) []byte {
+	if resourceMetrics.Resource != nil {
+		b = appendProtoMessage(b, 1, resourceMetrics.Resource)
+	}
+	for i := range resourceMetrics.ScopeMetrics {
+		b = appendProtoMessage(b, 2, &resourceMetrics.ScopeMetrics[i])
+	}
+	return b
+}
+
+func (scopeMetrics *otlpScopeMetrics) appendProto(b []byte) []byte {
+	b = appendProtoMessage(b, 1, &scopeMetrics.Scope)
+	for i := range scopeMetrics.Metrics {
+		b = appendProtoMessage(b, 2, &scopeMetrics.Metrics[i])
+	}
+	return b
+}
+
+func (metric *otlpMetric) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 1, metric.Name)
+	b = appendProtoString(b, 3, metric.Unit)
+	switch {
+	case metric.Gauge != nil:
+		b = appendProtoMessage(b, 5, metric.Gauge)
+	case metric.Sum != nil:
+		b = appendProtoMessage(b, 7, metric.Sum)
+	case metric.Summary != nil:
+		b = appendProtoMessage(b, 11, metric.Summary)
+	}
+	return b
+}
+
+func (gauge *otlpGauge) appendProto(b []byte) []byte {
+	for i := range gauge.DataPoints {
+		b = appendProtoMessage(b, 1, &gauge.DataPoints[i])
+	}
+	return b
+}
+
+func (sum *otlpSum) appendProto(b []byte) []byte {
+	for i := range sum.DataPoints {
+		b = appendProtoMessage(b, 1, &sum.DataPoints[i])
+	}
+	b = appendProtoUvarint(b, 2, uint64(sum.AggregationTemporality))
+	if sum.IsMonotonic {
+		b = appendProtoUvarint(b, 3, 1)
+	}
+	return b
+}
+
+func (summary *otlpSummary) appendProto(b []byte) []byte {
+	for i := range summary.DataPoints {
+		b = appendProtoMessage(b, 1, &summary.DataPoints[i])
+	}
+	return b
+}
+
+func (point *otlpNumberDataPoint) appendProto(b []byte) []byte {
+	b = appendProtoFixed64(b, 2, parseProtoNano(point.StartTimeUnixNano))
+	b = appendProtoFixed64(b, 3, parseProtoNano(point.TimeUnixNano))
+
+	// as_int is in a oneof, so it is sent even if it is zero
+	asInt, _ := strconv.ParseInt(point.AsInt, 10, 64)
+	b = appendProtoTag(b, 6, protoWireFixed64)
+	var encoded [8]byte
+	binary.LittleEndian.PutUint64(encoded[:], uint64(asInt))
+	b = append(b, encoded[:]...)
+
+	return appendProtoAttributes(b, 7, point.Attributes)
+}
+
+func (point *otlpSummaryDataPoint) appendProto(b []byte) []byte {
+	b = appendProtoFixed64(b, 2, parseProtoNano(point.StartTimeUnixNano))
+	b = appendProtoFixed64(b, 3, parseProtoNano(point.TimeUnixNano))
+	b = appendProtoFixed64(b, 4, parseProtoNano(point.Count))
+	b = appendProtoDouble(b, 5, point.Sum)
+	for i := range point.QuantileValues {
+		b = appendProtoMessage(b, 6, &point.QuantileValues[i])
+	}
+	return appendProtoAttributes(b, 7, point.Attributes)
+}
+
+func (quantile *otlpQuantileValue) appendProto(b []byte) []byte {
+	b = appendProtoDouble(b, 1, quantile.Quantile)
+	return appendProtoDouble(b, 2, quantile.Value)
+}
+
+// ExportTraceServiceRequest
+func (request *otlpTracesRequest) appendProto(b []byte) []byte {
+	for i := range request.ResourceSpans {
+		b = appendProtoMessage(b, 1, &request.ResourceSpans[i])
+	}
+	return b
+}
+
+func (resourceSpans *otlpResourceSpans) appendProto(b []byte) []byte {
+	if resourceSpans.Resource != nil {
+		b = appendProtoMessage(b, 1, resourceSpans.Resource)
+	}
+	for i := range resourceSpans.ScopeSpans {
+		b = appendProtoMessage(b, 2, &resourceSpans.ScopeSpans[i])
+	}
+	return b
+}
+
+func (scopeSpans *otlpScopeSpans) appendProto(b []byte) []byte {
+	b = appendProtoMessage(b, 1, &scopeSpans.Scope)
+	for i := range scopeSpans.Spans {
+		b = appendProtoMessage(b, 2, &scopeSpans.Spans[i])
+	}
+	return b
+}
+
+func (span *otlpSpan) appendProto(b []byte) []byte {
+	b = appendProtoBytes(b, 1, parseProtoID(span.TraceID))
+	b = appendProtoBytes(b, 2, parseProtoID(span.SpanID))
+	if span.ParentSpanID != "" {
+		b = appendProtoBytes(b, 4, parseProtoID(span.ParentSpanID))
+	}
+	b = appendProtoString(b, 5, span.Name)
+	b = appendProtoUvarint(b, 6, uint64(span.Kind))
+	b = appendProtoFixed64(b, 7, parseProtoNano(span.StartTimeUnixNano))
+	b = appendProtoFixed64(b, 8, parseProtoNano(span.EndTimeUnixNano))
+	b = appendProtoAttributes(b, 9, span.Attributes)
+	if span.Status != nil {
+		b = appendProtoMessage(b, 15, span.Status)
+	}
+	return b
+}
+
+func (status *otlpStatus) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 2, status.Message)
+	return appendProtoUvarint(b, 3, uint64(status.Code))
+}
@@ -0,0 +1,208 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// protoField is a single field of a protobuf message, as read by decodeProto. Varint and fixed64 fields are in value,
+// and length-delimited fields (strings, bytes, and messages) are in bytes
+type protoField struct {
+	value uint64
+	bytes []byte
+}
+
+// decodeProto reads the fields of a protobuf message, by field number
+func decodeProto(t *testing.T, message []byte) map[int][]protoField {
+	fields := make(map[int][]protoField)
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		message = message[n:]
+		var field protoField
+		switch tag & 7 {
+		case protoWireVarint:
+			field.value, n = binary.Uvarint(message)
+			message = message[n:]
+		case protoWireFixed64:
+			field.value = binary.LittleEndian.Uint64(message)
+			message = message[8:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(message)
+			field.bytes = message[n : n+int(length)]
+			message = message[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %v", tag&7)
+		}
+		fields[int(tag>>3)] = append(fields[int(tag>>3)], field)
+	}
+	return fields
+}
+
+func fixtureOTLPMetrics() []*OTLPMetric {
+	return []*OTLPMetric{
+		{
+			Name:   "burrow.group.lag",
+			Type:   OTLPGauge,
+			Points: []OTLPDataPoint{{Attributes: map[string]interface{}{"group": "testgroup"}, Value: 42}},
+		},
+		{
+			Name:   "burrow.requests",
+			Type:   OTLPCounter,
+			Points: []OTLPDataPoint{{Value: 0}},
+		},
+		{
+			Name:   "burrow.send-time",
+			Type:   OTLPSummary,
+			Points: []OTLPDataPoint{{Count: 2, Sum: 3.5, Quantiles: map[float64]float64{0.5: 1.5}}},
+		},
+	}
+}
+
+// assertOTLPMetricsProto checks the protobuf encoding of the request for fixtureOTLPMetrics
+func assertOTLPMetricsProto(t *testing.T, request []byte) {
+	resourceMetrics := decodeProto(t, decodeProto(t, request)[1][0].bytes)
+	resource := decodeProto(t, resourceMetrics[1][0].bytes)
+	assert.NotEmpty(t, resource[1], "Expected resource attributes")
+
+	scopeMetrics := decodeProto(t, resourceMetrics[2][0].bytes)
+	assert.Equal(t, "testscope", string(decodeProto(t, scopeMetrics[1][0].bytes)[1][0].bytes), "Expected the scope name")
+	metrics := scopeMetrics[2]
+	assert.Len(t, metrics, 3, "Expected three metrics")
+
+	gauge := decodeProto(t, metrics[0].bytes)
+	assert.Equal(t, "burrow.group.lag", string(gauge[1][0].bytes), "Expected the gauge name")
+	point := decodeProto(t, decodeProto(t, gauge[5][0].bytes)[1][0].bytes)
+	assert.Equal(t, uint64(42), point[6][0].value, "Expected the gauge value")
+	assert.NotZero(t, point[3][0].value, "Expected the time of the data point")
+	attribute := decodeProto(t, point[7][0].bytes)
+	assert.Equal(t, "group", string(attribute[1][0].bytes), "Expected the attribute key")
+	assert.Equal(t, "testgroup", string(decodeProto(t, attribute[2][0].bytes)[1][0].bytes), "Expected the attribute value")
+
+	sum := decodeProto(t, decodeProto(t, metrics[1].bytes)[7][0].bytes)
+	assert.Equal(t, uint64(otlpTemporalityCumulative), sum[2][0].value, "Expected cumulative temporality")
+	assert.Equal(t, uint64(1), sum[3][0].value, "Expected a monotonic sum")
+	point = decodeProto(t, sum[1][0].bytes)
+	assert.Len(t, point[6], 1, "Expected the counter value to be sent even though it is zero")
+	assert.NotZero(t, point[2][0].value, "Expected the start time of the counter")
+
+	summaryPoint := decodeProto(t, decodeProto(t, decodeProto(t, metrics[2].bytes)[11][0].bytes)[1][0].bytes)
+	assert.Equal(t, uint64(2), summaryPoint[4][0].value, "Expected the summary count")
+	assert.Equal(t, 3.5, math.Float64frombits(summaryPoint[5][0].value), "Expected the summary sum")
+	quantile := decodeProto(t, summaryPoint[6][0].bytes)
+	assert.Equal(t, 0.5, math.Float64frombits(quantile[1][0].value), "Expected the quantile")
+	assert.Equal(t, 1.5, math.Float64frombits(quantile[2][0].value), "Expected the quantile value")
+}
+
+func fixtureOTLPClient(endpoint, protocol string) *OTLPClient {
+	viper.Reset()
+	viper.Set("otlp.endpoint", endpoint)
+	viper.Set("otlp.protocol", protocol)
+	return GetOTLPClient()
+}
+
+func TestGetOTLPClient_BadProtocol(t *testing.T) {
+	assert.Panics(t, func() { fixtureOTLPClient("http://localhost:4317", "thrift") }, "Expected panic for an unknown protocol")
+}
+
+func TestOTLPClient_ExportMetrics_HTTPProtobuf(t *testing.T) {
+	requests := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path, "Expected metrics to be sent to /v1/metrics")
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"), "Expected a protobuf request")
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- body
+	}))
+	defer collector.Close()
+
+	client := fixtureOTLPClient(collector.URL, OTLPProtocolHTTPProtobuf)
+	assert.NoError(t, client.ExportMetrics("testscope", time.Now(), fixtureOTLPMetrics()), "Expected the metrics to be sent")
+	assertOTLPMetricsProto(t, <-requests)
+}
+
+// fixtureGRPCCollector returns a collector that accepts gRPC over HTTP/2 without TLS, and responds with the gRPC status
+func fixtureGRPCCollector(t *testing.T, status string, requests chan []byte) *httptest.Server {
+	return httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor, "Expected HTTP/2")
+		assert.Equal(t, "application/grpc", r.Header.Get("Content-Type"), "Expected a gRPC request")
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"), "Expected the configured header to be sent")
+		body, _ := ioutil.ReadAll(r.Body)
+		if assert.True(t, len(body) >= 5, "Expected a gRPC message") {
+			assert.Equal(t, byte(0), body[0], "Expected an uncompressed message")
+			assert.Equal(t, uint32(len(body)-5), binary.BigEndian.Uint32(body[1:5]), "Expected the message length")
+			requests <- body[5:]
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", status)
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "collector%20unavailable")
+	}), &http2.Server{}))
+}
+
+func TestOTLPClient_ExportMetrics_GRPC(t *testing.T) {
+	requests := make(chan []byte, 1)
+	collector := fixtureGRPCCollector(t, "0", requests)
+	defer collector.Close()
+
+	client := fixtureOTLPClient(collector.URL, OTLPProtocolGRPC)
+	client.Headers["x-api-key"] = "secret"
+	assert.NoError(t, client.ExportMetrics("testscope", time.Now(), fixtureOTLPMetrics()), "Expected the metrics to be sent")
+	assertOTLPMetricsProto(t, <-requests)
+}
+
+func TestOTLPClient_Export_GRPCError(t *testing.T) {
+	requests := make(chan []byte, 1)
+	collector := fixtureGRPCCollector(t, "14", requests)
+	defer collector.Close()
+
+	client := fixtureOTLPClient(collector.URL, OTLPProtocolGRPC)
+	client.Headers["x-api-key"] = "secret"
+	err := client.ExportMetrics("testscope", time.Now(), fixtureOTLPMetrics())
+	if assert.IsType(t, &OTLPStatusError{}, err, "Expected a status error") {
+		assert.Equal(t, 14, err.(*OTLPStatusError).StatusCode, "Expected the gRPC status code")
+		assert.Equal(t, "gRPC status 14: collector unavailable", err.(*OTLPStatusError).Status, "Expected the gRPC message")
+	}
+}
+
+func TestOTLPSpan_Proto(t *testing.T) {
+	span := &otlpSpan{
+		TraceID:           "0af7651916cd43dd8448eb211c80319c",
+		SpanID:            "b7ad6b7169203331",
+		Name:              "HTTP GET",
+		Kind:              SpanKindServer,
+		StartTimeUnixNano: "1000",
+		EndTimeUnixNano:   "2000",
+		Status:            &otlpStatus{Code: otlpStatusCodeError, Message: "failed"},
+	}
+	fields := decodeProto(t, span.appendProto(nil))
+	assert.Equal(t, []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c}, fields[1][0].bytes, "Expected the trace ID as bytes")
+	assert.Len(t, fields[2][0].bytes, 8, "Expected the span ID as bytes")
+	assert.Empty(t, fields[4], "Expected no parent span ID")
+	assert.Equal(t, "HTTP GET", string(fields[5][0].bytes), "Expected the span name")
+	assert.Equal(t, uint64(SpanKindServer), fields[6][0].value, "Expected the span kind")
+	assert.Equal(t, uint64(1000), fields[7][0].value, "Expected the start time")
+	assert.Equal(t, uint64(2000), fields[8][0].value, "Expected the end time")
+	status := decodeProto(t, fields[15][0].bytes)
+	assert.Equal(t, "failed", string(status[2][0].bytes), "Expected the status message")
+	assert.Equal(t, uint64(otlpStatusCodeError), status[3][0].value, "Expected the status code")
+}
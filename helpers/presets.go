@@ -0,0 +1,126 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"crypto/tls"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+)
+
+// clientProfilePreset holds the settings for a hosted Kafka service that are applied to a client profile when it sets
+// the preset config. A preset sets up the security configs that the service requires, so the profile does not need
+// separate tls and sasl profiles, and checks that the rest of the profile is compatible with the service.
+type clientProfilePreset struct {
+	// kafkaVersion is the default kafka-version for the profile
+	kafkaVersion string
+
+	// configure is called after the rest of the client profile has been applied to the sarama.Config. It must panic if
+	// the profile is not valid for the service
+	configure func(saramaConfig *sarama.Config, profileName string)
+}
+
+var clientProfilePresets = map[string]*clientProfilePreset{
+	"confluent-cloud": {
+		kafkaVersion: "2.6.0",
+		configure:    configureConfluentCloud,
+	},
+	"azure-event-hubs": {
+		kafkaVersion: "1.0.0",
+		configure:    configureAzureEventHubs,
+	},
+}
+
+// getClientProfilePreset returns the preset named in the client profile, or nil if no preset is set. If the preset is
+// unknown, this func will panic.
+func getClientProfilePreset(profileName string) *clientProfilePreset {
+	presetName := viper.GetString("client-profile." + profileName + ".preset")
+	if presetName == "" {
+		return nil
+	}
+
+	preset, ok := clientProfilePresets[presetName]
+	if !ok {
+		panic("unknown preset '" + presetName + "' in client-profile " + profileName)
+	}
+	return preset
+}
+
+// configureConfluentCloud sets up a client profile for Confluent Cloud, which requires TLS and SASL PLAIN, with an API
+// key and secret as the username and password. The API key and secret are given as api-key and api-secret in the
+// client profile. A tls profile may be used (for example, to set a CA), but it may not disable certificate
+// verification. Metadata is refreshed more often than the sarama default, as brokers are replaced during maintenance.
+func configureConfluentCloud(saramaConfig *sarama.Config, profileName string) {
+	configRoot := "client-profile." + profileName
+
+	apiKey := viper.GetString(configRoot + ".api-key")
+	apiSecret := viper.GetString(configRoot + ".api-secret")
+	if (apiKey == "") || (apiSecret == "") {
+		panic("client-profile " + profileName + " uses the confluent-cloud preset, and must have an api-key and api-secret")
+	}
+
+	configurePresetSASLPlain(saramaConfig, profileName, "confluent-cloud", apiKey, apiSecret)
+	saramaConfig.Metadata.RefreshFrequency = 5 * time.Minute
+	saramaConfig.Metadata.Retry.Max = 5
+	saramaConfig.Net.KeepAlive = 30 * time.Second
+}
+
+// configureAzureEventHubs sets up a client profile for the Kafka endpoint of an Azure Event Hubs namespace, which
+// requires TLS and SASL PLAIN, with the literal username $ConnectionString and the namespace connection string as the
+// password. The connection string is given as connection-string in the client profile. Event Hubs closes connections
+// that have been idle for 240 seconds, so metadata is refreshed more often than that, and requests are given longer to
+// complete, as recommended by Microsoft.
+//
+// Event Hubs does not allow reading __consumer_offsets, so consumer groups must be fetched with the event_hubs (or
+// kafka_admin) consumer module.
+func configureAzureEventHubs(saramaConfig *sarama.Config, profileName string) {
+	configRoot := "client-profile." + profileName
+
+	connectionString := viper.GetString(configRoot + ".connection-string")
+	if !strings.HasPrefix(connectionString, "Endpoint=sb://") {
+		panic("client-profile " + profileName + " uses the azure-event-hubs preset, and must have a connection-string starting with Endpoint=sb://")
+	}
+
+	configurePresetSASLPlain(saramaConfig, profileName, "azure-event-hubs", "$ConnectionString", connectionString)
+	saramaConfig.Metadata.RefreshFrequency = 180 * time.Second
+	saramaConfig.Net.ReadTimeout = 60 * time.Second
+	saramaConfig.Net.KeepAlive = 30 * time.Second
+}
+
+// configurePresetSASLPlain enables TLS (with verification) and SASL PLAIN with the given username and password, which
+// is how most hosted Kafka services authenticate clients. The profile may not have its own sasl profile, and must have
+// a kafka-version of at least 1.0.0 to use version 1 of the SASL handshake.
+func configurePresetSASLPlain(saramaConfig *sarama.Config, profileName, presetName, username, password string) {
+	if viper.IsSet("client-profile." + profileName + ".sasl") {
+		panic("client-profile " + profileName + " uses the " + presetName + " preset, and cannot also have a sasl profile")
+	}
+	if !saramaConfig.Version.IsAtLeast(sarama.V1_0_0_0) {
+		panic("client-profile " + profileName + " uses the " + presetName + " preset, which requires a kafka-version of at least 1.0.0")
+	}
+
+	if saramaConfig.Net.TLS.Config == nil {
+		saramaConfig.Net.TLS.Config = &tls.Config{}
+	} else if saramaConfig.Net.TLS.Config.InsecureSkipVerify {
+		panic("client-profile " + profileName + " uses the " + presetName + " preset, and cannot disable TLS verification")
+	}
+	saramaConfig.Net.TLS.Enable = true
+	saramaConfig.Net.TLS.Config.MinVersion = tls.VersionTLS12
+
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	saramaConfig.Net.SASL.Version = sarama.SASLHandshakeV1
+	saramaConfig.Net.SASL.Handshake = true
+	saramaConfig.Net.SASL.User = username
+	saramaConfig.Net.SASL.Password = password
+}
@@ -0,0 +1,90 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureConfluentCloudProfile() {
+	viper.Reset()
+	viper.Set("client-profile.test.preset", "confluent-cloud")
+	viper.Set("client-profile.test.api-key", "testkey")
+	viper.Set("client-profile.test.api-secret", "testsecret")
+}
+
+func TestGetSaramaConfigFromClientProfile_ConfluentCloud(t *testing.T) {
+	fixtureConfluentCloudProfile()
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equalf(t, sarama.V2_6_0_0, saramaConfig.Version, "Expected Version to default to 2.6.0, not %v", saramaConfig.Version)
+	assert.True(t, saramaConfig.Net.TLS.Enable, "Expected TLS to be enabled")
+	assert.NotNil(t, saramaConfig.Net.TLS.Config, "Expected TLS config to be set")
+	assert.True(t, saramaConfig.Net.SASL.Enable, "Expected SASL to be enabled")
+	assert.Equalf(t, sarama.SASLMechanism(sarama.SASLTypePlaintext), saramaConfig.Net.SASL.Mechanism, "Expected SASL mechanism to be PLAIN, not %v", saramaConfig.Net.SASL.Mechanism)
+	assert.Equalf(t, "testkey", saramaConfig.Net.SASL.User, "Expected SASL user to be testkey, not %v", saramaConfig.Net.SASL.User)
+	assert.Equalf(t, "testsecret", saramaConfig.Net.SASL.Password, "Expected SASL password to be testsecret, not %v", saramaConfig.Net.SASL.Password)
+	assert.Nil(t, saramaConfig.Validate(), "Expected sarama config to be valid")
+}
+
+func TestGetSaramaConfigFromClientProfile_ConfluentCloudBadConfig(t *testing.T) {
+	fixtureConfluentCloudProfile()
+	viper.Set("client-profile.test.api-secret", "")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic for a missing api-secret")
+
+	fixtureConfluentCloudProfile()
+	viper.Set("client-profile.test.kafka-version", "0.10.2")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic for an old kafka-version")
+
+	fixtureConfluentCloudProfile()
+	viper.Set("client-profile.test.sasl", "test")
+	viper.Set("sasl.test.username", "user")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic for a sasl profile")
+
+	fixtureConfluentCloudProfile()
+	viper.Set("client-profile.test.tls", "test")
+	viper.Set("tls.test.noverify", true)
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic for disabled TLS verification")
+}
+
+func TestGetSaramaConfigFromClientProfile_UnknownPreset(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.preset", "nosuchservice")
+
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic")
+}
+
+func TestGetSaramaConfigFromClientProfile_AzureEventHubs(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.preset", "azure-event-hubs")
+	viper.Set("client-profile.test.connection-string", "Endpoint=sb://test.servicebus.windows.net/;SharedAccessKeyName=key;SharedAccessKey=secret")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Equalf(t, sarama.V1_0_0_0, saramaConfig.Version, "Expected Version to default to 1.0.0, not %v", saramaConfig.Version)
+	assert.True(t, saramaConfig.Net.TLS.Enable, "Expected TLS to be enabled")
+	assert.Equalf(t, "$ConnectionString", saramaConfig.Net.SASL.User, "Expected SASL user to be $ConnectionString, not %v", saramaConfig.Net.SASL.User)
+	assert.Equalf(t, viper.GetString("client-profile.test.connection-string"), saramaConfig.Net.SASL.Password, "Expected SASL password to be the connection string, not %v", saramaConfig.Net.SASL.Password)
+	assert.Truef(t, saramaConfig.Metadata.RefreshFrequency < 240*time.Second, "Expected metadata refresh to be less than the idle timeout, not %v", saramaConfig.Metadata.RefreshFrequency)
+	assert.Nil(t, saramaConfig.Validate(), "Expected sarama config to be valid")
+}
+
+func TestGetSaramaConfigFromClientProfile_AzureEventHubsBadConnectionString(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.preset", "azure-event-hubs")
+	viper.Set("client-profile.test.connection-string", "SharedAccessKeyName=key;SharedAccessKey=secret")
+
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic")
+}
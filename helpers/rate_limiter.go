@@ -0,0 +1,63 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of some quantity (such as bytes) to a number per second, allowing bursts of up to one
+// second's worth. It is safe to share a RateLimiter between goroutines.
+type RateLimiter struct {
+	rate      float64
+	available float64
+	last      time.Time
+	lock      sync.Mutex
+}
+
+// NewRateLimiter returns a RateLimiter that allows the given number per second.
+func NewRateLimiter(perSecond int64) *RateLimiter {
+	return &RateLimiter{
+		rate:      float64(perSecond),
+		available: float64(perSecond),
+		last:      time.Now(),
+	}
+}
+
+// Wait takes n from the limiter, blocking until the rate allows it. If the quit channel is closed while waiting, it
+// returns false immediately. As the amount is taken before waiting, a single large amount will delay later callers
+// rather than waiting forever for enough to be available.
+func (limiter *RateLimiter) Wait(n int, quit <-chan struct{}) bool {
+	limiter.lock.Lock()
+	now := time.Now()
+	limiter.available += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.available > limiter.rate {
+		limiter.available = limiter.rate
+	}
+	limiter.last = now
+	limiter.available -= float64(n)
+	wait := time.Duration(-limiter.available / limiter.rate * float64(time.Second))
+	limiter.lock.Unlock()
+
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-quit:
+		return false
+	}
+}
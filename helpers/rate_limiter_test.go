@@ -0,0 +1,44 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+	quit := make(chan struct{})
+
+	// The first second's worth is allowed immediately
+	start := time.Now()
+	assert.True(t, limiter.Wait(1000, quit), "Expected Wait to return true")
+	assert.Truef(t, time.Since(start) < 50*time.Millisecond, "Expected Wait to return immediately, not after %v", time.Since(start))
+
+	// After that, 100 more should take about 100ms
+	start = time.Now()
+	assert.True(t, limiter.Wait(100, quit), "Expected Wait to return true")
+	assert.Truef(t, time.Since(start) >= 80*time.Millisecond, "Expected Wait to block for about 100ms, not %v", time.Since(start))
+}
+
+func TestRateLimiter_Wait_Quit(t *testing.T) {
+	limiter := NewRateLimiter(10)
+	quit := make(chan struct{})
+	close(quit)
+
+	// This would wait for 10 seconds if the quit channel was not closed
+	start := time.Now()
+	assert.False(t, limiter.Wait(110, quit), "Expected Wait to return false")
+	assert.Truef(t, time.Since(start) < time.Second, "Expected Wait to return immediately, not after %v", time.Since(start))
+}
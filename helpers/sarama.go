@@ -14,6 +14,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+	"strings"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -61,19 +62,27 @@ var kafkaVersions = map[string]sarama.KafkaVersion{
 	"2.6.0":    sarama.V2_6_0_0,
 }
 
+// parseKafkaVersion returns the sarama.KafkaVersion to use for the given version string. Versions that are newer than
+// the newest version that sarama supports (such as 3.x, including KRaft mode clusters) use the newest sarama version,
+// as brokers continue to support older protocol versions.
 func parseKafkaVersion(kafkaVersion string) sarama.KafkaVersion {
 	version, ok := kafkaVersions[kafkaVersion]
-	if !ok {
-		panic("Unknown Kafka Version: " + kafkaVersion)
+	if ok {
+		return version
 	}
 
-	return version
+	version, err := sarama.ParseKafkaVersion(kafkaVersion)
+	if (err != nil) || (!version.IsAtLeast(sarama.MaxVersion)) {
+		panic("Unknown Kafka Version: " + kafkaVersion)
+	}
+	return sarama.MaxVersion
 }
 
 // GetSaramaConfigFromClientProfile takes the name of a client-profile configuration entry and returns a sarama.Config
-// object that can be used to create a Sarama client with the specified configuration. This includes the Kafka version,
-// client ID, TLS, and SASL configs. If there is any error in the configuration, such as a bad TLS certificate file,
-// this func will panic as it is normally called when configuring modules.
+// object that can be used to create a Sarama client with the specified configuration. This includes the Kafka version
+// (which defaults to "auto", to be negotiated by NewSaramaClient), client ID, TLS, and SASL configs, as well as the
+// configs for a hosted Kafka service if the profile sets a preset. If there is any error in the configuration, such as
+// a bad TLS certificate file, this func will panic as it is normally called when configuring modules.
 func GetSaramaConfigFromClientProfile(profileName string) *sarama.Config {
 	// Set config root and defaults
 	configRoot := "client-profile." + profileName
@@ -82,73 +91,182 @@ func GetSaramaConfigFromClientProfile(profileName string) *sarama.Config {
 	}
 
 	viper.SetDefault(configRoot+".client-id", "burrow-lagchecker")
-	viper.SetDefault(configRoot+".kafka-version", "0.8")
+	viper.SetDefault(configRoot+".kafka-version", "auto")
+
+	preset := getClientProfilePreset(profileName)
+	if preset != nil {
+		viper.SetDefault(configRoot+".kafka-version", preset.kafkaVersion)
+	}
 
 	saramaConfig := sarama.NewConfig()
 	saramaConfig.ClientID = viper.GetString(configRoot + ".client-id")
-	saramaConfig.Version = parseKafkaVersion(viper.GetString(configRoot + ".kafka-version"))
+
+	// With a kafka-version of auto, the version is negotiated when the client is created. Until then, use the newest
+	// version so that settings which depend on the version are available
+	kafkaVersion := viper.GetString(configRoot + ".kafka-version")
+	if kafkaVersion == "auto" {
+		saramaConfig.Version = sarama.MaxVersion
+		autoVersionConfigs.Store(saramaConfig, true)
+	} else {
+		saramaConfig.Version = parseKafkaVersion(kafkaVersion)
+	}
 	saramaConfig.Consumer.Return.Errors = true
 
 	// Configure TLS if enabled
 	if viper.IsSet(configRoot + ".tls") {
-		tlsName := viper.GetString(configRoot + ".tls")
-
-		saramaConfig.Net.TLS.Enable = true
-		certFile := viper.GetString("tls." + tlsName + ".certfile")
-		keyFile := viper.GetString("tls." + tlsName + ".keyfile")
-		caFile := viper.GetString("tls." + tlsName + ".cafile")
-
-		if caFile == "" {
-			saramaConfig.Net.TLS.Config = &tls.Config{}
-		} else {
-			caCert, err := ioutil.ReadFile(caFile)
-			if err != nil {
-				panic("cannot read TLS CA file: " + err.Error())
-			}
-			caCertPool := x509.NewCertPool()
-			caCertPool.AppendCertsFromPEM(caCert)
-			saramaConfig.Net.TLS.Config = &tls.Config{
-				RootCAs: caCertPool,
-			}
-
-			if certFile != "" && keyFile != "" {
-				cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-				if err != nil {
-					panic("cannot read TLS certificate or key file: " + err.Error())
-				}
-				saramaConfig.Net.TLS.Config.Certificates = []tls.Certificate{cert}
-			}
-
-			shims.ApplyPeerVerification(saramaConfig, caCertPool)
-		}
-		saramaConfig.Net.TLS.Config.InsecureSkipVerify = viper.GetBool("tls." + tlsName + ".noverify")
+		configureTLS(saramaConfig, viper.GetString(configRoot+".tls"))
 	}
 
 	// Configure SASL if enabled
 	if viper.IsSet(configRoot + ".sasl") {
-		saslName := viper.GetString(configRoot + ".sasl")
-
-		saramaConfig.Net.SASL.Enable = true
-		mechanism := viper.GetString("sasl." + saslName + ".mechanism")
-		if mechanism == "SCRAM-SHA-256" {
-			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
-			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
-				return &XDGSCRAMClient{HashGeneratorFcn: SHA256}
-			}
-		} else if mechanism == "SCRAM-SHA-512" {
-			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
-			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
-				return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
-			}
-		}
-		saramaConfig.Net.SASL.Handshake = viper.GetBool("sasl." + saslName + ".handshake-first")
-		saramaConfig.Net.SASL.User = viper.GetString("sasl." + saslName + ".username")
-		saramaConfig.Net.SASL.Password = viper.GetString("sasl." + saslName + ".password")
+		configureSASL(saramaConfig, viper.GetString(configRoot+".sasl"))
+	}
+
+	if preset != nil {
+		preset.configure(saramaConfig, profileName)
 	}
 
 	return saramaConfig
 }
 
+// configureTLS sets up the TLS configs for the named tls profile on the sarama.Config. Any configuration error, such as
+// an unreadable file or a bad certificate, will cause a panic.
+func configureTLS(saramaConfig *sarama.Config, tlsName string) {
+	tlsConfig := GetTLSConfigFromProfile(tlsName)
+
+	saramaConfig.Net.TLS.Enable = true
+	saramaConfig.Net.TLS.Config = tlsConfig
+	if tlsConfig.RootCAs != nil {
+		shims.ApplyPeerVerification(saramaConfig, tlsConfig.RootCAs)
+	}
+}
+
+// GetTLSConfigFromProfile returns a tls.Config for the named tls profile. The CA, client certificate, and key can each
+// be given either inline as PEM (ca, cert, key) or as a filename (cafile, certfile, keyfile). Any configuration error,
+// such as an unreadable file or a bad certificate, will cause a panic.
+func GetTLSConfigFromProfile(tlsName string) *tls.Config {
+	configRoot := "tls." + tlsName
+	tlsConfig := &tls.Config{
+		ServerName:         viper.GetString(configRoot + ".server-name"),
+		InsecureSkipVerify: viper.GetBool(configRoot + ".noverify"),
+	}
+
+	caCert := getTLSPEM(configRoot, "ca", "cafile")
+	if caCert != nil {
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			panic("no certificates found in TLS CA for tls profile " + tlsName)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	cert := getTLSPEM(configRoot, "cert", "certfile")
+	key := getTLSPEM(configRoot, "key", "keyfile")
+	if (cert != nil) && (key != nil) {
+		keyPair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			panic("cannot load TLS certificate or key: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	} else if (cert != nil) || (key != nil) {
+		panic("both a TLS certificate and key are required for tls profile " + tlsName)
+	}
+
+	return tlsConfig
+}
+
+// getTLSPEM returns the PEM data for a TLS config, either from the inline config key or from the file named in the file
+// config key. If neither is set, nil is returned. If the file cannot be read, this func panics.
+func getTLSPEM(configRoot, inlineKey, fileKey string) []byte {
+	if inline := viper.GetString(configRoot + "." + inlineKey); inline != "" {
+		return []byte(inline)
+	}
+	if filename := viper.GetString(configRoot + "." + fileKey); filename != "" {
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			panic("cannot read TLS " + fileKey + ": " + err.Error())
+		}
+		return contents
+	}
+	return nil
+}
+
+// configureSASL sets up the SASL configs for the named sasl profile on the sarama.Config. The mechanism defaults to
+// PLAIN if it is not set, and the SCRAM mechanisms require a username. OAUTHBEARER uses a token provider that is
+// configured in the same sasl profile, AWS_MSK_IAM uses OAUTHBEARER with tokens signed by AWS credentials, and GSSAPI
+// uses the Kerberos configs in the sasl profile. Any configuration error will cause a panic.
+func configureSASL(saramaConfig *sarama.Config, saslName string) {
+	configRoot := "sasl." + saslName
+	viper.SetDefault(configRoot+".mechanism", sarama.SASLTypePlaintext)
+	viper.SetDefault(configRoot+".handshake-first", true)
+
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.Handshake = viper.GetBool(configRoot + ".handshake-first")
+	saramaConfig.Net.SASL.User = viper.GetString(configRoot + ".username")
+	saramaConfig.Net.SASL.Password = viper.GetString(configRoot + ".password")
+
+	mechanism := viper.GetString(configRoot + ".mechanism")
+	switch mechanism {
+	case sarama.SASLTypePlaintext:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA256}
+		}
+	case sarama.SASLTypeSCRAMSHA512:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
+		}
+	case sarama.SASLTypeOAuth:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		saramaConfig.Net.SASL.TokenProvider = getOAuthTokenProvider(saslName)
+	case "AWS_MSK_IAM":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		saramaConfig.Net.SASL.TokenProvider = getMSKIAMTokenProvider(saslName)
+	case sarama.SASLTypeGSSAPI:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeGSSAPI
+		configureGSSAPI(saramaConfig, saslName)
+	default:
+		panic("unknown SASL mechanism '" + mechanism + "' in sasl profile " + saslName)
+	}
+
+	if (saramaConfig.Net.SASL.SCRAMClientGeneratorFunc != nil) && (saramaConfig.Net.SASL.User == "") {
+		panic("SASL mechanism " + mechanism + " requires a username in sasl profile " + saslName)
+	}
+}
+
+// configureGSSAPI sets up Kerberos authentication for the named sasl profile. The principal is given as user@REALM,
+// and either a keytab or a password must be provided. Any configuration error will cause a panic.
+func configureGSSAPI(saramaConfig *sarama.Config, saslName string) {
+	configRoot := "sasl." + saslName
+	viper.SetDefault(configRoot+".service-name", "kafka")
+	viper.SetDefault(configRoot+".krb5-config", "/etc/krb5.conf")
+
+	principal := strings.SplitN(viper.GetString(configRoot+".principal"), "@", 2)
+	if (len(principal) != 2) || (principal[0] == "") || (principal[1] == "") {
+		panic("GSSAPI requires a principal in the form user@REALM in sasl profile " + saslName)
+	}
+
+	gssapiConfig := &saramaConfig.Net.SASL.GSSAPI
+	gssapiConfig.Username = principal[0]
+	gssapiConfig.Realm = principal[1]
+	gssapiConfig.ServiceName = viper.GetString(configRoot + ".service-name")
+	gssapiConfig.KerberosConfigPath = viper.GetString(configRoot + ".krb5-config")
+	gssapiConfig.DisablePAFXFAST = viper.GetBool(configRoot + ".disable-pafx-fast")
+
+	if viper.IsSet(configRoot + ".keytab") {
+		gssapiConfig.AuthType = sarama.KRB5_KEYTAB_AUTH
+		gssapiConfig.KeyTabPath = viper.GetString(configRoot + ".keytab")
+	} else if viper.IsSet(configRoot + ".password") {
+		gssapiConfig.AuthType = sarama.KRB5_USER_AUTH
+		gssapiConfig.Password = viper.GetString(configRoot + ".password")
+	} else {
+		panic("GSSAPI requires either a keytab or a password in sasl profile " + saslName)
+	}
+}
+
 // SaramaClient is an internal interface to the sarama.Client. We use our own interface because while sarama.Client is
 // an interface, sarama.Broker is not. This makes it difficult to test code which uses the Broker objects. This
 // interface operates in the same way, with the addition of an interface function for creating consumers on the client.
@@ -198,6 +316,10 @@ type SaramaClient interface {
 	// works on Kafka 0.8.2 and higher.
 	RefreshCoordinator(consumerGroup string) error
 
+	// Controller returns the broker that is the controller for the cluster, as determined by the cluster metadata. This
+	// function only works on Kafka 0.10.0 and higher.
+	Controller() (SaramaBroker, error)
+
 	// Close shuts down all broker connections managed by this client. It is required to call this function before a client
 	// object passes out of scope, as it will otherwise leak memory. You must close any Producers or Consumers using a
 	// client before you close the client.
@@ -300,6 +422,17 @@ func (c *BurrowSaramaClient) RefreshCoordinator(consumerGroup string) error {
 	return c.Client.RefreshCoordinator(consumerGroup)
 }
 
+// Controller returns the broker that is the controller for the cluster, as determined by the cluster metadata. This
+// function only works on Kafka 0.10.0 and higher.
+func (c *BurrowSaramaClient) Controller() (SaramaBroker, error) {
+	broker, err := c.Client.Controller()
+	var shimBroker *BurrowSaramaBroker
+	if broker != nil {
+		shimBroker = &BurrowSaramaBroker{broker}
+	}
+	return shimBroker, err
+}
+
 // Close shuts down all broker connections managed by this client. It is required to call this function before a client
 // object passes out of scope, as it will otherwise leak memory. You must close any Producers or Consumers using a
 // client before you close the client.
@@ -326,6 +459,12 @@ type SaramaBroker interface {
 	// ID returns the broker ID retrieved from Kafka's metadata, or -1 if that is not known.
 	ID() int32
 
+	// Addr returns the host:port of the broker, as retrieved from Kafka's metadata.
+	Addr() string
+
+	// Rack returns the rack of the broker, as retrieved from Kafka's metadata, or an empty string if it is not set.
+	Rack() string
+
 	// Close closes the connection associated with the broker
 	Close() error
 
@@ -343,6 +482,16 @@ func (b *BurrowSaramaBroker) ID() int32 {
 	return b.broker.ID()
 }
 
+// Addr returns the host:port of the broker, as retrieved from Kafka's metadata.
+func (b *BurrowSaramaBroker) Addr() string {
+	return b.broker.Addr()
+}
+
+// Rack returns the rack of the broker, as retrieved from Kafka's metadata, or an empty string if it is not set.
+func (b *BurrowSaramaBroker) Rack() string {
+	return b.broker.Rack()
+}
+
 // Close closes the connection associated with the broker
 func (b *BurrowSaramaBroker) Close() error {
 	return b.broker.Close()
@@ -353,6 +502,28 @@ func (b *BurrowSaramaBroker) GetAvailableOffsets(request *sarama.OffsetRequest)
 	return b.broker.GetAvailableOffsets(request)
 }
 
+// SaramaClusterAdmin is an internal interface to the parts of sarama.ClusterAdmin that are used inside Burrow. As
+// sarama.ClusterAdmin is already an interface, any sarama.ClusterAdmin can be used where a SaramaClusterAdmin is
+// needed. Only defining the methods we use makes it possible to mock the admin client in tests.
+type SaramaClusterAdmin interface {
+	// ListConsumerGroups lists the consumer groups available in the cluster, mapped to their protocol type.
+	ListConsumerGroups() (map[string]string, error)
+
+	// DescribeConsumerGroups describes the given consumer groups, including the members and their assignments.
+	DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error)
+
+	// ListConsumerGroupOffsets fetches the committed offsets for a consumer group. If topicPartitions is nil, the
+	// offsets for all partitions the group has committed are returned (this requires Kafka 0.10.2 or higher).
+	ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error)
+
+	// DescribeConfig fetches the configuration of a resource, such as a topic. If the resource has ConfigNames set,
+	// only those configs are returned.
+	DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error)
+
+	// Close shuts down the admin client and the underlying client.
+	Close() error
+}
+
 // MockSaramaClient is a mock of SaramaClient. It is used in tests by multiple packages. It should never be used in the
 // normal code.
 type MockSaramaClient struct {
@@ -430,6 +601,12 @@ func (m *MockSaramaClient) Coordinator(consumerGroup string) (SaramaBroker, erro
 	return args.Get(0).(SaramaBroker), args.Error(1)
 }
 
+// Controller mocks SaramaClient.Controller
+func (m *MockSaramaClient) Controller() (SaramaBroker, error) {
+	args := m.Called()
+	return args.Get(0).(SaramaBroker), args.Error(1)
+}
+
 // RefreshCoordinator mocks SaramaClient.RefreshCoordinator
 func (m *MockSaramaClient) RefreshCoordinator(consumerGroup string) error {
 	args := m.Called(consumerGroup)
@@ -466,6 +643,18 @@ func (m *MockSaramaBroker) ID() int32 {
 	return args.Get(0).(int32)
 }
 
+// Addr mocks SaramaBroker.Addr
+func (m *MockSaramaBroker) Addr() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+// Rack mocks SaramaBroker.Rack
+func (m *MockSaramaBroker) Rack() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // Close mocks SaramaBroker.Close
 func (m *MockSaramaBroker) Close() error {
 	args := m.Called()
@@ -478,6 +667,42 @@ func (m *MockSaramaBroker) GetAvailableOffsets(request *sarama.OffsetRequest) (*
 	return args.Get(0).(*sarama.OffsetResponse), args.Error(1)
 }
 
+// MockSaramaClusterAdmin is a mock of SaramaClusterAdmin. It is used in tests by multiple packages. It should never be
+// used in the normal code.
+type MockSaramaClusterAdmin struct {
+	mock.Mock
+}
+
+// ListConsumerGroups mocks SaramaClusterAdmin.ListConsumerGroups
+func (m *MockSaramaClusterAdmin) ListConsumerGroups() (map[string]string, error) {
+	args := m.Called()
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+// DescribeConsumerGroups mocks SaramaClusterAdmin.DescribeConsumerGroups
+func (m *MockSaramaClusterAdmin) DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error) {
+	args := m.Called(groups)
+	return args.Get(0).([]*sarama.GroupDescription), args.Error(1)
+}
+
+// ListConsumerGroupOffsets mocks SaramaClusterAdmin.ListConsumerGroupOffsets
+func (m *MockSaramaClusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	args := m.Called(group, topicPartitions)
+	return args.Get(0).(*sarama.OffsetFetchResponse), args.Error(1)
+}
+
+// DescribeConfig mocks SaramaClusterAdmin.DescribeConfig
+func (m *MockSaramaClusterAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	args := m.Called(resource)
+	return args.Get(0).([]sarama.ConfigEntry), args.Error(1)
+}
+
+// Close mocks SaramaClusterAdmin.Close
+func (m *MockSaramaClusterAdmin) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 // MockSaramaConsumer is a mock of sarama.Consumer. It is used in tests by multiple packages. It should never be used
 // in the normal code.
 type MockSaramaConsumer struct {
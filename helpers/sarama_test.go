@@ -11,12 +11,23 @@
 package helpers
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,6 +47,15 @@ func TestMockSaramaBroker_ImplementsSaramaBroker(t *testing.T) {
 	assert.Implements(t, (*SaramaBroker)(nil), new(MockSaramaBroker))
 }
 
+func TestSaramaClusterAdmin_ImplementedBySaramaClusterAdmin(t *testing.T) {
+	var admin sarama.ClusterAdmin
+	var _ SaramaClusterAdmin = admin
+}
+
+func TestMockSaramaClusterAdmin_ImplementsSaramaClusterAdmin(t *testing.T) {
+	assert.Implements(t, (*SaramaClusterAdmin)(nil), new(MockSaramaClusterAdmin))
+}
+
 func TestMockSaramaConsumer_ImplementsSaramaConsumer(t *testing.T) {
 	assert.Implements(t, (*sarama.Consumer)(nil), new(MockSaramaConsumer))
 }
@@ -62,3 +82,196 @@ func TestInitSaramaLogging(t *testing.T) {
 	assert.Equal(t, entries[0].Message, "hello")
 	assert.Equal(t, entries[0].Level, zap.DebugLevel)
 }
+
+func TestGetSaramaConfigFromClientProfile_SASLDefaults(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.username", "testuser")
+	viper.Set("sasl.testsasl.password", "testpass")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.True(t, saramaConfig.Net.SASL.Enable, "Expected SASL to be enabled")
+	assert.True(t, saramaConfig.Net.SASL.Handshake, "Expected SASL handshake to be enabled by default")
+	assert.Equalf(t, sarama.SASLMechanism(sarama.SASLTypePlaintext), saramaConfig.Net.SASL.Mechanism, "Expected mechanism to be PLAIN, not %v", saramaConfig.Net.SASL.Mechanism)
+	assert.Equalf(t, "testuser", saramaConfig.Net.SASL.User, "Expected user to be testuser, not %v", saramaConfig.Net.SASL.User)
+	assert.Equalf(t, "testpass", saramaConfig.Net.SASL.Password, "Expected password to be testpass, not %v", saramaConfig.Net.SASL.Password)
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLSCRAM(t *testing.T) {
+	for _, mechanism := range []string{sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512} {
+		viper.Reset()
+		viper.Set("client-profile.test.sasl", "testsasl")
+		viper.Set("sasl.testsasl.mechanism", mechanism)
+		viper.Set("sasl.testsasl.username", "testuser")
+		viper.Set("sasl.testsasl.password", "testpass")
+
+		saramaConfig := GetSaramaConfigFromClientProfile("test")
+		assert.Equalf(t, sarama.SASLMechanism(mechanism), saramaConfig.Net.SASL.Mechanism, "Expected mechanism to be %v, not %v", mechanism, saramaConfig.Net.SASL.Mechanism)
+		assert.NotNil(t, saramaConfig.Net.SASL.SCRAMClientGeneratorFunc, "Expected SCRAM client generator to be set")
+		assert.IsType(t, &XDGSCRAMClient{}, saramaConfig.Net.SASL.SCRAMClientGeneratorFunc(), "Expected SCRAM client to be an XDGSCRAMClient")
+		assert.NoError(t, saramaConfig.Validate(), "Expected sarama config to be valid")
+	}
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLSCRAMNoUsername(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "SCRAM-SHA-512")
+
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic")
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLBadMechanism(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "NOSUCHMECHANISM")
+	viper.Set("sasl.testsasl.username", "testuser")
+
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "The code did not panic")
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLGSSAPI(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "GSSAPI")
+	viper.Set("sasl.testsasl.principal", "burrow@EXAMPLE.COM")
+	viper.Set("sasl.testsasl.keytab", "/etc/security/burrow.keytab")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	gssapiConfig := saramaConfig.Net.SASL.GSSAPI
+	assert.Equalf(t, sarama.SASLMechanism(sarama.SASLTypeGSSAPI), saramaConfig.Net.SASL.Mechanism, "Expected mechanism to be GSSAPI, not %v", saramaConfig.Net.SASL.Mechanism)
+	assert.Equalf(t, sarama.KRB5_KEYTAB_AUTH, gssapiConfig.AuthType, "Expected keytab auth, not %v", gssapiConfig.AuthType)
+	assert.Equalf(t, "burrow", gssapiConfig.Username, "Expected username to be burrow, not %v", gssapiConfig.Username)
+	assert.Equalf(t, "EXAMPLE.COM", gssapiConfig.Realm, "Expected realm to be EXAMPLE.COM, not %v", gssapiConfig.Realm)
+	assert.Equalf(t, "kafka", gssapiConfig.ServiceName, "Expected default service name to be kafka, not %v", gssapiConfig.ServiceName)
+	assert.Equalf(t, "/etc/krb5.conf", gssapiConfig.KerberosConfigPath, "Expected default krb5 config, not %v", gssapiConfig.KerberosConfigPath)
+	assert.NoError(t, saramaConfig.Validate(), "Expected sarama config to be valid")
+
+	// Without a keytab, the password is used
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "GSSAPI")
+	viper.Set("sasl.testsasl.principal", "burrow@EXAMPLE.COM")
+	viper.Set("sasl.testsasl.password", "testpass")
+	saramaConfig = GetSaramaConfigFromClientProfile("test")
+	assert.Equalf(t, sarama.KRB5_USER_AUTH, saramaConfig.Net.SASL.GSSAPI.AuthType, "Expected user auth, not %v", saramaConfig.Net.SASL.GSSAPI.AuthType)
+	assert.Equalf(t, "testpass", saramaConfig.Net.SASL.GSSAPI.Password, "Expected password to be testpass, not %v", saramaConfig.Net.SASL.GSSAPI.Password)
+}
+
+func TestGetSaramaConfigFromClientProfile_SASLGSSAPIBadConfig(t *testing.T) {
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "GSSAPI")
+	viper.Set("sasl.testsasl.keytab", "/etc/security/burrow.keytab")
+	viper.Set("sasl.testsasl.principal", "burrow")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with no realm in principal")
+
+	viper.Reset()
+	viper.Set("client-profile.test.sasl", "testsasl")
+	viper.Set("sasl.testsasl.mechanism", "GSSAPI")
+	viper.Set("sasl.testsasl.principal", "burrow@EXAMPLE.COM")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with no keytab or password")
+}
+
+func TestXDGSCRAMClient_Begin(t *testing.T) {
+	client := &XDGSCRAMClient{HashGeneratorFcn: SHA256}
+	err := client.Begin("testuser", "testpass", "")
+	assert.NoError(t, err, "Expected Begin to return no error")
+	assert.False(t, client.Done(), "Expected conversation to not be done")
+
+	response, err := client.Step("")
+	assert.NoError(t, err, "Expected first Step to return no error")
+	assert.Contains(t, response, "n=testuser", "Expected client-first message to contain the username")
+}
+
+func fixtureTLSCertificate() (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "burrow-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	certDER, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestGetSaramaConfigFromClientProfile_TLSInline(t *testing.T) {
+	certPEM, keyPEM := fixtureTLSCertificate()
+
+	viper.Reset()
+	viper.Set("client-profile.test.tls", "testtls")
+	viper.Set("tls.testtls.ca", certPEM)
+	viper.Set("tls.testtls.cert", certPEM)
+	viper.Set("tls.testtls.key", keyPEM)
+	viper.Set("tls.testtls.server-name", "kafka.example.com")
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.True(t, saramaConfig.Net.TLS.Enable, "Expected TLS to be enabled")
+	assert.NotNil(t, saramaConfig.Net.TLS.Config.RootCAs, "Expected CA pool to be set")
+	assert.Len(t, saramaConfig.Net.TLS.Config.Certificates, 1, "Expected client certificate to be set")
+	assert.Equalf(t, "kafka.example.com", saramaConfig.Net.TLS.Config.ServerName, "Expected server name override, not %v", saramaConfig.Net.TLS.Config.ServerName)
+	assert.False(t, saramaConfig.Net.TLS.Config.InsecureSkipVerify, "Expected verification to be enabled")
+}
+
+func TestGetSaramaConfigFromClientProfile_TLSFiles(t *testing.T) {
+	certPEM, keyPEM := fixtureTLSCertificate()
+	certFile, _ := ioutil.TempFile("", "burrow-cert")
+	defer os.Remove(certFile.Name())
+	certFile.WriteString(certPEM)
+	certFile.Close()
+	keyFile, _ := ioutil.TempFile("", "burrow-key")
+	defer os.Remove(keyFile.Name())
+	keyFile.WriteString(keyPEM)
+	keyFile.Close()
+
+	// A client certificate can be used without a custom CA
+	viper.Reset()
+	viper.Set("client-profile.test.tls", "testtls")
+	viper.Set("tls.testtls.certfile", certFile.Name())
+	viper.Set("tls.testtls.keyfile", keyFile.Name())
+	viper.Set("tls.testtls.noverify", true)
+
+	saramaConfig := GetSaramaConfigFromClientProfile("test")
+	assert.Nil(t, saramaConfig.Net.TLS.Config.RootCAs, "Expected system CA pool to be used")
+	assert.Len(t, saramaConfig.Net.TLS.Config.Certificates, 1, "Expected client certificate to be set")
+	assert.True(t, saramaConfig.Net.TLS.Config.InsecureSkipVerify, "Expected verification to be disabled")
+}
+
+func TestGetSaramaConfigFromClientProfile_TLSBadConfig(t *testing.T) {
+	certPEM, _ := fixtureTLSCertificate()
+
+	viper.Reset()
+	viper.Set("client-profile.test.tls", "testtls")
+	viper.Set("tls.testtls.cafile", "/nonexistent/ca.pem")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with missing CA file")
+
+	viper.Reset()
+	viper.Set("client-profile.test.tls", "testtls")
+	viper.Set("tls.testtls.ca", "not a certificate")
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with bad CA")
+
+	viper.Reset()
+	viper.Set("client-profile.test.tls", "testtls")
+	viper.Set("tls.testtls.cert", certPEM)
+	assert.Panics(t, func() { GetSaramaConfigFromClientProfile("test") }, "Expected panic with no key")
+}
+
+func TestParseKafkaVersion(t *testing.T) {
+	assert.Equal(t, sarama.V0_10_2_0, parseKafkaVersion(""), "Expected default version to be 0.10.2")
+	assert.Equal(t, sarama.V2_6_0_0, parseKafkaVersion("2.6.0"), "Expected version 2.6.0")
+
+	// Versions newer than sarama knows about, such as KRaft clusters, use the newest version sarama supports
+	assert.Equal(t, sarama.MaxVersion, parseKafkaVersion("2.8.0"), "Expected 2.8.0 to use the newest sarama version")
+	assert.Equal(t, sarama.MaxVersion, parseKafkaVersion("3.6.1"), "Expected 3.6.1 to use the newest sarama version")
+
+	assert.Panics(t, func() { parseKafkaVersion("0.7.0") }, "Expected panic for unsupported old version")
+	assert.Panics(t, func() { parseKafkaVersion("notaversion") }, "Expected panic for bad version")
+}
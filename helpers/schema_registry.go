@@ -0,0 +1,110 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// SchemaRegistryClient fetches schemas by ID from a Confluent Schema Registry. Schemas are immutable once they are
+// registered, so callers are free to cache the results.
+type SchemaRegistryClient struct {
+	// URL is the base URL of the Schema Registry, with no trailing slash
+	URL string
+
+	// Username and Password are used for basic authentication, if Username is not empty
+	Username string
+	Password string
+
+	// HTTPClient is the client used to make requests
+	HTTPClient *http.Client
+}
+
+// RegisteredSchema is a schema returned by the Schema Registry. The SchemaType is AVRO, PROTOBUF, or JSON
+type RegisteredSchema struct {
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+// GetSchemaRegistryClientFromProfile takes the name of a schema-registry configuration entry and returns a client for
+// it. The url is required, and the timeout for requests defaults to 10 seconds. If the registry requires basic
+// authentication, a username and password may be provided, and the name of a tls profile may be given for HTTPS. If
+// there is any error in the configuration, this func will panic as it is normally called when configuring modules.
+func GetSchemaRegistryClientFromProfile(profileName string) *SchemaRegistryClient {
+	configRoot := "schema-registry." + profileName
+	if !viper.IsSet(configRoot) {
+		panic("unknown schema-registry '" + profileName + "'")
+	}
+
+	viper.SetDefault(configRoot+".timeout", 10)
+
+	registryURL := strings.TrimSuffix(viper.GetString(configRoot+".url"), "/")
+	parsedURL, err := url.Parse(registryURL)
+	if (registryURL == "") || (err != nil) || ((parsedURL.Scheme != "http") && (parsedURL.Scheme != "https")) || (parsedURL.Host == "") {
+		panic("bad or missing url in schema-registry " + profileName)
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if viper.IsSet(configRoot + ".tls") {
+		transport.TLSClientConfig = GetTLSConfigFromProfile(viper.GetString(configRoot + ".tls"))
+	}
+
+	return &SchemaRegistryClient{
+		URL:      registryURL,
+		Username: viper.GetString(configRoot + ".username"),
+		Password: viper.GetString(configRoot + ".password"),
+		HTTPClient: &http.Client{
+			Timeout:   time.Duration(viper.GetInt(configRoot+".timeout")) * time.Second,
+			Transport: transport,
+		},
+	}
+}
+
+// GetSchemaByID fetches the schema with the given ID. If the registry does not return a schema type, as older
+// versions do not, it is AVRO.
+func (client *SchemaRegistryClient) GetSchemaByID(id int32) (*RegisteredSchema, error) {
+	req, err := http.NewRequest("GET", client.URL+"/schemas/ids/"+strconv.Itoa(int(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if client.Username != "" {
+		req.SetBasicAuth(client.Username, client.Password)
+	}
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected response status " + resp.Status)
+	}
+
+	schema := &RegisteredSchema{}
+	err = json.NewDecoder(resp.Body).Decode(schema)
+	if err != nil {
+		return nil, err
+	}
+	if schema.SchemaType == "" {
+		schema.SchemaType = "AVRO"
+	}
+	return schema, nil
+}
@@ -0,0 +1,63 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSchemaRegistryClientFromProfile(t *testing.T) {
+	viper.Reset()
+	viper.Set("schema-registry.test.url", "https://registry.example.com:8081/")
+	viper.Set("schema-registry.test.username", "user")
+
+	client := GetSchemaRegistryClientFromProfile("test")
+	assert.Equalf(t, "https://registry.example.com:8081", client.URL, "Expected URL to have no trailing slash, not %v", client.URL)
+	assert.Equalf(t, "user", client.Username, "Expected Username to be user, not %v", client.Username)
+}
+
+func TestGetSchemaRegistryClientFromProfile_BadURL(t *testing.T) {
+	viper.Reset()
+	viper.Set("schema-registry.test.url", "registry.example.com")
+
+	assert.Panics(t, func() { GetSchemaRegistryClientFromProfile("test") }, "The code did not panic")
+	assert.Panics(t, func() { GetSchemaRegistryClientFromProfile("nosuchprofile") }, "The code did not panic")
+}
+
+func TestSchemaRegistryClient_GetSchemaByID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if (!ok) || (username != "user") || (password != "pass") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/schemas/ids/5" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"schema": "\"string\""}`))
+	}))
+	defer server.Close()
+
+	client := &SchemaRegistryClient{URL: server.URL, Username: "user", Password: "pass", HTTPClient: server.Client()}
+	schema, err := client.GetSchemaByID(5)
+	assert.Nil(t, err, "Expected GetSchemaByID to return no error")
+	assert.Equalf(t, "AVRO", schema.SchemaType, "Expected SchemaType to default to AVRO, not %v", schema.SchemaType)
+	assert.Equalf(t, `"string"`, schema.Schema, "Expected Schema to be \"string\", not %v", schema.Schema)
+
+	_, err = client.GetSchemaByID(6)
+	assert.NotNil(t, err, "Expected GetSchemaByID to return an error for an unknown schema")
+}
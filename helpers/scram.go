@@ -1,3 +1,13 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
 package helpers
 
 import (
@@ -7,15 +17,21 @@ import (
 	"github.com/xdg/scram"
 )
 
+// SHA256 is the hash generator used for the SCRAM-SHA-256 SASL mechanism
 var SHA256 scram.HashGeneratorFcn = sha256.New
+
+// SHA512 is the hash generator used for the SCRAM-SHA-512 SASL mechanism
 var SHA512 scram.HashGeneratorFcn = sha512.New
 
+// XDGSCRAMClient is an implementation of the sarama.SCRAMClient interface, using the xdg/scram package to handle the
+// SCRAM conversation with the broker
 type XDGSCRAMClient struct {
 	*scram.Client
 	*scram.ClientConversation
 	scram.HashGeneratorFcn
 }
 
+// Begin prepares the client for the SCRAM exchange with the server with a user name and a password
 func (x *XDGSCRAMClient) Begin(userName, password, authzID string) (err error) {
 	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
 	if err != nil {
@@ -25,11 +41,13 @@ func (x *XDGSCRAMClient) Begin(userName, password, authzID string) (err error) {
 	return nil
 }
 
+// Step steps the client through the SCRAM exchange. It is called repeatedly until it errors or Done returns true
 func (x *XDGSCRAMClient) Step(challenge string) (response string, err error) {
 	response, err = x.ClientConversation.Step(challenge)
 	return
 }
 
+// Done should return true when the SCRAM conversation is over
 func (x *XDGSCRAMClient) Done() bool {
 	return x.ClientConversation.Done()
 }
@@ -0,0 +1,154 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// The number of groups that GetConsumerStatuses evaluates at the same time, and how long it waits for each, unless
+// general.status-concurrency or general.status-timeout (in seconds) is set
+const (
+	defaultStatusConcurrency = 8
+	defaultStatusTimeout     = 10 * time.Second
+)
+
+// GetConsumerStatuses evaluates every consumer group in every cluster that is accepted by the filter (or every group,
+// if the filter is nil), and returns the statuses of the groups that were found, in the order that storage lists them.
+// The meta group, if it is enabled, is included in every cluster, so that Burrow's own health is reported with the
+// groups. If showAll is false, only the partitions that are not OK are included in each status.
+//
+// The groups are evaluated general.status-concurrency at a time (8 by default), so that a group that is slow to
+// evaluate does not hold up the others. A group that is not evaluated within general.status-timeout seconds (10 by
+// default) is left out, and counted in the status.timeouts metric. If the quit channel is closed while waiting for
+// storage or the evaluator, the statuses that have been evaluated so far are returned.
+func GetConsumerStatuses(app *protocol.ApplicationContext, filter *ConsumerFilter, showAll bool, quit <-chan struct{}) []*protocol.ConsumerGroupStatus {
+	type clusterGroup struct {
+		cluster string
+		group   string
+	}
+
+	var groups []clusterGroup
+	clusters, _ := fetchStatusStorage(app, &protocol.StorageRequest{RequestType: protocol.StorageFetchClusters}, quit).([]string)
+	for _, cluster := range clusters {
+		clusterGroups, _ := fetchStatusStorage(app, &protocol.StorageRequest{
+			RequestType: protocol.StorageFetchConsumers,
+			Cluster:     cluster,
+		}, quit).([]string)
+		if metaGroup := GetMetaGroup(); metaGroup != "" {
+			clusterGroups = append(clusterGroups, metaGroup)
+		}
+		for _, group := range clusterGroups {
+			if (filter == nil) || filter.AcceptGroup(group) {
+				groups = append(groups, clusterGroup{cluster: cluster, group: group})
+			}
+		}
+	}
+
+	concurrency := viper.GetInt("general.status-concurrency")
+	if concurrency <= 0 {
+		concurrency = defaultStatusConcurrency
+	}
+	timeout := time.Duration(viper.GetInt("general.status-timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = defaultStatusTimeout
+	}
+
+	// Each worker stores the statuses at the index of the group, to keep them in order
+	results := make([]*protocol.ConsumerGroupStatus, len(groups))
+	indexes := make(chan int)
+	var workers sync.WaitGroup
+	for i := 0; (i < concurrency) && (i < len(groups)); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				results[index] = evaluateStatus(app, groups[index].cluster, groups[index].group, showAll, timeout, quit)
+			}
+		}()
+	}
+
+sendGroups:
+	for i := range groups {
+		select {
+		case indexes <- i:
+		case <-quit:
+			break sendGroups
+		}
+	}
+	close(indexes)
+	workers.Wait()
+
+	statuses := make([]*protocol.ConsumerGroupStatus, 0, len(results))
+	for _, status := range results {
+		// The group can be removed from storage between the list and the evaluation
+		if (status != nil) && (status.Status != protocol.StatusNotFound) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// evaluateStatus sends a request for the status of the group to the evaluator, and returns the status, or nil if it
+// takes longer than the timeout or the quit channel is closed first
+func evaluateStatus(app *protocol.ApplicationContext, cluster, group string, showAll bool, timeout time.Duration, quit <-chan struct{}) *protocol.ConsumerGroupStatus {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// The reply channel has space for the reply, so the evaluator is not left waiting if the request times out
+	request := &protocol.EvaluatorRequest{
+		Cluster:   cluster,
+		Group:     group,
+		ShowAll:   showAll,
+		Reply:     make(chan *protocol.ConsumerGroupStatus, 1),
+		Submitted: time.Now(),
+	}
+	select {
+	case app.EvaluatorChannel <- request:
+	case <-timer.C:
+		GetMetricCounter("status.timeouts").Inc(1)
+		return nil
+	case <-quit:
+		return nil
+	}
+	select {
+	case status := <-request.Reply:
+		return status
+	case <-timer.C:
+		GetMetricCounter("status.timeouts").Inc(1)
+		return nil
+	case <-quit:
+		return nil
+	}
+}
+
+// fetchStatusStorage sends a request to the storage coordinator and returns the response, or nil if the quit channel
+// is closed first
+func fetchStatusStorage(app *protocol.ApplicationContext, request *protocol.StorageRequest, quit <-chan struct{}) interface{} {
+	request.Reply = make(chan interface{}, 1)
+	request.Submitted = time.Now()
+	select {
+	case app.GetStorageChannel(protocol.StoragePriorityEvaluation) <- request:
+	case <-quit:
+		return nil
+	}
+	select {
+	case response := <-request.Reply:
+		return response
+	case <-quit:
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestGetConsumerStatuses(t *testing.T) {
+	viper.Reset()
+	viper.Set("general.status-timeout", 1)
+	app := &protocol.ApplicationContext{
+		StorageChannel:   make(chan *protocol.StorageRequest),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+	}
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for {
+			select {
+			case request := <-app.StorageChannel:
+				if request.RequestType == protocol.StorageFetchClusters {
+					request.Reply <- []string{"testcluster"}
+				} else {
+					request.Reply <- []string{"group1", "slowgroup", "group2", "removedgroup", "group3"}
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	// The slow group is never answered, and the removed group is no longer in storage
+	go func() {
+		for {
+			select {
+			case request := <-app.EvaluatorChannel:
+				switch request.Group {
+				case "slowgroup":
+				case "removedgroup":
+					request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusNotFound}
+				default:
+					request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusOK}
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	timeouts := GetMetricCounter("status.timeouts").Count()
+	statuses := GetConsumerStatuses(app, nil, true, quit)
+
+	groups := make([]string, len(statuses))
+	for i, status := range statuses {
+		groups[i] = status.Group
+	}
+	assert.Equal(t, []string{"group1", "group2", "group3"}, groups, "Expected the groups that were evaluated, in order")
+	assert.Equal(t, timeouts+1, GetMetricCounter("status.timeouts").Count(), "Expected the slow group to time out")
+}
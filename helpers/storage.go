@@ -17,13 +17,17 @@ import (
 )
 
 // TimeoutSendStorageRequest is a helper func for sending a protocol.StorageRequest to a channel with a timeout,
-// specified in seconds. If the request is sent, return true. Otherwise, if the timeout is hit, return false.
+// specified in seconds. If the request is sent, return true. Otherwise, if the timeout is hit, return false. Requests
+// that time out are counted in the storage.send-timeouts metric, as they are lost. A request that times out is returned
+// to the pool, if it came from protocol.AcquireStorageRequest.
 func TimeoutSendStorageRequest(storageChannel chan *protocol.StorageRequest, request *protocol.StorageRequest, maxTime int) bool {
 	timeout := time.After(time.Duration(maxTime) * time.Second)
 	select {
 	case storageChannel <- request:
 		return true
 	case <-timeout:
+		GetMetricCounter("storage.send-timeouts").Inc(1)
+		protocol.ReleaseStorageRequest(request)
 		return false
 	}
 }
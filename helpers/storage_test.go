@@ -47,3 +47,14 @@ func TestTimeoutSendStorageRequest_Timeout(t *testing.T) {
 	default:
 	}
 }
+
+func TestTimeoutSendStorageRequest_TimeoutReleases(t *testing.T) {
+	storageChannel := make(chan *protocol.StorageRequest)
+	storageRequest := protocol.AcquireStorageRequest(protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+	})
+
+	assert.False(t, TimeoutSendStorageRequest(storageChannel, storageRequest, 0), "Expected the request to time out")
+	assert.Equal(t, protocol.StorageRequest{}, *storageRequest, "Expected the request to be cleared when it was released")
+}
@@ -0,0 +1,490 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// SpanKind is the OpenTelemetry kind of a span, which says how the work it records relates to other spans
+type SpanKind int
+
+// The kinds of span, with the values that OTLP uses for them
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// Span records a piece of work in a trace, such as handling an HTTP request or a single storage request. Spans are only
+// created when tracing is enabled and the trace is sampled, so every method can be called on a nil Span (and does
+// nothing), which means that callers do not need to check whether tracing is on.
+type Span struct {
+	tracer       *tracer
+	traceID      string
+	spanID       string
+	parentSpanID string
+	name         string
+	kind         SpanKind
+	start        time.Time
+
+	lock         sync.Mutex
+	end          time.Time
+	attributes   map[string]interface{}
+	errorMessage string
+	failed       bool
+	ended        bool
+}
+
+// tracer exports the spans that have ended to the collector in batches, from a single goroutine
+type tracer struct {
+	log              *zap.Logger
+	client           *OTLPClient
+	sampleRate       float64
+	ingestSampleRate float64
+	batchSize        int
+	exportInterval   time.Duration
+
+	queue     chan *Span
+	quitChan  chan struct{}
+	doneChan  chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// activeTracer is the tracer that spans are created with, or nil if tracing is not enabled
+var activeTracer = struct {
+	lock   sync.RWMutex
+	tracer *tracer
+}{}
+
+type spanContextKey struct{}
+
+// newTracer returns a tracer for the configuration in the tracing section, or nil if tracing is not enabled. If there
+// is any error in the configuration, this func will panic.
+func newTracer(logger *zap.Logger) *tracer {
+	viper.SetDefault("tracing.sample-rate", 1.0)
+	viper.SetDefault("tracing.ingest-sample-rate", 0.0)
+	viper.SetDefault("tracing.batch-size", 512)
+	viper.SetDefault("tracing.queue-size", 2048)
+	viper.SetDefault("tracing.export-interval", 5)
+
+	if !viper.GetBool("tracing.enabled") {
+		return nil
+	}
+
+	sampleRate := viper.GetFloat64("tracing.sample-rate")
+	if (sampleRate < 0) || (sampleRate > 1) {
+		panic("tracing.sample-rate must be between 0 and 1")
+	}
+	ingestSampleRate := viper.GetFloat64("tracing.ingest-sample-rate")
+	if (ingestSampleRate < 0) || (ingestSampleRate > 1) {
+		panic("tracing.ingest-sample-rate must be between 0 and 1")
+	}
+	batchSize := viper.GetInt("tracing.batch-size")
+	if batchSize <= 0 {
+		panic("tracing.batch-size must be greater than 0")
+	}
+	queueSize := viper.GetInt("tracing.queue-size")
+	if queueSize <= 0 {
+		panic("tracing.queue-size must be greater than 0")
+	}
+	exportInterval := viper.GetInt("tracing.export-interval")
+	if exportInterval <= 0 {
+		panic("tracing.export-interval must be greater than 0")
+	}
+
+	return &tracer{
+		log:              logger,
+		client:           GetOTLPClient(),
+		sampleRate:       sampleRate,
+		ingestSampleRate: ingestSampleRate,
+		batchSize:        batchSize,
+		exportInterval:   time.Duration(exportInterval) * time.Second,
+		queue:            make(chan *Span, queueSize),
+		quitChan:         make(chan struct{}),
+		doneChan:         make(chan struct{}),
+	}
+}
+
+// CheckTracingConfig returns an error if the configuration in the tracing section (and the otlp section, if tracing is
+// enabled) is not valid
+func CheckTracingConfig() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	newTracer(zap.NewNop())
+	return nil
+}
+
+// StartTracing starts exporting spans to the OTLP collector, if tracing.enabled is set in the configuration. The
+// configuration is:
+//
+// * sample-rate - The fraction of HTTP requests to trace, from 0 to 1 (defaults to 1). A request that has a traceparent
+// header is traced if the caller sampled it, so that the trace continues from the caller.
+//
+// * ingest-sample-rate - The fraction of consumer offset commits to trace, from 0 to 1 (defaults to 0)
+//
+// * batch-size - The most spans to send to the collector at once (defaults to 512)
+//
+// * queue-size - The most spans to hold while waiting to send them. Spans that end when the queue is full are dropped
+// (defaults to 2048)
+//
+// * export-interval - The number of seconds between sending spans to the collector (defaults to 5)
+//
+// The collector is configured in the otlp section (see GetOTLPClient). If there is any error in the configuration, this
+// func will panic. StopTracing must be called before Burrow exits, so that the spans that have not been sent are not
+// lost.
+func StartTracing(logger *zap.Logger) {
+	newActive := newTracer(logger.With(zap.String("type", "tracing")))
+	if newActive == nil {
+		return
+	}
+
+	activeTracer.lock.Lock()
+	previous := activeTracer.tracer
+	activeTracer.tracer = newActive
+	activeTracer.lock.Unlock()
+
+	if previous != nil {
+		previous.stop()
+	}
+	newActive.startOnce.Do(func() {
+		go newActive.exportLoop()
+	})
+}
+
+// StopTracing stops creating spans, and sends all of the spans that have ended to the collector
+func StopTracing() {
+	activeTracer.lock.Lock()
+	previous := activeTracer.tracer
+	activeTracer.tracer = nil
+	activeTracer.lock.Unlock()
+
+	if previous != nil {
+		previous.stop()
+	}
+}
+
+func getTracer() *tracer {
+	activeTracer.lock.RLock()
+	defer activeTracer.lock.RUnlock()
+	return activeTracer.tracer
+}
+
+// StartTrace starts a new trace, returning its root span. Consumer spans (offset commits that are ingested) are sampled
+// with tracing.ingest-sample-rate, and all other spans with tracing.sample-rate. If tracing is not enabled, or the
+// trace is not sampled, nil is returned.
+func StartTrace(name string, kind SpanKind) *Span {
+	active := getTracer()
+	if active == nil {
+		return nil
+	}
+	sampleRate := active.sampleRate
+	if kind == SpanKindConsumer {
+		sampleRate = active.ingestSampleRate
+	}
+	if (sampleRate <= 0) || ((sampleRate < 1) && (mathrand.Float64() >= sampleRate)) {
+		return nil
+	}
+	return active.newSpan(name, kind, newTraceID(), "")
+}
+
+// ContinueTrace starts a span in the trace given by a W3C traceparent header, such as one sent by the client of an HTTP
+// request. If the header is empty or not valid, a new trace is started instead (see StartTrace). If the caller did not
+// sample the trace, nil is returned.
+func ContinueTrace(name string, kind SpanKind, traceparent string) *Span {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if (len(parts) < 4) || (parts[0] == "ff") || !isHexID(parts[0], 2) || !isHexID(parts[1], 32) ||
+		!isHexID(parts[2], 16) || !isHexID(parts[3], 2) || ((parts[0] == "00") && (len(parts) != 4)) {
+		return StartTrace(name, kind)
+	}
+
+	active := getTracer()
+	if active == nil {
+		return nil
+	}
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	if flags&0x01 == 0 {
+		return nil
+	}
+	return active.newSpan(name, kind, parts[1], parts[2])
+}
+
+// StartSpan starts a span for work done for a request that is being traced, as a child of the span given. If the parent
+// is nil (the request is not being traced) or tracing is not enabled, nil is returned.
+func StartSpan(name string, kind SpanKind, parent *protocol.TraceContext) *Span {
+	if parent == nil {
+		return nil
+	}
+	active := getTracer()
+	if active == nil {
+		return nil
+	}
+	return active.newSpan(name, kind, parent.TraceID, parent.SpanID)
+}
+
+// ContextWithSpan returns a copy of the context that carries the span, for passing it through an HTTP handler
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span in the context, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+func (t *tracer) newSpan(name string, kind SpanKind, traceID, parentSpanID string) *Span {
+	return &Span{
+		tracer:       t,
+		traceID:      traceID,
+		spanID:       newSpanID(),
+		parentSpanID: parentSpanID,
+		name:         name,
+		kind:         kind,
+		start:        time.Now(),
+		attributes:   make(map[string]interface{}),
+	}
+}
+
+// Context returns the trace context for the span, to set in the requests that are sent for the work in the span. If the
+// span is nil, nil is returned.
+func (span *Span) Context() *protocol.TraceContext {
+	if span == nil {
+		return nil
+	}
+	return &protocol.TraceContext{TraceID: span.traceID, SpanID: span.spanID}
+}
+
+// TraceParent returns the span as a W3C traceparent header value, or an empty string if the span is nil
+func (span *Span) TraceParent() string {
+	if span == nil {
+		return ""
+	}
+	return "00-" + span.traceID + "-" + span.spanID + "-01"
+}
+
+// SetAttribute sets an attribute of the span, such as the cluster that a request is for
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.attributes[key] = value
+}
+
+// SetError marks the span as failed, with a message that says why
+func (span *Span) SetError(message string) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.failed = true
+	span.errorMessage = message
+}
+
+// End records the time that the span ended, and queues it to be sent to the collector. Only the first call to End for a
+// span has any effect.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	if span.ended {
+		span.lock.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.lock.Unlock()
+
+	select {
+	case span.tracer.queue <- span:
+	default:
+		GetMetricCounter("tracing.dropped-spans").Inc(1)
+	}
+}
+
+// exportLoop sends the spans in the queue to the collector whenever a full batch is ready, and every export-interval,
+// until the tracer is stopped. When it is stopped, all of the spans that are left in the queue are sent.
+func (t *tracer) exportLoop() {
+	defer close(t.doneChan)
+
+	ticker := time.NewTicker(t.exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.batchSize)
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= t.batchSize {
+				t.export(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = batch[:0]
+			}
+		case <-t.quitChan:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+					if len(batch) >= t.batchSize {
+						t.export(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						t.export(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *tracer) stop() {
+	t.stopOnce.Do(func() {
+		close(t.quitChan)
+	})
+	<-t.doneChan
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource    `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// The OTLP status code for a span that failed
+const otlpStatusCodeError = 2
+
+func (t *tracer) export(batch []*Span) {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		span.lock.Lock()
+		exported := otlpSpan{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentSpanID,
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+		}
+		if span.failed {
+			exported.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.errorMessage}
+		}
+		span.lock.Unlock()
+		spans = append(spans, exported)
+	}
+
+	err := t.client.Export("/v1/traces", &otlpTracesRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: t.client.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/linkedin/Burrow"},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		GetMetricCounter("tracing.export-errors").Inc(1)
+		t.log.Warn("failed to export spans", zap.Int("spans", len(spans)), zap.Error(err))
+		return
+	}
+	GetMetricCounter("tracing.exported-spans").Inc(int64(len(spans)))
+}
+
+// isHexID returns true if the string is lowercase hex of the given length, and is not all zeros (which is not a valid
+// trace or span ID)
+func isHexID(id string, length int) bool {
+	if len(id) != length {
+		return false
+	}
+	nonZero := false
+	for _, c := range id {
+		if !(((c >= '0') && (c <= '9')) || ((c >= 'a') && (c <= 'f'))) {
+			return false
+		}
+		if c != '0' {
+			nonZero = true
+		}
+	}
+	return nonZero || (length == 2)
+}
+
+func newTraceID() string {
+	return randomHexID(16)
+}
+
+func newSpanID() string {
+	return randomHexID(8)
+}
+
+func randomHexID(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		// This should never happen, but if it does, fall back to the less random source rather than a zero ID
+		mathrand.Read(id) // nolint:gosec
+	}
+	return hex.EncodeToString(id)
+}
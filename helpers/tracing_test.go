@@ -0,0 +1,151 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testTraceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+// fixtureTraceCollector starts tracing with a collector that keeps every request it is sent. StopTracing must be
+// called before the requests are read.
+func fixtureTraceCollector(t *testing.T) (*httptest.Server, *[]otlpTracesRequest) {
+	lock := &sync.Mutex{}
+	requests := make([]otlpTracesRequest, 0)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path, "Expected spans to be sent to /v1/traces")
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"), "Expected the configured header to be sent")
+
+		var request otlpTracesRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request), "Expected a valid OTLP request")
+		lock.Lock()
+		requests = append(requests, request)
+		lock.Unlock()
+	}))
+
+	viper.Reset()
+	viper.Set("tracing.enabled", true)
+	viper.Set("otlp.endpoint", collector.URL)
+	viper.Set("otlp.headers.x-api-key", "secret")
+	StartTracing(zap.NewNop())
+	return collector, &requests
+}
+
+func TestSpan_Nil(t *testing.T) {
+	StopTracing()
+	viper.Reset()
+
+	span := ContinueTrace("HTTP GET", SpanKindServer, testTraceParent)
+	assert.Nil(t, span, "Expected no span when tracing is not enabled")
+
+	// None of these should panic
+	span.SetAttribute("key", "value")
+	span.SetError("failed")
+	span.End()
+	assert.Nil(t, span.Context(), "Expected no trace context for a nil span")
+	assert.Equal(t, "", span.TraceParent(), "Expected no traceparent for a nil span")
+	assert.Nil(t, StartSpan("child", SpanKindInternal, span.Context()), "Expected no child of a nil span")
+	assert.Nil(t, SpanFromContext(context.Background()), "Expected no span in an empty context")
+}
+
+func TestTracing_Export(t *testing.T) {
+	collector, requests := fixtureTraceCollector(t)
+	defer collector.Close()
+
+	span := ContinueTrace("HTTP GET", SpanKindServer, testTraceParent)
+	assert.NotNil(t, span, "Expected a span for a sampled traceparent")
+	span.SetAttribute("http.status_code", 500)
+	span.SetError("Internal Server Error")
+	assert.Equal(t, span, SpanFromContext(ContextWithSpan(context.Background(), span)), "Expected the span from the context")
+
+	child := StartSpan("storage StorageFetchConsumer", SpanKindInternal, span.Context())
+	assert.NotNil(t, child, "Expected a child span")
+	child.End()
+	child.End()
+	span.End()
+	StopTracing()
+
+	spans := make([]otlpSpan, 0)
+	for _, request := range *requests {
+		assert.Len(t, request.ResourceSpans, 1, "Expected one resource")
+		for _, attribute := range request.ResourceSpans[0].Resource.Attributes {
+			if attribute.Key == "service.name" {
+				assert.Equal(t, "burrow", *attribute.Value.StringValue, "Expected the default service name")
+			}
+		}
+		for _, scopeSpans := range request.ResourceSpans[0].ScopeSpans {
+			spans = append(spans, scopeSpans.Spans...)
+		}
+	}
+	assert.Len(t, spans, 2, "Expected each span to be sent once")
+
+	assert.Equal(t, "storage StorageFetchConsumer", spans[0].Name, "Expected the child span first, as it ended first")
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].TraceID, "Expected the child to be in the same trace")
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID, "Expected the child to have the server span as its parent")
+	assert.Nil(t, spans[0].Status, "Expected no status for a span without an error")
+
+	assert.Equal(t, "HTTP GET", spans[1].Name, "Expected the server span")
+	assert.Equal(t, SpanKindServer, spans[1].Kind, "Expected the server span kind")
+	assert.Equal(t, "b7ad6b7169203331", spans[1].ParentSpanID, "Expected the server span to continue the caller's trace")
+	assert.Equal(t, "500", *spans[1].Attributes[0].Value.IntValue, "Expected the status code attribute")
+	assert.Equal(t, &otlpStatus{Code: otlpStatusCodeError, Message: "Internal Server Error"}, spans[1].Status, "Expected an error status")
+}
+
+func TestContinueTrace(t *testing.T) {
+	collector, _ := fixtureTraceCollector(t)
+	defer collector.Close()
+	defer StopTracing()
+
+	assert.Nil(t, ContinueTrace("HTTP GET", SpanKindServer, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"),
+		"Expected no span when the caller did not sample the trace")
+
+	for _, traceparent := range []string{"", "garbage", "00-00000000000000000000000000000000-b7ad6b7169203331-01", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra"} {
+		span := ContinueTrace("HTTP GET", SpanKindServer, traceparent)
+		assert.NotNilf(t, span, "Expected a new trace for traceparent %v", traceparent)
+		assert.Equalf(t, "", span.parentSpanID, "Expected a root span for traceparent %v", traceparent)
+		assert.NotEqualf(t, "0af7651916cd43dd8448eb211c80319c", span.traceID, "Expected a new trace ID for traceparent %v", traceparent)
+	}
+}
+
+func TestStartTrace_SampleRate(t *testing.T) {
+	collector, _ := fixtureTraceCollector(t)
+	defer collector.Close()
+	defer StopTracing()
+
+	assert.NotNil(t, StartTrace("HTTP GET", SpanKindServer), "Expected a span with the default sample rate")
+	assert.Nil(t, StartTrace("consumer offset commit", SpanKindConsumer), "Expected no span with the default ingest sample rate")
+}
+
+func TestCheckTracingConfig(t *testing.T) {
+	viper.Reset()
+	viper.Set("tracing.sample-rate", 2)
+	assert.NoError(t, CheckTracingConfig(), "Expected no error when tracing is not enabled")
+
+	viper.Set("tracing.enabled", true)
+	viper.Set("otlp.endpoint", "http://localhost:4318")
+	assert.Error(t, CheckTracingConfig(), "Expected an error for a bad sample rate")
+
+	viper.Set("tracing.sample-rate", 0.5)
+	assert.NoError(t, CheckTracingConfig(), "Expected no error for a valid configuration")
+
+	viper.Set("otlp.endpoint", "localhost:4318")
+	assert.Error(t, CheckTracingConfig(), "Expected an error for an endpoint without a scheme")
+}
@@ -0,0 +1,193 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// VaultClient reads secrets from HashiCorp Vault using a token. It supports both version 1 and version 2 of the KV
+// secrets engine, as well as any other secrets engine that returns its values in the data of the response.
+type VaultClient struct {
+	Address    string
+	Token      string
+	Namespace  string
+	HTTPClient *http.Client
+}
+
+type vaultSecretResponse struct {
+	LeaseID   string                 `json:"lease_id"`
+	Renewable bool                   `json:"renewable"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+type vaultTokenResponse struct {
+	Data struct {
+		Renewable bool `json:"renewable"`
+	} `json:"data"`
+}
+
+// The secrets with renewable leases (such as credentials from the database or AWS secrets engines), by address and
+// path. When the secret is read again, the lease is renewed and the same values are used, rather than a new secret
+// being created each time the configuration is reloaded. A new secret is only read once the lease cannot be renewed.
+var vaultLeases = &sync.Map{}
+
+// newVaultClient creates a VaultClient from the vault section of the configuration. The address and token default to
+// the VAULT_ADDR and VAULT_TOKEN environment variables, and if vault.tls is set, that tls profile is used to connect.
+// The address and token may refer to environment variables or files, but not to Vault itself.
+func newVaultClient(config *viper.Viper) (client *VaultClient, err error) {
+	// GetTLSConfigFromProfile panics on a bad profile, which must not stop a reload
+	defer func() {
+		if r := recover(); r != nil {
+			client, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+
+	client = &VaultClient{
+		Address:   config.GetString("vault.address"),
+		Token:     config.GetString("vault.token"),
+		Namespace: config.GetString("vault.namespace"),
+	}
+	if client.Address == "" {
+		client.Address = os.Getenv("VAULT_ADDR")
+	}
+	if client.Token == "" {
+		client.Token = os.Getenv("VAULT_TOKEN")
+	}
+	for _, setting := range []*string{&client.Address, &client.Token} {
+		if *setting, _, err = interpolateLocalValue(*setting); err != nil {
+			return nil, err
+		}
+	}
+	if client.Address == "" {
+		return nil, errors.New("no Vault address is configured")
+	}
+	client.Address = strings.TrimSuffix(client.Address, "/")
+
+	timeout := 10 * time.Second
+	if config.IsSet("vault.timeout") {
+		timeout = time.Duration(config.GetInt("vault.timeout")) * time.Second
+	}
+	client.HTTPClient = &http.Client{Timeout: timeout}
+	if tlsName := config.GetString("vault.tls"); tlsName != "" {
+		client.HTTPClient.Transport = &http.Transport{TLSClientConfig: GetTLSConfigFromProfile(tlsName)}
+	}
+
+	// The client is created each time the configuration is read, so with general.secret-refresh set, the token is
+	// renewed at each refresh. If renewing fails, the token may still be valid, so any error is left to the reads
+	client.RenewToken()
+	return client, nil
+}
+
+// RenewToken renews the client's token, if it is renewable, so that it does not expire while Burrow is running. Tokens
+// that are not renewable (such as root tokens, which do not expire) are left as they are.
+func (client *VaultClient) RenewToken() error {
+	body, err := client.request("GET", "auth/token/lookup-self", nil)
+	if err != nil {
+		return err
+	}
+	token := &vaultTokenResponse{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return err
+	}
+	if !token.Data.Renewable {
+		return nil
+	}
+	_, err = client.request("POST", "auth/token/renew-self", nil)
+	return err
+}
+
+// ReadSecret returns a single field of the secret at the given path. The path is the API path of the secret, without
+// the leading /v1/ (for example, "secret/data/kafka" for the secret "kafka" in a KV version 2 engine mounted at
+// "secret"). The field must be a string. If the secret was read before with a renewable lease, the lease is renewed and
+// the same secret is returned, and a new secret is only read once the lease cannot be renewed.
+func (client *VaultClient) ReadSecret(path, field string) (string, error) {
+	path = strings.TrimPrefix(path, "/")
+	data, err := client.readSecretData(path)
+	if err != nil {
+		return "", err
+	}
+
+	// KV version 2 nests the values under data, alongside the metadata for the version
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = inner
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %v has no field %v", path, field)
+	}
+	return value, nil
+}
+
+func (client *VaultClient) readSecretData(path string) (map[string]interface{}, error) {
+	leaseKey := client.Address + "/v1/" + path
+	if cached, ok := vaultLeases.Load(leaseKey); ok {
+		secret := cached.(*vaultSecretResponse)
+		leaseRequest, _ := json.Marshal(map[string]string{"lease_id": secret.LeaseID})
+		if _, err := client.request("PUT", "sys/leases/renew", leaseRequest); err == nil {
+			return secret.Data, nil
+		}
+		vaultLeases.Delete(leaseKey)
+	}
+
+	body, err := client.request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	secret := &vaultSecretResponse{}
+	if err := json.Unmarshal(body, secret); err != nil {
+		return nil, err
+	}
+	if secret.Renewable && (secret.LeaseID != "") {
+		vaultLeases.Store(leaseKey, secret)
+	}
+	return secret.Data, nil
+}
+
+// request sends a request to the Vault API at the path (without the leading /v1/), and returns the body of the response
+func (client *VaultClient) request(method, path string, body []byte) ([]byte, error) {
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
+	}
+	request, err := http.NewRequest(method, client.Address+"/v1/"+path, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Vault-Token", client.Token)
+	if client.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", client.Namespace)
+	}
+
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if (response.StatusCode != http.StatusOK) && (response.StatusCode != http.StatusNoContent) {
+		return nil, fmt.Errorf("vault request for %v failed with status %v", path, response.StatusCode)
+	}
+	return ioutil.ReadAll(response.Body)
+}
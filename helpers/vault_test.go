@@ -0,0 +1,146 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureVaultServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "testtoken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/kafka":
+			w.Write([]byte(`{"data": {"data": {"username": "kafkauser", "password": "kafkapassword"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/kafka":
+			w.Write([]byte(`{"lease_duration": 3600, "data": {"password": "v1password"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultClient_ReadSecret(t *testing.T) {
+	server := fixtureVaultServer(t)
+	defer server.Close()
+	client := &VaultClient{Address: server.URL, Token: "testtoken", HTTPClient: server.Client()}
+
+	value, err := client.ReadSecret("secret/data/kafka", "password")
+	assert.NoError(t, err, "Expected KV v2 secret to be read")
+	assert.Equal(t, "kafkapassword", value, "Expected the password from the KV v2 secret")
+
+	value, err = client.ReadSecret("kv/kafka", "password")
+	assert.NoError(t, err, "Expected KV v1 secret to be read")
+	assert.Equal(t, "v1password", value, "Expected the password from the KV v1 secret")
+
+	_, err = client.ReadSecret("secret/data/kafka", "nofield")
+	assert.Error(t, err, "Expected an error for a missing field")
+	_, err = client.ReadSecret("secret/data/nosecret", "password")
+	assert.Error(t, err, "Expected an error for a missing secret")
+
+	client.Token = "badtoken"
+	_, err = client.ReadSecret("secret/data/kafka", "password")
+	assert.Error(t, err, "Expected an error for a bad token")
+}
+
+func TestInterpolateConfig_Vault(t *testing.T) {
+	server := fixtureVaultServer(t)
+	defer server.Close()
+
+	config := fixtureInterpolationConfig(t, `
+[vault]
+address="`+server.URL+`"
+token="testtoken"
+[sasl.test]
+username="vault:secret/data/kafka#username"
+password="vault:secret/data/kafka#password"
+`)
+	assert.NoError(t, InterpolateConfig(config), "Expected InterpolateConfig to return no error")
+	assert.Equal(t, "kafkauser", config.GetString("sasl.test.username"), "Expected username from Vault")
+	assert.Equal(t, "kafkapassword", config.GetString("sasl.test.password"), "Expected password from Vault")
+
+	config = fixtureInterpolationConfig(t, `
+[vault]
+address="`+server.URL+`"
+token="testtoken"
+[sasl.test]
+password="vault:secret/data/kafka"
+`)
+	assert.Error(t, InterpolateConfig(config), "Expected an error for a reference without a field")
+}
+
+func TestVaultClient_RenewToken(t *testing.T) {
+	renewed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/auth/token/lookup-self":
+			w.Write([]byte(fmt.Sprintf(`{"data": {"renewable": %v}}`, r.Header.Get("X-Vault-Token") == "renewabletoken")))
+		case (r.URL.Path == "/v1/auth/token/renew-self") && (r.Method == "POST"):
+			renewed++
+			w.Write([]byte(`{"auth": {"renewable": true, "lease_duration": 3600}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &VaultClient{Address: server.URL, Token: "roottoken", HTTPClient: server.Client()}
+	assert.NoError(t, client.RenewToken(), "Expected no error for a token that is not renewable")
+	assert.Equal(t, 0, renewed, "Expected a token that is not renewable to not be renewed")
+
+	client.Token = "renewabletoken"
+	assert.NoError(t, client.RenewToken(), "Expected no error renewing the token")
+	assert.Equal(t, 1, renewed, "Expected the token to be renewed")
+}
+
+func TestVaultClient_ReadSecret_Lease(t *testing.T) {
+	reads := 0
+	renewFails := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/database/creds/burrow":
+			reads++
+			w.Write([]byte(fmt.Sprintf(`{"lease_id": "database/creds/burrow/%v", "renewable": true, "lease_duration": 3600, "data": {"password": "password%v"}}`, reads, reads)))
+		case (r.URL.Path == "/v1/sys/leases/renew") && (r.Method == "PUT"):
+			if renewFails {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"lease_id": "database/creds/burrow/1", "renewable": true, "lease_duration": 3600}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := &VaultClient{Address: server.URL, Token: "testtoken", HTTPClient: server.Client()}
+
+	value, err := client.ReadSecret("database/creds/burrow", "password")
+	assert.NoError(t, err, "Expected the secret to be read")
+	assert.Equal(t, "password1", value, "Expected the password from the first read")
+
+	value, err = client.ReadSecret("database/creds/burrow", "password")
+	assert.NoError(t, err, "Expected the secret to be read from the renewed lease")
+	assert.Equal(t, "password1", value, "Expected the same password while the lease is renewed")
+	assert.Equal(t, 1, reads, "Expected the secret to be read once while the lease is renewed")
+
+	renewFails = true
+	value, err = client.ReadSecret("database/creds/burrow", "password")
+	assert.NoError(t, err, "Expected the secret to be read again")
+	assert.Equal(t, "password2", value, "Expected a new password once the lease cannot be renewed")
+}
@@ -0,0 +1,279 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func (hc *Coordinator) handleFilterList(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	names := helpers.GetConsumerFilterNames()
+	filters := make(map[string]helpers.ConsumerFilterSettings, len(names))
+	for _, name := range names {
+		filters[name] = helpers.GetConsumerFilter(name).Settings()
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseFilterList{
+		Error:   false,
+		Message: "filter list returned",
+		Filters: filters,
+		Request: requestInfo,
+	})
+}
+
+func (hc *Coordinator) handleFilterDetail(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter := helpers.GetConsumerFilter(params.ByName("module"))
+	if filter == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "module not found")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseFilterDetail{
+		Error:   false,
+		Message: "filter returned",
+		Filter:  filter.Settings(),
+		Request: requestInfo,
+	})
+}
+
+// handleFilterUpdate replaces the group and topic lists for a module with the ones in the request body. Lists that are
+// not in the body are removed. The update is not saved in the configuration, so it is undone by a reload, unless there
+// is a dynamic config file for it to be saved in.
+func (hc *Coordinator) handleFilterUpdate(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter := helpers.GetConsumerFilter(params.ByName("module"))
+	if filter == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "module not found")
+		return
+	}
+
+	var settings helpers.ConsumerFilterSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "could not decode filter: "+err.Error())
+		return
+	}
+	if err := filter.Update(settings); err != nil {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "invalid filter: "+err.Error())
+		return
+	}
+	if err := helpers.SaveDynamicFilter(params.ByName("module"), &settings); err != nil {
+		hc.Log.Error("failed to save filter", zap.String("module", params.ByName("module")), zap.Error(err))
+		hc.writeErrorResponse(w, r, http.StatusInternalServerError, "filter updated, but could not be saved: "+err.Error())
+		return
+	}
+	hc.Log.Info("filter updated",
+		zap.String("module", params.ByName("module")),
+		zap.String("group_allowlist", settings.GroupAllowlist),
+		zap.String("group_denylist", settings.GroupDenylist),
+		zap.String("topic_allowlist", settings.TopicAllowlist),
+		zap.String("topic_denylist", settings.TopicDenylist),
+	)
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseFilterDetail{
+		Error:   false,
+		Message: "filter updated",
+		Filter:  filter.Settings(),
+		Request: requestInfo,
+	})
+}
+
+// handleFilterReset sets the group and topic lists for a module back to the ones in the configuration, and removes any
+// lists saved for it in the dynamic config file.
+func (hc *Coordinator) handleFilterReset(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter := helpers.GetConsumerFilter(params.ByName("module"))
+	if filter == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "module not found")
+		return
+	}
+	if err := helpers.SaveDynamicFilter(params.ByName("module"), nil); err != nil {
+		hc.Log.Error("failed to save filter", zap.String("module", params.ByName("module")), zap.Error(err))
+		hc.writeErrorResponse(w, r, http.StatusInternalServerError, "could not save filter: "+err.Error())
+		return
+	}
+	if err := filter.Reload(); err != nil {
+		hc.writeErrorResponse(w, r, http.StatusInternalServerError, "invalid filter in configuration: "+err.Error())
+		return
+	}
+	hc.Log.Info("filter reset", zap.String("module", params.ByName("module")))
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseFilterDetail{
+		Error:   false,
+		Message: "filter reset to configuration",
+		Filter:  filter.Settings(),
+		Request: requestInfo,
+	})
+}
+
+// handleClientMetrics returns the metrics that sarama has recorded for the Kafka client of each cluster and consumer
+// module, labeled with the cluster that the client connects to.
+func (hc *Coordinator) handleClientMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseClientMetrics{
+		Error:   false,
+		Message: "client metrics returned",
+		Clients: helpers.GetClientMetrics(),
+		Request: requestInfo,
+	})
+}
+
+// handleMetrics returns Burrow's own metrics, such as the number of requests to the storage and evaluator subsystems,
+// the time taken to handle them, and the depth of the channels and queues between them
+func (hc *Coordinator) handleMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseMetrics{
+		Error:   false,
+		Message: "metrics returned",
+		Metrics: helpers.GetMetrics(),
+		Request: requestInfo,
+	})
+}
+
+// handleHealth returns the health of each coordinator and its modules, and the worst of them as the health of Burrow.
+// If anything has FAILED, the response code is 503, so that a load balancer or orchestrator that only checks the code
+// sees that Burrow is not working.
+func (hc *Coordinator) handleHealth(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	state, coordinators := helpers.GetHealth()
+	statusCode := http.StatusOK
+	if state == protocol.HealthFailed {
+		statusCode = http.StatusServiceUnavailable
+	}
+	hc.writeResponse(w, r, statusCode, httpResponseHealth{
+		Error:        false,
+		Message:      "health returned",
+		State:        state,
+		Coordinators: coordinators,
+		Request:      makeRequestInfo(r),
+	})
+}
+
+// handleDecodeFailures returns the number of messages that each consumer module could not decode, by the key and
+// value versions of the message
+func (hc *Coordinator) handleDecodeFailures(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseDecodeFailures{
+		Error:    false,
+		Message:  "decode failures returned",
+		Failures: helpers.GetDeadLetterCounts(),
+		Request:  requestInfo,
+	})
+}
+
+// handleConfigReload reloads the configuration file, the same as sending SIGHUP to Burrow, and returns which of the
+// changed configs were applied and which require a restart.
+func (hc *Coordinator) handleConfigReload(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if hc.App.ReloadChannel == nil {
+		hc.writeErrorResponse(w, r, http.StatusServiceUnavailable, "reload is not available")
+		return
+	}
+
+	request := &protocol.ReloadRequest{Reply: make(chan *protocol.ConfigReload, 1)}
+	select {
+	case hc.App.ReloadChannel <- request:
+	case <-time.After(5 * time.Second):
+		hc.writeErrorResponse(w, r, http.StatusServiceUnavailable, "timed out waiting for reload")
+		return
+	}
+	result := <-request.Reply
+
+	requestInfo := makeRequestInfo(r)
+	if result.Error != "" {
+		hc.writeResponse(w, r, http.StatusInternalServerError, httpResponseConfigReload{
+			Error:   true,
+			Message: "failed to reload configuration: " + result.Error,
+			Reload:  result,
+			Request: requestInfo,
+		})
+		return
+	}
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConfigReload{
+		Error:   false,
+		Message: "configuration reloaded",
+		Reload:  result,
+		Request: requestInfo,
+	})
+}
+
+// handleLogLevelGet returns the default logging level, and the levels for subsystems that have their own level
+func (hc *Coordinator) handleLogLevelGet(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	hc.writeLogLevelResponse(w, r, "log level returned")
+}
+
+// handleLogLevelSet sets the default logging level to the level in the request body. As with the filters, the change
+// is not saved in the configuration, so it is undone by a reload.
+func (hc *Coordinator) handleLogLevelSet(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	level, ok := hc.decodeLogLevel(w, r)
+	if !ok {
+		return
+	}
+	hc.App.LogLevel.SetLevel(level)
+	hc.Log.Info("log level set", zap.String("level", level.String()))
+	hc.writeLogLevelResponse(w, r, "log level set")
+}
+
+// handleSubsystemLogLevelSet sets the logging level for a single subsystem (such as "consumer") to the level in the
+// request body, overriding the default level for it
+func (hc *Coordinator) handleSubsystemLogLevelSet(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	level, ok := hc.decodeLogLevel(w, r)
+	if !ok {
+		return
+	}
+	helpers.SetSubsystemLogLevel(params.ByName("subsystem"), level)
+	hc.Log.Info("log level set", zap.String("subsystem", params.ByName("subsystem")), zap.String("level", level.String()))
+	hc.writeLogLevelResponse(w, r, "log level set")
+}
+
+// handleSubsystemLogLevelReset removes the logging level for a subsystem, so that it uses the default level again
+func (hc *Coordinator) handleSubsystemLogLevelReset(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	helpers.ClearSubsystemLogLevel(params.ByName("subsystem"))
+	hc.Log.Info("log level reset", zap.String("subsystem", params.ByName("subsystem")))
+	hc.writeLogLevelResponse(w, r, "log level reset")
+}
+
+// decodeLogLevel reads the level from a request body such as {"level": "debug"}. If the level is missing or invalid, an
+// error response is written and false is returned.
+func (hc *Coordinator) decodeLogLevel(w http.ResponseWriter, r *http.Request) (zapcore.Level, bool) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "could not decode log level: "+err.Error())
+		return zapcore.InfoLevel, false
+	}
+	level, ok := helpers.ParseLogLevel(body.Level)
+	if (body.Level == "") || !ok {
+		hc.writeErrorResponse(w, r, http.StatusBadRequest, "invalid log level: "+body.Level)
+		return zapcore.InfoLevel, false
+	}
+	return level, true
+}
+
+func (hc *Coordinator) writeLogLevelResponse(w http.ResponseWriter, r *http.Request, message string) {
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseLogLevel{
+		Error:   false,
+		Message: message,
+		Level:   hc.App.LogLevel.Level().String(),
+		Levels:  helpers.GetSubsystemLogLevels(),
+		Request: requestInfo,
+	})
+}
@@ -0,0 +1,305 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureFilter() *helpers.ConsumerFilter {
+	viper.Set("consumer.filtertest.group-denylist", "^denied$")
+	filter, _ := helpers.NewConsumerFilter("consumer.filtertest")
+	return filter
+}
+
+func TestHttpServer_handleFilterList(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	fixtureFilter()
+
+	req, err := http.NewRequest("GET", "/v3/admin/filter", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseFilterList
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, "^denied$", resp.Filters["consumer.filtertest"].GroupDenylist, "Expected group denylist to be ^denied$, not %v", resp.Filters["consumer.filtertest"].GroupDenylist)
+}
+
+func TestHttpServer_handleFilterDetail_NotFound(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("GET", "/v3/admin/filter/consumer.nomodule", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleFilterUpdate(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	filter := fixtureFilter()
+
+	body := `{"group-denylist": "^other$", "topic-denylist": "-changelog$"}`
+	req, err := http.NewRequest("PUT", "/v3/admin/filter/consumer.filtertest", strings.NewReader(body))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseFilterDetail
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equalf(t, "-changelog$", resp.Filter.TopicDenylist, "Expected topic denylist to be -changelog$, not %v", resp.Filter.TopicDenylist)
+	assert.True(t, filter.AcceptGroup("denied"), "Expected denied group to be accepted after the update")
+	assert.False(t, filter.AcceptTopic("app-changelog"), "Expected changelog topic to be rejected after the update")
+
+	// Resetting goes back to the configuration
+	req, err = http.NewRequest("DELETE", "/v3/admin/filter/consumer.filtertest", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.False(t, filter.AcceptGroup("denied"), "Expected denied group to be rejected after the reset")
+	assert.True(t, filter.AcceptTopic("app-changelog"), "Expected changelog topic to be accepted after the reset")
+}
+
+func TestHttpServer_handleFilterUpdate_BadRegexp(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	filter := fixtureFilter()
+
+	req, err := http.NewRequest("PUT", "/v3/admin/filter/consumer.filtertest", strings.NewReader(`{"group-allowlist": "["}`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+	assert.False(t, filter.AcceptGroup("denied"), "Expected filter to be unchanged")
+}
+
+func TestHttpServer_handleClientMetrics(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	helpers.RegisterClientMetrics("cluster.metricstest", "metricstest", sarama.NewConfig())
+
+	req, err := http.NewRequest("GET", "/v3/admin/client-metrics", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseClientMetrics
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+
+	found := false
+	for _, clientMetrics := range resp.Clients {
+		if clientMetrics.Module == "cluster.metricstest" {
+			found = true
+			assert.Equalf(t, "metricstest", clientMetrics.Cluster, "Expected cluster to be metricstest, not %v", clientMetrics.Cluster)
+		}
+	}
+	assert.True(t, found, "Expected metrics for the registered module")
+}
+
+func TestHttpServer_handleMetrics(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	helpers.GetMetricCounter("storage.metricstest.errors").Inc(2)
+
+	req, err := http.NewRequest("GET", "/v3/admin/metrics", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseMetrics
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Contains(t, resp.Metrics, "storage.metricstest.errors", "Expected the counter to be returned")
+	assert.Equal(t, float64(2), resp.Metrics["storage.metricstest.errors"]["count"], "Expected a count of 2")
+}
+
+func TestHttpServer_handleHealth(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	helpers.RegisterCoordinatorHealth("healthtest", func() *protocol.CoordinatorHealth {
+		return helpers.NewCoordinatorHealth([]*protocol.ModuleHealth{{Name: "local", State: protocol.HealthOK}})
+	})
+
+	req, err := http.NewRequest("GET", "/v3/admin/health", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseHealth
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equal(t, protocol.HealthOK, resp.State, "Expected Burrow to be OK")
+	assert.Contains(t, resp.Coordinators, "healthtest", "Expected the coordinator health to be returned")
+	assert.Equal(t, "local", resp.Coordinators["healthtest"].Modules[0].Name, "Expected the module health to be returned")
+
+	// A failed module fails the coordinator, and Burrow, so the response code shows it
+	helpers.RegisterCoordinatorHealth("healthtest", func() *protocol.CoordinatorHealth {
+		return helpers.NewCoordinatorHealth([]*protocol.ModuleHealth{{Name: "local", State: protocol.HealthFailed, Reason: "down"}})
+	})
+	defer helpers.RegisterCoordinatorHealth("healthtest", func() *protocol.CoordinatorHealth {
+		return helpers.NewCoordinatorHealth([]*protocol.ModuleHealth{})
+	})
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusServiceUnavailable, rr.Code, "Expected response code to be 503, not %v", rr.Code)
+
+	resp = httpResponseHealth{}
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equal(t, protocol.HealthFailed, resp.State, "Expected Burrow to be FAILED")
+	assert.Equal(t, "local: down", resp.Coordinators["healthtest"].Reason, "Expected the reason for the failure")
+}
+
+func TestHttpServer_handleDecodeFailures(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	queue, err := helpers.NewDeadLetterQueue("consumer.decodetest", "decodetest")
+	assert.NoError(t, err, "Expected NewDeadLetterQueue to return no error")
+	assert.NoError(t, queue.Add(&helpers.DeadLetter{KeyVersion: 1, ValueVersion: 9}))
+
+	req, err := http.NewRequest("GET", "/v3/admin/decode-failures", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseDecodeFailures
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+
+	found := false
+	for _, failure := range resp.Failures {
+		if failure.Module == "consumer.decodetest" {
+			found = true
+			assert.Equalf(t, "decodetest", failure.Cluster, "Expected cluster to be decodetest, not %v", failure.Cluster)
+			assert.Equalf(t, uint64(1), failure.Count, "Expected count to be 1, not %v", failure.Count)
+		}
+	}
+	assert.True(t, found, "Expected decode failures for the module")
+}
+
+func TestHttpServer_handleConfigReload(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	coordinator.App.ReloadChannel = make(chan *protocol.ReloadRequest)
+
+	go func() {
+		request := <-coordinator.App.ReloadChannel
+		request.Reply <- &protocol.ConfigReload{
+			Applied:         []string{"logging.level"},
+			RestartRequired: []string{"cluster.test.servers"},
+		}
+	}()
+
+	req, err := http.NewRequest("POST", "/v3/admin/reload", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseConfigReload
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, []string{"logging.level"}, resp.Reload.Applied, "Expected logging.level to be applied")
+	assert.Equal(t, []string{"cluster.test.servers"}, resp.Reload.RestartRequired, "Expected cluster.test.servers to require a restart")
+}
+
+func TestHttpServer_handleConfigReload_Failed(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	coordinator.App.ReloadChannel = make(chan *protocol.ReloadRequest)
+
+	go func() {
+		request := <-coordinator.App.ReloadChannel
+		request.Reply <- &protocol.ConfigReload{Error: "invalid logging.level: loud"}
+	}()
+
+	req, err := http.NewRequest("POST", "/v3/admin/reload", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusInternalServerError, rr.Code, "Expected response code to be 500, not %v", rr.Code)
+
+	var resp httpResponseConfigReload
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.True(t, resp.Error, "Expected response Error to be true")
+}
+
+func TestHttpServer_handleConfigReload_NotRunning(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("POST", "/v3/admin/reload", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusServiceUnavailable, rr.Code, "Expected response code to be 503, not %v", rr.Code)
+}
+
+func TestHttpServer_handleLogLevelSet(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("PUT", "/v3/admin/loglevel", strings.NewReader(`{"level": "debug"}`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.Equal(t, "debug", coordinator.App.LogLevel.Level().String(), "Expected the log level to be changed")
+
+	req, err = http.NewRequest("PUT", "/v3/admin/loglevel", strings.NewReader(`{"level": "loud"}`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
+
+func TestHttpServer_handleSubsystemLogLevel(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("PUT", "/v3/admin/loglevel/consumer", strings.NewReader(`{"level": "debug"}`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseLogLevel
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equal(t, "info", resp.Level, "Expected the default level to be unchanged")
+	assert.Equal(t, "debug", resp.Levels["consumer"], "Expected the consumer level to be set")
+
+	req, err = http.NewRequest("DELETE", "/v3/admin/loglevel/consumer", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	_, ok := helpers.GetSubsystemLogLevels()["consumer"]
+	assert.False(t, ok, "Expected the consumer level to be removed")
+}
@@ -0,0 +1,221 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+)
+
+// auditEntry is a record of a single request that changed Burrow's state
+type auditEntry struct {
+	// The time (in milliseconds) that the request finished
+	Timestamp int64 `json:"timestamp"`
+
+	// The action that was requested, such as consumer-delete
+	Action string `json:"action"`
+
+	// The user that the request was authenticated as, or an empty string if the listener has no authentication
+	Principal string `json:"principal"`
+
+	// The IP address that the request came from
+	SourceIP string `json:"source_ip"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// The status code of the response, which shows whether the action was done
+	StatusCode int `json:"status_code"`
+}
+
+// auditLog keeps the most recent audit entries in memory, so that they can be fetched from the HTTP server, and
+// optionally appends every entry to a file as a line of JSON. The file is never truncated or rotated by Burrow. It is
+// safe to use an auditLog from multiple goroutines.
+type auditLog struct {
+	lock       sync.Mutex
+	entries    []*auditEntry
+	maxEntries int
+	file       *os.File
+	log        *zap.Logger
+	errorCount metrics.Counter
+}
+
+// principalContextKey is the request context key for the user that a request was authenticated as
+type principalContextKey struct{}
+
+// newAuditLog sets up the audit log from the audit section of the configuration. If audit.filename is set, the file is
+// opened for appending (it is created if it does not exist), and the last audit.max-entries entries (default 1000)
+// are read from it, so that the entries from before a restart can still be fetched. If the file cannot be opened, this
+// func panics, as an audit log that cannot be written is a configuration failure.
+func newAuditLog(logger *zap.Logger) *auditLog {
+	viper.SetDefault("audit.max-entries", 1000)
+	audit := &auditLog{
+		entries:    make([]*auditEntry, 0),
+		maxEntries: viper.GetInt("audit.max-entries"),
+		log:        logger,
+		errorCount: helpers.GetMetricCounter("httpserver.audit-errors"),
+	}
+	if audit.maxEntries < 1 {
+		panic("audit.max-entries must be at least 1")
+	}
+
+	filename := viper.GetString("audit.filename")
+	if filename == "" {
+		return audit
+	}
+	if existing, err := os.Open(filename); err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			entry := &auditEntry{}
+			if json.Unmarshal(scanner.Bytes(), entry) == nil {
+				audit.addEntry(entry)
+			}
+		}
+		existing.Close()
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		panic("cannot open audit.filename: " + err.Error())
+	}
+	audit.file = file
+	return audit
+}
+
+// addEntry adds the entry to the entries in memory, removing the oldest if there are more than max-entries. The lock
+// must be held by the caller.
+func (audit *auditLog) addEntry(entry *auditEntry) {
+	audit.entries = append(audit.entries, entry)
+	if len(audit.entries) > audit.maxEntries {
+		audit.entries = audit.entries[len(audit.entries)-audit.maxEntries:]
+	}
+}
+
+// record adds the entry to the audit log, and writes it to the audit file if there is one. It is also logged, so that
+// it is not lost if the file cannot be written
+func (audit *auditLog) record(entry *auditEntry) {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	audit.addEntry(entry)
+	audit.log.Info("audit",
+		zap.String("action", entry.Action),
+		zap.String("principal", entry.Principal),
+		zap.String("source_ip", entry.SourceIP),
+		zap.String("path", entry.Path),
+		zap.Int("status_code", entry.StatusCode),
+	)
+	if audit.file == nil {
+		return
+	}
+	line, _ := json.Marshal(entry)
+	if _, err := audit.file.Write(append(line, '\n')); err != nil {
+		audit.errorCount.Inc(1)
+		audit.log.Error("failed to write audit entry", zap.Error(err))
+	}
+}
+
+// getEntries returns the entries in memory that are at or after the timestamp (in milliseconds), and that are for the
+// principal if it is not empty, oldest first
+func (audit *auditLog) getEntries(since int64, principal string) []*auditEntry {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	entries := make([]*auditEntry, 0)
+	for _, entry := range audit.entries {
+		if (entry.Timestamp >= since) && ((principal == "") || (entry.Principal == principal)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// close closes the audit file, if there is one. Entries that are recorded after this are still kept in memory.
+func (audit *auditLog) close() {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	if audit.file != nil {
+		audit.file.Close()
+		audit.file = nil
+	}
+}
+
+// applyPrincipalMiddleware adds the user that each request was authenticated as to the request context, if the listener
+// requires basic authentication. It must be applied inside the basic authentication middleware, so that the user has
+// been checked. Without authentication, the username in a request cannot be trusted, so none is added.
+func applyPrincipalMiddleware(httpServerConfigName string, next http.Handler) http.Handler {
+	if (viper.GetString(httpServerConfigName+".basic-auth-username") == "") || (viper.GetString(httpServerConfigName+".basic-auth-password") == "") {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, _, _ := r.BasicAuth()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, username)))
+	})
+}
+
+// audited wraps the handler for a request that changes Burrow's state, so that each request is recorded in the audit
+// log with the action name once it has been handled
+func (hc *Coordinator) audited(action string, handler httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		handler(recorder, r, params)
+
+		principal, _ := r.Context().Value(principalContextKey{}).(string)
+		sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			sourceIP = r.RemoteAddr
+		}
+		hc.audit.record(&auditEntry{
+			Timestamp:  time.Now().UnixNano() / int64(time.Millisecond),
+			Action:     action,
+			Principal:  principal,
+			SourceIP:   sourceIP,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			StatusCode: recorder.statusCode,
+		})
+	}
+}
+
+// handleAuditLog returns the recent audit entries, oldest first. The since (a timestamp in milliseconds) and principal
+// query parameters limit the entries returned.
+func (hc *Coordinator) handleAuditLog(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			hc.writeErrorResponse(w, r, http.StatusBadRequest, "since must be a timestamp in milliseconds")
+			return
+		}
+	}
+
+	hc.writeResponse(w, r, http.StatusOK, httpResponseAuditLog{
+		Error:   false,
+		Message: "audit log returned",
+		Entries: hc.audit.getEntries(since, r.URL.Query().Get("principal")),
+		Request: makeRequestInfo(r),
+	})
+}
@@ -0,0 +1,117 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+	"github.com/linkedin/Burrow/shims"
+)
+
+func TestHttpServer_Audit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow-audit")
+	assert.NoError(t, err, "Expected temp dir setup to return no error")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "audit.log")
+
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:           zap.NewNop(),
+			StorageChannel:   make(chan *protocol.StorageRequest, 1),
+			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		},
+	}
+	viper.Reset()
+	viper.Set("audit.filename", filename)
+	viper.Set("httpserver.test.address", ":0")
+	viper.Set("httpserver.test.basic-auth-username", "admin")
+	viper.Set("httpserver.test.basic-auth-password", "secret")
+	coordinator.Configure()
+	handler := shims.ApplyBasicAuthMiddleware("httpserver.test", applyPrincipalMiddleware("httpserver.test", coordinator.router))
+
+	req, err := http.NewRequest("DELETE", "/v3/kafka/testcluster/consumer/testgroup", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req.SetBasicAuth("admin", "secret")
+	req.RemoteAddr = "192.0.2.1:51234"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Requests that do not change anything are not recorded
+	req, err = http.NewRequest("GET", "/v3/admin/audit", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req.SetBasicAuth("admin", "secret")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseAuditLog
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Lenf(t, resp.Entries, 1, "Expected one audit entry, not %v", len(resp.Entries))
+	assert.Equal(t, "consumer-delete", resp.Entries[0].Action, "Expected the consumer delete to be recorded")
+	assert.Equal(t, "admin", resp.Entries[0].Principal, "Expected the authenticated user")
+	assert.Equal(t, "192.0.2.1", resp.Entries[0].SourceIP, "Expected the source IP without the port")
+	assert.Equal(t, "/v3/kafka/testcluster/consumer/testgroup", resp.Entries[0].Path, "Expected the request path")
+	assert.Equal(t, http.StatusOK, resp.Entries[0].StatusCode, "Expected the response code")
+
+	// The entry is in the file, and is read back when the server is configured again
+	coordinator.Stop()
+	contents, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err, "Expected the audit file to be written")
+	assert.Equal(t, 1, strings.Count(string(contents), "\n"), "Expected one line in the audit file")
+
+	coordinator.Configure()
+	defer coordinator.Stop()
+	assert.Len(t, coordinator.audit.getEntries(0, "admin"), 1, "Expected the entry to be read from the audit file")
+	assert.Len(t, coordinator.audit.getEntries(0, "other"), 0, "Expected no entries for another principal")
+	assert.Len(t, coordinator.audit.getEntries(resp.Entries[0].Timestamp+1, ""), 0, "Expected no entries after the timestamp")
+}
+
+func TestHttpServer_Audit_NoAuthentication(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Without authentication on the listener, the username that was sent is not trusted
+	req, err := http.NewRequest("PUT", "/v3/admin/loglevel", strings.NewReader(`{"level": "debug"}`))
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req.SetBasicAuth("someone", "anything")
+	rr := httptest.NewRecorder()
+	applyPrincipalMiddleware("httpserver.default", coordinator.router).ServeHTTP(rr, req)
+
+	entries := coordinator.audit.getEntries(0, "")
+	assert.Lenf(t, entries, 1, "Expected one audit entry, not %v", len(entries))
+	assert.Equal(t, "loglevel-set", entries[0].Action, "Expected the log level change to be recorded")
+	assert.Equal(t, "", entries[0].Principal, "Expected no principal")
+	assert.Equal(t, rr.Code, entries[0].StatusCode, "Expected the response code")
+}
+
+func TestHttpServer_handleAuditLog_BadSince(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("GET", "/v3/admin/audit?since=yesterday", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
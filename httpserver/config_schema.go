@@ -0,0 +1,28 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+package httpserver
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("httpserver.*", "",
+		helpers.ConfigKey{Name: "address", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "timeout", Type: helpers.ConfigTypeInteger, Default: 300},
+		helpers.ConfigKey{Name: "slow-request-threshold", Type: helpers.ConfigTypeInteger, Default: 0},
+		helpers.ConfigKey{Name: "basic-auth-username", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "basic-auth-password", Type: helpers.ConfigTypeString},
+	)
+	helpers.RegisterConfigKeys("audit", "",
+		helpers.ConfigKey{Name: "filename", Type: helpers.ConfigTypeString},
+		helpers.ConfigKey{Name: "max-entries", Type: helpers.ConfigTypeInteger, Default: 1000},
+	)
+}
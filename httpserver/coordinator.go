@@ -15,7 +15,6 @@
 package httpserver
 
 import (
-	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
@@ -43,6 +42,8 @@ type Coordinator struct {
 
 	router  *httprouter.Router
 	servers map[string]*http.Server
+	audit   *auditLog
+	json    helpers.JSONEncoder
 }
 
 // Configure is called to configure the HTTP server. This includes validating all configurations for each configured
@@ -54,6 +55,13 @@ type Coordinator struct {
 func (hc *Coordinator) Configure() {
 	hc.Log.Info("configuring")
 	hc.router = httprouter.New()
+	hc.audit = newAuditLog(hc.Log)
+
+	encoder, err := helpers.GetJSONEncoder()
+	if err != nil {
+		panic(err)
+	}
+	hc.json = encoder
 
 	// If no HTTP server configured, add a default HTTP server that listens on a random port
 	servers := viper.GetStringMap("httpserver")
@@ -66,8 +74,10 @@ func (hc *Coordinator) Configure() {
 	hc.servers = make(map[string]*http.Server)
 	for name := range servers {
 		configRoot := "httpserver." + name
+		viper.SetDefault(configRoot+".slow-request-threshold", 0)
 		server := &http.Server{
-			Handler: shims.ApplyBasicAuthMiddleware(configRoot, hc.router),
+			Handler: applyTracingMiddleware(hc.applySlowRequestMiddleware(configRoot,
+				shims.ApplyBasicAuthMiddleware(configRoot, applyPrincipalMiddleware(configRoot, hc.router)))),
 		}
 
 		server.Addr = viper.GetString(configRoot + ".address")
@@ -95,19 +105,49 @@ func (hc *Coordinator) Configure() {
 	hc.router.GET("/v3/kafka/:cluster/topic", hc.handleTopicList)
 	hc.router.GET("/v3/kafka/:cluster/topic/:topic", hc.handleTopicDetail)
 	hc.router.GET("/v3/kafka/:cluster/topic/:topic/consumers", hc.handleTopicConsumerList)
+	hc.router.GET("/v3/kafka/:cluster/topic/:topic/config", hc.handleTopicConfig)
+	hc.router.GET("/v3/kafka/:cluster/topic/:topic/rate", hc.handleTopicProduceRates)
 	hc.router.GET("/v3/kafka/:cluster/consumer", hc.handleConsumerList)
 	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDetail)
 	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer/status", hc.handleConsumerStatus)
 	hc.router.GET("/v3/kafka/:cluster/consumer/:consumer/lag", hc.handleConsumerStatusComplete)
+	hc.router.GET("/v3/kafka/:cluster/expected", hc.handleExpectedGroupList)
+	hc.router.GET("/v3/kafka/:cluster/self-lag", hc.handleSelfLag)
+	hc.router.GET("/v3/kafka/:cluster/replication", hc.handleClusterReplication)
+	hc.router.GET("/v3/kafka/:cluster/leader-churn", hc.handleClusterLeaderChurn)
+	hc.router.GET("/v3/kafka/:cluster/health", hc.handleClusterHealth)
+	hc.router.GET("/v3/kafka/:cluster/broker", hc.handleBrokerList)
+	hc.router.GET("/v3/kafka/:cluster/connector", hc.handleConnectorList)
+	hc.router.GET("/v3/kafka/:cluster/connector/:connector", hc.handleConnectorDetail)
+	hc.router.GET("/v3/kafka/:cluster/connector/:connector/lag", hc.handleConnectorLag)
+	hc.router.GET("/v3/events", hc.handleEvents)
+	hc.router.GET("/metrics", hc.handlePrometheusMetrics)
 
 	// TODO: This should really have authentication protecting it
-	hc.router.DELETE("/v3/kafka/:cluster/consumer/:consumer", hc.handleConsumerDelete)
+	// Requests that change Burrow's state are recorded in the audit log
+	hc.router.DELETE("/v3/kafka/:cluster/consumer/:consumer", hc.audited("consumer-delete", hc.handleConsumerDelete))
+	hc.router.PUT("/v3/kafka/:cluster/expected/:consumer", hc.audited("expected-group-add", hc.handleExpectedGroupAdd))
+	hc.router.DELETE("/v3/kafka/:cluster/expected/:consumer", hc.audited("expected-group-delete", hc.handleExpectedGroupDelete))
+	hc.router.GET("/v3/admin/filter", hc.handleFilterList)
+	hc.router.GET("/v3/admin/filter/:module", hc.handleFilterDetail)
+	hc.router.PUT("/v3/admin/filter/:module", hc.audited("filter-update", hc.handleFilterUpdate))
+	hc.router.DELETE("/v3/admin/filter/:module", hc.audited("filter-reset", hc.handleFilterReset))
+	hc.router.GET("/v3/admin/client-metrics", hc.handleClientMetrics)
+	hc.router.GET("/v3/admin/metrics", hc.handleMetrics)
+	hc.router.GET("/v3/admin/health", hc.handleHealth)
+	hc.router.GET("/v3/admin/decode-failures", hc.handleDecodeFailures)
+	hc.router.GET("/v3/admin/audit", hc.handleAuditLog)
+	hc.router.POST("/v3/admin/reload", hc.audited("config-reload", hc.handleConfigReload))
+	hc.router.GET("/v3/admin/loglevel", hc.handleLogLevelGet)
+	hc.router.PUT("/v3/admin/loglevel", hc.audited("loglevel-set", hc.handleLogLevelSet))
+	hc.router.PUT("/v3/admin/loglevel/:subsystem", hc.audited("loglevel-set", hc.handleSubsystemLogLevelSet))
+	hc.router.DELETE("/v3/admin/loglevel/:subsystem", hc.audited("loglevel-reset", hc.handleSubsystemLogLevelReset))
 }
 
 // Start is responsible for starting the listener on each configured address. If any listener fails to start, the error
 // is logged, and the listeners that have already been started are stopped. The func then returns the error encountered
 // to the caller. Once the listeners are all started, the HTTP server itself is started on each listener to respond to
-// requests.
+// requests. If Burrow was started by a graceful restart, the listeners of the previous process are used.
 func (hc *Coordinator) Start() error {
 	hc.Log.Info("starting")
 
@@ -115,10 +155,11 @@ func (hc *Coordinator) Start() error {
 	listeners := make(map[string]net.Listener)
 
 	for name, server := range hc.servers {
-		ln, err := net.Listen("tcp", hc.servers[name].Addr)
+		ln, err := helpers.Listen("httpserver."+name, hc.servers[name].Addr)
 		if err != nil {
 			hc.Log.Error("failed to listen", zap.String("listener", hc.servers[name].Addr), zap.Error(err))
-			for _, listenerToClose := range listeners {
+			for nameToClose, listenerToClose := range listeners {
+				helpers.ForgetListener("httpserver." + nameToClose)
 				if listenerToClose != nil {
 					closeErr := listenerToClose.Close()
 					if closeErr != nil {
@@ -131,13 +172,20 @@ func (hc *Coordinator) Start() error {
 		hc.Log.Info("started listener", zap.String("listener", ln.Addr().String()))
 		listeners[name] = tcpKeepAliveListener{
 			Keepalive:   server.IdleTimeout,
-			TCPListener: ln.(*net.TCPListener),
+			TCPListener: ln,
 		}
 	}
 
-	// Start the HTTP server on the listeners
+	// Start the HTTP server on the listeners. If a server stops for any reason other than being closed, its listener is
+	// FAILED, as it is not serving requests any more
 	for name, server := range hc.servers {
-		go server.Serve(listeners[name])
+		helpers.SetModuleHealth("httpserver."+name, protocol.HealthOK, "")
+		go func(name string, server *http.Server, listener net.Listener) {
+			if err := server.Serve(listener); err != http.ErrServerClosed {
+				hc.Log.Error("listener stopped", zap.String("listener", server.Addr), zap.Error(err))
+				helpers.SetModuleHealth("httpserver."+name, protocol.HealthFailed, "listener stopped: "+err.Error())
+			}
+		}(name, server, listeners[name])
 	}
 	return nil
 }
@@ -147,10 +195,12 @@ func (hc *Coordinator) Start() error {
 // other listeners from being closed. A generic error will be returned to the caller in this case.
 func (hc *Coordinator) Stop() error {
 	hc.Log.Info("shutdown")
+	defer hc.audit.close()
 
 	// Close all servers
 	collectedErrors := make([]zapcore.Field, 0)
-	for _, server := range hc.servers {
+	for name, server := range hc.servers {
+		helpers.ForgetListener("httpserver." + name)
 		err := server.Close()
 		if err != nil {
 			collectedErrors = append(collectedErrors, zap.Error(err))
@@ -164,6 +214,15 @@ func (hc *Coordinator) Stop() error {
 	return nil
 }
 
+// Health returns the health of each HTTP server listener. A listener is FAILED if it has stopped serving requests.
+func (hc *Coordinator) Health() *protocol.CoordinatorHealth {
+	servers := make([]*protocol.ModuleHealth, 0, len(hc.servers))
+	for name := range hc.servers {
+		servers = append(servers, helpers.GetModuleHealth(name, "httpserver."+name))
+	}
+	return helpers.NewCoordinatorHealth(servers)
+}
+
 // tcpKeepAliveListener sets TCP keep-alive timeouts on accepted connections. It's used by ListenAndServe and
 // ListenAndServeTLS so dead TCP connections (e.g. closing laptop mid-download) eventually go away.
 type tcpKeepAliveListener struct {
@@ -184,6 +243,58 @@ func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
 	return tc, nil
 }
 
+// applyTracingMiddleware starts a span for each request, if tracing is enabled, continuing the trace from the client's
+// traceparent header if it sent one. The span is passed to the handlers in the request context, so that the storage and
+// evaluator requests sent for the HTTP request are in the same trace (see traceContext). The traceparent of the span is
+// returned in the traceresponse header, so that a slow request can be looked up in the tracing system.
+func applyTracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := helpers.ContinueTrace("HTTP "+r.Method, helpers.SpanKindServer, r.Header.Get("traceparent"))
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		w.Header().Set("traceresponse", span.TraceParent())
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(helpers.ContextWithSpan(r.Context(), span)))
+
+		span.SetAttribute("http.status_code", recorder.statusCode)
+		if recorder.statusCode >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(recorder.statusCode))
+		}
+	})
+}
+
+// statusRecorder keeps the status code that a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush sends any buffered data to the client, if the underlying ResponseWriter supports it, so that streamed
+// responses work when they are being traced or audited
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// traceContext returns the trace context to set in the storage and evaluator requests that are sent for the HTTP
+// request, or nil if the request is not being traced
+func traceContext(r *http.Request) *protocol.TraceContext {
+	return helpers.SpanFromContext(r.Context()).Context()
+}
+
 func makeRequestInfo(r *http.Request) httpResponseRequestInfo {
 	hostname, _ := os.Hostname()
 	return httpResponseRequestInfo{
@@ -192,7 +303,8 @@ func makeRequestInfo(r *http.Request) httpResponseRequestInfo {
 	}
 }
 
-func (hc *Coordinator) writeResponse(w http.ResponseWriter, r *http.Request, statusCode int, jsonObj interface{}) {
+// setResponseHeaders sets the headers that every JSON response has
+func (hc *Coordinator) setResponseHeaders(w http.ResponseWriter) {
 	// Add CORS header, if configured
 	corsHeader := viper.GetString("general.access-control-allow-origin")
 	if corsHeader != "" {
@@ -200,10 +312,19 @@ func (hc *Coordinator) writeResponse(w http.ResponseWriter, r *http.Request, sta
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+}
+
+// writeEncodingError writes the response for a response that could not be encoded
+func writeEncodingError(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("{\"error\":true,\"message\":\"could not encode JSON\",\"result\":{}}"))
+}
+
+func (hc *Coordinator) writeResponse(w http.ResponseWriter, r *http.Request, statusCode int, jsonObj interface{}) {
+	hc.setResponseHeaders(w)
 
-	if jsonBytes, err := json.Marshal(jsonObj); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("{\"error\":true,\"message\":\"could not encode JSON\",\"result\":{}}"))
+	if jsonBytes, err := hc.json.Marshal(jsonObj); err != nil {
+		writeEncodingError(w)
 	} else {
 		w.WriteHeader(statusCode)
 		w.Write(jsonBytes)
@@ -20,6 +20,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -61,3 +62,31 @@ func TestHttpServer_DefaultHandler(t *testing.T) {
 
 	assert.True(t, resp.Error, "Expected response Error to be true")
 }
+
+func TestHttpServer_TracingMiddleware(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	viper.Set("tracing.enabled", true)
+	viper.Set("otlp.endpoint", collector.URL)
+	helpers.StartTracing(zap.NewNop())
+	defer helpers.StopTracing()
+
+	// The storage request is sent in the trace of the HTTP request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.NotNil(t, request.Trace, "Expected the storage request to have a trace context")
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", request.Trace.TraceID, "Expected the caller's trace ID")
+		request.Reply <- []string{"testcluster"}
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	rr := httptest.NewRecorder()
+	applyTracingMiddleware(coordinator.router).ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.Regexp(t, "^00-0af7651916cd43dd8448eb211c80319c-[0-9a-f]{16}-01$", rr.Header().Get("traceresponse"), "Expected the span in the traceresponse header")
+}
@@ -0,0 +1,73 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// handleEvents streams the events from the event bus to the client as server-sent events, until the client goes away.
+// Each event is sent with its type as the event name, and the event as JSON as the data. The type query parameter
+// (which may be given more than once) limits the events sent to those types. The stream is closed by the server when
+// the listener's timeout is reached, and clients are expected to reconnect, as EventSource does.
+func (hc *Coordinator) handleEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	types := make([]protocol.EventType, 0)
+	for _, name := range r.URL.Query()["type"] {
+		var eventType protocol.EventType
+		if err := eventType.UnmarshalText([]byte(name)); err != nil {
+			hc.writeErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		types = append(types, eventType)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	subscription := helpers.SubscribeEvents(types...)
+	defer subscription.Close()
+
+	if corsHeader := viper.GetString("general.access-control-allow-origin"); corsHeader != "" {
+		w.Header().Set("Access-Control-Allow-Origin", corsHeader)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			data, err := hc.json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestHttpServer_handleEvents(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	server := httptest.NewServer(coordinator.router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v3/events?type=status-change")
+	assert.NoError(t, err, "Expected request to return no error")
+	defer resp.Body.Close()
+	assert.Equalf(t, http.StatusOK, resp.StatusCode, "Expected response code to be 200, not %v", resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "Expected an event stream")
+
+	// The subscription is made before the headers are sent, so these are received
+	helpers.PublishEvent(&protocol.Event{Type: protocol.EventGroupExpired, Cluster: "testcluster", Group: "othergroup"})
+	helpers.PublishEvent(&protocol.Event{
+		Type:           protocol.EventStatusChange,
+		Timestamp:      1000,
+		Cluster:        "testcluster",
+		Group:          "testgroup",
+		PreviousStatus: protocol.StatusOK,
+		Status:         protocol.StatusError,
+	})
+
+	reader := bufio.NewReader(resp.Body)
+	line, _ := reader.ReadString('\n')
+	assert.Equal(t, "event: status-change\n", line, "Expected only the status change event")
+	line, _ = reader.ReadString('\n')
+	assert.Equal(t, `data: {"type":"status-change","timestamp":1000,"cluster":"testcluster","group":"testgroup","previous_status":"OK","status":"ERR"}`+"\n", line,
+		"Expected the event as JSON")
+}
+
+func TestHttpServer_handleEvents_BadType(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	req, err := http.NewRequest("GET", "/v3/events?type=nosuchtype", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusBadRequest, rr.Code, "Expected response code to be 400, not %v", rr.Code)
+}
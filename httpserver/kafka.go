@@ -12,10 +12,14 @@ package httpserver
 
 import (
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -24,15 +28,31 @@ func (hc *Coordinator) handleClusterList(w http.ResponseWriter, r *http.Request,
 	request := &protocol.StorageRequest{
 		RequestType: protocol.StorageFetchClusters,
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
+
+	// Only return the clusters that match every label selector (of the form name=value) in the query
+	selectors := r.URL.Query()["label"]
+	clusters := make([]string, 0)
+	labels := make(map[string]map[string]string)
+	for _, cluster := range response.([]string) {
+		if !helpers.MatchClusterLabels(cluster, selectors) {
+			continue
+		}
+		clusters = append(clusters, cluster)
+		if clusterLabels := helpers.GetClusterLabels(cluster); clusterLabels != nil {
+			labels[cluster] = clusterLabels
+		}
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
 
 	requestInfo := makeRequestInfo(r)
 	hc.writeResponse(w, r, http.StatusOK, httpResponseClusterList{
 		Error:    false,
 		Message:  "cluster list returned",
-		Clusters: response.([]string),
+		Clusters: clusters,
+		Labels:   labels,
 		Request:  requestInfo,
 	})
 }
@@ -44,11 +64,12 @@ func getTLSProfile(name string) *httpResponseTLSProfile {
 	}
 
 	return &httpResponseTLSProfile{
-		Name:     name,
-		CertFile: viper.GetString(configRoot + ".certfile"),
-		KeyFile:  viper.GetString(configRoot + ".keyfile"),
-		CAFile:   viper.GetString(configRoot + ".cafile"),
-		NoVerify: viper.GetBool(configRoot + ".noverify"),
+		Name:       name,
+		CertFile:   viper.GetString(configRoot + ".certfile"),
+		KeyFile:    viper.GetString(configRoot + ".keyfile"),
+		CAFile:     viper.GetString(configRoot + ".cafile"),
+		NoVerify:   viper.GetBool(configRoot + ".noverify"),
+		ServerName: viper.GetString(configRoot + ".server-name"),
 	}
 }
 
@@ -60,6 +81,7 @@ func getSASLProfile(name string) *httpResponseSASLProfile {
 
 	return &httpResponseSASLProfile{
 		Name:           name,
+		Mechanism:      viper.GetString(configRoot + ".mechanism"),
 		HandshakeFirst: viper.GetBool(configRoot + ".handshake-first"),
 		Username:       viper.GetString(configRoot + ".username"),
 	}
@@ -71,6 +93,7 @@ func getClientProfile(name string) httpResponseClientProfile {
 		Name:         name,
 		ClientID:     viper.GetString(configRoot + ".client-id"),
 		KafkaVersion: viper.GetString(configRoot + ".kafka-version"),
+		Preset:       viper.GetString(configRoot + ".preset"),
 		TLS:          getTLSProfile(viper.GetString(configRoot + ".tls")),
 		SASL:         getSASLProfile(viper.GetString(configRoot + ".sasl")),
 	}
@@ -92,35 +115,214 @@ func (hc *Coordinator) handleClusterDetail(w http.ResponseWriter, r *http.Reques
 				TopicRefresh:  viper.GetInt64(configRoot + ".topic-refresh"),
 				OffsetRefresh: viper.GetInt64(configRoot + ".offset-refresh"),
 				ClientProfile: getClientProfile(viper.GetString(configRoot + ".client-profile")),
+				Labels:        helpers.GetClusterLabels(params.ByName("cluster")),
 			},
 			Request: requestInfo,
 		})
 	}
 }
 
-func (hc *Coordinator) handleTopicList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	// Fetch topic list from the storage module
+// handleBrokerList returns the brokers in the cluster, and which of them is the controller, as seen by the cluster
+// module at its last metadata refresh
+func (hc *Coordinator) handleBrokerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	request := &protocol.StorageRequest{
-		RequestType: protocol.StorageFetchTopics,
+		RequestType: protocol.StorageFetchClusterBrokers,
 		Cluster:     params.ByName("cluster"),
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	response := hc.sendStorageRequest(r, request)
 
 	if response == nil {
-		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found or brokers not available")
 	} else {
+		brokers := response.(*protocol.ClusterBrokers)
 		requestInfo := makeRequestInfo(r)
-		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicList{
-			Error:   false,
-			Message: "topic list returned",
-			Topics:  response.([]string),
-			Request: requestInfo,
+		hc.writeResponse(w, r, http.StatusOK, httpResponseBrokerList{
+			Error:        false,
+			Message:      "broker list returned",
+			Brokers:      brokers.Brokers,
+			ControllerID: brokers.ControllerID,
+			Request:      requestInfo,
 		})
 	}
 }
 
+// handleClusterReplication returns the partitions in the cluster that were under-replicated or offline at the last
+// metadata refresh
+func (hc *Coordinator) handleClusterReplication(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterReplication,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
+
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found or replication status not available")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseClusterReplication{
+			Error:       false,
+			Message:     "cluster replication status returned",
+			Replication: response.(*protocol.ClusterReplication),
+			Request:     requestInfo,
+		})
+	}
+}
+
+func (hc *Coordinator) handleClusterLeaderChurn(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterLeaderChurn,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
+
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found or leader churn not available")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseClusterLeaderChurn{
+			Error:       false,
+			Message:     "cluster leader churn returned",
+			LeaderChurn: response.(*protocol.ClusterLeaderChurn),
+			Request:     requestInfo,
+		})
+	}
+}
+
+// handleClusterHealth returns a single health document for the cluster, for use by external uptime checks. The health
+// is ERR (with a 503 response code) if the cluster module is not fetching metadata or broker offsets, or if partitions
+// are offline. It is WARN if partitions are under-replicated, there is no controller, no recent consumer offset commits
+// have been seen, or any consumer group is in an ERR (or worse) state.
+func (hc *Coordinator) handleClusterHealth(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	cluster := params.ByName("cluster")
+	activityResponse := hc.fetchStorage(r, protocol.StorageFetchClusterActivity, cluster)
+	if activityResponse == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+	activity := activityResponse.(*protocol.ClusterActivity)
+
+	configRoot := "cluster." + cluster
+	now := time.Now().Unix() * 1000
+	health := httpResponseClusterHealth{
+		Error:           false,
+		Message:         "cluster health returned",
+		Brokers:         httpResponseHealthBrokers{Status: protocol.StatusError, ControllerID: -1},
+		Replication:     httpResponseHealthReplication{Status: protocol.StatusError},
+		Metadata:        httpResponseHealthAge{Status: protocol.StatusError, MaxAge: viper.GetInt64(configRoot+".health-max-metadata-age") * 1000},
+		BrokerOffsets:   getHealthAge(activity.LastBrokerOffset, now, viper.GetInt64(configRoot+".health-max-offset-age")*1000, protocol.StatusError),
+		ConsumerOffsets: getHealthAge(activity.LastConsumerOffset, now, viper.GetInt64(configRoot+".health-max-commit-age")*1000, protocol.StatusWarning),
+		Consumers:       httpResponseHealthConsumers{Status: protocol.StatusOK, Statuses: make(map[string]int)},
+		Request:         makeRequestInfo(r),
+	}
+
+	// The brokers and replication status are sent by the cluster module at the same time, at each metadata refresh
+	if brokersResponse := hc.fetchStorage(r, protocol.StorageFetchClusterBrokers, cluster); brokersResponse != nil {
+		brokers := brokersResponse.(*protocol.ClusterBrokers)
+		health.Metadata = getHealthAge(brokers.Timestamp, now, health.Metadata.MaxAge, protocol.StatusError)
+		health.Brokers.Count = len(brokers.Brokers)
+		health.Brokers.ControllerID = brokers.ControllerID
+		switch {
+		case len(brokers.Brokers) == 0:
+			health.Brokers.Status = protocol.StatusError
+		case brokers.ControllerID == -1:
+			health.Brokers.Status = protocol.StatusWarning
+		default:
+			health.Brokers.Status = protocol.StatusOK
+		}
+	}
+	if replicationResponse := hc.fetchStorage(r, protocol.StorageFetchClusterReplication, cluster); replicationResponse != nil {
+		replication := replicationResponse.(*protocol.ClusterReplication)
+		health.Replication.UnderReplicated = len(replication.UnderReplicated)
+		health.Replication.Offline = len(replication.Offline)
+		switch {
+		case len(replication.Offline) > 0:
+			health.Replication.Status = protocol.StatusError
+		case len(replication.UnderReplicated) > 0:
+			health.Replication.Status = protocol.StatusWarning
+		default:
+			health.Replication.Status = protocol.StatusOK
+		}
+	}
+
+	// Bad consumer groups are counted, but they are a problem with the consumers rather than the cluster, so they only
+	// make the cluster health WARN
+	if consumersResponse := hc.fetchStorage(r, protocol.StorageFetchConsumers, cluster); consumersResponse != nil {
+		for _, group := range consumersResponse.([]string) {
+			request := &protocol.EvaluatorRequest{
+				Cluster:   cluster,
+				Group:     group,
+				ShowAll:   false,
+				Reply:     make(chan *protocol.ConsumerGroupStatus),
+				Submitted: time.Now(),
+				Trace:     traceContext(r),
+			}
+			status := hc.sendEvaluatorRequest(r, request)
+
+			health.Consumers.Total++
+			health.Consumers.Statuses[status.Status.String()]++
+			if status.Status >= protocol.StatusError {
+				health.Consumers.Status = protocol.StatusWarning
+			}
+		}
+	}
+
+	health.Status = protocol.StatusOK
+	for _, status := range []protocol.StatusConstant{health.Brokers.Status, health.Replication.Status, health.Metadata.Status, health.BrokerOffsets.Status, health.ConsumerOffsets.Status, health.Consumers.Status} {
+		if status > health.Status {
+			health.Status = status
+		}
+	}
+
+	statusCode := http.StatusOK
+	if health.Status == protocol.StatusError {
+		statusCode = http.StatusServiceUnavailable
+	}
+	hc.writeResponse(w, r, statusCode, health)
+}
+
+// fetchStorage sends a fetch request of the given type for the cluster to the storage module, and returns the reply
+func (hc *Coordinator) fetchStorage(r *http.Request, requestType protocol.StorageRequestConstant, cluster string) interface{} {
+	request := &protocol.StorageRequest{
+		RequestType: requestType,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	return hc.sendStorageRequest(r, request)
+}
+
+// getHealthAge returns how long ago the timestamp was, and the status, which is badStatus if the timestamp is older
+// than maxAge or was never set
+func getHealthAge(timestamp, now, maxAge int64, badStatus protocol.StatusConstant) httpResponseHealthAge {
+	health := httpResponseHealthAge{
+		Status:    protocol.StatusOK,
+		Timestamp: timestamp,
+		MaxAge:    maxAge,
+	}
+	if timestamp > 0 {
+		health.Age = now - timestamp
+	}
+	if (timestamp == 0) || (health.Age > maxAge) {
+		health.Status = badStatus
+	}
+	return health
+}
+
+func (hc *Coordinator) handleTopicList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Stream the topic list from the storage module
+	hc.writeStreamedListResponse(w, r, protocol.StorageStreamTopics, params.ByName("cluster"), "topic list returned", "topics")
+}
+
 func (hc *Coordinator) handleTopicDetail(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch topic offsets from the storage module
 	request := &protocol.StorageRequest{
@@ -128,9 +330,10 @@ func (hc *Coordinator) handleTopicDetail(w http.ResponseWriter, r *http.Request,
 		Cluster:     params.ByName("cluster"),
 		Topic:       params.ByName("topic"),
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	response := hc.sendStorageRequest(r, request)
 
 	if response == nil {
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or topic not found")
@@ -145,53 +348,86 @@ func (hc *Coordinator) handleTopicDetail(w http.ResponseWriter, r *http.Request,
 	}
 }
 
-func (hc *Coordinator) handleTopicConsumerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	// Fetch topic offsets from the storage module
+// handleTopicProduceRates returns the number of messages per second being produced to each partition of the topic
+func (hc *Coordinator) handleTopicProduceRates(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	request := &protocol.StorageRequest{
-		RequestType: protocol.StorageFetchConsumersForTopic,
+		RequestType: protocol.StorageFetchTopicProduceRates,
 		Cluster:     params.ByName("cluster"),
 		Topic:       params.ByName("topic"),
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	response := hc.sendStorageRequest(r, request)
 
 	if response == nil {
-		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or topic not found")
 	} else {
 		requestInfo := makeRequestInfo(r)
-		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicConsumerDetail{
-			Error:     false,
-			Message:   "consumers of topic returned",
-			Consumers: response.([]string),
-			Request:   requestInfo,
+		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicProduceRates{
+			Error:        false,
+			Message:      "topic produce rates returned",
+			ProduceRates: response.([]float64),
+			Request:      requestInfo,
 		})
 	}
 }
 
-func (hc *Coordinator) handleConsumerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	// Fetch consumer list from the storage module
+// handleTopicConfig returns the configuration of the topic, as last fetched by the cluster module
+func (hc *Coordinator) handleTopicConfig(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	request := &protocol.StorageRequest{
-		RequestType: protocol.StorageFetchConsumers,
+		RequestType: protocol.StorageFetchTopicConfig,
 		Cluster:     params.ByName("cluster"),
+		Topic:       params.ByName("topic"),
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	response := hc.sendStorageRequest(r, request)
+
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or topic config not found")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicConfig{
+			Error:   false,
+			Message: "topic config returned",
+			Config:  response.(map[string]string),
+			Request: requestInfo,
+		})
+	}
+}
+
+func (hc *Coordinator) handleTopicConsumerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Fetch topic offsets from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumersForTopic,
+		Cluster:     params.ByName("cluster"),
+		Topic:       params.ByName("topic"),
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
 
 	if response == nil {
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
 	} else {
 		requestInfo := makeRequestInfo(r)
-		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerList{
+		hc.writeResponse(w, r, http.StatusOK, httpResponseTopicConsumerDetail{
 			Error:     false,
-			Message:   "consumer list returned",
+			Message:   "consumers of topic returned",
 			Consumers: response.([]string),
 			Request:   requestInfo,
 		})
 	}
 }
 
+func (hc *Coordinator) handleConsumerList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Stream the consumer list from the storage module
+	hc.writeStreamedListResponse(w, r, protocol.StorageStreamConsumers, params.ByName("cluster"), "consumer list returned", "consumers")
+}
+
 func (hc *Coordinator) handleConsumerDetail(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch consumer data from the storage module
 	request := &protocol.StorageRequest{
@@ -199,70 +435,120 @@ func (hc *Coordinator) handleConsumerDetail(w http.ResponseWriter, r *http.Reque
 		Cluster:     params.ByName("cluster"),
 		Group:       params.ByName("consumer"),
 		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
-	response := <-request.Reply
+	detail, _ := hc.sendStorageRequest(r, request).(*protocol.ConsumerGroupDetail)
 
-	if response == nil {
+	// An expected group that is not stored has no topics, and is not found either
+	if (detail == nil) || (detail.Topics == nil) {
 		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or consumer not found")
-	} else {
-		requestInfo := makeRequestInfo(r)
-		hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerDetail{
-			Error:   false,
-			Message: "consumer detail returned",
-			Topics:  response.(protocol.ConsumerTopics),
-			Request: requestInfo,
-		})
+		return
+	}
+
+	// It's not an error if the group has no members
+	members := detail.Members
+	if members == nil {
+		members = make([]*protocol.ConsumerGroupMember, 0)
 	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConsumerDetail{
+		Error:   false,
+		Message: "consumer detail returned",
+		Topics:  detail.Topics,
+		Members: members,
+		State:   detail.State,
+		Request: requestInfo,
+	})
 }
 
 func (hc *Coordinator) handleConsumerStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch consumer data from the storage module
 	request := &protocol.EvaluatorRequest{
-		Cluster: params.ByName("cluster"),
-		Group:   params.ByName("consumer"),
-		ShowAll: false,
-		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster:   params.ByName("cluster"),
+		Group:     params.ByName("consumer"),
+		ShowAll:   false,
+		Reply:     make(chan *protocol.ConsumerGroupStatus),
+		Submitted: time.Now(),
+		Trace:     traceContext(r),
 	}
-	hc.App.EvaluatorChannel <- request
-	response := <-request.Reply
+	response := hc.sendEvaluatorRequest(r, request)
 
 	responseCode := http.StatusOK
 	if response.Status == protocol.StatusNotFound {
 		responseCode = http.StatusNotFound
 	}
 
-	requestInfo := makeRequestInfo(r)
-	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatus{
-		Error:   false,
-		Message: "consumer status returned",
-		Status:  *response,
-		Request: requestInfo,
-	})
+	hc.writeConsumerStatusResponse(w, r, responseCode, "consumer status returned", response)
 }
 
 func (hc *Coordinator) handleConsumerStatusComplete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	// Fetch consumer data from the storage module
 	request := &protocol.EvaluatorRequest{
-		Cluster: params.ByName("cluster"),
-		Group:   params.ByName("consumer"),
-		ShowAll: true,
-		Reply:   make(chan *protocol.ConsumerGroupStatus),
+		Cluster:   params.ByName("cluster"),
+		Group:     params.ByName("consumer"),
+		ShowAll:   true,
+		Reply:     make(chan *protocol.ConsumerGroupStatus),
+		Submitted: time.Now(),
+		Trace:     traceContext(r),
 	}
-	hc.App.EvaluatorChannel <- request
-	response := <-request.Reply
+	response := hc.sendEvaluatorRequest(r, request)
 
 	responseCode := http.StatusOK
 	if response.Status == protocol.StatusNotFound {
 		responseCode = http.StatusNotFound
 	}
 
+	hc.writeConsumerStatusResponse(w, r, responseCode, "consumer status returned", response)
+}
+
+// handleSelfLag returns the status of the groups that the kafka consumer modules for the cluster report for their own
+// position in the offsets topic. If Burrow falls behind reading the offsets topic, every other status goes stale, so
+// this alerts if the group is WARN or worse, or if its total lag is over the self-lag-threshold for the module.
+func (hc *Coordinator) handleSelfLag(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	cluster := params.ByName("cluster")
+	if !viper.IsSet("cluster." + cluster) {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	consumers := make([]*httpResponseSelfLagConsumer, 0)
+	for name := range viper.GetStringMap("consumer") {
+		configRoot := "consumer." + name
+		if (viper.GetString(configRoot+".class-name") != "kafka") || (viper.GetString(configRoot+".cluster") != cluster) {
+			continue
+		}
+
+		request := &protocol.EvaluatorRequest{
+			Cluster:   cluster,
+			Group:     "burrow-" + name,
+			ShowAll:   true,
+			Reply:     make(chan *protocol.ConsumerGroupStatus),
+			Submitted: time.Now(),
+			Trace:     traceContext(r),
+		}
+		status := hc.sendEvaluatorRequest(r, request)
+
+		consumer := &httpResponseSelfLagConsumer{
+			Name:      name,
+			Group:     request.Group,
+			Threshold: viper.GetInt64(configRoot + ".self-lag-threshold"),
+			Status:    status,
+		}
+		if status.Status != protocol.StatusNotFound {
+			consumer.Alert = (status.Status >= protocol.StatusWarning) || ((consumer.Threshold > 0) && (int64(status.TotalLag) > consumer.Threshold))
+		}
+		consumers = append(consumers, consumer)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+
 	requestInfo := makeRequestInfo(r)
-	hc.writeResponse(w, r, responseCode, httpResponseConsumerStatus{
-		Error:   false,
-		Message: "consumer status returned",
-		Status:  *response,
-		Request: requestInfo,
+	hc.writeResponse(w, r, http.StatusOK, httpResponseSelfLag{
+		Error:     false,
+		Message:   "self lag returned",
+		Consumers: consumers,
+		Request:   requestInfo,
 	})
 }
 
@@ -272,8 +558,9 @@ func (hc *Coordinator) handleConsumerDelete(w http.ResponseWriter, r *http.Reque
 		RequestType: protocol.StorageSetDeleteGroup,
 		Cluster:     params.ByName("cluster"),
 		Group:       params.ByName("consumer"),
+		Trace:       traceContext(r),
 	}
-	hc.App.StorageChannel <- request
+	hc.sendStorageRequest(r, request)
 
 	requestInfo := makeRequestInfo(r)
 	hc.writeResponse(w, r, http.StatusOK, httpResponseError{
@@ -282,3 +569,159 @@ func (hc *Coordinator) handleConsumerDelete(w http.ResponseWriter, r *http.Reque
 		Request: requestInfo,
 	})
 }
+
+func (hc *Coordinator) handleExpectedGroupList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Fetch expected group list from the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchExpectedGroups,
+		Cluster:     params.ByName("cluster"),
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
+
+	if response == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+	} else {
+		requestInfo := makeRequestInfo(r)
+		hc.writeResponse(w, r, http.StatusOK, httpResponseExpectedGroupList{
+			Error:     false,
+			Message:   "expected consumer list returned",
+			Consumers: response.(map[string]int64),
+			Request:   requestInfo,
+		})
+	}
+}
+
+func (hc *Coordinator) handleExpectedGroupAdd(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Register the consumer as expected with the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+		Trace:       traceContext(r),
+	}
+	hc.sendStorageRequest(r, request)
+	if err := helpers.SaveDynamicExpectedGroup(params.ByName("cluster"), params.ByName("consumer"), true); err != nil {
+		hc.Log.Error("failed to save expected group", zap.String("cluster", params.ByName("cluster")), zap.String("consumer", params.ByName("consumer")), zap.Error(err))
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseError{
+		Error:   false,
+		Message: "expected consumer group added",
+		Request: requestInfo,
+	})
+}
+
+func (hc *Coordinator) handleExpectedGroupDelete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// Remove the consumer from the expected groups in the storage module
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteExpectedGroup,
+		Cluster:     params.ByName("cluster"),
+		Group:       params.ByName("consumer"),
+		Trace:       traceContext(r),
+	}
+	hc.sendStorageRequest(r, request)
+	if err := helpers.SaveDynamicExpectedGroup(params.ByName("cluster"), params.ByName("consumer"), false); err != nil {
+		hc.Log.Error("failed to save expected group", zap.String("cluster", params.ByName("cluster")), zap.String("consumer", params.ByName("consumer")), zap.Error(err))
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseError{
+		Error:   false,
+		Message: "expected consumer group removed",
+		Request: requestInfo,
+	})
+}
+
+// fetchConnectors returns all the connectors for the cluster, or nil if the cluster does not exist
+func (hc *Coordinator) fetchConnectors(r *http.Request, cluster string) []*protocol.Connector {
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConnectors,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	response := hc.sendStorageRequest(r, request)
+
+	if response == nil {
+		return nil
+	}
+	return response.([]*protocol.Connector)
+}
+
+// findConnector returns the named connector for the cluster, or nil if either the cluster or connector does not exist
+func (hc *Coordinator) findConnector(r *http.Request, cluster, name string) *protocol.Connector {
+	for _, connector := range hc.fetchConnectors(r, cluster) {
+		if connector.Name == name {
+			return connector
+		}
+	}
+	return nil
+}
+
+func (hc *Coordinator) handleConnectorList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	connectors := hc.fetchConnectors(r, params.ByName("cluster"))
+	if connectors == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConnectorList{
+		Error:      false,
+		Message:    "connector list returned",
+		Connectors: connectors,
+		Request:    requestInfo,
+	})
+}
+
+func (hc *Coordinator) handleConnectorDetail(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	connector := hc.findConnector(r, params.ByName("cluster"), params.ByName("connector"))
+	if connector == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or connector not found")
+		return
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConnectorDetail{
+		Error:     false,
+		Message:   "connector detail returned",
+		Connector: connector,
+		Request:   requestInfo,
+	})
+}
+
+func (hc *Coordinator) handleConnectorLag(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	connector := hc.findConnector(r, params.ByName("cluster"), params.ByName("connector"))
+	if connector == nil {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster or connector not found")
+		return
+	}
+
+	// Source connectors do not have a consumer group, so there is no lag to return
+	var status *protocol.ConsumerGroupStatus
+	if connector.Group != "" {
+		request := &protocol.EvaluatorRequest{
+			Cluster:   params.ByName("cluster"),
+			Group:     connector.Group,
+			ShowAll:   true,
+			Reply:     make(chan *protocol.ConsumerGroupStatus),
+			Submitted: time.Now(),
+			Trace:     traceContext(r),
+		}
+		status = hc.sendEvaluatorRequest(r, request)
+	}
+
+	requestInfo := makeRequestInfo(r)
+	hc.writeResponse(w, r, http.StatusOK, httpResponseConnectorLag{
+		Error:     false,
+		Message:   "connector lag returned",
+		Connector: connector,
+		Status:    status,
+		Request:   requestInfo,
+	})
+}
@@ -54,11 +54,38 @@ func TestHttpServer_handleClusterList(t *testing.T) {
 	assert.Equalf(t, []string{"testcluster"}, resp.Clusters, "Expected Clusters list to contain just testcluster, not %v", resp.Clusters)
 }
 
+func TestHttpServer_handleClusterList_Labels(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.prodcluster.labels", map[string]interface{}{"env": "prod", "region": "us-west"})
+	viper.Set("cluster.stagingcluster.labels", map[string]interface{}{"env": "staging"})
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		request.Reply <- []string{"prodcluster", "stagingcluster", "testcluster"}
+		close(request.Reply)
+	}()
+
+	// Only the clusters that match the selector are returned, with their labels
+	req, err := http.NewRequest("GET", "/v3/kafka?label=env=prod", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseClusterList
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equalf(t, []string{"prodcluster"}, resp.Clusters, "Expected Clusters list to contain just prodcluster, not %v", resp.Clusters)
+	assert.Equal(t, map[string]map[string]string{"prodcluster": {"env": "prod", "region": "us-west"}}, resp.Labels, "Expected the labels for prodcluster")
+}
+
 func TestHttpServer_handleClusterDetail(t *testing.T) {
 	coordinator := fixtureConfiguredCoordinator()
 	viper.Set("client-profile.test.client-id", "testid")
 	viper.Set("cluster.testcluster.class-name", "kafka")
 	viper.Set("cluster.testcluster.client-profile", "test")
+	viper.Set("cluster.testcluster.labels", map[string]interface{}{"env": "prod"})
 
 	// Set up a request
 	req, err := http.NewRequest("GET", "/v3/kafka/testcluster", nil)
@@ -85,6 +112,7 @@ func TestHttpServer_handleClusterDetail(t *testing.T) {
 	assert.NoError(t, err, "Expected body decode to return no error")
 	assert.False(t, resp.Error, "Expected response Error to be false")
 	assert.Equalf(t, "kafka", resp.Module.ClassName, "Expected response to contain a module with type kafka, not %v", resp.Module.ClassName)
+	assert.Equal(t, map[string]string{"env": "prod"}, resp.Module.Labels, "Expected response to contain the cluster labels")
 
 	// Call again for a 404
 	req, err = http.NewRequest("GET", "/v3/kafka/nocluster", nil)
@@ -100,14 +128,15 @@ func TestHttpServer_handleTopicList(t *testing.T) {
 	// Respond to the expected storage request
 	go func() {
 		request := <-coordinator.App.StorageChannel
-		assert.Equalf(t, protocol.StorageFetchTopics, request.RequestType, "Expected request of type StorageFetchTopics, not %v", request.RequestType)
+		assert.Equalf(t, protocol.StorageStreamTopics, request.RequestType, "Expected request of type StorageStreamTopics, not %v", request.RequestType)
 		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
-		request.Reply <- []string{"testtopic"}
+		request.Reply <- &protocol.StorageListChunk{Names: []string{"testtopic"}}
+		request.Reply <- &protocol.StorageListChunk{Names: []string{}, Last: true}
 		close(request.Reply)
 
 		// Second request is a 404
 		request = <-coordinator.App.StorageChannel
-		assert.Equalf(t, protocol.StorageFetchTopics, request.RequestType, "Expected request of type StorageFetchTopics, not %v", request.RequestType)
+		assert.Equalf(t, protocol.StorageStreamTopics, request.RequestType, "Expected request of type StorageStreamTopics, not %v", request.RequestType)
 		assert.Equalf(t, "nocluster", request.Cluster, "Expected request Cluster to be nocluster, not %v", request.Cluster)
 		close(request.Reply)
 	}()
@@ -144,14 +173,15 @@ func TestHttpServer_handleConsumerList(t *testing.T) {
 	// Respond to the expected storage request
 	go func() {
 		request := <-coordinator.App.StorageChannel
-		assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request of type StorageFetchConsumers, not %v", request.RequestType)
+		assert.Equalf(t, protocol.StorageStreamConsumers, request.RequestType, "Expected request of type StorageStreamConsumers, not %v", request.RequestType)
 		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
-		request.Reply <- []string{"testgroup"}
+		request.Reply <- &protocol.StorageListChunk{Names: []string{"testgroup"}}
+		request.Reply <- &protocol.StorageListChunk{Names: []string{}, Last: true}
 		close(request.Reply)
 
 		// Second request is a 404
 		request = <-coordinator.App.StorageChannel
-		assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request of type StorageFetchConsumers, not %v", request.RequestType)
+		assert.Equalf(t, protocol.StorageStreamConsumers, request.RequestType, "Expected request of type StorageStreamConsumers, not %v", request.RequestType)
 		assert.Equalf(t, "nocluster", request.Cluster, "Expected request Cluster to be nocluster, not %v", request.Cluster)
 		close(request.Reply)
 	}()
@@ -242,6 +272,281 @@ func TestHttpServer_handleTopicDetail(t *testing.T) {
 	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
 }
 
+func TestHttpServer_handleBrokerList(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterBrokers, request.RequestType, "Expected request of type StorageFetchClusterBrokers, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- &protocol.ClusterBrokers{
+			Brokers:      []*protocol.Broker{{ID: 1, Host: "broker1.example.com", Port: 9092, Rack: "rack-a"}},
+			ControllerID: 1,
+			Timestamp:    1234,
+		}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/broker", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseBrokerList
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, []*protocol.Broker{{ID: 1, Host: "broker1.example.com", Port: 9092, Rack: "rack-a"}}, resp.Brokers, "Expected the broker list")
+	assert.Equalf(t, int32(1), resp.ControllerID, "Expected ControllerID to be 1, not %v", resp.ControllerID)
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/broker", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleClusterReplication(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterReplication, request.RequestType, "Expected request of type StorageFetchClusterReplication, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- &protocol.ClusterReplication{
+			UnderReplicated: []*protocol.PartitionReplicas{{Topic: "testtopic", Partition: 3, Replicas: []int32{1, 2}, InSyncReplicas: []int32{1}, Since: 1234}},
+			Offline:         []*protocol.PartitionReplicas{},
+			Timestamp:       5678,
+		}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/replication", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseClusterReplication
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Lenf(t, resp.Replication.UnderReplicated, 1, "Expected 1 under-replicated partition, not %v", len(resp.Replication.UnderReplicated))
+	assert.Equalf(t, int32(3), resp.Replication.UnderReplicated[0].Partition, "Expected partition 3, not %v", resp.Replication.UnderReplicated[0].Partition)
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/replication", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleClusterLeaderChurn(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterLeaderChurn, request.RequestType, "Expected request of type StorageFetchClusterLeaderChurn, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- &protocol.ClusterLeaderChurn{
+			Partitions: []*protocol.PartitionLeaderChurn{{Topic: "testtopic", Partition: 3, Leader: 2, LeaderChanges: 4, LastChange: 1234}},
+			Window:     3600,
+			Timestamp:  5678,
+		}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/leader-churn", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseClusterLeaderChurn
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Lenf(t, resp.LeaderChurn.Partitions, 1, "Expected 1 partition, not %v", len(resp.LeaderChurn.Partitions))
+	assert.Equalf(t, 4, resp.LeaderChurn.Partitions[0].LeaderChanges, "Expected 4 leader changes, not %v", resp.LeaderChurn.Partitions[0].LeaderChanges)
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/leader-churn", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleTopicProduceRates(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchTopicProduceRates, request.RequestType, "Expected request of type StorageFetchTopicProduceRates, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+		request.Reply <- []float64{12.5, 0}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/rate", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseTopicProduceRates
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, []float64{12.5, 0}, resp.ProduceRates, "Expected the produce rates to be returned")
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/topic/notopic/rate", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleClusterHealth(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.health-max-offset-age", 30)
+	viper.Set("cluster.testcluster.health-max-metadata-age", 180)
+	viper.Set("cluster.testcluster.health-max-commit-age", 600)
+
+	// Respond to the expected storage and evaluator requests
+	now := time.Now().Unix() * 1000
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterActivity, request.RequestType, "Expected request of type StorageFetchClusterActivity, not %v", request.RequestType)
+		request.Reply <- &protocol.ClusterActivity{LastBrokerOffset: now - 5000, LastConsumerOffset: now - 10000}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterBrokers, request.RequestType, "Expected request of type StorageFetchClusterBrokers, not %v", request.RequestType)
+		request.Reply <- &protocol.ClusterBrokers{Brokers: []*protocol.Broker{{ID: 1}, {ID: 2}}, ControllerID: 1, Timestamp: now - 60000}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchClusterReplication, request.RequestType, "Expected request of type StorageFetchClusterReplication, not %v", request.RequestType)
+		request.Reply <- &protocol.ClusterReplication{
+			UnderReplicated: []*protocol.PartitionReplicas{{Topic: "testtopic", Partition: 0}},
+			Offline:         []*protocol.PartitionReplicas{},
+		}
+		close(request.Reply)
+
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConsumers, request.RequestType, "Expected request of type StorageFetchConsumers, not %v", request.RequestType)
+		request.Reply <- []string{"goodgroup", "badgroup"}
+		close(request.Reply)
+
+		evalRequest := <-coordinator.App.EvaluatorChannel
+		evalRequest.Reply <- &protocol.ConsumerGroupStatus{Cluster: "testcluster", Group: evalRequest.Group, Status: protocol.StatusOK}
+		evalRequest = <-coordinator.App.EvaluatorChannel
+		evalRequest.Reply <- &protocol.ConsumerGroupStatus{Cluster: "testcluster", Group: evalRequest.Group, Status: protocol.StatusError}
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/health", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp map[string]interface{}
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Equalf(t, "WARN", resp["status"], "Expected health status to be WARN, not %v", resp["status"])
+	assert.Equal(t, "OK", resp["brokers"].(map[string]interface{})["status"], "Expected brokers to be OK")
+	assert.Equal(t, "WARN", resp["replication"].(map[string]interface{})["status"], "Expected replication to be WARN")
+	assert.Equal(t, "OK", resp["metadata"].(map[string]interface{})["status"], "Expected metadata to be OK")
+	assert.Equal(t, "OK", resp["broker_offsets"].(map[string]interface{})["status"], "Expected broker offsets to be OK")
+	assert.Equal(t, "OK", resp["consumer_offsets"].(map[string]interface{})["status"], "Expected consumer offsets to be OK")
+	assert.Equal(t, map[string]interface{}{"OK": float64(1), "ERR": float64(1)}, resp["consumers"].(map[string]interface{})["statuses"], "Expected one OK and one ERR consumer")
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/health", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestGetHealthAge(t *testing.T) {
+	health := getHealthAge(1000, 5000, 10000, protocol.StatusError)
+	assert.Equal(t, httpResponseHealthAge{Status: protocol.StatusOK, Timestamp: 1000, Age: 4000, MaxAge: 10000}, health, "Expected a recent timestamp to be OK")
+
+	health = getHealthAge(1000, 50000, 10000, protocol.StatusError)
+	assert.Equalf(t, protocol.StatusError, health.Status, "Expected an old timestamp to be ERR, not %v", health.Status)
+
+	health = getHealthAge(0, 50000, 10000, protocol.StatusWarning)
+	assert.Equalf(t, protocol.StatusWarning, health.Status, "Expected a missing timestamp to be WARN, not %v", health.Status)
+}
+
+func TestHttpServer_handleTopicConfig(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchTopicConfig, request.RequestType, "Expected request of type StorageFetchTopicConfig, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+		request.Reply <- map[string]string{"retention.ms": "86400000"}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		close(request.Reply)
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/topic/testtopic/config", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	var resp httpResponseTopicConfig
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equal(t, map[string]string{"retention.ms": "86400000"}, resp.Config, "Expected the topic config")
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/topic/notopic/config", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
 func TestHttpServer_handleConsumerDetail(t *testing.T) {
 	coordinator := fixtureConfiguredCoordinator()
 
@@ -263,7 +568,18 @@ func TestHttpServer_handleConsumerDetail(t *testing.T) {
 			Timestamp: 12837487,
 			Lag:       &protocol.Lag{Value: 2355},
 		}
-		request.Reply <- response
+		request.Reply <- &protocol.ConsumerGroupDetail{
+			Topics: response,
+			Members: []*protocol.ConsumerGroupMember{
+				{
+					MemberID:   "testmember",
+					ClientID:   "testclient",
+					ClientHost: "somehost",
+					Assignment: map[string][]int32{"testtopic": {0}},
+				},
+			},
+			State: "Stable",
+		}
 		close(request.Reply)
 
 		// Second request is a 404
@@ -273,11 +589,12 @@ func TestHttpServer_handleConsumerDetail(t *testing.T) {
 		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
 		close(request.Reply)
 
-		// Third request is a 404
+		// Third request is a 404, as the group is expected but not stored
 		request = <-coordinator.App.StorageChannel
 		assert.Equalf(t, protocol.StorageFetchConsumer, request.RequestType, "Expected request of type StorageFetchConsumer, not %v", request.RequestType)
 		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
 		assert.Equalf(t, "nogroup", request.Group, "Expected request Group to be nogroup, not %v", request.Group)
+		request.Reply <- &protocol.ConsumerGroupDetail{ExpectedSince: 12837487}
 		close(request.Reply)
 	}()
 
@@ -307,6 +624,10 @@ func TestHttpServer_handleConsumerDetail(t *testing.T) {
 	assert.Equalf(t, int64(9837458), topic[0].Offsets[0].Offset, "Expected Offset to be 9837458, not %v", topic[0].Offsets[0].Offset)
 	assert.Equalf(t, int64(12837487), topic[0].Offsets[0].Timestamp, "Expected Timestamp to be 12837487, not %v", topic[0].Offsets[0].Timestamp)
 	assert.Equalf(t, &protocol.Lag{Value: uint64(2355)}, topic[0].Offsets[0].Lag, "Expected Lag to be 2355, not %v", topic[0].Offsets[0].Lag)
+	assert.Lenf(t, resp.Members, 1, "Expected response to contain exactly one member, not %v", len(resp.Members))
+	assert.Equalf(t, "testmember", resp.Members[0].MemberID, "Expected member MemberID to be testmember, not %v", resp.Members[0].MemberID)
+	assert.Equalf(t, []int32{0}, resp.Members[0].Assignment["testtopic"], "Expected member to be assigned testtopic partition 0, not %v", resp.Members[0].Assignment)
+	assert.Equalf(t, "Stable", resp.State, "Expected response State to be Stable, not %v", resp.State)
 
 	// Call again for a 404
 	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/consumer/testgroup", nil)
@@ -562,3 +883,364 @@ func TestHttpServer_handleConsumerDelete(t *testing.T) {
 	assert.NoError(t, err, "Expected body decode to return no error")
 	assert.False(t, resp.Error, "Expected response Error to be false")
 }
+
+func TestHttpServer_handleExpectedGroupList(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchExpectedGroups, request.RequestType, "Expected request of type StorageFetchExpectedGroups, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- map[string]int64{"testgroup": 1234}
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchExpectedGroups, request.RequestType, "Expected request of type StorageFetchExpectedGroups, not %v", request.RequestType)
+		assert.Equalf(t, "nocluster", request.Cluster, "Expected request Cluster to be nocluster, not %v", request.Cluster)
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/expected", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseExpectedGroupList
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, map[string]int64{"testgroup": 1234}, resp.Consumers, "Expected Consumers to contain just testgroup, not %v", resp.Consumers)
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/expected", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleExpectedGroupAdd(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageSetExpectedGroup, request.RequestType, "Expected request of type StorageSetExpectedGroup, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		// No response expected
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("PUT", "/v3/kafka/testcluster/expected/testgroup", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Sleep briefly just to catch the goroutine above throwing a failure
+	time.Sleep(100 * time.Millisecond)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseError
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+}
+
+func TestHttpServer_handleExpectedGroupDelete(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageSetDeleteExpectedGroup, request.RequestType, "Expected request of type StorageSetDeleteExpectedGroup, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "testgroup", request.Group, "Expected request Group to be testgroup, not %v", request.Group)
+		// No response expected
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("DELETE", "/v3/kafka/testcluster/expected/testgroup", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Sleep briefly just to catch the goroutine above throwing a failure
+	time.Sleep(100 * time.Millisecond)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseError
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+}
+
+func fixtureConnectors() []*protocol.Connector {
+	return []*protocol.Connector{
+		{
+			Name:           "testsink",
+			ConnectCluster: "testconnect",
+			Type:           "sink",
+			State:          "RUNNING",
+			Group:          "connect-testsink",
+			Tasks:          []*protocol.ConnectorTask{{ID: 0, State: "RUNNING"}},
+		},
+		{
+			Name:           "testsource",
+			ConnectCluster: "testconnect",
+			Type:           "source",
+			State:          "RUNNING",
+			Tasks:          []*protocol.ConnectorTask{{ID: 0, State: "FAILED", Trace: "test error"}},
+		},
+	}
+}
+
+func TestHttpServer_handleConnectorList(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage request
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConnectors, request.RequestType, "Expected request of type StorageFetchConnectors, not %v", request.RequestType)
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		request.Reply <- fixtureConnectors()
+		close(request.Reply)
+
+		// Second request is a 404
+		request = <-coordinator.App.StorageChannel
+		assert.Equalf(t, "nocluster", request.Cluster, "Expected request Cluster to be nocluster, not %v", request.Cluster)
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/connector", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseConnectorList
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Lenf(t, resp.Connectors, 2, "Expected response to contain exactly two connectors, not %v", len(resp.Connectors))
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/nocluster/connector", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+func TestHttpServer_handleConnectorDetail(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage requests
+	go func() {
+		for i := 0; i < 2; i++ {
+			request := <-coordinator.App.StorageChannel
+			assert.Equalf(t, protocol.StorageFetchConnectors, request.RequestType, "Expected request of type StorageFetchConnectors, not %v", request.RequestType)
+			request.Reply <- fixtureConnectors()
+			close(request.Reply)
+		}
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/connector/testsource", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp httpResponseConnectorDetail
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, "testsource", resp.Connector.Name, "Expected connector Name to be testsource, not %v", resp.Connector.Name)
+	assert.Equalf(t, "FAILED", resp.Connector.Tasks[0].State, "Expected task State to be FAILED, not %v", resp.Connector.Tasks[0].State)
+
+	// Call again for a 404
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/connector/noconnector", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
+
+// Custom response type for connector lag, as the status field will be a string
+type ResponseConnectorLag struct {
+	Error     bool                    `json:"error"`
+	Message   string                  `json:"message"`
+	Connector *protocol.Connector     `json:"connector"`
+	Status    *ResponseStatus         `json:"status"`
+	Request   httpResponseRequestInfo `json:"request"`
+}
+
+func TestHttpServer_handleConnectorLag(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the expected storage and evaluator requests
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageFetchConnectors, request.RequestType, "Expected request of type StorageFetchConnectors, not %v", request.RequestType)
+		request.Reply <- fixtureConnectors()
+		close(request.Reply)
+
+		evaluatorRequest := <-coordinator.App.EvaluatorChannel
+		assert.Equalf(t, "testcluster", evaluatorRequest.Cluster, "Expected request Cluster to be testcluster, not %v", evaluatorRequest.Cluster)
+		assert.Equalf(t, "connect-testsink", evaluatorRequest.Group, "Expected request Group to be connect-testsink, not %v", evaluatorRequest.Group)
+		assert.True(t, evaluatorRequest.ShowAll, "Expected request ShowAll to be True")
+		evaluatorRequest.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:    evaluatorRequest.Cluster,
+			Group:      evaluatorRequest.Group,
+			Status:     protocol.StatusWarning,
+			Complete:   1.0,
+			Partitions: make([]*protocol.PartitionStatus, 0),
+			TotalLag:   1234,
+		}
+		close(evaluatorRequest.Reply)
+
+		// The source connector has no group, so there is no evaluator request
+		request = <-coordinator.App.StorageChannel
+		request.Reply <- fixtureConnectors()
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/connector/testsink/lag", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp ResponseConnectorLag
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Equalf(t, "testsink", resp.Connector.Name, "Expected connector Name to be testsink, not %v", resp.Connector.Name)
+	assert.NotNil(t, resp.Status, "Expected response Status to be present")
+	assert.Equalf(t, uint64(1234), resp.Status.TotalLag, "Expected TotalLag to be 1234, not %v", resp.Status.TotalLag)
+
+	// Call again for the source connector
+	req, err = http.NewRequest("GET", "/v3/kafka/testcluster/connector/testsource/lag", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr = httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	decoder = json.NewDecoder(rr.Body)
+	resp = ResponseConnectorLag{}
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.Nil(t, resp.Status, "Expected response Status to be nil for a source connector")
+}
+
+type ResponseSelfLag struct {
+	Error     bool   `json:"error"`
+	Message   string `json:"message"`
+	Consumers []struct {
+		Name      string          `json:"name"`
+		Group     string          `json:"group"`
+		Threshold int64           `json:"threshold"`
+		Alert     bool            `json:"alert"`
+		Status    *ResponseStatus `json:"status"`
+	} `json:"consumers"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
+func TestHttpServer_handleSelfLag(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("consumer.testconsumer.class-name", "kafka")
+	viper.Set("consumer.testconsumer.cluster", "testcluster")
+	viper.Set("consumer.testconsumer.self-lag-threshold", 1000)
+	viper.Set("consumer.otheradmin.class-name", "kafka_admin")
+	viper.Set("consumer.otheradmin.cluster", "testcluster")
+
+	// Respond to the expected evaluator request. The group is OK, but the lag is over the threshold
+	go func() {
+		request := <-coordinator.App.EvaluatorChannel
+		assert.Equalf(t, "testcluster", request.Cluster, "Expected request Cluster to be testcluster, not %v", request.Cluster)
+		assert.Equalf(t, "burrow-testconsumer", request.Group, "Expected request Group to be burrow-testconsumer, not %v", request.Group)
+		assert.True(t, request.ShowAll, "Expected request ShowAll to be True")
+		request.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster:    request.Cluster,
+			Group:      request.Group,
+			Status:     protocol.StatusOK,
+			Complete:   1.0,
+			Partitions: make([]*protocol.PartitionStatus, 0),
+			TotalLag:   1234,
+		}
+		close(request.Reply)
+	}()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/self-lag", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	// Parse response body
+	decoder := json.NewDecoder(rr.Body)
+	var resp ResponseSelfLag
+	err = decoder.Decode(&resp)
+	assert.NoError(t, err, "Expected body decode to return no error")
+	assert.False(t, resp.Error, "Expected response Error to be false")
+	assert.Lenf(t, resp.Consumers, 1, "Expected response to contain exactly one consumer, not %v", len(resp.Consumers))
+	assert.Equalf(t, "testconsumer", resp.Consumers[0].Name, "Expected consumer Name to be testconsumer, not %v", resp.Consumers[0].Name)
+	assert.Equalf(t, int64(1000), resp.Consumers[0].Threshold, "Expected consumer Threshold to be 1000, not %v", resp.Consumers[0].Threshold)
+	assert.True(t, resp.Consumers[0].Alert, "Expected consumer Alert to be true")
+	assert.Equalf(t, uint64(1234), resp.Consumers[0].Status.TotalLag, "Expected TotalLag to be 1234, not %v", resp.Consumers[0].Status.TotalLag)
+}
+
+func TestHttpServer_handleSelfLag_BadCluster(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Set up a request
+	req, err := http.NewRequest("GET", "/v3/kafka/nocluster/self-lag", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Call the handler via httprouter
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusNotFound, rr.Code, "Expected response code to be 404, not %v", rr.Code)
+}
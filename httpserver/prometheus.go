@@ -0,0 +1,148 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// prometheusFamily is one metric in the Prometheus text format, with a line for each of its samples
+type prometheusFamily struct {
+	name    string
+	help    string
+	samples []string
+}
+
+// add appends a sample with the labels given as name, value pairs. Every sample of a family has the same label names,
+// with an empty value if it is not known, so that the label sets are stable
+func (family *prometheusFamily) add(value float64, labels ...string) {
+	var line strings.Builder
+	line.WriteString(family.name)
+	line.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		line.WriteString(labels[i])
+		line.WriteString("=\"")
+		line.WriteString(prometheusLabelEscaper.Replace(labels[i+1]))
+		line.WriteByte('"')
+	}
+	line.WriteString("} ")
+	line.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	family.samples = append(family.samples, line.String())
+}
+
+var prometheusLabelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+// handlePrometheusMetrics returns the status and lag of every consumer group, and the broker offsets of every topic, in
+// the Prometheus text format. The metric names and labels are the same as the Burrow exporters that dashboards are
+// commonly built on: cluster, consumer_group, topic, and partition, with the owner and client_id of each partition
+// (which are empty if the group does not report them). The status metrics use the values of protocol.StatusConstant.
+//
+// A group or topic that Burrow no longer has is left out of the response, so Prometheus marks its series as stale. The
+// time of the last commit for each partition is included, so that a group that has stopped committing can be found.
+func (hc *Coordinator) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	groupStatus := &prometheusFamily{name: "burrow_kafka_consumer_status", help: "The status of the consumer group"}
+	groupLag := &prometheusFamily{name: "burrow_kafka_consumer_total_lag", help: "The total lag of the consumer group"}
+	groupPartitions := &prometheusFamily{name: "burrow_kafka_consumer_partition_count", help: "The number of partitions the consumer group has committed offsets for"}
+	partitionStatus := &prometheusFamily{name: "burrow_kafka_consumer_partition_status", help: "The status of the partition for the consumer group"}
+	partitionLag := &prometheusFamily{name: "burrow_kafka_consumer_partition_lag", help: "The lag of the partition for the consumer group"}
+	partitionOffset := &prometheusFamily{name: "burrow_kafka_consumer_current_offset", help: "The last offset committed for the partition by the consumer group"}
+	partitionTimeLag := &prometheusFamily{name: "burrow_kafka_consumer_partition_time_lag_seconds", help: "How far behind the consumer group is in time for the partition"}
+	partitionCommit := &prometheusFamily{name: "burrow_kafka_consumer_partition_last_commit_timestamp_seconds", help: "The time of the last offset committed for the partition by the consumer group"}
+	topicOffset := &prometheusFamily{name: "burrow_kafka_topic_partition_offset", help: "The latest broker offset of the partition"}
+
+	clusters, _ := hc.fetchStorage(r, protocol.StorageFetchClusters, "").([]string)
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		groups, _ := hc.fetchStorage(r, protocol.StorageFetchConsumers, cluster).([]string)
+		if metaGroup := helpers.GetMetaGroup(); metaGroup != "" {
+			groups = append(groups, metaGroup)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			status := hc.sendEvaluatorRequest(r, &protocol.EvaluatorRequest{
+				Cluster:   cluster,
+				Group:     group,
+				ShowAll:   true,
+				Reply:     make(chan *protocol.ConsumerGroupStatus),
+				Submitted: time.Now(),
+				Trace:     traceContext(r),
+			})
+			// The group can be removed from storage between the list and the evaluation
+			if status.Status == protocol.StatusNotFound {
+				continue
+			}
+
+			groupStatus.add(float64(status.Status), "cluster", cluster, "consumer_group", group)
+			groupLag.add(float64(status.TotalLag), "cluster", cluster, "consumer_group", group)
+			groupPartitions.add(float64(status.TotalPartitions), "cluster", cluster, "consumer_group", group)
+			for _, partition := range status.Partitions {
+				labels := []string{
+					"cluster", cluster,
+					"consumer_group", group,
+					"topic", partition.Topic,
+					"partition", strconv.FormatInt(int64(partition.Partition), 10),
+					"owner", partition.Owner,
+					"client_id", partition.ClientID,
+				}
+				partitionStatus.add(float64(partition.Status), labels...)
+				partitionLag.add(float64(partition.CurrentLag), labels...)
+				partitionTimeLag.add(float64(partition.TimeLag)/1000, labels...)
+				if partition.End != nil {
+					partitionOffset.add(float64(partition.End.Offset), labels...)
+					partitionCommit.add(float64(partition.End.Timestamp)/1000, labels...)
+				}
+			}
+		}
+
+		topics, _ := hc.fetchStorage(r, protocol.StorageFetchTopics, cluster).([]string)
+		sort.Strings(topics)
+		for _, topic := range topics {
+			offsets, _ := hc.sendStorageRequest(r, &protocol.StorageRequest{
+				RequestType: protocol.StorageFetchTopic,
+				Cluster:     cluster,
+				Topic:       topic,
+				Reply:       make(chan interface{}),
+				Submitted:   time.Now(),
+				Trace:       traceContext(r),
+			}).([]int64)
+			for partition, offset := range offsets {
+				topicOffset.add(float64(offset), "cluster", cluster, "topic", topic, "partition", strconv.Itoa(partition))
+			}
+		}
+	}
+
+	var body bytes.Buffer
+	for _, family := range []*prometheusFamily{groupStatus, groupLag, groupPartitions, partitionStatus, partitionLag,
+		partitionOffset, partitionTimeLag, partitionCommit, topicOffset} {
+		body.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		body.WriteString("# TYPE " + family.name + " gauge\n")
+		for _, sample := range family.samples {
+			body.WriteString(sample + "\n")
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
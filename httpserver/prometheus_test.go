@@ -0,0 +1,98 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestHttpServer_handlePrometheusMetrics(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Respond to the storage requests for one cluster, with one group that exists and one that is removed before it is
+	// evaluated, and one topic
+	go func() {
+		for request := range coordinator.App.StorageChannel {
+			switch request.RequestType {
+			case protocol.StorageFetchClusters:
+				request.Reply <- []string{"testcluster"}
+			case protocol.StorageFetchConsumers:
+				request.Reply <- []string{"testgroup", "removedgroup"}
+			case protocol.StorageFetchTopics:
+				request.Reply <- []string{"testtopic"}
+			case protocol.StorageFetchTopic:
+				assert.Equalf(t, "testtopic", request.Topic, "Expected request Topic to be testtopic, not %v", request.Topic)
+				request.Reply <- []int64{1000, 2000}
+			}
+		}
+	}()
+	defer close(coordinator.App.StorageChannel)
+	go func() {
+		for request := range coordinator.App.EvaluatorChannel {
+			assert.True(t, request.ShowAll, "Expected request ShowAll to be True")
+			if request.Group != "testgroup" {
+				request.Reply <- &protocol.ConsumerGroupStatus{Cluster: request.Cluster, Group: request.Group, Status: protocol.StatusNotFound}
+				continue
+			}
+			request.Reply <- &protocol.ConsumerGroupStatus{
+				Cluster:         request.Cluster,
+				Group:           request.Group,
+				Status:          protocol.StatusWarning,
+				TotalPartitions: 1,
+				TotalLag:        500,
+				Partitions: []*protocol.PartitionStatus{{
+					Topic:      "testtopic",
+					Partition:  1,
+					Owner:      "testhost",
+					Status:     protocol.StatusWarning,
+					End:        &protocol.ConsumerOffset{Offset: 1500, Timestamp: 1500000000000},
+					CurrentLag: 500,
+					TimeLag:    2500,
+				}},
+			}
+		}
+	}()
+	defer close(coordinator.App.EvaluatorChannel)
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	coordinator.router.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"), "Expected the Prometheus text format")
+
+	lines := strings.Split(rr.Body.String(), "\n")
+	partitionLabels := `{cluster="testcluster",consumer_group="testgroup",topic="testtopic",partition="1",owner="testhost",client_id=""}`
+	assert.Contains(t, lines, "# TYPE burrow_kafka_consumer_status gauge", "Expected the metric type")
+	assert.Contains(t, lines, `burrow_kafka_consumer_status{cluster="testcluster",consumer_group="testgroup"} 2`, "Expected the group status")
+	assert.Contains(t, lines, `burrow_kafka_consumer_total_lag{cluster="testcluster",consumer_group="testgroup"} 500`, "Expected the total lag")
+	assert.Contains(t, lines, "burrow_kafka_consumer_partition_lag"+partitionLabels+" 500", "Expected the partition lag")
+	assert.Contains(t, lines, "burrow_kafka_consumer_current_offset"+partitionLabels+" 1500", "Expected the committed offset")
+	assert.Contains(t, lines, "burrow_kafka_consumer_partition_time_lag_seconds"+partitionLabels+" 2.5", "Expected the time lag")
+	assert.Contains(t, lines, "burrow_kafka_consumer_partition_last_commit_timestamp_seconds"+partitionLabels+" 1.5e+09",
+		"Expected the time of the last commit")
+	assert.Contains(t, lines, `burrow_kafka_topic_partition_offset{cluster="testcluster",topic="testtopic",partition="1"} 2000`,
+		"Expected the broker offset")
+	assert.NotContains(t, rr.Body.String(), "removedgroup", "Expected the removed group to be left out")
+}
+
+func TestPrometheusFamily_add(t *testing.T) {
+	family := &prometheusFamily{name: "test_metric"}
+	family.add(1, "label", "a \"quoted\\\" value\n")
+	assert.Equal(t, []string{`test_metric{label="a \"quoted\\\" value\n"} 1`}, family.samples, "Expected the label value to be escaped")
+}
@@ -0,0 +1,116 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+type requestTimingContextKey struct{}
+
+// requestTiming is the time that the handler for a request spent waiting on the storage and evaluator subsystems
+type requestTiming struct {
+	storageTime       time.Duration
+	storageRequests   int
+	evaluatorTime     time.Duration
+	evaluatorRequests int
+}
+
+// applySlowRequestMiddleware logs each request that takes longer than the slow-request-threshold of the listener (in
+// milliseconds) to handle, with the time that was spent waiting on the storage and evaluator subsystems, so that the
+// requests responsible for tail latency can be found. If the threshold is 0 (the default), requests are not timed.
+func (hc *Coordinator) applySlowRequestMiddleware(httpServerConfigName string, next http.Handler) http.Handler {
+	threshold := time.Duration(viper.GetInt64(httpServerConfigName+".slow-request-threshold")) * time.Millisecond
+	if threshold <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &requestTiming{}
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		startTime := time.Now()
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestTimingContextKey{}, timing)))
+
+		elapsed := time.Since(startTime)
+		if elapsed < threshold {
+			return
+		}
+		params := make(map[string]string)
+		if _, routeParams, _ := hc.router.Lookup(r.Method, r.URL.Path); routeParams != nil {
+			for _, param := range routeParams {
+				params[param.Key] = param.Value
+			}
+		}
+		hc.Log.Warn("slow request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("query", r.URL.RawQuery),
+			zap.Any("params", params),
+			zap.Int("status", recorder.statusCode),
+			zap.Duration("elapsed", elapsed),
+			zap.Duration("storage_time", timing.storageTime),
+			zap.Int("storage_requests", timing.storageRequests),
+			zap.Duration("evaluator_time", timing.evaluatorTime),
+			zap.Int("evaluator_requests", timing.evaluatorRequests),
+		)
+	})
+}
+
+// storageRequestPriority returns the priority to send a storage request from the HTTP server with. Fetches have the API
+// priority, so they wait for requests from other subsystems. Requests that change the stored information, such as
+// deleting a group, have the ingestion priority, so that they are handled in order with the offsets and groups that the
+// clusters and consumers store.
+func storageRequestPriority(requestType protocol.StorageRequestConstant) protocol.StoragePriority {
+	switch requestType {
+	case protocol.StorageSetDeleteTopic, protocol.StorageSetDeleteGroup, protocol.StorageSetExpectedGroup, protocol.StorageSetDeleteExpectedGroup:
+		return protocol.StoragePriorityIngestion
+	default:
+		return protocol.StoragePriorityAPI
+	}
+}
+
+// sendStorageRequest sends the request to the storage subsystem for the HTTP request, and returns the reply, or nil if
+// the request has no reply channel. The channel is selected by storageRequestPriority. The time spent is added to the
+// timing of the HTTP request, if it is being timed.
+func (hc *Coordinator) sendStorageRequest(r *http.Request, request *protocol.StorageRequest) interface{} {
+	startTime := time.Now()
+	hc.App.GetStorageChannel(storageRequestPriority(request.RequestType)) <- request
+	var response interface{}
+	if request.Reply != nil {
+		response = <-request.Reply
+	}
+
+	if timing, ok := r.Context().Value(requestTimingContextKey{}).(*requestTiming); ok {
+		timing.storageTime += time.Since(startTime)
+		timing.storageRequests++
+	}
+	return response
+}
+
+// sendEvaluatorRequest sends the request to the evaluator subsystem for the HTTP request, and returns the status. The
+// time spent is added to the timing of the HTTP request, if it is being timed.
+func (hc *Coordinator) sendEvaluatorRequest(r *http.Request, request *protocol.EvaluatorRequest) *protocol.ConsumerGroupStatus {
+	startTime := time.Now()
+	hc.App.EvaluatorChannel <- request
+	response := <-request.Reply
+
+	if timing, ok := r.Context().Value(requestTimingContextKey{}).(*requestTiming); ok {
+		timing.evaluatorTime += time.Since(startTime)
+		timing.evaluatorRequests++
+	}
+	return response
+}
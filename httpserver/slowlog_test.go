@@ -0,0 +1,106 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func TestHttpServer_applySlowRequestMiddleware_Disabled(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	assert.Equal(t, coordinator.router, coordinator.applySlowRequestMiddleware("httpserver.default", coordinator.router),
+		"Expected the handler to be returned unchanged")
+
+	// Without a threshold, the storage requests are not timed
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		request.Reply <- []string{"testcluster"}
+	}()
+	req, err := http.NewRequest("GET", "/v3/kafka", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	response := coordinator.sendStorageRequest(req, &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+	})
+	assert.Equal(t, []string{"testcluster"}, response, "Expected the storage reply")
+}
+
+func TestHttpServer_sendStorageRequest_Priority(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	coordinator.App.StorageAPIChannel = make(chan *protocol.StorageRequest, 1)
+	req, err := http.NewRequest("DELETE", "/v3/kafka/testcluster/consumer/testgroup", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+
+	// Fetches are sent at the API priority
+	go func() {
+		request := <-coordinator.App.StorageAPIChannel
+		request.Reply <- []string{"testcluster"}
+	}()
+	response := coordinator.sendStorageRequest(req, &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+	})
+	assert.Equal(t, []string{"testcluster"}, response, "Expected the fetch to be sent on the API channel")
+
+	// Requests that change the stored information are sent with the ingestion priority
+	for _, requestType := range []protocol.StorageRequestConstant{protocol.StorageSetDeleteGroup, protocol.StorageSetExpectedGroup, protocol.StorageSetDeleteExpectedGroup} {
+		go coordinator.sendStorageRequest(req, &protocol.StorageRequest{RequestType: requestType, Cluster: "testcluster", Group: "testgroup"})
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, requestType, request.RequestType, "Expected %v to be sent on the storage channel", requestType)
+	}
+	assert.Empty(t, coordinator.App.StorageAPIChannel, "Expected no requests on the API channel")
+}
+
+func TestHttpServer_applySlowRequestMiddleware(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+	logs, observed := observer.New(zap.WarnLevel)
+	coordinator.Log = zap.New(logs)
+	viper.Set("httpserver.default.slow-request-threshold", 1)
+	handler := coordinator.applySlowRequestMiddleware("httpserver.default", coordinator.router)
+
+	// Respond to the evaluator request slowly, so that the request is over the threshold
+	go func() {
+		request := <-coordinator.App.EvaluatorChannel
+		time.Sleep(10 * time.Millisecond)
+		request.Reply <- &protocol.ConsumerGroupStatus{
+			Cluster: request.Cluster,
+			Group:   request.Group,
+			Status:  protocol.StatusOK,
+		}
+	}()
+
+	req, err := http.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/status?x=1", nil)
+	assert.NoError(t, err, "Expected request setup to return no error")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equalf(t, http.StatusOK, rr.Code, "Expected response code to be 200, not %v", rr.Code)
+
+	entries := observed.FilterMessage("slow request").All()
+	assert.Len(t, entries, 1, "Expected the request to be logged")
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "/v3/kafka/testcluster/consumer/testgroup/status", fields["path"], "Expected the path")
+	assert.Equal(t, "x=1", fields["query"], "Expected the query")
+	assert.Equal(t, map[string]string{"cluster": "testcluster", "consumer": "testgroup"}, fields["params"],
+		"Expected the route parameters")
+	assert.Equal(t, int64(1), fields["evaluator_requests"], "Expected one evaluator request")
+	assert.Equal(t, int64(0), fields["storage_requests"], "Expected no storage requests")
+	assert.True(t, fields["evaluator_time"].(time.Duration) >= 10*time.Millisecond, "Expected the evaluator time")
+}
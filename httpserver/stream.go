@@ -0,0 +1,164 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"bufio"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// The size of the buffer that a streamed response is written to. The buffer is sent to the client each time it fills
+const streamBufferSize = 32 * 1024
+
+// streamedConsumerStatus is encoded as the status of a consumer group without its partitions. The Partitions field
+// shadows the one in the embedded status, and is left empty so that it is omitted.
+type streamedConsumerStatus struct {
+	*protocol.ConsumerGroupStatus
+	Partitions []*protocol.PartitionStatus `json:"partitions,omitempty"`
+}
+
+// writeConsumerStatusResponse writes the same response that writeResponse does for an httpResponseConsumerStatus, but
+// the partitions are encoded one at a time into a buffer that is sent to the client as it fills, instead of the whole
+// response being encoded in memory first. A group with tens of thousands of partitions has a response of many
+// megabytes. The partitions are the last field of the status, rather than being in the order of the struct.
+//
+// Everything other than the partitions is encoded before the status code is written, so that an error can still be
+// returned. Once the partitions are being written, an error can only end the response early.
+func (hc *Coordinator) writeConsumerStatusResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string, status *protocol.ConsumerGroupStatus) {
+	hc.setResponseHeaders(w)
+
+	messageBytes, err := hc.json.Marshal(message)
+	var statusBytes, requestBytes []byte
+	if err == nil {
+		statusBytes, err = hc.json.Marshal(streamedConsumerStatus{ConsumerGroupStatus: status})
+	}
+	if err == nil {
+		requestBytes, err = hc.json.Marshal(makeRequestInfo(r))
+	}
+	if err != nil {
+		writeEncodingError(w)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+	buffer := bufio.NewWriterSize(w, streamBufferSize)
+	buffer.WriteString("{\"error\":false,\"message\":")
+	buffer.Write(messageBytes)
+	buffer.WriteString(",\"status\":")
+
+	// The status is always encoded as an object, so the partitions are added before the closing brace
+	buffer.Write(statusBytes[:len(statusBytes)-1])
+	buffer.WriteString(",\"partitions\":")
+	if status.Partitions == nil {
+		buffer.WriteString("null")
+	} else {
+		buffer.WriteByte('[')
+		encoder := hc.json.NewEncoder(buffer)
+		for i, partition := range status.Partitions {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := encoder.Encode(partition); err != nil {
+				hc.Log.Warn("failed to stream response",
+					zap.String("path", r.URL.Path),
+					zap.Error(err),
+				)
+				buffer.Flush()
+				return
+			}
+		}
+		buffer.WriteByte(']')
+	}
+	buffer.WriteString("},\"request\":")
+	buffer.Write(requestBytes)
+	buffer.WriteByte('}')
+	buffer.Flush()
+}
+
+// writeStreamedListResponse sends a StorageStreamConsumers or StorageStreamTopics request for the cluster, and writes
+// the same response that writeResponse does for an httpResponseConsumerList or httpResponseTopicList, with the names in
+// the field given. Each chunk of names is written as it is received from storage, so the list is never held in memory
+// all at once. If storage gives up on the request before the last chunk, the response ends early.
+func (hc *Coordinator) writeStreamedListResponse(w http.ResponseWriter, r *http.Request, requestType protocol.StorageRequestConstant, cluster, message, field string) {
+	request := &protocol.StorageRequest{
+		RequestType: requestType,
+		Cluster:     cluster,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+		Trace:       traceContext(r),
+	}
+	chunk, ok := hc.sendStorageRequest(r, request).(*protocol.StorageListChunk)
+	if !ok {
+		hc.writeErrorResponse(w, r, http.StatusNotFound, "cluster not found")
+		return
+	}
+
+	// Storage waits for each chunk to be taken, holding up one of its workers, so every chunk is read even if the
+	// response fails
+	defer func() {
+		for range request.Reply {
+		}
+	}()
+
+	messageBytes, err := hc.json.Marshal(message)
+	var requestBytes []byte
+	if err == nil {
+		requestBytes, err = hc.json.Marshal(makeRequestInfo(r))
+	}
+	hc.setResponseHeaders(w)
+	if err != nil {
+		writeEncodingError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	buffer := bufio.NewWriterSize(w, streamBufferSize)
+	buffer.WriteString("{\"error\":false,\"message\":")
+	buffer.Write(messageBytes)
+	buffer.WriteString(",\"" + field + "\":[")
+
+	written := 0
+	for {
+		if len(chunk.Names) > 0 {
+			// The names are encoded as an array, and written without the brackets
+			names, _ := hc.json.Marshal(chunk.Names)
+			if written > 0 {
+				buffer.WriteByte(',')
+			}
+			buffer.Write(names[1 : len(names)-1])
+			written += len(chunk.Names)
+		}
+		if chunk.Last {
+			break
+		}
+
+		response, ok := <-request.Reply
+		if !ok {
+			hc.Log.Warn("storage did not send the whole list",
+				zap.String("path", r.URL.Path),
+				zap.Int("written", written),
+			)
+			buffer.Flush()
+			return
+		}
+		chunk = response.(*protocol.StorageListChunk)
+	}
+
+	buffer.WriteString("],\"request\":")
+	buffer.Write(requestBytes)
+	buffer.WriteByte('}')
+	buffer.Flush()
+}
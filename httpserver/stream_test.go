@@ -0,0 +1,117 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// decodeJSON decodes the bytes into generic values, so that responses can be compared regardless of the field order
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	var value interface{}
+	assert.Nil(t, json.Unmarshal(data, &value), "Expected the response to be valid JSON: %s", string(data))
+	return value
+}
+
+func TestHttpServer_writeConsumerStatusResponse(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	status := &protocol.ConsumerGroupStatus{
+		Cluster:         "testcluster",
+		Group:           "testgroup",
+		Status:          protocol.StatusWarning,
+		Complete:        1.0,
+		TotalPartitions: 3,
+		TotalLag:        30,
+	}
+	for i := int32(0); i < 3; i++ {
+		status.Partitions = append(status.Partitions, &protocol.PartitionStatus{
+			Topic:      "test<topic>",
+			Partition:  i,
+			Status:     protocol.StatusWarning,
+			Start:      &protocol.ConsumerOffset{Offset: 100, Timestamp: 1000},
+			End:        &protocol.ConsumerOffset{Offset: 200, Timestamp: 2000},
+			CurrentLag: 10,
+		})
+	}
+	status.Maxlag = status.Partitions[2]
+
+	for _, partitions := range [][]*protocol.PartitionStatus{status.Partitions, {}, nil} {
+		status.Partitions = partitions
+		req := httptest.NewRequest("GET", "/v3/kafka/testcluster/consumer/testgroup/lag", nil)
+		rr := httptest.NewRecorder()
+		coordinator.writeConsumerStatusResponse(rr, req, http.StatusOK, "consumer status returned", status)
+
+		expected, err := json.Marshal(httpResponseConsumerStatus{
+			Error:   false,
+			Message: "consumer status returned",
+			Status:  *status,
+			Request: makeRequestInfo(req),
+		})
+		assert.Nil(t, err, "Expected the response to be encoded")
+		assert.Equal(t, http.StatusOK, rr.Code, "Unexpected response code")
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "Unexpected content type")
+		assert.Equal(t, decodeJSON(t, expected), decodeJSON(t, rr.Body.Bytes()), "Expected the streamed response to match the encoded response")
+	}
+}
+
+func TestHttpServer_writeStreamedListResponse(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	chunks := [][]string{{"group1", "group<2>"}, {}, {"group\"3\""}}
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		assert.Equalf(t, protocol.StorageStreamConsumers, request.RequestType, "Expected request of type StorageStreamConsumers, not %v", request.RequestType)
+		for i, names := range chunks {
+			request.Reply <- &protocol.StorageListChunk{Names: names, Last: i == len(chunks)-1}
+		}
+		close(request.Reply)
+	}()
+
+	req := httptest.NewRequest("GET", "/v3/kafka/testcluster/consumer", nil)
+	rr := httptest.NewRecorder()
+	coordinator.writeStreamedListResponse(rr, req, protocol.StorageStreamConsumers, "testcluster", "consumer list returned", "consumers")
+
+	expected, err := json.Marshal(httpResponseConsumerList{
+		Error:     false,
+		Message:   "consumer list returned",
+		Consumers: []string{"group1", "group<2>", "group\"3\""},
+		Request:   makeRequestInfo(req),
+	})
+	assert.Nil(t, err, "Expected the response to be encoded")
+	assert.Equal(t, http.StatusOK, rr.Code, "Unexpected response code")
+	assert.Equal(t, string(expected), rr.Body.String(), "Expected the streamed response to match the encoded response")
+}
+
+func TestHttpServer_writeStreamedListResponse_Incomplete(t *testing.T) {
+	coordinator := fixtureConfiguredCoordinator()
+
+	// Storage gives up on the request before the last chunk
+	go func() {
+		request := <-coordinator.App.StorageChannel
+		request.Reply <- &protocol.StorageListChunk{Names: []string{"testtopic"}}
+		close(request.Reply)
+	}()
+
+	req := httptest.NewRequest("GET", "/v3/kafka/testcluster/topic", nil)
+	rr := httptest.NewRecorder()
+	coordinator.writeStreamedListResponse(rr, req, protocol.StorageStreamTopics, "testcluster", "topic list returned", "topics")
+
+	var resp httpResponseTopicList
+	assert.Error(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Expected an incomplete response not to be valid JSON")
+}
@@ -10,7 +10,10 @@
 
 package httpserver
 
-import "github.com/linkedin/Burrow/protocol"
+import (
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
 
 type httpResponseRequestInfo struct {
 	URI  string `json:"url"`
@@ -24,15 +27,17 @@ type httpResponseError struct {
 }
 
 type httpResponseTLSProfile struct {
-	Name     string `json:"name"`
-	NoVerify bool   `json:"noverify"`
-	CertFile string `json:"certfile"`
-	KeyFile  string `json:"keyfile"`
-	CAFile   string `json:"cafile"`
+	Name       string `json:"name"`
+	NoVerify   bool   `json:"noverify"`
+	CertFile   string `json:"certfile"`
+	KeyFile    string `json:"keyfile"`
+	CAFile     string `json:"cafile"`
+	ServerName string `json:"server-name"`
 }
 
 type httpResponseSASLProfile struct {
 	Name           string `json:"name"`
+	Mechanism      string `json:"mechanism"`
 	HandshakeFirst bool   `json:"handshake-first"`
 	Username       string `json:"username"`
 }
@@ -41,15 +46,17 @@ type httpResponseClientProfile struct {
 	Name         string                   `json:"name"`
 	ClientID     string                   `json:"client-id"`
 	KafkaVersion string                   `json:"kafka-version"`
+	Preset       string                   `json:"preset,omitempty"`
 	TLS          *httpResponseTLSProfile  `json:"tls"`
 	SASL         *httpResponseSASLProfile `json:"sasl"`
 }
 
 type httpResponseClusterList struct {
-	Error    bool                    `json:"error"`
-	Message  string                  `json:"message"`
-	Clusters []string                `json:"clusters"`
-	Request  httpResponseRequestInfo `json:"request"`
+	Error    bool                         `json:"error"`
+	Message  string                       `json:"message"`
+	Clusters []string                     `json:"clusters"`
+	Labels   map[string]map[string]string `json:"labels,omitempty"`
+	Request  httpResponseRequestInfo      `json:"request"`
 }
 
 type httpResponseTopicList struct {
@@ -66,6 +73,80 @@ type httpResponseTopicDetail struct {
 	Request httpResponseRequestInfo `json:"request"`
 }
 
+type httpResponseBrokerList struct {
+	Error        bool                    `json:"error"`
+	Message      string                  `json:"message"`
+	Brokers      []*protocol.Broker      `json:"brokers"`
+	ControllerID int32                   `json:"controller_id"`
+	Request      httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseClusterReplication struct {
+	Error       bool                         `json:"error"`
+	Message     string                       `json:"message"`
+	Replication *protocol.ClusterReplication `json:"replication"`
+	Request     httpResponseRequestInfo      `json:"request"`
+}
+
+type httpResponseClusterLeaderChurn struct {
+	Error       bool                         `json:"error"`
+	Message     string                       `json:"message"`
+	LeaderChurn *protocol.ClusterLeaderChurn `json:"leader_churn"`
+	Request     httpResponseRequestInfo      `json:"request"`
+}
+
+type httpResponseTopicProduceRates struct {
+	Error        bool                    `json:"error"`
+	Message      string                  `json:"message"`
+	ProduceRates []float64               `json:"produce_rates"`
+	Request      httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseClusterHealth struct {
+	Error           bool                          `json:"error"`
+	Message         string                        `json:"message"`
+	Status          protocol.StatusConstant       `json:"status"`
+	Brokers         httpResponseHealthBrokers     `json:"brokers"`
+	Replication     httpResponseHealthReplication `json:"replication"`
+	Metadata        httpResponseHealthAge         `json:"metadata"`
+	BrokerOffsets   httpResponseHealthAge         `json:"broker_offsets"`
+	ConsumerOffsets httpResponseHealthAge         `json:"consumer_offsets"`
+	Consumers       httpResponseHealthConsumers   `json:"consumers"`
+	Request         httpResponseRequestInfo       `json:"request"`
+}
+
+type httpResponseHealthBrokers struct {
+	Status       protocol.StatusConstant `json:"status"`
+	Count        int                     `json:"count"`
+	ControllerID int32                   `json:"controller_id"`
+}
+
+type httpResponseHealthReplication struct {
+	Status          protocol.StatusConstant `json:"status"`
+	UnderReplicated int                     `json:"under_replicated"`
+	Offline         int                     `json:"offline"`
+}
+
+type httpResponseHealthAge struct {
+	Status    protocol.StatusConstant `json:"status"`
+	Timestamp int64                   `json:"timestamp"`
+	Age       int64                   `json:"age"`
+	MaxAge    int64                   `json:"max_age"`
+}
+
+type httpResponseHealthConsumers struct {
+	Status   protocol.StatusConstant `json:"status"`
+	Total    int                     `json:"total"`
+	Statuses map[string]int          `json:"statuses"`
+}
+
+type httpResponseTopicConfig struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Config  map[string]string       `json:"config"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
 type httpResponseTopicConsumerDetail struct {
 	Error     bool                    `json:"error"`
 	Message   string                  `json:"message"`
@@ -80,11 +161,57 @@ type httpResponseConsumerList struct {
 	Request   httpResponseRequestInfo `json:"request"`
 }
 
+type httpResponseExpectedGroupList struct {
+	Error     bool                    `json:"error"`
+	Message   string                  `json:"message"`
+	Consumers map[string]int64        `json:"consumers"`
+	Request   httpResponseRequestInfo `json:"request"`
+}
+
 type httpResponseConsumerDetail struct {
-	Error   bool                    `json:"error"`
-	Message string                  `json:"message"`
-	Topics  protocol.ConsumerTopics `json:"topics"`
-	Request httpResponseRequestInfo `json:"request"`
+	Error   bool                            `json:"error"`
+	Message string                          `json:"message"`
+	Topics  protocol.ConsumerTopics         `json:"topics"`
+	Members []*protocol.ConsumerGroupMember `json:"members"`
+	State   string                          `json:"state"`
+	Request httpResponseRequestInfo         `json:"request"`
+}
+
+type httpResponseConnectorList struct {
+	Error      bool                    `json:"error"`
+	Message    string                  `json:"message"`
+	Connectors []*protocol.Connector   `json:"connectors"`
+	Request    httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseConnectorDetail struct {
+	Error     bool                    `json:"error"`
+	Message   string                  `json:"message"`
+	Connector *protocol.Connector     `json:"connector"`
+	Request   httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseConnectorLag struct {
+	Error     bool                          `json:"error"`
+	Message   string                        `json:"message"`
+	Connector *protocol.Connector           `json:"connector"`
+	Status    *protocol.ConsumerGroupStatus `json:"status"`
+	Request   httpResponseRequestInfo       `json:"request"`
+}
+
+type httpResponseSelfLag struct {
+	Error     bool                           `json:"error"`
+	Message   string                         `json:"message"`
+	Consumers []*httpResponseSelfLagConsumer `json:"consumers"`
+	Request   httpResponseRequestInfo        `json:"request"`
+}
+
+type httpResponseSelfLagConsumer struct {
+	Name      string                        `json:"name"`
+	Group     string                        `json:"group"`
+	Threshold int64                         `json:"threshold"`
+	Alert     bool                          `json:"alert"`
+	Status    *protocol.ConsumerGroupStatus `json:"status"`
 }
 
 type httpResponseConsumerStatus struct {
@@ -107,4 +234,70 @@ type httpResponseConfigModuleCluster struct {
 	ClientProfile httpResponseClientProfile `json:"client-profile"`
 	TopicRefresh  int64                     `json:"topic-refresh"`
 	OffsetRefresh int64                     `json:"offset-refresh"`
+	Labels        map[string]string         `json:"labels,omitempty"`
+}
+
+type httpResponseFilterList struct {
+	Error   bool                                      `json:"error"`
+	Message string                                    `json:"message"`
+	Filters map[string]helpers.ConsumerFilterSettings `json:"filters"`
+	Request httpResponseRequestInfo                   `json:"request"`
+}
+
+type httpResponseFilterDetail struct {
+	Error   bool                           `json:"error"`
+	Message string                         `json:"message"`
+	Filter  helpers.ConsumerFilterSettings `json:"filter"`
+	Request httpResponseRequestInfo        `json:"request"`
+}
+
+type httpResponseClientMetrics struct {
+	Error   bool                     `json:"error"`
+	Message string                   `json:"message"`
+	Clients []*helpers.ClientMetrics `json:"clients"`
+	Request httpResponseRequestInfo  `json:"request"`
+}
+
+type httpResponseMetrics struct {
+	Error   bool                              `json:"error"`
+	Message string                            `json:"message"`
+	Metrics map[string]map[string]interface{} `json:"metrics"`
+	Request httpResponseRequestInfo           `json:"request"`
+}
+
+type httpResponseConfigReload struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Reload  *protocol.ConfigReload  `json:"reload"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseDecodeFailures struct {
+	Error    bool                       `json:"error"`
+	Message  string                     `json:"message"`
+	Failures []*helpers.DeadLetterCount `json:"failures"`
+	Request  httpResponseRequestInfo    `json:"request"`
+}
+
+type httpResponseAuditLog struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Entries []*auditEntry           `json:"entries"`
+	Request httpResponseRequestInfo `json:"request"`
+}
+
+type httpResponseHealth struct {
+	Error        bool                                   `json:"error"`
+	Message      string                                 `json:"message"`
+	State        protocol.HealthState                   `json:"state"`
+	Coordinators map[string]*protocol.CoordinatorHealth `json:"coordinators"`
+	Request      httpResponseRequestInfo                `json:"request"`
+}
+
+type httpResponseLogLevel struct {
+	Error   bool                    `json:"error"`
+	Message string                  `json:"message"`
+	Level   string                  `json:"level"`
+	Levels  map[string]string       `json:"levels"`
+	Request httpResponseRequestInfo `json:"request"`
 }
@@ -0,0 +1,340 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// Module is a module that runs in a plugin executable. It is configured with the class-name "plugin" for the cluster,
+// consumer, and storage coordinators. When started, it runs the plugin, configures and starts the module in it, and
+// serves the requests that the module sends to the storage and evaluator subsystems. For a storage plugin, the requests
+// sent to the module's communication channel are forwarded to the plugin.
+type Module struct {
+	// App is a pointer to the application context. This stores the channel to the storage subsystem
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name        string
+	configRoot  string
+	path        string
+	args        []string
+	stopTimeout time.Duration
+
+	cmd            *exec.Cmd
+	client         *rpc.Client
+	exited         chan struct{}
+	requestChannel chan *protocol.StorageRequest
+	events         *helpers.EventSubscription
+	running        sync.WaitGroup
+}
+
+// conn joins the two halves of the pipes to a process into a connection that RPC can be used over
+type conn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (c *conn) Close() error {
+	err := c.WriteCloser.Close()
+	if readErr := c.ReadCloser.Close(); err == nil {
+		err = readErr
+	}
+	return err
+}
+
+// Configure validates the configuration for the module. The configuration of the module in the plugin is not checked
+// until it is started, as this does not run the plugin.
+func (module *Module) Configure(name string, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.configRoot = configRoot
+	module.requestChannel = make(chan *protocol.StorageRequest)
+
+	viper.SetDefault(configRoot+".stop-timeout", 10)
+	module.path = viper.GetString(configRoot + ".plugin-path")
+	module.args = viper.GetStringSlice(configRoot + ".plugin-args")
+	module.stopTimeout = time.Duration(viper.GetInt(configRoot+".stop-timeout")) * time.Second
+
+	if module.path == "" {
+		panic("No plugin-path configured for module " + name)
+	}
+	if info, err := os.Stat(module.path); (err != nil) || info.IsDir() {
+		panic("Plugin " + module.path + " for module " + name + " is not a file")
+	}
+}
+
+// GetCommunicationChannel returns the RequestChannel that has been setup for this module, which is used by a storage
+// plugin
+func (module *Module) GetCommunicationChannel() chan *protocol.StorageRequest {
+	return module.requestChannel
+}
+
+// Start runs the plugin, and then configures and starts the module in it. If the module's configuration is not valid,
+// or it fails to start, the plugin is stopped and an error is returned.
+func (module *Module) Start() error {
+	module.Log.Info("starting")
+
+	config, err := json.Marshal(viper.AllSettings())
+	if err != nil {
+		return err
+	}
+	if err := module.startPlugin(); err != nil {
+		module.Log.Error("failed to run plugin", zap.String("path", module.path), zap.Error(err))
+		return err
+	}
+
+	args := &ConfigureArgs{Name: module.name, ConfigRoot: module.configRoot, Config: config}
+	if err := module.client.Call("Plugin.Configure", args, &struct{}{}); err != nil {
+		module.Log.Error("failed to configure plugin", zap.Error(err))
+		module.stopPlugin()
+		return err
+	}
+	if err := module.client.Call("Plugin.Start", struct{}{}, &struct{}{}); err != nil {
+		module.Log.Error("failed to start plugin", zap.Error(err))
+		module.stopPlugin()
+		return err
+	}
+
+	helpers.SetModuleHealth(module.configRoot, protocol.HealthOK, "")
+	module.running.Add(1)
+	go module.forwardStorageRequests()
+
+	// Plugins built before events were added do not have the EventTypes call, and do not get events
+	eventTypes := &EventTypesReply{}
+	if err := module.client.Call("Plugin.EventTypes", struct{}{}, eventTypes); (err == nil) && eventTypes.Subscribe {
+		module.events = helpers.SubscribeEvents(eventTypes.Types...)
+		module.running.Add(1)
+		go module.forwardEvents()
+	}
+	return nil
+}
+
+// Stop stops the module in the plugin, and waits for the plugin to exit. If it does not exit within the stop-timeout,
+// it is killed.
+func (module *Module) Stop() error {
+	module.Log.Info("stopping")
+	if module.client == nil {
+		return nil
+	}
+
+	close(module.requestChannel)
+	if module.events != nil {
+		module.events.Close()
+	}
+	module.running.Wait()
+
+	if err := module.client.Call("Plugin.Stop", struct{}{}, &struct{}{}); err != nil {
+		module.Log.Warn("failed to stop plugin", zap.Error(err))
+	}
+	module.stopPlugin()
+	return nil
+}
+
+// startPlugin runs the plugin executable. RPC calls to the plugin are made over its stdin and stdout, and it makes
+// calls to Burrow over a pair of pipes that are its file descriptors 3 and 4.
+func (module *Module) startPlugin() error {
+	cmd := exec.Command(module.path, module.args...)
+	cmd.Env = append(os.Environ(), pluginEnv+"="+pluginProtocolVersion)
+
+	pluginIn, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	pluginOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	pluginErr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	hostIn, pluginHostOut, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	pluginHostIn, hostOut, err := os.Pipe()
+	if err != nil {
+		hostIn.Close()
+		pluginHostOut.Close()
+		return err
+	}
+	cmd.ExtraFiles = []*os.File{pluginHostIn, pluginHostOut}
+
+	err = cmd.Start()
+	pluginHostIn.Close()
+	pluginHostOut.Close()
+	if err != nil {
+		hostIn.Close()
+		hostOut.Close()
+		return err
+	}
+	module.cmd = cmd
+	module.exited = make(chan struct{})
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Host", &hostService{app: module.App}); err != nil {
+		return err
+	}
+	go server.ServeConn(&conn{ReadCloser: hostIn, WriteCloser: hostOut})
+	go module.logPluginOutput(pluginErr)
+	module.client = rpc.NewClient(&conn{ReadCloser: pluginOut, WriteCloser: pluginIn})
+	return nil
+}
+
+// stopPlugin closes the connection to the plugin, which tells it to exit, and then waits for it to do so
+func (module *Module) stopPlugin() {
+	module.client.Close()
+	go func() {
+		module.cmd.Wait()
+		close(module.exited)
+	}()
+
+	select {
+	case <-module.exited:
+	case <-time.After(module.stopTimeout):
+		module.Log.Warn("plugin did not exit, killing it")
+		module.cmd.Process.Kill()
+		<-module.exited
+	}
+}
+
+// forwardStorageRequests sends the requests for a storage plugin to it, and the responses back to the sender
+func (module *Module) forwardStorageRequests() {
+	defer module.running.Done()
+
+	for request := range module.requestChannel {
+		reply := &StorageReply{}
+		err := module.client.Call("Plugin.Storage", &StorageCall{Request: request, WantReply: request.Reply != nil}, reply)
+		if err != nil {
+			module.Log.Error("failed to send storage request to plugin",
+				zap.String("request", request.RequestType.String()),
+				zap.Error(err),
+			)
+			module.checkShutdown(err)
+			reply = &StorageReply{}
+		}
+		if request.Reply != nil {
+			sendStorageReply(request, reply)
+		}
+	}
+}
+
+// forwardEvents sends the events that the module in the plugin subscribed to, until the subscription is closed
+func (module *Module) forwardEvents() {
+	defer module.running.Done()
+
+	for event := range module.events.Events {
+		if err := module.client.Call("Plugin.Event", event, &struct{}{}); err != nil {
+			module.Log.Warn("failed to send event to plugin", zap.String("type", event.Type.String()), zap.Error(err))
+			module.checkShutdown(err)
+		}
+	}
+}
+
+// checkShutdown marks the module FAILED if the error from a call to the plugin is because the plugin has exited
+func (module *Module) checkShutdown(err error) {
+	if err == rpc.ErrShutdown {
+		helpers.SetModuleHealth(module.configRoot, protocol.HealthFailed, "plugin is not running")
+	}
+}
+
+// logPluginOutput writes what the plugin logs to Burrow's log. The plugin logs JSON, so the level, message, and fields
+// of each entry are kept. Other output is logged at the info level.
+func (module *Module) logPluginOutput(output io.Reader) {
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			module.Log.Info(scanner.Text())
+			continue
+		}
+
+		level := zapcore.InfoLevel
+		if levelName, ok := entry["level"].(string); ok {
+			level.UnmarshalText([]byte(levelName))
+		}
+		message, _ := entry["msg"].(string)
+		delete(entry, "level")
+		delete(entry, "msg")
+		delete(entry, "ts")
+
+		keys := make([]string, 0, len(entry))
+		for key := range entry {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]zap.Field, len(keys))
+		for i, key := range keys {
+			fields[i] = zap.Any(key, entry[key])
+		}
+		if checked := module.Log.Check(level, message); checked != nil {
+			checked.Write(fields...)
+		}
+	}
+}
+
+// hostService serves the requests that the module in a plugin sends to the storage and evaluator subsystems
+type hostService struct {
+	app *protocol.ApplicationContext
+}
+
+// Storage sends a request to the storage subsystem, and waits for the response if the request has one
+func (service *hostService) Storage(call *StorageCall, reply *StorageReply) error {
+	if call.Request == nil {
+		return errors.New("no storage request given")
+	}
+	request := call.Request
+	if call.WantReply {
+		request.Reply = make(chan interface{}, 1)
+	}
+	service.app.StorageChannel <- request
+	if call.WantReply {
+		value, ok := <-request.Reply
+		*reply = *newStorageReply(value, ok)
+	}
+	return nil
+}
+
+// Evaluate sends a request to the evaluator subsystem, and waits for the status of the group
+func (service *hostService) Evaluate(call *EvaluatorCall, reply *protocol.ConsumerGroupStatus) error {
+	if call.Request == nil {
+		return errors.New("no evaluator request given")
+	}
+	request := call.Request
+	request.Reply = make(chan *protocol.ConsumerGroupStatus, 1)
+	service.app.EvaluatorChannel <- request
+	if status := <-request.Reply; status != nil {
+		*reply = *status
+	}
+	return nil
+}
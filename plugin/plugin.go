@@ -0,0 +1,189 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+// Package plugin - External module plugins.
+// The plugin package allows cluster, consumer, and storage modules to be built as separate executables, so that
+// integrations (such as with a proprietary offset store) can be shipped without maintaining a fork of Burrow. A module
+// that is configured with the class-name "plugin" runs the executable given in its plugin-path config, and Burrow
+// talks to the module in that process over RPC. The plugin class is registered when this package is imported, which
+// the core package does unless Burrow is built with the no_plugin tag.
+//
+// Writing a Plugin
+//
+// A plugin is a Go program whose main func calls Serve with a factory for its module. The module is written in the same
+// way as a module that is built in to Burrow: the factory is given an application context and a logger, and the module
+// reads its configuration with viper in Configure. The StorageChannel and EvaluatorChannel in the application context
+// send requests to the Burrow process that started the plugin. A storage plugin must return a module that also has a
+// GetCommunicationChannel func, as storage modules built in to Burrow do.
+//
+//	func main() {
+//		plugin.Serve(func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+//			return &MyConsumer{App: app, Log: log}
+//		})
+//	}
+//
+// The plugin is given the whole of Burrow's configuration, so it may use sections such as client-profile. Anything
+// that the plugin logs is written to Burrow's log. Plugins use stdin, stdout, and file descriptors 3 and 4 to talk to
+// Burrow, and so plugins are not supported on Windows.
+//
+// Protocol
+//
+// This package does not use HashiCorp's go-plugin, so that Burrow does not depend on it and on gRPC. Burrow calls the
+// plugin with net/rpc (gob encoding) over the plugin's stdin and stdout, and the plugin calls Burrow back the same way
+// over file descriptors 3 and 4. As a result, go-plugin plugins cannot be used, plugins must be written in Go with this
+// package, and a plugin must be built with the same protocol version as Burrow (which is checked when it starts).
+// go-plugin's TLS between the processes, and its checksum verification of the executable, are not provided.
+//
+// Events
+//
+// If the module in a plugin implements EventHandler, Burrow sends it the events of the types it returns from
+// EventTypes as they are published to the event bus (see helpers.SubscribeEvents). This lets a plugin react to changes,
+// such as a group's status changing, without polling. Events are sent one at a time, and events that are published
+// while the plugin is slow to handle them are dropped.
+//
+// Configuration
+//
+// A module that runs a plugin has the following configs, in addition to the configs of the module in the plugin:
+//
+// * plugin-path - The path to the plugin executable (required)
+//
+// * plugin-args - A list of arguments to run the plugin executable with
+//
+// * stop-timeout - The number of seconds to wait for the plugin to exit when Burrow is stopped, before it is killed
+// (defaults to 10)
+package plugin
+
+import (
+	"encoding/gob"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// pluginEnv is the environment variable that Burrow sets when starting a plugin, with the version of the plugin
+// protocol as its value. Serve checks this, so that a plugin run by hand explains what it is, rather than waiting on
+// stdin.
+const pluginEnv = "BURROW_PLUGIN"
+
+const pluginProtocolVersion = "1"
+
+// ConfigureArgs is sent to the plugin to configure its module
+type ConfigureArgs struct {
+	// The name of the module, and the root of its configuration
+	Name       string
+	ConfigRoot string
+
+	// The whole of Burrow's configuration, encoded as JSON
+	Config []byte
+}
+
+// StorageCall is a StorageRequest sent between Burrow and a plugin. It is sent to the plugin for a storage plugin, and
+// to Burrow for requests that the module in a plugin sends on its StorageChannel.
+type StorageCall struct {
+	Request *protocol.StorageRequest
+
+	// WantReply is true if the request had a Reply channel
+	WantReply bool
+}
+
+// StorageReply is the response to a StorageCall. If the request had a Reply channel that was closed without a
+// response, Value is nil.
+type StorageReply struct {
+	Value interface{}
+
+	// Some requests reply with an error, which is sent as a string
+	Error string
+}
+
+// EventHandler is implemented by the module in a plugin that wants to receive events from Burrow
+type EventHandler interface {
+	// EventTypes returns the types of events to send to the module, or nil for every type. It is called once, after
+	// the module is started.
+	EventTypes() []protocol.EventType
+
+	// HandleEvent is called with each event. Burrow waits for it to return before sending the next event
+	HandleEvent(event *protocol.Event)
+}
+
+// EventTypesReply is the response to a request for the event types that the module in a plugin wants. If Subscribe is
+// false, the module does not implement EventHandler.
+type EventTypesReply struct {
+	Subscribe bool
+	Types     []protocol.EventType
+}
+
+// EvaluatorCall is an EvaluatorRequest sent to Burrow from a plugin
+type EvaluatorCall struct {
+	Request *protocol.EvaluatorRequest
+}
+
+func init() {
+	for _, coordinator := range []string{"cluster", "consumer", "storage"} {
+		helpers.RegisterModuleClass(coordinator, "plugin", newModule)
+		helpers.RegisterConfigKeys(coordinator+".*", "plugin",
+			helpers.ConfigKey{Name: "plugin-path", Type: helpers.ConfigTypeString},
+			helpers.ConfigKey{Name: "plugin-args", Type: helpers.ConfigTypeStringList},
+			helpers.ConfigKey{Name: "stop-timeout", Type: helpers.ConfigTypeInteger, Default: 10},
+
+			// The module in the plugin has its own configs, which are not known
+			helpers.ConfigKey{Name: "*"},
+		)
+	}
+
+	// The types that storage modules reply with must be registered, so that they can be sent as a StorageReply
+	gob.Register([]string{})
+	gob.Register([]int64{})
+	gob.Register([]float64{})
+	gob.Register(map[string]string{})
+	gob.Register(map[string]int64{})
+	gob.Register(protocol.ConsumerTopics{})
+	gob.Register(&protocol.ConsumerGroupDetail{})
+	gob.Register(&protocol.ClusterReplication{})
+	gob.Register(&protocol.ClusterBrokers{})
+	gob.Register(&protocol.ClusterLeaderChurn{})
+	gob.Register(&protocol.ClusterActivity{})
+	gob.Register([]*protocol.ConsumerGroupMember{})
+	gob.Register([]*protocol.OffsetRewind{})
+	gob.Register([]*protocol.TopicRemoval{})
+	gob.Register([]*protocol.Connector{})
+}
+
+func newModule(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+	return &Module{
+		App: app,
+		Log: log,
+	}
+}
+
+// newStorageReply converts the response to a storage request to a StorageReply
+func newStorageReply(value interface{}, ok bool) *StorageReply {
+	if !ok {
+		return &StorageReply{}
+	}
+	if err, isError := value.(error); isError {
+		return &StorageReply{Error: err.Error()}
+	}
+	return &StorageReply{Value: value}
+}
+
+// sendStorageReply sends the response in a StorageReply to the Reply channel of a request, if there is one, and then
+// closes it, as the storage modules in Burrow do
+func sendStorageReply(request *protocol.StorageRequest, reply *StorageReply) {
+	defer close(request.Reply)
+	switch {
+	case reply.Error != "":
+		request.Reply <- errors.New(reply.Error)
+	case reply.Value != nil:
+		request.Reply <- reply.Value
+	}
+}
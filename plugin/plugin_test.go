@@ -0,0 +1,272 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package plugin
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// The test binary is used as the plugin, with the module given in testPluginEnv
+const testPluginEnv = "BURROW_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginEnv) != "" {
+		switch os.Getenv(testPluginEnv) {
+		case "consumer":
+			Serve(func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+				return &testConsumer{App: app, Log: log}
+			})
+		case "storage":
+			Serve(func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+				return &testStorage{App: app, Log: log}
+			})
+		case "events":
+			Serve(func(app *protocol.ApplicationContext, log *zap.Logger) protocol.Module {
+				return &testEvents{App: app}
+			})
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testConsumer fetches the list of clusters, and stores an offset for the configured topic in the first one
+type testConsumer struct {
+	App   *protocol.ApplicationContext
+	Log   *zap.Logger
+	topic string
+}
+
+func (module *testConsumer) Configure(name string, configRoot string) {
+	module.topic = viper.GetString(configRoot + ".topic")
+	if module.topic == "" {
+		panic("no topic configured")
+	}
+}
+
+func (module *testConsumer) Start() error {
+	go func() {
+		request := &protocol.StorageRequest{
+			RequestType: protocol.StorageFetchClusters,
+			Reply:       make(chan interface{}),
+		}
+		module.App.StorageChannel <- request
+		clusters := (<-request.Reply).([]string)
+		module.Log.Info("fetched clusters", zap.Strings("clusters", clusters))
+
+		module.App.StorageChannel <- &protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     clusters[0],
+			Topic:       module.topic,
+			Group:       "testgroup",
+			Offset:      100,
+		}
+	}()
+	return nil
+}
+
+func (module *testConsumer) Stop() error {
+	return nil
+}
+
+// testStorage replies to requests for the list of clusters, and has no topics
+type testStorage struct {
+	App            *protocol.ApplicationContext
+	Log            *zap.Logger
+	requestChannel chan *protocol.StorageRequest
+}
+
+func (module *testStorage) Configure(name string, configRoot string) {
+	module.requestChannel = make(chan *protocol.StorageRequest)
+}
+
+func (module *testStorage) GetCommunicationChannel() chan *protocol.StorageRequest {
+	return module.requestChannel
+}
+
+func (module *testStorage) Start() error {
+	go func() {
+		for request := range module.requestChannel {
+			switch request.RequestType {
+			case protocol.StorageFetchClusters:
+				request.Reply <- []string{"cluster1", "cluster2"}
+			default:
+				close(request.Reply)
+			}
+		}
+	}()
+	return nil
+}
+
+func (module *testStorage) Stop() error {
+	close(module.requestChannel)
+	return nil
+}
+
+// testEvents subscribes to group expiry events, and deletes each group that expired, so that the events it receives
+// can be seen in the requests it sends
+type testEvents struct {
+	App *protocol.ApplicationContext
+}
+
+func (module *testEvents) Configure(name string, configRoot string) {}
+
+func (module *testEvents) Start() error {
+	return nil
+}
+
+func (module *testEvents) Stop() error {
+	return nil
+}
+
+func (module *testEvents) EventTypes() []protocol.EventType {
+	return []protocol.EventType{protocol.EventGroupExpired}
+}
+
+func (module *testEvents) HandleEvent(event *protocol.Event) {
+	module.App.StorageChannel <- &protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteGroup,
+		Cluster:     event.Cluster,
+		Group:       event.Group,
+	}
+}
+
+func fixtureModule(t *testing.T, class string, configRoot string) *Module {
+	os.Setenv(testPluginEnv, class)
+	viper.Reset()
+	viper.Set(configRoot+".class-name", "plugin")
+	viper.Set(configRoot+".plugin-path", os.Args[0])
+	viper.Set(configRoot+".stop-timeout", 5)
+
+	return &Module{
+		App: &protocol.ApplicationContext{
+			Logger:           zap.NewNop(),
+			StorageChannel:   make(chan *protocol.StorageRequest),
+			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		},
+		Log: zap.NewNop(),
+	}
+}
+
+func TestModule_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(Module))
+	assert.Implements(t, (*storageModule)(nil), new(Module))
+}
+
+func TestModule_Configure(t *testing.T) {
+	module := fixtureModule(t, "consumer", "consumer.test")
+	module.Configure("test", "consumer.test")
+	assert.Equal(t, os.Args[0], module.path, "Expected plugin-path to be read")
+	assert.Equal(t, 5*time.Second, module.stopTimeout, "Expected stop-timeout to be read")
+}
+
+func TestModule_Configure_NoPath(t *testing.T) {
+	module := fixtureModule(t, "consumer", "consumer.test")
+	viper.Set("consumer.test.plugin-path", "")
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestModule_Configure_BadPath(t *testing.T) {
+	module := fixtureModule(t, "consumer", "consumer.test")
+	viper.Set("consumer.test.plugin-path", "/nonexistent/plugin")
+	assert.Panics(t, func() { module.Configure("test", "consumer.test") }, "The code did not panic")
+}
+
+func TestModule_Consumer(t *testing.T) {
+	module := fixtureModule(t, "consumer", "consumer.test")
+	viper.Set("consumer.test.topic", "testtopic")
+	module.Configure("test", "consumer.test")
+	assert.Nil(t, module.Start(), "Expected Start to return no error")
+
+	select {
+	case request := <-module.App.StorageChannel:
+		assert.Equal(t, protocol.StorageFetchClusters, request.RequestType, "Expected a request for the clusters")
+		request.Reply <- []string{"testcluster"}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a request from the plugin")
+	}
+	select {
+	case request := <-module.App.StorageChannel:
+		assert.Equal(t, protocol.StorageSetConsumerOffset, request.RequestType, "Expected a consumer offset")
+		assert.Equal(t, "testcluster", request.Cluster, "Expected the offset for the cluster that was fetched")
+		assert.Equal(t, "testtopic", request.Topic, "Expected the offset for the configured topic")
+		assert.Equal(t, int64(100), request.Offset, "Expected offset 100")
+		assert.Nil(t, request.Reply, "Expected no reply channel")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a request from the plugin")
+	}
+
+	assert.Nil(t, module.Stop(), "Expected Stop to return no error")
+	select {
+	case <-module.exited:
+	default:
+		t.Error("Expected the plugin to have exited")
+	}
+}
+
+func TestModule_Consumer_BadConfig(t *testing.T) {
+	module := fixtureModule(t, "consumer", "consumer.test")
+	module.Configure("test", "consumer.test")
+	assert.NotNil(t, module.Start(), "Expected Start to fail without a topic")
+}
+
+func TestModule_Storage(t *testing.T) {
+	module := fixtureModule(t, "storage", "storage.test")
+	module.Configure("test", "storage.test")
+	assert.Nil(t, module.Start(), "Expected Start to return no error")
+
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+	}
+	module.GetCommunicationChannel() <- request
+	assert.Equal(t, []string{"cluster1", "cluster2"}, <-request.Reply, "Expected the clusters from the plugin")
+
+	request = &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchTopics,
+		Cluster:     "cluster1",
+		Reply:       make(chan interface{}),
+	}
+	module.GetCommunicationChannel() <- request
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected the reply channel to be closed")
+
+	assert.Nil(t, module.Stop(), "Expected Stop to return no error")
+}
+
+func TestModule_Events(t *testing.T) {
+	module := fixtureModule(t, "events", "consumer.test")
+	module.Configure("test", "consumer.test")
+	assert.Nil(t, module.Start(), "Expected Start to return no error")
+
+	// Only the types the plugin subscribed to are sent to it
+	helpers.PublishEvent(&protocol.Event{Type: protocol.EventStatusChange, Cluster: "testcluster", Group: "othergroup"})
+	helpers.PublishEvent(&protocol.Event{Type: protocol.EventGroupExpired, Cluster: "testcluster", Group: "testgroup"})
+	select {
+	case request := <-module.App.StorageChannel:
+		assert.Equal(t, protocol.StorageSetDeleteGroup, request.RequestType, "Expected the plugin to handle the event")
+		assert.Equal(t, "testcluster", request.Cluster, "Expected the cluster from the event")
+		assert.Equal(t, "testgroup", request.Group, "Expected the group from the expiry event")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a request from the plugin")
+	}
+
+	assert.Nil(t, module.Stop(), "Expected Stop to return no error")
+}
@@ -0,0 +1,246 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"os"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// storageModule is the interface that the module in a storage plugin must satisfy. It is the same as the Module
+// interface of the storage coordinator.
+type storageModule interface {
+	protocol.Module
+	GetCommunicationChannel() chan *protocol.StorageRequest
+}
+
+// Serve runs the module created by the factory as a plugin, serving Burrow's requests to configure, start, and stop it
+// until Burrow closes the connection to the plugin. It must be called from the main func of the plugin, and it exits
+// the process if the plugin was not started by Burrow.
+func Serve(factory helpers.ModuleFactory) {
+	if os.Getenv(pluginEnv) != pluginProtocolVersion {
+		fmt.Fprintln(os.Stderr, "This is a Burrow plugin, and can only be run by Burrow as a module with class-name plugin")
+		os.Exit(1)
+	}
+
+	// Anything written to stdout would break the RPC connection, so it is sent to stderr (and Burrow's log) instead
+	pluginIn, pluginOut := os.Stdin, os.Stdout
+	os.Stdout = os.Stderr
+	hostIn, hostOut := os.NewFile(3, "burrow-in"), os.NewFile(4, "burrow-out")
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = ""
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stderr), zap.DebugLevel))
+	defer logger.Sync()
+
+	service := &pluginService{
+		factory: factory,
+		log:     logger,
+		host:    rpc.NewClient(&conn{ReadCloser: hostIn, WriteCloser: hostOut}),
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", service); err != nil {
+		logger.Error("failed to serve plugin", zap.Error(err))
+		os.Exit(1)
+	}
+	server.ServeConn(&conn{ReadCloser: pluginIn, WriteCloser: pluginOut})
+
+	// Burrow has closed the connection, so the module is stopped if it was not stopped already
+	service.stop()
+	service.host.Close()
+}
+
+// pluginService serves Burrow's requests to the module in the plugin
+type pluginService struct {
+	factory helpers.ModuleFactory
+	log     *zap.Logger
+	host    *rpc.Client
+
+	lock    sync.Mutex
+	app     *protocol.ApplicationContext
+	module  protocol.Module
+	started bool
+	quit    chan struct{}
+	running sync.WaitGroup
+}
+
+// Configure creates the module, and configures it with the configuration that Burrow sent. If the module panics
+// because its configuration is not valid, the panic is returned as an error.
+func (service *pluginService) Configure(args *ConfigureArgs, reply *struct{}) (err error) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	if service.module != nil {
+		return errors.New("the plugin module has already been configured")
+	}
+
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(bytes.NewReader(args.Config)); err != nil {
+		return err
+	}
+
+	level := zap.NewAtomicLevelAt(zap.DebugLevel)
+	service.app = &protocol.ApplicationContext{
+		Logger:           service.log,
+		LogLevel:         &level,
+		StorageChannel:   make(chan *protocol.StorageRequest),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+	}
+	module := service.factory(service.app, service.log.With(zap.String("name", args.Name)))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid configuration: %v", r)
+		}
+	}()
+	module.Configure(args.Name, args.ConfigRoot)
+	service.module = module
+	return nil
+}
+
+// Start starts the module, and starts forwarding the requests it sends to Burrow
+func (service *pluginService) Start(args struct{}, reply *struct{}) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	if service.module == nil {
+		return errors.New("the plugin module has not been configured")
+	}
+	if service.started {
+		return nil
+	}
+
+	service.quit = make(chan struct{})
+	service.running.Add(2)
+	go service.forwardStorageRequests()
+	go service.forwardEvaluatorRequests()
+	if err := service.module.Start(); err != nil {
+		close(service.quit)
+		service.running.Wait()
+		return err
+	}
+	service.started = true
+	return nil
+}
+
+// Stop stops the module
+func (service *pluginService) Stop(args struct{}, reply *struct{}) error {
+	service.stop()
+	return nil
+}
+
+func (service *pluginService) stop() {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	if !service.started {
+		return
+	}
+	service.started = false
+	if err := service.module.Stop(); err != nil {
+		service.log.Warn("failed to stop module", zap.Error(err))
+	}
+	close(service.quit)
+	service.running.Wait()
+}
+
+// EventTypes returns the types of events that the module wants, if it implements EventHandler
+func (service *pluginService) EventTypes(args struct{}, reply *EventTypesReply) error {
+	if handler, ok := service.module.(EventHandler); ok {
+		reply.Subscribe = true
+		reply.Types = handler.EventTypes()
+	}
+	return nil
+}
+
+// Event sends an event from Burrow to the module
+func (service *pluginService) Event(event *protocol.Event, reply *struct{}) error {
+	handler, ok := service.module.(EventHandler)
+	if !ok {
+		return errors.New("the plugin module does not handle events")
+	}
+	handler.HandleEvent(event)
+	return nil
+}
+
+// Storage sends a request from Burrow to the module in a storage plugin, and waits for the response if the request has
+// one
+func (service *pluginService) Storage(call *StorageCall, reply *StorageReply) error {
+	module, ok := service.module.(storageModule)
+	if !ok {
+		return errors.New("the plugin module is not a storage module")
+	}
+	if call.Request == nil {
+		return errors.New("no storage request given")
+	}
+	request := call.Request
+	if call.WantReply {
+		request.Reply = make(chan interface{}, 1)
+	}
+	module.GetCommunicationChannel() <- request
+	if call.WantReply {
+		value, ok := <-request.Reply
+		*reply = *newStorageReply(value, ok)
+	}
+	return nil
+}
+
+// forwardStorageRequests sends the requests that the module sends to its StorageChannel to Burrow, and the responses
+// back to the module
+func (service *pluginService) forwardStorageRequests() {
+	defer service.running.Done()
+	for {
+		select {
+		case request := <-service.app.StorageChannel:
+			reply := &StorageReply{}
+			err := service.host.Call("Host.Storage", &StorageCall{Request: request, WantReply: request.Reply != nil}, reply)
+			if err != nil {
+				service.log.Error("failed to send storage request to Burrow", zap.Error(err))
+				reply = &StorageReply{}
+			}
+			if request.Reply != nil {
+				sendStorageReply(request, reply)
+			}
+		case <-service.quit:
+			return
+		}
+	}
+}
+
+// forwardEvaluatorRequests sends the requests that the module sends to its EvaluatorChannel to Burrow, and the status
+// of each group back to the module
+func (service *pluginService) forwardEvaluatorRequests() {
+	defer service.running.Done()
+	for {
+		select {
+		case request := <-service.app.EvaluatorChannel:
+			status := &protocol.ConsumerGroupStatus{}
+			if err := service.host.Call("Host.Evaluate", &EvaluatorCall{Request: request}, status); err != nil {
+				service.log.Error("failed to send evaluator request to Burrow", zap.Error(err))
+				status = &protocol.ConsumerGroupStatus{
+					Cluster: request.Cluster,
+					Group:   request.Group,
+					Status:  protocol.StatusNotFound,
+				}
+			}
+			request.Reply <- status
+		case <-service.quit:
+			return
+		}
+	}
+}
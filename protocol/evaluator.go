@@ -10,7 +10,11 @@
 
 package protocol
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // EvaluatorRequest is sent over the EvaluatorChannel that is stored in the application context. It is a query for the
 // status of a group in a cluster. The response to this query is sent over the reply channel. This request is typically
@@ -31,6 +35,13 @@ type EvaluatorRequest struct {
 	// regardless of the state of that partition. If false (the default), only partitions that have a status of WARN
 	// or above are returned in the status object.
 	ShowAll bool
+
+	// If the request is being traced, the span that it was sent from
+	Trace *TraceContext
+
+	// The time that the sender built the request. If it is set, the evaluator module records the time from then until
+	// the sender has the status in the evaluator.round-trip-time histogram
+	Submitted time.Time
 }
 
 // PartitionStatus represents the state of a single consumed partition
@@ -60,10 +71,29 @@ type PartitionStatus struct {
 	// last committed offset and the current broker end offset
 	CurrentLag uint64 `json:"current_lag"`
 
+	// An estimate of how far behind the consumer is in time for this partition, in milliseconds. See the TimeLag field
+	// of ConsumerPartition for how this is calculated
+	TimeLag int64 `json:"time_lag"`
+
+	// The number of offsets committed for this partition per minute. See the CommitRate field of ConsumerPartition
+	CommitRate float64 `json:"commit_rate"`
+
+	// The number of messages produced to this partition per second. See the ProduceRate field of ConsumerPartition
+	ProduceRate float64 `json:"produce_rate"`
+
+	// An estimate of how long (in milliseconds) until retention removes the last offset committed for this partition,
+	// or -1 if it cannot be estimated. See the RetentionHorizon field of ConsumerPartition
+	RetentionHorizon int64 `json:"retention_horizon"`
+
 	// A number between 0.0 and 1.0 that describes the percentage complete the offset information is for this partition.
 	// For example, if Burrow has been configured to store 10 offsets, and Burrow has only stored 7 commits for this
 	// partition, Complete will be 0.7
 	Complete float32 `json:"complete"`
+
+	// True if the broker end offset for this partition has not changed over the stored window. Idle partitions that the
+	// consumer has read to the end of are evaluated with a reduced set of rules, as there is no traffic for the consumer
+	// to keep up with
+	Idle bool `json:"idle"`
 }
 
 // ConsumerGroupStatus is the response object that is sent in reply to an EvaluatorRequest. It describes the current
@@ -75,8 +105,8 @@ type ConsumerGroupStatus struct {
 	// The name of the consumer group
 	Group string `json:"group"`
 
-	// The status of the consumer group. This is either NOTFOUND, OK, WARN, or ERR. It is calculated from the highest
-	// Status for the individual partitions
+	// The status of the consumer group. This is either NOTFOUND, OK, WARN, ERR, or MISSING. It is calculated from the
+	// highest Status for the individual partitions, unless the group is expected and has not committed recently
 	Status StatusConstant `json:"status"`
 
 	// A number between 0.0 and 1.0 that describes the percentage complete the partition information is for this group.
@@ -98,6 +128,35 @@ type ConsumerGroupStatus struct {
 
 	// The sum of all partition CurrentLag values for the group
 	TotalLag uint64 `json:"totallag"`
+
+	// The highest partition TimeLag value for the group, in milliseconds
+	MaxTimeLag int64 `json:"max_time_lag"`
+
+	// The sum of all partition CommitRate values for the group, in commits per minute. A rate that drops to zero while
+	// the group has lag is often the first sign that a consumer is stuck
+	CommitRate float64 `json:"commit_rate"`
+
+	// The sum of all partition ProduceRate values for the group, in messages per second
+	ProduceRate float64 `json:"produce_rate"`
+
+	// The current members of the group and their partition assignments, if the consumer module provides them
+	Members []*ConsumerGroupMember `json:"members,omitempty"`
+
+	// The state of the group as reported by Kafka (such as Stable or PreparingRebalance), if the consumer module
+	// provides it. This shows whether a lagging group is rebalancing
+	State string `json:"state,omitempty"`
+
+	// The recent offset rewinds for the group, oldest first. A rewind is recorded when the group commits an offset for
+	// a partition that is lower than its previous commit by at least the storage module's rewind-threshold
+	Rewinds []*OffsetRewind `json:"rewinds,omitempty"`
+
+	// The topics that were recently removed from the group because they were deleted or recreated, oldest first. The
+	// group's offsets for these topics were discarded at the time of removal
+	TopicRemovals []*TopicRemoval `json:"topic_removals,omitempty"`
+
+	// The labels configured for the cluster (such as env, region, or owner), so that consumers of the status can
+	// filter and route on them
+	ClusterLabels map[string]string `json:"cluster_labels,omitempty"`
 }
 
 // StatusConstant describes the state of a partition or group as a single value. These values are ordered from least
@@ -112,12 +171,12 @@ const (
 	// good state.
 	StatusOK StatusConstant = 1
 
-	// StatusWarning indicates that a partition is lagging - it is making progress, but falling further behind. For a
-	// group, it indicates that one or more partitions are lagging.
+	// StatusWarning indicates that a partition is lagging - it is making progress, but falling further behind, or it is
+	// close to losing data to retention. For a group, it indicates that one or more partitions are lagging.
 	StatusWarning StatusConstant = 2
 
-	// StatusError indicates that a group has one or more partitions that are in the Stop, Stall, or Rewind states. It
-	// is not used for partition status.
+	// StatusError indicates that a group has one or more partitions that are in the Stop, Stall, Rewind, or DataLoss
+	// states. It is not used for partition status.
 	StatusError StatusConstant = 3
 
 	// StatusStop indicates that the consumer has not committed an offset for that partition in some time, and the lag
@@ -131,9 +190,18 @@ const (
 	// StatusRewind indicates that the consumer has committed an offset for the partition that is less than the
 	// previous offset. It is not used for group status.
 	StatusRewind StatusConstant = 6
+
+	// StatusMissing indicates that the consumer group has been registered as expected for the cluster, but it has not
+	// committed offsets within the configured grace period. It is not used for partition status.
+	StatusMissing StatusConstant = 7
+
+	// StatusDataLoss indicates that the last offset the consumer committed for the partition is less than the log
+	// start offset, so messages were removed by retention before the consumer read them. It is not used for group
+	// status.
+	StatusDataLoss StatusConstant = 8
 )
 
-var statusStrings = [...]string{"NOTFOUND", "OK", "WARN", "ERR", "STOP", "STALL", "REWIND"}
+var statusStrings = [...]string{"NOTFOUND", "OK", "WARN", "ERR", "STOP", "STALL", "REWIND", "MISSING", "DATA_LOSS"}
 
 // String returns a string representation of a StatusConstant
 func (c StatusConstant) String() string {
@@ -149,6 +217,18 @@ func (c StatusConstant) MarshalText() ([]byte, error) {
 	return []byte(c.String()), nil
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading the string representation of a
+// StatusConstant
+func (c *StatusConstant) UnmarshalText(text []byte) error {
+	for i, name := range statusStrings {
+		if name == string(text) {
+			*c = StatusConstant(i)
+			return nil
+		}
+	}
+	return errors.New("unknown status: " + string(text))
+}
+
 // MarshalJSON implements the json.Marshaler interface. The status is the string representation of StatusConstant
 func (c StatusConstant) MarshalJSON() ([]byte, error) {
 	return json.Marshal(c.String())
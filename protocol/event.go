@@ -0,0 +1,94 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// EventType identifies the kind of change that an Event describes
+type EventType int
+
+const (
+	// EventStatusChange is published by the evaluator when the status of a consumer group is different from the last
+	// time it was evaluated. Cluster, Group, PreviousStatus, and Status are set.
+	EventStatusChange EventType = 0
+
+	// EventGroupExpired is published by the storage module when a consumer group is removed because it has not
+	// committed offsets within the expire-group interval. Cluster and Group are set.
+	EventGroupExpired EventType = 1
+
+	// EventPartitionCountChange is published by the storage module when the number of partitions of a topic that it
+	// already has offsets for increases. Cluster, Topic, PreviousPartitions, and Partitions are set.
+	EventPartitionCountChange EventType = 2
+
+	// EventModuleError is published by a module when it fails to do its work, such as fetching metadata or reading a
+	// message. Module (the config root of the module), Cluster (if the module is for one), and Message are set.
+	EventModuleError EventType = 3
+)
+
+var eventTypeStrings = [...]string{"status-change", "group-expired", "partition-count-change", "module-error"}
+
+// String returns a string representation of an EventType
+func (t EventType) String() string {
+	if (t >= 0) && (t < EventType(len(eventTypeStrings))) {
+		return eventTypeStrings[t]
+	}
+	return "unknown"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The type is the string representation of EventType
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading the string representation of an EventType
+func (t *EventType) UnmarshalText(text []byte) error {
+	for i, name := range eventTypeStrings {
+		if name == string(text) {
+			*t = EventType(i)
+			return nil
+		}
+	}
+	return errors.New("unknown event type: " + string(text))
+}
+
+// MarshalJSON implements the json.Marshaler interface. The type is the string representation of EventType
+func (t EventType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Event describes a change in the state that Burrow is monitoring, or in Burrow itself. Events are published to the
+// event bus (see helpers.PublishEvent), and are delivered to every subscriber for their type. Which fields are set
+// depends on the Type.
+type Event struct {
+	Type EventType `json:"type"`
+
+	// The time (in milliseconds) that the event happened
+	Timestamp int64 `json:"timestamp"`
+
+	Cluster string `json:"cluster,omitempty"`
+	Group   string `json:"group,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	Module  string `json:"module,omitempty"`
+
+	// For a status change, the status of the group before and after the change
+	PreviousStatus StatusConstant `json:"previous_status,omitempty"`
+	Status         StatusConstant `json:"status,omitempty"`
+
+	// For a partition count change, the number of partitions of the topic before and after the change
+	PreviousPartitions int `json:"previous_partitions,omitempty"`
+	Partitions         int `json:"partitions,omitempty"`
+
+	// For a module error, a description of the error
+	Message string `json:"message,omitempty"`
+}
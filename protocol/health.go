@@ -0,0 +1,78 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// HealthState describes whether a module or coordinator is able to do its work. These values are ordered from least
+// to most "bad", so that the health of a coordinator is the worst health of its modules.
+type HealthState int
+
+const (
+	// HealthOK indicates that the module is working normally
+	HealthOK HealthState = 0
+
+	// HealthDegraded indicates that the module is working, but has had errors recently, so some of its data may be
+	// missing or out of date
+	HealthDegraded HealthState = 1
+
+	// HealthFailed indicates that the module is not able to do its work, such as when it cannot reach its cluster
+	HealthFailed HealthState = 2
+)
+
+var healthStateStrings = [...]string{"OK", "DEGRADED", "FAILED"}
+
+// String returns a string representation of a HealthState
+func (s HealthState) String() string {
+	if (s >= 0) && (s < HealthState(len(healthStateStrings))) {
+		return healthStateStrings[s]
+	}
+	return "UNKNOWN"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The state is the string representation of HealthState
+func (s HealthState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading the string representation of a HealthState
+func (s *HealthState) UnmarshalText(text []byte) error {
+	for i, name := range healthStateStrings {
+		if name == string(text) {
+			*s = HealthState(i)
+			return nil
+		}
+	}
+	return errors.New("unknown health state: " + string(text))
+}
+
+// MarshalJSON implements the json.Marshaler interface. The state is the string representation of HealthState
+func (s HealthState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ModuleHealth is the health of a single module, with the reason that it is not OK
+type ModuleHealth struct {
+	Name   string      `json:"name"`
+	State  HealthState `json:"state"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// CoordinatorHealth is the health of a coordinator, which is the worst health of its modules (or of the coordinator
+// itself, if it has a problem of its own). The reason is that of the first module that is in the worst state.
+type CoordinatorHealth struct {
+	State   HealthState     `json:"state"`
+	Reason  string          `json:"reason,omitempty"`
+	Modules []*ModuleHealth `json:"modules"`
+}
@@ -45,6 +45,68 @@ type ApplicationContext struct {
 	// This is the channel over which any module should send storage requests for storage of offsets and group
 	// information, or to fetch the same information. It is serviced by the storage Coordinator.
 	StorageChannel chan *StorageRequest
+
+	// These are the channels over which the evaluators, and the HTTP server, send their requests to fetch information
+	// from storage. They are serviced by the storage Coordinator, which forwards the requests on the StorageChannel to
+	// the storage module first, then those on the StorageEvaluationChannel, and then those on the StorageAPIChannel, so
+	// that a flood of API requests cannot delay offsets being stored or groups being evaluated. If either is nil, the
+	// requests are sent over the StorageChannel instead. Use GetStorageChannel to get the channel for a priority.
+	StorageEvaluationChannel chan *StorageRequest
+	StorageAPIChannel        chan *StorageRequest
+
+	// This is the channel over which a reload of the configuration can be requested (such as via an HTTP call). It is
+	// serviced by core.Start(), and is nil if Burrow is not running.
+	ReloadChannel chan *ReloadRequest
+}
+
+// StoragePriority is the priority of a storage request, which selects the channel it is sent over
+type StoragePriority int
+
+const (
+	// StoragePriorityIngestion is the priority of the requests that store offsets and group information, which are
+	// forwarded to the storage module before any others
+	StoragePriorityIngestion StoragePriority = 0
+
+	// StoragePriorityEvaluation is the priority of the requests that fetch the information to evaluate groups
+	StoragePriorityEvaluation StoragePriority = 1
+
+	// StoragePriorityAPI is the priority of the fetches that the HTTP server sends, which are forwarded last
+	StoragePriorityAPI StoragePriority = 2
+)
+
+// GetStorageChannel returns the channel over which storage requests with the priority are sent. This is the
+// StorageChannel for ingestion, and for any priority that does not have its own channel.
+func (app *ApplicationContext) GetStorageChannel(priority StoragePriority) chan *StorageRequest {
+	switch {
+	case (priority == StoragePriorityEvaluation) && (app.StorageEvaluationChannel != nil):
+		return app.StorageEvaluationChannel
+	case (priority == StoragePriorityAPI) && (app.StorageAPIChannel != nil):
+		return app.StorageAPIChannel
+	default:
+		return app.StorageChannel
+	}
+}
+
+// ReloadRequest is sent over the ReloadChannel to reload the configuration. The result of the reload is sent to the
+// Reply channel, which must have a buffer of 1 so that the reload does not block if the requester has gone away.
+type ReloadRequest struct {
+	Reply chan *ConfigReload
+}
+
+// ConfigReload is the result of reloading the configuration. Applied holds the config keys that changed and were put
+// into use, and RestartRequired holds the config keys that changed but are only used when Burrow starts. If the reload
+// failed, Error is set and nothing was changed.
+type ConfigReload struct {
+	Error           string   `json:"error,omitempty"`
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart-required"`
+}
+
+// TraceContext identifies the span that a request was sent from, when the request is being traced, so that the work
+// done for the request can be recorded in the same trace. The IDs are in hex, as in a W3C traceparent header.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
 }
 
 // Module is a common interface for all modules so that they can be manipulated by the coordinators in the same way.
@@ -104,4 +166,8 @@ type Coordinator interface {
 	// any of its modules, and stop any goroutines that it has started. While it can return an error if there is a
 	// problem, the errors are mostly ignored.
 	Stop() error
+
+	// Health is called to get the health of the coordinator and each of its modules. It may be called at any time
+	// after Configure, from any goroutine, so it must not block.
+	Health() *CoordinatorHealth
 }
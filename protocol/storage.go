@@ -10,7 +10,12 @@
 
 package protocol
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
 
 // StorageRequestConstant is used in StorageRequest to indicate the type of request. Numeric ordering is not important
 type StorageRequestConstant int
@@ -47,7 +52,7 @@ const (
 	StorageFetchTopics StorageRequestConstant = 7
 
 	// StorageFetchConsumer is the request type to retrieve all stored information for a single consumer group. Requires
-	// Reply, Cluster, and Group fields. Returns a ConsumerTopics object
+	// Reply, Cluster, and Group fields. Returns a *ConsumerGroupDetail, which only has Topics if the group is stored
 	StorageFetchConsumer StorageRequestConstant = 8
 
 	// StorageFetchTopic is the request type to retrieve the current broker offsets (one per partition) for a topic.
@@ -62,6 +67,119 @@ const (
 	// StorageFetchConsumersForTopic is the request type to obtain a list of all consumer groups consuming from a topic.
 	// Returns a []string
 	StorageFetchConsumersForTopic StorageRequestConstant = 11
+
+	// StorageSetExpectedGroup is the request type to register a consumer group as expected for a cluster. Requires
+	// Cluster and Group fields. If Timestamp is set, it is used as the registration time (otherwise, now)
+	StorageSetExpectedGroup StorageRequestConstant = 12
+
+	// StorageSetDeleteExpectedGroup is the request type to remove a consumer group from the expected groups for a
+	// cluster. Requires Cluster and Group fields
+	StorageSetDeleteExpectedGroup StorageRequestConstant = 13
+
+	// StorageFetchExpectedGroups is the request type to retrieve the expected consumer groups for a cluster. Requires
+	// Reply and Cluster fields. Returns a map[string]int64 of group name to the time (in milliseconds) at which the
+	// group was registered as expected
+	StorageFetchExpectedGroups StorageRequestConstant = 14
+
+	// StorageSetConsumerMembers is the request type to replace the list of members for a consumer group. Requires
+	// Cluster, Group, and Members fields. An empty Members slice indicates that the group has no active members. The
+	// GroupState field may also be set
+	StorageSetConsumerMembers StorageRequestConstant = 15
+
+	// StorageFetchConsumerMembers is the request type to retrieve the current members of a consumer group. Requires
+	// Reply, Cluster, and Group fields. Returns a []*ConsumerGroupMember
+	StorageFetchConsumerMembers StorageRequestConstant = 16
+
+	// StorageSetConnectors is the request type to replace the Kafka Connect connectors that were fetched from a single
+	// Connect cluster. Requires Cluster, Source, and Connectors fields
+	StorageSetConnectors StorageRequestConstant = 17
+
+	// StorageFetchConnectors is the request type to retrieve the Kafka Connect connectors for a cluster, from all
+	// Connect clusters. Requires Reply and Cluster fields. Returns a []*Connector, sorted by name
+	StorageFetchConnectors StorageRequestConstant = 18
+
+	// StorageSetBrokerLogStartOffset is the request type to store the log start offset (the oldest offset that has not
+	// been removed by retention) for a partition. Requires Cluster, Topic, Partition, and Offset fields
+	StorageSetBrokerLogStartOffset StorageRequestConstant = 19
+
+	// StorageFetchConsumerRewinds is the request type to retrieve the recent offset rewinds for a consumer group.
+	// Requires Reply, Cluster, and Group fields. Returns a []*OffsetRewind, oldest first
+	StorageFetchConsumerRewinds StorageRequestConstant = 20
+
+	// StorageFetchConsumerGroupState is the request type to retrieve the state of a consumer group (such as Stable or
+	// Empty), from the most recent StorageSetConsumerMembers request. Requires Reply, Cluster, and Group fields.
+	// Returns a string, which is empty if the state is not known
+	StorageFetchConsumerGroupState StorageRequestConstant = 21
+
+	// StorageSetTopicConfig is the request type to replace the configuration of a topic, as fetched from the brokers.
+	// Requires Cluster, Topic, and TopicConfig fields
+	StorageSetTopicConfig StorageRequestConstant = 22
+
+	// StorageFetchTopicConfig is the request type to retrieve the configuration of a topic. Requires Reply, Cluster,
+	// and Topic fields. Returns a map[string]string of config name to value. No reply is sent if the configuration of
+	// the topic has not been fetched
+	StorageFetchTopicConfig StorageRequestConstant = 23
+
+	// StorageSetClusterReplication is the request type to replace the under-replicated and offline partitions for a
+	// cluster, as found at the last metadata refresh. Requires Cluster and Replication fields
+	StorageSetClusterReplication StorageRequestConstant = 24
+
+	// StorageFetchClusterReplication is the request type to retrieve the under-replicated and offline partitions for a
+	// cluster. Requires Reply and Cluster fields. Returns a *ClusterReplication, which must not be modified. No reply is
+	// sent if the cluster module has not refreshed metadata yet
+	StorageFetchClusterReplication StorageRequestConstant = 25
+
+	// StorageSetClusterBrokers is the request type to replace the brokers for a cluster, as found at the last metadata
+	// refresh. Requires Cluster and Brokers fields
+	StorageSetClusterBrokers StorageRequestConstant = 26
+
+	// StorageFetchClusterBrokers is the request type to retrieve the brokers for a cluster. Requires Reply and Cluster
+	// fields. Returns a *ClusterBrokers, which must not be modified. No reply is sent if the cluster module has not
+	// refreshed metadata yet
+	StorageFetchClusterBrokers StorageRequestConstant = 27
+
+	// StorageFetchConsumerTopicRemovals is the request type to retrieve the topics that were recently removed from a
+	// consumer group because the topic was deleted or recreated. Requires Reply, Cluster, and Group fields. Returns a
+	// []*TopicRemoval, oldest first
+	StorageFetchConsumerTopicRemovals StorageRequestConstant = 28
+
+	// StorageSetClusterLeaderChurn is the request type to replace the partition leadership changes for a cluster, as
+	// tracked by the cluster module. Requires Cluster and LeaderChurn fields
+	StorageSetClusterLeaderChurn StorageRequestConstant = 29
+
+	// StorageFetchClusterLeaderChurn is the request type to retrieve the partitions in a cluster that have changed
+	// leader recently. Requires Reply and Cluster fields. Returns a *ClusterLeaderChurn, which must not be modified. No
+	// reply is sent if the cluster module has not refreshed metadata yet
+	StorageFetchClusterLeaderChurn StorageRequestConstant = 30
+
+	// StorageFetchTopicProduceRates is the request type to retrieve the rate at which messages are being produced to
+	// each partition of a topic. Requires Reply, Cluster, and Topic fields. Returns a []float64 of messages per second,
+	// indexed by partition
+	StorageFetchTopicProduceRates StorageRequestConstant = 31
+
+	// StorageFetchClusterActivity is the request type to retrieve when offsets were last received for a cluster.
+	// Requires Reply and Cluster fields. Returns a *ClusterActivity
+	StorageFetchClusterActivity StorageRequestConstant = 32
+
+	// StorageFetchConsumedTopics is the request type to retrieve the topics in a cluster that consumer groups have
+	// committed offsets for recently, whether or not the broker offsets for the topic are known. Requires Reply and
+	// Cluster fields. Returns a []string of topic names, sorted
+	StorageFetchConsumedTopics StorageRequestConstant = 33
+
+	// StorageWriteSnapshot is the request type to write the stored offsets to the storage module's snapshot file, so
+	// that they can be restored by another Burrow process. Requires the Reply field. Returns an error, which is nil if
+	// the snapshot was written (or if the module has no snapshot file)
+	StorageWriteSnapshot StorageRequestConstant = 34
+
+	// StorageStreamConsumers is the request type to retrieve the names of all consumer groups in a cluster, in chunks,
+	// instead of all at once as with StorageFetchConsumers. Requires Reply and Cluster fields. Sends a
+	// *StorageListChunk for each chunk. The Reply channel is closed without a chunk if the cluster is not found
+	StorageStreamConsumers StorageRequestConstant = 35
+
+	// StorageStreamTopics is the request type to retrieve the names of all topics in a cluster, in chunks, instead of
+	// all at once as with StorageFetchTopics. Requires Reply and Cluster fields. Sends a *StorageListChunk for each
+	// chunk. The Reply channel is closed without a chunk if the cluster is not found
+	StorageStreamTopics StorageRequestConstant = 36
 )
 
 var storageRequestStrings = [...]string{
@@ -77,6 +195,31 @@ var storageRequestStrings = [...]string{
 	"StorageFetchTopic",
 	"StorageClearConsumerOwners",
 	"StorageFetchConsumersForTopic",
+	"StorageSetExpectedGroup",
+	"StorageSetDeleteExpectedGroup",
+	"StorageFetchExpectedGroups",
+	"StorageSetConsumerMembers",
+	"StorageFetchConsumerMembers",
+	"StorageSetConnectors",
+	"StorageFetchConnectors",
+	"StorageSetBrokerLogStartOffset",
+	"StorageFetchConsumerRewinds",
+	"StorageFetchConsumerGroupState",
+	"StorageSetTopicConfig",
+	"StorageFetchTopicConfig",
+	"StorageSetClusterReplication",
+	"StorageFetchClusterReplication",
+	"StorageSetClusterBrokers",
+	"StorageFetchClusterBrokers",
+	"StorageFetchConsumerTopicRemovals",
+	"StorageSetClusterLeaderChurn",
+	"StorageFetchClusterLeaderChurn",
+	"StorageFetchTopicProduceRates",
+	"StorageFetchClusterActivity",
+	"StorageFetchConsumedTopics",
+	"StorageWriteSnapshot",
+	"StorageStreamConsumers",
+	"StorageStreamTopics",
 }
 
 // String returns a string representation of a StorageRequestConstant for logging
@@ -93,6 +236,18 @@ func (c StorageRequestConstant) MarshalText() ([]byte, error) {
 	return []byte(c.String()), nil
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading the string representation of a
+// StorageRequestConstant
+func (c *StorageRequestConstant) UnmarshalText(text []byte) error {
+	for i, name := range storageRequestStrings {
+		if name == string(text) {
+			*c = StorageRequestConstant(i)
+			return nil
+		}
+	}
+	return errors.New("unknown storage request type: " + string(text))
+}
+
 // MarshalJSON implements the json.Marshaler interface. The status is the string representation of
 // StorageRequestConstant
 func (c StorageRequestConstant) MarshalJSON() ([]byte, error) {
@@ -119,6 +274,10 @@ type StorageRequest struct {
 	// The name of the topic to which the request applies
 	Topic string
 
+	// For StorageSetDeleteTopic requests, why the topic is being removed (such as "deleted"). This is recorded with
+	// each consumer group that had offsets for the topic
+	Reason string
+
 	// The ID of the partition to which the request applies
 	Partition int32
 
@@ -139,6 +298,69 @@ type StorageRequest struct {
 
 	// For StorageSetConsumerOwner requests, a string containing the client_id set by the consumer
 	ClientID string
+
+	// For StorageSetConsumerMembers requests, the members of the group and their partition assignments
+	Members []*ConsumerGroupMember
+
+	// For StorageSetConsumerMembers requests, the state of the group as reported by Kafka (Stable,
+	// PreparingRebalance, CompletingRebalance, Empty, or Dead), if the consumer module knows it
+	GroupState string
+
+	// For StorageSetConnectors requests, the name of the consumer module that the connectors were fetched from
+	Source string
+
+	// For StorageSetConnectors requests, all of the connectors that are currently running in the Connect cluster
+	Connectors []*Connector
+
+	// For StorageSetTopicConfig requests, the configuration of the topic, as config name to value
+	TopicConfig map[string]string
+
+	// For StorageSetClusterReplication requests, the partitions in the cluster that are under-replicated or offline
+	Replication *ClusterReplication
+
+	// For StorageSetClusterBrokers requests, the brokers in the cluster and the controller
+	Brokers *ClusterBrokers
+
+	// For StorageSetClusterLeaderChurn requests, the partitions in the cluster that have changed leader recently
+	LeaderChurn *ClusterLeaderChurn
+
+	// If the request is being traced, the span that it was sent from
+	Trace *TraceContext
+
+	// The time that the sender built the request. If it is set, the storage module records the time from then until the
+	// sender has the first reply in the storage.round-trip-time histogram for the RequestType
+	Submitted time.Time
+
+	// Whether the request came from AcquireStorageRequest, and should be returned to the pool once it is handled
+	pooled bool
+}
+
+var storageRequestPool = sync.Pool{
+	New: func() interface{} {
+		return new(StorageRequest)
+	},
+}
+
+// AcquireStorageRequest returns a StorageRequest from a pool, set to the values given, to avoid allocating one for each
+// offset that is sent to storage. It is only for "Set" requests, which have no Reply. Once the request is sent, the
+// sender must not use it again, as the storage module returns it to the pool with ReleaseStorageRequest after it has
+// been handled.
+func AcquireStorageRequest(values StorageRequest) *StorageRequest {
+	request := storageRequestPool.Get().(*StorageRequest)
+	*request = values
+	request.pooled = true
+	return request
+}
+
+// ReleaseStorageRequest clears the request and returns it to the pool, if it came from AcquireStorageRequest.
+// Otherwise, it does nothing. It is called by storage modules once they have handled a request, and by senders for a
+// request that was never sent. A storage module that does not call it only means that the request is not reused.
+func ReleaseStorageRequest(request *StorageRequest) {
+	if (request == nil) || !request.pooled {
+		return
+	}
+	*request = StorageRequest{}
+	storageRequestPool.Put(request)
 }
 
 // ConsumerPartition represents the information stored for a group for a single partition. It is used as part of the
@@ -146,7 +368,8 @@ type StorageRequest struct {
 type ConsumerPartition struct {
 	// A slice containing a ConsumerOffset object for each offset Burrow has stored for this partition. This can be any
 	// length up to the number of intervals Burrow has been configured to store, depending on how many offset commits
-	// have been seen for this partition
+	// have been seen for this partition. The slice and the offsets in it may be shared with other responses, so they
+	// must not be modified
 	Offsets []*ConsumerOffset `json:"offsets"`
 
 	// A slice containing the history of broker offsets stored for this partition. This is used for evaluation only,
@@ -163,6 +386,243 @@ type ConsumerPartition struct {
 	// The current number of messages that the consumer is behind for this partition. This is calculated using the
 	// last committed offset and the current broker end offset
 	CurrentLag uint64 `json:"current-lag"`
+
+	// An estimate of how far behind the consumer is in time for this partition, in milliseconds. This is how long ago
+	// the broker end offset passed the last committed offset, interpolated from the stored broker offsets. If that
+	// happened before the oldest stored broker offset, it is the age of that offset, so the real lag is higher
+	TimeLag int64 `json:"time-lag"`
+
+	// The log start offset for the partition, which is the oldest offset that the broker has not removed due to
+	// retention. Zero if it has not been fetched yet. If the last committed offset is less than this, the consumer has
+	// lost data
+	LogStartOffset int64 `json:"log-start-offset"`
+
+	// An estimate of how long (in milliseconds) until retention removes the last offset the consumer committed for this
+	// partition, based on how fast the log start offset has been advancing. Zero if the consumer has already lost data,
+	// and -1 if it cannot be estimated (such as when the log start offset has not been seen to advance)
+	RetentionHorizon int64 `json:"retention-horizon"`
+
+	// The number of offsets the consumer has committed for this partition per minute, averaged over the storage
+	// module's commit-rate-window. Commits are counted by their timestamp, so this is accurate even for commits that
+	// were read when Burrow started
+	CommitRate float64 `json:"commit-rate"`
+
+	// The number of messages produced to this partition per second, calculated from the stored broker offsets. Comparing
+	// this with CommitRate and CurrentLag shows whether the consumer can catch up
+	ProduceRate float64 `json:"produce-rate"`
+}
+
+// ConsumerGroupMember describes a single member of a consumer group, as found in the group metadata. It is the
+// response to a StorageFetchConsumerMembers request
+type ConsumerGroupMember struct {
+	// The member ID assigned to the consumer by the group coordinator
+	MemberID string `json:"member_id"`
+
+	// The static membership ID configured by the consumer, if any
+	GroupInstanceID string `json:"group_instance_id,omitempty"`
+
+	// A string containing the client_id set by the consumer
+	ClientID string `json:"client_id"`
+
+	// A string that describes the consumer host
+	ClientHost string `json:"client_host"`
+
+	// A map of topic names to the partition IDs that are assigned to this member
+	Assignment map[string][]int32 `json:"assignment"`
+}
+
+// OffsetRewind describes a single time that a consumer group committed an offset for a partition that was lower than
+// the offset it had committed before, such as when the group's offsets are reset. It is part of the response to a
+// StorageFetchConsumerRewinds request
+type OffsetRewind struct {
+	// The topic name for the partition
+	Topic string `json:"topic"`
+
+	// The partition ID
+	Partition int32 `json:"partition"`
+
+	// The offset that was committed before the rewind
+	PreviousOffset int64 `json:"previous_offset"`
+
+	// The offset that the group rewound to
+	Offset int64 `json:"offset"`
+
+	// The timestamp of the commit that rewound the offset, in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+// TopicRemoval describes a single time that a topic was removed from a consumer group because the cluster module
+// found that the topic was deleted, or was recreated with fewer partitions. It is part of the response to a
+// StorageFetchConsumerTopicRemovals request
+type TopicRemoval struct {
+	// The topic name
+	Topic string `json:"topic"`
+
+	// Why the topic was removed
+	Reason string `json:"reason"`
+
+	// The time that the topic was removed, in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Connector describes a single Kafka Connect connector and its tasks. It is part of the response to a
+// StorageFetchConnectors request
+type Connector struct {
+	// The name of the connector
+	Name string `json:"name"`
+
+	// The name of the consumer module that the connector was fetched from, which identifies the Connect cluster
+	ConnectCluster string `json:"connect_cluster"`
+
+	// The type of connector, either "sink" or "source"
+	Type string `json:"type"`
+
+	// The state of the connector, such as RUNNING, PAUSED, or FAILED
+	State string `json:"state"`
+
+	// The Connect worker that is running the connector
+	WorkerID string `json:"worker_id"`
+
+	// For sink connectors, the consumer group that is used to consume from Kafka
+	Group string `json:"group,omitempty"`
+
+	// The state of each of the connector's tasks
+	Tasks []*ConnectorTask `json:"tasks"`
+}
+
+// ClusterReplication describes the partitions in a cluster that are not fully replicated, as seen by the cluster
+// module at its last metadata refresh. It is the response to a StorageFetchClusterReplication request
+type ClusterReplication struct {
+	// The partitions that have fewer in-sync replicas than replicas
+	UnderReplicated []*PartitionReplicas `json:"under_replicated"`
+
+	// The partitions that have no leader, so they cannot be produced to or consumed from
+	Offline []*PartitionReplicas `json:"offline"`
+
+	// The time (in milliseconds) of the metadata refresh
+	Timestamp int64 `json:"timestamp"`
+}
+
+// PartitionReplicas describes the replicas of a single partition that is under-replicated or offline. It is part of
+// ClusterReplication
+type PartitionReplicas struct {
+	// The name of the topic
+	Topic string `json:"topic"`
+
+	// The ID of the partition
+	Partition int32 `json:"partition"`
+
+	// The IDs of the brokers that are assigned as replicas for the partition
+	Replicas []int32 `json:"replicas"`
+
+	// The IDs of the replicas that are in sync with the leader
+	InSyncReplicas []int32 `json:"isr"`
+
+	// The time (in milliseconds) at which the partition was first seen in this state. This is reset if the partition
+	// recovers, and is never earlier than when the cluster module started
+	Since int64 `json:"since"`
+}
+
+// ClusterBrokers describes the brokers in a cluster, as seen by the cluster module at its last metadata refresh. It is
+// the response to a StorageFetchClusterBrokers request
+type ClusterBrokers struct {
+	// The brokers in the cluster, sorted by ID
+	Brokers []*Broker `json:"brokers"`
+
+	// The ID of the broker that is the controller, or -1 if it is not known
+	ControllerID int32 `json:"controller_id"`
+
+	// The time (in milliseconds) of the metadata refresh
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Broker describes a single broker in a cluster. It is part of ClusterBrokers
+type Broker struct {
+	// The ID of the broker
+	ID int32 `json:"id"`
+
+	// The host name of the broker, as advertised in the metadata
+	Host string `json:"host"`
+
+	// The port of the broker, as advertised in the metadata
+	Port int32 `json:"port"`
+
+	// The rack of the broker, if one is configured
+	Rack string `json:"rack,omitempty"`
+}
+
+// ClusterLeaderChurn describes the partitions in a cluster that have changed leader within the churn window of the
+// cluster module. It is the response to a StorageFetchClusterLeaderChurn request
+type ClusterLeaderChurn struct {
+	// The partitions that have changed leader within the window, sorted by the number of changes (most first)
+	Partitions []*PartitionLeaderChurn `json:"partitions"`
+
+	// The length of the window (in seconds) that leader changes are counted over
+	Window int64 `json:"window"`
+
+	// The time (in milliseconds) of the metadata refresh
+	Timestamp int64 `json:"timestamp"`
+}
+
+// StorageListChunk is part of the response to a StorageStreamConsumers or StorageStreamTopics request. The names are
+// sorted, across all of the chunks. The receiver must keep reading from the Reply channel until it is closed, as the
+// storage module waits for each chunk to be taken. If the receiver does not read the next chunk in time, the storage
+// module closes the Reply channel without sending the last chunk, so the list is only complete if Last was set.
+type StorageListChunk struct {
+	// The names of the consumer groups or topics in this chunk
+	Names []string
+
+	// Whether this is the last chunk of the list
+	Last bool
+}
+
+// ClusterActivity describes when offsets were last received for a cluster, which shows whether the modules that fetch
+// them are keeping up. It is the response to a StorageFetchClusterActivity request
+type ClusterActivity struct {
+	// The time (in milliseconds) of the latest broker offset, or zero if none have been received
+	LastBrokerOffset int64 `json:"last_broker_offset"`
+
+	// The latest timestamp (in milliseconds) of a consumer offset commit, or zero if none have been received
+	LastConsumerOffset int64 `json:"last_consumer_offset"`
+
+	// The number of requests waiting in the storage module's queues, and the number that the queues can hold. These
+	// are for the storage module as a whole, not only the cluster. A full queue means the storage module is not
+	// keeping up, and that senders are blocked
+	QueueDepth    int `json:"queue_depth"`
+	QueueCapacity int `json:"queue_capacity"`
+}
+
+// PartitionLeaderChurn describes the leadership changes of a single partition. It is part of ClusterLeaderChurn
+type PartitionLeaderChurn struct {
+	// The name of the topic
+	Topic string `json:"topic"`
+
+	// The ID of the partition
+	Partition int32 `json:"partition"`
+
+	// The ID of the broker that is currently the leader for the partition, or -1 if the partition has no leader
+	Leader int32 `json:"leader"`
+
+	// The number of times the leader has changed within the window
+	LeaderChanges int `json:"leader_changes"`
+
+	// The time (in milliseconds) of the most recent leader change
+	LastChange int64 `json:"last_change"`
+}
+
+// ConnectorTask describes the state of a single task for a Kafka Connect connector
+type ConnectorTask struct {
+	// The ID of the task
+	ID int32 `json:"id"`
+
+	// The state of the task, such as RUNNING, PAUSED, or FAILED
+	State string `json:"state"`
+
+	// The Connect worker that is running the task
+	WorkerID string `json:"worker_id"`
+
+	// If the task has failed, the error that caused the failure
+	Trace string `json:"trace,omitempty"`
 }
 
 // Lag is just a wrapper for a uint64, but it can be `nil`
@@ -200,8 +660,22 @@ type ConsumerOffset struct {
 	Lag *Lag `json:"lag"`
 }
 
-// ConsumerTopics is the response that is sent for a StorageFetchConsumer request. It is a map of topic names to
-// ConsumerPartitions objects that describe that topic
+// ConsumerGroupDetail is the response that is sent for a StorageFetchConsumer request. All of it is read from storage
+// at the same time, so it describes the group at a single moment. If the group is not stored, but is expected, only
+// ExpectedSince is set.
+type ConsumerGroupDetail struct {
+	Topics        ConsumerTopics
+	Members       []*ConsumerGroupMember
+	State         string
+	Rewinds       []*OffsetRewind
+	TopicRemovals []*TopicRemoval
+
+	// The time (in milliseconds) at which the group was registered as expected, or zero if it is not expected
+	ExpectedSince int64
+}
+
+// ConsumerTopics is the set of topics in a ConsumerGroupDetail. It is a map of topic names to ConsumerPartitions
+// objects that describe that topic
 type ConsumerTopics map[string]ConsumerPartitions
 
 // ConsumerPartitions describes all partitions for a single topic. The index indicates the partition ID, and the value
@@ -0,0 +1,36 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("reporter.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+	)
+	helpers.RegisterConfigKeys("reporter.*", "statsd", append([]helpers.ConfigKey{
+		{Name: "network", Type: helpers.ConfigTypeString, Default: "udp"},
+		{Name: "address", Type: helpers.ConfigTypeString, Default: "localhost:8125"},
+		{Name: "prefix", Type: helpers.ConfigTypeString, Default: "burrow."},
+		{Name: "format", Type: helpers.ConfigTypeString, Default: "dogstatsd"},
+		{Name: "tags", Type: helpers.ConfigTypeStringList},
+		{Name: "interval", Type: helpers.ConfigTypeInteger, Default: 60},
+		{Name: "consumer-status", Type: helpers.ConfigTypeBoolean, Default: true},
+		{Name: "max-packet-size", Type: helpers.ConfigTypeInteger, Default: 1432},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+	helpers.RegisterConfigKeys("reporter.*", "otlp", append([]helpers.ConfigKey{
+		{Name: "prefix", Type: helpers.ConfigTypeString, Default: "burrow."},
+		{Name: "interval", Type: helpers.ConfigTypeInteger, Default: 60},
+		{Name: "consumer-status", Type: helpers.ConfigTypeBoolean, Default: true},
+	}, helpers.ConsumerFilterConfigKeys...)...)
+}
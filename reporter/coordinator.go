@@ -0,0 +1,121 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+// Package reporter - Metrics reporting subsystem.
+// The reporter subsystem periodically pushes Burrow's own metrics (see helpers.GetMetrics), and the status and lag of
+// each consumer group, to an external metrics system. This is for sites that collect metrics by having them pushed to
+// an agent, rather than by polling the HTTP server.
+//
+// Modules
+//
+// Currently, the following modules are provided:
+//
+// * statsd - Send metrics to a StatsD or DogStatsD agent
+//
+// * otlp - Send metrics to an OpenTelemetry collector, using OTLP over HTTP or gRPC
+package reporter
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// Coordinator manages all reporter modules, making sure they are configured, started, and stopped at the appropriate
+// time.
+type Coordinator struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	modules map[string]protocol.Module
+}
+
+// getModuleForClass returns the correct module based on the passed className. As part of the Configure steps, if there
+// is any error, it will panic with an appropriate message describing the problem.
+func getModuleForClass(app *protocol.ApplicationContext, moduleName, className string) protocol.Module {
+	logger := app.Logger.With(
+		zap.String("type", "module"),
+		zap.String("coordinator", "reporter"),
+		zap.String("class", className),
+		zap.String("name", moduleName),
+	)
+
+	switch className {
+	case "statsd":
+		return &StatsdReporter{
+			App: app,
+			Log: logger,
+		}
+	case "otlp":
+		return &OTLPReporter{
+			App: app,
+			Log: logger,
+		}
+	default:
+		if factory := helpers.GetModuleClass("reporter", className); factory != nil {
+			return factory(app, logger)
+		}
+		panic("Unknown reporter className provided: " + className)
+	}
+}
+
+// Configure is called to create each of the configured reporter modules and call their Configure funcs to validate
+// their individual configurations and set them up. If there are any problems, it is expected that these funcs will
+// panic with a descriptive error message, as configuration failures are not recoverable errors.
+func (rc *Coordinator) Configure() {
+	rc.Log.Info("configuring")
+
+	rc.modules = make(map[string]protocol.Module)
+
+	// Create all configured reporter modules. Unlike the other coordinators, having none is normal
+	modules := viper.GetStringMap("reporter")
+	for name := range modules {
+		configRoot := "reporter." + name
+		module := getModuleForClass(rc.App, name, viper.GetString(configRoot+".class-name"))
+		module.Configure(name, configRoot)
+		rc.modules[name] = module
+	}
+}
+
+// Start calls each of the configured reporter modules' underlying Start funcs. As the coordinator itself has no ongoing
+// work to do, it does not start any other goroutines. If any module Start returns an error, this func stops immediately
+// and returns that error to the caller. No further modules will be loaded after that.
+func (rc *Coordinator) Start() error {
+	rc.Log.Info("starting")
+
+	err := helpers.StartCoordinatorModules(rc.modules)
+	if err != nil {
+		return errors.New("Error starting reporter module: " + err.Error())
+	}
+	return nil
+}
+
+// Stop calls each of the configured reporter modules' underlying Stop funcs. It is expected that the module Stop will
+// not return until the module has been completely stopped. While an error can be returned, this func always returns no
+// error, as a failure during stopping is not a critical failure
+func (rc *Coordinator) Stop() error {
+	rc.Log.Info("stopping")
+
+	helpers.StopCoordinatorModules(rc.modules)
+	return nil
+}
+
+// Health returns the health of each reporter module
+func (rc *Coordinator) Health() *protocol.CoordinatorHealth {
+	return helpers.GetCoordinatorHealth("reporter", rc.modules)
+}
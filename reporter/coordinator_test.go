@@ -0,0 +1,80 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureCoordinator() *Coordinator {
+	coordinator := Coordinator{
+		Log: zap.NewNop(),
+	}
+	coordinator.App = &protocol.ApplicationContext{
+		Logger:           zap.NewNop(),
+		StorageChannel:   make(chan *protocol.StorageRequest),
+		EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+	}
+
+	viper.Reset()
+	viper.Set("reporter.test.class-name", "statsd")
+	return &coordinator
+}
+
+func TestCoordinator_ImplementsCoordinator(t *testing.T) {
+	assert.Implements(t, (*protocol.Coordinator)(nil), new(Coordinator))
+}
+
+func TestCoordinator_Configure(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	assert.Lenf(t, coordinator.modules, 1, "Expected 1 module configured, not %v", len(coordinator.modules))
+	assert.IsType(t, &StatsdReporter{}, coordinator.modules["test"], "Expected a statsd module")
+}
+
+func TestCoordinator_Configure_NoModules(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Reset()
+	coordinator.Configure()
+
+	assert.Empty(t, coordinator.modules, "Expected no modules configured")
+}
+
+func TestCoordinator_Configure_BadClass(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	viper.Set("reporter.test.class-name", "nonexistent")
+	assert.Panics(t, coordinator.Configure, "Expected panic for an unknown class")
+}
+
+func TestCoordinator_StartStop(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+
+	// Swap out the coordinator modules with a mock for testing
+	mockModule := &helpers.MockModule{}
+	mockModule.On("Start").Return(nil)
+	mockModule.On("Stop").Return(nil)
+	coordinator.modules["test"] = mockModule
+
+	coordinator.Start()
+	mockModule.AssertCalled(t, "Start")
+
+	coordinator.Stop()
+	mockModule.AssertCalled(t, "Stop")
+}
@@ -0,0 +1,76 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/linkedin/Burrow/helpers"
+)
+
+// registeredMetric is one of Burrow's own metrics, which is a go-metrics Counter, Gauge, or Histogram
+type registeredMetric struct {
+	name   string
+	metric interface{}
+}
+
+// getRegisteredMetrics returns all of Burrow's own metrics, sorted by name so that they are sent in the same order
+// each interval
+func getRegisteredMetrics() []registeredMetric {
+	registered := make([]registeredMetric, 0)
+	helpers.GetMetricsRegistry().Each(func(name string, metric interface{}) {
+		registered = append(registered, registeredMetric{name: name, metric: metric})
+	})
+	sort.Slice(registered, func(i, j int) bool { return registered[i].name < registered[j].name })
+	return registered
+}
+
+// deliveryMetrics are the metrics for the sends that a reporter makes to its target, so that a target that cannot be
+// reached can be alerted on separately from what is being reported. Each send is counted in <configRoot>.attempts, and
+// then in either <configRoot>.successes or <configRoot>.failures. Failures where the target responded with an HTTP
+// status are also counted in <configRoot>.failures.<status>, and the time of each send is in <configRoot>.send-time.
+type deliveryMetrics struct {
+	configRoot   string
+	attemptCount metrics.Counter
+	successCount metrics.Counter
+	failureCount metrics.Counter
+	sendTime     metrics.Histogram
+}
+
+func newDeliveryMetrics(configRoot string) *deliveryMetrics {
+	return &deliveryMetrics{
+		configRoot:   configRoot,
+		attemptCount: helpers.GetMetricCounter(configRoot + ".attempts"),
+		successCount: helpers.GetMetricCounter(configRoot + ".successes"),
+		failureCount: helpers.GetMetricCounter(configRoot + ".failures"),
+		sendTime:     helpers.GetMetricHistogram(configRoot + ".send-time"),
+	}
+}
+
+// record counts one send that started at the time given, and returns the error from it unchanged
+func (d *deliveryMetrics) record(startTime time.Time, err error) error {
+	helpers.UpdateMetricTime(d.sendTime, startTime)
+	d.attemptCount.Inc(1)
+	if err == nil {
+		d.successCount.Inc(1)
+		return nil
+	}
+
+	d.failureCount.Inc(1)
+	if statusErr, ok := err.(*helpers.OTLPStatusError); ok {
+		helpers.GetMetricCounter(d.configRoot + ".failures." + strconv.Itoa(statusErr.StatusCode)).Inc(1)
+	}
+	return err
+}
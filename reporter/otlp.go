@@ -0,0 +1,211 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// OTLPReporter is a reporter module that pushes metrics to an OpenTelemetry collector each interval, using OTLP over
+// HTTP or gRPC. The collector, the protocol, and the resource attributes that are sent with the metrics, are configured
+// in the otlp section, which is shared with tracing (see helpers.GetOTLPClient).
+//
+// Burrow's own metrics are sent with their registry names, after the prefix: counters are sent as cumulative sums,
+// gauges as gauges, and histograms as summaries with the 50th, 95th, and 99th percentiles. The histograms of durations
+// (named "...-time") have the unit "us", as they are in microseconds.
+//
+// If consumer-status is enabled (the default), every consumer group accepted by the group-allowlist and group-denylist
+// is evaluated each interval, and its status, total lag, max lag, time lag, and partition count are sent as gauges,
+// with the cluster, group, and cluster labels as attributes.
+type OTLPReporter struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name           string
+	client         *helpers.OTLPClient
+	prefix         string
+	interval       time.Duration
+	consumerStatus bool
+	filter         *helpers.ConsumerFilter
+
+	startTime   time.Time
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+
+	reportTime metrics.Histogram
+	sentCount  metrics.Counter
+	errorCount metrics.Counter
+	delivery   *deliveryMetrics
+}
+
+// Configure validates the configuration for the module, including the otlp section. The interval defaults to 60
+// seconds, and the prefix of every metric name to "burrow.". If there are any problems, it is expected that this func
+// will panic with a descriptive error message, as configuration failures are not recoverable errors.
+func (module *OTLPReporter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+	module.reportTime = helpers.GetMetricHistogram(configRoot + ".report-time")
+	module.sentCount = helpers.GetMetricCounter(configRoot + ".sent")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+	module.delivery = newDeliveryMetrics(configRoot)
+
+	viper.SetDefault(configRoot+".prefix", "burrow.")
+	viper.SetDefault(configRoot+".interval", 60)
+	viper.SetDefault(configRoot+".consumer-status", true)
+
+	module.client = helpers.GetOTLPClient()
+	module.prefix = viper.GetString(configRoot + ".prefix")
+	module.interval = time.Duration(viper.GetInt(configRoot+".interval")) * time.Second
+	if module.interval <= 0 {
+		panic("Reporter '" + name + "' has an invalid interval")
+	}
+
+	module.consumerStatus = viper.GetBool(configRoot + ".consumer-status")
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		panic("Reporter '" + name + "' has an invalid filter: " + err.Error())
+	}
+	module.filter = filter
+}
+
+// Start starts the goroutine that sends the metrics each interval. The collector is not contacted until the first
+// interval, so a collector that is not running is not an error here, but is counted in the errors metric.
+func (module *OTLPReporter) Start() error {
+	module.Log.Info("starting")
+
+	module.startTime = time.Now()
+	module.running.Add(1)
+	go module.mainLoop()
+	return nil
+}
+
+// Stop stops sending metrics. It waits for an export that is in progress to finish, which is limited by otlp.timeout.
+func (module *OTLPReporter) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	return nil
+}
+
+func (module *OTLPReporter) mainLoop() {
+	defer module.running.Done()
+
+	ticker := time.NewTicker(module.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			module.report()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+// report sends all of the metrics for one interval
+func (module *OTLPReporter) report() {
+	defer helpers.UpdateMetricTime(module.reportTime, time.Now())
+
+	exported := module.getRegistryMetrics()
+	if module.consumerStatus {
+		exported = append(exported, module.getConsumerMetrics(helpers.GetConsumerStatuses(module.App, module.filter, false, module.quitChannel))...)
+	}
+
+	sendStart := time.Now()
+	err := module.client.ExportMetrics("github.com/linkedin/Burrow/reporter", module.startTime, exported)
+	if err = module.delivery.record(sendStart, err); err != nil {
+		module.errorCount.Inc(1)
+		module.Log.Warn("failed to export metrics", zap.Error(err))
+		helpers.PublishModuleError("reporter."+module.name, "", "failed to export metrics: "+err.Error())
+		return
+	}
+	module.sentCount.Inc(int64(len(exported)))
+}
+
+// getRegistryMetrics returns each of Burrow's own metrics
+func (module *OTLPReporter) getRegistryMetrics() []*helpers.OTLPMetric {
+	registered := getRegisteredMetrics()
+	exported := make([]*helpers.OTLPMetric, 0, len(registered))
+	for _, entry := range registered {
+		exportedMetric := &helpers.OTLPMetric{Name: module.prefix + entry.name}
+		switch metric := entry.metric.(type) {
+		case metrics.Counter:
+			exportedMetric.Type = helpers.OTLPCounter
+			exportedMetric.Points = []helpers.OTLPDataPoint{{Value: metric.Count()}}
+		case metrics.Gauge:
+			exportedMetric.Type = helpers.OTLPGauge
+			exportedMetric.Points = []helpers.OTLPDataPoint{{Value: metric.Value()}}
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			percentiles := snapshot.Percentiles([]float64{0.5, 0.95, 0.99})
+			exportedMetric.Type = helpers.OTLPSummary
+			exportedMetric.Points = []helpers.OTLPDataPoint{{
+				Count:     snapshot.Count(),
+				Sum:       float64(snapshot.Sum()),
+				Quantiles: map[float64]float64{0.5: percentiles[0], 0.95: percentiles[1], 0.99: percentiles[2]},
+			}}
+			if strings.HasSuffix(entry.name, "-time") {
+				exportedMetric.Unit = "us"
+			}
+		default:
+			continue
+		}
+		exported = append(exported, exportedMetric)
+	}
+	return exported
+}
+
+// getConsumerMetrics returns the gauges for the status of the consumer groups, with a data point for each group
+func (module *OTLPReporter) getConsumerMetrics(statuses []*protocol.ConsumerGroupStatus) []*helpers.OTLPMetric {
+	status := &helpers.OTLPMetric{Name: module.prefix + "consumer.status", Type: helpers.OTLPGauge}
+	totalLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.total_lag", Type: helpers.OTLPGauge}
+	maxLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.max_lag", Type: helpers.OTLPGauge}
+	maxTimeLag := &helpers.OTLPMetric{Name: module.prefix + "consumer.max_time_lag", Type: helpers.OTLPGauge, Unit: "ms"}
+	partitions := &helpers.OTLPMetric{Name: module.prefix + "consumer.partitions", Type: helpers.OTLPGauge}
+
+	for _, groupStatus := range statuses {
+		attributes := map[string]interface{}{
+			"kafka.cluster":        groupStatus.Cluster,
+			"kafka.consumer_group": groupStatus.Group,
+		}
+		for label, value := range groupStatus.ClusterLabels {
+			attributes[label] = value
+		}
+
+		var groupMaxLag uint64
+		if groupStatus.Maxlag != nil {
+			groupMaxLag = groupStatus.Maxlag.CurrentLag
+		}
+		status.Points = append(status.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupStatus.Status)})
+		totalLag.Points = append(totalLag.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupStatus.TotalLag)})
+		maxLag.Points = append(maxLag.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupMaxLag)})
+		maxTimeLag.Points = append(maxTimeLag.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: groupStatus.MaxTimeLag})
+		partitions.Points = append(partitions.Points, helpers.OTLPDataPoint{Attributes: attributes, Value: int64(groupStatus.TotalPartitions)})
+	}
+	return []*helpers.OTLPMetric{status, totalLag, maxLag, maxTimeLag, partitions}
+}
@@ -0,0 +1,149 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureOTLPModule(endpoint string) *OTLPReporter {
+	module := OTLPReporter{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:           zap.NewNop(),
+			StorageChannel:   make(chan *protocol.StorageRequest),
+			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("otlp.endpoint", endpoint)
+	viper.Set("otlp.resource-attributes.deployment.environment", "test")
+	viper.Set("reporter.test.class-name", "otlp")
+	viper.Set("reporter.test.group-denylist", "^dropped$")
+	return &module
+}
+
+// findOTLPMetric returns the metric with the name from an OTLP metrics request that was decoded as JSON
+func findOTLPMetric(request map[string]interface{}, name string) map[string]interface{} {
+	resourceMetrics := request["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	scopeMetrics := resourceMetrics["scopeMetrics"].([]interface{})[0].(map[string]interface{})
+	for _, metric := range scopeMetrics["metrics"].([]interface{}) {
+		if metric.(map[string]interface{})["name"] == name {
+			return metric.(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+func TestOTLPReporter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(OTLPReporter))
+}
+
+func TestOTLPReporter_Configure_NoEndpoint(t *testing.T) {
+	module := fixtureOTLPModule("")
+	assert.Panics(t, func() { module.Configure("test", "reporter.test") }, "Expected panic without an otlp.endpoint")
+}
+
+func TestOTLPReporter_Report(t *testing.T) {
+	requests := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path, "Expected metrics to be sent to /v1/metrics")
+		var request map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request), "Expected a valid OTLP request")
+		requests <- request
+	}))
+	defer collector.Close()
+
+	module := fixtureOTLPModule(collector.URL)
+	module.Configure("test", "reporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	helpers.GetMetricCounter("test.otlp-counter").Inc(5)
+	helpers.GetMetricHistogram("test.otlp-time").Update(100)
+	go respondToStatusRequests(module.App)
+	module.report()
+	request := <-requests
+
+	resource := request["resourceMetrics"].([]interface{})[0].(map[string]interface{})["resource"].(map[string]interface{})
+	assert.Contains(t, resource["attributes"], map[string]interface{}{
+		"key":   "deployment.environment",
+		"value": map[string]interface{}{"stringValue": "test"},
+	}, "Expected the configured resource attribute")
+
+	counter := findOTLPMetric(request, "burrow.test.otlp-counter")
+	assert.NotNil(t, counter, "Expected the counter")
+	assert.Equal(t, true, counter["sum"].(map[string]interface{})["isMonotonic"], "Expected the counter to be a monotonic sum")
+	assert.Equal(t, "5", counter["sum"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})["asInt"],
+		"Expected the counter value")
+
+	histogram := findOTLPMetric(request, "burrow.test.otlp-time")
+	assert.NotNil(t, histogram, "Expected the histogram")
+	assert.Equal(t, "us", histogram["unit"], "Expected durations in microseconds")
+	assert.NotNil(t, histogram["summary"], "Expected the histogram to be a summary")
+
+	totalLag := findOTLPMetric(request, "burrow.consumer.total_lag")
+	assert.NotNil(t, totalLag, "Expected the consumer total lag")
+	points := totalLag["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	assert.Len(t, points, 1, "Expected one data point, as the other group is denied")
+	assert.Equal(t, "2500", points[0].(map[string]interface{})["asInt"], "Expected the total lag of the group")
+	assert.Contains(t, points[0].(map[string]interface{})["attributes"], map[string]interface{}{
+		"key":   "kafka.consumer_group",
+		"value": map[string]interface{}{"stringValue": "testgroup"},
+	}, "Expected the group as an attribute")
+}
+
+func TestOTLPReporter_Report_DeliveryMetrics(t *testing.T) {
+	status := http.StatusOK
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer collector.Close()
+
+	module := fixtureOTLPModule(collector.URL)
+	viper.Set("reporter.test.consumer-status", false)
+	module.Configure("test", "reporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	attempts := helpers.GetMetricCounter("reporter.test.attempts").Count()
+	successes := helpers.GetMetricCounter("reporter.test.successes").Count()
+	failures := helpers.GetMetricCounter("reporter.test.failures").Count()
+	unavailable := helpers.GetMetricCounter("reporter.test.failures.503").Count()
+
+	module.report()
+	status = http.StatusServiceUnavailable
+	module.report()
+
+	assert.Equal(t, attempts+2, helpers.GetMetricCounter("reporter.test.attempts").Count(), "Expected two attempts")
+	assert.Equal(t, successes+1, helpers.GetMetricCounter("reporter.test.successes").Count(), "Expected one success")
+	assert.Equal(t, failures+1, helpers.GetMetricCounter("reporter.test.failures").Count(), "Expected one failure")
+	assert.Equal(t, unavailable+1, helpers.GetMetricCounter("reporter.test.failures.503").Count(),
+		"Expected the failure to be counted by status")
+
+	collector.Close()
+	module.report()
+	assert.Equal(t, failures+2, helpers.GetMetricCounter("reporter.test.failures").Count(),
+		"Expected a failure when the collector cannot be reached")
+	assert.Equal(t, unavailable+1, helpers.GetMetricCounter("reporter.test.failures.503").Count(),
+		"Expected no status for a collector that cannot be reached")
+}
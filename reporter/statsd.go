@@ -0,0 +1,322 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// StatsdReporter is a reporter module that sends metrics to a StatsD agent over UDP (or a unix socket) each interval.
+// Burrow's own metrics are sent with their registry names: counters are sent as StatsD counters (the change since the
+// last interval), gauges as gauges, and histograms as a set of gauges for the count, mean, max, and percentiles.
+//
+// If consumer-status is enabled (the default), every consumer group accepted by the group-allowlist and group-denylist
+// is evaluated each interval, and its status, total lag, max lag, and time lag are sent as gauges. With the dogstatsd
+// format (the default), the cluster and group are sent as tags, along with the cluster labels and the configured tags.
+// Plain StatsD has no tags, so with the statsd format the cluster and group are put in the metric name instead, and
+// the tags are not sent.
+type StatsdReporter struct {
+	// App is a pointer to the application context. This stores the channels to the storage and evaluator subsystems
+	App *protocol.ApplicationContext
+
+	// Log is a logger that has been configured for this module to use. Normally, this means it has been set up with
+	// fields that are appropriate to identify this coordinator
+	Log *zap.Logger
+
+	name           string
+	network        string
+	address        string
+	prefix         string
+	tags           []string
+	dogstatsd      bool
+	interval       time.Duration
+	consumerStatus bool
+	maxPacketSize  int
+	filter         *helpers.ConsumerFilter
+
+	conn        net.Conn
+	lastCounts  map[string]int64
+	quitChannel chan struct{}
+	running     sync.WaitGroup
+
+	reportTime metrics.Histogram
+	sentCount  metrics.Counter
+	errorCount metrics.Counter
+	delivery   *deliveryMetrics
+}
+
+// Configure validates the configuration for the module. The address defaults to localhost:8125 over UDP, the interval
+// to 60 seconds, and the prefix of every metric name to "burrow.". If there are any problems, it is expected that this
+// func will panic with a descriptive error message, as configuration failures are not recoverable errors.
+func (module *StatsdReporter) Configure(name, configRoot string) {
+	module.Log.Info("configuring")
+
+	module.name = name
+	module.quitChannel = make(chan struct{})
+	module.running = sync.WaitGroup{}
+	module.lastCounts = make(map[string]int64)
+	module.reportTime = helpers.GetMetricHistogram(configRoot + ".report-time")
+	module.sentCount = helpers.GetMetricCounter(configRoot + ".sent")
+	module.errorCount = helpers.GetMetricCounter(configRoot + ".errors")
+	module.delivery = newDeliveryMetrics(configRoot)
+
+	viper.SetDefault(configRoot+".network", "udp")
+	viper.SetDefault(configRoot+".address", "localhost:8125")
+	viper.SetDefault(configRoot+".prefix", "burrow.")
+	viper.SetDefault(configRoot+".format", "dogstatsd")
+	viper.SetDefault(configRoot+".interval", 60)
+	viper.SetDefault(configRoot+".consumer-status", true)
+	viper.SetDefault(configRoot+".max-packet-size", 1432)
+
+	module.network = viper.GetString(configRoot + ".network")
+	module.address = viper.GetString(configRoot + ".address")
+	switch module.network {
+	case "udp":
+		if !helpers.ValidateHostPort(module.address, true) {
+			panic("Reporter '" + name + "' has an invalid address (must be host:port)")
+		}
+	case "unixgram":
+		if module.address == "" {
+			panic("Reporter '" + name + "' has no socket address")
+		}
+	default:
+		panic("Reporter '" + name + "' has an unknown network (must be udp or unixgram)")
+	}
+
+	switch viper.GetString(configRoot + ".format") {
+	case "dogstatsd":
+		module.dogstatsd = true
+	case "statsd":
+		module.dogstatsd = false
+	default:
+		panic("Reporter '" + name + "' has an unknown format (must be dogstatsd or statsd)")
+	}
+
+	module.prefix = viper.GetString(configRoot + ".prefix")
+	module.tags = viper.GetStringSlice(configRoot + ".tags")
+	for _, tag := range module.tags {
+		if (tag == "") || strings.ContainsAny(tag, ",|#\n") {
+			panic("Reporter '" + name + "' has an invalid tag: " + tag)
+		}
+	}
+
+	module.interval = time.Duration(viper.GetInt(configRoot+".interval")) * time.Second
+	module.maxPacketSize = viper.GetInt(configRoot + ".max-packet-size")
+	if (module.interval <= 0) || (module.maxPacketSize < 512) {
+		panic("Reporter '" + name + "' has an invalid interval or max-packet-size (must be at least 512)")
+	}
+
+	module.consumerStatus = viper.GetBool(configRoot + ".consumer-status")
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		panic("Reporter '" + name + "' has an invalid filter: " + err.Error())
+	}
+	module.filter = filter
+}
+
+// Start opens the socket to the agent, and starts the goroutine that sends the metrics each interval. As UDP is
+// connectionless, an agent that is not running is not an error here, but is counted in the errors metric when metrics
+// are sent.
+func (module *StatsdReporter) Start() error {
+	module.Log.Info("starting")
+
+	conn, err := net.Dial(module.network, module.address)
+	if err != nil {
+		module.Log.Error("failed to open socket", zap.String("address", module.address), zap.Error(err))
+		return err
+	}
+	module.conn = conn
+
+	module.running.Add(1)
+	go module.mainLoop()
+	return nil
+}
+
+// Stop stops sending metrics, and closes the socket
+func (module *StatsdReporter) Stop() error {
+	module.Log.Info("stopping")
+
+	close(module.quitChannel)
+	module.running.Wait()
+	return module.conn.Close()
+}
+
+func (module *StatsdReporter) mainLoop() {
+	defer module.running.Done()
+
+	ticker := time.NewTicker(module.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			module.report()
+		case <-module.quitChannel:
+			return
+		}
+	}
+}
+
+// report sends all of the metrics for one interval
+func (module *StatsdReporter) report() {
+	defer helpers.UpdateMetricTime(module.reportTime, time.Now())
+
+	lines := module.getMetricLines()
+	if module.consumerStatus {
+		for _, status := range helpers.GetConsumerStatuses(module.App, module.filter, false, module.quitChannel) {
+			lines = append(lines, module.getConsumerLines(status)...)
+		}
+	}
+	module.send(lines)
+}
+
+// getMetricLines returns a line for each of Burrow's own metrics
+func (module *StatsdReporter) getMetricLines() []string {
+	registered := getRegisteredMetrics()
+	lines := make([]string, 0, len(registered))
+	for _, entry := range registered {
+		name := entry.name
+		switch metric := entry.metric.(type) {
+		case metrics.Counter:
+			count := metric.Count()
+			lines = append(lines, module.formatLine(name, count-module.lastCounts[name], "c", nil))
+			module.lastCounts[name] = count
+		case metrics.Gauge:
+			lines = append(lines, module.formatLine(name, metric.Value(), "g", nil))
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			percentiles := snapshot.Percentiles([]float64{0.5, 0.95, 0.99})
+			lines = append(lines,
+				module.formatLine(name+".count", snapshot.Count(), "g", nil),
+				module.formatLine(name+".mean", int64(snapshot.Mean()), "g", nil),
+				module.formatLine(name+".max", snapshot.Max(), "g", nil),
+				module.formatLine(name+".p50", int64(percentiles[0]), "g", nil),
+				module.formatLine(name+".p95", int64(percentiles[1]), "g", nil),
+				module.formatLine(name+".p99", int64(percentiles[2]), "g", nil),
+			)
+		}
+	}
+	return lines
+}
+
+// getConsumerLines returns the gauges for the status of a consumer group
+func (module *StatsdReporter) getConsumerLines(status *protocol.ConsumerGroupStatus) []string {
+	name := "consumer."
+	var tags []string
+	if module.dogstatsd {
+		tags = []string{"cluster:" + sanitizeTag(status.Cluster), "consumer_group:" + sanitizeTag(status.Group)}
+		for label, value := range status.ClusterLabels {
+			tags = append(tags, sanitizeTag(label)+":"+sanitizeTag(value))
+		}
+		sort.Strings(tags[2:])
+	} else {
+		name += sanitizeNamePart(status.Cluster) + "." + sanitizeNamePart(status.Group) + "."
+	}
+
+	var maxLag uint64
+	if status.Maxlag != nil {
+		maxLag = status.Maxlag.CurrentLag
+	}
+	return []string{
+		module.formatLine(name+"status", int64(status.Status), "g", tags),
+		module.formatLine(name+"total_lag", int64(status.TotalLag), "g", tags),
+		module.formatLine(name+"max_lag", int64(maxLag), "g", tags),
+		module.formatLine(name+"max_time_lag", status.MaxTimeLag, "g", tags),
+		module.formatLine(name+"partitions", int64(status.TotalPartitions), "g", tags),
+	}
+}
+
+// formatLine returns a single metric in the StatsD line format, with the tags (and the configured tags) added if the
+// format is dogstatsd
+func (module *StatsdReporter) formatLine(name string, value int64, metricType string, tags []string) string {
+	line := sanitizeName(module.prefix+name) + ":" + strconv.FormatInt(value, 10) + "|" + metricType
+	if module.dogstatsd && ((len(tags) > 0) || (len(module.tags) > 0)) {
+		allTags := make([]string, 0, len(tags)+len(module.tags))
+		allTags = append(append(allTags, tags...), module.tags...)
+		line += "|#" + strings.Join(allTags, ",")
+	}
+	return line
+}
+
+// send writes the lines to the socket, with as many lines in each packet as fit in max-packet-size
+func (module *StatsdReporter) send(lines []string) {
+	var packet bytes.Buffer
+	failed := 0
+	flush := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		sendStart := time.Now()
+		_, err := module.conn.Write(packet.Bytes())
+		if err = module.delivery.record(sendStart, err); err != nil {
+			failed++
+			module.errorCount.Inc(1)
+		}
+		packet.Reset()
+	}
+
+	for _, line := range lines {
+		if (packet.Len() > 0) && (packet.Len()+1+len(line) > module.maxPacketSize) {
+			flush()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	flush()
+
+	module.sentCount.Inc(int64(len(lines)))
+	if failed > 0 {
+		module.Log.Warn("failed to send metrics", zap.Int("packets", failed))
+		helpers.PublishModuleError("reporter."+module.name, "", "failed to send metrics")
+	}
+}
+
+// sanitizeName replaces the characters that have a meaning in the StatsD line format with underscores
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// sanitizeNamePart sanitizes a part of a metric name, such as a group name, which must also not have dots in it, as
+// they would split it into more parts
+func sanitizeNamePart(part string) string {
+	return strings.Replace(sanitizeName(part), ".", "_", -1)
+}
+
+// sanitizeTag replaces the characters that have a meaning in a DogStatsD tag with underscores
+func sanitizeTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '|', '#', ',', ':', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, tag)
+}
@@ -0,0 +1,159 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package reporter
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/helpers"
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fixtureStatsdModule(address string) *StatsdReporter {
+	module := StatsdReporter{
+		Log: zap.NewNop(),
+		App: &protocol.ApplicationContext{
+			Logger:           zap.NewNop(),
+			StorageChannel:   make(chan *protocol.StorageRequest),
+			EvaluatorChannel: make(chan *protocol.EvaluatorRequest),
+		},
+	}
+
+	viper.Reset()
+	viper.Set("reporter.test.class-name", "statsd")
+	viper.Set("reporter.test.address", address)
+	viper.Set("reporter.test.tags", []string{"env:test"})
+	viper.Set("reporter.test.group-denylist", "^dropped$")
+	return &module
+}
+
+// respondToStatusRequests answers the requests that helpers.GetConsumerStatuses makes, for one cluster with two groups
+func respondToStatusRequests(app *protocol.ApplicationContext) {
+	request := <-app.StorageChannel
+	request.Reply <- []string{"testcluster"}
+	request = <-app.StorageChannel
+	request.Reply <- []string{"testgroup", "dropped"}
+
+	evaluatorRequest := <-app.EvaluatorChannel
+	evaluatorRequest.Reply <- &protocol.ConsumerGroupStatus{
+		Cluster:         evaluatorRequest.Cluster,
+		Group:           evaluatorRequest.Group,
+		Status:          protocol.StatusWarning,
+		TotalLag:        2500,
+		TotalPartitions: 3,
+		Maxlag:          &protocol.PartitionStatus{CurrentLag: 2000},
+		ClusterLabels:   map[string]string{"region": "us-west"},
+	}
+}
+
+func readPackets(t *testing.T, listener net.PacketConn) []string {
+	lines := make([]string, 0)
+	buffer := make([]byte, 65536)
+	for {
+		listener.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			return lines
+		}
+		assert.LessOrEqual(t, n, 1432, "Expected packets to be no larger than max-packet-size")
+		lines = append(lines, strings.Split(string(buffer[:n]), "\n")...)
+	}
+}
+
+func TestStatsdReporter_ImplementsModule(t *testing.T) {
+	assert.Implements(t, (*protocol.Module)(nil), new(StatsdReporter))
+}
+
+func TestStatsdReporter_Configure(t *testing.T) {
+	module := fixtureStatsdModule("localhost:8125")
+	module.Configure("test", "reporter.test")
+	assert.True(t, module.dogstatsd, "Expected the dogstatsd format by default")
+	assert.Equal(t, 60*time.Second, module.interval, "Expected the default interval")
+}
+
+func TestStatsdReporter_Configure_BadAddress(t *testing.T) {
+	module := fixtureStatsdModule("localhost")
+	assert.Panics(t, func() { module.Configure("test", "reporter.test") }, "Expected panic for an address without a port")
+}
+
+func TestStatsdReporter_Configure_BadFormat(t *testing.T) {
+	module := fixtureStatsdModule("localhost:8125")
+	viper.Set("reporter.test.format", "graphite")
+	assert.Panics(t, func() { module.Configure("test", "reporter.test") }, "Expected panic for an unknown format")
+}
+
+func TestStatsdReporter_Report(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err, "Expected to listen for packets")
+	defer listener.Close()
+
+	module := fixtureStatsdModule(listener.LocalAddr().String())
+	module.Configure("test", "reporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	attempts := helpers.GetMetricCounter("reporter.test.attempts").Count()
+	successes := helpers.GetMetricCounter("reporter.test.successes").Count()
+
+	helpers.GetMetricCounter("test.reporter-counter").Inc(5)
+	go respondToStatusRequests(module.App)
+	module.report()
+
+	lines := readPackets(t, listener)
+	sent := helpers.GetMetricCounter("reporter.test.attempts").Count() - attempts
+	assert.True(t, sent > 0, "Expected each packet to be counted as an attempt")
+	assert.Equal(t, successes+sent, helpers.GetMetricCounter("reporter.test.successes").Count(),
+		"Expected each packet to be counted as a success")
+	assert.Contains(t, lines, "burrow.test.reporter-counter:5|c|#env:test", "Expected the counter with the configured tag")
+	assert.Contains(t, lines, "burrow.consumer.total_lag:2500|g|#cluster:testcluster,consumer_group:testgroup,region:us-west,env:test",
+		"Expected the total lag of the group")
+	assert.Contains(t, lines, "burrow.consumer.status:2|g|#cluster:testcluster,consumer_group:testgroup,region:us-west,env:test",
+		"Expected the status of the group")
+	assert.Contains(t, lines, "burrow.consumer.max_lag:2000|g|#cluster:testcluster,consumer_group:testgroup,region:us-west,env:test",
+		"Expected the max lag of the group")
+	for _, line := range lines {
+		assert.NotContains(t, line, "dropped", "Expected the denied group to not be reported")
+	}
+
+	// Counters are sent as the change since the last report
+	module.consumerStatus = false
+	module.report()
+	assert.Contains(t, readPackets(t, listener), "burrow.test.reporter-counter:0|c|#env:test", "Expected no change in the counter")
+}
+
+func TestStatsdReporter_Report_StatsdFormat(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err, "Expected to listen for packets")
+	defer listener.Close()
+
+	module := fixtureStatsdModule(listener.LocalAddr().String())
+	viper.Set("reporter.test.format", "statsd")
+	module.Configure("test", "reporter.test")
+	assert.NoError(t, module.Start(), "Expected module to start")
+	defer module.Stop()
+
+	go respondToStatusRequests(module.App)
+	module.report()
+
+	lines := readPackets(t, listener)
+	assert.Contains(t, lines, "burrow.consumer.testcluster.testgroup.total_lag:2500|g", "Expected the cluster and group in the name")
+}
+
+func TestSanitizeNamePart(t *testing.T) {
+	assert.Equal(t, "my_group_v1_a_b", sanitizeNamePart("my.group:v1|a b"), "Expected special characters to be replaced")
+}
@@ -0,0 +1,42 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+package storage
+
+import (
+	"github.com/linkedin/Burrow/helpers"
+)
+
+func init() {
+	helpers.RegisterConfigKeys("storage.*", "",
+		helpers.ConfigKey{Name: "class-name", Type: helpers.ConfigTypeString},
+	)
+
+	inmemoryKeys := []helpers.ConfigKey{
+		{Name: "workers", Type: helpers.ConfigTypeInteger, Default: 20},
+		{Name: "min-workers", Type: helpers.ConfigTypeInteger},
+		{Name: "max-workers", Type: helpers.ConfigTypeInteger},
+		{Name: "scale-interval", Type: helpers.ConfigTypeInteger, Default: 10},
+		{Name: "scale-down-delay", Type: helpers.ConfigTypeInteger, Default: 300},
+		{Name: "queue-depth", Type: helpers.ConfigTypeInteger, Default: 1},
+		{Name: "overflow-policy", Type: helpers.ConfigTypeString, Default: "block"},
+		{Name: "intervals", Type: helpers.ConfigTypeInteger, Default: 10},
+		{Name: "min-distance", Type: helpers.ConfigTypeInteger},
+		{Name: "expire-group", Type: helpers.ConfigTypeInteger, Default: 604800},
+		{Name: "commit-rate-window", Type: helpers.ConfigTypeInteger, Default: 5},
+		{Name: "rewind-history", Type: helpers.ConfigTypeInteger, Default: 10},
+		{Name: "rewind-threshold", Type: helpers.ConfigTypeInteger, Default: 1},
+		{Name: "interpolate-broker-offsets", Type: helpers.ConfigTypeBoolean},
+		{Name: "snapshot-file", Type: helpers.ConfigTypeString},
+		{Name: "snapshot-max-age", Type: helpers.ConfigTypeInteger, Default: 3600},
+	}
+	inmemoryKeys = append(inmemoryKeys, helpers.ConsumerFilterConfigKeys...)
+	inmemoryKeys = append(inmemoryKeys, helpers.RemovedGroupFilterConfigKeys...)
+	helpers.RegisterConfigKeys("storage.*", "inmemory", inmemoryKeys...)
+}
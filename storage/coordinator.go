@@ -14,15 +14,19 @@
 //
 // Modules
 //
-// Currently, only one module is provided:
+// Currently, the following modules are provided:
 //
 // * inmemory - Store all information in a set of in-memory maps
+//
+// * plugin - Run a storage module that is built as a separate executable (see the plugin package). This is left out
+// of builds with the no_plugin tag
 package storage
 
 import (
 	"errors"
 	"sync"
 
+	"github.com/rcrowley/go-metrics"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
@@ -71,6 +75,18 @@ func getModuleForClass(app *protocol.ApplicationContext, moduleName, className s
 			),
 		}
 	default:
+		if factory := helpers.GetModuleClass("storage", className); factory != nil {
+			module, ok := factory(app, app.Logger.With(
+				zap.String("type", "module"),
+				zap.String("coordinator", "storage"),
+				zap.String("class", className),
+				zap.String("name", moduleName),
+			)).(Module)
+			if !ok {
+				panic("Storage className " + className + " is not a storage module")
+			}
+			return module
+		}
 		panic("Unknown storage className provided: " + className)
 	}
 }
@@ -110,9 +126,10 @@ func (sc *Coordinator) Configure() {
 // Start calls the storage module's underlying Start func. If the module Start returns an error, this func stops
 // immediately and returns that error to the caller.
 //
-// We also start a request forwarder goroutine. This listens to the StorageChannel that is provided in the application
-// context that all modules receive, and forwards those requests to the storage modules. At the present time, the
-// storage subsystem only supports one module, so this is a simple "accept and forward".
+// We also start a request forwarder goroutine. This listens to the storage channels that are provided in the
+// application context that all modules receive, and forwards those requests to the storage modules, in order of
+// priority. At the present time, the storage subsystem only supports one module, so this is a simple "accept and
+// forward".
 func (sc *Coordinator) Start() error {
 	sc.Log.Info("starting")
 
@@ -141,6 +158,18 @@ func (sc *Coordinator) Stop() error {
 	return nil
 }
 
+// Health returns the health of each storage module. The coordinator is DEGRADED if the channel for storage requests is
+// full, as the senders are waiting.
+func (sc *Coordinator) Health() *protocol.CoordinatorHealth {
+	health := helpers.GetCoordinatorHealth("storage", sc.modules)
+	storageChannel := sc.App.StorageChannel
+	if (health.State == protocol.HealthOK) && (cap(storageChannel) > 0) && (len(storageChannel) == cap(storageChannel)) {
+		health.State = protocol.HealthDegraded
+		health.Reason = "storage request channel is full"
+	}
+	return health
+}
+
 func (sc *Coordinator) mainLoop() {
 	sc.running.Add(1)
 	defer sc.running.Done()
@@ -151,15 +180,78 @@ func (sc *Coordinator) mainLoop() {
 		channel = module.(Module).GetCommunicationChannel()
 	}
 
+	// Count the requests of each type, and how many are waiting to be forwarded. Each time the module is not ready for a
+	// request, the forwarder has to wait, and senders start to wait once the channel for their priority is full
+	requestCounts := make(map[protocol.StorageRequestConstant]metrics.Counter)
+	storageChannel := sc.App.StorageChannel
+	helpers.RegisterMetricGaugeFunc("storage.channel-depth", func() int64 {
+		return int64(len(storageChannel))
+	})
+	evaluationChannel := sc.App.GetStorageChannel(protocol.StoragePriorityEvaluation)
+	helpers.RegisterMetricGaugeFunc("storage.evaluation-channel-depth", func() int64 {
+		return int64(len(evaluationChannel))
+	})
+	apiChannel := sc.App.GetStorageChannel(protocol.StoragePriorityAPI)
+	helpers.RegisterMetricGaugeFunc("storage.api-channel-depth", func() int64 {
+		return int64(len(apiChannel))
+	})
+	helpers.GetMetricGauge("storage.channel-capacity").Update(int64(cap(storageChannel)))
+	blockedCount := helpers.GetMetricCounter("storage.channel-blocked")
+
 	for {
+		request := sc.nextRequest()
+		if request == nil {
+			return
+		}
+		counter, ok := requestCounts[request.RequestType]
+		if !ok {
+			counter = helpers.GetMetricCounter("storage.requests." + request.RequestType.String())
+			requestCounts[request.RequestType] = counter
+		}
+		counter.Inc(1)
+
+		// Yes, this forwarder is silly. However, in the future we want to support multiple storage modules
+		// concurrently. However, that will require implementing a router that properly handles sets and
+		// fetches and makes sure only 1 module responds to fetches
 		select {
-		case request := <-sc.App.StorageChannel:
-			// Yes, this forwarder is silly. However, in the future we want to support multiple storage modules
-			// concurrently. However, that will require implementing a router that properly handles sets and
-			// fetches and makes sure only 1 module responds to fetches
+		case channel <- request:
+		default:
+			blockedCount.Inc(1)
 			channel <- request
-		case <-sc.quitChannel:
-			return
 		}
 	}
 }
+
+// nextRequest returns the next request to forward to the storage module. Requests are taken from the StorageChannel
+// first, then the StorageEvaluationChannel, and then the StorageAPIChannel, so a request is only taken from a channel
+// if none of the channels before it has a request waiting. If none of them do, it waits for a request on any of them.
+// Nil is returned if the coordinator is stopped.
+func (sc *Coordinator) nextRequest() *protocol.StorageRequest {
+	storageChannel := sc.App.StorageChannel
+	evaluationChannel := sc.App.GetStorageChannel(protocol.StoragePriorityEvaluation)
+	apiChannel := sc.App.GetStorageChannel(protocol.StoragePriorityAPI)
+
+	select {
+	case request := <-storageChannel:
+		return request
+	default:
+	}
+	select {
+	case request := <-storageChannel:
+		return request
+	case request := <-evaluationChannel:
+		return request
+	default:
+	}
+
+	select {
+	case request := <-storageChannel:
+		return request
+	case request := <-evaluationChannel:
+		return request
+	case request := <-apiChannel:
+		return request
+	case <-sc.quitChannel:
+		return nil
+	}
+}
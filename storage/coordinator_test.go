@@ -20,6 +20,7 @@ import (
 
 	"time"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -67,6 +68,22 @@ func TestCoordinator_Configure_TwoModules(t *testing.T) {
 	assert.Panics(t, coordinator.Configure, "Expected panic")
 }
 
+func TestCoordinator_Health(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.App.StorageChannel = make(chan *protocol.StorageRequest, 1)
+	coordinator.Configure()
+
+	health := coordinator.Health()
+	assert.Equal(t, protocol.HealthOK, health.State, "Expected the coordinator to be OK")
+	assert.Len(t, health.Modules, 1, "Expected the health of the module")
+	assert.Equal(t, "test", health.Modules[0].Name, "Expected the health of the test module")
+
+	coordinator.App.StorageChannel <- &protocol.StorageRequest{}
+	health = coordinator.Health()
+	assert.Equal(t, protocol.HealthDegraded, health.State, "Expected the coordinator to be DEGRADED with a full channel")
+	assert.Equal(t, "storage request channel is full", health.Reason, "Expected the reason to be the full channel")
+}
+
 func TestCoordinator_Start(t *testing.T) {
 	coordinator := fixtureCoordinator()
 	coordinator.Configure()
@@ -92,7 +109,80 @@ func TestCoordinator_Start(t *testing.T) {
 	coordinator.Stop()
 }
 
+func TestCoordinator_RoundTripTime(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.Configure()
+	coordinator.Start()
+	histogram := helpers.GetMetricHistogram("storage.round-trip-time.StorageFetchClusters")
+	count := histogram.Count()
+
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+		Submitted:   time.Now(),
+	}
+	coordinator.App.StorageChannel <- request
+	response := <-request.Reply
+	assert.Equal(t, []string{"testcluster"}, response, "Expected the reply to be sent")
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+
+	// The time is recorded once the reply has been taken, so it may not be there yet
+	for i := 0; (i < 100) && (histogram.Count() == count); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, count+1, histogram.Count(), "Expected the round trip time to be recorded")
+
+	// A request that was not given a submit time is not recorded
+	request = &protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusters,
+		Reply:       make(chan interface{}),
+	}
+	coordinator.App.StorageChannel <- request
+	for range request.Reply {
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, count+1, histogram.Count(), "Expected no round trip time without a submit time")
+
+	coordinator.Stop()
+}
+
 func TestCoordinator_MultipleRequests(t *testing.T) {
 	coordinator := CoordinatorWithOffsets()
 	coordinator.Stop()
 }
+
+func TestCoordinator_nextRequest(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.App.StorageChannel = make(chan *protocol.StorageRequest, 1)
+	coordinator.App.StorageEvaluationChannel = make(chan *protocol.StorageRequest, 1)
+	coordinator.App.StorageAPIChannel = make(chan *protocol.StorageRequest, 1)
+	coordinator.quitChannel = make(chan struct{})
+
+	// Requests are taken in order of priority, rather than the order they were sent in
+	coordinator.App.StorageAPIChannel <- &protocol.StorageRequest{RequestType: protocol.StorageFetchTopics}
+	coordinator.App.StorageEvaluationChannel <- &protocol.StorageRequest{RequestType: protocol.StorageFetchConsumer}
+	coordinator.App.StorageChannel <- &protocol.StorageRequest{RequestType: protocol.StorageSetConsumerOffset}
+
+	for _, requestType := range []protocol.StorageRequestConstant{protocol.StorageSetConsumerOffset, protocol.StorageFetchConsumer, protocol.StorageFetchTopics} {
+		request := coordinator.nextRequest()
+		assert.NotNil(t, request, "Expected a request")
+		assert.Equalf(t, requestType, request.RequestType, "Expected request of type %v, not %v", requestType, request.RequestType)
+	}
+
+	// Once there are no requests, it waits for the coordinator to be stopped
+	close(coordinator.quitChannel)
+	assert.Nil(t, coordinator.nextRequest(), "Expected no request once stopped")
+}
+
+func TestCoordinator_nextRequest_SharedChannel(t *testing.T) {
+	coordinator := fixtureCoordinator()
+	coordinator.App.StorageChannel = make(chan *protocol.StorageRequest, 2)
+	coordinator.quitChannel = make(chan struct{})
+
+	// Without their own channels, evaluation and API requests are sent over the StorageChannel
+	coordinator.App.GetStorageChannel(protocol.StoragePriorityAPI) <- &protocol.StorageRequest{RequestType: protocol.StorageFetchTopics}
+	coordinator.App.GetStorageChannel(protocol.StoragePriorityEvaluation) <- &protocol.StorageRequest{RequestType: protocol.StorageFetchConsumer}
+	assert.Equal(t, protocol.StorageFetchTopics, coordinator.nextRequest().RequestType, "Expected the requests in the order they were sent")
+	assert.Equal(t, protocol.StorageFetchConsumer, coordinator.nextRequest().RequestType, "Expected the requests in the order they were sent")
+}
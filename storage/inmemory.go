@@ -11,23 +11,27 @@
 package storage
 
 import (
+	"container/heap"
 	"container/ring"
 	"math/rand"
-	"regexp"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OneOfOne/xxhash"
+	"github.com/rcrowley/go-metrics"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
 // InMemoryStorage is a storage module that maintains the entire data set in memory in a series of maps. It has a
 // configurable number of worker goroutines to service requests, and for requests that are group-specific, the group
 // and cluster name are used to hash the request to a consistent worker. This assures that requests for a group are
-// processed in order.
+// processed in order. The settings for the module are described in the README.
 type InMemoryStorage struct {
 	// App is a pointer to the application context. This stores the channel to the storage subsystem
 	App *protocol.ApplicationContext
@@ -37,21 +41,57 @@ type InMemoryStorage struct {
 	Log *zap.Logger
 
 	name        string
+	configRoot  string
 	intervals   int
 	numWorkers  int
 	expireGroup int64
 	minDistance int64
 	queueDepth  int
 
+	// What to do with a request when the queue of the worker it is for is full, and the counts of the requests that
+	// had to wait or were dropped
+	overflowPolicy string
+	blockedCount   metrics.Counter
+	droppedCount   metrics.Counter
+
+	// The bounds on the number of workers. Each scale-interval, the workers are doubled if a request had to wait for
+	// a full worker queue (or was dropped) since the last check, and halved if none has for scale-down-delay
+	minWorkers     int
+	maxWorkers     int
+	scaleInterval  time.Duration
+	scaleDownDelay time.Duration
+	lastPressure   int64
+	lastBusy       time.Time
+	workerCount    metrics.Gauge
+	scaleUpCount   metrics.Counter
+	scaleDownCount metrics.Counter
+	scaleTime      metrics.Histogram
+
+	rewindThreshold  int64
+	rewindHistory    int
+	commitRateWindow int
+
+	interpolateBrokerOffsets bool
+
+	snapshotFile   string
+	snapshotMaxAge int64
+	snapshotLock   sync.Mutex
+
 	requestChannel chan *protocol.StorageRequest
 	workersRunning sync.WaitGroup
 	mainRunning    sync.WaitGroup
 	offsets        map[string]clusterOffsets
-	groupAllowlist *regexp.Regexp
-	groupDenylist  *regexp.Regexp
+	filter         *helpers.ConsumerFilter
 	workers        []chan *protocol.StorageRequest
+
+	// Only the main loop changes the workers, but the gauges and the cluster activity request read them
+	workersLock sync.RWMutex
 }
 
+// workerBarrier is a request that is only sent to the workers by the main loop. The worker closes the reply channel
+// when it gets to it, which means that every request queued for the worker before it has been handled.
+const workerBarrier protocol.StorageRequestConstant = -1
+
 type brokerOffset struct {
 	Offset    int64
 	Timestamp int64
@@ -61,6 +101,84 @@ type consumerPartition struct {
 	offsets  *ring.Ring
 	owner    string
 	clientID string
+	commits  *commitCounter
+
+	// The offsets in the ring as a []*protocol.ConsumerOffset, which is shared by every fetch until the ring changes.
+	// The offsets in the ring are never modified once they are stored, so the slice can be used without the lock.
+	snapshot atomic.Value
+}
+
+// noOffsets is returned for a partition that has no offsets ring
+var noOffsets = make([]*protocol.ConsumerOffset, 0)
+
+// getOffsets returns the offsets in the ring, oldest first, with nil for slots that have not been filled. The slice is
+// only built the first time it is asked for after the ring changes, rather than copying the ring for each fetch, and
+// must not be modified. The group lock must be held, for read or write: as a writer cannot hold the lock at the same
+// time, readers that build the slice at once store the same offsets.
+func (partition *consumerPartition) getOffsets() []*protocol.ConsumerOffset {
+	if partition.offsets == nil {
+		return noOffsets
+	}
+	if offsets, _ := partition.snapshot.Load().([]*protocol.ConsumerOffset); offsets != nil {
+		return offsets
+	}
+
+	offsets := make([]*protocol.ConsumerOffset, partition.offsets.Len())
+	ringPtr := partition.offsets
+	for i := range offsets {
+		offsets[i], _ = ringPtr.Value.(*protocol.ConsumerOffset)
+		ringPtr = ringPtr.Next()
+	}
+	partition.snapshot.Store(offsets)
+	return offsets
+}
+
+// offsetsChanged discards the slice returned by getOffsets, so that the next fetch builds it again. The group write
+// lock must be held
+func (partition *consumerPartition) offsetsChanged() {
+	partition.snapshot.Store([]*protocol.ConsumerOffset(nil))
+}
+
+// commitCounter counts the commits for a partition in one-minute buckets, by the minute of the commit timestamp. Each
+// bucket is reused for a later minute once it falls out of the window
+type commitCounter struct {
+	minutes []int64
+	counts  []uint64
+}
+
+func newCommitCounter(window int) *commitCounter {
+	return &commitCounter{
+		minutes: make([]int64, window),
+		counts:  make([]uint64, window),
+	}
+}
+
+// add counts a commit with the given timestamp, in milliseconds. Commits that are older than the minute that their
+// bucket is counting are dropped
+func (counter *commitCounter) add(timestamp int64) {
+	minute := timestamp / 60000
+	bucket := int(minute % int64(len(counter.minutes)))
+	if minute > counter.minutes[bucket] {
+		counter.minutes[bucket] = minute
+		counter.counts[bucket] = 0
+	} else if minute < counter.minutes[bucket] {
+		return
+	}
+	counter.counts[bucket]++
+}
+
+// rate returns the average commits per minute over the window that ends at the given time, in milliseconds
+func (counter *commitCounter) rate(now int64) float64 {
+	window := int64(len(counter.minutes))
+	current := now / 60000
+
+	var total uint64
+	for i, minute := range counter.minutes {
+		if (minute <= current) && (current-minute < window) {
+			total += counter.counts[i]
+		}
+	}
+	return float64(total) / float64(window)
 }
 
 type consumerGroup struct {
@@ -68,18 +186,95 @@ type consumerGroup struct {
 	lock       *sync.RWMutex
 	topics     map[string][]*consumerPartition
 	lastCommit int64
+
+	// The current members and state of the group, from the most recent group metadata
+	members []*protocol.ConsumerGroupMember
+	state   string
+
+	// The most recent offset rewinds for the group, oldest first
+	rewinds []*protocol.OffsetRewind
+
+	// The most recent topics that were removed from the group because they were deleted or recreated, oldest first
+	topicRemovals []*protocol.TopicRemoval
+}
+
+// maxTopicRemovals is the number of topic removals that are kept for each group
+const maxTopicRemovals = 10
+
+// logStartOffset is the log start offset for a partition, along with the first log start offset that was seen and each
+// time that it advanced after that, oldest first. These are used to estimate how fast retention is removing messages
+// from the partition
+type logStartOffset struct {
+	offset  int64
+	samples []*brokerOffset
+}
+
+// maxLogStartSamples is the number of log start offset samples that are kept for each partition
+const maxLogStartSamples = 10
+
+// clusterMetadata holds the information for a cluster that is replaced as a whole at each metadata refresh, so that it
+// can be replaced without replacing the clusterOffsets in the offsets map
+type clusterMetadata struct {
+	replication *protocol.ClusterReplication
+	brokers     *protocol.ClusterBrokers
+	leaderChurn *protocol.ClusterLeaderChurn
+}
+
+// clusterActivity is the time (in milliseconds) of the latest broker offset and consumer offset commit that have been
+// received for a cluster. The fields must only be accessed atomically
+type clusterActivity struct {
+	lastBrokerOffset   int64
+	lastConsumerOffset int64
+}
+
+// updateLatest sets the timestamp to the new one, if the new one is later. It is safe to call concurrently
+func updateLatest(timestamp *int64, latest int64) {
+	for {
+		current := atomic.LoadInt64(timestamp)
+		if (latest <= current) || atomic.CompareAndSwapInt64(timestamp, current, latest) {
+			return
+		}
+	}
 }
 
 type clusterOffsets struct {
 	broker   map[string][]*ring.Ring
 	consumer map[string]*consumerGroup
 
+	// Log start offsets for each topic, indexed by partition. These are protected by brokerLock
+	logStart map[string][]*logStartOffset
+
+	// Configuration for each topic, as fetched by the cluster module. These are protected by brokerLock
+	topicConfig map[string]map[string]string
+
+	// Replication status and brokers from the last metadata refresh. These are protected by brokerLock
+	metadata *clusterMetadata
+
+	// When the latest offsets were received for the cluster. This does not need a lock
+	activity *clusterActivity
+
+	// The latest commit timestamp (in milliseconds) seen for each topic that consumer groups commit offsets for, as an
+	// int64. This is a sync.Map, so it does not need a lock
+	consumedTopics *sync.Map
+
+	// Map of expected consumer groups to the time (in milliseconds) they were registered
+	expected map[string]int64
+
+	// Kafka Connect connectors, by the name of the module they were fetched from and then by the connector name
+	connectors map[string]map[string]*protocol.Connector
+
 	// This lock is used when modifying broker topics or offsets
 	brokerLock *sync.RWMutex
 
 	// This lock is used when modifying the overall consumer list
 	// It does not need to be held for modifying an individual group
 	consumerLock *sync.RWMutex
+
+	// This lock is used when modifying the expected consumer groups
+	expectedLock *sync.RWMutex
+
+	// This lock is used when modifying the connectors
+	connectorLock *sync.RWMutex
 }
 
 // Represents the destination of adding an offset into
@@ -113,22 +308,52 @@ func (destination *offsetRingDestination) isShift() bool {
 
 // Configure validates the configuration for the module, creates a channel to receive requests on, and sets up the
 // storage map. If no expiration time for groups is set, a default value of 7 days is used. If no interval count is
-// set, a default of 10 intervals is used. If no worker count is set, a default of 20 workers is used.
+// set, a default of 10 intervals is used. If no worker count is set, a default of 20 workers is used. The number of
+// workers only changes if min-workers or max-workers is set to a different number. By default, any commit that moves
+// backwards is recorded as a rewind, and the last 10 rewinds are kept for each group. Setting rewind-history to zero
+// disables recording rewinds.
 func (module *InMemoryStorage) Configure(name, configRoot string) {
 	module.Log.Info("configuring")
 
 	module.name = name
+	module.configRoot = configRoot
 
 	// Set defaults for configs if needed
 	viper.SetDefault(configRoot+".intervals", 10)
 	viper.SetDefault(configRoot+".expire-group", 604800)
 	viper.SetDefault(configRoot+".workers", 20)
 	viper.SetDefault(configRoot+".queue-depth", 1)
+	viper.SetDefault(configRoot+".overflow-policy", helpers.OverflowBlock)
+	viper.SetDefault(configRoot+".rewind-threshold", 1)
+	viper.SetDefault(configRoot+".rewind-history", 10)
+	viper.SetDefault(configRoot+".commit-rate-window", 5)
+	viper.SetDefault(configRoot+".snapshot-max-age", 3600)
+	viper.SetDefault(configRoot+".min-workers", viper.GetInt(configRoot+".workers"))
+	viper.SetDefault(configRoot+".max-workers", viper.GetInt(configRoot+".workers"))
+	viper.SetDefault(configRoot+".scale-interval", 10)
+	viper.SetDefault(configRoot+".scale-down-delay", 300)
 	module.intervals = viper.GetInt(configRoot + ".intervals")
 	module.expireGroup = viper.GetInt64(configRoot + ".expire-group")
 	module.numWorkers = viper.GetInt(configRoot + ".workers")
 	module.minDistance = viper.GetInt64(configRoot + ".min-distance")
 	module.queueDepth = viper.GetInt(configRoot + ".queue-depth")
+	module.overflowPolicy = viper.GetString(configRoot + ".overflow-policy")
+	module.blockedCount = helpers.GetMetricCounter(configRoot + ".queue-blocked")
+	module.droppedCount = helpers.GetMetricCounter(configRoot + ".queue-dropped")
+	module.rewindThreshold = viper.GetInt64(configRoot + ".rewind-threshold")
+	module.rewindHistory = viper.GetInt(configRoot + ".rewind-history")
+	module.commitRateWindow = viper.GetInt(configRoot + ".commit-rate-window")
+	module.interpolateBrokerOffsets = viper.GetBool(configRoot + ".interpolate-broker-offsets")
+	module.snapshotFile = viper.GetString(configRoot + ".snapshot-file")
+	module.snapshotMaxAge = viper.GetInt64(configRoot + ".snapshot-max-age")
+	module.minWorkers = viper.GetInt(configRoot + ".min-workers")
+	module.maxWorkers = viper.GetInt(configRoot + ".max-workers")
+	module.scaleInterval = time.Duration(viper.GetInt(configRoot+".scale-interval")) * time.Second
+	module.scaleDownDelay = time.Duration(viper.GetInt(configRoot+".scale-down-delay")) * time.Second
+	module.workerCount = helpers.GetMetricGauge(configRoot + ".workers")
+	module.scaleUpCount = helpers.GetMetricCounter(configRoot + ".workers-scaled-up")
+	module.scaleDownCount = helpers.GetMetricCounter(configRoot + ".workers-scaled-down")
+	module.scaleTime = helpers.GetMetricHistogram(configRoot + ".scale-time")
 
 	module.requestChannel = make(chan *protocol.StorageRequest, module.queueDepth)
 	module.workersRunning = sync.WaitGroup{}
@@ -140,26 +365,48 @@ func (module *InMemoryStorage) Configure(name, configRoot string) {
 		module.Log.Panic("Please change configurations to allowlist and denylist")
 		panic("Please change configurations to allowlist and denylist")
 	}
+	if module.rewindThreshold < 1 {
+		module.Log.Panic("rewind-threshold must be at least 1")
+		panic("rewind-threshold must be at least 1")
+	}
+	if module.rewindHistory < 0 {
+		module.Log.Panic("rewind-history must not be negative")
+		panic("rewind-history must not be negative")
+	}
+	if module.commitRateWindow < 1 {
+		module.Log.Panic("commit-rate-window must be at least 1")
+		panic("commit-rate-window must be at least 1")
+	}
+	if !helpers.IsOverflowPolicy(module.overflowPolicy) {
+		module.Log.Panic("overflow-policy must be block or drop")
+		panic("overflow-policy must be block or drop")
+	}
+	if module.minWorkers < 1 {
+		module.Log.Panic("min-workers must be at least 1")
+		panic("min-workers must be at least 1")
+	}
+	if module.maxWorkers < module.minWorkers {
+		module.Log.Panic("max-workers must not be less than min-workers")
+		panic("max-workers must not be less than min-workers")
+	}
+	if (module.maxWorkers > module.minWorkers) && ((module.scaleInterval <= 0) || (module.scaleDownDelay < 0)) {
+		module.Log.Panic("scale-interval must be more than zero, and scale-down-delay must not be negative")
+		panic("scale-interval must be more than zero, and scale-down-delay must not be negative")
+	}
 
-	allowlist := viper.GetString(configRoot + ".group-allowlist")
-	if allowlist != "" {
-		re, err := regexp.Compile(allowlist)
-		if err != nil {
-			module.Log.Panic("Failed to compile group allowlist")
-			panic(err)
-		}
-		module.groupAllowlist = re
+	// The workers start at the configured count, within the bounds
+	if module.numWorkers < module.minWorkers {
+		module.numWorkers = module.minWorkers
+	} else if module.numWorkers > module.maxWorkers {
+		module.numWorkers = module.maxWorkers
 	}
 
-	denylist := viper.GetString(configRoot + ".group-denylist")
-	if denylist != "" {
-		re, err := regexp.Compile(denylist)
-		if err != nil {
-			module.Log.Panic("Failed to compile group denylist")
-			panic(err)
-		}
-		module.groupDenylist = re
+	filter, err := helpers.NewConsumerFilter(configRoot)
+	if err != nil {
+		module.Log.Panic("Failed to compile group or topic filter")
+		panic(err)
 	}
+	module.filter = filter
 }
 
 // GetCommunicationChannel returns the RequestChannel that has been setup for this module.
@@ -167,22 +414,48 @@ func (module *InMemoryStorage) GetCommunicationChannel() chan *protocol.StorageR
 	return module.requestChannel
 }
 
-// Start sets up the rest of the storage map for each configured cluster. It then starts the configured number of
-// worker routines to handle requests. Finally, it starts a main loop which will receive requests and hash them to the
-// correct worker.
+// Start sets up the rest of the storage map for each configured cluster, including any expected consumer groups that
+// are listed in the cluster configuration or were saved in the dynamic config file, and restores the offsets from the
+// snapshot file. It then starts the configured number of worker routines to handle requests. Finally, it starts a main
+// loop which will receive requests and hash them to the correct worker.
 func (module *InMemoryStorage) Start() error {
 	module.Log.Info("starting")
 
+	startTime := time.Now().Unix() * 1000
 	for cluster := range viper.GetStringMap("cluster") {
 		module.
 			offsets[cluster] = clusterOffsets{
-			broker:       make(map[string][]*ring.Ring),
-			consumer:     make(map[string]*consumerGroup),
-			brokerLock:   &sync.RWMutex{},
-			consumerLock: &sync.RWMutex{},
+			broker:         make(map[string][]*ring.Ring),
+			logStart:       make(map[string][]*logStartOffset),
+			topicConfig:    make(map[string]map[string]string),
+			metadata:       &clusterMetadata{},
+			activity:       &clusterActivity{},
+			consumedTopics: &sync.Map{},
+			consumer:       make(map[string]*consumerGroup),
+			expected:       make(map[string]int64),
+			connectors:     make(map[string]map[string]*protocol.Connector),
+			brokerLock:     &sync.RWMutex{},
+			consumerLock:   &sync.RWMutex{},
+			expectedLock:   &sync.RWMutex{},
+			connectorLock:  &sync.RWMutex{},
+		}
+
+		for _, group := range viper.GetStringSlice("cluster." + cluster + ".expected-groups") {
+			module.offsets[cluster].expected[group] = startTime
+		}
+
+		// Apply the changes to the expected groups that were made through the HTTP server before a restart
+		for group, expected := range helpers.GetDynamicExpectedGroups(cluster) {
+			if expected {
+				module.offsets[cluster].expected[group] = startTime
+			} else {
+				delete(module.offsets[cluster].expected, group)
+			}
 		}
 	}
 
+	module.restoreSnapshot()
+
 	// Start the appropriate number of workers, with a channel for each
 	module.workers = make([]chan *protocol.StorageRequest, module.numWorkers)
 	for i := 0; i < module.numWorkers; i++ {
@@ -191,13 +464,23 @@ func (module *InMemoryStorage) Start() error {
 		go module.requestWorker(i, module.workers[i])
 	}
 
+	// The queue depth is the number of requests that are waiting, either to be hashed to a worker or in a worker queue
+	helpers.RegisterMetricGaugeFunc(module.configRoot+".queue-depth", func() int64 {
+		depth, _ := module.getQueueDepth()
+		return int64(depth)
+	})
+	module.updateWorkerMetrics()
+	module.lastPressure = module.blockedCount.Count() + module.droppedCount.Count()
+	module.lastBusy = time.Now()
+
 	module.mainRunning.Add(1)
 	go module.mainLoop()
 	return nil
 }
 
 // Stop closes the incoming request channel, which will close the main loop. It then closes each of the worker
-// channels, to close the workers, and waits for all goroutines to exit before returning.
+// channels, to close the workers, and waits for all goroutines to exit before returning. Once the workers have exited,
+// the snapshot file is written, if one is configured.
 func (module *InMemoryStorage) Stop() error {
 	module.Log.Info("stopping")
 
@@ -209,6 +492,9 @@ func (module *InMemoryStorage) Stop() error {
 	}
 	module.workersRunning.Wait()
 
+	if err := module.writeSnapshot(); err != nil {
+		module.Log.Error("failed to write snapshot", zap.String("file", module.snapshotFile), zap.Error(err))
+	}
 	return nil
 }
 
@@ -217,23 +503,70 @@ func (module *InMemoryStorage) requestWorker(workerNum int, requestChannel chan
 
 	// Using a map for the request types avoids a bit of complexity below
 	var requestTypeMap = map[protocol.StorageRequestConstant]func(*protocol.StorageRequest, *zap.Logger){
-		protocol.StorageSetBrokerOffset:        module.addBrokerOffset,
-		protocol.StorageSetConsumerOffset:      module.addConsumerOffset,
-		protocol.StorageSetConsumerOwner:       module.addConsumerOwner,
-		protocol.StorageSetDeleteTopic:         module.deleteTopic,
-		protocol.StorageSetDeleteGroup:         module.deleteGroup,
-		protocol.StorageFetchClusters:          module.fetchClusterList,
-		protocol.StorageFetchConsumers:         module.fetchConsumerList,
-		protocol.StorageFetchTopics:            module.fetchTopicList,
-		protocol.StorageFetchConsumer:          module.fetchConsumer,
-		protocol.StorageFetchTopic:             module.fetchTopic,
-		protocol.StorageClearConsumerOwners:    module.clearConsumerOwners,
-		protocol.StorageFetchConsumersForTopic: module.fetchConsumersForTopicList,
+		protocol.StorageSetBrokerOffset:            module.addBrokerOffset,
+		protocol.StorageSetBrokerLogStartOffset:    module.addBrokerLogStartOffset,
+		protocol.StorageSetConsumerOffset:          module.addConsumerOffset,
+		protocol.StorageSetConsumerOwner:           module.addConsumerOwner,
+		protocol.StorageSetDeleteTopic:             module.deleteTopic,
+		protocol.StorageSetDeleteGroup:             module.deleteGroup,
+		protocol.StorageFetchClusters:              module.fetchClusterList,
+		protocol.StorageFetchConsumers:             module.fetchConsumerList,
+		protocol.StorageFetchTopics:                module.fetchTopicList,
+		protocol.StorageFetchConsumer:              module.fetchConsumer,
+		protocol.StorageFetchTopic:                 module.fetchTopic,
+		protocol.StorageClearConsumerOwners:        module.clearConsumerOwners,
+		protocol.StorageFetchConsumersForTopic:     module.fetchConsumersForTopicList,
+		protocol.StorageSetExpectedGroup:           module.addExpectedGroup,
+		protocol.StorageSetDeleteExpectedGroup:     module.deleteExpectedGroup,
+		protocol.StorageFetchExpectedGroups:        module.fetchExpectedGroups,
+		protocol.StorageSetConsumerMembers:         module.setConsumerMembers,
+		protocol.StorageFetchConsumerMembers:       module.fetchConsumerMembers,
+		protocol.StorageFetchConsumerRewinds:       module.fetchConsumerRewinds,
+		protocol.StorageFetchConsumerGroupState:    module.fetchConsumerGroupState,
+		protocol.StorageSetConnectors:              module.setConnectors,
+		protocol.StorageFetchConnectors:            module.fetchConnectors,
+		protocol.StorageSetTopicConfig:             module.setTopicConfig,
+		protocol.StorageFetchTopicConfig:           module.fetchTopicConfig,
+		protocol.StorageSetClusterReplication:      module.setClusterReplication,
+		protocol.StorageFetchClusterReplication:    module.fetchClusterReplication,
+		protocol.StorageSetClusterBrokers:          module.setClusterBrokers,
+		protocol.StorageFetchClusterBrokers:        module.fetchClusterBrokers,
+		protocol.StorageFetchConsumerTopicRemovals: module.fetchConsumerTopicRemovals,
+		protocol.StorageSetClusterLeaderChurn:      module.setClusterLeaderChurn,
+		protocol.StorageFetchClusterLeaderChurn:    module.fetchClusterLeaderChurn,
+		protocol.StorageFetchTopicProduceRates:     module.fetchTopicProduceRates,
+		protocol.StorageFetchClusterActivity:       module.fetchClusterActivity,
+		protocol.StorageFetchConsumedTopics:        module.fetchConsumedTopics,
+		protocol.StorageWriteSnapshot:              module.writeSnapshotRequest,
+		protocol.StorageStreamConsumers:            module.streamConsumerList,
+		protocol.StorageStreamTopics:               module.streamTopicList,
+	}
+
+	// The time that each type of request takes to handle, once a worker has it
+	requestTimes := make(map[protocol.StorageRequestConstant]metrics.Histogram, len(requestTypeMap))
+	for requestType := range requestTypeMap {
+		requestTimes[requestType] = helpers.GetMetricHistogram(module.configRoot + ".request-time." + requestType.String())
 	}
 
 	workerLogger := module.Log.With(zap.Int("worker", workerNum))
 	for r := range requestChannel {
+		if r.RequestType == workerBarrier {
+			close(r.Reply)
+			continue
+		}
 		if requestFunc, ok := requestTypeMap[r.RequestType]; ok {
+			// If the request is being traced, the time it takes to handle is recorded in a span under the sender's span
+			span := helpers.StartSpan("storage "+r.RequestType.String(), helpers.SpanKindInternal, r.Trace)
+			span.SetAttribute("storage.module", module.name)
+			span.SetAttribute("kafka.cluster", r.Cluster)
+			if r.Group != "" {
+				span.SetAttribute("kafka.consumer_group", r.Group)
+			}
+			if r.Topic != "" {
+				span.SetAttribute("kafka.topic", r.Topic)
+			}
+
+			start := time.Now()
 			requestFunc(r, workerLogger.With(
 				zap.String("cluster", r.Cluster),
 				zap.String("consumer", r.Group),
@@ -245,30 +578,172 @@ func (module *InMemoryStorage) requestWorker(workerNum int, requestChannel chan
 				zap.String("owner", r.Owner),
 				zap.String("client_id", r.ClientID),
 				zap.String("request", r.RequestType.String())))
+			helpers.UpdateMetricTime(requestTimes[r.RequestType], start)
+			recordRoundTrip(r)
+			span.End()
 		}
+		protocol.ReleaseStorageRequest(r)
 	}
 }
 
 func (module *InMemoryStorage) mainLoop() {
 	defer module.mainRunning.Done()
 
-	for r := range module.requestChannel {
-		switch r.RequestType {
-		case protocol.StorageSetBrokerOffset, protocol.StorageSetDeleteTopic, protocol.StorageFetchClusters, protocol.StorageFetchConsumers, protocol.StorageFetchTopics, protocol.StorageFetchTopic, protocol.StorageFetchConsumersForTopic:
-			// Send to any worker
-			module.workers[int(rand.Int31n(int32(module.numWorkers)))] <- r
-		case protocol.StorageSetConsumerOffset, protocol.StorageSetConsumerOwner, protocol.StorageSetDeleteGroup, protocol.StorageClearConsumerOwners, protocol.StorageFetchConsumer:
-			// Hash to a consistent worker
-			module.workers[int(xxhash.ChecksumString64(r.Cluster+r.Group)%uint64(module.numWorkers))] <- r
-		default:
-			module.Log.Error("unknown storage request type",
-				zap.Int("request_type", int(r.RequestType)),
-			)
-			if r.Reply != nil {
-				close(r.Reply)
+	// The number of workers is only checked if it is allowed to change
+	var scaleTicker <-chan time.Time
+	if module.maxWorkers > module.minWorkers {
+		ticker := time.NewTicker(module.scaleInterval)
+		defer ticker.Stop()
+		scaleTicker = ticker.C
+	}
+
+	for {
+		select {
+		case r, ok := <-module.requestChannel:
+			if !ok {
+				return
+			}
+			module.routeRequest(r)
+		case <-scaleTicker:
+			module.autoscaleWorkers(time.Now())
+		}
+	}
+}
+
+// routeRequest sends the request to a worker. Requests for a group are always hashed to the same worker, so that they
+// are handled in the order they were received.
+func (module *InMemoryStorage) routeRequest(r *protocol.StorageRequest) {
+	switch r.RequestType {
+	case protocol.StorageSetBrokerOffset, protocol.StorageSetBrokerLogStartOffset, protocol.StorageSetDeleteTopic, protocol.StorageFetchClusters, protocol.StorageFetchConsumers, protocol.StorageFetchTopics, protocol.StorageFetchTopic, protocol.StorageFetchConsumersForTopic, protocol.StorageFetchExpectedGroups, protocol.StorageFetchConnectors, protocol.StorageSetTopicConfig, protocol.StorageFetchTopicConfig, protocol.StorageSetClusterReplication, protocol.StorageFetchClusterReplication, protocol.StorageSetClusterBrokers, protocol.StorageFetchClusterBrokers, protocol.StorageSetClusterLeaderChurn, protocol.StorageFetchClusterLeaderChurn, protocol.StorageFetchTopicProduceRates, protocol.StorageFetchClusterActivity, protocol.StorageFetchConsumedTopics, protocol.StorageWriteSnapshot, protocol.StorageStreamConsumers, protocol.StorageStreamTopics:
+		// Send to any worker
+		module.sendToWorker(int(rand.Int31n(int32(module.numWorkers))), r)
+	case protocol.StorageSetConsumerOffset, protocol.StorageSetConsumerOwner, protocol.StorageSetDeleteGroup, protocol.StorageClearConsumerOwners, protocol.StorageFetchConsumer, protocol.StorageSetExpectedGroup, protocol.StorageSetDeleteExpectedGroup, protocol.StorageSetConsumerMembers, protocol.StorageFetchConsumerMembers, protocol.StorageFetchConsumerRewinds, protocol.StorageFetchConsumerGroupState, protocol.StorageSetConnectors, protocol.StorageFetchConsumerTopicRemovals:
+		// Hash to a consistent worker
+		module.sendToWorker(int(xxhash.ChecksumString64(r.Cluster+r.Group)%uint64(module.numWorkers)), r)
+	default:
+		module.Log.Error("unknown storage request type",
+			zap.Int("request_type", int(r.RequestType)),
+		)
+		helpers.GetMetricCounter(module.configRoot + ".errors").Inc(1)
+		helpers.PublishModuleError(module.configRoot, r.Cluster, "unknown storage request type "+r.RequestType.String())
+		if r.Reply != nil {
+			close(r.Reply)
+		}
+	}
+}
+
+// autoscaleWorkers doubles the number of workers, up to max-workers, if any request has had to wait for a full worker
+// queue, or was dropped, since it was last called. If none has for scale-down-delay, the number of workers is halved,
+// down to min-workers. It must only be called from the main loop.
+func (module *InMemoryStorage) autoscaleWorkers(now time.Time) {
+	pressure := module.blockedCount.Count() + module.droppedCount.Count()
+	if pressure > module.lastPressure {
+		module.lastPressure = pressure
+		module.lastBusy = now
+		if module.numWorkers < module.maxWorkers {
+			count := module.numWorkers * 2
+			if count > module.maxWorkers {
+				count = module.maxWorkers
 			}
+			module.resizeWorkers(count)
+			module.scaleUpCount.Inc(1)
+		}
+		return
+	}
+
+	if (module.numWorkers > module.minWorkers) && (now.Sub(module.lastBusy) >= module.scaleDownDelay) {
+		count := module.numWorkers / 2
+		if count < module.minWorkers {
+			count = module.minWorkers
 		}
+		module.resizeWorkers(count)
+		module.scaleDownCount.Inc(1)
+
+		// Wait for another idle period before scaling down again
+		module.lastBusy = now
+	}
+}
+
+// resizeWorkers changes the number of workers to count. Changing the number of workers changes the worker that most
+// groups are hashed to, so first this waits for every worker to handle the requests that are already in its queue.
+// Otherwise, a request for a group could be handled by its new worker before an earlier one is handled by the old
+// worker. No requests are routed while this waits. It must only be called from the main loop.
+func (module *InMemoryStorage) resizeWorkers(count int) {
+	startTime := time.Now()
+	previous := module.numWorkers
+
+	barriers := make([]chan interface{}, previous)
+	for i := range barriers {
+		barriers[i] = make(chan interface{})
+		module.workers[i] <- &protocol.StorageRequest{RequestType: workerBarrier, Reply: barriers[i]}
+	}
+	for _, barrier := range barriers {
+		<-barrier
 	}
+
+	workers := make([]chan *protocol.StorageRequest, count)
+	copy(workers, module.workers)
+	for i := previous; i < count; i++ {
+		workers[i] = make(chan *protocol.StorageRequest, module.queueDepth)
+		module.workersRunning.Add(1)
+		go module.requestWorker(i, workers[i])
+	}
+	for i := count; i < previous; i++ {
+		close(module.workers[i])
+	}
+
+	module.workersLock.Lock()
+	module.workers = workers
+	module.numWorkers = count
+	module.workersLock.Unlock()
+
+	module.updateWorkerMetrics()
+	helpers.UpdateMetricTime(module.scaleTime, startTime)
+	module.Log.Info("changed number of workers",
+		zap.Int("previous", previous),
+		zap.Int("workers", count),
+		zap.Duration("elapsed", time.Since(startTime)),
+	)
+}
+
+// getQueueDepth returns the number of requests that are waiting, either to be hashed to a worker or in a worker queue,
+// and the number that can be queued before requests have to wait
+func (module *InMemoryStorage) getQueueDepth() (int, int) {
+	module.workersLock.RLock()
+	defer module.workersLock.RUnlock()
+
+	depth := len(module.requestChannel)
+	capacity := cap(module.requestChannel)
+	for _, worker := range module.workers {
+		depth += len(worker)
+		capacity += cap(worker)
+	}
+	return depth, capacity
+}
+
+// updateWorkerMetrics sets the gauges for the number of workers, and the queue capacity, which changes with it
+func (module *InMemoryStorage) updateWorkerMetrics() {
+	_, capacity := module.getQueueDepth()
+	module.workerCount.Update(int64(len(module.workers)))
+	helpers.GetMetricGauge(module.configRoot + ".queue-capacity").Update(int64(capacity))
+}
+
+// sendToWorker queues the request for the worker. If the worker's queue is full, the request is dropped if the
+// overflow policy is drop and nothing is waiting for a reply to it. Otherwise, this waits until the worker has space.
+func (module *InMemoryStorage) sendToWorker(worker int, r *protocol.StorageRequest) {
+	select {
+	case module.workers[worker] <- r:
+		return
+	default:
+	}
+
+	if (module.overflowPolicy == helpers.OverflowDrop) && (r.Reply == nil) {
+		module.droppedCount.Inc(1)
+		protocol.ReleaseStorageRequest(r)
+		return
+	}
+	module.blockedCount.Inc(1)
+	module.workers[worker] <- r
 }
 
 func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
@@ -278,6 +753,7 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 		requestLogger.Warn("unknown cluster")
 		return
 	}
+	updateLatest(&clusterMap.activity.lastBrokerOffset, request.Timestamp)
 
 	clusterMap.brokerLock.Lock()
 	defer clusterMap.brokerLock.Unlock()
@@ -289,6 +765,15 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 	}
 	if request.TopicPartitionCount >= int32(len(topicList)) {
 		// The partition count has increased. Append enough extra partitions, with offset rings, to our slice
+		if ok && (request.TopicPartitionCount > int32(len(topicList))) {
+			helpers.PublishEvent(&protocol.Event{
+				Type:               protocol.EventPartitionCountChange,
+				Cluster:            request.Cluster,
+				Topic:              request.Topic,
+				PreviousPartitions: len(topicList),
+				Partitions:         int(request.TopicPartitionCount),
+			})
+		}
 		for i := int32(len(topicList)); i < request.TopicPartitionCount; i++ {
 			topicList = append(topicList, ring.New(module.intervals))
 		}
@@ -314,6 +799,103 @@ func (module *InMemoryStorage) addBrokerOffset(request *protocol.StorageRequest,
 	clusterMap.broker[request.Topic] = topicList
 }
 
+func (module *InMemoryStorage) addBrokerLogStartOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+	if request.Partition < 0 {
+		requestLogger.Warn("negative partition")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	defer clusterMap.brokerLock.Unlock()
+
+	partitions := clusterMap.logStart[request.Topic]
+	for int32(len(partitions)) <= request.Partition {
+		partitions = append(partitions, nil)
+	}
+	partition := partitions[request.Partition]
+	if partition == nil {
+		partition = &logStartOffset{}
+		partitions[request.Partition] = partition
+	}
+	clusterMap.logStart[request.Topic] = partitions
+
+	// Only keep a sample when the log start offset moves. If it moved backwards, the partition was reset, and the
+	// older samples no longer say anything about how fast retention is removing messages
+	if (len(partition.samples) == 0) || (request.Offset != partition.offset) {
+		if request.Offset < partition.offset {
+			partition.samples = nil
+		}
+		partition.samples = append(partition.samples, &brokerOffset{Offset: request.Offset, Timestamp: request.Timestamp})
+		if len(partition.samples) > maxLogStartSamples {
+			partition.samples = partition.samples[len(partition.samples)-maxLogStartSamples:]
+		}
+	}
+	partition.offset = request.Offset
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) setTopicConfig(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	clusterMap.topicConfig[request.Topic] = request.TopicConfig
+	clusterMap.brokerLock.Unlock()
+
+	requestLogger.Debug("ok", zap.Int("configs", len(request.TopicConfig)))
+}
+
+func (module *InMemoryStorage) setClusterReplication(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	clusterMap.metadata.replication = request.Replication
+	clusterMap.brokerLock.Unlock()
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) setClusterBrokers(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	clusterMap.metadata.brokers = request.Brokers
+	clusterMap.brokerLock.Unlock()
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) setClusterLeaderChurn(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.Lock()
+	clusterMap.metadata.leaderChurn = request.LeaderChurn
+	clusterMap.brokerLock.Unlock()
+
+	requestLogger.Debug("ok")
+}
+
 func (module *InMemoryStorage) getBrokerOffset(clusterMap *clusterOffsets, topic string, partition int32, requestLogger *zap.Logger) (int64, int32) {
 	clusterMap.brokerLock.RLock()
 	defer clusterMap.brokerLock.RUnlock()
@@ -342,6 +924,66 @@ func (module *InMemoryStorage) getBrokerOffset(clusterMap *clusterOffsets, topic
 	return topicPartitionList[partition].Value.(*brokerOffset).Offset, int32(len(topicPartitionList))
 }
 
+// getInterpolatedBrokerOffset returns the estimated broker offset for the partition at the timestamp. The broker offset
+// for the partition must exist, which is checked by getBrokerOffset
+func (module *InMemoryStorage) getInterpolatedBrokerOffset(clusterMap *clusterOffsets, topic string, partition int32, timestamp int64) int64 {
+	clusterMap.brokerLock.RLock()
+	defer clusterMap.brokerLock.RUnlock()
+
+	return interpolateBrokerOffset(getBrokerOffsetList(clusterMap.broker[topic][partition]), timestamp)
+}
+
+// getBrokerOffsetList returns the broker offsets in the ring, oldest first. The ring must point at the most recent
+// entry. The brokerLock for the cluster must be held
+func getBrokerOffsetList(offsetRing *ring.Ring) []*brokerOffset {
+	brokerOffsets := make([]*brokerOffset, 0, offsetRing.Len())
+	offsetRing.Next().Do(func(item interface{}) {
+		if item != nil {
+			brokerOffsets = append(brokerOffsets, item.(*brokerOffset))
+		}
+	})
+	return brokerOffsets
+}
+
+// interpolateBrokerOffset returns the estimated broker end offset at the timestamp. The broker offsets must be in
+// order, oldest first. Between two stored broker offsets, the offset is interpolated linearly. After the most recent
+// one, it is extrapolated from the rate between the two most recent, for at most the time between them. Before the
+// oldest one, or if there is not enough history, the most recent broker offset is returned.
+func interpolateBrokerOffset(brokerOffsets []*brokerOffset, timestamp int64) int64 {
+	if len(brokerOffsets) == 0 {
+		return 0
+	}
+	latest := brokerOffsets[len(brokerOffsets)-1]
+	if (len(brokerOffsets) < 2) || (timestamp < brokerOffsets[0].Timestamp) {
+		return latest.Offset
+	}
+
+	if timestamp >= latest.Timestamp {
+		previous := brokerOffsets[len(brokerOffsets)-2]
+		interval := latest.Timestamp - previous.Timestamp
+		if (interval <= 0) || (latest.Offset <= previous.Offset) {
+			return latest.Offset
+		}
+		elapsed := timestamp - latest.Timestamp
+		if elapsed > interval {
+			elapsed = interval
+		}
+		return latest.Offset + (latest.Offset-previous.Offset)*elapsed/interval
+	}
+
+	for i, after := range brokerOffsets[1:] {
+		before := brokerOffsets[i]
+		if (timestamp >= after.Timestamp) || (after.Timestamp == before.Timestamp) {
+			continue
+		}
+		if after.Offset <= before.Offset {
+			return after.Offset
+		}
+		return before.Offset + (after.Offset-before.Offset)*(timestamp-before.Timestamp)/(after.Timestamp-before.Timestamp)
+	}
+	return latest.Offset
+}
+
 func (module *InMemoryStorage) getConsumerPartition(consumerMap *consumerGroup, topic string, partition, partitionCount int32, requestLogger *zap.Logger) *consumerPartition {
 	// Get or create the topic for the consumer
 	consumerTopicMap, ok := consumerMap.topics[topic]
@@ -368,13 +1010,7 @@ func (module *InMemoryStorage) getConsumerPartition(consumerMap *consumerGroup,
 }
 
 func (module *InMemoryStorage) acceptConsumerGroup(group string) bool {
-	if (module.groupAllowlist != nil) && (!module.groupAllowlist.MatchString(group)) {
-		return false
-	}
-	if (module.groupDenylist != nil) && module.groupDenylist.MatchString(group) {
-		return false
-	}
-	return true
+	return module.filter.AcceptGroup(group)
 }
 
 func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageRequest, requestLogger *zap.Logger) {
@@ -385,6 +1021,9 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		return
 	}
 
+	// Any commit shows that the consumer module is reading offsets, even if the commit is not stored
+	updateLatest(&clusterMap.activity.lastConsumerOffset, request.Timestamp)
+
 	if request.Timestamp < ((time.Now().Unix() - module.expireGroup) * 1000) {
 		requestLogger.Debug("dropped", zap.String("reason", "old offset"))
 		return
@@ -394,6 +1033,16 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		requestLogger.Debug("dropped", zap.String("reason", "group not allowlisted"))
 		return
 	}
+	if !module.filter.AcceptTopic(request.Topic) {
+		requestLogger.Debug("dropped", zap.String("reason", "topic not allowlisted"))
+		return
+	}
+
+	// Record that the topic is consumed before checking the broker offset, as the cluster module may only fetch broker
+	// offsets for consumed topics
+	if latest, ok := clusterMap.consumedTopics.Load(request.Topic); !ok || (latest.(int64) < request.Timestamp) {
+		clusterMap.consumedTopics.Store(request.Topic, request.Timestamp)
+	}
 
 	// Get the broker offset for this partition, as well as the partition count
 	brokerOffset, partitionCount := module.getBrokerOffset(&clusterMap, request.Topic, request.Partition, requestLogger)
@@ -401,6 +1050,9 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		// If the returned partitionCount is zero, there was an error that was already logged. Just stop processing
 		return
 	}
+	if module.interpolateBrokerOffsets {
+		brokerOffset = module.getInterpolatedBrokerOffset(&clusterMap, request.Topic, request.Partition, request.Timestamp)
+	}
 
 	// Make the consumer group if it does not yet exist
 	clusterMap.consumerLock.Lock()
@@ -427,6 +1079,11 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		return
 	}
 
+	if consumerPartition.commits == nil {
+		consumerPartition.commits = newCommitCounter(module.commitRateWindow)
+	}
+	consumerPartition.commits.add(request.Timestamp)
+
 	var partitionLag *protocol.Lag
 	if destination.isAppend() {
 		// Calculate the lag against the brokerOffset
@@ -438,12 +1095,37 @@ func (module *InMemoryStorage) addConsumerOffset(request *protocol.StorageReques
 		}
 		requestLogger.Debug("ok", zap.Uint64("lag", partitionLag.Value))
 		consumerMap.lastCommit = request.Timestamp
+		module.recordRewind(consumerMap, consumerPartitionRing, request, requestLogger)
 	}
 
 	destination = module.mergeFrequentCommitIntoPrevious(destination, request, requestLogger)
 	module.storeConsumerOffset(consumerPartition, destination, request, partitionLag)
 }
 
+// If the offset in the request is lower than the latest offset in the ring by at least the rewind threshold, add a
+// rewind to the consumer group. The request must be newer than everything in the ring, and the group lock must be held
+func (module *InMemoryStorage) recordRewind(consumerMap *consumerGroup, offsetRing *ring.Ring, request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	if module.rewindHistory == 0 {
+		return
+	}
+	latest, _ := offsetRing.Prev().Value.(*protocol.ConsumerOffset)
+	if (latest == nil) || (latest.Offset-request.Offset < module.rewindThreshold) {
+		return
+	}
+
+	requestLogger.Info("offset rewind", zap.Int64("previous_offset", latest.Offset))
+	consumerMap.rewinds = append(consumerMap.rewinds, &protocol.OffsetRewind{
+		Topic:          request.Topic,
+		Partition:      request.Partition,
+		PreviousOffset: latest.Offset,
+		Offset:         request.Offset,
+		Timestamp:      request.Timestamp,
+	})
+	if len(consumerMap.rewinds) > module.rewindHistory {
+		consumerMap.rewinds = append([]*protocol.OffsetRewind(nil), consumerMap.rewinds[len(consumerMap.rewinds)-module.rewindHistory:]...)
+	}
+}
+
 // Given a consumer offset ring and a storage request, find the destination
 // based on the order of commits.
 func findConsumerOffsetDestination(offsetRing *ring.Ring, request *protocol.StorageRequest, requestLogger *zap.Logger) *offsetRingDestination {
@@ -530,18 +1212,16 @@ func (module *InMemoryStorage) storeConsumerOffset(consumerPartition *consumerPa
 		}
 	}
 
-	// Write into the destination
-	destSlot := destination.destinationSlot()
-	ringval, _ := destSlot.Value.(*protocol.ConsumerOffset)
-	if ringval == nil {
-		ringval = &protocol.ConsumerOffset{}
-		destSlot.Value = ringval
+	// Write a new offset into the destination, rather than updating the one that is there, as fetches that have already
+	// returned may still be using it
+	destination.destinationSlot().Value = &protocol.ConsumerOffset{
+		Offset:            request.Offset,
+		Order:             request.Order,
+		Timestamp:         request.Timestamp,
+		ObservedTimestamp: time.Now().Unix() * 1000,
+		Lag:               partitionLag,
 	}
-	ringval.Offset = request.Offset
-	ringval.Order = request.Order
-	ringval.Timestamp = request.Timestamp
-	ringval.ObservedTimestamp = time.Now().Unix() * 1000
-	ringval.Lag = partitionLag
+	consumerPartition.offsetsChanged()
 
 	if destination.extendDest != nil {
 		// We've extended the ring by either appending or shifting, update the ring pointer
@@ -636,39 +1316,91 @@ func (module *InMemoryStorage) clearConsumerOwners(request *protocol.StorageRequ
 	requestLogger.Debug("ok")
 }
 
-func (module *InMemoryStorage) deleteTopic(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+func (module *InMemoryStorage) setConsumerMembers(request *protocol.StorageRequest, requestLogger *zap.Logger) {
 	clusterMap, ok := module.offsets[request.Cluster]
 	if !ok {
+		// Ignore metadata for clusters that we don't know about - should never happen anyways
 		requestLogger.Warn("unknown cluster")
 		return
 	}
 
-	// Work backwards - remove the topic from consumer groups first
-	for _, consumerMap := range clusterMap.consumer {
-		consumerMap.lock.Lock()
-		// No need to check for existence
-		delete(consumerMap.topics, request.Topic)
-		consumerMap.lock.Unlock()
+	if !module.acceptConsumerGroup(request.Group) {
+		requestLogger.Debug("dropped", zap.String("reason", "group not allowlisted"))
+		return
 	}
 
-	// Now remove the topic from the broker list
-	clusterMap.brokerLock.Lock()
-	delete(clusterMap.broker, request.Topic)
-	clusterMap.brokerLock.Unlock()
+	// Make the consumer group if it does not yet exist, unless there are no members to store
+	clusterMap.consumerLock.Lock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	if !ok {
+		if len(request.Members) == 0 {
+			clusterMap.consumerLock.Unlock()
+			return
+		}
+		clusterMap.consumer[request.Group] = &consumerGroup{
+			lock:   &sync.RWMutex{},
+			topics: make(map[string][]*consumerPartition),
+		}
+		consumerMap = clusterMap.consumer[request.Group]
+	}
+	clusterMap.consumerLock.Unlock()
 
-	requestLogger.Debug("ok")
+	consumerMap.lock.Lock()
+	consumerMap.members = request.Members
+	consumerMap.state = request.GroupState
+	consumerMap.lock.Unlock()
+
+	requestLogger.Debug("ok", zap.Int("members", len(request.Members)), zap.String("state", request.GroupState))
 }
 
-func (module *InMemoryStorage) deleteGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+func (module *InMemoryStorage) deleteTopic(request *protocol.StorageRequest, requestLogger *zap.Logger) {
 	clusterMap, ok := module.offsets[request.Cluster]
 	if !ok {
 		requestLogger.Warn("unknown cluster")
 		return
 	}
 
-	clusterMap.consumerLock.Lock()
-	delete(clusterMap.consumer, request.Group)
-	clusterMap.consumerLock.Unlock()
+	// Work backwards - remove the topic from consumer groups first. Groups that had offsets for the topic keep a record
+	// of the removal, so it is clear why the partitions are gone from the group's status
+	timestamp := time.Now().Unix() * 1000
+	for group, consumerMap := range clusterMap.consumer {
+		consumerMap.lock.Lock()
+		if _, ok := consumerMap.topics[request.Topic]; ok {
+			delete(consumerMap.topics, request.Topic)
+			requestLogger.Info("removed topic from group", zap.String("group", group), zap.String("reason", request.Reason))
+			consumerMap.topicRemovals = append(consumerMap.topicRemovals, &protocol.TopicRemoval{
+				Topic:     request.Topic,
+				Reason:    request.Reason,
+				Timestamp: timestamp,
+			})
+			if len(consumerMap.topicRemovals) > maxTopicRemovals {
+				consumerMap.topicRemovals = append([]*protocol.TopicRemoval(nil), consumerMap.topicRemovals[len(consumerMap.topicRemovals)-maxTopicRemovals:]...)
+			}
+		}
+		consumerMap.lock.Unlock()
+	}
+
+	// Now remove the topic from the broker list
+	clusterMap.brokerLock.Lock()
+	delete(clusterMap.broker, request.Topic)
+	delete(clusterMap.logStart, request.Topic)
+	delete(clusterMap.topicConfig, request.Topic)
+	clusterMap.brokerLock.Unlock()
+	clusterMap.consumedTopics.Delete(request.Topic)
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) deleteGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.Lock()
+	delete(clusterMap.consumer, request.Group)
+	clusterMap.consumerLock.Unlock()
 
 	requestLogger.Debug("ok")
 }
@@ -725,6 +1457,167 @@ func (module *InMemoryStorage) fetchConsumerList(request *protocol.StorageReques
 	request.Reply <- consumerList
 }
 
+// The number of names in each chunk sent for a StorageStreamConsumers or StorageStreamTopics request, and how long the
+// worker waits for the receiver to take each chunk before giving up on the request
+const (
+	streamChunkSize    = 1000
+	streamChunkTimeout = 5 * time.Second
+)
+
+// streamConsumerList sends the names of the groups in the cluster in chunks. Each chunk is built while the consumer
+// lock is held, and sent once it is released, so that a slow receiver does not hold up offsets being stored.
+func (module *InMemoryStorage) streamConsumerList(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	streamed := streamNames(request, clusterMap.consumerLock, func(add func(string)) {
+		for consumer := range clusterMap.consumer {
+			add(consumer)
+		}
+	})
+	if streamed {
+		requestLogger.Debug("ok")
+	} else {
+		requestLogger.Warn("stream abandoned")
+	}
+}
+
+// streamTopicList sends the names of the topics in the cluster in chunks. Each chunk is built while the broker lock is
+// held, and sent once it is released, so that a slow receiver does not hold up offsets being stored.
+func (module *InMemoryStorage) streamTopicList(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	streamed := streamNames(request, clusterMap.brokerLock, func(add func(string)) {
+		for topic := range clusterMap.broker {
+			add(topic)
+		}
+	})
+	if streamed {
+		requestLogger.Debug("ok")
+	} else {
+		requestLogger.Warn("stream abandoned")
+	}
+}
+
+// streamNames sends names on the reply channel of the request in order, streamChunkSize at a time, with the last chunk
+// marked. For each chunk, scan is called with the lock held to pass every name to add, and the names that follow the
+// previous chunk are kept. This means that only a chunk of names is copied at a time, however many there are, and a
+// name that is there for the whole stream is sent exactly once. It returns false if the receiver did not take a chunk
+// in time, in which case the rest are not sent.
+func streamNames(request *protocol.StorageRequest, lock *sync.RWMutex, scan func(add func(string))) bool {
+	chunk := &nameChunk{}
+	for {
+		lock.RLock()
+		scan(chunk.add)
+		lock.RUnlock()
+
+		names, last := chunk.next()
+		if !sendChunk(request, &protocol.StorageListChunk{Names: names, Last: last}) {
+			return false
+		}
+		recordRoundTrip(request)
+		if last {
+			return true
+		}
+	}
+}
+
+// nameChunk finds the streamChunkSize lowest names that are after the previous chunk. One name more than that is kept,
+// to tell whether there are any names after the chunk. Once that many are kept, they are a heap with the highest name
+// at the top, so that most names can be passed over with a single comparison.
+type nameChunk struct {
+	after   string
+	started bool
+	names   nameHeap
+}
+
+func (c *nameChunk) add(name string) {
+	if c.started && (name <= c.after) {
+		return
+	}
+	if len(c.names) <= streamChunkSize {
+		c.names = append(c.names, name)
+		if len(c.names) > streamChunkSize {
+			heap.Init(&c.names)
+		}
+		return
+	}
+	if name < c.names[0] {
+		c.names[0] = name
+		heap.Fix(&c.names, 0)
+	}
+}
+
+// next returns the chunk of names, and whether it is the last, and starts the next chunk after it
+func (c *nameChunk) next() ([]string, bool) {
+	names := make([]string, len(c.names))
+	copy(names, c.names)
+	sort.Strings(names)
+	c.names = c.names[:0]
+
+	last := len(names) <= streamChunkSize
+	if !last {
+		names = names[:streamChunkSize:streamChunkSize]
+		c.after = names[streamChunkSize-1]
+		c.started = true
+	}
+	return names, last
+}
+
+// nameHeap is a heap of names with the highest name at the top
+type nameHeap []string
+
+func (h nameHeap) Len() int           { return len(h) }
+func (h nameHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h nameHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *nameHeap) Push(x interface{}) {
+	*h = append(*h, x.(string))
+}
+
+func (h *nameHeap) Pop() interface{} {
+	old := *h
+	name := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return name
+}
+
+// recordRoundTrip records the time since the sender built the request, if it set Submitted, in the round trip time
+// histogram for the request type. It is called once the sender has the first reply, or the request has been handled,
+// and only the first call for a request is recorded.
+func recordRoundTrip(request *protocol.StorageRequest) {
+	if request.Submitted.IsZero() {
+		return
+	}
+	histogram := helpers.GetMetricHistogram("storage.round-trip-time." + request.RequestType.String())
+	helpers.UpdateMetricTime(histogram, request.Submitted)
+	request.Submitted = time.Time{}
+}
+
+// sendChunk sends the chunk on the reply channel of the request, and returns false if it was not taken in time
+func sendChunk(request *protocol.StorageRequest, chunk *protocol.StorageListChunk) bool {
+	timer := time.NewTimer(streamChunkTimeout)
+	defer timer.Stop()
+
+	select {
+	case request.Reply <- chunk:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 func (module *InMemoryStorage) fetchTopic(request *protocol.StorageRequest, requestLogger *zap.Logger) {
 	defer close(request.Reply)
 
@@ -754,48 +1647,280 @@ func (module *InMemoryStorage) fetchTopic(request *protocol.StorageRequest, requ
 	request.Reply <- offsetList
 }
 
+func (module *InMemoryStorage) fetchTopicProduceRates(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	topicList, ok := clusterMap.broker[request.Topic]
+	if !ok {
+		requestLogger.Warn("unknown topic")
+		clusterMap.brokerLock.RUnlock()
+		return
+	}
+
+	rateList := make([]float64, len(topicList))
+	for i, partition := range topicList {
+		rateList[i] = getProduceRate(getBrokerOffsetList(partition))
+	}
+	clusterMap.brokerLock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- rateList
+}
+
+func (module *InMemoryStorage) fetchTopicConfig(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.brokerLock.RLock()
+	topicConfig, ok := clusterMap.topicConfig[request.Topic]
+	if !ok {
+		requestLogger.Debug("unknown topic")
+		clusterMap.brokerLock.RUnlock()
+		return
+	}
+	configCopy := make(map[string]string, len(topicConfig))
+	for name, value := range topicConfig {
+		configCopy[name] = value
+	}
+	clusterMap.brokerLock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- configCopy
+}
+
+func (module *InMemoryStorage) fetchClusterReplication(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// The replication status is replaced as a whole, and never modified, so it can be returned without copying
+	clusterMap.brokerLock.RLock()
+	replication := clusterMap.metadata.replication
+	clusterMap.brokerLock.RUnlock()
+
+	if replication == nil {
+		requestLogger.Debug("no replication status")
+		return
+	}
+	requestLogger.Debug("ok")
+	request.Reply <- replication
+}
+
+func (module *InMemoryStorage) fetchClusterBrokers(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// The brokers are replaced as a whole, and never modified, so they can be returned without copying
+	clusterMap.brokerLock.RLock()
+	brokers := clusterMap.metadata.brokers
+	clusterMap.brokerLock.RUnlock()
+
+	if brokers == nil {
+		requestLogger.Debug("no brokers")
+		return
+	}
+	requestLogger.Debug("ok")
+	request.Reply <- brokers
+}
+
+func (module *InMemoryStorage) fetchClusterActivity(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	queueDepth, queueCapacity := module.getQueueDepth()
+
+	requestLogger.Debug("ok")
+	request.Reply <- &protocol.ClusterActivity{
+		LastBrokerOffset:   atomic.LoadInt64(&clusterMap.activity.lastBrokerOffset),
+		LastConsumerOffset: atomic.LoadInt64(&clusterMap.activity.lastConsumerOffset),
+		QueueDepth:         queueDepth,
+		QueueCapacity:      queueCapacity,
+	}
+}
+
+// fetchConsumedTopics returns the topics that have had a commit within the expire-group interval. Topics without a
+// recent commit are removed, as every group that consumed them has expired
+func (module *InMemoryStorage) fetchConsumedTopics(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	expireTime := (time.Now().Unix() - module.expireGroup) * 1000
+	topics := make([]string, 0)
+	clusterMap.consumedTopics.Range(func(key, value interface{}) bool {
+		if value.(int64) < expireTime {
+			clusterMap.consumedTopics.Delete(key)
+		} else {
+			topics = append(topics, key.(string))
+		}
+		return true
+	})
+	sort.Strings(topics)
+
+	requestLogger.Debug("ok", zap.Int("topics", len(topics)))
+	request.Reply <- topics
+}
+
+func (module *InMemoryStorage) fetchClusterLeaderChurn(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// The leader churn is replaced as a whole, and never modified, so it can be returned without copying
+	clusterMap.brokerLock.RLock()
+	leaderChurn := clusterMap.metadata.leaderChurn
+	clusterMap.brokerLock.RUnlock()
+
+	if leaderChurn == nil {
+		requestLogger.Debug("no leader churn")
+		return
+	}
+	requestLogger.Debug("ok")
+	request.Reply <- leaderChurn
+}
+
 func getConsumerTopicList(consumerMap *consumerGroup) protocol.ConsumerTopics {
-	topicList := make(protocol.ConsumerTopics)
 	consumerMap.lock.RLock()
 	defer consumerMap.lock.RUnlock()
+	return consumerTopicList(consumerMap, time.Now().Unix()*1000)
+}
 
+// getConsumerGroupDetail returns the offsets and the other information stored for the group, all read under the lock
+// for the group. The lag of the partitions is not set
+func getConsumerGroupDetail(consumerMap *consumerGroup) *protocol.ConsumerGroupDetail {
+	consumerMap.lock.RLock()
+	defer consumerMap.lock.RUnlock()
+	return &protocol.ConsumerGroupDetail{
+		Topics:        consumerTopicList(consumerMap, time.Now().Unix()*1000),
+		Members:       copyConsumerMembers(consumerMap.members),
+		State:         consumerMap.state,
+		Rewinds:       copyOffsetRewinds(consumerMap.rewinds),
+		TopicRemovals: copyTopicRemovals(consumerMap.topicRemovals),
+	}
+}
+
+// consumerTopicList copies the offsets stored for the group. The lock for the group must be held
+func consumerTopicList(consumerMap *consumerGroup, now int64) protocol.ConsumerTopics {
+	topicList := make(protocol.ConsumerTopics)
 	for topic, partitions := range consumerMap.topics {
 		topicList[topic] = make(protocol.ConsumerPartitions, len(partitions))
 
 		for partitionID, partition := range partitions {
-			consumerPartition := &protocol.ConsumerPartition{Owner: partition.owner, ClientID: partition.clientID}
-			if partition.offsets != nil {
-				offsetRing := partition.offsets
-				consumerPartition.Offsets = make([]*protocol.ConsumerOffset, offsetRing.Len())
-
-				ringPtr := offsetRing
-				for i := 0; i < offsetRing.Len(); i++ {
-					if ringPtr.Value == nil {
-						consumerPartition.Offsets[i] = nil
-					} else {
-						ringval, _ := ringPtr.Value.(*protocol.ConsumerOffset)
-
-						// Make a copy so that we can release the lock and be safe
-						consumerPartition.Offsets[i] = &protocol.ConsumerOffset{
-							Offset:            ringval.Offset,
-							Order:             ringval.Order,
-							Lag:               ringval.Lag,
-							Timestamp:         ringval.Timestamp,
-							ObservedTimestamp: ringval.ObservedTimestamp,
-						}
-					}
-					ringPtr = ringPtr.Next()
-				}
-			} else {
-				consumerPartition.Offsets = make([]*protocol.ConsumerOffset, 0)
+			consumerPartition := &protocol.ConsumerPartition{Owner: partition.owner, ClientID: partition.clientID, RetentionHorizon: -1}
+			if partition.commits != nil {
+				consumerPartition.CommitRate = partition.commits.rate(now)
 			}
+			consumerPartition.Offsets = partition.getOffsets()
 			topicList[topic][partitionID] = consumerPartition
 		}
 	}
 	return topicList
 }
 
-func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+func (module *InMemoryStorage) fetchConsumerMembers(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.RLock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
+	if !ok {
+		requestLogger.Warn("unknown consumer")
+		return
+	}
+
+	consumerMap.lock.RLock()
+	members := copyConsumerMembers(consumerMap.members)
+	consumerMap.lock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- members
+}
+
+// copyConsumerMembers copies the members of a group, so the caller can't modify what we have stored
+func copyConsumerMembers(stored []*protocol.ConsumerGroupMember) []*protocol.ConsumerGroupMember {
+	members := make([]*protocol.ConsumerGroupMember, len(stored))
+	for i, member := range stored {
+		assignment := make(map[string][]int32, len(member.Assignment))
+		for topic, partitions := range member.Assignment {
+			assignment[topic] = append([]int32(nil), partitions...)
+		}
+		members[i] = &protocol.ConsumerGroupMember{
+			MemberID:        member.MemberID,
+			GroupInstanceID: member.GroupInstanceID,
+			ClientID:        member.ClientID,
+			ClientHost:      member.ClientHost,
+			Assignment:      assignment,
+		}
+	}
+	return members
+}
+
+func (module *InMemoryStorage) fetchConsumerGroupState(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.RLock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
+	if !ok {
+		requestLogger.Warn("unknown consumer")
+		return
+	}
+
+	consumerMap.lock.RLock()
+	state := consumerMap.state
+	consumerMap.lock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- state
+}
+
+func (module *InMemoryStorage) fetchConsumerRewinds(request *protocol.StorageRequest, requestLogger *zap.Logger) {
 	defer close(request.Reply)
 
 	clusterMap, ok := module.offsets[request.Cluster]
@@ -806,29 +1931,121 @@ func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, r
 
 	clusterMap.consumerLock.RLock()
 	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
 	if !ok {
 		requestLogger.Warn("unknown consumer")
+		return
+	}
+
+	consumerMap.lock.RLock()
+	rewinds := copyOffsetRewinds(consumerMap.rewinds)
+	consumerMap.lock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- rewinds
+}
+
+// copyOffsetRewinds copies the rewinds of a group, so the caller can't modify what we have stored
+func copyOffsetRewinds(stored []*protocol.OffsetRewind) []*protocol.OffsetRewind {
+	rewinds := make([]*protocol.OffsetRewind, len(stored))
+	for i, rewind := range stored {
+		rewindCopy := *rewind
+		rewinds[i] = &rewindCopy
+	}
+	return rewinds
+}
+
+func (module *InMemoryStorage) fetchConsumerTopicRemovals(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.consumerLock.RLock()
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	clusterMap.consumerLock.RUnlock()
+	if !ok {
+		requestLogger.Warn("unknown consumer")
+		return
+	}
+
+	consumerMap.lock.RLock()
+	removals := copyTopicRemovals(consumerMap.topicRemovals)
+	consumerMap.lock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- removals
+}
+
+// copyTopicRemovals copies the topic removals of a group, so the caller can't modify what we have stored
+func copyTopicRemovals(stored []*protocol.TopicRemoval) []*protocol.TopicRemoval {
+	removals := make([]*protocol.TopicRemoval, len(stored))
+	for i, removal := range stored {
+		removalCopy := *removal
+		removals[i] = &removalCopy
+	}
+	return removals
+}
+
+func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// The registration of an expected group is read under the consumer lock as well, so that it is consistent with the
+	// rest of the reply. It is sent even if the group is not stored, so that the group can be reported as missing
+	clusterMap.consumerLock.RLock()
+	clusterMap.expectedLock.RLock()
+	expectedSince := clusterMap.expected[request.Group]
+	clusterMap.expectedLock.RUnlock()
+
+	consumerMap, ok := clusterMap.consumer[request.Group]
+	if !ok {
 		clusterMap.consumerLock.RUnlock()
+		replyExpectedConsumer(request, requestLogger, expectedSince)
 		return
 	}
 
-	// Lazily purge consumers that haven't committed in longer than the defined interval. Return as a 404
+	// Lazily purge consumers that haven't committed in longer than the defined interval. Reply as if it was not stored
 	if ((time.Now().Unix() - module.expireGroup) * 1000) > consumerMap.lastCommit {
 		// Swap for a write lock
 		clusterMap.consumerLock.RUnlock()
 
 		clusterMap.consumerLock.Lock()
 		requestLogger.Debug("purge expired consumer", zap.Int64("last_commit", consumerMap.lastCommit))
-		delete(clusterMap.consumer, request.Group)
+
+		// Another request for the group may have purged it while the lock was swapped
+		purged := clusterMap.consumer[request.Group] == consumerMap
+		if purged {
+			delete(clusterMap.consumer, request.Group)
+		}
 		clusterMap.consumerLock.Unlock()
+		if purged {
+			helpers.PublishEvent(&protocol.Event{
+				Type:    protocol.EventGroupExpired,
+				Cluster: request.Cluster,
+				Group:   request.Group,
+			})
+		}
+		replyExpectedConsumer(request, requestLogger, expectedSince)
 		return
 	}
 
-	topicList := getConsumerTopicList(consumerMap)
+	detail := getConsumerGroupDetail(consumerMap)
+	detail.ExpectedSince = expectedSince
+	topicList := detail.Topics
 	clusterMap.consumerLock.RUnlock()
 
 	// Calculate the current lag for each now. We do this separate from getting the consumer info so we can avoid
 	// locking both the consumers and the brokers at the same time
+	now := time.Now().Unix() * 1000
 	clusterMap.brokerLock.RLock()
 	for topic, partitions := range topicList {
 		topicMap, ok := clusterMap.broker[topic]
@@ -836,19 +2053,29 @@ func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, r
 			// The topic may have just been deleted, so we'll skip this part and just return the consumer data we have
 			continue
 		}
+		logStartOffsets := clusterMap.logStart[topic]
 
 		for p, partition := range partitions {
-			// Build the slice of broker offsets to return
-			partition.BrokerOffsets = make([]int64, 0, module.intervals)
-			brokerOffsetPtr := topicMap[p].Next()
-			brokerOffsetPtr.Do(func(item interface{}) {
-				if item != nil {
-					partition.BrokerOffsets = append(partition.BrokerOffsets, item.(*brokerOffset).Offset) // nolint:scopelint
+			if (p < len(logStartOffsets)) && (logStartOffsets[p] != nil) {
+				partition.LogStartOffset = logStartOffsets[p].offset
+				if (len(partition.Offsets) > 0) && (partition.Offsets[len(partition.Offsets)-1] != nil) {
+					partition.RetentionHorizon = estimateRetentionHorizon(logStartOffsets[p], partition.Offsets[len(partition.Offsets)-1].Offset)
 				}
-			})
+			}
+
+			// Build the slice of broker offsets to return
+			brokerOffsets := getBrokerOffsetList(topicMap[p])
+			partition.BrokerOffsets = make([]int64, len(brokerOffsets))
+			for i, item := range brokerOffsets {
+				partition.BrokerOffsets[i] = item.Offset
+			}
+			partition.ProduceRate = getProduceRate(brokerOffsets)
 
-			if len(partition.Offsets) > 0 {
+			if (len(partition.Offsets) > 0) && (len(brokerOffsets) > 0) {
 				brokerOffset := partition.BrokerOffsets[len(partition.BrokerOffsets)-1]
+				if module.interpolateBrokerOffsets {
+					brokerOffset = interpolateBrokerOffset(brokerOffsets, now)
+				}
 				lastOffset := partition.Offsets[len(partition.Offsets)-1]
 				if lastOffset != nil {
 					if brokerOffset < lastOffset.Offset {
@@ -858,6 +2085,7 @@ func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, r
 					} else {
 						partition.CurrentLag = uint64(brokerOffset - lastOffset.Offset)
 					}
+					partition.TimeLag = estimateTimeLag(brokerOffsets, lastOffset.Offset)
 				}
 			}
 		}
@@ -865,7 +2093,81 @@ func (module *InMemoryStorage) fetchConsumer(request *protocol.StorageRequest, r
 	clusterMap.brokerLock.RUnlock()
 
 	requestLogger.Debug("ok")
-	request.Reply <- topicList
+	request.Reply <- detail
+}
+
+// replyExpectedConsumer replies to a StorageFetchConsumer request for a group that is not stored. If the group is
+// expected, the reply only has the time it was registered at. Otherwise, there is no reply.
+func replyExpectedConsumer(request *protocol.StorageRequest, requestLogger *zap.Logger, expectedSince int64) {
+	if expectedSince == 0 {
+		requestLogger.Warn("unknown consumer")
+		return
+	}
+	requestLogger.Debug("expected consumer not stored")
+	request.Reply <- &protocol.ConsumerGroupDetail{ExpectedSince: expectedSince}
+}
+
+// estimateRetentionHorizon returns how long (in milliseconds) until retention removes the consumer offset from the
+// partition, assuming the log start offset keeps advancing at the average rate seen over its stored samples. If the
+// consumer offset is already at or below the log start offset, zero is returned. If the log start offset has not been
+// seen to advance, the time cannot be estimated, and -1 is returned.
+func estimateRetentionHorizon(logStart *logStartOffset, consumerOffset int64) int64 {
+	if len(logStart.samples) < 2 {
+		return -1
+	}
+	first := logStart.samples[0]
+	last := logStart.samples[len(logStart.samples)-1]
+	if (last.Timestamp <= first.Timestamp) || (last.Offset <= first.Offset) {
+		return -1
+	}
+	if consumerOffset <= logStart.offset {
+		return 0
+	}
+
+	rate := float64(last.Offset-first.Offset) / float64(last.Timestamp-first.Timestamp)
+	return int64(float64(consumerOffset-logStart.offset) / rate)
+}
+
+// getProduceRate returns the number of messages produced to the partition per second, over the span of the broker
+// offsets, which must be in order, oldest first. If there are not enough broker offsets to tell, or the offsets went
+// backwards (such as when a topic is recreated), zero is returned.
+func getProduceRate(brokerOffsets []*brokerOffset) float64 {
+	if len(brokerOffsets) < 2 {
+		return 0
+	}
+	first := brokerOffsets[0]
+	last := brokerOffsets[len(brokerOffsets)-1]
+	if (last.Timestamp <= first.Timestamp) || (last.Offset < first.Offset) {
+		return 0
+	}
+	return float64(last.Offset-first.Offset) * 1000 / float64(last.Timestamp-first.Timestamp)
+}
+
+// estimateTimeLag returns how long before the latest broker offset the broker end offset passed the consumer offset,
+// in milliseconds. The broker offsets must be in order, oldest first. The time is interpolated between the stored
+// broker offsets on either side of the consumer offset. If the consumer offset is older than all of the stored broker
+// offsets, the age of the oldest one is returned, which is as far back as can be seen.
+func estimateTimeLag(brokerOffsets []*brokerOffset, consumerOffset int64) int64 {
+	if len(brokerOffsets) == 0 {
+		return 0
+	}
+	latest := brokerOffsets[len(brokerOffsets)-1]
+	if consumerOffset >= latest.Offset {
+		return 0
+	}
+
+	for i, after := range brokerOffsets {
+		if after.Offset <= consumerOffset {
+			continue
+		}
+		if i == 0 {
+			return latest.Timestamp - after.Timestamp
+		}
+		before := brokerOffsets[i-1]
+		passed := before.Timestamp + (consumerOffset-before.Offset)*(after.Timestamp-before.Timestamp)/(after.Offset-before.Offset)
+		return latest.Timestamp - passed
+	}
+	return 0
 }
 
 func (module *InMemoryStorage) fetchConsumersForTopicList(request *protocol.StorageRequest, requestLogger *zap.Logger) {
@@ -896,3 +2198,115 @@ func (module *InMemoryStorage) fetchConsumersForTopicList(request *protocol.Stor
 	requestLogger.Debug("ok")
 	request.Reply <- consumerListForTopic
 }
+
+func (module *InMemoryStorage) addExpectedGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	registered := request.Timestamp
+	if registered == 0 {
+		registered = time.Now().Unix() * 1000
+	}
+
+	clusterMap.expectedLock.Lock()
+	if _, ok := clusterMap.expected[request.Group]; !ok {
+		// Keep the original registration time if the group is registered more than once
+		clusterMap.expected[request.Group] = registered
+	}
+	clusterMap.expectedLock.Unlock()
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) deleteExpectedGroup(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.expectedLock.Lock()
+	delete(clusterMap.expected, request.Group)
+	clusterMap.expectedLock.Unlock()
+
+	requestLogger.Debug("ok")
+}
+
+func (module *InMemoryStorage) fetchExpectedGroups(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	clusterMap.expectedLock.RLock()
+	expectedGroups := make(map[string]int64, len(clusterMap.expected))
+	for group, registered := range clusterMap.expected {
+		expectedGroups[group] = registered
+	}
+	clusterMap.expectedLock.RUnlock()
+
+	requestLogger.Debug("ok")
+	request.Reply <- expectedGroups
+}
+
+func (module *InMemoryStorage) setConnectors(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	connectors := make(map[string]*protocol.Connector, len(request.Connectors))
+	for _, connector := range request.Connectors {
+		connectors[connector.Name] = connector
+	}
+
+	// Replace all the connectors from this source, so ones that have been removed from the Connect cluster go away
+	clusterMap.connectorLock.Lock()
+	clusterMap.connectors[request.Source] = connectors
+	clusterMap.connectorLock.Unlock()
+
+	requestLogger.Debug("ok", zap.Int("connectors", len(connectors)))
+}
+
+func (module *InMemoryStorage) fetchConnectors(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	defer close(request.Reply)
+
+	clusterMap, ok := module.offsets[request.Cluster]
+	if !ok {
+		requestLogger.Warn("unknown cluster")
+		return
+	}
+
+	// Copy the connectors so the caller can't modify what we have stored
+	connectors := make([]*protocol.Connector, 0)
+	clusterMap.connectorLock.RLock()
+	for _, sourceConnectors := range clusterMap.connectors {
+		for _, connector := range sourceConnectors {
+			connectorCopy := *connector
+			connectorCopy.Tasks = make([]*protocol.ConnectorTask, len(connector.Tasks))
+			for i, task := range connector.Tasks {
+				taskCopy := *task
+				connectorCopy.Tasks[i] = &taskCopy
+			}
+			connectors = append(connectors, &connectorCopy)
+		}
+	}
+	clusterMap.connectorLock.RUnlock()
+
+	sort.Slice(connectors, func(i, j int) bool {
+		if connectors[i].Name == connectors[j].Name {
+			return connectors[i].ConnectCluster < connectors[j].ConnectCluster
+		}
+		return connectors[i].Name < connectors[j].Name
+	})
+
+	requestLogger.Debug("ok")
+	request.Reply <- connectors
+}
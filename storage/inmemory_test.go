@@ -12,6 +12,11 @@ package storage
 
 import (
 	"container/ring"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -22,6 +27,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/linkedin/Burrow/helpers"
 	"github.com/linkedin/Burrow/protocol"
 )
 
@@ -125,6 +131,134 @@ func TestInMemoryStorage_Configure_BadDenylistRegexp(t *testing.T) {
 	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
 }
 
+func TestInMemoryStorage_Configure_BadOverflowPolicy(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.overflow-policy", "wait")
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_sendToWorker(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.overflow-policy", "drop")
+	module.Configure("test", "storage.test")
+	module.workers = []chan *protocol.StorageRequest{make(chan *protocol.StorageRequest, 1)}
+	dropped := module.droppedCount.Count()
+
+	// The first request fits in the queue, and the second is dropped
+	module.sendToWorker(0, &protocol.StorageRequest{RequestType: protocol.StorageSetConsumerOffset})
+	module.sendToWorker(0, &protocol.StorageRequest{RequestType: protocol.StorageSetConsumerOffset})
+	assert.Equal(t, dropped+1, module.droppedCount.Count(), "Expected one request to be dropped")
+	assert.Len(t, module.workers[0], 1, "Expected one request to be queued")
+
+	// A request with a reply waits for space instead
+	blocked := module.blockedCount.Count()
+	fetch := &protocol.StorageRequest{RequestType: protocol.StorageFetchConsumer, Reply: make(chan interface{})}
+	sent := make(chan struct{})
+	go func() {
+		module.sendToWorker(0, fetch)
+		close(sent)
+	}()
+	for i := 0; (i < 100) && (module.blockedCount.Count() == blocked); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, blocked+1, module.blockedCount.Count(), "Expected the fetch request to wait")
+	<-module.workers[0]
+	<-sent
+	assert.Equal(t, fetch, <-module.workers[0], "Expected the fetch request to be queued")
+	assert.Equal(t, dropped+1, module.droppedCount.Count(), "Expected the fetch request not to be dropped")
+}
+
+func TestInMemoryStorage_Configure_BadWorkerBounds(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.min-workers", 8)
+	viper.Set("storage.test.max-workers", 4)
+
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_Configure_WorkersWithinBounds(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.min-workers", 2)
+	viper.Set("storage.test.max-workers", 8)
+	module.Configure("test", "storage.test")
+
+	assert.Equal(t, 8, module.numWorkers, "Expected the default of 20 workers to be limited to max-workers")
+}
+
+func TestInMemoryStorage_autoscaleWorkers(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.testcluster.servers", []string{"broker1.example.com:1234"})
+	viper.Set("storage.test.workers", 2)
+	viper.Set("storage.test.min-workers", 2)
+	viper.Set("storage.test.max-workers", 8)
+	viper.Set("storage.test.scale-down-delay", 60)
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	scaledUp := module.scaleUpCount.Count()
+	scaledDown := module.scaleDownCount.Count()
+	now := time.Now()
+
+	// Each check after a request had to wait doubles the workers, up to the maximum
+	for _, expected := range []int{4, 8, 8} {
+		module.blockedCount.Inc(1)
+		module.autoscaleWorkers(now)
+		assert.Equal(t, expected, module.numWorkers, "Unexpected number of workers after scaling up")
+		assert.Len(t, module.workers, expected, "Unexpected number of worker channels after scaling up")
+	}
+	assert.Equal(t, scaledUp+2, module.scaleUpCount.Count(), "Expected two scale ups")
+	assert.Equal(t, int64(8), module.workerCount.Value(), "Expected the workers gauge to be updated")
+
+	// The workers are halved once each idle period, down to the minimum
+	module.autoscaleWorkers(now.Add(30 * time.Second))
+	assert.Equal(t, 8, module.numWorkers, "Expected no change before the scale down delay")
+	for i, expected := range []int{4, 2, 2} {
+		module.autoscaleWorkers(now.Add(time.Duration(61*(i+1)) * time.Second))
+		assert.Equal(t, expected, module.numWorkers, "Unexpected number of workers after scaling down")
+	}
+	assert.Equal(t, scaledDown+2, module.scaleDownCount.Count(), "Expected two scale downs")
+
+	// Requests are still handled after the workers change
+	request := &protocol.StorageRequest{RequestType: protocol.StorageFetchClusters, Reply: make(chan interface{})}
+	module.requestChannel <- request
+	assert.Equal(t, []string{"testcluster"}, <-request.Reply, "Expected the cluster list after scaling")
+}
+
+func TestInMemoryStorage_ReleasesPooledRequests(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.AcquireStorageRequest(protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              4321,
+		Timestamp:           9876,
+	})
+	module.requestChannel <- request
+
+	// The offset and the fetch can be handled by different workers, so wait until the offset is stored
+	var offsets []int64
+	for i := 0; (i < 100) && (len(offsets) == 0); i++ {
+		fetch := &protocol.StorageRequest{RequestType: protocol.StorageFetchTopic, Cluster: "testcluster", Topic: "testtopic", Reply: make(chan interface{})}
+		module.requestChannel <- fetch
+		offsets, _ = (<-fetch.Reply).([]int64)
+		if len(offsets) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	assert.Equal(t, []int64{4321}, offsets, "Expected the pooled request to be stored")
+
+	// Stop waits for the workers, so the release of the request is visible after it returns. Releasing the request
+	// clears it before it is returned to the pool
+	module.Stop()
+	assert.Equal(t, protocol.StorageRequest{}, *request, "Expected the pooled request to be released")
+}
+
 func TestInMemoryStorage_Start(t *testing.T) {
 	module := startWithTestCluster("")
 	assert.Len(t, module.offsets, 1, "Module start did not define 1 cluster")
@@ -249,6 +383,34 @@ func TestInMemoryStorage_addBrokerOffset_AddMany(t *testing.T) {
 	}
 }
 
+func TestInMemoryStorage_addBrokerOffset_PartitionCountEvent(t *testing.T) {
+	module := startWithTestBrokerOffsets("")
+	events := helpers.SubscribeEvents(protocol.EventPartitionCountChange)
+	defer events.Close()
+
+	request := protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           1,
+		TopicPartitionCount: 3,
+		Offset:              4321,
+		Timestamp:           9876,
+	}
+	module.addBrokerOffset(&request, module.Log)
+	module.addBrokerOffset(&request, module.Log)
+
+	// A new topic is not a change in the partition count
+	request.Topic = "newtopic"
+	module.addBrokerOffset(&request, module.Log)
+
+	assert.Len(t, events.Events, 1, "Expected one event for the partition count change")
+	event := <-events.Events
+	assert.Equal(t, "testtopic", event.Topic, "Expected the event to be for testtopic")
+	assert.Equal(t, 1, event.PreviousPartitions, "Expected the previous partition count to be 1")
+	assert.Equal(t, 3, event.Partitions, "Expected the partition count to be 3")
+}
+
 func TestInMemoryStorage_addBrokerOffset_BadCluster(t *testing.T) {
 	module := startWithTestCluster("")
 	request := protocol.StorageRequest{
@@ -360,6 +522,33 @@ func TestInMemoryStorage_addConsumerOffset_Allowlist(t *testing.T) {
 	assert.False(t, ok, "Group testgroup created when not allowlisted")
 }
 
+func TestInMemoryStorage_addConsumerOffset_TopicFilterUpdate(t *testing.T) {
+	module := startWithTestBrokerOffsets("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      1000,
+		Order:       500,
+		Timestamp:   (time.Now().Unix() * 1000) - 10000,
+	}
+	module.addConsumerOffset(&request, module.Log)
+
+	// Deny the topic while running. The stored offset is kept, but new offsets are dropped
+	err := module.filter.Update(helpers.ConsumerFilterSettings{TopicDenylist: "^testtopic$"})
+	assert.Nil(t, err, "Expected filter update to return no error")
+	request.Offset = 1100
+	request.Order = 501
+	module.addConsumerOffset(&request, module.Log)
+
+	offsets := getPartitionOffsets(module)
+	assert.Len(t, offsets, 1, "Expected only one offset to be stored")
+	assert.Equalf(t, int64(1000), offsets[0].Offset, "Expected stored offset to be 1000, not %v", offsets[0].Offset)
+}
+
 func TestInMemoryStorage_addConsumerOffset_TooOld(t *testing.T) {
 	module := startWithTestConsumerOffsets("testgroup", 1000000)
 
@@ -379,7 +568,7 @@ func getPartitionOffsets(module *InMemoryStorage) []*protocol.ConsumerOffset {
 
 	go module.fetchConsumer(&request, module.Log)
 	response := <-request.Reply
-	partition := response.(protocol.ConsumerTopics)["testtopic"][0]
+	partition := response.(*protocol.ConsumerGroupDetail).Topics["testtopic"][0]
 
 	var ret []*protocol.ConsumerOffset
 	for _, x := range partition.Offsets {
@@ -783,6 +972,7 @@ func TestInMemoryStorage_deleteTopic(t *testing.T) {
 		RequestType: protocol.StorageSetDeleteTopic,
 		Cluster:     "testcluster",
 		Topic:       "testtopic",
+		Reason:      "deleted",
 	}
 	module.deleteTopic(&request, module.Log)
 
@@ -791,6 +981,9 @@ func TestInMemoryStorage_deleteTopic(t *testing.T) {
 	consumerMap := module.offsets["testcluster"].consumer["testgroup"]
 	_, ok = consumerMap.topics["testtopic"]
 	assert.False(t, ok, "Topic not deleted from group offsets")
+	assert.Lenf(t, consumerMap.topicRemovals, 1, "Expected one topic removal, not %v", len(consumerMap.topicRemovals))
+	assert.Equal(t, "testtopic", consumerMap.topicRemovals[0].Topic, "Expected removal Topic to be testtopic")
+	assert.Equal(t, "deleted", consumerMap.topicRemovals[0].Reason, "Expected removal Reason to be deleted")
 }
 
 func TestInMemoryStorage_deleteTopic_BadCluster(t *testing.T) {
@@ -825,6 +1018,7 @@ func TestInMemoryStorage_deleteTopic_NoTopic(t *testing.T) {
 	consumerMap := module.offsets["testcluster"].consumer["testgroup"]
 	_, ok = consumerMap.topics["testtopic"]
 	assert.True(t, ok, "Wrong topic deleted from group offsets")
+	assert.Empty(t, consumerMap.topicRemovals, "Expected no topic removals for a group without the topic")
 }
 
 func TestInMemoryStorage_deleteGroup(t *testing.T) {
@@ -977,6 +1171,133 @@ func TestInMemoryStorage_fetchConsumerList_BadCluster(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_streamConsumerList(t *testing.T) {
+	module := startWithTestCluster("")
+	clusterMap := module.offsets["testcluster"]
+	for i := 0; i < (streamChunkSize*2)+500; i++ {
+		clusterMap.consumer[fmt.Sprintf("testgroup%v", i)] = &consumerGroup{}
+	}
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageStreamConsumers,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.streamConsumerList(&request, module.Log)
+
+	names := make(map[string]bool)
+	var chunks []*protocol.StorageListChunk
+	for response := range request.Reply {
+		assert.IsType(t, &protocol.StorageListChunk{}, response, "Expected response to be of type *StorageListChunk")
+		chunk := response.(*protocol.StorageListChunk)
+		chunks = append(chunks, chunk)
+		for _, name := range chunk.Names {
+			names[name] = true
+		}
+	}
+	assert.Len(t, chunks, 3, "Expected the groups in three chunks")
+	assert.Len(t, chunks[0].Names, streamChunkSize, "Expected the first chunk to be full")
+	assert.False(t, chunks[0].Last, "Expected the first chunk not to be the last")
+	assert.True(t, chunks[2].Last, "Expected the third chunk to be the last")
+	assert.Len(t, names, len(clusterMap.consumer), "Expected every group to be sent once")
+}
+
+// streamFirstChunkAlloc returns the bytes allocated to stream the names of count groups, up to the first chunk being
+// received
+func streamFirstChunkAlloc(count int) uint64 {
+	module := startWithTestCluster("")
+	defer module.Stop()
+	clusterMap := module.offsets["testcluster"]
+	for i := 0; i < count; i++ {
+		clusterMap.consumer[fmt.Sprintf("testgroup%v", i)] = &consumerGroup{}
+	}
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageStreamConsumers,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	go module.streamConsumerList(&request, module.Log)
+	<-request.Reply
+	runtime.ReadMemStats(&after)
+	for range request.Reply {
+	}
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestInMemoryStorage_streamConsumerList_Allocation(t *testing.T) {
+	// Only a chunk of names is copied at a time, so the memory needed does not grow with the number of groups
+	small := streamFirstChunkAlloc(streamChunkSize * 2)
+	large := streamFirstChunkAlloc(streamChunkSize * 20)
+	assert.Truef(t, large < small*2, "Expected the allocation for 10 times the groups (%v bytes) to be about the same as for the smaller cluster (%v bytes)", large, small)
+}
+
+func TestInMemoryStorage_streamConsumerList_SlowReader(t *testing.T) {
+	module := startWithTestBrokerOffsets("")
+	clusterMap := module.offsets["testcluster"]
+	for i := 0; i < streamChunkSize*2; i++ {
+		clusterMap.consumer[fmt.Sprintf("testgroup%v", i)] = &consumerGroup{}
+	}
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageStreamConsumers,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+	go module.streamConsumerList(&request, module.Log)
+	<-request.Reply
+
+	// While the reader has not taken the next chunk, offsets for a new group are still stored
+	stored := make(chan struct{})
+	go func() {
+		module.addConsumerOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetConsumerOffset,
+			Cluster:     "testcluster",
+			Topic:       "testtopic",
+			Group:       "slowreadergroup",
+			Partition:   0,
+			Offset:      1000,
+			Order:       500,
+			Timestamp:   time.Now().Unix() * 1000,
+		}, module.Log)
+		close(stored)
+	}()
+	select {
+	case <-stored:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected the offset to be stored while the list is streamed")
+	}
+
+	for range request.Reply {
+	}
+	clusterMap.consumerLock.RLock()
+	_, ok := clusterMap.consumer["slowreadergroup"]
+	clusterMap.consumerLock.RUnlock()
+	assert.True(t, ok, "Expected the group to be stored")
+}
+
+func TestInMemoryStorage_streamTopicList_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageStreamTopics,
+		Cluster:     "nocluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.streamTopicList(&request, module.Log)
+	response, ok := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
 func TestInMemoryStorage_fetchTopic(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -1001,6 +1322,45 @@ func TestInMemoryStorage_fetchTopic(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestInMemoryStorage_fetchTopicProduceRates(t *testing.T) {
+	module := startWithTestBrokerOffsets("")
+
+	// The broker offset advances 1000 messages in 10 seconds
+	module.addBrokerOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              5321,
+		Timestamp:           19876,
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchTopicProduceRates,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchTopicProduceRates(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Equal(t, []float64{100}, response, "Expected produce rate of 100 messages per second")
+
+	// Unknown topics get no reply
+	request.Topic = "notopic"
+	request.Reply = make(chan interface{})
+	go module.fetchTopicProduceRates(&request, module.Log)
+	response = <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestGetProduceRate(t *testing.T) {
+	assert.Equal(t, float64(0), getProduceRate([]*brokerOffset{{Offset: 1000, Timestamp: 1000}}), "Expected no rate from one offset")
+	assert.Equal(t, float64(50), getProduceRate([]*brokerOffset{{Offset: 1000, Timestamp: 1000}, {Offset: 1200, Timestamp: 3000}, {Offset: 1500, Timestamp: 11000}}), "Expected rate of 50")
+	assert.Equal(t, float64(0), getProduceRate([]*brokerOffset{{Offset: 1000, Timestamp: 1000}, {Offset: 500, Timestamp: 3000}}), "Expected no rate from decreasing offsets")
+}
+
 func TestInMemoryStorage_fetchTopic_BadCluster(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000) - 100000
 	module := startWithTestConsumerOffsets("", startTime)
@@ -1039,6 +1399,98 @@ func TestInMemoryStorage_fetchTopic_BadTopic(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
+func TestEstimateTimeLag(t *testing.T) {
+	brokerOffsets := []*brokerOffset{
+		{Offset: 1000, Timestamp: 10000},
+		{Offset: 2000, Timestamp: 20000},
+		{Offset: 2000, Timestamp: 30000},
+		{Offset: 4000, Timestamp: 40000},
+	}
+
+	// Caught up
+	assert.Equal(t, int64(0), estimateTimeLag(brokerOffsets, 4000), "Expected no time lag when caught up")
+
+	// Halfway between the second and first offsets, so the broker passed it at 15000
+	assert.Equal(t, int64(25000), estimateTimeLag(brokerOffsets, 1500), "Expected interpolated time lag of 25000")
+
+	// The broker was idle at 2000, so it passed 3000 halfway between the last two offsets
+	assert.Equal(t, int64(5000), estimateTimeLag(brokerOffsets, 3000), "Expected interpolated time lag of 5000")
+
+	// Older than all stored offsets, so the lag is at least the age of the oldest
+	assert.Equal(t, int64(30000), estimateTimeLag(brokerOffsets, 500), "Expected time lag of the oldest broker offset")
+
+	assert.Equal(t, int64(0), estimateTimeLag(nil, 500), "Expected no time lag without broker offsets")
+}
+
+func TestInterpolateBrokerOffset(t *testing.T) {
+	brokerOffsets := []*brokerOffset{
+		{Offset: 1000, Timestamp: 10000},
+		{Offset: 2000, Timestamp: 20000},
+		{Offset: 4000, Timestamp: 30000},
+	}
+
+	assert.Equal(t, int64(1500), interpolateBrokerOffset(brokerOffsets, 15000), "Expected offset between the first two")
+	assert.Equal(t, int64(3000), interpolateBrokerOffset(brokerOffsets, 25000), "Expected offset between the last two")
+	assert.Equal(t, int64(4000), interpolateBrokerOffset(brokerOffsets, 30000), "Expected the latest offset at its timestamp")
+
+	// After the latest, extrapolated at the recent rate for at most one interval
+	assert.Equal(t, int64(5000), interpolateBrokerOffset(brokerOffsets, 35000), "Expected extrapolated offset")
+	assert.Equal(t, int64(6000), interpolateBrokerOffset(brokerOffsets, 90000), "Expected extrapolation to be capped")
+
+	// Before the oldest, or without enough history, the latest is used
+	assert.Equal(t, int64(4000), interpolateBrokerOffset(brokerOffsets, 5000), "Expected the latest offset before the oldest")
+	assert.Equal(t, int64(4000), interpolateBrokerOffset(brokerOffsets[2:], 35000), "Expected the latest offset with one stored")
+	assert.Equal(t, int64(0), interpolateBrokerOffset(nil, 35000), "Expected zero without broker offsets")
+
+	// Offsets that go backwards are not extrapolated
+	brokerOffsets = append(brokerOffsets, &brokerOffset{Offset: 100, Timestamp: 40000})
+	assert.Equal(t, int64(100), interpolateBrokerOffset(brokerOffsets, 45000), "Expected the latest offset after it went backwards")
+}
+
+func TestInMemoryStorage_addConsumerOffset_Interpolated(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.interpolate-broker-offsets", true)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.testcluster.servers", []string{"broker1.example.com:1234"})
+	module.Configure("test", "storage.test")
+	module.Start()
+	defer module.Stop()
+
+	timestampBase := (time.Now().Unix() * 1000) - 100000
+	for i, offset := range []int64{1000, 2000} {
+		module.addBrokerOffset(&protocol.StorageRequest{
+			RequestType:         protocol.StorageSetBrokerOffset,
+			Cluster:             "testcluster",
+			Topic:               "testtopic",
+			Partition:           0,
+			TopicPartitionCount: 1,
+			Offset:              offset,
+			Timestamp:           timestampBase + int64(i*10000),
+		}, module.Log)
+	}
+
+	// The commit is halfway between the broker offsets, so the broker was at 1500
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      1200,
+		Order:       1,
+		Timestamp:   timestampBase + 5000,
+	}, module.Log)
+
+	var consumerOffset *protocol.ConsumerOffset
+	module.offsets["testcluster"].consumer["testgroup"].topics["testtopic"][0].offsets.Do(func(item interface{}) {
+		if item != nil {
+			consumerOffset = item.(*protocol.ConsumerOffset)
+		}
+	})
+	assert.NotNil(t, consumerOffset, "Expected the consumer offset to be stored")
+	assert.Equalf(t, &protocol.Lag{Value: 300}, consumerOffset.Lag, "Expected lag to be 300, not %v", consumerOffset.Lag)
+}
+
 func TestInMemoryStorage_fetchConsumer(t *testing.T) {
 	startTime := (time.Now().Unix() * 1000)
 	timestampBase := startTime - 100000
@@ -1068,8 +1520,8 @@ func TestInMemoryStorage_fetchConsumer(t *testing.T) {
 	response := <-request.Reply
 	completeTime := time.Now().Unix() * 1000
 
-	assert.IsType(t, protocol.ConsumerTopics{}, response, "Expected response to be of type map[string][]*protocol.consumerPartition")
-	val := response.(protocol.ConsumerTopics)
+	assert.IsType(t, &protocol.ConsumerGroupDetail{}, response, "Expected response to be of type *protocol.ConsumerGroupDetail")
+	val := response.(*protocol.ConsumerGroupDetail).Topics
 	assert.Len(t, val, 1, "One topic for consumer not returned")
 	_, ok := val["testtopic"]
 	assert.True(t, ok, "Expected response to contain topic testtopic")
@@ -1098,75 +1550,74 @@ func TestInMemoryStorage_fetchConsumer(t *testing.T) {
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
-func TestInMemoryStorage_fetchConsumer_BadCluster(t *testing.T) {
-	startTime := (time.Now().Unix() * 1000) - 100000
-	module := startWithTestConsumerOffsets("", startTime)
+func TestInMemoryStorage_fetchConsumer_SharedOffsets(t *testing.T) {
+	timestampBase := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", timestampBase)
 
-	request := protocol.StorageRequest{
-		RequestType: protocol.StorageFetchConsumer,
-		Cluster:     "nocluster",
-		Group:       "testgroup",
-		Reply:       make(chan interface{}),
+	fetchOffsets := func() []*protocol.ConsumerOffset {
+		request := protocol.StorageRequest{
+			RequestType: protocol.StorageFetchConsumer,
+			Cluster:     "testcluster",
+			Group:       "testgroup",
+			Reply:       make(chan interface{}),
+		}
+		go module.fetchConsumer(&request, module.Log)
+		response := <-request.Reply
+		return response.(*protocol.ConsumerGroupDetail).Topics["testtopic"][0].Offsets
 	}
 
-	// Can't read a reply without concurrency
-	go module.fetchConsumer(&request, module.Log)
-	response, ok := <-request.Reply
-
-	assert.Nil(t, response, "Expected response to be nil")
-	assert.False(t, ok, "Expected channel to be closed")
-}
-
-func TestInMemoryStorage_fetchConsumer_BadGroup(t *testing.T) {
-	startTime := (time.Now().Unix() * 1000) - 100000
-	module := startWithTestConsumerOffsets("", startTime)
+	// Fetches share the offsets until the ring changes
+	first := fetchOffsets()
+	second := fetchOffsets()
+	assert.Len(t, first, 10, "Expected to get 10 offsets for the partition")
+	assert.True(t, &first[0] == &second[0], "Expected the offsets to be shared by the fetches")
 
 	request := protocol.StorageRequest{
-		RequestType: protocol.StorageFetchConsumer,
+		RequestType: protocol.StorageSetConsumerOffset,
 		Cluster:     "testcluster",
-		Group:       "nogroup",
-		Reply:       make(chan interface{}),
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      2000,
+		Order:       510,
+		Timestamp:   timestampBase + 100000,
 	}
+	module.addConsumerOffset(&request, module.Log)
 
-	// Can't read a reply without concurrency
-	go module.fetchConsumer(&request, module.Log)
-	response, ok := <-request.Reply
+	// The offsets that were already returned are unchanged
+	assert.Equalf(t, int64(1000), first[0].Offset, "Expected the first offset to still be 1000, not %v", first[0].Offset)
+	assert.Equalf(t, int64(1900), first[9].Offset, "Expected the last offset to still be 1900, not %v", first[9].Offset)
 
-	assert.Nil(t, response, "Expected response to be nil")
-	assert.False(t, ok, "Expected channel to be closed")
+	third := fetchOffsets()
+	assert.False(t, &first[0] == &third[0], "Expected new offsets after the commit")
+	assert.Equalf(t, int64(1100), third[0].Offset, "Expected the first offset to be 1100, not %v", third[0].Offset)
+	assert.Equalf(t, int64(2000), third[9].Offset, "Expected the last offset to be 2000, not %v", third[9].Offset)
 }
 
-func TestInMemoryStorage_fetchConsumer_Expired(t *testing.T) {
-	// We can't insert these offsets normally, so we need to mash them into the module
-	module := startWithTestBrokerOffsets("")
+func TestCommitCounter(t *testing.T) {
+	counter := newCommitCounter(5)
+	now := int64(100) * 60000
 
-	clusterMap := module.offsets["testcluster"]
-	clusterMap.consumerLock.Lock()
-	clusterMap.consumer["testgroup"] = &consumerGroup{
-		lock:   &sync.RWMutex{},
-		topics: make(map[string][]*consumerPartition),
+	// Two commits a minute for the last five minutes
+	for minute := int64(96); minute <= 100; minute++ {
+		counter.add(minute*60000 + 1000)
+		counter.add(minute*60000 + 2000)
 	}
-	consumerMap := clusterMap.consumer["testgroup"]
-	clusterMap.consumerLock.Unlock()
+	assert.Equalf(t, 2.0, counter.rate(now), "Expected rate to be 2.0, not %v", counter.rate(now))
 
-	consumerMap.lock.Lock()
-	consumerMap.topics["testtopic"] = []*consumerPartition{{offsets: ring.New(module.intervals)}}
-	consumerTopicMap := consumerMap.topics["testtopic"]
-	consumerPartitionRing := consumerTopicMap[0].offsets
-	consumerMap.lock.Unlock()
+	// A commit that is older than the minute its bucket is counting is dropped
+	counter.add(91 * 60000)
+	assert.Equalf(t, 2.0, counter.rate(now), "Expected rate to be 2.0, not %v", counter.rate(now))
 
-	for i := 0; i < 10; i++ {
-		offset := uint64(1000 + (i * 100))
-		ts := 1000000 + int64(i*10000)
+	// Minutes that are out of the window are not counted
+	assert.Equalf(t, 0.4, counter.rate(now+4*60000), "Expected rate to be 0.4, not %v", counter.rate(now+4*60000))
+	assert.Equalf(t, 0.0, counter.rate(now+10*60000), "Expected rate to be 0.0, not %v", counter.rate(now+10*60000))
+}
 
-		consumerPartitionRing.Value = &protocol.ConsumerOffset{
-			Offset:    int64(offset),
-			Timestamp: ts,
-			Lag:       &protocol.Lag{Value: 4321 - offset},
-		}
-		consumerMap.lastCommit = ts
-		consumerMap.topics["testtopic"][0].offsets = consumerMap.topics["testtopic"][0].offsets.Next()
-	}
+func TestInMemoryStorage_fetchConsumer_CommitRate(t *testing.T) {
+	// The fixture commits 10 offsets, 10 seconds apart, ending in the last minute or two
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
 
 	request := protocol.StorageRequest{
 		RequestType: protocol.StorageFetchConsumer,
@@ -1177,9 +1628,242 @@ func TestInMemoryStorage_fetchConsumer_Expired(t *testing.T) {
 
 	// Can't read a reply without concurrency
 	go module.fetchConsumer(&request, module.Log)
-	response, ok := <-request.Reply
+	response := <-request.Reply
 
-	assert.Nil(t, response, "Expected response to be nil")
+	val := response.(*protocol.ConsumerGroupDetail).Topics
+	assert.Equalf(t, 2.0, val["testtopic"][0].CommitRate, "Expected commit rate to be 2.0, not %v", val["testtopic"][0].CommitRate)
+}
+
+func TestInMemoryStorage_fetchConsumer_LogStartOffset(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000)
+	module := startWithTestConsumerOffsets("", startTime-100000)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetBrokerLogStartOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Partition:   0,
+		Offset:      2500,
+	}
+	module.addBrokerLogStartOffset(&request, module.Log)
+	assert.Equal(t, int64(2500), module.offsets["testcluster"].logStart["testtopic"][0].offset, "Expected log start offset to be stored")
+
+	request = protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response := <-request.Reply
+
+	val := response.(*protocol.ConsumerGroupDetail).Topics
+	assert.Equalf(t, int64(2500), val["testtopic"][0].LogStartOffset, "Expected log start offset to be 2500, not %v", val["testtopic"][0].LogStartOffset)
+
+	// Deleting the topic removes the log start offsets too
+	request = protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+	}
+	module.deleteTopic(&request, module.Log)
+	_, ok := module.offsets["testcluster"].logStart["testtopic"]
+	assert.False(t, ok, "Expected log start offsets for the topic to be deleted")
+}
+
+func TestInMemoryStorage_fetchConsumer_RetentionHorizon(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000)
+	module := startWithTestConsumerOffsets("", startTime-100000)
+
+	// The consumer has not been seen to lose data to retention yet, so there is no horizon
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response := <-request.Reply
+	val := response.(*protocol.ConsumerGroupDetail).Topics
+	assert.Equalf(t, int64(-1), val["testtopic"][0].RetentionHorizon, "Expected retention horizon to be -1, not %v", val["testtopic"][0].RetentionHorizon)
+
+	// The log start offset advances 200 offsets in 30 seconds. Repeated offsets are not sampled
+	for i, offset := range []int64{1000, 1000, 1100, 1200} {
+		module.addBrokerLogStartOffset(&protocol.StorageRequest{
+			RequestType: protocol.StorageSetBrokerLogStartOffset,
+			Cluster:     "testcluster",
+			Topic:       "testtopic",
+			Partition:   0,
+			Offset:      offset,
+			Timestamp:   startTime - 30000 + int64(i)*10000,
+		}, module.Log)
+	}
+	assert.Lenf(t, module.offsets["testcluster"].logStart["testtopic"][0].samples, 3, "Expected 3 log start samples, not %v", len(module.offsets["testcluster"].logStart["testtopic"][0].samples))
+
+	request.Reply = make(chan interface{})
+	go module.fetchConsumer(&request, module.Log)
+	response = <-request.Reply
+	val = response.(*protocol.ConsumerGroupDetail).Topics
+	consumerOffset := val["testtopic"][0].Offsets[len(val["testtopic"][0].Offsets)-1].Offset
+	expected := (consumerOffset - 1200) * 150
+	assert.Equalf(t, expected, val["testtopic"][0].RetentionHorizon, "Expected retention horizon to be %v, not %v", expected, val["testtopic"][0].RetentionHorizon)
+}
+
+func TestEstimateRetentionHorizon(t *testing.T) {
+	logStart := &logStartOffset{
+		offset:  2000,
+		samples: []*brokerOffset{{Offset: 1000, Timestamp: 10000}, {Offset: 2000, Timestamp: 20000}},
+	}
+	assert.Equal(t, int64(5000), estimateRetentionHorizon(logStart, 2500), "Expected horizon of 5000")
+	assert.Equal(t, int64(0), estimateRetentionHorizon(logStart, 1500), "Expected horizon of 0 for a consumer that lost data")
+
+	logStart.samples = logStart.samples[1:]
+	assert.Equal(t, int64(-1), estimateRetentionHorizon(logStart, 2500), "Expected no horizon with one sample")
+}
+
+func TestInMemoryStorage_fetchConsumer_BadCluster(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "nocluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response, ok := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
+func TestInMemoryStorage_fetchConsumer_BadGroup(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "nogroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response, ok := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
+func TestInMemoryStorage_fetchConsumer_Detail(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.offsets["testcluster"].expected["testgroup"] = 1234
+	module.setConsumerMembers(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members:     []*protocol.ConsumerGroupMember{{MemberID: "testmember", Assignment: map[string][]int32{"testtopic": {0}}}},
+		GroupState:  "Stable",
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response := (<-request.Reply).(*protocol.ConsumerGroupDetail)
+
+	// The rest of the group information is in the same reply as the offsets
+	assert.Len(t, response.Topics, 1, "Expected the topics of the group")
+	assert.Len(t, response.Members, 1, "Expected the members of the group")
+	assert.Equal(t, "testmember", response.Members[0].MemberID, "Expected the member ID")
+	assert.Equal(t, "Stable", response.State, "Expected the group state")
+	assert.Empty(t, response.Rewinds, "Expected no rewinds")
+	assert.Empty(t, response.TopicRemovals, "Expected no topic removals")
+	assert.Equal(t, int64(1234), response.ExpectedSince, "Expected the time the group was registered as expected")
+
+	// Changing the reply must not change what is stored
+	response.Members[0].Assignment["testtopic"][0] = 5
+	assert.Equal(t, int32(0), module.offsets["testcluster"].consumer["testgroup"].members[0].Assignment["testtopic"][0], "Expected stored assignment to be unchanged")
+}
+
+func TestInMemoryStorage_fetchConsumer_ExpectedGroup(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+	module.offsets["testcluster"].expected["nogroup"] = 1234
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "nogroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response := <-request.Reply
+
+	// A group that is expected, but not stored, only has the time it was registered
+	assert.Equal(t, &protocol.ConsumerGroupDetail{ExpectedSince: 1234}, response, "Expected only the registration time")
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
+func TestInMemoryStorage_fetchConsumer_Expired(t *testing.T) {
+	// We can't insert these offsets normally, so we need to mash them into the module
+	module := startWithTestBrokerOffsets("")
+
+	clusterMap := module.offsets["testcluster"]
+	clusterMap.consumerLock.Lock()
+	clusterMap.consumer["testgroup"] = &consumerGroup{
+		lock:   &sync.RWMutex{},
+		topics: make(map[string][]*consumerPartition),
+	}
+	consumerMap := clusterMap.consumer["testgroup"]
+	clusterMap.consumerLock.Unlock()
+
+	consumerMap.lock.Lock()
+	consumerMap.topics["testtopic"] = []*consumerPartition{{offsets: ring.New(module.intervals)}}
+	consumerTopicMap := consumerMap.topics["testtopic"]
+	consumerPartitionRing := consumerTopicMap[0].offsets
+	consumerMap.lock.Unlock()
+
+	for i := 0; i < 10; i++ {
+		offset := uint64(1000 + (i * 100))
+		ts := 1000000 + int64(i*10000)
+
+		consumerPartitionRing.Value = &protocol.ConsumerOffset{
+			Offset:    int64(offset),
+			Timestamp: ts,
+			Lag:       &protocol.Lag{Value: 4321 - offset},
+		}
+		consumerMap.lastCommit = ts
+		consumerMap.topics["testtopic"][0].offsets = consumerMap.topics["testtopic"][0].offsets.Next()
+	}
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumer(&request, module.Log)
+	response, ok := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
 	assert.False(t, ok, "Expected channel to be closed")
 }
 
@@ -1309,3 +1993,706 @@ func TestInMemoryStorage_fetchConsumersForTopic_BadCluster(t *testing.T) {
 	assert.Nil(t, response, "Expected response to be nil")
 	assert.False(t, ok, "Expected channel to be closed")
 }
+
+func TestInMemoryStorage_Start_ExpectedGroups(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.testcluster.servers", []string{"broker1.example.com:1234"})
+	viper.Set("cluster.testcluster.expected-groups", []string{"testgroup"})
+	module.Configure("test", "storage.test")
+	module.Start()
+
+	assert.Lenf(t, module.offsets["testcluster"].expected, 1, "Expected 1 expected group, not %v", len(module.offsets["testcluster"].expected))
+	assert.Containsf(t, module.offsets["testcluster"].expected, "testgroup", "Expected testgroup to be an expected group")
+}
+
+func TestInMemoryStorage_Start_DynamicExpectedGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	defer os.RemoveAll(dir)
+	defer helpers.LoadDynamicConfig("")
+	helpers.LoadDynamicConfig(filepath.Join(dir, "dynamic.json"))
+	helpers.SaveDynamicExpectedGroup("testcluster", "addedgroup", true)
+	helpers.SaveDynamicExpectedGroup("testcluster", "testgroup", false)
+
+	module := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("cluster.testcluster.servers", []string{"broker1.example.com:1234"})
+	viper.Set("cluster.testcluster.expected-groups", []string{"testgroup", "othergroup"})
+	module.Configure("test", "storage.test")
+	module.Start()
+
+	// Groups added through the HTTP server are expected, and groups removed through it are not
+	assert.Lenf(t, module.offsets["testcluster"].expected, 2, "Expected 2 expected groups, not %v", len(module.offsets["testcluster"].expected))
+	assert.Containsf(t, module.offsets["testcluster"].expected, "addedgroup", "Expected addedgroup to be an expected group")
+	assert.Containsf(t, module.offsets["testcluster"].expected, "othergroup", "Expected othergroup to be an expected group")
+}
+
+func TestInMemoryStorage_addExpectedGroup(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Timestamp:   1234,
+	}
+	module.addExpectedGroup(&request, module.Log)
+
+	// Registering the group again must not change the registration time
+	request.Timestamp = 5678
+	module.addExpectedGroup(&request, module.Log)
+
+	registered, ok := module.offsets["testcluster"].expected["testgroup"]
+	assert.True(t, ok, "Expected testgroup to be an expected group")
+	assert.Equalf(t, int64(1234), registered, "Expected registration time to be 1234, not %v", registered)
+}
+
+func TestInMemoryStorage_addExpectedGroup_DefaultTimestamp(t *testing.T) {
+	module := startWithTestCluster("")
+	startTime := time.Now().Unix() * 1000
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}
+	module.addExpectedGroup(&request, module.Log)
+
+	registered := module.offsets["testcluster"].expected["testgroup"]
+	assert.Truef(t, registered >= startTime, "Expected registration time to be at least %v, not %v", startTime, registered)
+}
+
+func TestInMemoryStorage_addExpectedGroup_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetExpectedGroup,
+		Cluster:     "nocluster",
+		Group:       "testgroup",
+	}
+	module.addExpectedGroup(&request, module.Log)
+
+	_, ok := module.offsets["nocluster"]
+	assert.False(t, ok, "Cluster nocluster created when it should not have been")
+}
+
+func TestInMemoryStorage_deleteExpectedGroup(t *testing.T) {
+	module := startWithTestCluster("")
+	module.offsets["testcluster"].expected["testgroup"] = 1234
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteExpectedGroup,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+	}
+	module.deleteExpectedGroup(&request, module.Log)
+
+	_, ok := module.offsets["testcluster"].expected["testgroup"]
+	assert.False(t, ok, "Expected testgroup to be removed from expected groups")
+}
+
+func TestInMemoryStorage_fetchExpectedGroups(t *testing.T) {
+	module := startWithTestCluster("")
+	module.offsets["testcluster"].expected["testgroup"] = 1234
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchExpectedGroups,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchExpectedGroups(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, map[string]int64{}, response, "Expected response to be of type map[string]int64")
+	val := response.(map[string]int64)
+	assert.Equalf(t, map[string]int64{"testgroup": 1234}, val, "Expected return value to contain testgroup, not %v", val)
+
+	_, ok := <-request.Reply
+	assert.False(t, ok, "Expected channel to be closed")
+}
+
+func TestInMemoryStorage_fetchExpectedGroups_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchExpectedGroups,
+		Cluster:     "nocluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchExpectedGroups(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_setConsumerMembers(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members: []*protocol.ConsumerGroupMember{
+			{
+				MemberID:   "testmember",
+				ClientID:   "testclient",
+				ClientHost: "testhost",
+				Assignment: map[string][]int32{"testtopic": {0, 1}},
+			},
+		},
+	}
+	module.setConsumerMembers(&request, module.Log)
+
+	consumer, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.True(t, ok, "Group testgroup not created")
+	assert.Lenf(t, consumer.members, 1, "Expected exactly one member, not %v", len(consumer.members))
+	assert.Equalf(t, "testmember", consumer.members[0].MemberID, "Expected MemberID to be testmember, not %v", consumer.members[0].MemberID)
+
+	// An empty member list replaces the existing one
+	request.Members = make([]*protocol.ConsumerGroupMember, 0)
+	module.setConsumerMembers(&request, module.Log)
+	assert.Lenf(t, consumer.members, 0, "Expected no members, not %v", len(consumer.members))
+}
+
+func TestInMemoryStorage_setConsumerMembers_NoGroup(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members:     make([]*protocol.ConsumerGroupMember, 0),
+	}
+	module.setConsumerMembers(&request, module.Log)
+
+	_, ok := module.offsets["testcluster"].consumer["testgroup"]
+	assert.False(t, ok, "Group testgroup created when it should not have been")
+}
+
+func TestInMemoryStorage_fetchConsumerMembers(t *testing.T) {
+	module := startWithTestCluster("")
+	module.setConsumerMembers(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members: []*protocol.ConsumerGroupMember{
+			{
+				MemberID:   "testmember",
+				ClientID:   "testclient",
+				ClientHost: "testhost",
+				Assignment: map[string][]int32{"testtopic": {0, 1}},
+			},
+		},
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerMembers(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, []*protocol.ConsumerGroupMember{}, response, "Expected response to be of type []*protocol.ConsumerGroupMember")
+	val := response.([]*protocol.ConsumerGroupMember)
+	assert.Lenf(t, val, 1, "Expected exactly one member, not %v", len(val))
+	assert.Equalf(t, "testclient", val[0].ClientID, "Expected ClientID to be testclient, not %v", val[0].ClientID)
+	assert.Equalf(t, []int32{0, 1}, val[0].Assignment["testtopic"], "Expected assignment to be testtopic partitions 0 and 1, not %v", val[0].Assignment)
+
+	// Changing the response must not change what is stored
+	val[0].Assignment["testtopic"][0] = 5
+	assert.Equalf(t, int32(0), module.offsets["testcluster"].consumer["testgroup"].members[0].Assignment["testtopic"][0], "Expected stored assignment to be unchanged")
+}
+
+func TestInMemoryStorage_fetchConsumerGroupState(t *testing.T) {
+	module := startWithTestCluster("")
+	module.setConsumerMembers(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Members:     []*protocol.ConsumerGroupMember{{MemberID: "testmember"}},
+		GroupState:  "PreparingRebalance",
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerGroupState,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerGroupState(&request, module.Log)
+	response := <-request.Reply
+	assert.Equalf(t, "PreparingRebalance", response, "Expected state to be PreparingRebalance, not %v", response)
+}
+
+func TestInMemoryStorage_fetchConsumerMembers_BadGroup(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerMembers,
+		Cluster:     "testcluster",
+		Group:       "nogroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerMembers(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_addConsumerOffset_Rewind(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := fixtureModule("", "")
+	viper.Set("storage.test.rewind-threshold", 500)
+	viper.Set("storage.test.rewind-history", 2)
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	module.Configure("test", "storage.test")
+	module.Start()
+
+	module.addBrokerOffset(&protocol.StorageRequest{
+		RequestType:         protocol.StorageSetBrokerOffset,
+		Cluster:             "testcluster",
+		Topic:               "testtopic",
+		Partition:           0,
+		TopicPartitionCount: 1,
+		Offset:              10000,
+		Timestamp:           startTime,
+	}, module.Log)
+
+	// Commits that go backwards by less than the threshold are not rewinds
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+	}
+	for i, offset := range []int64{5000, 4800, 4000, 6000, 1000, 600, 0} {
+		request.Offset = offset
+		request.Order = int64(i)
+		request.Timestamp = startTime + int64(i*10000)
+		module.addConsumerOffset(&request, module.Log)
+	}
+
+	request = protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerRewinds,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerRewinds(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, []*protocol.OffsetRewind{}, response, "Expected response to be of type []*protocol.OffsetRewind")
+	val := response.([]*protocol.OffsetRewind)
+	assert.Lenf(t, val, 2, "Expected the last 2 rewinds, not %v", len(val))
+	assert.Equal(t, &protocol.OffsetRewind{Topic: "testtopic", Partition: 0, PreviousOffset: 6000, Offset: 1000, Timestamp: startTime + 40000}, val[0])
+	assert.Equal(t, &protocol.OffsetRewind{Topic: "testtopic", Partition: 0, PreviousOffset: 600, Offset: 0, Timestamp: startTime + 60000}, val[1])
+}
+
+func TestInMemoryStorage_fetchConsumerRewinds_BadGroup(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerRewinds,
+		Cluster:     "testcluster",
+		Group:       "nogroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerRewinds(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchConsumerTopicRemovals(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	module.deleteTopic(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Reason:      "partition count decreased",
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerTopicRemovals,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerTopicRemovals(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, []*protocol.TopicRemoval{}, response, "Expected response to be of type []*protocol.TopicRemoval")
+	removals := response.([]*protocol.TopicRemoval)
+	assert.Lenf(t, removals, 1, "Expected one topic removal, not %v", len(removals))
+	assert.Equal(t, "testtopic", removals[0].Topic, "Expected removal Topic to be testtopic")
+	assert.Equal(t, "partition count decreased", removals[0].Reason, "Expected removal Reason to be partition count decreased")
+
+	// Modifying the response must not change what is stored
+	removals[0].Reason = "changed"
+	assert.Equal(t, "partition count decreased", module.offsets["testcluster"].consumer["testgroup"].topicRemovals[0].Reason, "Expected stored removal to be unchanged")
+}
+
+func TestInMemoryStorage_fetchConsumerTopicRemovals_BadGroup(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumerTopicRemovals,
+		Cluster:     "testcluster",
+		Group:       "nogroup",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConsumerTopicRemovals(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_Configure_BadCommitRateWindow(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.commit-rate-window", 0)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_Configure_BadRewindThreshold(t *testing.T) {
+	module := fixtureModule("", "")
+	viper.Set("storage.test.rewind-threshold", 0)
+	assert.Panics(t, func() { module.Configure("test", "storage.test") }, "The code did not panic")
+}
+
+func TestInMemoryStorage_setConnectors(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConnectors,
+		Cluster:     "testcluster",
+		Source:      "testconnect",
+		Connectors: []*protocol.Connector{
+			{Name: "testsink", ConnectCluster: "testconnect", Type: "sink", Group: "connect-testsink"},
+			{Name: "testsource", ConnectCluster: "testconnect", Type: "source"},
+		},
+	}
+	module.setConnectors(&request, module.Log)
+	assert.Lenf(t, module.offsets["testcluster"].connectors["testconnect"], 2, "Expected two connectors, not %v", len(module.offsets["testcluster"].connectors["testconnect"]))
+
+	// Connectors that are not in the next request are removed
+	request.Connectors = request.Connectors[0:1]
+	module.setConnectors(&request, module.Log)
+	_, ok := module.offsets["testcluster"].connectors["testconnect"]["testsource"]
+	assert.False(t, ok, "Expected testsource connector to be removed")
+}
+
+func TestInMemoryStorage_setConnectors_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageSetConnectors,
+		Cluster:     "nocluster",
+		Source:      "testconnect",
+	}
+	module.setConnectors(&request, module.Log)
+
+	_, ok := module.offsets["nocluster"]
+	assert.False(t, ok, "Cluster nocluster created when it should not have been")
+}
+
+func TestInMemoryStorage_fetchConnectors(t *testing.T) {
+	module := startWithTestCluster("")
+	module.setConnectors(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConnectors,
+		Cluster:     "testcluster",
+		Source:      "connect2",
+		Connectors:  []*protocol.Connector{{Name: "sinkB", ConnectCluster: "connect2"}},
+	}, module.Log)
+	module.setConnectors(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConnectors,
+		Cluster:     "testcluster",
+		Source:      "connect1",
+		Connectors: []*protocol.Connector{
+			{Name: "sinkA", ConnectCluster: "connect1", Tasks: []*protocol.ConnectorTask{{ID: 0, State: "RUNNING"}}},
+		},
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConnectors,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConnectors(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, []*protocol.Connector{}, response, "Expected response to be of type []*protocol.Connector")
+	val := response.([]*protocol.Connector)
+	assert.Lenf(t, val, 2, "Expected two connectors, not %v", len(val))
+	assert.Equalf(t, "sinkA", val[0].Name, "Expected first connector to be sinkA, not %v", val[0].Name)
+	assert.Equalf(t, "sinkB", val[1].Name, "Expected second connector to be sinkB, not %v", val[1].Name)
+
+	// Changing the response must not change what is stored
+	val[0].Tasks[0].State = "FAILED"
+	assert.Equalf(t, "RUNNING", module.offsets["testcluster"].connectors["connect1"]["sinkA"].Tasks[0].State, "Expected stored task state to be unchanged")
+}
+
+func TestInMemoryStorage_fetchConnectors_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConnectors,
+		Cluster:     "nocluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchConnectors(&request, module.Log)
+	response := <-request.Reply
+
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchTopicConfig(t *testing.T) {
+	module := startWithTestCluster("")
+	module.setTopicConfig(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetTopicConfig,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		TopicConfig: map[string]string{"retention.ms": "86400000", "cleanup.policy": "delete"},
+	}, module.Log)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchTopicConfig,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchTopicConfig(&request, module.Log)
+	response := <-request.Reply
+
+	assert.IsType(t, map[string]string{}, response, "Expected response to be of type map[string]string")
+	val := response.(map[string]string)
+	assert.Equal(t, map[string]string{"retention.ms": "86400000", "cleanup.policy": "delete"}, val, "Expected the stored topic config")
+
+	// Changing the response must not change what is stored
+	val["retention.ms"] = "1"
+	assert.Equalf(t, "86400000", module.offsets["testcluster"].topicConfig["testtopic"]["retention.ms"], "Expected stored config to be unchanged")
+
+	// The config is removed with the topic
+	module.deleteTopic(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetDeleteTopic,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+	}, module.Log)
+	_, ok := module.offsets["testcluster"].topicConfig["testtopic"]
+	assert.False(t, ok, "Topic config not deleted with the topic")
+}
+
+func TestInMemoryStorage_fetchTopicConfig_NoTopic(t *testing.T) {
+	module := startWithTestCluster("")
+
+	for _, cluster := range []string{"testcluster", "nocluster"} {
+		request := protocol.StorageRequest{
+			RequestType: protocol.StorageFetchTopicConfig,
+			Cluster:     cluster,
+			Topic:       "notopic",
+			Reply:       make(chan interface{}),
+		}
+
+		// Can't read a reply without concurrency
+		go module.fetchTopicConfig(&request, module.Log)
+		response := <-request.Reply
+		assert.Nilf(t, response, "Expected response to be nil for cluster %v", cluster)
+	}
+}
+
+func TestInMemoryStorage_fetchClusterReplication(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterReplication,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Nothing is returned until the replication status has been set
+	go module.fetchClusterReplication(&request, module.Log)
+	response := <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+
+	replication := &protocol.ClusterReplication{
+		UnderReplicated: []*protocol.PartitionReplicas{{Topic: "testtopic", Partition: 0, Replicas: []int32{1, 2}, InSyncReplicas: []int32{1}}},
+		Offline:         []*protocol.PartitionReplicas{},
+		Timestamp:       1234,
+	}
+	module.setClusterReplication(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetClusterReplication,
+		Cluster:     "testcluster",
+		Replication: replication,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchClusterReplication(&request, module.Log)
+	response = <-request.Reply
+	assert.Equal(t, replication, response, "Expected the stored replication status")
+}
+
+func TestInMemoryStorage_fetchClusterReplication_BadCluster(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterReplication,
+		Cluster:     "nocluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Can't read a reply without concurrency
+	go module.fetchClusterReplication(&request, module.Log)
+	response := <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchClusterLeaderChurn(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterLeaderChurn,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Nothing is returned until the leader churn has been set
+	go module.fetchClusterLeaderChurn(&request, module.Log)
+	response := <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+
+	leaderChurn := &protocol.ClusterLeaderChurn{
+		Partitions: []*protocol.PartitionLeaderChurn{{Topic: "testtopic", Partition: 0, Leader: 2, LeaderChanges: 3, LastChange: 1234}},
+		Window:     3600,
+		Timestamp:  1234,
+	}
+	module.setClusterLeaderChurn(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetClusterLeaderChurn,
+		Cluster:     "testcluster",
+		LeaderChurn: leaderChurn,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchClusterLeaderChurn(&request, module.Log)
+	response = <-request.Reply
+	assert.Equal(t, leaderChurn, response, "Expected the stored leader churn")
+}
+
+func TestInMemoryStorage_fetchClusterActivity(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterActivity,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchClusterActivity(&request, module.Log)
+	response := <-request.Reply
+
+	// The broker offset time, and the latest commit time, are returned, along with the queues of the default 20 workers
+	// and the request channel, which are empty
+	assert.Equal(t, &protocol.ClusterActivity{LastBrokerOffset: 9876, LastConsumerOffset: startTime + 90000, QueueDepth: 0, QueueCapacity: 21}, response, "Expected the latest offset times")
+
+	request.Cluster = "nocluster"
+	request.Reply = make(chan interface{})
+	go module.fetchClusterActivity(&request, module.Log)
+	response = <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchConsumedTopics(t *testing.T) {
+	startTime := (time.Now().Unix() * 1000) - 100000
+	module := startWithTestConsumerOffsets("", startTime)
+
+	// A commit for a topic that has no broker offsets is dropped, but the topic is still consumed
+	module.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "othertopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      1000,
+		Order:       600,
+		Timestamp:   startTime,
+	}, module.Log)
+
+	// A commit older than expire-group is removed
+	module.offsets["testcluster"].consumedTopics.Store("oldtopic", startTime-(module.expireGroup*1000))
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumedTopics,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumedTopics(&request, module.Log)
+	response := <-request.Reply
+	assert.Equal(t, []string{"othertopic", "testtopic"}, response, "Expected the consumed topics")
+	_, ok := module.offsets["testcluster"].consumedTopics.Load("oldtopic")
+	assert.False(t, ok, "Expected oldtopic to be removed")
+
+	request.Cluster = "nocluster"
+	request.Reply = make(chan interface{})
+	go module.fetchConsumedTopics(&request, module.Log)
+	response = <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+}
+
+func TestInMemoryStorage_fetchClusterBrokers(t *testing.T) {
+	module := startWithTestCluster("")
+
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchClusterBrokers,
+		Cluster:     "testcluster",
+		Reply:       make(chan interface{}),
+	}
+
+	// Nothing is returned until the brokers have been set
+	go module.fetchClusterBrokers(&request, module.Log)
+	response := <-request.Reply
+	assert.Nil(t, response, "Expected response to be nil")
+
+	brokers := &protocol.ClusterBrokers{
+		Brokers:      []*protocol.Broker{{ID: 1, Host: "broker1.example.com", Port: 9092}},
+		ControllerID: 1,
+		Timestamp:    1234,
+	}
+	module.setClusterBrokers(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetClusterBrokers,
+		Cluster:     "testcluster",
+		Brokers:     brokers,
+	}, module.Log)
+
+	request.Reply = make(chan interface{})
+	go module.fetchClusterBrokers(&request, module.Log)
+	response = <-request.Reply
+	assert.Equal(t, brokers, response, "Expected the stored brokers")
+}
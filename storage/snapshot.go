@@ -0,0 +1,282 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package storage
+
+import (
+	"container/ring"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+// storageSnapshot is the offset history of an InMemoryStorage module, as it is written to the snapshot file. Only the
+// offsets (and the owners of the partitions) are kept. Everything else that is stored, such as the topic configs and
+// broker metadata, is fetched again by the cluster modules soon after starting.
+type storageSnapshot struct {
+	Time     int64                       `json:"time"`
+	Clusters map[string]*clusterSnapshot `json:"clusters"`
+}
+
+type clusterSnapshot struct {
+	// Broker offsets for each topic, indexed by partition, oldest first
+	Brokers map[string][][]*brokerOffset `json:"brokers"`
+
+	Groups map[string]*groupSnapshot `json:"groups"`
+}
+
+type groupSnapshot struct {
+	LastCommit int64                           `json:"last-commit"`
+	Topics     map[string][]*partitionSnapshot `json:"topics"`
+}
+
+type partitionSnapshot struct {
+	Owner    string            `json:"owner"`
+	ClientID string            `json:"client-id"`
+	Offsets  []*offsetSnapshot `json:"offsets"`
+}
+
+// offsetSnapshot is a protocol.ConsumerOffset, including the order of the commit (which is not in its JSON encoding)
+type offsetSnapshot struct {
+	Offset            int64         `json:"offset"`
+	Order             int64         `json:"order"`
+	Timestamp         int64         `json:"timestamp"`
+	ObservedTimestamp int64         `json:"observed"`
+	Lag               *protocol.Lag `json:"lag"`
+}
+
+func (module *InMemoryStorage) writeSnapshotRequest(request *protocol.StorageRequest, requestLogger *zap.Logger) {
+	err := module.writeSnapshot()
+	if err != nil {
+		requestLogger.Error("failed to write snapshot", zap.String("file", module.snapshotFile), zap.Error(err))
+	} else if module.snapshotFile != "" {
+		requestLogger.Info("wrote snapshot", zap.String("file", module.snapshotFile))
+	}
+	request.Reply <- err
+	close(request.Reply)
+}
+
+// writeSnapshot writes the offsets for every cluster to the snapshot file, if one is configured. The file is written
+// to a temporary file and then renamed, so that a process reading it never sees a partial snapshot. This is safe to
+// call while the workers are running, as the same locks are held as for a fetch.
+func (module *InMemoryStorage) writeSnapshot() error {
+	if module.snapshotFile == "" {
+		return nil
+	}
+
+	module.snapshotLock.Lock()
+	defer module.snapshotLock.Unlock()
+
+	snapshot := &storageSnapshot{
+		Time:     time.Now().Unix() * 1000,
+		Clusters: make(map[string]*clusterSnapshot, len(module.offsets)),
+	}
+	for cluster, clusterMap := range module.offsets {
+		snapshot.Clusters[cluster] = getClusterSnapshot(clusterMap)
+	}
+
+	contents, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tempFilename := module.snapshotFile + ".tmp"
+	if err := ioutil.WriteFile(tempFilename, contents, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempFilename, module.snapshotFile)
+}
+
+func getClusterSnapshot(clusterMap clusterOffsets) *clusterSnapshot {
+	snapshot := &clusterSnapshot{
+		Brokers: make(map[string][][]*brokerOffset),
+		Groups:  make(map[string]*groupSnapshot),
+	}
+
+	clusterMap.brokerLock.RLock()
+	for topic, partitions := range clusterMap.broker {
+		snapshot.Brokers[topic] = make([][]*brokerOffset, len(partitions))
+		for partition, offsetRing := range partitions {
+			// The broker ring points to the most recent offset, so the oldest is the next one
+			snapshot.Brokers[topic][partition] = make([]*brokerOffset, 0)
+			offsetRing.Next().Do(func(value interface{}) {
+				if value != nil {
+					offset := *value.(*brokerOffset)
+					snapshot.Brokers[topic][partition] = append(snapshot.Brokers[topic][partition], &offset)
+				}
+			})
+		}
+	}
+	clusterMap.brokerLock.RUnlock()
+
+	clusterMap.consumerLock.RLock()
+	groups := make(map[string]*consumerGroup, len(clusterMap.consumer))
+	for group, consumerMap := range clusterMap.consumer {
+		groups[group] = consumerMap
+	}
+	clusterMap.consumerLock.RUnlock()
+
+	for group, consumerMap := range groups {
+		snapshot.Groups[group] = getGroupSnapshot(consumerMap)
+	}
+	return snapshot
+}
+
+func getGroupSnapshot(consumerMap *consumerGroup) *groupSnapshot {
+	consumerMap.lock.RLock()
+	defer consumerMap.lock.RUnlock()
+
+	snapshot := &groupSnapshot{
+		LastCommit: consumerMap.lastCommit,
+		Topics:     make(map[string][]*partitionSnapshot, len(consumerMap.topics)),
+	}
+	for topic, partitions := range consumerMap.topics {
+		snapshot.Topics[topic] = make([]*partitionSnapshot, len(partitions))
+		for i, partition := range partitions {
+			partitionSnapshot := &partitionSnapshot{
+				Owner:    partition.owner,
+				ClientID: partition.clientID,
+				Offsets:  make([]*offsetSnapshot, 0),
+			}
+			if partition.offsets != nil {
+				// The consumer ring points to the oldest offset
+				partition.offsets.Do(func(value interface{}) {
+					if value != nil {
+						offset := value.(*protocol.ConsumerOffset)
+						partitionSnapshot.Offsets = append(partitionSnapshot.Offsets, &offsetSnapshot{
+							Offset:            offset.Offset,
+							Order:             offset.Order,
+							Timestamp:         offset.Timestamp,
+							ObservedTimestamp: offset.ObservedTimestamp,
+							Lag:               offset.Lag,
+						})
+					}
+				})
+			}
+			snapshot.Topics[topic][i] = partitionSnapshot
+		}
+	}
+	return snapshot
+}
+
+// restoreSnapshot loads the offsets from the snapshot file, if one is configured and it was written within the last
+// snapshot-max-age seconds. Clusters that are no longer configured are skipped, as are groups that have expired. This
+// must be called before the workers are started.
+func (module *InMemoryStorage) restoreSnapshot() {
+	if module.snapshotFile == "" {
+		return
+	}
+	contents, err := ioutil.ReadFile(module.snapshotFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			module.Log.Warn("failed to read snapshot", zap.String("file", module.snapshotFile), zap.Error(err))
+		}
+		return
+	}
+	snapshot := &storageSnapshot{}
+	if err := json.Unmarshal(contents, snapshot); err != nil {
+		module.Log.Warn("failed to decode snapshot", zap.String("file", module.snapshotFile), zap.Error(err))
+		return
+	}
+	if snapshot.Time < (time.Now().Unix()-module.snapshotMaxAge)*1000 {
+		module.Log.Info("not restoring snapshot, as it is too old", zap.String("file", module.snapshotFile),
+			zap.Int64("snapshot_time", snapshot.Time))
+		return
+	}
+
+	expireTime := (time.Now().Unix() - module.expireGroup) * 1000
+	groupCount := 0
+	for cluster, clusterSnapshot := range snapshot.Clusters {
+		clusterMap, ok := module.offsets[cluster]
+		if !ok {
+			continue
+		}
+		for topic, partitions := range clusterSnapshot.Brokers {
+			clusterMap.broker[topic] = make([]*ring.Ring, len(partitions))
+			for partition, offsets := range partitions {
+				offsetRing := ring.New(module.intervals)
+				for _, offset := range lastOffsets(len(offsets), module.intervals) {
+					offsetRing = offsetRing.Next()
+					offsetRing.Value = offsets[offset]
+				}
+				clusterMap.broker[topic][partition] = offsetRing
+			}
+		}
+
+		for group, groupSnapshot := range clusterSnapshot.Groups {
+			if (groupSnapshot.LastCommit < expireTime) || !module.acceptConsumerGroup(group) {
+				continue
+			}
+			clusterMap.consumer[group] = module.restoreGroup(clusterMap, groupSnapshot)
+			groupCount++
+		}
+	}
+	module.Log.Info("restored snapshot", zap.String("file", module.snapshotFile), zap.Int("groups", groupCount))
+}
+
+func (module *InMemoryStorage) restoreGroup(clusterMap clusterOffsets, snapshot *groupSnapshot) *consumerGroup {
+	consumerMap := &consumerGroup{
+		lock:       &sync.RWMutex{},
+		topics:     make(map[string][]*consumerPartition, len(snapshot.Topics)),
+		lastCommit: snapshot.LastCommit,
+	}
+	for topic, partitions := range snapshot.Topics {
+		if !module.filter.AcceptTopic(topic) {
+			continue
+		}
+		consumerMap.topics[topic] = make([]*consumerPartition, len(partitions))
+		for i, partitionSnapshot := range partitions {
+			partition := &consumerPartition{
+				owner:    partitionSnapshot.Owner,
+				clientID: partitionSnapshot.ClientID,
+			}
+			if len(partitionSnapshot.Offsets) > 0 {
+				// Leave the ring pointing at the slot after the latest offset, which is the oldest offset in a full ring
+				partition.offsets = ring.New(module.intervals)
+				for _, offset := range lastOffsets(len(partitionSnapshot.Offsets), module.intervals) {
+					stored := partitionSnapshot.Offsets[offset]
+					partition.offsets.Value = &protocol.ConsumerOffset{
+						Offset:            stored.Offset,
+						Order:             stored.Order,
+						Timestamp:         stored.Timestamp,
+						ObservedTimestamp: stored.ObservedTimestamp,
+						Lag:               stored.Lag,
+					}
+					partition.offsets = partition.offsets.Next()
+
+					if latest, ok := clusterMap.consumedTopics.Load(topic); !ok || (latest.(int64) < stored.Timestamp) {
+						clusterMap.consumedTopics.Store(topic, stored.Timestamp)
+					}
+				}
+			}
+			consumerMap.topics[topic][i] = partition
+		}
+	}
+	return consumerMap
+}
+
+// lastOffsets returns the indexes of the last intervals items of a list of count items, in order, as the number of
+// intervals may have been reduced since the snapshot was written
+func lastOffsets(count, intervals int) []int {
+	start := 0
+	if count > intervals {
+		start = count - intervals
+	}
+	indexes := make([]int, 0, count-start)
+	for i := start; i < count; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
@@ -0,0 +1,122 @@
+/* Copyright 2017 LinkedIn Corp. Licensed under the Apache License, Version
+ * 2.0 (the "License"); you may not use this file except in compliance with
+ * the License. You may obtain a copy of the License at
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ */
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkedin/Burrow/protocol"
+)
+
+func fetchTestConsumer(module *InMemoryStorage) protocol.ConsumerTopics {
+	request := protocol.StorageRequest{
+		RequestType: protocol.StorageFetchConsumer,
+		Cluster:     "testcluster",
+		Group:       "testgroup",
+		Reply:       make(chan interface{}),
+	}
+	go module.fetchConsumer(&request, module.Log)
+	response, _ := (<-request.Reply).(*protocol.ConsumerGroupDetail)
+	if response == nil {
+		return nil
+	}
+	return response.Topics
+}
+
+func TestInMemoryStorage_Snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow-snapshot")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+
+	module := startWithTestConsumerOffsets("", (time.Now().Unix()*1000)-100000)
+	module.addConsumerOwner(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOwner,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Owner:       "testhost.example.com",
+		ClientID:    "test_client_id",
+	}, module.Log)
+	module.snapshotFile = snapshotFile
+
+	request := &protocol.StorageRequest{
+		RequestType: protocol.StorageWriteSnapshot,
+		Reply:       make(chan interface{}, 1),
+	}
+	module.writeSnapshotRequest(request, module.Log)
+	assert.Nil(t, <-request.Reply, "Expected the snapshot to be written")
+	expected := fetchTestConsumer(module)
+	module.Stop()
+
+	// Restore into a new module with fewer intervals, which keeps only the latest offsets
+	restored := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("storage.test.snapshot-file", snapshotFile)
+	viper.Set("storage.test.intervals", 5)
+	restored.Configure("test", "storage.test")
+	restored.Start()
+	defer restored.Stop()
+
+	clusterMap := restored.offsets["testcluster"]
+	brokerOffset, partitionCount := restored.getBrokerOffset(&clusterMap, "testtopic", 0, restored.Log)
+	assert.Equal(t, int64(4321), brokerOffset, "Expected the broker offset to be restored")
+	assert.Equal(t, int32(1), partitionCount, "Expected the partition count to be restored")
+
+	actual := fetchTestConsumer(restored)
+	assert.Len(t, actual["testtopic"], 1, "Expected one partition to be restored")
+	assert.Equal(t, expected["testtopic"][0].Offsets[5:], actual["testtopic"][0].Offsets, "Expected the latest offsets to be restored")
+	assert.Equal(t, "testhost.example.com", actual["testtopic"][0].Owner, "Expected the owner to be restored")
+
+	// New offsets are added after the restored ones
+	restored.addConsumerOffset(&protocol.StorageRequest{
+		RequestType: protocol.StorageSetConsumerOffset,
+		Cluster:     "testcluster",
+		Topic:       "testtopic",
+		Group:       "testgroup",
+		Partition:   0,
+		Offset:      2000,
+		Order:       600,
+		Timestamp:   time.Now().Unix() * 1000,
+	}, restored.Log)
+	actual = fetchTestConsumer(restored)
+	assert.Equal(t, int64(2000), actual["testtopic"][0].Offsets[4].Offset, "Expected the new offset to be the latest")
+	assert.Equal(t, expected["testtopic"][0].Offsets[6].Offset, actual["testtopic"][0].Offsets[0].Offset, "Expected the oldest offset to be dropped")
+}
+
+func TestInMemoryStorage_Snapshot_TooOld(t *testing.T) {
+	dir, err := ioutil.TempDir("", "burrow-snapshot")
+	assert.NoError(t, err, "Expected temp dir to be created")
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+
+	module := startWithTestConsumerOffsets("", (time.Now().Unix()*1000)-100000)
+	module.snapshotFile = snapshotFile
+	module.Stop()
+
+	restored := fixtureModule("", "")
+	viper.Set("cluster.testcluster.class-name", "kafka")
+	viper.Set("storage.test.snapshot-file", snapshotFile)
+	viper.Set("storage.test.snapshot-max-age", -10)
+	restored.Configure("test", "storage.test")
+	restored.Start()
+	defer restored.Stop()
+
+	assert.Nil(t, fetchTestConsumer(restored), "Expected the snapshot not to be restored")
+}